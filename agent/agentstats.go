@@ -0,0 +1,163 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupDurationBuckets are the upper bounds (seconds) of the backup duration histogram
+var backupDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// AgentStats holds process-wide counters about the agent itself, exported on /metrics
+type AgentStats struct {
+	mu               sync.Mutex
+	startTime        time.Time
+	reconnects       uint64
+	messagesReceived map[string]uint64 // subject -> count
+	messagesSent     map[string]uint64 // subject -> count
+	sendErrors       map[string]uint64 // subject -> count
+	backupDurations  map[backupStatKey]*durationHistogram
+}
+
+// backupStatKey identifies a backup duration histogram
+type backupStatKey struct {
+	Operation string // create or restore
+	Result    string // success or failure
+}
+
+// durationHistogram is a cumulative histogram of durations in seconds
+type durationHistogram struct {
+	Buckets []float64
+	Counts  []uint64 // Counts[i] = observations <= Buckets[i]
+	Count   uint64
+	Sum     float64
+}
+
+// agentStats is the agent's stats instance
+var agentStats = NewAgentStats()
+
+// NewAgentStats creates empty stats
+func NewAgentStats() *AgentStats {
+	return &AgentStats{
+		startTime:        time.Now(),
+		messagesReceived: make(map[string]uint64),
+		messagesSent:     make(map[string]uint64),
+		sendErrors:       make(map[string]uint64),
+		backupDurations:  make(map[backupStatKey]*durationHistogram),
+	}
+}
+
+// statsSubject collapses reply inboxes (_INBOX.<uuid>) so subjects stay a bounded label set
+func statsSubject(subject string) string {
+	if strings.HasPrefix(subject, "_INBOX.") {
+		return "_INBOX"
+	}
+	if subject == "" {
+		return "unknown"
+	}
+	return subject
+}
+
+// Reconnected counts a lost manager connection
+func (s *AgentStats) Reconnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconnects++
+}
+
+// MessageReceived counts a message from the manager
+func (s *AgentStats) MessageReceived(subject string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messagesReceived[statsSubject(subject)]++
+}
+
+// MessageSent counts a message to the manager and whether it failed
+func (s *AgentStats) MessageSent(subject string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subject = statsSubject(subject)
+	s.messagesSent[subject]++
+	if err != nil {
+		s.sendErrors[subject]++
+	}
+}
+
+// BackupFinished records how long a backup create or restore took
+func (s *AgentStats) BackupFinished(operation string, d time.Duration, err error) {
+	key := backupStatKey{Operation: operation, Result: "success"}
+	if err != nil {
+		key.Result = "failure"
+	}
+	recordBackupDuration(key.Operation, key.Result, d)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.backupDurations[key]
+	if !ok {
+		h = &durationHistogram{Buckets: backupDurationBuckets, Counts: make([]uint64, len(backupDurationBuckets))}
+		s.backupDurations[key] = h
+	}
+	h.observe(d.Seconds())
+}
+
+func (h *durationHistogram) observe(seconds float64) {
+	for i, bound := range h.Buckets {
+		if seconds <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += seconds
+}
+
+// AgentStatsSnapshot is a consistent copy of the stats
+type AgentStatsSnapshot struct {
+	StartTime        time.Time
+	Reconnects       uint64
+	MessagesReceived map[string]uint64
+	MessagesSent     map[string]uint64
+	SendErrors       map[string]uint64
+	BackupDurations  map[backupStatKey]durationHistogram
+}
+
+// Snapshot copies the current stats
+func (s *AgentStats) Snapshot() AgentStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := AgentStatsSnapshot{
+		StartTime:        s.startTime,
+		Reconnects:       s.reconnects,
+		MessagesReceived: copyCounts(s.messagesReceived),
+		MessagesSent:     copyCounts(s.messagesSent),
+		SendErrors:       copyCounts(s.sendErrors),
+		BackupDurations:  make(map[backupStatKey]durationHistogram, len(s.backupDurations)),
+	}
+	for k, h := range s.backupDurations {
+		c := *h
+		c.Counts = append([]uint64{}, h.Counts...)
+		snap.BackupDurations[k] = c
+	}
+	return snap
+}
+
+func copyCounts(m map[string]uint64) map[string]uint64 {
+	c := make(map[string]uint64, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// sortedKeys returns the keys of a counter map in order
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,13 +1,11 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -27,8 +25,10 @@ type EmailColors struct {
 
 // AlertRecipient holds a recipient email and their personalized theme colors.
 type AlertRecipient struct {
-	Email  string      `json:"email"`
-	Colors EmailColors `json:"colors"`
+	Email       string      `json:"email"`
+	Colors      EmailColors `json:"colors"`
+	MinSeverity string      `json:"minSeverity,omitempty"` // Skip less severe alerts (default: all)
+	Locale      string      `json:"locale,omitempty"`      // Template locale, e.g. "fr" (default: en)
 }
 
 // DefaultEmailColors returns the Solar Flare theme colors (used as fallback).
@@ -90,11 +90,13 @@ func LoadAlertConfig() (*AlertConfig, error) {
 		}
 	}
 
+	RegisterSecret(config.ResendApiKey)
 	return &config, nil
 }
 
 // SaveAlertConfig saves the alert config to disk.
 func SaveAlertConfig(config *AlertConfig) error {
+	RegisterSecret(config.ResendApiKey)
 	if err := ensureStateDir(); err != nil {
 		return err
 	}
@@ -112,168 +114,117 @@ func SaveAlertConfig(config *AlertConfig) error {
 	return nil
 }
 
-// SendAlertEmail sends an offline alert email to all configured recipients via Resend API.
-func (a *Agent) SendAlertEmail(reason string, failingSince time.Time) {
-	if a.alertConfig == nil || a.alertConfig.ResendApiKey == "" || len(a.alertConfig.AlertRecipients) == 0 {
-		return
-	}
-
-	elapsed := time.Since(failingSince).Round(time.Minute)
-	subject := fmt.Sprintf(`[ZedOps] Agent "%s" cannot reach manager`, a.agentName)
-
-	log.Printf("Sending offline alert email to %d recipient(s)...", len(a.alertConfig.AlertRecipients))
-
-	for _, r := range a.alertConfig.AlertRecipients {
-		c := r.Colors
-		body := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head><meta charset="utf-8"><meta name="color-scheme" content="dark"></head>
-<body style="margin:0;padding:0;background-color:%s;font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;color:%s;">
-  <table width="100%%%%" cellpadding="0" cellspacing="0" bgcolor="%s" style="padding:40px 20px;">
-    <tr><td align="center">
-      <table width="100%%%%" cellpadding="0" cellspacing="0" bgcolor="%s" style="max-width:520px;border-radius:12px;border:1px solid %s;">
-        <tr><td bgcolor="%s" style="padding:32px 32px 16px;text-align:center;border-bottom:1px solid %s;">
-          <h1 style="margin:0;font-size:26px;font-weight:700;color:%s;">ZedOps</h1>
-          <p style="margin:6px 0 0;font-size:12px;color:%s;letter-spacing:1px;text-transform:uppercase;">Agent Alert</p>
-        </td></tr>
-        <tr><td bgcolor="%s" style="padding:24px 32px;">
-          <p style="margin:0 0 16px;font-size:16px;color:%s;">
-            Agent <strong style="color:%s;">%s</strong> has been unable to reach the ZedOps manager for <strong style="color:%s;">%v</strong>.
-          </p>
-          <p style="margin:0 0 8px;font-size:14px;color:%s;">Reason: <strong style="color:%s;">%s</strong></p>
-          <p style="margin:0 0 8px;font-size:14px;color:%s;">Failing since: <strong style="color:%s;">%s</strong></p>
-          <p style="margin:16px 0 0;font-size:14px;color:%s;">
-            The agent will keep retrying automatically. This alert is sent once per outage.
-          </p>
-        </td></tr>
-        <tr><td bgcolor="%s" style="padding:16px 32px;border-top:1px solid %s;">
-          <p style="margin:0;font-size:12px;color:%s;text-align:center;">Sent directly by the agent because the manager is unreachable.</p>
-        </td></tr>
-      </table>
-    </td></tr>
-  </table>
-</body>
-</html>`,
-			c.Bg, c.Text, // body
-			c.Bg,                  // outer table
-			c.Card, c.Border,      // inner table
-			c.Card, c.Border,      // header td
-			c.Accent,              // h1
-			c.Muted,               // subtitle
-			c.Card,                // body td
-			c.Text,                // body p
-			c.Accent, a.agentName, // agent name
-			c.Error, elapsed, // duration
-			c.Muted, c.Text, reason, // reason
-			c.Muted, c.Text, failingSince.UTC().Format("2006-01-02 15:04:05 UTC"), // failing since
-			c.Muted,          // footer p
-			c.Card, c.Border, // footer td
-			c.Muted,          // footer text
-		)
-
-		if err := sendResendEmail(a.alertConfig.ResendApiKey, a.alertConfig.ResendFromEmail, r.Email, subject, body); err != nil {
-			log.Printf("Failed to send alert to %s: %v", r.Email, err)
-		}
-	}
+// agentOfflineFingerprint identifies the "cannot reach manager" alert
+var agentOfflineFingerprint = alertFingerprint(NotifyAgentOffline, "", "", "")
+
+// NotifyAgentOffline reports that the agent cannot reach the manager. Called on every failed
+// attempt; the alert manager notifies once per outage, after the offline escalation delay.
+func (a *Agent) NotifyAgentOffline(reason string) {
+	failingSince := a.alerts.StartedAt(agentOfflineFingerprint)
+	if failingSince.IsZero() {
+		failingSince = time.Now()
+	}
+	since := failingSince.UTC().Format("2006-01-02 15:04:05 UTC")
+	a.alerts.Fire(Notification{
+		Kind:         NotifyAgentOffline,
+		Status:       MetricAlertFiring,
+		Severity:     "critical",
+		Title:        fmt.Sprintf(`Agent "%s" cannot reach manager`, a.agentName),
+		Text:         fmt.Sprintf("Agent %s has been unable to reach the ZedOps manager since %s. It will keep retrying automatically.", a.agentName, since),
+		Fields:       []NotificationField{{Name: "Reason", Value: reason}, {Name: "Failing since", Value: since}},
+		AgentName:    a.agentName,
+		Timestamp:    failingSince,
+		Reason:       reason,
+		FailingSince: failingSince,
+	})
 }
 
-// SendRecoveryEmail sends a recovery email to all configured recipients via Resend API.
-func (a *Agent) SendRecoveryEmail(failingSince time.Time) {
-	if a.alertConfig == nil || a.alertConfig.ResendApiKey == "" || len(a.alertConfig.AlertRecipients) == 0 {
+// NotifyAgentRecovered reports that the agent reached the manager. Only outages that were
+// notified produce a recovery notification.
+func (a *Agent) NotifyAgentRecovered() {
+	failingSince := a.alerts.StartedAt(agentOfflineFingerprint)
+	if failingSince.IsZero() {
 		return
 	}
-
 	downtime := time.Since(failingSince).Round(time.Minute)
-	subject := fmt.Sprintf(`[ZedOps] Agent "%s" is back online`, a.agentName)
-
-	log.Printf("Sending recovery email to %d recipient(s)...", len(a.alertConfig.AlertRecipients))
-
-	for _, r := range a.alertConfig.AlertRecipients {
-		c := r.Colors
-		body := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head><meta charset="utf-8"><meta name="color-scheme" content="dark"></head>
-<body style="margin:0;padding:0;background-color:%s;font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;color:%s;">
-  <table width="100%%%%" cellpadding="0" cellspacing="0" bgcolor="%s" style="padding:40px 20px;">
-    <tr><td align="center">
-      <table width="100%%%%" cellpadding="0" cellspacing="0" bgcolor="%s" style="max-width:520px;border-radius:12px;border:1px solid %s;">
-        <tr><td bgcolor="%s" style="padding:32px 32px 16px;text-align:center;border-bottom:1px solid %s;">
-          <h1 style="margin:0;font-size:26px;font-weight:700;color:%s;">ZedOps</h1>
-          <p style="margin:6px 0 0;font-size:12px;color:%s;letter-spacing:1px;text-transform:uppercase;">Agent Recovery</p>
-        </td></tr>
-        <tr><td bgcolor="%s" style="padding:24px 32px;">
-          <p style="margin:0 0 16px;font-size:16px;color:%s;">
-            Agent <strong style="color:%s;">%s</strong> is back <strong style="color:%s;">online</strong>.
-          </p>
-          <p style="margin:0;font-size:14px;color:%s;">
-            Total downtime: <strong style="color:%s;">%v</strong>
-          </p>
-        </td></tr>
-        <tr><td bgcolor="%s" style="padding:16px 32px;border-top:1px solid %s;">
-          <p style="margin:0;font-size:12px;color:%s;text-align:center;">Connection to ZedOps manager has been restored.</p>
-        </td></tr>
-      </table>
-    </td></tr>
-  </table>
-</body>
-</html>`,
-			c.Bg, c.Text, // body
-			c.Bg,                  // outer table
-			c.Card, c.Border,      // inner table
-			c.Card, c.Border,      // header td
-			c.Accent,              // h1
-			c.Muted,               // subtitle
-			c.Card,                // body td
-			c.Text,                // body p
-			c.Accent, a.agentName, // agent name
-			c.Success,        // "online"
-			c.Muted,          // downtime label
-			c.Text, downtime, // downtime value
-			c.Card, c.Border, // footer td
-			c.Muted,          // footer text
-		)
-
-		if err := sendResendEmail(a.alertConfig.ResendApiKey, a.alertConfig.ResendFromEmail, r.Email, subject, body); err != nil {
-			log.Printf("Failed to send recovery email to %s: %v", r.Email, err)
-		}
-	}
+	a.alerts.Resolve(agentOfflineFingerprint, Notification{
+		Kind:         NotifyAgentRecovered,
+		Status:       MetricAlertResolved,
+		Severity:     "critical",
+		Title:        fmt.Sprintf(`Agent "%s" is back online`, a.agentName),
+		Text:         fmt.Sprintf("Agent %s is back online. Connection to the ZedOps manager has been restored.", a.agentName),
+		Fields:       []NotificationField{{Name: "Downtime", Value: downtime.String()}},
+		AgentName:    a.agentName,
+		Timestamp:    time.Now(),
+		FailingSince: failingSince,
+	})
 }
 
-// sendResendEmail sends a single email via Resend API.
-func sendResendEmail(apiKey, fromEmail, to, subject, html string) error {
-	from := fromEmail
-	if from == "" {
-		from = "ZedOps Alerts <noreply@example.com>"
-	}
-	payload := map[string]interface{}{
-		"from":    from,
-		"to":      []string{to},
-		"subject": subject,
-		"html":    html,
-	}
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal email payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://api.resend.com/emails", bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// NotifyMetricAlert reports a firing or resolved metric alert. managerDelivered means the
+// manager received it and emails its recipients itself.
+func (a *Agent) NotifyMetricAlert(event MetricAlertEvent, managerDelivered bool) {
+	title := fmt.Sprintf(`FIRING: %s on agent "%s"`, event.RuleName, a.agentName)
+	if event.Status == MetricAlertResolved {
+		title = fmt.Sprintf(`RESOLVED: %s on agent "%s"`, event.RuleName, a.agentName)
+	}
+	fields := []NotificationField{{Name: "Severity", Value: event.Severity}}
+	if event.ServerName != "" {
+		fields = append(fields, NotificationField{Name: "Server", Value: event.ServerName})
+	}
+	n := Notification{
+		Kind:             NotifyMetricAlert,
+		Status:           event.Status,
+		Severity:         event.Severity,
+		Title:            title,
+		Text:             event.Summary,
+		Fields:           fields,
+		RuleID:           event.RuleID,
+		Instance:         event.Instance,
+		AgentName:        a.agentName,
+		ServerID:         event.ServerID,
+		ServerName:       event.ServerName,
+		Timestamp:        time.UnixMilli(event.Timestamp),
+		Event:            event,
+		ManagerDelivered: managerDelivered,
+		FailingSince:     time.UnixMilli(event.StartedAt),
+	}
+	if event.Status == MetricAlertResolved {
+		a.alerts.Resolve(n.Fingerprint(), n)
+		return
 	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	a.alerts.Fire(n)
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-	defer resp.Body.Close()
+// NotifyLogAlert reports a log alert rule that fired
+func (a *Agent) NotifyLogAlert(event LogAlertEvent, managerDelivered bool) {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d line(s) matching %q within %ds.", event.MatchCount, event.Pattern, event.WindowSeconds)
+	for _, line := range event.Lines {
+		text.WriteString("\n")
+		text.WriteString(truncateRunes(line.Line, 300))
+	}
+	a.alerts.Event(Notification{
+		Kind:             NotifyLogAlert,
+		Status:           MetricAlertFiring,
+		Severity:         event.Severity,
+		Title:            fmt.Sprintf(`%s on server "%s"`, event.RuleName, event.ServerName),
+		Text:             text.String(),
+		Fields:           []NotificationField{{Name: "Severity", Value: event.Severity}, {Name: "Server", Value: event.ServerName}},
+		RuleID:           event.RuleID,
+		AgentName:        a.agentName,
+		ServerID:         event.ServerID,
+		ServerName:       event.ServerName,
+		Timestamp:        time.UnixMilli(event.Timestamp),
+		Event:            event,
+		ManagerDelivered: managerDelivered,
+	})
+}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("resend API returned status %d", resp.StatusCode)
+// alertEmailNotifier is the implicit email channel: Resend with the manager-provided config
+func (a *Agent) alertEmailNotifier() Notifier {
+	config := a.alertConfig
+	if config == nil || config.ResendApiKey == "" || len(config.AlertRecipients) == 0 {
+		return nil
 	}
-
-	return nil
+	return &ResendNotifier{APIKey: config.ResendApiKey, From: config.ResendFromEmail, Recipients: config.AlertRecipients}
 }
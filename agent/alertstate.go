@@ -0,0 +1,747 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	alertStateFile    = "alert-state.json"
+	alertSilencesFile = "alert-silences.json"
+	alertTickInterval = 10 * time.Second
+	// Restored alerts that their source doesn't fire again within this window are resolved
+	// (metric rules re-fire after their for-duration if the condition still holds)
+	alertRestoreGrace = time.Hour
+)
+
+// AlertPolicy controls how notifications are batched and repeated
+type AlertPolicy struct {
+	GroupBy               []string `json:"groupBy,omitempty"`               // kind, serverId, ruleId, severity, status (default: kind, serverId)
+	GroupWaitSeconds      int      `json:"groupWaitSeconds,omitempty"`      // Wait to batch the first notifications of a group
+	GroupIntervalSeconds  int      `json:"groupIntervalSeconds,omitempty"`  // Minimum gap between sends for the same group
+	RepeatIntervalSeconds int      `json:"repeatIntervalSeconds,omitempty"` // Remind about still-firing alerts (0 = never)
+}
+
+// AlertEscalation notifies more channels the longer an alert keeps firing.
+// The first matching escalation applies; alerts without one notify their routes immediately.
+type AlertEscalation struct {
+	Name        string                `json:"name"`
+	Kinds       []string              `json:"kinds,omitempty"` // Notification kinds, "alert.*" matches a prefix
+	MinSeverity string                `json:"minSeverity,omitempty"`
+	ServerIDs   []string              `json:"serverIds,omitempty"`
+	Tiers       []AlertEscalationTier `json:"tiers"`
+}
+
+// AlertEscalationTier is one step of an escalation
+type AlertEscalationTier struct {
+	AfterSeconds int      `json:"afterSeconds"`       // Time since the alert started firing
+	Channels     []string `json:"channels,omitempty"` // Channels to notify (empty = routes decide)
+}
+
+// defaultAlertEscalations holds back offline alerts for 10 minutes so DO cold starts and
+// Worker redeploys don't page anyone
+var defaultAlertEscalations = []AlertEscalation{
+	{Name: "agent-offline", Kinds: []string{NotifyAgentOffline}, Tiers: []AlertEscalationTier{{AfterSeconds: 600}}},
+}
+
+// AlertSilence mutes matching alerts until it expires. Empty matchers match everything.
+type AlertSilence struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind,omitempty"` // "alert.*" matches a prefix
+	ServerID    string `json:"serverId,omitempty"`
+	RuleID      string `json:"ruleId,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	StartsAt    int64  `json:"startsAt,omitempty"` // Unix ms (0 = immediately)
+	EndsAt      int64  `json:"endsAt"`             // Unix ms
+	Comment     string `json:"comment,omitempty"`
+	CreatedBy   string `json:"createdBy,omitempty"`
+}
+
+// matches reports whether a silence mutes a notification at the given time
+func (s AlertSilence) matches(n Notification, fingerprint string, now time.Time) bool {
+	ms := now.UnixMilli()
+	if ms < s.StartsAt || ms >= s.EndsAt {
+		return false
+	}
+	if s.Kind != "" && s.Kind != n.Kind && !(strings.HasSuffix(s.Kind, ".*") && strings.HasPrefix(n.Kind, strings.TrimSuffix(s.Kind, "*"))) {
+		return false
+	}
+	return (s.ServerID == "" || s.ServerID == n.ServerID) &&
+		(s.RuleID == "" || s.RuleID == n.RuleID) &&
+		(s.Fingerprint == "" || s.Fingerprint == fingerprint)
+}
+
+// AlertStatus describes an active alert for alert.silences.get
+type AlertStatus struct {
+	Fingerprint    string `json:"fingerprint"`
+	Kind           string `json:"kind"`
+	Severity       string `json:"severity"`
+	Title          string `json:"title"`
+	ServerID       string `json:"serverId,omitempty"`
+	RuleID         string `json:"ruleId,omitempty"`
+	StartsAt       int64  `json:"startsAt"`
+	LastNotifiedAt int64  `json:"lastNotifiedAt,omitempty"`
+	TiersNotified  int    `json:"tiersNotified"`
+	Silenced       bool   `json:"silenced"`
+}
+
+// alertRecord tracks one firing alert
+type alertRecord struct {
+	Fingerprint    string       `json:"fingerprint"`
+	Notification   Notification `json:"notification"`
+	StartsAt       time.Time    `json:"startsAt"`
+	LastNotifiedAt time.Time    `json:"lastNotifiedAt,omitempty"`
+	Tier           int          `json:"tier"` // Escalation tiers notified so far
+	Silenced       bool         `json:"silenced,omitempty"`
+
+	restoredAt time.Time // Loaded from disk and not fired again since
+}
+
+// alertGroup batches notifications waiting to be sent together
+type alertGroup struct {
+	Key           string         `json:"key"`
+	FirstAt       time.Time      `json:"firstAt"`
+	Notifications []Notification `json:"notifications"`
+}
+
+// alertStateFileData is the alert-state.json file
+type alertStateFileData struct {
+	Alerts   []*alertRecord       `json:"alerts"`
+	Groups   []*alertGroup        `json:"groups,omitempty"`
+	LastSent map[string]time.Time `json:"lastSent,omitempty"`
+}
+
+// AlertManager deduplicates alerts by fingerprint, escalates long-running ones, applies
+// silences and batches notifications before handing them to the router
+type AlertManager struct {
+	mu          sync.Mutex
+	policy      AlertPolicy
+	escalations []AlertEscalation
+	alerts      map[string]*alertRecord
+	silences    []AlertSilence
+	groups      map[string]*alertGroup
+	lastSent    map[string]time.Time
+
+	statePath    string
+	silencesPath string
+	now          func() time.Time
+	send         func(n Notification)
+	stopCh       chan struct{}
+}
+
+// alertFingerprint identifies an alert by its source
+func alertFingerprint(kind, ruleID, serverID, instance string) string {
+	sum := sha256.Sum256([]byte(kind + "|" + ruleID + "|" + serverID + "|" + instance))
+	return hex.EncodeToString(sum[:8])
+}
+
+// NewAlertManager creates an alert manager that delivers through the router.
+// Policy and escalations come from the notifiers config (nil = defaults).
+func NewAlertManager(config *NotifyConfig, router *NotifyRouter) *AlertManager {
+	m := &AlertManager{
+		escalations:  defaultAlertEscalations,
+		alerts:       make(map[string]*alertRecord),
+		groups:       make(map[string]*alertGroup),
+		lastSent:     make(map[string]time.Time),
+		statePath:    filepath.Join(StateDir(), alertStateFile),
+		silencesPath: filepath.Join(StateDir(), alertSilencesFile),
+		now:          time.Now,
+		stopCh:       make(chan struct{}),
+	}
+	if config != nil {
+		m.policy = config.Policy
+		if len(config.Escalations) > 0 {
+			m.escalations = append(append([]AlertEscalation{}, config.Escalations...), defaultAlertEscalations...)
+		}
+	}
+	m.send = func(n Notification) {
+		if router != nil {
+			go router.Send(context.Background(), n) // Logs failures per channel
+		}
+	}
+	return m
+}
+
+// Start restores persisted state and begins the escalation/repeat loop
+func (m *AlertManager) Start() {
+	m.load()
+	go m.loop()
+}
+
+// Stop stops the loop; state is already on disk
+func (m *AlertManager) Stop() {
+	close(m.stopCh)
+}
+
+func (m *AlertManager) loop() {
+	ticker := time.NewTicker(alertTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.tick()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// load restores alerts, pending groups and silences from disk
+func (m *AlertManager) load() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+
+	if data, err := os.ReadFile(m.statePath); err == nil {
+		var state alertStateFileData
+		if err := json.Unmarshal(data, &state); err != nil {
+			alertLogger.Warnf("Ignoring alert state: %v", err)
+		} else {
+			for _, rec := range state.Alerts {
+				restoreNotificationEvent(&rec.Notification)
+				rec.restoredAt = now
+				m.alerts[rec.Fingerprint] = rec
+			}
+			for _, g := range state.Groups {
+				for i := range g.Notifications {
+					restoreNotificationEvent(&g.Notifications[i])
+				}
+				m.groups[g.Key] = g
+			}
+			for key, t := range state.LastSent {
+				m.lastSent[key] = t
+			}
+			if len(state.Alerts) > 0 {
+				alertLogger.Infof("Restored %d active alert(s)", len(state.Alerts))
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		alertLogger.Warnf("Failed to read alert state: %v", err)
+	}
+
+	if data, err := os.ReadFile(m.silencesPath); err == nil {
+		if err := json.Unmarshal(data, &m.silences); err != nil {
+			alertLogger.Warnf("Ignoring cached silences: %v", err)
+			m.silences = nil
+		}
+	} else if !os.IsNotExist(err) {
+		alertLogger.Warnf("Failed to read silences: %v", err)
+	}
+}
+
+// restoreNotificationEvent decodes a persisted event back into its type, so templates can
+// use its fields
+func restoreNotificationEvent(n *Notification) {
+	data, err := json.Marshal(n.Event)
+	if err != nil || n.Event == nil {
+		return
+	}
+	switch n.Kind {
+	case NotifyMetricAlert:
+		var event MetricAlertEvent
+		if json.Unmarshal(data, &event) == nil {
+			n.Event = event
+		}
+	case NotifyLogAlert:
+		var event LogAlertEvent
+		if json.Unmarshal(data, &event) == nil {
+			n.Event = event
+		}
+	}
+}
+
+// saveLocked writes the alert state to disk. Caller holds m.mu.
+func (m *AlertManager) saveLocked() {
+	state := alertStateFileData{LastSent: m.lastSent}
+	for _, rec := range m.alerts {
+		state.Alerts = append(state.Alerts, rec)
+	}
+	sort.Slice(state.Alerts, func(i, j int) bool { return state.Alerts[i].StartsAt.Before(state.Alerts[j].StartsAt) })
+	for _, g := range m.groups {
+		state.Groups = append(state.Groups, g)
+	}
+
+	if err := ensureStateDir(); err != nil {
+		alertLogger.Warnf("%v", err)
+		return
+	}
+	if err := writeFileAtomic(m.statePath, state); err != nil {
+		alertLogger.Warnf("Failed to save alert state: %v", err)
+	}
+}
+
+// Fire reports that an alert is firing. Repeated calls for the same fingerprint refresh the
+// alert without notifying again.
+func (m *AlertManager) Fire(n Notification) {
+	m.mu.Lock()
+	now := m.now()
+	if n.Timestamp.IsZero() {
+		n.Timestamp = now
+	}
+	fp := n.Fingerprint()
+	rec, ok := m.alerts[fp]
+	if !ok {
+		rec = &alertRecord{Fingerprint: fp, StartsAt: n.Timestamp}
+		m.alerts[fp] = rec
+		alertLogger.Infof("Alert firing: %s", n.Title)
+	}
+	rec.Notification = n
+	rec.restoredAt = time.Time{}
+	m.evaluateLocked(rec, now)
+	m.saveLocked()
+	ready := m.flushLocked(now)
+	m.mu.Unlock()
+
+	m.deliver(ready)
+}
+
+// Resolve reports that the alert with the given fingerprint stopped firing. Channels that
+// were notified about it receive the resolved notification.
+func (m *AlertManager) Resolve(fingerprint string, n Notification) {
+	m.mu.Lock()
+	now := m.now()
+	if m.resolveLocked(fingerprint, n, now) {
+		m.saveLocked()
+	}
+	ready := m.flushLocked(now)
+	m.mu.Unlock()
+
+	m.deliver(ready)
+}
+
+// Event sends a one-shot notification (e.g. a log alert) unless it is silenced
+func (m *AlertManager) Event(n Notification) {
+	m.mu.Lock()
+	now := m.now()
+	if n.Timestamp.IsZero() {
+		n.Timestamp = now
+	}
+	if m.silencedLocked(n, n.Fingerprint(), now) {
+		m.mu.Unlock()
+		alertLogger.Debugf("Silenced: %s", n.Title)
+		return
+	}
+	m.enqueueLocked(n, now)
+	m.saveLocked()
+	ready := m.flushLocked(now)
+	m.mu.Unlock()
+
+	m.deliver(ready)
+}
+
+// StartedAt returns when a firing alert started, or zero if it isn't firing
+func (m *AlertManager) StartedAt(fingerprint string) time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rec, ok := m.alerts[fingerprint]; ok {
+		return rec.StartsAt
+	}
+	return time.Time{}
+}
+
+// Active returns the firing alerts, oldest first
+func (m *AlertManager) Active() []AlertStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := make([]AlertStatus, 0, len(m.alerts))
+	for _, rec := range m.alerts {
+		status := AlertStatus{
+			Fingerprint:   rec.Fingerprint,
+			Kind:          rec.Notification.Kind,
+			Severity:      rec.Notification.Severity,
+			Title:         rec.Notification.Title,
+			ServerID:      rec.Notification.ServerID,
+			RuleID:        rec.Notification.RuleID,
+			StartsAt:      rec.StartsAt.UnixMilli(),
+			TiersNotified: rec.Tier,
+			Silenced:      rec.Silenced,
+		}
+		if !rec.LastNotifiedAt.IsZero() {
+			status.LastNotifiedAt = rec.LastNotifiedAt.UnixMilli()
+		}
+		active = append(active, status)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].StartsAt < active[j].StartsAt })
+	return active
+}
+
+// Silences returns the silences that haven't expired
+func (m *AlertManager) Silences() []AlertSilence {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now().UnixMilli()
+	active := []AlertSilence{}
+	for _, s := range m.silences {
+		// Expired silences stay until the next tick prunes them
+		if s.EndsAt > now {
+			active = append(active, s)
+		}
+	}
+	return active
+}
+
+// SetSilences replaces all silences and caches them to disk
+func (m *AlertManager) SetSilences(silences []AlertSilence) error {
+	seen := make(map[string]bool)
+	for _, s := range silences {
+		if s.ID == "" {
+			return fmt.Errorf("silence has no id")
+		}
+		if seen[s.ID] {
+			return fmt.Errorf("duplicate silence id %q", s.ID)
+		}
+		seen[s.ID] = true
+		if s.EndsAt <= s.StartsAt {
+			return fmt.Errorf("silence %q ends before it starts", s.ID)
+		}
+	}
+
+	m.mu.Lock()
+	now := m.now()
+	m.silences = nil
+	for _, s := range silences {
+		if s.EndsAt > now.UnixMilli() {
+			m.silences = append(m.silences, s)
+		}
+	}
+	m.saveSilencesLocked()
+	// Lifted silences notify immediately instead of on the next tick
+	for _, rec := range m.alerts {
+		m.evaluateLocked(rec, now)
+	}
+	m.saveLocked()
+	active := len(m.silences)
+	ready := m.flushLocked(now)
+	m.mu.Unlock()
+
+	alertLogger.Infof("Silences updated (%d active)", active)
+	m.deliver(ready)
+	return nil
+}
+
+// saveSilencesLocked caches the silences to disk. Caller holds m.mu.
+func (m *AlertManager) saveSilencesLocked() {
+	if err := ensureStateDir(); err != nil {
+		alertLogger.Warnf("%v", err)
+		return
+	}
+	silences := m.silences
+	if silences == nil {
+		silences = []AlertSilence{}
+	}
+	if err := writeFileAtomic(m.silencesPath, silences); err != nil {
+		alertLogger.Warnf("Failed to save silences: %v", err)
+	}
+}
+
+// tick expires silences, escalates and repeats firing alerts and flushes waiting groups
+func (m *AlertManager) tick() {
+	m.mu.Lock()
+	now := m.now()
+
+	silences := m.silences[:0]
+	for _, s := range m.silences {
+		if s.EndsAt > now.UnixMilli() {
+			silences = append(silences, s)
+		}
+	}
+	if len(silences) != len(m.silences) {
+		m.silences = silences
+		m.saveSilencesLocked()
+	}
+
+	changed := false
+	for fp, rec := range m.alerts {
+		if !rec.restoredAt.IsZero() && now.Sub(rec.restoredAt) >= alertRestoreGrace {
+			resolved := rec.Notification
+			resolved.Status = MetricAlertResolved
+			resolved.Title = "RESOLVED: " + strings.TrimPrefix(resolved.Title, "FIRING: ")
+			resolved.Text = "No longer reported since the agent restarted."
+			resolved.Timestamp = now
+			m.resolveLocked(fp, resolved, now)
+			changed = true
+			continue
+		}
+		tier, notified := rec.Tier, rec.LastNotifiedAt
+		m.evaluateLocked(rec, now)
+		if rec.Tier != tier || !rec.LastNotifiedAt.Equal(notified) {
+			changed = true
+		}
+	}
+
+	pending := len(m.groups)
+	ready := m.flushLocked(now)
+	if changed || len(m.groups) != pending {
+		m.saveLocked()
+	}
+	m.mu.Unlock()
+
+	m.deliver(ready)
+}
+
+// evaluateLocked notifies escalation tiers an alert has reached and repeats reminders.
+// Caller holds m.mu.
+func (m *AlertManager) evaluateLocked(rec *alertRecord, now time.Time) {
+	rec.Silenced = m.silencedLocked(rec.Notification, rec.Fingerprint, now)
+	if rec.Silenced {
+		return
+	}
+
+	tiers := m.tiersFor(rec.Notification)
+	notified := false
+	for rec.Tier < len(tiers) && !now.Before(rec.StartsAt.Add(time.Duration(tiers[rec.Tier].AfterSeconds)*time.Second)) {
+		n := rec.Notification
+		n.Channels = tiers[rec.Tier].Channels
+		m.enqueueLocked(n, now)
+		rec.Tier++
+		notified = true
+	}
+	if notified {
+		rec.LastNotifiedAt = now
+		return
+	}
+
+	repeat := time.Duration(m.policy.RepeatIntervalSeconds) * time.Second
+	if rec.Tier > 0 && repeat > 0 && now.Sub(rec.LastNotifiedAt) >= repeat {
+		n := rec.Notification
+		n.Title = "Reminder: " + n.Title
+		n.Reminder = true
+		n.Channels = tiers[rec.Tier-1].Channels
+		m.enqueueLocked(n, now)
+		rec.LastNotifiedAt = now
+	}
+}
+
+// resolveLocked removes an alert and queues its resolution to the channels that heard about
+// it. Returns false if the alert wasn't firing. Caller holds m.mu.
+func (m *AlertManager) resolveLocked(fingerprint string, n Notification, now time.Time) bool {
+	rec, ok := m.alerts[fingerprint]
+	if !ok {
+		return false
+	}
+	delete(m.alerts, fingerprint)
+	alertLogger.Infof("Alert resolved: %s", rec.Notification.Title)
+
+	if rec.Tier == 0 || m.silencedLocked(rec.Notification, fingerprint, now) {
+		return true // Nobody was told it fired
+	}
+	if n.Timestamp.IsZero() {
+		n.Timestamp = now
+	}
+
+	// Routed tiers resolve through the routes, explicit tiers through their channels
+	routed := false
+	var channels []string
+	seen := make(map[string]bool)
+	tiers := m.tiersFor(rec.Notification)
+	for i := 0; i < rec.Tier && i < len(tiers); i++ {
+		if len(tiers[i].Channels) == 0 {
+			routed = true
+		}
+		for _, name := range tiers[i].Channels {
+			if !seen[name] {
+				seen[name] = true
+				channels = append(channels, name)
+			}
+		}
+	}
+	if routed || len(channels) == 0 {
+		n.Channels = nil
+		m.enqueueLocked(n, now)
+	}
+	if len(channels) > 0 {
+		n.Channels = channels
+		m.enqueueLocked(n, now)
+	}
+	return true
+}
+
+// silencedLocked reports whether any silence mutes a notification. Caller holds m.mu.
+func (m *AlertManager) silencedLocked(n Notification, fingerprint string, now time.Time) bool {
+	for _, s := range m.silences {
+		if s.matches(n, fingerprint, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// tiersFor returns the escalation tiers of a notification (one immediate routed tier if no
+// escalation matches)
+func (m *AlertManager) tiersFor(n Notification) []AlertEscalationTier {
+	for _, e := range m.escalations {
+		route := NotifyRoute{Kinds: e.Kinds, MinSeverity: e.MinSeverity, ServerIDs: e.ServerIDs}
+		if len(e.Tiers) > 0 && route.matches(n) {
+			return e.Tiers
+		}
+	}
+	return []AlertEscalationTier{{}}
+}
+
+// groupKey returns the batch a notification belongs to
+func (m *AlertManager) groupKey(n Notification) string {
+	by := m.policy.GroupBy
+	if len(by) == 0 {
+		by = []string{"kind", "serverId"}
+	}
+	parts := make([]string, 0, len(by)+2)
+	for _, field := range by {
+		switch field {
+		case "kind":
+			parts = append(parts, n.Kind)
+		case "serverId":
+			parts = append(parts, n.ServerID)
+		case "ruleId":
+			parts = append(parts, n.RuleID)
+		case "severity":
+			parts = append(parts, n.Severity)
+		case "status":
+			parts = append(parts, n.Status)
+		}
+	}
+	// Batches must share their destination
+	parts = append(parts, strings.Join(n.Channels, ","), fmt.Sprint(n.ManagerDelivered))
+	return strings.Join(parts, "|")
+}
+
+// enqueueLocked adds a notification to its group. Caller holds m.mu.
+func (m *AlertManager) enqueueLocked(n Notification, now time.Time) {
+	key := m.groupKey(n)
+	g, ok := m.groups[key]
+	if !ok {
+		g = &alertGroup{Key: key, FirstAt: now}
+		m.groups[key] = g
+	}
+	g.Notifications = append(g.Notifications, n)
+}
+
+// flushLocked returns the groups whose wait and interval have passed, merged into one
+// notification each. Caller holds m.mu.
+func (m *AlertManager) flushLocked(now time.Time) []Notification {
+	wait := time.Duration(m.policy.GroupWaitSeconds) * time.Second
+	interval := time.Duration(m.policy.GroupIntervalSeconds) * time.Second
+
+	var ready []Notification
+	for key, g := range m.groups {
+		due := g.FirstAt.Add(wait)
+		if last, ok := m.lastSent[key]; ok && last.Add(interval).After(due) {
+			due = last.Add(interval)
+		}
+		if now.Before(due) {
+			continue
+		}
+		ready = append(ready, mergeNotifications(g.Notifications))
+		delete(m.groups, key)
+		if interval > 0 {
+			m.lastSent[key] = now
+		}
+	}
+	for key, last := range m.lastSent {
+		if now.Sub(last) >= interval {
+			delete(m.lastSent, key)
+		}
+	}
+	return ready
+}
+
+// deliver sends flushed notifications
+func (m *AlertManager) deliver(ready []Notification) {
+	for _, n := range ready {
+		m.send(n)
+	}
+}
+
+// mergeNotifications combines a batch into one notification
+func mergeNotifications(ns []Notification) Notification {
+	if len(ns) == 1 {
+		return ns[0]
+	}
+
+	merged := ns[0]
+	merged.Title = fmt.Sprintf("%s (+%d more)", ns[0].Title, len(ns)-1)
+	merged.Fields = nil
+	merged.Template = "default" // Kind templates describe a single alert
+	merged.RuleID = ""
+	merged.Instance = ""
+
+	var text strings.Builder
+	events := make([]interface{}, 0, len(ns))
+	for i, n := range ns {
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		fmt.Fprintf(&text, "- %s: %s", n.Title, truncateRunes(n.Text, 300))
+		if notifySeverityRank[n.Severity] > notifySeverityRank[merged.Severity] {
+			merged.Severity = n.Severity
+		}
+		if n.Status == MetricAlertFiring {
+			merged.Status = MetricAlertFiring
+		}
+		if n.ServerID != merged.ServerID {
+			merged.ServerID, merged.ServerName = "", ""
+		}
+		if n.Timestamp.After(merged.Timestamp) {
+			merged.Timestamp = n.Timestamp
+		}
+		events = append(events, n.Event)
+	}
+	merged.Text = text.String()
+	merged.Event = events
+	return merged
+}
+
+// AlertSilencesRequest is the alert.silences.set request
+type AlertSilencesRequest struct {
+	Silences []AlertSilence `json:"silences"`
+}
+
+// AlertSilencesResponse is the reply to alert.silences.set and alert.silences.get
+type AlertSilencesResponse struct {
+	Success  bool           `json:"success"`
+	Silences []AlertSilence `json:"silences,omitempty"`
+	Alerts   []AlertStatus  `json:"alerts,omitempty"` // Currently firing alerts
+	Error    string         `json:"error,omitempty"`
+}
+
+// handleAlertSilencesSet handles alert.silences.set messages
+func (a *Agent) handleAlertSilencesSet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req AlertSilencesRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendAlertSilencesResponse(msg.Reply, AlertSilencesResponse{Error: "Invalid request format"})
+		return
+	}
+
+	if err := a.alerts.SetSilences(req.Silences); err != nil {
+		a.sendAlertSilencesResponse(msg.Reply, AlertSilencesResponse{Error: err.Error()})
+		return
+	}
+	a.sendAlertSilencesResponse(msg.Reply, AlertSilencesResponse{Success: true, Silences: a.alerts.Silences(), Alerts: a.alerts.Active()})
+}
+
+// handleAlertSilencesGet handles alert.silences.get messages
+func (a *Agent) handleAlertSilencesGet(msg Message) {
+	a.sendAlertSilencesResponse(msg.Reply, AlertSilencesResponse{Success: true, Silences: a.alerts.Silences(), Alerts: a.alerts.Active()})
+}
+
+// sendAlertSilencesResponse replies to an alert.silences request
+func (a *Agent) sendAlertSilencesResponse(replyTo string, resp AlertSilencesResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
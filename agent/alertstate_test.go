@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertManagerSilencesHidesExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := NewAlertManager(nil, nil)
+	m.now = func() time.Time { return now }
+	m.silences = []AlertSilence{
+		{ID: "active", EndsAt: now.Add(time.Hour).UnixMilli()},
+		{ID: "scheduled", StartsAt: now.Add(time.Hour).UnixMilli(), EndsAt: now.Add(2 * time.Hour).UnixMilli()},
+		{ID: "expired", EndsAt: now.Add(-time.Minute).UnixMilli()},
+		{ID: "ending now", EndsAt: now.UnixMilli()},
+	}
+
+	got := m.Silences()
+	if len(got) != 2 || got[0].ID != "active" || got[1].ID != "scheduled" {
+		t.Errorf("Silences() = %+v, want active and scheduled", got)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -32,7 +31,7 @@ type AutoUpdater struct {
 func NewAutoUpdater(managerURL string) *AutoUpdater {
 	executable, err := os.Executable()
 	if err != nil {
-		log.Printf("Warning: Could not determine executable path: %v", err)
+		updateLogger.Warnf("Could not determine executable path: %v", err)
 		executable = os.Args[0]
 	}
 
@@ -55,36 +54,36 @@ func (u *AutoUpdater) CheckOnce() {
 
 // TriggerUpdate is called when manager pushes an update notification
 func (u *AutoUpdater) TriggerUpdate(version string) {
-	log.Printf("Received update notification from manager: version %s available", version)
+	updateLogger.Infof("Received update notification from manager: version %s available", version)
 	go u.checkAndUpdate(true) // Bust cache when triggered by push notification
 }
 
 // checkAndUpdate checks for updates and applies them if available
 // bustCache: if true, adds timestamp to bypass Cloudflare cache
 func (u *AutoUpdater) checkAndUpdate(bustCache bool) {
-	log.Println("Checking for updates...")
+	updateLogger.Infof("Checking for updates...")
 
 	latestVersion, downloadURL, err := u.getLatestVersion(bustCache)
 	if err != nil {
-		log.Printf("Failed to check for updates: %v", err)
+		updateLogger.Errorf("Failed to check for updates: %v", err)
 		return
 	}
 
 	if latestVersion == Version {
-		log.Printf("Agent is up to date (version %s)", Version)
+		updateLogger.Infof("Agent is up to date (version %s)", Version)
 		return
 	}
 
 	if !isNewerVersion(latestVersion, Version) {
-		log.Printf("Remote version %s is not newer than current %s, skipping", latestVersion, Version)
+		updateLogger.Infof("Remote version %s is not newer than current %s, skipping", latestVersion, Version)
 		return
 	}
 
-	log.Printf("New version available: %s (current: %s)", latestVersion, Version)
+	updateLogger.Infof("New version available: %s (current: %s)", latestVersion, Version)
 
 	// Download and apply update
 	if err := u.downloadAndApply(downloadURL, latestVersion); err != nil {
-		log.Printf("Failed to apply update: %v", err)
+		updateLogger.Errorf("Failed to apply update: %v", err)
 		return
 	}
 }
@@ -130,7 +129,7 @@ func (u *AutoUpdater) getLatestVersion(bustCache bool) (string, string, error) {
 
 // downloadAndApply downloads the new binary and restarts the agent
 func (u *AutoUpdater) downloadAndApply(downloadURL, newVersion string) error {
-	log.Printf("Downloading update from %s", downloadURL)
+	updateLogger.Infof("Downloading update from %s", downloadURL)
 
 	// Download to temp file
 	resp, err := http.Get(downloadURL)
@@ -168,16 +167,16 @@ func (u *AutoUpdater) downloadAndApply(downloadURL, newVersion string) error {
 
 	// Verify the new binary works
 	// We could run it with --version flag to verify
-	log.Println("Verifying new binary...")
+	updateLogger.Infof("Verifying new binary...")
 
 	// Replace current binary
-	log.Println("Applying update...")
+	updateLogger.Infof("Applying update...")
 	if err := os.Rename(tmpPath, u.currentBinary); err != nil {
 		os.Remove(tmpPath)
 		return fmt.Errorf("failed to replace binary: %w", err)
 	}
 
-	log.Printf("Update applied successfully! Restarting to version %s...", newVersion)
+	updateLogger.Infof("Update applied successfully! Restarting to version %s...", newVersion)
 
 	// Restart by exec'ing into the new binary
 	// This replaces the current process with the new one
@@ -200,7 +199,7 @@ func (u *AutoUpdater) restart() error {
 
 	// Exec into the new binary
 	// This replaces the current process - code after this won't execute
-	log.Println("Executing new binary...")
+	updateLogger.Infof("Executing new binary...")
 	return syscall.Exec(u.currentBinary, args, env)
 }
 
@@ -7,17 +7,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorcon/rcon"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// MaxBackupsPerServer is the retention limit
+// MaxBackupsPerServer is the retention limit for servers without a retention policy
 const MaxBackupsPerServer = 10
 
 // BackupProgress represents progress updates during backup/restore
@@ -34,10 +35,13 @@ type BackupMeta struct {
 	BackupID       string `json:"backupId"`
 	ServerName     string `json:"serverName"`
 	Filename       string `json:"filename"`
-	SizeBytes      int64  `json:"sizeBytes"`
+	Format         string `json:"format,omitempty"`       // chunked or tar.gz (empty: tar.gz, written before chunked existed)
+	SizeBytes      int64  `json:"sizeBytes"`              // On disk; for chunked, the bytes this backup added to the repository
+	LogicalBytes   int64  `json:"logicalBytes,omitempty"` // Size of the backed up data
 	Notes          string `json:"notes,omitempty"`
 	PreSaveSuccess bool   `json:"preSaveSuccess"`
-	CreatedAt      int64  `json:"createdAt"` // Unix seconds
+	Pinned         bool   `json:"pinned,omitempty"` // Exempt from retention pruning
+	CreatedAt      int64  `json:"createdAt"`        // Unix seconds
 }
 
 // BackupCreateRequest is the agent-side request payload
@@ -46,6 +50,8 @@ type BackupCreateRequest struct {
 	DataPath    string `json:"dataPath"`
 	BackupID    string `json:"backupId"`
 	Notes       string `json:"notes"`
+	Pinned      bool   `json:"pinned"`      // Exempt from retention pruning (e.g. before an update)
+	Format      string `json:"format"`      // chunked (default) or tar.gz
 	ContainerID string `json:"containerId"` // For RCON pre-save (empty if server stopped)
 	RCONPort    int    `json:"rconPort"`
 	RCONPassword string `json:"rconPassword"`
@@ -53,12 +59,14 @@ type BackupCreateRequest struct {
 
 // BackupCreateResponse is the agent-side response
 type BackupCreateResponse struct {
-	Success        bool   `json:"success"`
-	BackupID       string `json:"backupId"`
-	Filename       string `json:"filename"`
-	SizeBytes      int64  `json:"sizeBytes"`
-	PreSaveSuccess bool   `json:"preSaveSuccess"`
-	Error          string `json:"error,omitempty"`
+	Success        bool     `json:"success"`
+	BackupID       string   `json:"backupId"`
+	Filename       string   `json:"filename"`
+	Format         string   `json:"format"`
+	SizeBytes      int64    `json:"sizeBytes"`
+	PreSaveSuccess bool     `json:"preSaveSuccess"`
+	Pruned         []string `json:"pruned,omitempty"` // Older backups deleted by retention
+	Error          string   `json:"error,omitempty"`
 }
 
 // BackupListRequest is the agent-side list request
@@ -106,12 +114,33 @@ type BackupRestoreResponse struct {
 // ProgressCallback for backup operations
 type BackupProgressCallback func(progress BackupProgress)
 
-// CreateBackup creates a tar.gz backup of the server's data/ directory
-func CreateBackup(serverName, dataPath, backupID, notes string, containerID string, rconPort int, rconPassword string, rconManager *RCONManager, progressFn BackupProgressCallback) (*BackupCreateResponse, error) {
+// serverBackupLocks lets one backup or restore run per server: a restore must not replace the
+// data directory while a backup reads it, whether the backup is manual or scheduled
+var serverBackupLocks sync.Map // server dir -> *sync.Mutex
+
+// tryLockServerBackups claims a server for a backup or restore. It fails instead of waiting,
+// since the running operation can take minutes.
+func tryLockServerBackups(serverName, dataPath string) (func(), error) {
+	mu, _ := serverBackupLocks.LoadOrStore(filepath.Clean(filepath.Join(dataPath, serverName)), &sync.Mutex{})
+	if !mu.(*sync.Mutex).TryLock() {
+		return nil, fmt.Errorf("another backup or restore of %s is in progress", serverName)
+	}
+	return mu.(*sync.Mutex).Unlock, nil
+}
+
+// CreateBackup backs up the server's data/ directory as a chunked snapshot (default) or a tar.gz archive
+func CreateBackup(serverName, dataPath, backupID, notes string, pinned bool, format string, containerID string, rconPort int, rconPassword string, rconManager *RCONManager, progressFn BackupProgressCallback) (*BackupCreateResponse, error) {
 	serverDir := filepath.Join(dataPath, serverName)
 	dataDir := filepath.Join(serverDir, "data")
 	backupsDir := filepath.Join(serverDir, "backups")
 
+	if format == "" {
+		format = BackupFormatChunked
+	}
+	if format != BackupFormatChunked && format != BackupFormatTarGz {
+		return nil, fmt.Errorf("unknown backup format %q", format)
+	}
+
 	// Verify data directory exists
 	if !dirExists(dataDir) {
 		return nil, fmt.Errorf("data directory does not exist: %s", dataDir)
@@ -130,11 +159,11 @@ func CreateBackup(serverName, dataPath, backupID, notes string, containerID stri
 		}
 		preSaveOK = attemptRCONSave(containerID, rconPort, rconPassword, rconManager)
 		if preSaveOK {
-			log.Printf("[Backup] RCON pre-save succeeded for %s", serverName)
+			backupLogger.Infof("RCON pre-save succeeded for %s", serverName)
 			// Wait a bit for save to flush to disk
 			time.Sleep(3 * time.Second)
 		} else {
-			log.Printf("[Backup] RCON pre-save failed for %s (continuing anyway)", serverName)
+			backupLogger.Warnf("RCON pre-save failed for %s (continuing anyway)", serverName)
 		}
 	}
 
@@ -159,7 +188,7 @@ func CreateBackup(serverName, dataPath, backupID, notes string, containerID stri
 		return nil, fmt.Errorf("failed to calculate data size: %w", err)
 	}
 
-	log.Printf("[Backup] Data size: %d bytes, %d files", totalBytes, totalFiles)
+	backupLogger.Infof("Data size: %d bytes, %d files", totalBytes, totalFiles)
 
 	// Generate filename
 	timestamp := time.Now().UTC().Format("2006-01-02T15-04-05")
@@ -171,42 +200,54 @@ func CreateBackup(serverName, dataPath, backupID, notes string, containerID stri
 			label = sanitized
 		}
 	}
-	filename := fmt.Sprintf("%s_%s.tar.gz", timestamp, label)
-	tarPath := filepath.Join(backupsDir, filename)
+	ext := ".tar.gz"
+	if format == BackupFormatChunked {
+		ext = snapshotExt
+	}
+	filename := fmt.Sprintf("%s_%s%s", timestamp, label, ext)
+	backupPath := filepath.Join(backupsDir, filename)
 
-	// Create tar.gz
 	if progressFn != nil {
 		progressFn(BackupProgress{BackupID: backupID, ServerName: serverName, Phase: "compressing", Percent: 15})
 	}
 
-	sizeBytes, err := createTarGz(tarPath, dataDir, backupID, serverName, totalBytes, totalFiles, progressFn)
+	var sizeBytes int64
+	if format == BackupFormatChunked {
+		// Only new chunks are written; unchanged files reuse the previous snapshot's chunks
+		sizeBytes, err = createSnapshot(backupPath, filepath.Join(backupsDir, backupRepoDir), dataDir, backupID, serverName, totalBytes, progressFn)
+	} else {
+		sizeBytes, err = createTarGz(backupPath, dataDir, backupID, serverName, totalBytes, totalFiles, progressFn)
+	}
 	if err != nil {
-		// Clean up partial file
-		os.Remove(tarPath)
+		// Clean up partial file (orphaned chunks are collected by the next GC)
+		os.Remove(backupPath)
 		return nil, fmt.Errorf("failed to create backup archive: %w", err)
 	}
 
-	log.Printf("[Backup] Archive created: %s (%d bytes)", filename, sizeBytes)
+	backupLogger.Infof("Backup created: %s (%d bytes)", filename, sizeBytes)
 
 	// Write .meta.json sidecar
 	meta := BackupMeta{
 		BackupID:       backupID,
 		ServerName:     serverName,
 		Filename:       filename,
+		Format:         format,
 		SizeBytes:      sizeBytes,
+		LogicalBytes:   totalBytes,
 		Notes:          notes,
 		PreSaveSuccess: preSaveOK,
+		Pinned:         pinned,
 		CreatedAt:      time.Now().Unix(),
 	}
 
-	metaPath := filepath.Join(backupsDir, strings.TrimSuffix(filename, ".tar.gz")+".meta.json")
+	metaPath := backupMetaPath(backupsDir, filename)
 	metaData, _ := json.MarshalIndent(meta, "", "  ")
 	if err := os.WriteFile(metaPath, metaData, 0644); err != nil {
-		log.Printf("[Backup] Warning: failed to write meta file: %v", err)
+		backupLogger.Warnf("Failed to write meta file: %v", err)
 	}
 
-	// Enforce retention (delete oldest if >MaxBackupsPerServer)
-	enforceRetention(backupsDir, serverName)
+	// Enforce the server's retention policy (default: keep the newest MaxBackupsPerServer)
+	pruned := enforceRetention(backupsDir, serverName)
 
 	// Send complete
 	if progressFn != nil {
@@ -217,8 +258,10 @@ func CreateBackup(serverName, dataPath, backupID, notes string, containerID stri
 		Success:        true,
 		BackupID:       backupID,
 		Filename:       filename,
+		Format:         format,
 		SizeBytes:      sizeBytes,
 		PreSaveSuccess: preSaveOK,
+		Pruned:         pruned,
 	}, nil
 }
 
@@ -244,20 +287,20 @@ func ListBackups(serverName, dataPath string) ([]BackupMeta, error) {
 		metaPath := filepath.Join(backupsDir, entry.Name())
 		data, err := os.ReadFile(metaPath)
 		if err != nil {
-			log.Printf("[Backup] Warning: failed to read meta file %s: %v", entry.Name(), err)
+			backupLogger.Warnf("Failed to read meta file %s: %v", entry.Name(), err)
 			continue
 		}
 
 		var meta BackupMeta
 		if err := json.Unmarshal(data, &meta); err != nil {
-			log.Printf("[Backup] Warning: failed to parse meta file %s: %v", entry.Name(), err)
+			backupLogger.Warnf("Failed to parse meta file %s: %v", entry.Name(), err)
 			continue
 		}
 
-		// Verify the tar.gz still exists
+		// Verify the tar.gz or snapshot still exists
 		tarPath := filepath.Join(backupsDir, meta.Filename)
 		if _, err := os.Stat(tarPath); os.IsNotExist(err) {
-			log.Printf("[Backup] Warning: orphaned meta file %s (tar.gz missing)", entry.Name())
+			backupLogger.Warnf("Orphaned meta file %s (backup file missing)", entry.Name())
 			continue
 		}
 
@@ -272,7 +315,8 @@ func ListBackups(serverName, dataPath string) ([]BackupMeta, error) {
 	return backups, nil
 }
 
-// DeleteBackup removes a backup's .tar.gz and .meta.json files
+// DeleteBackup removes a backup's file and .meta.json sidecar. Deleting a snapshot also
+// garbage-collects repository chunks no other snapshot uses.
 func DeleteBackup(serverName, dataPath, filename string) error {
 	backupsDir := filepath.Join(dataPath, serverName, "backups")
 	tarPath := filepath.Join(backupsDir, filename)
@@ -282,18 +326,24 @@ func DeleteBackup(serverName, dataPath, filename string) error {
 		return fmt.Errorf("invalid filename")
 	}
 
-	// Remove tar.gz
+	// Remove tar.gz or snapshot manifest
 	if err := os.Remove(tarPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove backup file: %w", err)
 	}
 
 	// Remove .meta.json
-	metaPath := filepath.Join(backupsDir, strings.TrimSuffix(filename, ".tar.gz")+".meta.json")
+	metaPath := backupMetaPath(backupsDir, filename)
 	if err := os.Remove(metaPath); err != nil && !os.IsNotExist(err) {
-		log.Printf("[Backup] Warning: failed to remove meta file: %v", err)
+		backupLogger.Warnf("Failed to remove meta file: %v", err)
 	}
 
-	log.Printf("[Backup] Deleted backup: %s/%s", serverName, filename)
+	if isSnapshot(filename) {
+		if _, _, err := gcBackupRepo(backupsDir); err != nil {
+			backupLogger.Warnf("Repository GC failed: %v", err)
+		}
+	}
+
+	backupLogger.Infof("Deleted backup: %s/%s", serverName, filename)
 	return nil
 }
 
@@ -321,9 +371,9 @@ func RestoreFromBackup(serverName, dataPath, filename, backupID, containerID str
 		if progressFn != nil {
 			progressFn(BackupProgress{BackupID: backupID, ServerName: serverName, Phase: "stopping", Percent: 10})
 		}
-		log.Printf("[Restore] Stopping container %s", containerID)
+		restoreLogger.Infof("Stopping container %s", containerID)
 		if err := docker.StopContainer(ctx, containerID); err != nil {
-			log.Printf("[Restore] Warning: failed to stop container (may already be stopped): %v", err)
+			restoreLogger.Warnf("Failed to stop container (may already be stopped): %v", err)
 		}
 		// Give it a moment to fully stop
 		time.Sleep(2 * time.Second)
@@ -336,7 +386,7 @@ func RestoreFromBackup(serverName, dataPath, filename, backupID, containerID str
 		if progressFn != nil {
 			progressFn(BackupProgress{BackupID: backupID, ServerName: serverName, Phase: "extracting", Percent: 20})
 		}
-		log.Printf("[Restore] Moving current data to %s", preRestoreDir)
+		restoreLogger.Infof("Moving current data to %s", preRestoreDir)
 		if err := os.Rename(dataDir, preRestoreDir); err != nil {
 			// Try to restart container before returning error
 			if containerID != "" {
@@ -350,16 +400,25 @@ func RestoreFromBackup(serverName, dataPath, filename, backupID, containerID str
 	if progressFn != nil {
 		progressFn(BackupProgress{BackupID: backupID, ServerName: serverName, Phase: "extracting", Percent: 30})
 	}
-	log.Printf("[Restore] Extracting %s to %s", filename, dataDir)
+	restoreLogger.Infof("Extracting %s to %s", filename, dataDir)
 
-	if err := extractTarGz(tarPath, dataDir, backupID, serverName, progressFn); err != nil {
-		log.Printf("[Restore] Extraction failed: %v", err)
+	var err error
+	if isSnapshot(filename) {
+		repoDir := filepath.Join(backupsDir, backupRepoDir)
+		unlock := lockBackupRepo(repoDir)
+		err = restoreSnapshot(tarPath, repoDir, dataDir, backupID, serverName, progressFn)
+		unlock()
+	} else {
+		err = extractTarGz(tarPath, dataDir, backupID, serverName, progressFn)
+	}
+	if err != nil {
+		restoreLogger.Errorf("Extraction failed: %v", err)
 		// Rollback: restore pre-restore data
 		if preRestoreDir != "" {
-			log.Printf("[Restore] Rolling back: restoring pre-restore data")
+			restoreLogger.Infof("Rolling back: restoring pre-restore data")
 			os.RemoveAll(dataDir) // Remove partial extraction
 			if renameErr := os.Rename(preRestoreDir, dataDir); renameErr != nil {
-				log.Printf("[Restore] CRITICAL: Failed to rollback! Pre-restore at: %s, error: %v", preRestoreDir, renameErr)
+				restoreLogger.Errorf("CRITICAL: Failed to rollback! Pre-restore at: %s, error: %v", preRestoreDir, renameErr)
 			}
 		}
 		// Try to restart container
@@ -374,9 +433,9 @@ func RestoreFromBackup(serverName, dataPath, filename, backupID, containerID str
 		if progressFn != nil {
 			progressFn(BackupProgress{BackupID: backupID, ServerName: serverName, Phase: "starting", Percent: 90})
 		}
-		log.Printf("[Restore] Starting container %s", containerID)
+		restoreLogger.Infof("Starting container %s", containerID)
 		if err := docker.StartContainer(ctx, containerID); err != nil {
-			log.Printf("[Restore] Warning: failed to start container: %v", err)
+			restoreLogger.Warnf("Failed to start container: %v", err)
 			// Don't fail the restore — data is restored, container can be started manually
 		}
 	}
@@ -384,7 +443,7 @@ func RestoreFromBackup(serverName, dataPath, filename, backupID, containerID str
 	// Clean up pre-restore directory (keep it for safety — user can delete manually)
 	// We don't auto-delete it because it's a safety net
 	if preRestoreDir != "" {
-		log.Printf("[Restore] Pre-restore data kept at: %s (can be deleted manually)", preRestoreDir)
+		restoreLogger.Infof("Pre-restore data kept at: %s (can be deleted manually)", preRestoreDir)
 	}
 
 	// Send complete
@@ -392,7 +451,7 @@ func RestoreFromBackup(serverName, dataPath, filename, backupID, containerID str
 		progressFn(BackupProgress{BackupID: backupID, ServerName: serverName, Phase: "complete", Percent: 100})
 	}
 
-	log.Printf("[Restore] Restore complete for %s from %s", serverName, filename)
+	restoreLogger.Infof("Restore complete for %s from %s", serverName, filename)
 	return nil
 }
 
@@ -402,35 +461,35 @@ func attemptRCONSave(containerID string, port int, password string, rconManager
 	ctx := context.Background()
 	inspect, err := rconManager.dockerClient.ContainerInspect(ctx, containerID)
 	if err != nil {
-		log.Printf("[Backup] RCON: failed to inspect container: %v", err)
+		backupLogger.Warnf("RCON: failed to inspect container: %v", err)
 		return false
 	}
 
 	network := inspect.NetworkSettings.Networks["zomboid-backend"]
 	if network == nil {
-		log.Printf("[Backup] RCON: container not on zomboid-backend network")
+		backupLogger.Infof("RCON: container not on zomboid-backend network")
 		return false
 	}
 
 	containerIP := network.IPAddress
 	if containerIP == "" {
-		log.Printf("[Backup] RCON: no IP address")
+		backupLogger.Infof("RCON: no IP address")
 		return false
 	}
 
 	addr := fmt.Sprintf("%s:%d", containerIP, port)
-	log.Printf("[Backup] RCON: connecting to %s for pre-save", addr)
+	backupLogger.Infof("RCON: connecting to %s for pre-save", addr)
 
 	conn, err := rcon.Dial(addr, password, rcon.SetDialTimeout(5*time.Second))
 	if err != nil {
-		log.Printf("[Backup] RCON: connection failed: %v", err)
+		backupLogger.Warnf("RCON: connection failed: %v", err)
 		return false
 	}
 	defer conn.Close()
 
 	_, err = conn.Execute("save")
 	if err != nil {
-		log.Printf("[Backup] RCON: save command failed: %v", err)
+		backupLogger.Warnf("RCON: save command failed: %v", err)
 		return false
 	}
 
@@ -566,7 +625,7 @@ func extractTarGz(tarPath, dstDir, backupID, serverName string, progressFn Backu
 
 		// Prevent path traversal
 		targetPath := filepath.Join(dstDir, header.Name)
-		if !strings.HasPrefix(filepath.Clean(targetPath), filepath.Clean(dstDir)) {
+		if targetPath != filepath.Clean(dstDir) && !strings.HasPrefix(targetPath, filepath.Clean(dstDir)+string(filepath.Separator)) {
 			return fmt.Errorf("tar entry attempts path traversal: %s", header.Name)
 		}
 
@@ -617,26 +676,39 @@ func extractTarGz(tarPath, dstDir, backupID, serverName string, progressFn Backu
 	return nil
 }
 
-// enforceRetention deletes the oldest backups if there are more than MaxBackupsPerServer
-func enforceRetention(backupsDir, serverName string) {
+// enforceRetention deletes the backups the server's retention policy doesn't keep
+// and returns their filenames
+func enforceRetention(backupsDir, serverName string) []string {
 	backups, err := ListBackups(serverName, filepath.Dir(filepath.Dir(backupsDir)))
 	if err != nil {
-		log.Printf("[Backup] Warning: failed to list backups for retention check: %v", err)
-		return
+		backupLogger.Warnf("Failed to list backups for retention check: %v", err)
+		return nil
 	}
 
-	if len(backups) <= MaxBackupsPerServer {
-		return
+	policy := backupRetention.Policy(serverName)
+	var usage *BackupUsage
+	if policy.MaxTotalBytes > 0 {
+		usage = loadBackupUsage(backupsDir, backups)
 	}
 
-	// backups are sorted newest-first; delete from the end
-	for i := MaxBackupsPerServer; i < len(backups); i++ {
-		log.Printf("[Backup] Retention: deleting old backup %s", backups[i].Filename)
-		tarPath := filepath.Join(backupsDir, backups[i].Filename)
-		metaPath := filepath.Join(backupsDir, strings.TrimSuffix(backups[i].Filename, ".tar.gz")+".meta.json")
-		os.Remove(tarPath)
-		os.Remove(metaPath)
+	var pruned []string
+	gc := false
+	for _, d := range ApplyRetention(policy, backups, time.Now(), usage) {
+		if d.Keep {
+			continue
+		}
+		backupLogger.Infof("Retention: deleting old backup %s (%s)", d.Filename, strings.Join(d.Reasons, ", "))
+		os.Remove(filepath.Join(backupsDir, d.Filename))
+		os.Remove(backupMetaPath(backupsDir, d.Filename))
+		pruned = append(pruned, d.Filename)
+		gc = gc || isSnapshot(d.Filename)
+	}
+	if gc {
+		if _, _, err := gcBackupRepo(backupsDir); err != nil {
+			backupLogger.Warnf("Repository GC failed: %v", err)
+		}
 	}
+	return pruned
 }
 
 // sanitizeForFilename returns a filename-safe version of the input
@@ -673,9 +745,14 @@ func (a *Agent) handleBackupCreate(msg Message) {
 		return
 	}
 
-	log.Printf("[Backup] Creating backup for %s (ID: %s)", req.ServerName, req.BackupID)
+	backupLogger.Infof("Creating backup for %s (ID: %s)", req.ServerName, req.BackupID)
+
+	_, span := startSpan(msg.Context(), "backup.create",
+		attribute.String("zedops.server.name", req.ServerName),
+		attribute.String("zedops.backup.id", req.BackupID))
 
 	progressFn := func(progress BackupProgress) {
+		addPhaseEvent(span, progress.Phase, progress.Percent)
 		progressMsg := Message{
 			Subject:   "backup.progress",
 			Data:      progress,
@@ -684,14 +761,22 @@ func (a *Agent) handleBackupCreate(msg Message) {
 		a.sendMessage(progressMsg)
 	}
 
-	result, err := CreateBackup(
-		req.ServerName, req.DataPath, req.BackupID, req.Notes,
-		req.ContainerID, req.RCONPort, req.RCONPassword,
-		a.rconManager, progressFn,
-	)
+	var result *BackupCreateResponse
+	unlock, err := tryLockServerBackups(req.ServerName, req.DataPath)
+	if err == nil {
+		started := time.Now()
+		result, err = CreateBackup(
+			req.ServerName, req.DataPath, req.BackupID, req.Notes, req.Pinned, req.Format,
+			req.ContainerID, req.RCONPort, req.RCONPassword,
+			a.rconManager, progressFn,
+		)
+		unlock()
+		agentStats.BackupFinished("create", time.Since(started), err)
+	}
+	endSpan(span, err)
 
 	if err != nil {
-		log.Printf("[Backup] Create failed for %s: %v", req.ServerName, err)
+		backupLogger.Error("Create failed", "server", req.ServerName, "error", err)
 		// Send error progress
 		progressFn(BackupProgress{BackupID: req.BackupID, ServerName: req.ServerName, Phase: "error", Error: err.Error()})
 
@@ -706,6 +791,10 @@ func (a *Agent) handleBackupCreate(msg Message) {
 		return
 	}
 
+	if a.backupUploader != nil {
+		a.backupUploader.Trigger(req.ServerName)
+	}
+
 	if msg.Reply != "" {
 		response := Message{
 			Subject:   msg.Reply,
@@ -810,9 +899,15 @@ func (a *Agent) handleBackupRestore(msg Message) {
 		return
 	}
 
-	log.Printf("[Restore] Starting restore for %s from %s (ID: %s)", req.ServerName, req.Filename, req.BackupID)
+	restoreLogger.Infof("Starting restore for %s from %s (ID: %s)", req.ServerName, req.Filename, req.BackupID)
+
+	_, span := startSpan(msg.Context(), "backup.restore",
+		attribute.String("zedops.server.name", req.ServerName),
+		attribute.String("zedops.backup.id", req.BackupID),
+		attribute.String("zedops.backup.filename", req.Filename))
 
 	progressFn := func(progress BackupProgress) {
+		addPhaseEvent(span, progress.Phase, progress.Percent)
 		progressMsg := Message{
 			Subject:   "backup.progress",
 			Data:      progress,
@@ -821,9 +916,21 @@ func (a *Agent) handleBackupRestore(msg Message) {
 		a.sendMessage(progressMsg)
 	}
 
-	err := RestoreFromBackup(req.ServerName, req.DataPath, req.Filename, req.BackupID, req.ContainerID, a.docker, progressFn)
+	unlock, err := tryLockServerBackups(req.ServerName, req.DataPath)
+	if err == nil {
+		// Free game ports held by a wake listener; the restore starts the container again
+		if a.idle != nil && req.ContainerID != "" {
+			a.idle.ReleaseContainer(req.ContainerID)
+		}
+
+		started := time.Now()
+		err = RestoreFromBackup(req.ServerName, req.DataPath, req.Filename, req.BackupID, req.ContainerID, a.docker, progressFn)
+		unlock()
+		agentStats.BackupFinished("restore", time.Since(started), err)
+	}
+	endSpan(span, err)
 	if err != nil {
-		log.Printf("[Restore] Failed for %s: %v", req.ServerName, err)
+		restoreLogger.Error("Restore failed", "server", req.ServerName, "backupId", req.BackupID, "error", err)
 		progressFn(BackupProgress{BackupID: req.BackupID, ServerName: req.ServerName, Phase: "error", Error: err.Error()})
 
 		if msg.Reply != "" {
@@ -0,0 +1,879 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backup formats
+const (
+	BackupFormatChunked = "chunked" // Snapshot manifest over a deduplicated chunk repository
+	BackupFormatTarGz   = "tar.gz"  // Self-contained archive
+)
+
+const (
+	snapshotExt     = ".snapshot"
+	backupRepoDir   = "repo" // Under backups/, holds chunks shared by all snapshots of a server
+	snapshotVersion = 1
+	snapshotChunker = "gear-256k-1m-4m" // Chunking parameters; changing them breaks dedup with older snapshots
+	chunkMinSize    = 256 * 1024
+	chunkMaxSize    = 4 * 1024 * 1024
+	chunkBoundary   = uint64(0xfffff) << 44 // 20 bits: ~1 MiB average chunk after the minimum
+)
+
+// gearTable maps bytes to random 64-bit values for the rolling gear hash. It is generated
+// with splitmix64 from a fixed seed so chunk boundaries are stable across agent versions.
+var gearTable = func() (t [256]uint64) {
+	x := uint64(0x6a09e667f3bcc908)
+	for i := range t {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		t[i] = z ^ (z >> 31)
+	}
+	return t
+}()
+
+// backupRepoLocks serializes writers and garbage collection per repository, so GC never
+// sees a half-written snapshot whose new chunks aren't referenced by a manifest yet
+var backupRepoLocks sync.Map // repo dir -> *sync.Mutex
+
+func lockBackupRepo(repoDir string) func() {
+	mu, _ := backupRepoLocks.LoadOrStore(filepath.Clean(repoDir), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// SnapshotManifest lists the files of a chunked backup and the chunks holding their content
+type SnapshotManifest struct {
+	Version    int            `json:"version"`
+	Chunker    string         `json:"chunker"`
+	BackupID   string         `json:"backupId"`
+	ServerName string         `json:"serverName"`
+	CreatedAt  int64          `json:"createdAt"` // Unix seconds
+	Files      []SnapshotFile `json:"files"`
+}
+
+// SnapshotFile is one file or directory in a snapshot
+type SnapshotFile struct {
+	Path    string   `json:"path"`    // Slash-separated, relative to data/
+	Mode    uint32   `json:"mode"`    // os.FileMode
+	ModTime int64    `json:"modTime"` // Unix nanoseconds
+	Size    int64    `json:"size,omitempty"`
+	Chunks  []string `json:"chunks,omitempty"` // SHA-256 of each chunk's content, in order
+}
+
+// chunker splits a stream into content-defined chunks: boundaries follow the data, so an
+// insertion only changes the chunks around it instead of shifting every later chunk
+type chunker struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{r: r, buf: make([]byte, 0, chunkMaxSize)}
+}
+
+// next returns the next chunk, or io.EOF. The returned slice is only valid until the next call.
+func (c *chunker) next(out []byte) ([]byte, error) {
+	for !c.eof && len(c.buf) < chunkMaxSize {
+		n, err := c.r.Read(c.buf[len(c.buf):chunkMaxSize])
+		c.buf = c.buf[:len(c.buf)+n]
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(c.buf) == 0 {
+		return nil, io.EOF
+	}
+
+	cut := chunkCutPoint(c.buf)
+	out = append(out[:0], c.buf[:cut]...)
+	c.buf = c.buf[:copy(c.buf, c.buf[cut:])]
+	return out, nil
+}
+
+// chunkCutPoint returns the length of the first chunk of data (at most len(data))
+func chunkCutPoint(data []byte) int {
+	if len(data) <= chunkMinSize {
+		return len(data)
+	}
+	var h uint64
+	for i := chunkMinSize; i < len(data); i++ {
+		h = (h << 1) + gearTable[data[i]]
+		if h&chunkBoundary == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// chunkPath returns where a chunk is stored: repo/chunks/ab/abcdef...
+func chunkPath(repoDir, hash string) string {
+	return filepath.Join(repoDir, "chunks", hash[:2], hash)
+}
+
+// writeChunk stores a chunk gzip-compressed if the repository doesn't have it yet.
+// Returns the bytes written to disk (0 for an existing chunk).
+func writeChunk(repoDir, hash string, data []byte) (int64, error) {
+	dst := chunkPath(repoDir, hash)
+	if _, err := os.Stat(dst); err == nil {
+		return 0, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz, _ := gzip.NewWriterLevel(tmp, gzip.BestSpeed)
+	if _, err := gz.Write(data); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	size, _ := tmp.Seek(0, io.SeekCurrent)
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// openChunk returns a reader over a chunk's content that fails on EOF if the content
+// doesn't match its hash
+func openChunk(repoDir, hash string) (io.ReadCloser, error) {
+	f, err := os.Open(chunkPath(repoDir, hash))
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("chunk %s: %w", hash, err)
+	}
+	return &verifiedChunk{f: f, gz: gz, hash: hash, h: sha256.New()}, nil
+}
+
+type verifiedChunk struct {
+	f    *os.File
+	gz   *gzip.Reader
+	hash string
+	h    hash.Hash
+}
+
+func (c *verifiedChunk) Read(p []byte) (int, error) {
+	n, err := c.gz.Read(p)
+	c.h.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(c.h.Sum(nil)) != c.hash {
+		return n, fmt.Errorf("chunk %s is corrupt (content hash mismatch)", c.hash)
+	}
+	return n, err
+}
+
+func (c *verifiedChunk) Close() error {
+	c.gz.Close()
+	return c.f.Close()
+}
+
+// snapshotBaseName strips the format extension from a backup filename
+func snapshotBaseName(filename string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(filename, ".tar.gz"), snapshotExt)
+}
+
+// isSnapshot reports whether a backup filename is a chunked snapshot
+func isSnapshot(filename string) bool {
+	return strings.HasSuffix(filename, snapshotExt)
+}
+
+// backupMetaPath returns the .meta.json sidecar path of a backup file
+func backupMetaPath(backupsDir, filename string) string {
+	return filepath.Join(backupsDir, snapshotBaseName(filename)+".meta.json")
+}
+
+// readSnapshotManifest loads a snapshot manifest
+func readSnapshotManifest(manifestPath string) (*SnapshotManifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var m SnapshotManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", filepath.Base(manifestPath), err)
+	}
+	if m.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot %s has unsupported version %d", filepath.Base(manifestPath), m.Version)
+	}
+	return &m, nil
+}
+
+// latestSnapshot returns the newest snapshot in backupsDir, used to skip re-reading unchanged files
+func latestSnapshot(backupsDir string) *SnapshotManifest {
+	entries, err := os.ReadDir(backupsDir)
+	if err != nil {
+		return nil
+	}
+	var latest *SnapshotManifest
+	for _, e := range entries {
+		if e.IsDir() || !isSnapshot(e.Name()) {
+			continue
+		}
+		m, err := readSnapshotManifest(filepath.Join(backupsDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		if latest == nil || m.CreatedAt > latest.CreatedAt {
+			latest = m
+		}
+	}
+	return latest
+}
+
+// createSnapshot chunks srcDir into the repository and writes the manifest to manifestPath.
+// Files whose size and modification time match the previous snapshot reuse its chunks without
+// being read. Returns the bytes added to disk (new chunks plus the manifest).
+func createSnapshot(manifestPath, repoDir, srcDir, backupID, serverName string, totalBytes int64, progressFn BackupProgressCallback) (int64, error) {
+	unlock := lockBackupRepo(repoDir)
+	defer unlock()
+
+	previous := make(map[string]SnapshotFile)
+	if parent := latestSnapshot(filepath.Dir(manifestPath)); parent != nil && parent.Chunker == snapshotChunker {
+		for _, f := range parent.Files {
+			previous[f.Path] = f
+		}
+	}
+
+	manifest := SnapshotManifest{
+		Version:    snapshotVersion,
+		Chunker:    snapshotChunker,
+		BackupID:   backupID,
+		ServerName: serverName,
+		CreatedAt:  time.Now().Unix(),
+	}
+
+	var added, bytesProcessed int64
+	var reused int
+	lastPercent := 0
+	buf := make([]byte, 0, chunkMaxSize)
+
+	err := filepath.Walk(srcDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		entry := SnapshotFile{
+			Path:    filepath.ToSlash(rel),
+			Mode:    uint32(info.Mode()),
+			ModTime: info.ModTime().UnixNano(),
+		}
+
+		switch {
+		case info.IsDir():
+			manifest.Files = append(manifest.Files, entry)
+			return nil
+		case !info.Mode().IsRegular():
+			backupLogger.Warnf("Skipping non-regular file %s", rel)
+			return nil
+		}
+
+		entry.Size = info.Size()
+		if prev, ok := previous[entry.Path]; ok && prev.Size == entry.Size && prev.ModTime == entry.ModTime && chunksExist(repoDir, prev.Chunks) {
+			entry.Chunks = prev.Chunks
+			reused++
+		} else {
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			c := newChunker(f)
+			for {
+				buf, err = c.next(buf)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					f.Close()
+					return err
+				}
+				sum := sha256.Sum256(buf)
+				hash := hex.EncodeToString(sum[:])
+				n, err := writeChunk(repoDir, hash, buf)
+				if err != nil {
+					f.Close()
+					return fmt.Errorf("failed to store chunk: %w", err)
+				}
+				added += n
+				entry.Chunks = append(entry.Chunks, hash)
+			}
+			f.Close()
+		}
+		manifest.Files = append(manifest.Files, entry)
+
+		bytesProcessed += entry.Size
+		if totalBytes > 0 && progressFn != nil {
+			percent := int((bytesProcessed*80)/totalBytes) + 15 // 15-95 range
+			if percent > 95 {
+				percent = 95
+			}
+			if percent-lastPercent >= 5 {
+				lastPercent = percent
+				progressFn(BackupProgress{BackupID: backupID, ServerName: serverName, Phase: "compressing", Percent: percent})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	data, _ := json.Marshal(manifest)
+	tmp := manifestPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, manifestPath); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+
+	backupLogger.Infof("Snapshot %s: %d files (%d unchanged), %d bytes added to repository",
+		filepath.Base(manifestPath), len(manifest.Files), reused, added)
+	return added + int64(len(data)), nil
+}
+
+// loadBackupUsage reads the chunks of a server's snapshots, to measure them against
+// MaxTotalBytes. Snapshots whose manifest can't be read count their SizeBytes.
+func loadBackupUsage(backupsDir string, backups []BackupMeta) *BackupUsage {
+	repoDir := filepath.Join(backupsDir, backupRepoDir)
+	chunkSize := func(hash string) int64 {
+		if info, err := os.Stat(chunkPath(repoDir, hash)); err == nil {
+			return info.Size()
+		}
+		return 0
+	}
+
+	usage := newBackupUsage()
+	for _, b := range backups {
+		if !isSnapshot(b.Filename) {
+			continue
+		}
+		manifestPath := filepath.Join(backupsDir, b.Filename)
+		info, err := os.Stat(manifestPath)
+		if err != nil {
+			continue
+		}
+		m, err := readSnapshotManifest(manifestPath)
+		if err != nil {
+			backupLogger.Warnf("Counting %s by its recorded size: %v", b.Filename, err)
+			continue
+		}
+		usage.addSnapshot(b.Filename, info.Size(), m, chunkSize)
+	}
+	return usage
+}
+
+// chunksExist checks that every chunk of a file is still in the repository
+func chunksExist(repoDir string, hashes []string) bool {
+	for _, hash := range hashes {
+		if _, err := os.Stat(chunkPath(repoDir, hash)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// restoreSnapshot writes a snapshot's files into dstDir
+func restoreSnapshot(manifestPath, repoDir, dstDir, backupID, serverName string, progressFn BackupProgressCallback) error {
+	manifest, err := readSnapshotManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	var totalBytes, bytesRestored int64
+	for _, f := range manifest.Files {
+		totalBytes += f.Size
+	}
+	lastPercent := 0
+
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+	var dirs []SnapshotFile
+	for _, f := range manifest.Files {
+		// Prevent path traversal from a tampered manifest
+		clean := path.Clean(f.Path)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("snapshot entry attempts path traversal: %s", f.Path)
+		}
+		target := filepath.Join(dstDir, filepath.FromSlash(clean))
+		mode := os.FileMode(f.Mode)
+
+		if mode.IsDir() {
+			if err := os.MkdirAll(target, mode.Perm()|0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, f)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+		if err != nil {
+			return err
+		}
+		for _, hash := range f.Chunks {
+			chunk, err := openChunk(repoDir, hash)
+			if err != nil {
+				out.Close()
+				return fmt.Errorf("%s: %w", f.Path, err)
+			}
+			_, err = io.Copy(out, chunk)
+			chunk.Close()
+			if err != nil {
+				out.Close()
+				return fmt.Errorf("%s: %w", f.Path, err)
+			}
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		modTime := time.Unix(0, f.ModTime)
+		os.Chtimes(target, modTime, modTime)
+
+		bytesRestored += f.Size
+		if totalBytes > 0 && progressFn != nil {
+			percent := 30 + int((bytesRestored*55)/totalBytes) // 30-85 range
+			if percent-lastPercent >= 5 {
+				lastPercent = percent
+				progressFn(BackupProgress{BackupID: backupID, ServerName: serverName, Phase: "extracting", Percent: percent})
+			}
+		}
+	}
+
+	// Directory times last, after their contents stopped changing them
+	for _, d := range dirs {
+		modTime := time.Unix(0, d.ModTime)
+		os.Chtimes(filepath.Join(dstDir, filepath.FromSlash(path.Clean(d.Path))), modTime, modTime)
+	}
+	return nil
+}
+
+// exportSnapshotTarGz writes a snapshot as a self-contained tar.gz archive
+func exportSnapshotTarGz(manifestPath, repoDir, tarPath string) (int64, error) {
+	manifest, err := readSnapshotManifest(manifestPath)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp := tarPath + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for _, f := range manifest.Files {
+		mode := os.FileMode(f.Mode)
+		header := &tar.Header{
+			Name:    f.Path,
+			Mode:    int64(mode.Perm()),
+			ModTime: time.Unix(0, f.ModTime),
+		}
+		if mode.IsDir() {
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+		} else {
+			header.Typeflag = tar.TypeReg
+			header.Size = f.Size
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			out.Close()
+			return 0, err
+		}
+		for _, hash := range f.Chunks {
+			chunk, err := openChunk(repoDir, hash)
+			if err != nil {
+				out.Close()
+				return 0, fmt.Errorf("%s: %w", f.Path, err)
+			}
+			_, err = io.Copy(tw, chunk)
+			chunk.Close()
+			if err != nil {
+				out.Close()
+				return 0, fmt.Errorf("%s: %w", f.Path, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		out.Close()
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return 0, err
+	}
+	size, _ := out.Seek(0, io.SeekCurrent)
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+	return size, os.Rename(tmp, tarPath)
+}
+
+// BackupVerifyResult reports the integrity of one backup
+type BackupVerifyResult struct {
+	Filename string   `json:"filename"`
+	Format   string   `json:"format"`
+	OK       bool     `json:"ok"`
+	Files    int      `json:"files"`
+	Chunks   int      `json:"chunks,omitempty"`   // Distinct chunks referenced
+	Missing  []string `json:"missing,omitempty"`  // Chunks not in the repository
+	Corrupt  []string `json:"corrupt,omitempty"`  // Chunks whose content doesn't match their hash
+	Problems []string `json:"problems,omitempty"` // Other errors (unreadable archive, bad manifest)
+}
+
+// VerifyBackup checks a backup's integrity. For snapshots, every referenced chunk must exist;
+// with full set each chunk is also decompressed and re-hashed. Archives are always read fully.
+func VerifyBackup(serverName, dataPath, filename string, full bool) (*BackupVerifyResult, error) {
+	if strings.Contains(filename, "/") || strings.Contains(filename, "..") {
+		return nil, fmt.Errorf("invalid filename")
+	}
+	backupsDir := filepath.Join(dataPath, serverName, "backups")
+	backupPath := filepath.Join(backupsDir, filename)
+	if _, err := os.Stat(backupPath); err != nil {
+		return nil, fmt.Errorf("backup file not found: %s", filename)
+	}
+
+	result := &BackupVerifyResult{Filename: filename}
+	if !isSnapshot(filename) {
+		result.Format = BackupFormatTarGz
+		files, err := verifyTarGz(backupPath)
+		result.Files = files
+		if err != nil {
+			result.Problems = append(result.Problems, err.Error())
+		}
+		result.OK = err == nil
+		return result, nil
+	}
+
+	result.Format = BackupFormatChunked
+	repoDir := filepath.Join(backupsDir, backupRepoDir)
+	unlock := lockBackupRepo(repoDir)
+	defer unlock()
+
+	manifest, err := readSnapshotManifest(backupPath)
+	if err != nil {
+		result.Problems = append(result.Problems, err.Error())
+		return result, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range manifest.Files {
+		result.Files++
+		for _, hash := range f.Chunks {
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			if _, err := os.Stat(chunkPath(repoDir, hash)); err != nil {
+				result.Missing = append(result.Missing, hash)
+				continue
+			}
+			if full {
+				chunk, err := openChunk(repoDir, hash)
+				if err == nil {
+					_, err = io.Copy(io.Discard, chunk)
+					chunk.Close()
+				}
+				if err != nil {
+					result.Corrupt = append(result.Corrupt, hash)
+				}
+			}
+		}
+	}
+	result.Chunks = len(seen)
+	result.OK = len(result.Missing) == 0 && len(result.Corrupt) == 0
+	return result, nil
+}
+
+// verifyTarGz reads an archive to the end, which checks the gzip CRC and tar structure
+func verifyTarGz(tarPath string) (int, error) {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	files := 0
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, err
+		}
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return files, err
+		}
+		files++
+	}
+}
+
+// gcBackupRepo deletes chunks no remaining snapshot in backupsDir references.
+// Returns the number of chunks and bytes freed.
+func gcBackupRepo(backupsDir string) (int, int64, error) {
+	repoDir := filepath.Join(backupsDir, backupRepoDir)
+	if !dirExists(repoDir) {
+		return 0, 0, nil
+	}
+	unlock := lockBackupRepo(repoDir)
+	defer unlock()
+
+	entries, err := os.ReadDir(backupsDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	referenced := make(map[string]bool)
+	for _, e := range entries {
+		if e.IsDir() || !isSnapshot(e.Name()) {
+			continue
+		}
+		m, err := readSnapshotManifest(filepath.Join(backupsDir, e.Name()))
+		if err != nil {
+			// Deleting chunks an unreadable manifest may need would make it unrecoverable
+			return 0, 0, fmt.Errorf("garbage collection aborted: %w", err)
+		}
+		for _, f := range m.Files {
+			for _, hash := range f.Chunks {
+				referenced[hash] = true
+			}
+		}
+	}
+
+	var freedChunks int
+	var freedBytes int64
+	err = filepath.Walk(filepath.Join(repoDir, "chunks"), func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || referenced[info.Name()] {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		freedChunks++
+		freedBytes += info.Size()
+		return nil
+	})
+	if freedChunks > 0 {
+		backupLogger.Infof("Repository GC in %s: removed %d chunk(s), %d bytes", backupsDir, freedChunks, freedBytes)
+	}
+	return freedChunks, freedBytes, err
+}
+
+// BackupFileRequest identifies one backup for backup.verify, backup.extract and backup.export
+type BackupFileRequest struct {
+	ServerName string `json:"serverName"`
+	DataPath   string `json:"dataPath"`
+	Filename   string `json:"filename"`
+	BackupID   string `json:"backupId,omitempty"` // For progress events
+	Full       bool   `json:"full,omitempty"`     // backup.verify: re-hash chunk contents
+	Target     string `json:"target,omitempty"`   // backup.extract: directory name under {server}/restores/
+}
+
+// BackupFileResponse is the backup.verify, backup.extract and backup.export response payload
+type BackupFileResponse struct {
+	Success   bool                `json:"success"`
+	Verify    *BackupVerifyResult `json:"verify,omitempty"`
+	Path      string              `json:"path,omitempty"`      // Extracted directory or exported archive
+	SizeBytes int64               `json:"sizeBytes,omitempty"` // Exported archive size
+	Error     string              `json:"error,omitempty"`
+}
+
+// ExtractBackup restores a backup (either format) into {server}/restores/{target} without
+// touching the live data/ directory, e.g. to recover a single file
+func ExtractBackup(serverName, dataPath, filename, target, backupID string, progressFn BackupProgressCallback) (string, error) {
+	if strings.Contains(filename, "/") || strings.Contains(filename, "..") {
+		return "", fmt.Errorf("invalid filename")
+	}
+	if target == "" {
+		target = snapshotBaseName(filename)
+	}
+	if target = sanitizeForFilename(target); target == "" {
+		return "", fmt.Errorf("invalid target name")
+	}
+
+	backupsDir := filepath.Join(dataPath, serverName, "backups")
+	backupPath := filepath.Join(backupsDir, filename)
+	if _, err := os.Stat(backupPath); err != nil {
+		return "", fmt.Errorf("backup file not found: %s", filename)
+	}
+	dstDir := filepath.Join(dataPath, serverName, "restores", target)
+	if entries, err := os.ReadDir(dstDir); err == nil && len(entries) > 0 {
+		return "", fmt.Errorf("restore directory %s is not empty", dstDir)
+	}
+
+	var err error
+	if isSnapshot(filename) {
+		repoDir := filepath.Join(backupsDir, backupRepoDir)
+		unlock := lockBackupRepo(repoDir)
+		err = restoreSnapshot(backupPath, repoDir, dstDir, backupID, serverName, progressFn)
+		unlock()
+	} else {
+		err = extractTarGz(backupPath, dstDir, backupID, serverName, progressFn)
+	}
+	if err != nil {
+		os.RemoveAll(dstDir)
+		return "", err
+	}
+	restoreLogger.Infof("Extracted %s/%s to %s", serverName, filename, dstDir)
+	return dstDir, nil
+}
+
+// ExportBackup writes a snapshot as a tar.gz archive under {server}/exports/
+func ExportBackup(serverName, dataPath, filename string) (string, int64, error) {
+	if strings.Contains(filename, "/") || strings.Contains(filename, "..") {
+		return "", 0, fmt.Errorf("invalid filename")
+	}
+	if !isSnapshot(filename) {
+		return "", 0, fmt.Errorf("%s is already a tar.gz archive", filename)
+	}
+
+	backupsDir := filepath.Join(dataPath, serverName, "backups")
+	manifestPath := filepath.Join(backupsDir, filename)
+	if _, err := os.Stat(manifestPath); err != nil {
+		return "", 0, fmt.Errorf("backup file not found: %s", filename)
+	}
+	exportsDir := filepath.Join(dataPath, serverName, "exports")
+	if err := os.MkdirAll(exportsDir, 0755); err != nil {
+		return "", 0, err
+	}
+	tarPath := filepath.Join(exportsDir, snapshotBaseName(filename)+".tar.gz")
+
+	repoDir := filepath.Join(backupsDir, backupRepoDir)
+	unlock := lockBackupRepo(repoDir)
+	defer unlock()
+
+	size, err := exportSnapshotTarGz(manifestPath, repoDir, tarPath)
+	if err != nil {
+		return "", 0, err
+	}
+	backupLogger.Infof("Exported %s/%s to %s (%d bytes)", serverName, filename, tarPath, size)
+	return tarPath, size, nil
+}
+
+// handleBackupVerify handles backup.verify messages
+func (a *Agent) handleBackupVerify(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupFileRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" || req.DataPath == "" || req.Filename == "" {
+		a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Error: "Invalid request format (serverName, dataPath and filename required)"})
+		return
+	}
+
+	result, err := VerifyBackup(req.ServerName, req.DataPath, req.Filename, req.Full)
+	if err != nil {
+		a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Error: err.Error()})
+		return
+	}
+	if !result.OK {
+		backupLogger.Warnf("Verification of %s/%s failed: %d missing, %d corrupt chunk(s), %v",
+			req.ServerName, req.Filename, len(result.Missing), len(result.Corrupt), result.Problems)
+	}
+	a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Success: true, Verify: result})
+}
+
+// handleBackupExtract handles backup.extract messages
+func (a *Agent) handleBackupExtract(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupFileRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" || req.DataPath == "" || req.Filename == "" {
+		a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Error: "Invalid request format (serverName, dataPath and filename required)"})
+		return
+	}
+
+	progressFn := func(progress BackupProgress) {
+		a.sendMessage(NewMessage("backup.progress", progress))
+	}
+	dir, err := ExtractBackup(req.ServerName, req.DataPath, req.Filename, req.Target, req.BackupID, progressFn)
+	if err != nil {
+		a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Error: err.Error()})
+		return
+	}
+	progressFn(BackupProgress{BackupID: req.BackupID, ServerName: req.ServerName, Phase: "complete", Percent: 100})
+	a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Success: true, Path: dir})
+}
+
+// handleBackupExport handles backup.export messages
+func (a *Agent) handleBackupExport(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupFileRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" || req.DataPath == "" || req.Filename == "" {
+		a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Error: "Invalid request format (serverName, dataPath and filename required)"})
+		return
+	}
+
+	tarPath, size, err := ExportBackup(req.ServerName, req.DataPath, req.Filename)
+	if err != nil {
+		a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Error: err.Error()})
+		return
+	}
+	a.sendBackupFileResponse(msg.Reply, BackupFileResponse{Success: true, Path: tarPath, SizeBytes: size})
+}
+
+// sendBackupFileResponse replies to a backup.verify, backup.extract or backup.export request
+func (a *Agent) sendBackupFileResponse(replyTo string, resp BackupFileResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chunkAll splits data with the chunker and returns the chunks
+func chunkAll(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	c := newChunker(bytes.NewReader(data))
+	var chunks [][]byte
+	var buf []byte
+	for {
+		chunk, err := c.next(buf)
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, append([]byte(nil), chunk...))
+	}
+}
+
+func TestChunkerBoundaries(t *testing.T) {
+	random := make([]byte, 12<<20)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"below the minimum", random[:chunkMinSize-1]},
+		{"exactly the minimum", random[:chunkMinSize]},
+		{"random", random},
+		// Without content to cut at, chunks stop at the maximum size
+		{"zeros", make([]byte, 2*chunkMaxSize+10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkAll(t, tt.data)
+			if !bytes.Equal(bytes.Join(chunks, nil), tt.data) {
+				t.Fatal("chunks don't reassemble the data")
+			}
+			for i, chunk := range chunks {
+				last := i == len(chunks)-1
+				if len(chunk) > chunkMaxSize || (!last && len(chunk) < chunkMinSize) || len(chunk) == 0 {
+					t.Errorf("chunk %d/%d has %d bytes", i, len(chunks), len(chunk))
+				}
+			}
+		})
+	}
+
+	// Boundaries follow the content: an insertion near the start leaves later chunks as they were
+	original := chunkAll(t, random)
+	shifted := chunkAll(t, append([]byte("inserted"), random...))
+	hashes := make(map[[32]byte]bool)
+	for _, chunk := range original {
+		hashes[sha256.Sum256(chunk)] = true
+	}
+	shared := 0
+	for _, chunk := range shifted {
+		if hashes[sha256.Sum256(chunk)] {
+			shared++
+		}
+	}
+	if len(original) < 4 || shared < len(original)-2 {
+		t.Errorf("%d of %d chunks survive an insertion", shared, len(original))
+	}
+}
+
+// newTestRepo creates the backups dir of a server with two snapshots that share most chunks
+func newTestRepo(t *testing.T) (dataPath, backupsDir string) {
+	t.Helper()
+	dataPath = t.TempDir()
+	world := make([]byte, 3<<20)
+	rand.New(rand.NewSource(2)).Read(world)
+	writeTree(t, dataPath, map[string]string{
+		"survival/data/Saves/map.bin":    string(world),
+		"survival/data/Saves/players.db": "one player",
+	})
+	backupsDir = filepath.Join(dataPath, "survival", "backups")
+	repoDir := filepath.Join(backupsDir, backupRepoDir)
+	srcDir := filepath.Join(dataPath, "survival", "data")
+	if _, err := createSnapshot(filepath.Join(backupsDir, "a.snapshot"), repoDir, srcDir, "a", "survival", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dataPath, map[string]string{"survival/data/Saves/players.db": "two players"})
+	if _, err := createSnapshot(filepath.Join(backupsDir, "b.snapshot"), repoDir, srcDir, "b", "survival", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	return dataPath, backupsDir
+}
+
+func snapshotChunks(t *testing.T, manifestPath string) map[string]bool {
+	t.Helper()
+	m, err := readSnapshotManifest(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := map[string]bool{}
+	for _, f := range m.Files {
+		for _, hash := range f.Chunks {
+			chunks[hash] = true
+		}
+	}
+	return chunks
+}
+
+func repoChunks(t *testing.T, backupsDir string) map[string]bool {
+	t.Helper()
+	chunks := map[string]bool{}
+	filepath.Walk(filepath.Join(backupsDir, backupRepoDir, "chunks"), func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			chunks[info.Name()] = true
+		}
+		return nil
+	})
+	return chunks
+}
+
+func TestDeleteBackupKeepsSharedChunks(t *testing.T) {
+	dataPath, backupsDir := newTestRepo(t)
+	a := snapshotChunks(t, filepath.Join(backupsDir, "a.snapshot"))
+	b := snapshotChunks(t, filepath.Join(backupsDir, "b.snapshot"))
+	onlyA := 0
+	for hash := range a {
+		if !b[hash] {
+			onlyA++
+		}
+	}
+	if onlyA == 0 || onlyA == len(a) {
+		t.Fatalf("%d of %d chunks unique to a, want some but not all", onlyA, len(a))
+	}
+
+	if err := DeleteBackup("survival", dataPath, "a.snapshot"); err != nil {
+		t.Fatal(err)
+	}
+	if got := repoChunks(t, backupsDir); len(got) != len(b) {
+		t.Errorf("repository holds %d chunks, want the %d of b", len(got), len(b))
+	} else {
+		for hash := range b {
+			if !got[hash] {
+				t.Errorf("chunk %s of b was collected", hash)
+			}
+		}
+	}
+	if result, err := VerifyBackup("survival", dataPath, "b.snapshot", true); err != nil || !result.OK {
+		t.Errorf("b after deleting a: %+v, %v", result, err)
+	}
+
+	// An unreadable manifest stops GC rather than lose chunks it might reference
+	writeTree(t, backupsDir, map[string]string{"broken.snapshot": "{"})
+	if err := DeleteBackup("survival", dataPath, "b.snapshot"); err != nil {
+		t.Fatal(err)
+	}
+	if got := repoChunks(t, backupsDir); len(got) != len(b) {
+		t.Errorf("GC with an unreadable manifest removed chunks: %d left of %d", len(got), len(b))
+	}
+	os.Remove(filepath.Join(backupsDir, "broken.snapshot"))
+	if _, _, err := gcBackupRepo(backupsDir); err != nil {
+		t.Fatal(err)
+	}
+	if got := repoChunks(t, backupsDir); len(got) != 0 {
+		t.Errorf("%d chunks left without snapshots", len(got))
+	}
+}
+
+func TestVerifyBackupReportsDamagedChunks(t *testing.T) {
+	dataPath, backupsDir := newTestRepo(t)
+	if result, err := VerifyBackup("survival", dataPath, "b.snapshot", true); err != nil || !result.OK || result.Files == 0 {
+		t.Fatalf("intact snapshot: %+v, %v", result, err)
+	}
+
+	var hashes []string
+	for hash := range snapshotChunks(t, filepath.Join(backupsDir, "b.snapshot")) {
+		hashes = append(hashes, hash)
+	}
+	repoDir := filepath.Join(backupsDir, backupRepoDir)
+	missing, corrupt := hashes[0], hashes[1]
+	if err := os.Remove(chunkPath(repoDir, missing)); err != nil {
+		t.Fatal(err)
+	}
+	// Valid gzip, wrong content
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("not the chunk"))
+	gz.Close()
+	if err := os.WriteFile(chunkPath(repoDir, corrupt), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		full    bool
+		corrupt []string
+	}{
+		{false, nil}, // Only checks that chunks exist
+		{true, []string{corrupt}},
+	}
+	for _, tt := range tests {
+		result, err := VerifyBackup("survival", dataPath, "b.snapshot", tt.full)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.OK || strings.Join(result.Missing, ",") != missing || strings.Join(result.Corrupt, ",") != strings.Join(tt.corrupt, ",") {
+			t.Errorf("full=%v: %+v, want %s missing and %v corrupt", tt.full, result, missing, tt.corrupt)
+		}
+	}
+}
+
+func TestExportAndExtractBackup(t *testing.T) {
+	dataPath, _ := newTestRepo(t)
+	srcDir := filepath.Join(dataPath, "survival", "data")
+
+	sameTree := func(dir string) {
+		t.Helper()
+		for _, name := range []string{"Saves/map.bin", "Saves/players.db"} {
+			want, _ := os.ReadFile(filepath.Join(srcDir, name))
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("%s in %s differs (%d bytes, want %d): %v", name, dir, len(got), len(want), err)
+			}
+		}
+	}
+
+	dir, err := ExtractBackup("survival", dataPath, "b.snapshot", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join(dataPath, "survival", "restores", "b") {
+		t.Errorf("extracted to %s", dir)
+	}
+	sameTree(dir)
+	if _, err := ExtractBackup("survival", dataPath, "b.snapshot", "", "", nil); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("extract over a previous one = %v", err)
+	}
+
+	// The export is a self-contained archive that extracts like any tar.gz backup
+	tarPath, size, err := ExportBackup("survival", dataPath, "b.snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(tarPath); err != nil || info.Size() != size {
+		t.Fatalf("export %s: %v, size %d", tarPath, err, size)
+	}
+	backupsDir := filepath.Join(dataPath, "survival", "backups")
+	if err := os.Rename(tarPath, filepath.Join(backupsDir, "exported.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := VerifyBackup("survival", dataPath, "exported.tar.gz", false); err != nil || !result.OK {
+		t.Errorf("exported archive: %+v, %v", result, err)
+	}
+	dir, err = ExtractBackup("survival", dataPath, "exported.tar.gz", "from-export", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameTree(dir)
+
+	if _, _, err := ExportBackup("survival", dataPath, "exported.tar.gz"); err == nil {
+		t.Error("exported an archive again")
+	}
+	for _, filename := range []string{"../b.snapshot", "a/b.snapshot"} {
+		if _, err := ExtractBackup("survival", dataPath, filename, "", "", nil); err == nil {
+			t.Errorf("extracted %s", filename)
+		}
+	}
+}
+
+func TestExtractBackupRejectsPathTraversal(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{"parent", "../escaped"},
+		{"server dir", "../../escaped"},
+		{"sibling with the same prefix", "../evilsibling/escaped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataPath := t.TempDir()
+			backupsDir := filepath.Join(dataPath, "survival", "backups")
+			if err := os.MkdirAll(filepath.Join(backupsDir, backupRepoDir, "chunks"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			// A tampered snapshot manifest
+			chunk := []byte("payload")
+			sum := sha256.Sum256(chunk)
+			hash := hex.EncodeToString(sum[:])
+			if _, err := writeChunk(filepath.Join(backupsDir, backupRepoDir), hash, chunk); err != nil {
+				t.Fatal(err)
+			}
+			manifest, _ := json.Marshal(SnapshotManifest{
+				Version: snapshotVersion,
+				Chunker: snapshotChunker,
+				Files:   []SnapshotFile{{Path: tt.entry, Mode: 0644, Size: int64(len(chunk)), Chunks: []string{hash}}},
+			})
+			writeTree(t, backupsDir, map[string]string{"evil.snapshot": string(manifest)})
+
+			// A tampered archive
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			tw.WriteHeader(&tar.Header{Name: tt.entry, Mode: 0644, Size: int64(len(chunk)), Typeflag: tar.TypeReg})
+			tw.Write(chunk)
+			tw.Close()
+			gz.Close()
+			writeTree(t, backupsDir, map[string]string{"evil.tar.gz": buf.String()})
+
+			for _, filename := range []string{"evil.snapshot", "evil.tar.gz"} {
+				// "evil" restores into restores/evil, next to restores/evilsibling
+				_, err := ExtractBackup("survival", dataPath, filename, "evil", "", nil)
+				if err == nil || !strings.Contains(err.Error(), "path traversal") {
+					t.Errorf("%s: err = %v, want path traversal", filename, err)
+				}
+			}
+			filepath.Walk(dataPath, func(p string, info os.FileInfo, err error) error {
+				if err == nil && info.Name() == "escaped" {
+					t.Errorf("wrote %s", p)
+				}
+				return nil
+			})
+		})
+	}
+}
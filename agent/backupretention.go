@@ -0,0 +1,544 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupRetentionFile = "backup-retention.json"
+
+// RetentionPolicy decides which backups of a server are kept. A backup is kept if any
+// keep rule selects it; MaxAgeDays and MaxTotalBytes then prune from the oldest end.
+// Pinned backups are never pruned and the newest backup is always kept.
+type RetentionPolicy struct {
+	ServerName    string `json:"serverName"`
+	KeepLast      int    `json:"keepLast,omitempty"`      // Most recent N backups
+	KeepHourly    int    `json:"keepHourly,omitempty"`    // Newest backup of each of the last N hours that have one
+	KeepDaily     int    `json:"keepDaily,omitempty"`     // ... days
+	KeepWeekly    int    `json:"keepWeekly,omitempty"`    // ... ISO weeks
+	KeepMonthly   int    `json:"keepMonthly,omitempty"`   // ... months
+	MaxTotalBytes int64  `json:"maxTotalBytes,omitempty"` // Prune oldest until the server's backups fit (pinned count too; shared chunks count once)
+	MaxAgeDays    int    `json:"maxAgeDays,omitempty"`    // Prune anything older, even if a keep rule selects it
+	Timezone      string `json:"timezone,omitempty"`      // IANA zone for hour/day/week/month boundaries (default UTC)
+}
+
+// defaultRetentionPolicy matches the fixed limit used before policies were configurable
+func defaultRetentionPolicy(serverName string) RetentionPolicy {
+	return RetentionPolicy{ServerName: serverName, KeepLast: MaxBackupsPerServer}
+}
+
+// hasKeepRules returns false for policies that only limit age or size
+func (p RetentionPolicy) hasKeepRules() bool {
+	return p.KeepLast > 0 || p.KeepHourly > 0 || p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0
+}
+
+// Validate checks a policy before it is stored
+func (p RetentionPolicy) Validate() error {
+	if p.ServerName == "" {
+		return fmt.Errorf("serverName is required")
+	}
+	if p.KeepLast < 0 || p.KeepHourly < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 || p.KeepMonthly < 0 ||
+		p.MaxTotalBytes < 0 || p.MaxAgeDays < 0 {
+		return fmt.Errorf("retention limits must not be negative")
+	}
+	if !p.hasKeepRules() && p.MaxTotalBytes == 0 && p.MaxAgeDays == 0 {
+		return fmt.Errorf("policy keeps every backup forever; set at least one limit")
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", p.Timezone)
+		}
+	}
+	return nil
+}
+
+// RetentionDecision is the verdict on one backup
+type RetentionDecision struct {
+	Filename  string   `json:"filename"`
+	BackupID  string   `json:"backupId"`
+	CreatedAt int64    `json:"createdAt"` // Unix seconds
+	SizeBytes int64    `json:"sizeBytes"`
+	Pinned    bool     `json:"pinned,omitempty"`
+	Keep      bool     `json:"keep"`
+	Reasons   []string `json:"reasons"` // Rules that kept it (e.g. "daily 2026-01-02"), or why it is pruned
+}
+
+// BackupUsage measures the storage backups occupy together. Chunked snapshots share repository
+// chunks, so the SizeBytes of each (the bytes it added) don't add up to what a set of them keeps
+// on disk: a snapshot costs its manifest plus the chunks no backup counted before it.
+type BackupUsage struct {
+	chunkBytes map[string]int64         // Chunk hash -> stored size
+	snapshots  map[string]snapshotUsage // Snapshot filename -> its manifest and chunks
+}
+
+type snapshotUsage struct {
+	manifestBytes int64
+	chunks        []string // Unique chunk hashes
+}
+
+func newBackupUsage() *BackupUsage {
+	return &BackupUsage{chunkBytes: make(map[string]int64), snapshots: make(map[string]snapshotUsage)}
+}
+
+// addSnapshot records a snapshot's manifest size and chunks; chunkSize returns a chunk's stored size
+func (u *BackupUsage) addSnapshot(filename string, manifestBytes int64, m *SnapshotManifest, chunkSize func(hash string) int64) {
+	su := snapshotUsage{manifestBytes: manifestBytes}
+	seen := make(map[string]bool)
+	for _, f := range m.Files {
+		for _, hash := range f.Chunks {
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			su.chunks = append(su.chunks, hash)
+			if _, ok := u.chunkBytes[hash]; !ok {
+				u.chunkBytes[hash] = chunkSize(hash)
+			}
+		}
+	}
+	u.snapshots[filename] = su
+}
+
+// charge returns what keeping b adds to the backups already charged, and marks its chunks
+// charged unless dryRun. A nil BackupUsage, and backups it doesn't know, cost their SizeBytes.
+func (u *BackupUsage) charge(b BackupMeta, charged map[string]bool, dryRun bool) int64 {
+	if u == nil {
+		return b.SizeBytes
+	}
+	su, ok := u.snapshots[b.Filename]
+	if !ok {
+		return b.SizeBytes
+	}
+	cost := su.manifestBytes
+	for _, hash := range su.chunks {
+		if !charged[hash] {
+			cost += u.chunkBytes[hash]
+			if !dryRun {
+				charged[hash] = true
+			}
+		}
+	}
+	return cost
+}
+
+// Total returns the storage a set of backups occupies together
+func (u *BackupUsage) Total(backups []BackupMeta) int64 {
+	charged := make(map[string]bool)
+	var total int64
+	for _, b := range backups {
+		total += u.charge(b, charged, false)
+	}
+	return total
+}
+
+// ApplyRetention decides which backups a policy keeps. backups must be sorted newest-first
+// (as returned by ListBackups); decisions are returned in the same order. usage measures
+// backups against MaxTotalBytes; nil counts each backup's SizeBytes.
+func ApplyRetention(policy RetentionPolicy, backups []BackupMeta, now time.Time, usage *BackupUsage) []RetentionDecision {
+	loc := time.UTC
+	if policy.Timezone != "" {
+		if l, err := time.LoadLocation(policy.Timezone); err == nil {
+			loc = l
+		}
+	}
+
+	decisions := make([]RetentionDecision, len(backups))
+	for i, b := range backups {
+		decisions[i] = RetentionDecision{
+			Filename:  b.Filename,
+			BackupID:  b.BackupID,
+			CreatedAt: b.CreatedAt,
+			SizeBytes: b.SizeBytes,
+			Pinned:    b.Pinned,
+			Reasons:   []string{},
+		}
+	}
+
+	// Keep rules select from unpinned backups, newest first
+	keepLast := 0
+	buckets := []struct {
+		name  string
+		limit int
+		key   func(t time.Time) string
+		last  string
+		count int
+	}{
+		{name: "hourly", limit: policy.KeepHourly, key: func(t time.Time) string { return t.Format("2006-01-02 15h") }},
+		{name: "daily", limit: policy.KeepDaily, key: func(t time.Time) string { return t.Format("2006-01-02") }},
+		{name: "weekly", limit: policy.KeepWeekly, key: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{name: "monthly", limit: policy.KeepMonthly, key: func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	newest := -1
+	for i := range decisions {
+		d := &decisions[i]
+		if d.Pinned {
+			d.Keep = true
+			d.Reasons = append(d.Reasons, "pinned")
+			continue
+		}
+		if newest < 0 {
+			newest = i
+		}
+		if !policy.hasKeepRules() {
+			d.Keep = true
+			d.Reasons = append(d.Reasons, "no keep rules")
+			continue
+		}
+		if keepLast < policy.KeepLast {
+			keepLast++
+			d.Keep = true
+			d.Reasons = append(d.Reasons, fmt.Sprintf("last %d", keepLast))
+		}
+		created := time.Unix(d.CreatedAt, 0).In(loc)
+		for j := range buckets {
+			bucket := &buckets[j]
+			if bucket.count >= bucket.limit {
+				continue
+			}
+			if key := bucket.key(created); key != bucket.last {
+				bucket.last = key
+				bucket.count++
+				d.Keep = true
+				d.Reasons = append(d.Reasons, bucket.name+" "+key)
+			}
+		}
+	}
+
+	if policy.MaxAgeDays > 0 {
+		cutoff := now.Add(-time.Duration(policy.MaxAgeDays) * 24 * time.Hour).Unix()
+		for i := range decisions {
+			d := &decisions[i]
+			if d.Keep && !d.Pinned && i != newest && d.CreatedAt < cutoff {
+				d.Keep = false
+				d.Reasons = []string{fmt.Sprintf("older than %d days", policy.MaxAgeDays)}
+			}
+		}
+	}
+
+	if policy.MaxTotalBytes > 0 {
+		// Pinned backups can't be pruned, so their size is reserved first
+		charged := make(map[string]bool)
+		var total int64
+		for i, d := range decisions {
+			if d.Pinned {
+				total += usage.charge(backups[i], charged, false)
+			}
+		}
+		for i := range decisions {
+			d := &decisions[i]
+			if !d.Keep || d.Pinned {
+				continue
+			}
+			if total+usage.charge(backups[i], charged, true) > policy.MaxTotalBytes && i != newest {
+				d.Keep = false
+				d.Reasons = []string{fmt.Sprintf("over total size limit (%d bytes)", policy.MaxTotalBytes)}
+				continue
+			}
+			total += usage.charge(backups[i], charged, false)
+		}
+	}
+
+	// The newest backup survives even a policy that selects nothing for it
+	if newest >= 0 && !decisions[newest].Keep {
+		decisions[newest].Keep = true
+		decisions[newest].Reasons = []string{"newest"}
+	}
+	for i := range decisions {
+		if !decisions[i].Keep && len(decisions[i].Reasons) == 0 {
+			decisions[i].Reasons = append(decisions[i].Reasons, "not selected by any keep rule")
+		}
+	}
+	return decisions
+}
+
+// BackupRetentionStore holds per-server retention policies
+type BackupRetentionStore struct {
+	mu       sync.RWMutex
+	policies map[string]RetentionPolicy // serverName -> policy
+}
+
+// backupRetention is consulted after every backup
+var backupRetention = &BackupRetentionStore{policies: make(map[string]RetentionPolicy)}
+
+// Policy returns a server's retention policy, or the default keep-last-10 policy
+func (s *BackupRetentionStore) Policy(serverName string) RetentionPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p, ok := s.policies[serverName]; ok {
+		return p
+	}
+	return defaultRetentionPolicy(serverName)
+}
+
+// Policies returns all configured policies sorted by server name
+func (s *BackupRetentionStore) Policies() []RetentionPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	policies := make([]RetentionPolicy, 0, len(s.policies))
+	for _, p := range s.policies {
+		policies = append(policies, p)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ServerName < policies[j].ServerName })
+	return policies
+}
+
+// SetPolicies replaces all policies
+func (s *BackupRetentionStore) SetPolicies(policies []RetentionPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies = make(map[string]RetentionPolicy, len(policies))
+	for _, p := range policies {
+		s.policies[p.ServerName] = p
+	}
+}
+
+// SetPolicy stores one server's policy (nil restores the default) and persists all policies
+func (s *BackupRetentionStore) SetPolicy(serverName string, policy *RetentionPolicy) error {
+	s.mu.Lock()
+	if policy == nil {
+		delete(s.policies, serverName)
+	} else {
+		s.policies[serverName] = *policy
+	}
+	s.mu.Unlock()
+	return SaveBackupRetention(s.Policies())
+}
+
+// GetBackupRetentionPath returns the path to the retention policies file
+func GetBackupRetentionPath() string {
+	return filepath.Join(StateDir(), backupRetentionFile)
+}
+
+// LoadBackupRetention loads retention policies from disk.
+// Returns nil if the file doesn't exist.
+func LoadBackupRetention() ([]RetentionPolicy, error) {
+	data, err := os.ReadFile(GetBackupRetentionPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup retention policies: %w", err)
+	}
+
+	var policies []RetentionPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse backup retention policies: %w", err)
+	}
+	return policies, nil
+}
+
+// SaveBackupRetention saves retention policies to disk.
+func SaveBackupRetention(policies []RetentionPolicy) error {
+	if err := ensureStateDir(); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(GetBackupRetentionPath(), policies); err != nil {
+		return fmt.Errorf("failed to write backup retention policies: %w", err)
+	}
+	return nil
+}
+
+// SetBackupPinned sets or clears a backup's pinned flag in its .meta.json sidecar
+func SetBackupPinned(serverName, dataPath, filename string, pinned bool) (*BackupMeta, error) {
+	if strings.Contains(filename, "/") || strings.Contains(filename, "..") {
+		return nil, fmt.Errorf("invalid filename")
+	}
+	metaPath := backupMetaPath(filepath.Join(dataPath, serverName, "backups"), filename)
+
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("backup not found: %s", filename)
+		}
+		return nil, fmt.Errorf("failed to read meta file: %w", err)
+	}
+	var meta BackupMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse meta file: %w", err)
+	}
+
+	meta.Pinned = pinned
+	data, _ = json.MarshalIndent(meta, "", "  ")
+	if err := os.WriteFile(metaPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write meta file: %w", err)
+	}
+	backupLogger.Infof("Backup %s/%s pinned=%v", serverName, filename, pinned)
+	return &meta, nil
+}
+
+// BackupRetentionSetRequest sets (or with a nil policy, resets) one server's policy
+type BackupRetentionSetRequest struct {
+	ServerName string           `json:"serverName"`
+	Policy     *RetentionPolicy `json:"policy"`
+}
+
+// BackupRetentionPreviewRequest previews a policy against a server's backups on disk.
+// Without a policy, the server's current policy is used.
+type BackupRetentionPreviewRequest struct {
+	ServerName string           `json:"serverName"`
+	DataPath   string           `json:"dataPath"`
+	Policy     *RetentionPolicy `json:"policy,omitempty"`
+}
+
+// BackupRetentionResponse is the backup.retention.set/get/preview response payload
+type BackupRetentionResponse struct {
+	Success    bool                `json:"success"`
+	Policy     *RetentionPolicy    `json:"policy,omitempty"`
+	Policies   []RetentionPolicy   `json:"policies,omitempty"`
+	Keep       []RetentionDecision `json:"keep,omitempty"`
+	Delete     []RetentionDecision `json:"delete,omitempty"`
+	FreedBytes int64               `json:"freedBytes,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// BackupPinRequest is the backup.pin request payload
+type BackupPinRequest struct {
+	ServerName string `json:"serverName"`
+	DataPath   string `json:"dataPath"`
+	Filename   string `json:"filename"`
+	Pinned     bool   `json:"pinned"`
+}
+
+// BackupPinResponse is the backup.pin response payload
+type BackupPinResponse struct {
+	Success bool        `json:"success"`
+	Backup  *BackupMeta `json:"backup,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// handleBackupRetentionSet handles backup.retention.set messages
+func (a *Agent) handleBackupRetentionSet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupRetentionSetRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" {
+		a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Error: "Invalid request format (serverName required)"})
+		return
+	}
+
+	if req.Policy != nil {
+		req.Policy.ServerName = req.ServerName
+		if err := req.Policy.Validate(); err != nil {
+			a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	if err := backupRetention.SetPolicy(req.ServerName, req.Policy); err != nil {
+		backupLogger.Errorf("Failed to save retention policy: %v", err)
+		a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Error: err.Error()})
+		return
+	}
+
+	policy := backupRetention.Policy(req.ServerName)
+	backupLogger.Infof("Retention for %s: last=%d hourly=%d daily=%d weekly=%d monthly=%d maxBytes=%d maxAgeDays=%d",
+		req.ServerName, policy.KeepLast, policy.KeepHourly, policy.KeepDaily, policy.KeepWeekly,
+		policy.KeepMonthly, policy.MaxTotalBytes, policy.MaxAgeDays)
+	a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Success: true, Policy: &policy})
+}
+
+// handleBackupRetentionGet handles backup.retention.get messages (serverName optional)
+func (a *Agent) handleBackupRetentionGet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req struct {
+		ServerName string `json:"serverName"`
+	}
+	json.Unmarshal(data, &req)
+
+	if req.ServerName != "" {
+		policy := backupRetention.Policy(req.ServerName)
+		a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Success: true, Policy: &policy})
+		return
+	}
+	a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Success: true, Policies: backupRetention.Policies()})
+}
+
+// handleBackupRetentionPreview handles backup.retention.preview messages
+func (a *Agent) handleBackupRetentionPreview(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupRetentionPreviewRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" || req.DataPath == "" {
+		a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Error: "Invalid request format (serverName and dataPath required)"})
+		return
+	}
+
+	policy := backupRetention.Policy(req.ServerName)
+	if req.Policy != nil {
+		policy = *req.Policy
+		policy.ServerName = req.ServerName
+		if err := policy.Validate(); err != nil {
+			a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	backups, err := ListBackups(req.ServerName, req.DataPath)
+	if err != nil {
+		a.sendBackupRetentionResponse(msg.Reply, BackupRetentionResponse{Error: err.Error()})
+		return
+	}
+
+	usage := loadBackupUsage(filepath.Join(req.DataPath, req.ServerName, "backups"), backups)
+	resp := BackupRetentionResponse{Success: true, Policy: &policy, Keep: []RetentionDecision{}, Delete: []RetentionDecision{}}
+	var kept []BackupMeta
+	for i, d := range ApplyRetention(policy, backups, time.Now(), usage) {
+		if d.Keep {
+			resp.Keep = append(resp.Keep, d)
+			kept = append(kept, backups[i])
+		} else {
+			resp.Delete = append(resp.Delete, d)
+		}
+	}
+	// Chunks still referenced by a kept snapshot aren't freed
+	resp.FreedBytes = usage.Total(backups) - usage.Total(kept)
+	a.sendBackupRetentionResponse(msg.Reply, resp)
+}
+
+// handleBackupPin handles backup.pin messages
+func (a *Agent) handleBackupPin(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupPinRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" || req.DataPath == "" || req.Filename == "" {
+		a.sendBackupPinResponse(msg.Reply, BackupPinResponse{Error: "Invalid request format (serverName, dataPath and filename required)"})
+		return
+	}
+
+	meta, err := SetBackupPinned(req.ServerName, req.DataPath, req.Filename, req.Pinned)
+	if err != nil {
+		a.sendBackupPinResponse(msg.Reply, BackupPinResponse{Error: err.Error()})
+		return
+	}
+	a.sendBackupPinResponse(msg.Reply, BackupPinResponse{Success: true, Backup: meta})
+}
+
+// sendBackupRetentionResponse replies to a backup retention request
+func (a *Agent) sendBackupRetentionResponse(replyTo string, resp BackupRetentionResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// sendBackupPinResponse replies to a backup.pin request
+func (a *Agent) sendBackupPinResponse(replyTo string, resp BackupPinResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyRetentionMaxTotalBytesCountsSharedChunks(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	// The oldest snapshot, which added the shared world chunk, was pruned long ago: the
+	// remaining snapshots each recorded only the 110 bytes they added
+	backups := []BackupMeta{
+		{Filename: "c.snapshot", CreatedAt: now.Add(-1 * time.Hour).Unix(), SizeBytes: 110},
+		{Filename: "b.snapshot", CreatedAt: now.Add(-2 * time.Hour).Unix(), SizeBytes: 110},
+		{Filename: "a.tar.gz", CreatedAt: now.Add(-3 * time.Hour).Unix(), SizeBytes: 50},
+	}
+	sizes := map[string]int64{"world": 1000, "c-delta": 100, "b-delta": 100}
+	usage := newBackupUsage()
+	for _, s := range []struct{ filename, delta string }{{"c.snapshot", "c-delta"}, {"b.snapshot", "b-delta"}} {
+		manifest := &SnapshotManifest{Files: []SnapshotFile{
+			{Path: "map.bin", Chunks: []string{"world", s.delta}},
+			{Path: "copy.bin", Chunks: []string{"world"}},
+		}}
+		usage.addSnapshot(s.filename, 10, manifest, func(hash string) int64 { return sizes[hash] })
+	}
+
+	if got := usage.Total(backups); got != 1000+2*110+50 {
+		t.Errorf("Total = %d, want 1270", got)
+	}
+
+	policy := RetentionPolicy{ServerName: "survival", MaxTotalBytes: 1200}
+	// By recorded sizes everything fits
+	for _, d := range ApplyRetention(policy, backups, now, nil) {
+		if !d.Keep {
+			t.Errorf("recorded sizes: %s pruned", d.Filename)
+		}
+	}
+
+	// By repository usage the newest snapshot alone holds 1110 bytes
+	decisions := ApplyRetention(policy, backups, now, usage)
+	keep := map[string]bool{}
+	for _, d := range decisions {
+		keep[d.Filename] = d.Keep
+	}
+	if !keep["c.snapshot"] || keep["b.snapshot"] || !keep["a.tar.gz"] {
+		t.Errorf("decisions = %+v, want b.snapshot pruned", decisions)
+	}
+	if !strings.HasPrefix(decisions[1].Reasons[0], "over total size limit") {
+		t.Errorf("reasons = %v", decisions[1].Reasons)
+	}
+}
+
+func TestLoadBackupUsageMatchesRepository(t *testing.T) {
+	dataPath := t.TempDir()
+	world := strings.Repeat("0123456789abcdef", 64<<10) // 1 MiB, several chunks
+	writeTree(t, dataPath, map[string]string{"survival/data/Saves/map.bin": world})
+
+	create := func(id string) {
+		if _, err := CreateBackup("survival", dataPath, id, id, false, BackupFormatChunked, "", 0, "", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(1100 * time.Millisecond) // Snapshot filenames have second resolution
+	}
+	create("first")
+	writeTree(t, dataPath, map[string]string{"survival/data/Saves/players.db": "one player"})
+	create("second")
+
+	backups, err := ListBackups("survival", dataPath)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("backups = %+v, %v", backups, err)
+	}
+	backupsDir := filepath.Join(dataPath, "survival", "backups")
+
+	// What the snapshots occupy: their manifests and the chunks in the repository
+	var onDisk int64
+	filepath.Walk(backupsDir, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && (isSnapshot(info.Name()) || strings.Contains(p, string(filepath.Separator)+"chunks"+string(filepath.Separator))) {
+			onDisk += info.Size()
+		}
+		return nil
+	})
+
+	usage := loadBackupUsage(backupsDir, backups)
+	if got := usage.Total(backups); got != onDisk {
+		t.Errorf("Total = %d, repository holds %d", got, onDisk)
+	}
+	// The newer snapshot alone needs the world chunks the first one added
+	if newest := usage.Total(backups[:1]); newest <= backups[0].SizeBytes || newest >= onDisk {
+		t.Errorf("newest snapshot occupies %d (recorded %d, all %d)", newest, backups[0].SizeBytes, onDisk)
+	}
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3MinPartSize  = 16 * 1024 * 1024 // Files up to this size are sent in one PUT
+	s3MaxParts     = 10000
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" // SHA-256 of ""
+)
+
+// s3BackupTarget stores backups in an S3-compatible bucket (AWS, MinIO, Backblaze B2, R2, ...),
+// signing requests with AWS Signature Version 4
+type s3BackupTarget struct {
+	endpoint      *url.URL
+	region        string
+	bucket        string
+	prefix        string
+	accessKey     string
+	secretKey     string
+	virtualHosted bool
+	client        *http.Client
+}
+
+func newS3BackupTarget(config BackupTargetConfig) (*s3BackupTarget, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 target needs endpoint, bucket, accessKeyId and secretAccessKey")
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", config.Endpoint)
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	prefix := strings.Trim(config.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 2 * time.Minute
+	return &s3BackupTarget{
+		endpoint:      endpoint,
+		region:        region,
+		bucket:        config.Bucket,
+		prefix:        prefix,
+		accessKey:     config.AccessKeyID,
+		secretKey:     config.SecretAccessKey,
+		virtualHosted: config.VirtualHosted,
+		client:        &http.Client{Transport: transport},
+	}, nil
+}
+
+// s3Error is the XML error body S3 returns
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// request builds a signed request for an object key ("" for the bucket itself)
+func (t *s3BackupTarget) request(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Request, error) {
+	u := *t.endpoint
+	p := strings.TrimSuffix(u.Path, "/")
+	if t.virtualHosted {
+		u.Host = t.bucket + "." + u.Host
+	} else {
+		p += "/" + s3Escape(t.bucket, false)
+	}
+	if key != "" {
+		p += "/" + s3Escape(key, true)
+	}
+	if p == "" {
+		p = "/"
+	}
+	u.RawPath = p
+	u.Path, _ = url.PathUnescape(p)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if payloadHash == "" {
+		payloadHash = s3EmptyPayload
+	}
+	t.sign(req, p, payloadHash, time.Now().UTC())
+	return req, nil
+}
+
+// sign adds SigV4 headers. canonicalURI is the escaped path exactly as sent.
+func (t *s3BackupTarget) sign(req *http.Request, canonicalURI, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + t.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+t.secretKey), day)
+	key = hmacSHA256(key, t.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+t.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape URI-encodes like SigV4 expects: everything but unreserved characters (and "/" in keys)
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3CanonicalQuery encodes a query sorted by key, as both sent and signed
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// do sends a request and returns the response body, or an error for non-2xx statuses
+func (t *s3BackupTarget) do(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16*1024*1024))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return resp, body, s3ResponseError(resp.StatusCode, body)
+	}
+	return resp, body, nil
+}
+
+func s3ResponseError(status int, body []byte) error {
+	var e s3Error
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("s3: %s: %s (HTTP %d)", e.Code, e.Message, status)
+	}
+	return fmt.Errorf("s3: HTTP %d", status)
+}
+
+// Put uploads a file in one request, or as a multipart upload that resumes a previous
+// unfinished upload of the same key: parts already there are kept if their ETag matches
+func (t *s3BackupTarget) Put(ctx context.Context, key, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size <= s3MinPartSize {
+		_, err := t.putPart(ctx, t.prefix+key, nil, io.NewSectionReader(f, 0, size))
+		return err
+	}
+
+	// Part size depends only on the file size, so a resumed upload splits it the same way
+	partSize := int64(s3MinPartSize)
+	for (size+partSize-1)/partSize > s3MaxParts {
+		partSize *= 2
+	}
+	parts := int((size + partSize - 1) / partSize)
+
+	uploadID, existing, err := t.findUpload(ctx, t.prefix+key)
+	if err != nil {
+		return err
+	}
+	if uploadID == "" {
+		if uploadID, err = t.createUpload(ctx, t.prefix+key); err != nil {
+			return err
+		}
+	} else {
+		backupLogger.Infof("Resuming upload of %s (%d of %d parts already uploaded)", key, len(existing), parts)
+	}
+
+	etags := make([]string, parts)
+	for i := 0; i < parts; i++ {
+		number := i + 1
+		section := io.NewSectionReader(f, int64(i)*partSize, min(partSize, size-int64(i)*partSize))
+		if part, ok := existing[number]; ok && part.Size == section.Size() {
+			h := md5.New()
+			if _, err := io.Copy(h, section); err != nil {
+				return err
+			}
+			if hex.EncodeToString(h.Sum(nil)) == strings.Trim(part.ETag, `"`) {
+				etags[i] = part.ETag
+				continue
+			}
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		etag, err := t.putPart(ctx, t.prefix+key, query, section)
+		if err != nil {
+			return fmt.Errorf("part %d/%d: %w", number, parts, err)
+		}
+		etags[i] = etag
+	}
+	return t.completeUpload(ctx, t.prefix+key, uploadID, etags)
+}
+
+// putPart uploads a whole object (query nil) or one part of a multipart upload and returns its ETag
+func (t *s3BackupTarget) putPart(ctx context.Context, objectKey string, query url.Values, section *io.SectionReader) (string, error) {
+	// The payload is read twice: once to hash it for the signature, once to send it
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(section, 0, section.Size())); err != nil {
+		return "", err
+	}
+	body := io.NewSectionReader(section, 0, section.Size())
+	req, err := t.request(ctx, http.MethodPut, objectKey, query, body, section.Size(), hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return "", err
+	}
+	resp, _, err := t.do(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// s3Part is an uploaded part of a multipart upload
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+	Size       int64  `xml:"Size"`
+}
+
+// findUpload returns the newest unfinished multipart upload of a key and its parts
+func (t *s3BackupTarget) findUpload(ctx context.Context, objectKey string) (string, map[int]s3Part, error) {
+	req, err := t.request(ctx, http.MethodGet, "", url.Values{"uploads": {""}, "prefix": {objectKey}}, nil, 0, "")
+	if err != nil {
+		return "", nil, err
+	}
+	_, body, err := t.do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+	}
+	var result struct {
+		Uploads []struct {
+			Key       string `xml:"Key"`
+			UploadID  string `xml:"UploadId"`
+			Initiated string `xml:"Initiated"`
+		} `xml:"Upload"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return "", nil, fmt.Errorf("invalid multipart upload listing: %w", err)
+	}
+	uploadID, initiated := "", ""
+	for _, u := range result.Uploads {
+		if u.Key == objectKey && u.Initiated >= initiated {
+			uploadID, initiated = u.UploadID, u.Initiated
+		}
+	}
+	if uploadID == "" {
+		return "", nil, nil
+	}
+
+	parts := make(map[int]s3Part)
+	marker := ""
+	for {
+		query := url.Values{"uploadId": {uploadID}}
+		if marker != "" {
+			query.Set("part-number-marker", marker)
+		}
+		req, err := t.request(ctx, http.MethodGet, objectKey, query, nil, 0, "")
+		if err != nil {
+			return "", nil, err
+		}
+		_, body, err := t.do(req)
+		if err != nil {
+			// The upload may have been completed or aborted meanwhile; start over
+			return "", nil, nil
+		}
+		var listing struct {
+			Parts                []s3Part `xml:"Part"`
+			IsTruncated          bool     `xml:"IsTruncated"`
+			NextPartNumberMarker string   `xml:"NextPartNumberMarker"`
+		}
+		if err := xml.Unmarshal(body, &listing); err != nil {
+			return "", nil, fmt.Errorf("invalid part listing: %w", err)
+		}
+		for _, p := range listing.Parts {
+			parts[p.PartNumber] = p
+		}
+		if !listing.IsTruncated || listing.NextPartNumberMarker == "" {
+			break
+		}
+		marker = listing.NextPartNumberMarker
+	}
+	return uploadID, parts, nil
+}
+
+func (t *s3BackupTarget) createUpload(ctx context.Context, objectKey string) (string, error) {
+	req, err := t.request(ctx, http.MethodPost, objectKey, url.Values{"uploads": {""}}, nil, 0, "")
+	if err != nil {
+		return "", err
+	}
+	_, body, err := t.do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("invalid multipart upload response")
+	}
+	return result.UploadID, nil
+}
+
+func (t *s3BackupTarget) completeUpload(ctx context.Context, objectKey, uploadID string, etags []string) error {
+	type completePart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	complete := struct {
+		XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+		Parts   []completePart `xml:"Part"`
+	}{}
+	for i, etag := range etags {
+		complete.Parts = append(complete.Parts, completePart{PartNumber: i + 1, ETag: etag})
+	}
+	payload, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(payload)
+	req, err := t.request(ctx, http.MethodPost, objectKey, url.Values{"uploadId": {uploadID}},
+		bytes.NewReader(payload), int64(len(payload)), hex.EncodeToString(hash[:]))
+	if err != nil {
+		return err
+	}
+	resp, body, err := t.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	// S3 can report a failed completion in a 200 response
+	if bytes.Contains(body, []byte("<Error>")) {
+		return fmt.Errorf("failed to complete multipart upload: %w", s3ResponseError(resp.StatusCode, body))
+	}
+	return nil
+}
+
+func (t *s3BackupTarget) Get(ctx context.Context, key, localPath string) error {
+	req, err := t.request(ctx, http.MethodGet, t.prefix+key, nil, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return s3ResponseError(resp.StatusCode, body)
+	}
+	return getFileAtomic(localPath, resp.Body)
+}
+
+// List pages through ListObjectsV2
+func (t *s3BackupTarget) List(ctx context.Context, prefix string) ([]RemoteObject, error) {
+	var objects []RemoteObject
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {t.prefix + prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := t.request(ctx, http.MethodGet, "", query, nil, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		_, body, err := t.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var listing struct {
+			Contents []struct {
+				Key  string `xml:"Key"`
+				Size int64  `xml:"Size"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &listing); err != nil {
+			return nil, fmt.Errorf("invalid object listing: %w", err)
+		}
+		for _, c := range listing.Contents {
+			objects = append(objects, RemoteObject{Key: strings.TrimPrefix(c.Key, t.prefix), Size: c.Size})
+		}
+		if !listing.IsTruncated || listing.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = listing.NextContinuationToken
+	}
+}
+
+func (t *s3BackupTarget) Delete(ctx context.Context, key string) error {
+	req, err := t.request(ctx, http.MethodDelete, t.prefix+key, nil, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	resp, _, err := t.do(req)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (t *s3BackupTarget) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+func (t *s3BackupTarget) String() string {
+	return "s3://" + t.bucket + "/" + t.prefix
+}
@@ -0,0 +1,661 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	_ "time/tzdata" // Schedules name IANA zones; don't depend on the host's zoneinfo
+
+	"github.com/google/uuid"
+)
+
+const (
+	backupSchedulesFile     = "backup-schedules.json"
+	backupScheduleStateFile = "backup-schedule-state.json"
+
+	backupScheduleTick       = 30 * time.Second
+	defaultBackupJitter      = 5 * time.Minute
+	maxPendingBackupResults  = 200 // Oldest results are dropped beyond this while offline
+	defaultBackupNotesFormat = "scheduled"
+)
+
+// Scheduled backup result statuses
+const (
+	ScheduledBackupSuccess = "success"
+	ScheduledBackupFailed  = "failed"
+	ScheduledBackupSkipped = "skipped"
+)
+
+// BackupSchedule runs backups of one server on a cron schedule, independently of the manager
+type BackupSchedule struct {
+	ID              string `json:"id"`
+	ServerID        string `json:"serverId"`
+	ServerName      string `json:"serverName"`              // Fallback; runs use the container's current name
+	DataPath        string `json:"dataPath"`                // Fallback; runs use the container's current data path
+	Cron            string `json:"cron"`                    // 5-field cron expression or @daily, @hourly, ...
+	Timezone        string `json:"timezone,omitempty"`      // IANA zone the cron is evaluated in (default UTC)
+	Enabled         bool   `json:"enabled"`                 // Disabled schedules are kept but never run
+	NotesTemplate   string `json:"notesTemplate,omitempty"` // text/template for backup notes (.ServerName, .Time, .Cron, .ScheduleID)
+	JitterSeconds   *int   `json:"jitterSeconds,omitempty"` // Max delay after the cron time (default 300, 0 disables)
+	SkipIfNoPlayers bool   `json:"skipIfNoPlayers"`         // Skip when nobody played since the last backup
+}
+
+// BackupScheduleStatus is a schedule with its run history, returned by backup.schedule.get
+type BackupScheduleStatus struct {
+	BackupSchedule
+	NextRun    int64 `json:"nextRun,omitempty"`    // Unix seconds, including jitter
+	LastRun    int64 `json:"lastRun,omitempty"`    // Unix seconds of the last handled cron time
+	LastBackup int64 `json:"lastBackup,omitempty"` // Unix seconds of the last successful scheduled backup
+}
+
+// ScheduledBackupResult is the backup.scheduled event payload
+type ScheduledBackupResult struct {
+	ScheduleID     string   `json:"scheduleId"`
+	ServerID       string   `json:"serverId"`
+	ServerName     string   `json:"serverName"`
+	BackupID       string   `json:"backupId,omitempty"`
+	Status         string   `json:"status"` // success, failed, skipped
+	Filename       string   `json:"filename,omitempty"`
+	SizeBytes      int64    `json:"sizeBytes,omitempty"`
+	PreSaveSuccess bool     `json:"preSaveSuccess,omitempty"`
+	Pruned         []string `json:"pruned,omitempty"` // Older backups deleted by retention
+	Notes          string   `json:"notes,omitempty"`
+	Reason         string   `json:"reason,omitempty"` // Why the run was skipped
+	Error          string   `json:"error,omitempty"`
+	ScheduledAt    int64    `json:"scheduledAt"` // Unix seconds (cron time, before jitter)
+	StartedAt      int64    `json:"startedAt"`   // Unix seconds
+	FinishedAt     int64    `json:"finishedAt"`  // Unix seconds
+}
+
+// BackupScheduleSetRequest replaces all schedules of one server
+type BackupScheduleSetRequest struct {
+	ServerID  string           `json:"serverId"`
+	Schedules []BackupSchedule `json:"schedules"`
+}
+
+// BackupScheduleResponse is the backup.schedule.set/get response payload
+type BackupScheduleResponse struct {
+	Success   bool                   `json:"success"`
+	Schedules []BackupScheduleStatus `json:"schedules,omitempty"`
+	Pending   int                    `json:"pending"` // Results not yet delivered to the manager
+	Error     string                 `json:"error,omitempty"`
+}
+
+// backupScheduleRun is the persisted run history of one schedule
+type backupScheduleRun struct {
+	LastRun    int64 `json:"lastRun"`
+	LastBackup int64 `json:"lastBackup,omitempty"`
+}
+
+// backupScheduleState is persisted so missed runs and undelivered results survive restarts
+type backupScheduleState struct {
+	Runs    map[string]*backupScheduleRun `json:"runs"` // scheduleID -> history
+	Pending []ScheduledBackupResult       `json:"pending"`
+}
+
+// scheduledBackup is a loaded schedule with its parsed cron and next due time
+type scheduledBackup struct {
+	BackupSchedule
+	cron  *CronSchedule
+	notes *template.Template
+	slot  time.Time // Next cron time to run
+}
+
+// due returns when the next run starts (cron time plus jitter)
+func (s *scheduledBackup) due() time.Time {
+	return s.slot.Add(s.jitter())
+}
+
+// jitter is a stable per-schedule offset, so servers sharing a cron expression don't
+// compress at the same moment and restarts don't move a schedule's run time
+func (s *scheduledBackup) jitter() time.Duration {
+	spread := defaultBackupJitter
+	if s.JitterSeconds != nil {
+		spread = time.Duration(*s.JitterSeconds) * time.Second
+	}
+	if spread <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(s.ServerID + "/" + s.ID))
+	return time.Duration(h.Sum64()%uint64(spread/time.Second)) * time.Second
+}
+
+// BackupScheduler runs scheduled backups and queues their results for the manager
+type BackupScheduler struct {
+	mu        sync.Mutex
+	agent     *Agent
+	schedules map[string]*scheduledBackup // scheduleID -> schedule
+	state     backupScheduleState
+	dir       string
+	now       func() time.Time
+	stopCh    chan struct{}
+
+	// locate returns a server's current name and data path (nil without Docker)
+	locate func(serverID string) (serverName, dataPath string, err error)
+}
+
+// NewBackupScheduler creates a scheduler that backs up servers through the agent
+func NewBackupScheduler(agent *Agent) *BackupScheduler {
+	bs := &BackupScheduler{
+		agent:     agent,
+		schedules: make(map[string]*scheduledBackup),
+		state:     backupScheduleState{Runs: make(map[string]*backupScheduleRun)},
+		dir:       StateDir(),
+		now:       time.Now,
+		stopCh:    make(chan struct{}),
+	}
+	if agent != nil && agent.docker != nil {
+		bs.locate = func(serverID string) (string, string, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return agent.docker.ServerLocation(ctx, serverID)
+		}
+	}
+	return bs
+}
+
+// Start loads persisted schedules and run history and starts the scheduling loop.
+// A run missed while the agent was down is caught up once.
+func (bs *BackupScheduler) Start() {
+	bs.mu.Lock()
+	var schedules []BackupSchedule
+	if data, err := os.ReadFile(filepath.Join(bs.dir, backupSchedulesFile)); err == nil {
+		if err := json.Unmarshal(data, &schedules); err != nil {
+			backupLogger.Warnf("Failed to parse backup schedules: %v", err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(bs.dir, backupScheduleStateFile)); err == nil {
+		if err := json.Unmarshal(data, &bs.state); err != nil {
+			backupLogger.Warnf("Failed to parse backup schedule state: %v", err)
+		}
+		if bs.state.Runs == nil {
+			bs.state.Runs = make(map[string]*backupScheduleRun)
+		}
+	}
+
+	now := bs.now()
+	for _, schedule := range schedules {
+		s, err := compileBackupSchedule(schedule)
+		if err != nil {
+			backupLogger.Warnf("Ignoring backup schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		s.slot = bs.firstSlot(s, now)
+		bs.schedules[s.ID] = s
+	}
+	backupLogger.Infof("Loaded %d backup schedule(s), %d undelivered result(s)", len(bs.schedules), len(bs.state.Pending))
+	bs.mu.Unlock()
+
+	go bs.loop()
+}
+
+// Stop stops the scheduling loop. A backup in progress runs to completion.
+func (bs *BackupScheduler) Stop() {
+	close(bs.stopCh)
+}
+
+// firstSlot picks the first cron time to run after (re)loading a schedule: the run
+// missed since the last handled one if any, otherwise the next one from now
+func (bs *BackupScheduler) firstSlot(s *scheduledBackup, now time.Time) time.Time {
+	if run, ok := bs.state.Runs[s.ID]; ok && run.LastRun > 0 {
+		if missed := s.cron.Next(time.Unix(run.LastRun, 0)); !missed.IsZero() && missed.Before(now) {
+			return missed
+		}
+	}
+	return s.cron.Next(now)
+}
+
+// loop checks for due schedules and delivers queued results
+func (bs *BackupScheduler) loop() {
+	ticker := time.NewTicker(backupScheduleTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bs.stopCh:
+			return
+		case <-ticker.C:
+			bs.runDue()
+			bs.FlushResults()
+		}
+	}
+}
+
+// runDue runs every due schedule, one backup at a time
+func (bs *BackupScheduler) runDue() {
+	for {
+		select {
+		case <-bs.stopCh:
+			return
+		default:
+		}
+
+		bs.mu.Lock()
+		s := bs.nextDueLocked(bs.now())
+		var schedule scheduledBackup
+		if s != nil {
+			schedule = *s
+		}
+		bs.mu.Unlock()
+		if s == nil {
+			return
+		}
+
+		result := bs.run(&schedule)
+
+		bs.mu.Lock()
+		if current, ok := bs.schedules[schedule.ID]; ok {
+			run := bs.state.Runs[schedule.ID]
+			if run == nil {
+				run = &backupScheduleRun{}
+				bs.state.Runs[schedule.ID] = run
+			}
+			run.LastRun = schedule.slot.Unix()
+			if result.Status == ScheduledBackupSuccess {
+				run.LastBackup = result.FinishedAt
+			}
+			// Cron times that passed during a long backup are not run again
+			if current.slot.Equal(schedule.slot) {
+				after := bs.now()
+				if schedule.slot.After(after) {
+					after = schedule.slot
+				}
+				current.slot = current.cron.Next(after)
+			}
+		}
+		bs.queueResultLocked(result)
+		bs.mu.Unlock()
+	}
+}
+
+// nextDueLocked returns the enabled schedule that has been due the longest. Must be called with bs.mu held.
+func (bs *BackupScheduler) nextDueLocked(now time.Time) *scheduledBackup {
+	var next *scheduledBackup
+	for _, s := range bs.schedules {
+		if !s.Enabled || s.slot.IsZero() || s.due().After(now) {
+			continue
+		}
+		if next == nil || s.due().Before(next.due()) {
+			next = s
+		}
+	}
+	return next
+}
+
+// resolveServer updates a schedule's server name and data path from the server's container:
+// the manager's copies go stale when the server is renamed or its data moved
+func (bs *BackupScheduler) resolveServer(s *scheduledBackup) {
+	if bs.locate == nil {
+		return
+	}
+	name, dataPath, err := bs.locate(s.ServerID)
+	if err != nil {
+		backupLogger.Debugf("Backup schedule %s: using the saved server location: %v", s.ID, err)
+		return
+	}
+	if name != "" {
+		s.ServerName = name
+	}
+	if dataPath != "" {
+		s.DataPath = dataPath
+	}
+}
+
+// run executes one scheduled backup. s is a copy, updated with the server's current location.
+func (bs *BackupScheduler) run(s *scheduledBackup) ScheduledBackupResult {
+	bs.resolveServer(s)
+	started := bs.now()
+	result := ScheduledBackupResult{
+		ScheduleID:  s.ID,
+		ServerID:    s.ServerID,
+		ServerName:  s.ServerName,
+		ScheduledAt: s.slot.Unix(),
+		StartedAt:   started.Unix(),
+	}
+	finish := func(status string) ScheduledBackupResult {
+		result.Status = status
+		result.FinishedAt = bs.now().Unix()
+		return result
+	}
+
+	unlock, err := tryLockServerBackups(s.ServerName, s.DataPath)
+	if err != nil {
+		backupLogger.Infof("Skipping scheduled backup of %s: %v", s.ServerName, err)
+		result.Reason = err.Error()
+		return finish(ScheduledBackupSkipped)
+	}
+	defer unlock()
+
+	if s.SkipIfNoPlayers {
+		if reason := bs.skipReason(s); reason != "" {
+			backupLogger.Infof("Skipping scheduled backup of %s: %s", s.ServerName, reason)
+			result.Reason = reason
+			return finish(ScheduledBackupSkipped)
+		}
+	}
+
+	notes, err := renderBackupNotes(s, s.slot)
+	if err != nil {
+		backupLogger.Warnf("Backup schedule %s: %v", s.ID, err)
+		notes = defaultBackupNotesFormat
+	}
+	result.Notes = notes
+	result.BackupID = uuid.New().String()
+
+	// Pre-save through RCON when the server is running
+	var containerID, rconPassword string
+	var rconPort int
+	if bs.agent.playerStats != nil {
+		if configs, err := bs.agent.playerStats.discoverServers(); err == nil {
+			for _, config := range configs {
+				if config.ServerID == s.ServerID {
+					containerID, rconPort, rconPassword = config.ContainerID, config.RCONPort, config.RCONPassword
+					break
+				}
+			}
+		}
+	}
+
+	backupLogger.Infof("Running scheduled backup of %s (schedule %s, ID: %s)", s.ServerName, s.ID, result.BackupID)
+	progressFn := func(progress BackupProgress) {
+		if bs.agent.IsAuthenticated() {
+			bs.agent.sendMessage(NewMessage("backup.progress", progress))
+		}
+	}
+
+	resp, err := CreateBackup(
+		s.ServerName, s.DataPath, result.BackupID, notes, false, "",
+		containerID, rconPort, rconPassword,
+		bs.agent.rconManager, progressFn,
+	)
+	agentStats.BackupFinished("create", bs.now().Sub(started), err)
+
+	if err != nil {
+		backupLogger.Error("Scheduled backup failed", "server", s.ServerName, "schedule", s.ID, "error", err)
+		progressFn(BackupProgress{BackupID: result.BackupID, ServerName: s.ServerName, Phase: "error", Error: err.Error()})
+		result.Error = err.Error()
+		return finish(ScheduledBackupFailed)
+	}
+
+	result.Filename = resp.Filename
+	result.SizeBytes = resp.SizeBytes
+	result.PreSaveSuccess = resp.PreSaveSuccess
+	result.Pruned = resp.Pruned
+	if bs.agent.backupUploader != nil {
+		bs.agent.backupUploader.Trigger(s.ServerName)
+	}
+	return finish(ScheduledBackupSuccess)
+}
+
+// skipReason returns why a skip-if-no-players schedule shouldn't back up, or "" to back up.
+// Activity is measured since the newest backup on disk, including manual ones.
+func (bs *BackupScheduler) skipReason(s *scheduledBackup) string {
+	if bs.agent.playerStats == nil {
+		return ""
+	}
+	backups, err := ListBackups(s.ServerName, s.DataPath)
+	if err != nil {
+		return ""
+	}
+	var last int64
+	for _, b := range backups {
+		if b.CreatedAt > last {
+			last = b.CreatedAt
+		}
+	}
+	if last == 0 {
+		return "" // Never backed up
+	}
+
+	sessions, _ := bs.agent.playerStats.sessions.Query(PlayerHistoryRequest{
+		ServerID: s.ServerID,
+		Since:    last,
+		Limit:    1,
+	}, bs.now())
+	if len(sessions) > 0 {
+		return ""
+	}
+	return fmt.Sprintf("no players since the last backup (%s)", time.Unix(last, 0).UTC().Format(time.RFC3339))
+}
+
+// queueResultLocked queues a result for the manager and persists run state. Must be called with bs.mu held.
+func (bs *BackupScheduler) queueResultLocked(result ScheduledBackupResult) {
+	bs.state.Pending = append(bs.state.Pending, result)
+	if over := len(bs.state.Pending) - maxPendingBackupResults; over > 0 {
+		bs.state.Pending = bs.state.Pending[over:]
+	}
+	bs.saveStateLocked()
+}
+
+// FlushResults sends queued results to the manager while connected
+func (bs *BackupScheduler) FlushResults() {
+	if bs.agent == nil || !bs.agent.IsAuthenticated() {
+		return
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	sent := 0
+	for _, result := range bs.state.Pending {
+		if err := bs.agent.sendMessage(NewMessage("backup.scheduled", result)); err != nil {
+			backupLogger.Warnf("Failed to report scheduled backup result: %v", err)
+			break
+		}
+		sent++
+	}
+	if sent == 0 {
+		return
+	}
+	bs.state.Pending = bs.state.Pending[sent:]
+	bs.saveStateLocked()
+	backupLogger.Infof("Reported %d scheduled backup result(s)", sent)
+}
+
+// SetSchedules replaces the schedules of one server and persists all schedules
+func (bs *BackupScheduler) SetSchedules(serverID string, schedules []BackupSchedule) error {
+	compiled := make([]*scheduledBackup, 0, len(schedules))
+	seen := make(map[string]bool)
+	for _, schedule := range schedules {
+		schedule.ServerID = serverID
+		if schedule.ID == "" {
+			return fmt.Errorf("schedule id is required")
+		}
+		if seen[schedule.ID] {
+			return fmt.Errorf("duplicate schedule id %q", schedule.ID)
+		}
+		seen[schedule.ID] = true
+		s, err := compileBackupSchedule(schedule)
+		if err != nil {
+			return fmt.Errorf("schedule %s: %w", schedule.ID, err)
+		}
+		compiled = append(compiled, s)
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	for id := range seen {
+		if existing, ok := bs.schedules[id]; ok && existing.ServerID != serverID {
+			return fmt.Errorf("schedule id %q belongs to another server", id)
+		}
+	}
+	for id, s := range bs.schedules {
+		if s.ServerID == serverID && !seen[id] {
+			delete(bs.schedules, id)
+			delete(bs.state.Runs, id)
+		}
+	}
+
+	now := bs.now()
+	for _, s := range compiled {
+		// Keep the pending cron time when only unrelated fields changed
+		existing, ok := bs.schedules[s.ID]
+		if ok && existing.Enabled && existing.Cron == s.Cron && existing.Timezone == s.Timezone {
+			s.slot = existing.slot
+		} else {
+			s.slot = s.cron.Next(now)
+		}
+		bs.schedules[s.ID] = s
+	}
+
+	bs.saveStateLocked()
+	return bs.saveSchedulesLocked()
+}
+
+// Snapshot returns all schedules with their run history and the number of undelivered results
+func (bs *BackupScheduler) Snapshot(serverID string) ([]BackupScheduleStatus, int) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	statuses := make([]BackupScheduleStatus, 0, len(bs.schedules))
+	for _, s := range bs.schedules {
+		if serverID != "" && s.ServerID != serverID {
+			continue
+		}
+		status := BackupScheduleStatus{BackupSchedule: s.BackupSchedule}
+		if s.Enabled && !s.slot.IsZero() {
+			status.NextRun = s.due().Unix()
+		}
+		if run, ok := bs.state.Runs[s.ID]; ok {
+			status.LastRun = run.LastRun
+			status.LastBackup = run.LastBackup
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].ServerID != statuses[j].ServerID {
+			return statuses[i].ServerID < statuses[j].ServerID
+		}
+		return statuses[i].ID < statuses[j].ID
+	})
+	return statuses, len(bs.state.Pending)
+}
+
+// saveSchedulesLocked persists schedule definitions. Must be called with bs.mu held.
+func (bs *BackupScheduler) saveSchedulesLocked() error {
+	schedules := make([]BackupSchedule, 0, len(bs.schedules))
+	for _, s := range bs.schedules {
+		schedules = append(schedules, s.BackupSchedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+
+	if err := ensureStateDir(); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(bs.dir, backupSchedulesFile), schedules)
+}
+
+// saveStateLocked persists run history and queued results. Must be called with bs.mu held.
+func (bs *BackupScheduler) saveStateLocked() {
+	if err := ensureStateDir(); err != nil {
+		backupLogger.Warnf("%v", err)
+		return
+	}
+	if err := writeFileAtomic(filepath.Join(bs.dir, backupScheduleStateFile), bs.state); err != nil {
+		backupLogger.Warnf("Failed to save backup schedule state: %v", err)
+	}
+}
+
+// compileBackupSchedule validates a schedule and parses its cron expression and notes template
+func compileBackupSchedule(schedule BackupSchedule) (*scheduledBackup, error) {
+	if schedule.ServerID == "" || schedule.ServerName == "" || schedule.DataPath == "" {
+		return nil, fmt.Errorf("serverId, serverName and dataPath are required")
+	}
+	if schedule.JitterSeconds != nil && *schedule.JitterSeconds < 0 {
+		return nil, fmt.Errorf("jitterSeconds must not be negative")
+	}
+	cron, err := ParseCron(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	s := &scheduledBackup{BackupSchedule: schedule, cron: cron}
+	if strings.TrimSpace(schedule.NotesTemplate) != "" {
+		if s.notes, err = template.New("notes").Option("missingkey=error").Parse(schedule.NotesTemplate); err != nil {
+			return nil, fmt.Errorf("invalid notes template: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// renderBackupNotes renders a schedule's notes template for a cron time
+func renderBackupNotes(s *scheduledBackup, slot time.Time) (string, error) {
+	if s.notes == nil {
+		return defaultBackupNotesFormat, nil
+	}
+	var b strings.Builder
+	err := s.notes.Execute(&b, struct {
+		ScheduleID string
+		ServerID   string
+		ServerName string
+		Cron       string
+		Time       time.Time // Cron time in the schedule's timezone
+	}{s.ID, s.ServerID, s.ServerName, s.Cron, slot.In(s.cron.Location())})
+	if err != nil {
+		return "", fmt.Errorf("failed to render notes: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// handleBackupScheduleSet handles backup.schedule.set messages
+func (a *Agent) handleBackupScheduleSet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupScheduleSetRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerID == "" {
+		a.sendBackupScheduleResponse(msg.Reply, BackupScheduleResponse{Error: "Invalid request format (serverId required)"})
+		return
+	}
+
+	if a.backupScheduler == nil {
+		a.sendBackupScheduleResponse(msg.Reply, BackupScheduleResponse{Error: "Docker client not initialized"})
+		return
+	}
+
+	if err := a.backupScheduler.SetSchedules(req.ServerID, req.Schedules); err != nil {
+		backupLogger.Errorf("Failed to save backup schedules: %v", err)
+		a.sendBackupScheduleResponse(msg.Reply, BackupScheduleResponse{Error: err.Error()})
+		return
+	}
+
+	backupLogger.Infof("Backup schedules for %s: %d schedule(s)", req.ServerID, len(req.Schedules))
+	schedules, pending := a.backupScheduler.Snapshot(req.ServerID)
+	a.sendBackupScheduleResponse(msg.Reply, BackupScheduleResponse{Success: true, Schedules: schedules, Pending: pending})
+}
+
+// handleBackupScheduleGet handles backup.schedule.get messages (serverId optional)
+func (a *Agent) handleBackupScheduleGet(msg Message) {
+	if a.backupScheduler == nil {
+		a.sendBackupScheduleResponse(msg.Reply, BackupScheduleResponse{Error: "Docker client not initialized"})
+		return
+	}
+
+	data, _ := json.Marshal(msg.Data)
+	var req struct {
+		ServerID string `json:"serverId"`
+	}
+	json.Unmarshal(data, &req)
+
+	schedules, pending := a.backupScheduler.Snapshot(req.ServerID)
+	a.sendBackupScheduleResponse(msg.Reply, BackupScheduleResponse{Success: true, Schedules: schedules, Pending: pending})
+}
+
+// sendBackupScheduleResponse replies to a backup schedule request
+func (a *Agent) sendBackupScheduleResponse(replyTo string, resp BackupScheduleResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
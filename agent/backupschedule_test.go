@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSchedule compiles a daily schedule whose saved location predates a rename and data move
+func newTestSchedule(t *testing.T) *scheduledBackup {
+	t.Helper()
+	s, err := compileBackupSchedule(BackupSchedule{
+		ID: "nightly", ServerID: "srv-1", ServerName: "old-name", DataPath: filepath.Join(t.TempDir(), "gone"),
+		Cron: "@daily", Enabled: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.slot = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	return s
+}
+
+func TestBackupSchedulerRunsAtCurrentServerLocation(t *testing.T) {
+	dataPath := t.TempDir()
+	writeTree(t, dataPath, map[string]string{"survival/data/Saves/map.bin": "world"})
+
+	bs := NewBackupScheduler(&Agent{})
+	bs.dir = t.TempDir()
+	bs.locate = func(serverID string) (string, string, error) {
+		if serverID != "srv-1" {
+			return "", "", errors.New("unknown server")
+		}
+		return "survival", dataPath, nil
+	}
+
+	result := bs.run(newTestSchedule(t))
+	if result.Status != ScheduledBackupSuccess {
+		t.Fatalf("result = %+v", result)
+	}
+	if result.ServerName != "survival" {
+		t.Errorf("result server = %q, want the current name", result.ServerName)
+	}
+	backups, err := ListBackups("survival", dataPath)
+	if err != nil || len(backups) != 1 || backups[0].BackupID != result.BackupID {
+		t.Errorf("backups at the current location = %+v, %v", backups, err)
+	}
+}
+
+func TestBackupSchedulerSkipsWhileServerIsBusy(t *testing.T) {
+	dataPath := t.TempDir()
+	writeTree(t, dataPath, map[string]string{"survival/data/Saves/map.bin": "world"})
+
+	bs := NewBackupScheduler(&Agent{})
+	bs.dir = t.TempDir()
+	bs.locate = func(string) (string, string, error) { return "survival", dataPath, nil }
+
+	// A manual backup or restore of the same server is running
+	unlock, err := tryLockServerBackups("survival", dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := bs.run(newTestSchedule(t))
+	unlock()
+	if result.Status != ScheduledBackupSkipped || !strings.Contains(result.Reason, "in progress") {
+		t.Errorf("result = %+v, want skipped while busy", result)
+	}
+
+	if _, err := tryLockServerBackups("survival", dataPath+"/"); err != nil {
+		t.Errorf("lock not released: %v", err)
+	}
+}
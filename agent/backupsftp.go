@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const sftpDialTimeout = 15 * time.Second
+
+// sftpBackupTarget stores backups in a directory on an SSH server
+type sftpBackupTarget struct {
+	conn   *ssh.Client
+	client *sftp.Client
+	root   string
+	addr   string
+}
+
+func newSFTPBackupTarget(config BackupTargetConfig) (*sftpBackupTarget, error) {
+	if config.Host == "" || config.Username == "" || config.Path == "" {
+		return nil, fmt.Errorf("sftp target needs host, username and path")
+	}
+	if config.Password == "" && config.PrivateKey == "" {
+		return nil, fmt.Errorf("sftp target needs a password or privateKey")
+	}
+
+	var auth []ssh.AuthMethod
+	if config.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(config.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sftp privateKey: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if config.Password != "" {
+		auth = append(auth, ssh.Password(config.Password))
+	}
+
+	port := config.Port
+	if port == 0 {
+		port = 22
+	}
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(port))
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            config.Username,
+		Auth:            auth,
+		HostKeyCallback: sftpHostKeyCallback(config.HostKey),
+		Timeout:         sftpDialTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ssh connection to %s failed: %w", addr, err)
+	}
+	client, err := sftp.NewClient(conn, sftp.UseConcurrentWrites(true))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sftp session on %s failed: %w", addr, err)
+	}
+	return &sftpBackupTarget{conn: conn, client: client, root: path.Clean(config.Path), addr: addr}, nil
+}
+
+// sftpHostKeyCallback only accepts the configured host key. Without one, the connection fails
+// with the key's fingerprint so it can be checked and pasted into the target config.
+func sftpHostKeyCallback(expected string) ssh.HostKeyCallback {
+	expected = strings.TrimSpace(expected)
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		fingerprint := ssh.FingerprintSHA256(key)
+		if expected == "" {
+			return fmt.Errorf("hostKey is required; %s presented %s %s", hostname, key.Type(), fingerprint)
+		}
+		if strings.HasPrefix(expected, "SHA256:") {
+			if expected == fingerprint {
+				return nil
+			}
+		} else if want, _, _, _, err := ssh.ParseAuthorizedKey([]byte(expected)); err == nil {
+			if bytes.Equal(want.Marshal(), key.Marshal()) {
+				return nil
+			}
+		} else {
+			return fmt.Errorf("invalid hostKey: %w", err)
+		}
+		return fmt.Errorf("host key mismatch: %s presented %s %s", hostname, key.Type(), fingerprint)
+	}
+}
+
+// path maps a key below the root, rejecting keys that escape it
+func (t *sftpBackupTarget) path(key string) (string, error) {
+	p := path.Join(t.root, key)
+	if p != t.root && !strings.HasPrefix(p, strings.TrimSuffix(t.root, "/")+"/") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return p, nil
+}
+
+// Put appends to a .partial file left by an interrupted upload, then renames it into place
+func (t *sftpBackupTarget) Put(ctx context.Context, key, localPath string) error {
+	dst, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if err := t.client.MkdirAll(path.Dir(dst)); err != nil {
+		return err
+	}
+
+	partial := dst + ".partial"
+	var offset int64
+	if st, err := t.client.Stat(partial); err == nil && st.Size() <= info.Size() {
+		offset = st.Size()
+	}
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := t.client.OpenFile(partial, flags)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, &contextReader{ctx: ctx, r: io.NewSectionReader(src, offset, info.Size()-offset)}); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// posix-rename replaces an existing file; plain SFTP rename refuses to
+	if err := t.client.PosixRename(partial, dst); err != nil {
+		t.client.Remove(dst)
+		return t.client.Rename(partial, dst)
+	}
+	return nil
+}
+
+func (t *sftpBackupTarget) Get(ctx context.Context, key, localPath string) error {
+	p, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	f, err := t.client.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return getFileAtomic(localPath, &contextReader{ctx: ctx, r: f})
+}
+
+func (t *sftpBackupTarget) List(ctx context.Context, prefix string) ([]RemoteObject, error) {
+	dir, err := t.path(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var objects []RemoteObject
+	walker := t.client.Walk(dir)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			if walker.Path() == dir && errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		st := walker.Stat()
+		if st.IsDir() || strings.HasSuffix(walker.Path(), ".partial") {
+			continue
+		}
+		key := strings.TrimPrefix(walker.Path(), strings.TrimSuffix(t.root, "/")+"/")
+		objects = append(objects, RemoteObject{Key: key, Size: st.Size()})
+	}
+	return objects, nil
+}
+
+func (t *sftpBackupTarget) Delete(ctx context.Context, key string) error {
+	p, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	if err := t.client.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (t *sftpBackupTarget) Close() error {
+	t.client.Close()
+	return t.conn.Close()
+}
+
+func (t *sftpBackupTarget) String() string {
+	return "sftp://" + t.addr + t.root
+}
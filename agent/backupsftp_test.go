@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestSFTPServer serves the sftp subsystem over SSH on localhost, with password auth, and
+// returns a target config for a fresh directory on it
+func newTestSFTPServer(t *testing.T) BackupTargetConfig {
+	t.Helper()
+	hostKey := newTestSSHSigner(t)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == "backup" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("access denied")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSFTP(nc, config)
+		}
+	}()
+
+	return BackupTargetConfig{
+		Type:     BackupTargetSFTP,
+		Host:     "127.0.0.1",
+		Port:     ln.Addr().(*net.TCPAddr).Port,
+		Username: "backup",
+		Password: "secret",
+		Path:     t.TempDir(),
+		HostKey:  string(ssh.MarshalAuthorizedKey(hostKey.PublicKey())),
+	}
+}
+
+func newTestSSHSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func serveTestSFTP(nc net.Conn, config *ssh.ServerConfig) {
+	conn, channels, requests, err := ssh.NewServerConn(nc, config)
+	if err != nil {
+		nc.Close()
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				// The subsystem request's payload is the length-prefixed name
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if !ok {
+					continue
+				}
+				go func() {
+					defer channel.Close()
+					server, err := sftp.NewServer(channel)
+					if err != nil {
+						return
+					}
+					server.Serve()
+					server.Close()
+				}()
+			}
+		}()
+	}
+}
+
+func TestSFTPBackupTargetObjects(t *testing.T) {
+	config := newTestSFTPServer(t)
+	target, err := newSFTPBackupTarget(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	keys := []string{"survival/backups/a.tar.gz", "survival/chunks/ab/abcdef", "creative/backups/b.tar.gz"}
+	for i, key := range keys {
+		local := filepath.Join(dir, fmt.Sprint(i))
+		if err := os.WriteFile(local, []byte("content of "+key), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := target.Put(ctx, key, local); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+	// An interrupted upload isn't an object yet
+	writeTree(t, config.Path, map[string]string{"survival/backups/c.tar.gz.partial": "half"})
+
+	objects, err := target.List(ctx, "survival/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(objects) != fmt.Sprintf("[{%s %d} {%s %d}]", keys[0], len("content of "+keys[0]), keys[1], len("content of "+keys[1])) {
+		t.Errorf("List = %v", objects)
+	}
+	if objects, err := target.List(ctx, "missing/"); err != nil || len(objects) != 0 {
+		t.Errorf("List(missing) = %v, %v", objects, err)
+	}
+
+	local := filepath.Join(dir, "fetched")
+	if err := target.Get(ctx, keys[1], local); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(local); string(data) != "content of "+keys[1] {
+		t.Errorf("Get = %q", data)
+	}
+
+	for _, key := range []string{keys[0], keys[0]} {
+		if err := target.Delete(ctx, key); err != nil {
+			t.Errorf("Delete(%s) = %v", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(config.Path, keys[0])); !os.IsNotExist(err) {
+		t.Errorf("%s still exists: %v", keys[0], err)
+	}
+
+	if err := target.Put(ctx, "../outside", local); err == nil || !strings.Contains(err.Error(), "invalid key") {
+		t.Errorf("Put(../outside) = %v, want invalid key", err)
+	}
+}
+
+func TestSFTPBackupTargetResumesPartialUpload(t *testing.T) {
+	config := newTestSFTPServer(t)
+	target, err := newSFTPBackupTarget(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	ctx := context.Background()
+
+	content := strings.Repeat("0123456789", 10000)
+	local := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := os.WriteFile(local, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const key = "survival/backups/backup.tar.gz"
+	dst := filepath.Join(config.Path, key)
+
+	// The upload continues after the bytes already there (marked so the test can tell)
+	half := strings.Repeat("x", len(content)/2)
+	writeTree(t, config.Path, map[string]string{key + ".partial": half})
+	if err := target.Put(ctx, key, local); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != half+content[len(half):] {
+		t.Errorf("resumed upload holds %d bytes, want the partial file followed by the rest", len(data))
+	}
+	if _, err := os.Stat(dst + ".partial"); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+
+	// A partial file longer than the source belongs to another file: start over, replacing dst
+	writeTree(t, config.Path, map[string]string{key + ".partial": content + "stale"})
+	if err := target.Put(ctx, key, local); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != content {
+		t.Errorf("upload holds %d bytes, want the %d of the source", len(data), len(content))
+	}
+}
+
+func TestSFTPBackupTargetHostKey(t *testing.T) {
+	config := newTestSFTPServer(t)
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(config.HostKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := string(ssh.MarshalAuthorizedKey(newTestSSHSigner(t).PublicKey()))
+
+	tests := []struct {
+		name    string
+		hostKey string
+		wantErr string
+	}{
+		{"authorized key", config.HostKey, ""},
+		{"fingerprint", ssh.FingerprintSHA256(hostKey), ""},
+		{"missing", "", "hostKey is required; 127.0.0.1:" + fmt.Sprint(config.Port) + " presented ssh-ed25519 " + ssh.FingerprintSHA256(hostKey)},
+		{"other key", other, "host key mismatch"},
+		{"other fingerprint", "SHA256:AAAA", "host key mismatch"},
+		{"invalid", "not a key", "invalid hostKey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config
+			c.HostKey = tt.hostKey
+			target, err := newSFTPBackupTarget(c)
+			if err == nil {
+				target.Close()
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("err = %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	config.Password = "wrong"
+	if _, err := newSFTPBackupTarget(config); err == nil {
+		t.Error("connected with a wrong password")
+	}
+}
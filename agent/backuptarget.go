@@ -0,0 +1,1150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTargetsFile = "backup-targets.json"
+
+	backupTargetRetryInterval = 15 * time.Minute // Failed syncs are retried this often
+	backupTargetStartDelay    = time.Minute      // Startup sync waits for the agent to settle
+)
+
+// Backup target types
+const (
+	BackupTargetLocal = "local"
+	BackupTargetS3    = "s3"
+	BackupTargetSFTP  = "sftp"
+)
+
+// BackupTarget stores backups off the server's disk. Keys are slash-separated and relative
+// to the target's base path or prefix.
+type BackupTarget interface {
+	// Put uploads a local file, resuming an interrupted upload of the same key
+	Put(ctx context.Context, key, localPath string) error
+	// Get downloads an object to a local file (written atomically)
+	Get(ctx context.Context, key, localPath string) error
+	// List returns every object under prefix, recursively
+	List(ctx context.Context, prefix string) ([]RemoteObject, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	Close() error
+	String() string
+}
+
+// RemoteObject is one object on a backup target
+type RemoteObject struct {
+	Key  string
+	Size int64
+}
+
+// BackupTargetConfig configures where a server's backups are copied
+type BackupTargetConfig struct {
+	Type string `json:"type"` // local, s3 or sftp
+
+	// local and sftp
+	Path string `json:"path,omitempty"` // Base directory
+
+	// s3
+	Endpoint        string `json:"endpoint,omitempty"` // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Region          string `json:"region,omitempty"`   // Default us-east-1
+	Bucket          string `json:"bucket,omitempty"`
+	Prefix          string `json:"prefix,omitempty"`
+	AccessKeyID     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	VirtualHosted   bool   `json:"virtualHosted,omitempty"` // bucket.endpoint URLs instead of endpoint/bucket
+
+	// sftp
+	Host       string `json:"host,omitempty"`
+	Port       int    `json:"port,omitempty"` // Default 22
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"` // Unencrypted PEM key
+	HostKey    string `json:"hostKey,omitempty"`    // authorized_keys line or SHA256: fingerprint of the server's host key
+}
+
+// secrets returns pointers to the config's secret fields
+func (c *BackupTargetConfig) secrets() []*string {
+	return []*string{&c.SecretAccessKey, &c.Password, &c.PrivateKey}
+}
+
+// redacted returns a copy safe to send to the manager
+func (c BackupTargetConfig) redacted() BackupTargetConfig {
+	for _, s := range c.secrets() {
+		if *s != "" {
+			*s = redactedPlaceholder
+		}
+	}
+	return c
+}
+
+// newBackupTarget connects to a backup target
+func newBackupTarget(config BackupTargetConfig) (BackupTarget, error) {
+	switch config.Type {
+	case BackupTargetLocal:
+		return newLocalBackupTarget(config)
+	case BackupTargetS3:
+		return newS3BackupTarget(config)
+	case BackupTargetSFTP:
+		return newSFTPBackupTarget(config)
+	default:
+		return nil, fmt.Errorf("unknown backup target type %q (expected local, s3 or sftp)", config.Type)
+	}
+}
+
+// BackupTargetSettings selects a server's off-host backup target
+type BackupTargetSettings struct {
+	ServerName string             `json:"serverName"`
+	DataPath   string             `json:"dataPath"`
+	Target     BackupTargetConfig `json:"target"`
+	AutoUpload bool               `json:"autoUpload"`          // Sync after every backup (otherwise only on request)
+	Retention  *RetentionPolicy   `json:"retention,omitempty"` // Applied to the remote copies (default: the local policy)
+}
+
+// BackupTargetStatus is the outcome of the last sync of a server
+type BackupTargetStatus struct {
+	ServerName    string   `json:"serverName"`
+	Target        string   `json:"target"`
+	Syncing       bool     `json:"syncing,omitempty"`
+	LastSync      int64    `json:"lastSync,omitempty"`    // Unix seconds
+	LastSuccess   int64    `json:"lastSuccess,omitempty"` // Unix seconds
+	Error         string   `json:"error,omitempty"`
+	Uploaded      []string `json:"uploaded,omitempty"`
+	Deleted       []string `json:"deleted,omitempty"` // Remote copies pruned by retention
+	RemoteBackups int      `json:"remoteBackups"`
+}
+
+// BackupTargetSetRequest sets (or with a null target, removes) a server's backup target
+type BackupTargetSetRequest struct {
+	ServerName string              `json:"serverName"`
+	DataPath   string              `json:"dataPath"`
+	Target     *BackupTargetConfig `json:"target"`
+	AutoUpload bool                `json:"autoUpload"`
+	Retention  *RetentionPolicy    `json:"retention,omitempty"`
+}
+
+// BackupTargetRequest is the payload of backup.target.get/sync and backup.remote.list/fetch
+type BackupTargetRequest struct {
+	ServerName string `json:"serverName"`
+	Filename   string `json:"filename,omitempty"` // backup.remote.fetch
+}
+
+// BackupTargetResponse is the response payload of the backup.target.* and backup.remote.* messages
+type BackupTargetResponse struct {
+	Success  bool                  `json:"success"`
+	Settings *BackupTargetSettings `json:"settings,omitempty"` // Secrets redacted
+	Status   *BackupTargetStatus   `json:"status,omitempty"`
+	Backups  []BackupMeta          `json:"backups,omitempty"` // backup.remote.list
+	Backup   *BackupMeta           `json:"backup,omitempty"`  // backup.remote.fetch
+	Error    string                `json:"error,omitempty"`
+}
+
+// Remote layout under the target's base: {server}/backups/{file} holds backups and their
+// .meta.json sidecars (uploaded last, so a meta marks a complete copy); {server}/chunks/ab/{hash}
+// holds the chunks of snapshots, shared by every snapshot of the server.
+func remoteBackupKey(serverName, filename string) string {
+	return serverName + "/backups/" + filename
+}
+
+func remoteMetaKey(serverName, filename string) string {
+	return remoteBackupKey(serverName, snapshotBaseName(filename)+".meta.json")
+}
+
+func remoteChunkKey(serverName, hash string) string {
+	return serverName + "/chunks/" + hash[:2] + "/" + hash
+}
+
+// BackupUploader copies backups to their server's target and applies retention there
+type BackupUploader struct {
+	mu       sync.Mutex
+	agent    *Agent
+	settings map[string]*BackupTargetSettings // serverName -> settings
+	status   map[string]*BackupTargetStatus
+	syncing  map[string]*sync.Mutex // Serializes syncs and fetches per server
+	dir      string
+	triggers chan string
+	stopCh   chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewBackupUploader creates an uploader that reports through the agent
+func NewBackupUploader(agent *Agent) *BackupUploader {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BackupUploader{
+		agent:    agent,
+		settings: make(map[string]*BackupTargetSettings),
+		status:   make(map[string]*BackupTargetStatus),
+		syncing:  make(map[string]*sync.Mutex),
+		dir:      StateDir(),
+		triggers: make(chan string, 64),
+		stopCh:   make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start loads persisted targets and starts the upload loop. Servers with auto upload are
+// synced shortly after startup, which also resumes uploads interrupted by a restart.
+func (u *BackupUploader) Start() {
+	u.mu.Lock()
+	if data, err := os.ReadFile(filepath.Join(u.dir, backupTargetsFile)); err == nil {
+		var settings []BackupTargetSettings
+		if err := json.Unmarshal(data, &settings); err != nil {
+			backupLogger.Warnf("Failed to parse backup targets: %v", err)
+		}
+		for i := range settings {
+			s := settings[i]
+			for _, secret := range s.Target.secrets() {
+				RegisterSecret(*secret)
+			}
+			u.settings[s.ServerName] = &s
+		}
+	}
+	backupLogger.Infof("Loaded %d backup target(s)", len(u.settings))
+	u.mu.Unlock()
+
+	go u.loop()
+}
+
+// Stop stops the upload loop and cancels a sync in progress (it resumes on the next start)
+func (u *BackupUploader) Stop() {
+	close(u.stopCh)
+	u.cancel()
+}
+
+// Trigger queues a sync of a server if it uploads automatically
+func (u *BackupUploader) Trigger(serverName string) {
+	u.mu.Lock()
+	s, ok := u.settings[serverName]
+	auto := ok && s.AutoUpload
+	u.mu.Unlock()
+	if !auto {
+		return
+	}
+	select {
+	case u.triggers <- serverName:
+	default:
+		backupLogger.Warnf("Upload queue full, %s will sync on the next retry", serverName)
+	}
+}
+
+// loop runs queued syncs one at a time and retries failed ones
+func (u *BackupUploader) loop() {
+	start := time.NewTimer(backupTargetStartDelay)
+	defer start.Stop()
+	retry := time.NewTicker(backupTargetRetryInterval)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-u.stopCh:
+			return
+		case serverName := <-u.triggers:
+			u.syncAndReport(serverName)
+		case <-start.C:
+			for _, serverName := range u.autoServers(false) {
+				u.syncAndReport(serverName)
+			}
+		case <-retry.C:
+			for _, serverName := range u.autoServers(true) {
+				u.syncAndReport(serverName)
+			}
+		}
+	}
+}
+
+// autoServers returns servers with auto upload, optionally only those whose last sync failed
+func (u *BackupUploader) autoServers(failedOnly bool) []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var servers []string
+	for name, s := range u.settings {
+		if !s.AutoUpload {
+			continue
+		}
+		if st := u.status[name]; failedOnly && (st == nil || st.Error == "") {
+			continue
+		}
+		servers = append(servers, name)
+	}
+	sort.Strings(servers)
+	return servers
+}
+
+// syncAndReport syncs a server and sends the outcome to the manager
+func (u *BackupUploader) syncAndReport(serverName string) {
+	status, _ := u.Sync(serverName)
+	if status != nil && u.agent.IsAuthenticated() {
+		u.agent.sendMessage(NewMessage("backup.target.synced", status))
+	}
+}
+
+// Settings returns a server's target settings
+func (u *BackupUploader) Settings(serverName string) (BackupTargetSettings, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	s, ok := u.settings[serverName]
+	if !ok {
+		return BackupTargetSettings{}, false
+	}
+	return *s, true
+}
+
+// Status returns the last sync status of a server
+func (u *BackupUploader) Status(serverName string) *BackupTargetStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if st, ok := u.status[serverName]; ok {
+		status := *st
+		return &status
+	}
+	return nil
+}
+
+// SetTarget validates and stores a server's target after checking it can be listed.
+// Secrets sent back as the redaction placeholder keep their stored value.
+func (u *BackupUploader) SetTarget(req BackupTargetSetRequest) (*BackupTargetSettings, error) {
+	if req.ServerName == "" {
+		return nil, fmt.Errorf("serverName is required")
+	}
+	if strings.ContainsAny(req.ServerName, `/\`) || req.ServerName == "." || req.ServerName == ".." {
+		return nil, fmt.Errorf("invalid serverName")
+	}
+
+	if req.Target == nil {
+		u.mu.Lock()
+		delete(u.settings, req.ServerName)
+		delete(u.status, req.ServerName)
+		err := u.saveLocked()
+		u.mu.Unlock()
+		return nil, err
+	}
+
+	if req.DataPath == "" {
+		return nil, fmt.Errorf("dataPath is required")
+	}
+	if req.Retention != nil {
+		req.Retention.ServerName = req.ServerName
+		if err := req.Retention.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	settings := BackupTargetSettings{
+		ServerName: req.ServerName,
+		DataPath:   req.DataPath,
+		Target:     *req.Target,
+		AutoUpload: req.AutoUpload,
+		Retention:  req.Retention,
+	}
+	if existing, ok := u.Settings(req.ServerName); ok {
+		previous := existing.Target.secrets()
+		for i, s := range settings.Target.secrets() {
+			if *s == redactedPlaceholder {
+				*s = *previous[i]
+			}
+		}
+	}
+	for _, s := range settings.Target.secrets() {
+		if *s == redactedPlaceholder {
+			return nil, fmt.Errorf("secret fields must be set")
+		}
+		RegisterSecret(*s)
+	}
+
+	target, err := newBackupTarget(settings.Target)
+	if err != nil {
+		return nil, err
+	}
+	defer target.Close()
+	ctx, cancel := context.WithTimeout(u.ctx, time.Minute)
+	defer cancel()
+	if _, err := target.List(ctx, remoteBackupKey(req.ServerName, "")); err != nil {
+		return nil, fmt.Errorf("target check failed: %w", err)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.settings[req.ServerName] = &settings
+	if err := u.saveLocked(); err != nil {
+		return nil, err
+	}
+	backupLogger.Infof("Backup target for %s set to %s", req.ServerName, target)
+	return &settings, nil
+}
+
+// saveLocked persists all settings. Caller must hold u.mu.
+func (u *BackupUploader) saveLocked() error {
+	settings := make([]BackupTargetSettings, 0, len(u.settings))
+	for _, s := range u.settings {
+		settings = append(settings, *s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].ServerName < settings[j].ServerName })
+	if err := ensureStateDir(); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(u.dir, backupTargetsFile), settings)
+}
+
+// serverLock returns the mutex serializing remote operations of a server
+func (u *BackupUploader) serverLock(serverName string) *sync.Mutex {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	mu, ok := u.syncing[serverName]
+	if !ok {
+		mu = &sync.Mutex{}
+		u.syncing[serverName] = mu
+	}
+	return mu
+}
+
+// connect opens a server's target
+func (u *BackupUploader) connect(serverName string) (BackupTargetSettings, BackupTarget, error) {
+	settings, ok := u.Settings(serverName)
+	if !ok {
+		return settings, nil, fmt.Errorf("no backup target configured for %s", serverName)
+	}
+	target, err := newBackupTarget(settings.Target)
+	if err != nil {
+		return settings, nil, fmt.Errorf("failed to connect to backup target: %w", err)
+	}
+	return settings, target, nil
+}
+
+// remoteBackup is a backup found on the target
+type remoteBackup struct {
+	meta  BackupMeta
+	local bool // The backup also exists locally
+}
+
+// Sync uploads local backups missing on the target and applies retention to the remote
+// copies. Retention considers local and remote backups together, so a backup the remote
+// policy prunes is not uploaded again while it still exists locally.
+func (u *BackupUploader) Sync(serverName string) (*BackupTargetStatus, error) {
+	lock := u.serverLock(serverName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	status := &BackupTargetStatus{ServerName: serverName, Syncing: true, LastSync: time.Now().Unix()}
+	if previous := u.Status(serverName); previous != nil {
+		status.LastSuccess = previous.LastSuccess
+	}
+	u.setStatus(status)
+
+	err := u.sync(serverName, status)
+	status.Syncing = false
+	if err != nil {
+		status.Error = err.Error()
+		backupLogger.Warnf("Backup sync of %s failed: %v", serverName, err)
+	} else {
+		status.LastSuccess = time.Now().Unix()
+		backupLogger.Infof("Backup sync of %s to %s: %d uploaded, %d pruned, %d on target",
+			serverName, status.Target, len(status.Uploaded), len(status.Deleted), status.RemoteBackups)
+	}
+	u.setStatus(status)
+	return status, err
+}
+
+func (u *BackupUploader) setStatus(status *BackupTargetStatus) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	stored := *status
+	u.status[status.ServerName] = &stored
+}
+
+func (u *BackupUploader) sync(serverName string, status *BackupTargetStatus) error {
+	settings, target, err := u.connect(serverName)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	status.Target = target.String()
+	ctx := u.ctx
+	backupsDir := filepath.Join(settings.DataPath, serverName, "backups")
+
+	local, err := ListBackups(serverName, settings.DataPath)
+	if err != nil {
+		return err
+	}
+	remote, err := u.listRemote(ctx, target, serverName)
+	if err != nil {
+		return err
+	}
+
+	// Local metas are authoritative (e.g. for pins changed after upload)
+	byName := make(map[string]*remoteBackup, len(remote))
+	for i := range remote {
+		byName[remote[i].meta.Filename] = &remote[i]
+	}
+	var candidates []BackupMeta
+	toUpload := make(map[string]bool)
+	for _, meta := range local {
+		if r, ok := byName[meta.Filename]; ok {
+			r.local = true
+			if r.meta.Pinned != meta.Pinned {
+				r.meta = meta
+				key := remoteMetaKey(serverName, meta.Filename)
+				if err := target.Put(ctx, key, backupMetaPath(backupsDir, meta.Filename)); err != nil {
+					return fmt.Errorf("failed to update %s: %w", key, err)
+				}
+			}
+			continue
+		}
+		toUpload[meta.Filename] = true
+		candidates = append(candidates, meta)
+	}
+	for _, r := range remote {
+		candidates = append(candidates, r.meta)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].CreatedAt > candidates[j].CreatedAt })
+
+	policy := backupRetention.Policy(serverName)
+	if settings.Retention != nil {
+		policy = *settings.Retention
+	}
+	var usage *BackupUsage
+	if policy.MaxTotalBytes > 0 {
+		if usage, err = u.remoteUsage(ctx, target, serverName, backupsDir, candidates); err != nil {
+			return err
+		}
+	}
+	var keep []BackupMeta
+	var prune []string
+	for i, d := range ApplyRetention(policy, candidates, time.Now(), usage) {
+		switch {
+		case d.Keep:
+			keep = append(keep, candidates[i])
+		case !toUpload[d.Filename]:
+			prune = append(prune, d.Filename)
+		}
+	}
+
+	// Upload oldest first, so a later snapshot finds the chunks it shares already there
+	var chunks map[string]bool
+	for i := len(keep) - 1; i >= 0; i-- {
+		meta := keep[i]
+		if !toUpload[meta.Filename] {
+			continue
+		}
+		if isSnapshot(meta.Filename) && chunks == nil {
+			if chunks, err = u.listRemoteChunks(ctx, target, serverName); err != nil {
+				return err
+			}
+		}
+		if err := u.upload(ctx, target, serverName, backupsDir, meta, chunks); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", meta.Filename, err)
+		}
+		status.Uploaded = append(status.Uploaded, meta.Filename)
+	}
+
+	gc := false
+	for _, filename := range prune {
+		// Meta first: a backup without its meta is treated as an incomplete upload
+		if err := target.Delete(ctx, remoteMetaKey(serverName, filename)); err != nil {
+			return fmt.Errorf("failed to delete remote %s: %w", filename, err)
+		}
+		if err := target.Delete(ctx, remoteBackupKey(serverName, filename)); err != nil {
+			return fmt.Errorf("failed to delete remote %s: %w", filename, err)
+		}
+		status.Deleted = append(status.Deleted, filename)
+		gc = gc || isSnapshot(filename)
+	}
+	if gc {
+		if err := u.gcRemote(ctx, target, serverName, backupsDir); err != nil {
+			return err
+		}
+	}
+
+	status.RemoteBackups = len(keep)
+	return nil
+}
+
+// listRemote returns the complete backups on the target, newest first
+func (u *BackupUploader) listRemote(ctx context.Context, target BackupTarget, serverName string) ([]remoteBackup, error) {
+	objects, err := target.List(ctx, remoteBackupKey(serverName, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target: %w", err)
+	}
+	present := make(map[string]bool, len(objects))
+	for _, o := range objects {
+		present[path.Base(o.Key)] = true
+	}
+
+	var backups []remoteBackup
+	for _, o := range objects {
+		if !strings.HasSuffix(o.Key, ".meta.json") {
+			continue
+		}
+		// Always the remote copy: its pinned flag may differ from the local meta
+		var meta BackupMeta
+		if err := u.readRemoteJSON(ctx, target, o.Key, "", &meta); err != nil {
+			backupLogger.Warnf("Skipping remote %s: %v", o.Key, err)
+			continue
+		}
+		if !present[meta.Filename] {
+			continue
+		}
+		backups = append(backups, remoteBackup{meta: meta})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].meta.CreatedAt > backups[j].meta.CreatedAt })
+	return backups, nil
+}
+
+// readRemoteJSON decodes a remote meta or manifest, using localPath instead when that file
+// exists (snapshot manifests never change once written)
+func (u *BackupUploader) readRemoteJSON(ctx context.Context, target BackupTarget, key, localPath string, v interface{}) error {
+	data, err := os.ReadFile(localPath)
+	if localPath == "" || err != nil {
+		tmp, err := os.CreateTemp("", "zedops-remote-*")
+		if err != nil {
+			return err
+		}
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+		if err := target.Get(ctx, key, tmp.Name()); err != nil {
+			return err
+		}
+		if data, err = os.ReadFile(tmp.Name()); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// remoteUsage reads the chunks of the candidate snapshots, to measure what they occupy on the
+// target. Chunks and manifests not uploaded yet count their local size.
+func (u *BackupUploader) remoteUsage(ctx context.Context, target BackupTarget, serverName, backupsDir string, candidates []BackupMeta) (*BackupUsage, error) {
+	objects, err := target.List(ctx, serverName+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target: %w", err)
+	}
+	sizes := make(map[string]int64, len(objects))
+	for _, o := range objects {
+		sizes[o.Key] = o.Size
+	}
+	localSize := func(p string) int64 {
+		if info, err := os.Stat(p); err == nil {
+			return info.Size()
+		}
+		return 0
+	}
+	repoDir := filepath.Join(backupsDir, backupRepoDir)
+	chunkSize := func(hash string) int64 {
+		if size, ok := sizes[remoteChunkKey(serverName, hash)]; ok {
+			return size
+		}
+		return localSize(chunkPath(repoDir, hash))
+	}
+
+	usage := newBackupUsage()
+	for _, meta := range candidates {
+		if !isSnapshot(meta.Filename) {
+			continue
+		}
+		key := remoteBackupKey(serverName, meta.Filename)
+		localPath := filepath.Join(backupsDir, meta.Filename)
+		var manifest SnapshotManifest
+		if err := u.readRemoteJSON(ctx, target, key, localPath, &manifest); err != nil {
+			return nil, fmt.Errorf("can't size %s: %w", meta.Filename, err)
+		}
+		manifestBytes, ok := sizes[key]
+		if !ok {
+			manifestBytes = localSize(localPath)
+		}
+		usage.addSnapshot(meta.Filename, manifestBytes, &manifest, chunkSize)
+	}
+	return usage, nil
+}
+
+// listRemoteChunks returns the hashes of the chunks on the target
+func (u *BackupUploader) listRemoteChunks(ctx context.Context, target BackupTarget, serverName string) (map[string]bool, error) {
+	objects, err := target.List(ctx, serverName+"/chunks/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote chunks: %w", err)
+	}
+	chunks := make(map[string]bool, len(objects))
+	for _, o := range objects {
+		chunks[path.Base(o.Key)] = true
+	}
+	return chunks, nil
+}
+
+// upload copies one backup: a snapshot's missing chunks, then the backup file, then its meta
+func (u *BackupUploader) upload(ctx context.Context, target BackupTarget, serverName, backupsDir string, meta BackupMeta, chunks map[string]bool) error {
+	backupPath := filepath.Join(backupsDir, meta.Filename)
+	progress := func(percent int) {
+		if u.agent.IsAuthenticated() {
+			u.agent.sendMessage(NewMessage("backup.progress", BackupProgress{
+				BackupID: meta.BackupID, ServerName: serverName, Phase: "uploading", Percent: percent,
+			}))
+		}
+	}
+	progress(0)
+
+	if isSnapshot(meta.Filename) {
+		manifest, err := readSnapshotManifest(backupPath)
+		if err != nil {
+			return err
+		}
+		var missing []string
+		for _, f := range manifest.Files {
+			for _, hash := range f.Chunks {
+				if !chunks[hash] {
+					chunks[hash] = true // Also dedups within this snapshot
+					missing = append(missing, hash)
+				}
+			}
+		}
+		repoDir := filepath.Join(backupsDir, backupRepoDir)
+		lastPercent := 0
+		for i, hash := range missing {
+			if err := target.Put(ctx, remoteChunkKey(serverName, hash), chunkPath(repoDir, hash)); err != nil {
+				return err
+			}
+			if percent := (i + 1) * 95 / len(missing); percent >= lastPercent+5 {
+				lastPercent = percent
+				progress(percent)
+			}
+		}
+	}
+
+	if err := target.Put(ctx, remoteBackupKey(serverName, meta.Filename), backupPath); err != nil {
+		return err
+	}
+	if err := target.Put(ctx, remoteMetaKey(serverName, meta.Filename), backupMetaPath(backupsDir, meta.Filename)); err != nil {
+		return err
+	}
+	progress(100)
+	return nil
+}
+
+// gcRemote deletes remote chunks no remote snapshot references. It gives up rather than
+// delete anything if a manifest can't be read.
+func (u *BackupUploader) gcRemote(ctx context.Context, target BackupTarget, serverName, backupsDir string) error {
+	objects, err := target.List(ctx, remoteBackupKey(serverName, ""))
+	if err != nil {
+		return fmt.Errorf("failed to list target: %w", err)
+	}
+	referenced := make(map[string]bool)
+	for _, o := range objects {
+		name := path.Base(o.Key)
+		if !isSnapshot(name) {
+			continue
+		}
+		var manifest SnapshotManifest
+		if err := u.readRemoteJSON(ctx, target, o.Key, filepath.Join(backupsDir, name), &manifest); err != nil {
+			return fmt.Errorf("remote GC aborted, can't read %s: %w", o.Key, err)
+		}
+		for _, f := range manifest.Files {
+			for _, hash := range f.Chunks {
+				referenced[hash] = true
+			}
+		}
+	}
+
+	chunks, err := target.List(ctx, serverName+"/chunks/")
+	if err != nil {
+		return fmt.Errorf("failed to list remote chunks: %w", err)
+	}
+	removed := 0
+	var bytes int64
+	for _, o := range chunks {
+		if referenced[path.Base(o.Key)] {
+			continue
+		}
+		if err := target.Delete(ctx, o.Key); err != nil {
+			return fmt.Errorf("failed to delete remote chunk: %w", err)
+		}
+		removed++
+		bytes += o.Size
+	}
+	backupLogger.Infof("Remote GC of %s on %s: removed %d chunk(s), %d bytes", serverName, target, removed, bytes)
+	return nil
+}
+
+// ListRemote returns the backups of a server on its target
+func (u *BackupUploader) ListRemote(serverName string) ([]BackupMeta, error) {
+	_, target, err := u.connect(serverName)
+	if err != nil {
+		return nil, err
+	}
+	defer target.Close()
+
+	remote, err := u.listRemote(u.ctx, target, serverName)
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]BackupMeta, len(remote))
+	for i, r := range remote {
+		backups[i] = r.meta
+	}
+	return backups, nil
+}
+
+// Fetch downloads a remote backup into the server's local backups, from where it is restored
+// like any other. A snapshot only downloads the chunks missing from the local repository.
+func (u *BackupUploader) Fetch(serverName, filename string) (*BackupMeta, error) {
+	if filename == "" || strings.Contains(filename, "/") || strings.Contains(filename, "..") {
+		return nil, fmt.Errorf("invalid filename")
+	}
+	lock := u.serverLock(serverName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	settings, target, err := u.connect(serverName)
+	if err != nil {
+		return nil, err
+	}
+	defer target.Close()
+	ctx := u.ctx
+
+	backupsDir := filepath.Join(settings.DataPath, serverName, "backups")
+	if err := os.MkdirAll(backupsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backups directory: %w", err)
+	}
+	backupPath := filepath.Join(backupsDir, filename)
+	metaPath := backupMetaPath(backupsDir, filename)
+	if _, err := os.Stat(metaPath); err == nil {
+		return nil, fmt.Errorf("%s already exists locally", filename)
+	}
+
+	// Stage the meta so it only appears (making the backup visible) once everything is in place
+	metaTmp := metaPath + ".fetch"
+	defer os.Remove(metaTmp)
+	if err := target.Get(ctx, remoteMetaKey(serverName, filename), metaTmp); err != nil {
+		return nil, fmt.Errorf("backup %s not found on target: %w", filename, err)
+	}
+	data, err := os.ReadFile(metaTmp)
+	if err != nil {
+		return nil, err
+	}
+	var meta BackupMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid remote meta: %w", err)
+	}
+
+	backupLogger.Infof("Fetching %s/%s from %s", serverName, filename, target)
+	if isSnapshot(filename) {
+		// Hold the repository lock so local GC doesn't collect chunks before the manifest lands
+		repoDir := filepath.Join(backupsDir, backupRepoDir)
+		unlock := lockBackupRepo(repoDir)
+		defer unlock()
+
+		manifestTmp := backupPath + ".fetch"
+		defer os.Remove(manifestTmp)
+		if err := target.Get(ctx, remoteBackupKey(serverName, filename), manifestTmp); err != nil {
+			return nil, err
+		}
+		manifest, err := readSnapshotManifest(manifestTmp)
+		if err != nil {
+			return nil, err
+		}
+		fetched := 0
+		for _, f := range manifest.Files {
+			for _, hash := range f.Chunks {
+				p := chunkPath(repoDir, hash)
+				if _, err := os.Stat(p); err == nil {
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					return nil, err
+				}
+				if err := target.Get(ctx, remoteChunkKey(serverName, hash), p); err != nil {
+					return nil, fmt.Errorf("failed to fetch chunk %s: %w", hash, err)
+				}
+				fetched++
+			}
+		}
+		if err := os.Rename(manifestTmp, backupPath); err != nil {
+			return nil, err
+		}
+		backupLogger.Infof("Fetched %d chunk(s) for %s", fetched, filename)
+	} else if err := target.Get(ctx, remoteBackupKey(serverName, filename), backupPath); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(metaTmp, metaPath); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// getFileAtomic writes r to localPath through a temporary file in the same directory
+func getFileAtomic(localPath string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), ".download-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), localPath); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// localBackupTarget copies backups to a directory, typically a mount of another disk or a NAS
+type localBackupTarget struct {
+	root string
+}
+
+func newLocalBackupTarget(config BackupTargetConfig) (*localBackupTarget, error) {
+	if !filepath.IsAbs(config.Path) {
+		return nil, fmt.Errorf("local target needs an absolute path")
+	}
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", config.Path, err)
+	}
+	return &localBackupTarget{root: filepath.Clean(config.Path)}, nil
+}
+
+// path maps a key below the root, rejecting keys that escape it
+func (t *localBackupTarget) path(key string) (string, error) {
+	p := filepath.Join(t.root, filepath.FromSlash(key))
+	if p != t.root && !strings.HasPrefix(p, t.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return p, nil
+}
+
+// Put appends to a .partial file left by an interrupted upload, then renames it into place
+func (t *localBackupTarget) Put(ctx context.Context, key, localPath string) error {
+	dst, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	partial := dst + ".partial"
+	f, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if offset > info.Size() {
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		offset, _ = f.Seek(0, io.SeekStart)
+	}
+	if _, err := io.Copy(f, &contextReader{ctx: ctx, r: io.NewSectionReader(src, offset, info.Size()-offset)}); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partial, dst)
+}
+
+func (t *localBackupTarget) Get(ctx context.Context, key, localPath string) error {
+	p, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return getFileAtomic(localPath, &contextReader{ctx: ctx, r: f})
+}
+
+func (t *localBackupTarget) List(ctx context.Context, prefix string) ([]RemoteObject, error) {
+	dir, err := t.path(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var objects []RemoteObject
+	err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, ".partial") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(t.root, p)
+		objects = append(objects, RemoteObject{Key: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	return objects, err
+}
+
+func (t *localBackupTarget) Delete(ctx context.Context, key string) error {
+	p, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (t *localBackupTarget) Close() error { return nil }
+
+func (t *localBackupTarget) String() string { return "local:" + t.root }
+
+// contextReader stops a long copy when its context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// handleBackupTargetSet handles backup.target.set messages
+func (a *Agent) handleBackupTargetSet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupTargetSetRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Error: "Invalid request format"})
+		return
+	}
+
+	settings, err := a.backupUploader.SetTarget(req)
+	if err != nil {
+		a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Error: err.Error()})
+		return
+	}
+	resp := BackupTargetResponse{Success: true}
+	if settings != nil {
+		redacted := *settings
+		redacted.Target = settings.Target.redacted()
+		resp.Settings = &redacted
+	}
+	a.sendBackupTargetResponse(msg.Reply, resp)
+}
+
+// handleBackupTargetGet handles backup.target.get messages
+func (a *Agent) handleBackupTargetGet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupTargetRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" {
+		a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Error: "Invalid request format (serverName required)"})
+		return
+	}
+
+	resp := BackupTargetResponse{Success: true, Status: a.backupUploader.Status(req.ServerName)}
+	if settings, ok := a.backupUploader.Settings(req.ServerName); ok {
+		settings.Target = settings.Target.redacted()
+		resp.Settings = &settings
+	}
+	a.sendBackupTargetResponse(msg.Reply, resp)
+}
+
+// handleBackupTargetSync handles backup.target.sync messages
+func (a *Agent) handleBackupTargetSync(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupTargetRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" {
+		a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Error: "Invalid request format (serverName required)"})
+		return
+	}
+
+	status, err := a.backupUploader.Sync(req.ServerName)
+	resp := BackupTargetResponse{Success: err == nil, Status: status}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	a.sendBackupTargetResponse(msg.Reply, resp)
+}
+
+// handleBackupRemoteList handles backup.remote.list messages
+func (a *Agent) handleBackupRemoteList(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupTargetRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" {
+		a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Error: "Invalid request format (serverName required)"})
+		return
+	}
+
+	backups, err := a.backupUploader.ListRemote(req.ServerName)
+	if err != nil {
+		a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Error: err.Error()})
+		return
+	}
+	if backups == nil {
+		backups = []BackupMeta{}
+	}
+	a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Success: true, Backups: backups})
+}
+
+// handleBackupRemoteFetch handles backup.remote.fetch messages
+func (a *Agent) handleBackupRemoteFetch(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req BackupTargetRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ServerName == "" || req.Filename == "" {
+		a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Error: "Invalid request format (serverName and filename required)"})
+		return
+	}
+
+	meta, err := a.backupUploader.Fetch(req.ServerName, req.Filename)
+	if err != nil {
+		backupLogger.Error("Fetch failed", "server", req.ServerName, "filename", req.Filename, "error", err)
+		a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Error: err.Error()})
+		return
+	}
+	a.sendBackupTargetResponse(msg.Reply, BackupTargetResponse{Success: true, Backup: meta})
+}
+
+// sendBackupTargetResponse replies to a backup.target.* or backup.remote.* request
+func (a *Agent) sendBackupTargetResponse(replyTo string, resp BackupTargetResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
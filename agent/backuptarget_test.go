@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupUploaderSyncCollectsRemoteChunks(t *testing.T) {
+	dataPath := t.TempDir()
+	var world strings.Builder
+	for i := 0; world.Len() < 1<<20; i++ {
+		fmt.Fprintf(&world, "%08x", i*2654435761) // 1 MiB, several chunks
+	}
+	writeTree(t, dataPath, map[string]string{
+		"survival/data/Saves/map.bin":    world.String(),
+		"survival/data/Saves/players.db": "one player",
+	})
+	create := func(id string) {
+		if _, err := CreateBackup("survival", dataPath, id, id, false, BackupFormatChunked, "", 0, "", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(1100 * time.Millisecond) // Snapshot filenames have second resolution
+	}
+	create("first")
+	writeTree(t, dataPath, map[string]string{"survival/data/Saves/players.db": "two players"})
+	create("second")
+
+	backups, err := ListBackups("survival", dataPath)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("backups = %+v, %v", backups, err)
+	}
+	newer, older := backups[0].Filename, backups[1].Filename
+	backupsDir := filepath.Join(dataPath, "survival", "backups")
+	manifestChunks := func(filename string) map[string]bool {
+		manifest, err := readSnapshotManifest(filepath.Join(backupsDir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks := map[string]bool{}
+		for _, f := range manifest.Files {
+			for _, hash := range f.Chunks {
+				chunks[hash] = true
+			}
+		}
+		return chunks
+	}
+	newerChunks, olderChunks := manifestChunks(newer), manifestChunks(older)
+	all := map[string]bool{}
+	shared := 0
+	for hash := range olderChunks {
+		all[hash] = true
+		if newerChunks[hash] {
+			shared++
+		}
+	}
+	for hash := range newerChunks {
+		all[hash] = true
+	}
+	if shared == 0 || shared == len(olderChunks) {
+		t.Fatalf("%d of %d chunks shared, want some but not all", shared, len(olderChunks))
+	}
+
+	s3, s3Server := newFakeS3(t)
+	targets := []struct {
+		name   string
+		config BackupTargetConfig
+	}{
+		{"local", BackupTargetConfig{Type: BackupTargetLocal, Path: t.TempDir()}},
+		{"s3", s3.config(s3Server.URL)},
+		{"sftp", newTestSFTPServer(t)},
+	}
+	for _, tt := range targets {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewBackupUploader(&Agent{})
+			settings := &BackupTargetSettings{
+				ServerName: "survival", DataPath: dataPath, Target: tt.config,
+				Retention: &RetentionPolicy{ServerName: "survival", KeepLast: 10},
+			}
+			u.settings["survival"] = settings
+			remoteChunks := func() map[string]bool {
+				target, err := newBackupTarget(tt.config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer target.Close()
+				objects, err := target.List(context.Background(), "survival/chunks/")
+				if err != nil {
+					t.Fatal(err)
+				}
+				chunks := map[string]bool{}
+				for _, o := range objects {
+					chunks[path.Base(o.Key)] = true
+				}
+				return chunks
+			}
+
+			status, err := u.Sync("survival")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(status.Uploaded) != fmt.Sprint([]string{older, newer}) || status.RemoteBackups != 2 {
+				t.Errorf("first sync = %+v, want both uploaded oldest first", status)
+			}
+			if got := remoteChunks(); fmt.Sprint(got) != fmt.Sprint(all) {
+				t.Errorf("remote holds %d chunks, want the %d of both snapshots", len(got), len(all))
+			}
+
+			// Keeping only the newer copy collects the chunks only the older one used
+			settings.Retention = &RetentionPolicy{ServerName: "survival", KeepLast: 1}
+			status, err = u.Sync("survival")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(status.Uploaded) != 0 || fmt.Sprint(status.Deleted) != fmt.Sprint([]string{older}) || status.RemoteBackups != 1 {
+				t.Errorf("second sync = %+v, want %s pruned", status, older)
+			}
+			if got := remoteChunks(); fmt.Sprint(got) != fmt.Sprint(newerChunks) {
+				t.Errorf("remote holds %d chunks, want the %d of %s", len(got), len(newerChunks), newer)
+			}
+			if remote, err := u.ListRemote("survival"); err != nil || len(remote) != 1 || remote[0].Filename != newer {
+				t.Errorf("ListRemote = %+v, %v", remote, err)
+			}
+
+			// What remains restores on another host
+			fetchPath := t.TempDir()
+			settings.DataPath = fetchPath
+			if _, err := u.Fetch("survival", newer); err != nil {
+				t.Fatal(err)
+			}
+			result, err := VerifyBackup("survival", fetchPath, newer, true)
+			if err != nil || !result.OK || result.Chunks != len(newerChunks) {
+				t.Errorf("fetched copy: %+v, %v", result, err)
+			}
+		})
+	}
+	if s3.badSignatures != 0 {
+		t.Errorf("%d S3 request(s) with a bad signature", s3.badSignatures)
+	}
+}
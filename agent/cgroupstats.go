@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cgroupUnlimited is the smallest cgroup v1 memory limit treated as "no limit" (the kernel reports
+// PAGE_COUNTER_MAX rounded to pages, which is close to 2^63)
+const cgroupUnlimited = 1 << 62
+
+// cgroupSearchDepth bounds the search for containers started with a custom --cgroup-parent
+const cgroupSearchDepth = 4
+
+var errCgroupNotFound = errors.New("container cgroup not found")
+
+// cgroupReader samples container resource usage straight from the cgroup filesystem, avoiding the
+// Docker stats API (which blocks about a second per container to take its pre-sample)
+type cgroupReader struct {
+	root     string // cgroup mount, normally /sys/fs/cgroup
+	procRoot string // proc mount, normally /proc (for network counters and MemTotal)
+	v2       bool
+
+	// pidOf returns the container's main PID as the Docker daemon sees it, in the host PID
+	// namespace that procRoot shows (nil = only the PIDs listed in cgroup.procs)
+	pidOf func(containerID string) (int, error)
+
+	mu    sync.Mutex
+	paths map[string]string // containerID -> cgroup directory (v2) or path below each controller (v1)
+	pids  map[string]int    // containerID -> main PID from pidOf
+}
+
+// newCgroupReader detects the cgroup version at root. It returns nil if no cgroup hierarchy is readable.
+func newCgroupReader(root, procRoot string) *cgroupReader {
+	r := &cgroupReader{root: root, procRoot: procRoot, paths: make(map[string]string), pids: make(map[string]int)}
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		r.v2 = true
+		return r
+	}
+	if _, err := os.Stat(filepath.Join(root, "cpuacct")); err == nil {
+		return r
+	}
+	if _, err := os.Stat(filepath.Join(root, "cpu,cpuacct")); err == nil {
+		return r
+	}
+	return nil
+}
+
+// version returns "v1" or "v2" for logging
+func (r *cgroupReader) version() string {
+	if r.v2 {
+		return "v2"
+	}
+	return "v1"
+}
+
+// resolve finds the container's cgroup for both the systemd (docker-<id>.scope) and cgroupfs
+// (docker/<id>) cgroup drivers, or anywhere near the top of the tree for a custom cgroup parent,
+// caching the result
+func (r *cgroupReader) resolve(containerID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.paths[containerID]; ok {
+		return p, nil
+	}
+
+	candidates := []string{
+		filepath.Join("system.slice", "docker-"+containerID+".scope"),
+		filepath.Join("docker", containerID),
+	}
+	base := r.root
+	if !r.v2 {
+		base = filepath.Join(r.root, "memory")
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(filepath.Join(base, c)); err == nil {
+			r.paths[containerID] = c
+			return c, nil
+		}
+	}
+	if c, err := findCgroup(base, containerID); err == nil {
+		r.paths[containerID] = c
+		return c, nil
+	}
+	return "", errCgroupNotFound
+}
+
+// findCgroup searches the top levels of a hierarchy for a directory named after the container
+// (<parent>/<id> with cgroupfs, <parent>/docker-<id>.scope with systemd)
+func findCgroup(base, containerID string) (string, error) {
+	var found string
+	filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil // Unreadable subtrees are skipped
+		}
+		rel, _ := filepath.Rel(base, path)
+		if rel == "." {
+			return nil
+		}
+		if strings.Contains(d.Name(), containerID) {
+			found = rel
+			return fs.SkipAll
+		}
+		if strings.Count(rel, string(filepath.Separator))+1 >= cgroupSearchDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if found == "" {
+		return "", errCgroupNotFound
+	}
+	return found, nil
+}
+
+// forget drops a cached path (the container is gone or was recreated)
+func (r *cgroupReader) forget(containerID string) {
+	r.mu.Lock()
+	delete(r.paths, containerID)
+	delete(r.pids, containerID)
+	r.mu.Unlock()
+}
+
+// sample reads the container's counters and fills the point-in-time fields of m
+func (r *cgroupReader) sample(containerID string, m *ContainerMetrics) (containerCounters, error) {
+	path, err := r.resolve(containerID)
+	if err != nil {
+		return containerCounters{}, err
+	}
+
+	c := containerCounters{Time: time.Now()}
+	if r.v2 {
+		err = r.sampleV2(filepath.Join(r.root, path), &c, m)
+	} else {
+		err = r.sampleV1(path, &c, m)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			r.forget(containerID)
+		}
+		return containerCounters{}, err
+	}
+
+	if m.MemoryLimitMB == 0 {
+		if total, err := r.memTotalBytes(); err == nil {
+			m.MemoryLimitMB = int64(total / 1024 / 1024)
+		}
+	}
+
+	// Network counters live in the container's network namespace, reachable through any of its processes
+	if pid, err := r.networkPID(containerID, path); err == nil {
+		if rx, tx, err := readNetDev(filepath.Join(r.procRoot, pid, "net", "dev")); err == nil {
+			c.NetRxBytes, c.NetTxBytes = rx, tx
+		}
+	}
+
+	c.apply(m)
+	return c, nil
+}
+
+// networkPID returns a process of the container as numbered in procRoot. The daemon's PID for
+// the container is in the host PID namespace, which procRoot shows; the PIDs in cgroup.procs are
+// in the agent's namespace and only match procRoot when the agent shares the host's.
+func (r *cgroupReader) networkPID(containerID, path string) (string, error) {
+	if pid, err := r.containerPID(containerID); err == nil {
+		return strconv.Itoa(pid), nil
+	}
+
+	procsDir := filepath.Join(r.root, path)
+	if !r.v2 {
+		procsDir = filepath.Join(r.root, "memory", path)
+	}
+	pid, err := firstCgroupPID(procsDir)
+	if err != nil {
+		return "", err
+	}
+	if !procInCgroup(r.procRoot, pid, containerID) {
+		return "", fmt.Errorf("process %s is not visible in %s", pid, r.procRoot)
+	}
+	return pid, nil
+}
+
+// containerPID returns the container's main PID from pidOf, cached until the process is gone
+// or the container restarted
+func (r *cgroupReader) containerPID(containerID string) (int, error) {
+	r.mu.Lock()
+	pid, ok := r.pids[containerID]
+	r.mu.Unlock()
+	if ok && procInCgroup(r.procRoot, strconv.Itoa(pid), containerID) {
+		return pid, nil
+	}
+	if r.pidOf == nil {
+		return 0, fmt.Errorf("container PID unavailable")
+	}
+
+	pid, err := r.pidOf(containerID)
+	if err != nil {
+		return 0, err
+	}
+	if pid <= 0 || !procInCgroup(r.procRoot, strconv.Itoa(pid), containerID) {
+		return 0, fmt.Errorf("container PID %d is not visible in %s", pid, r.procRoot)
+	}
+	r.mu.Lock()
+	r.pids[containerID] = pid
+	r.mu.Unlock()
+	return pid, nil
+}
+
+// procInCgroup reports whether a process of procRoot belongs to the container's cgroup, which
+// guards against PIDs from another namespace or reused by an unrelated process
+func procInCgroup(procRoot, pid, containerID string) bool {
+	data, err := os.ReadFile(filepath.Join(procRoot, pid, "cgroup"))
+	return err == nil && strings.Contains(string(data), containerID)
+}
+
+// sampleV2 reads a unified hierarchy cgroup directory
+func (r *cgroupReader) sampleV2(dir string, c *containerCounters, m *ContainerMetrics) error {
+	cpu, err := readKeyValueFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	c.CPUUsageNs = cpu["usage_usec"] * 1000
+	c.CPUPeriods = cpu["nr_periods"]
+	c.CPUThrottledPeriods = cpu["nr_throttled"]
+	c.CPUThrottledTimeNs = cpu["throttled_usec"] * 1000
+
+	current, err := readUintFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return err
+	}
+	m.MemoryUsedMB = int64(current / 1024 / 1024)
+	if limit, err := readUintFile(filepath.Join(dir, "memory.max")); err == nil {
+		m.MemoryLimitMB = int64(limit / 1024 / 1024)
+	}
+	if stat, err := readKeyValueFile(filepath.Join(dir, "memory.stat")); err == nil {
+		m.MemoryRSSMB, m.MemoryCacheMB = memoryBreakdown(stat)
+	}
+
+	if io, err := os.ReadFile(filepath.Join(dir, "io.stat")); err == nil {
+		c.DiskReadBytes, c.DiskWriteBytes = parseIOStat(string(io))
+	}
+
+	if pids, err := readUintFile(filepath.Join(dir, "pids.current")); err == nil {
+		m.PIDs = pids
+	}
+	if limit, err := readUintFile(filepath.Join(dir, "pids.max")); err == nil {
+		m.PIDsLimit = limit
+	}
+	return nil
+}
+
+// sampleV1 reads the per-controller hierarchies of a cgroup v1 host
+func (r *cgroupReader) sampleV1(path string, c *containerCounters, m *ContainerMetrics) error {
+	cpuacct := r.v1Controller("cpuacct", "cpu,cpuacct")
+	usage, err := readUintFile(filepath.Join(r.root, cpuacct, path, "cpuacct.usage"))
+	if err != nil {
+		return err
+	}
+	c.CPUUsageNs = usage
+	if cpu, err := readKeyValueFile(filepath.Join(r.root, r.v1Controller("cpu", "cpu,cpuacct"), path, "cpu.stat")); err == nil {
+		c.CPUPeriods = cpu["nr_periods"]
+		c.CPUThrottledPeriods = cpu["nr_throttled"]
+		c.CPUThrottledTimeNs = cpu["throttled_time"]
+	}
+
+	memDir := filepath.Join(r.root, "memory", path)
+	used, err := readUintFile(filepath.Join(memDir, "memory.usage_in_bytes"))
+	if err != nil {
+		return err
+	}
+	m.MemoryUsedMB = int64(used / 1024 / 1024)
+	if limit, err := readUintFile(filepath.Join(memDir, "memory.limit_in_bytes")); err == nil && limit < cgroupUnlimited {
+		m.MemoryLimitMB = int64(limit / 1024 / 1024)
+	}
+	if stat, err := readKeyValueFile(filepath.Join(memDir, "memory.stat")); err == nil {
+		m.MemoryRSSMB, m.MemoryCacheMB = memoryBreakdown(stat)
+	}
+
+	if blkio, err := os.ReadFile(filepath.Join(r.root, "blkio", path, "blkio.throttle.io_service_bytes_recursive")); err == nil {
+		c.DiskReadBytes, c.DiskWriteBytes = parseBlkioServiceBytes(string(blkio))
+	}
+
+	pidsDir := filepath.Join(r.root, "pids", path)
+	if pids, err := readUintFile(filepath.Join(pidsDir, "pids.current")); err == nil {
+		m.PIDs = pids
+	}
+	if limit, err := readUintFile(filepath.Join(pidsDir, "pids.max")); err == nil {
+		m.PIDsLimit = limit
+	}
+	return nil
+}
+
+// v1Controller returns the first controller directory that exists (cpu and cpuacct are often co-mounted)
+func (r *cgroupReader) v1Controller(names ...string) string {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(r.root, name)); err == nil {
+			return name
+		}
+	}
+	return names[0]
+}
+
+// memTotalBytes reads MemTotal, which Docker reports as the limit of containers without one
+func (r *cgroupReader) memTotalBytes() (uint64, error) {
+	info, err := readKeyValueFile(filepath.Join(r.procRoot, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	total, ok := info["MemTotal:"]
+	if !ok {
+		return 0, fmt.Errorf("MemTotal missing from meminfo")
+	}
+	return total * 1024, nil
+}
+
+// readUintFile reads a single-value cgroup file. "max" (no limit) reads as 0.
+func readUintFile(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// readKeyValueFile reads "key value" lines (cpu.stat, memory.stat, meminfo); unparseable lines are skipped
+func readKeyValueFile(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, scanner.Err()
+}
+
+// parseIOStat sums rbytes and wbytes over all devices of a cgroup v2 io.stat
+func parseIOStat(data string) (read, write uint64) {
+	for _, line := range strings.Split(data, "\n") {
+		for _, field := range strings.Fields(line) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				read += v
+			case "wbytes":
+				write += v
+			}
+		}
+	}
+	return read, write
+}
+
+// parseBlkioServiceBytes sums Read and Write over all devices of a cgroup v1
+// blkio.throttle.io_service_bytes_recursive ("8:0 Read 1234" lines plus a "Total" line)
+func parseBlkioServiceBytes(data string) (read, write uint64) {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += v
+		case "Write":
+			write += v
+		}
+	}
+	return read, write
+}
+
+// firstCgroupPID returns a process of the cgroup. Processes outside the reader's PID
+// namespace are listed as 0 and skipped.
+func firstCgroupPID(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return "", err
+	}
+	for _, pid := range strings.Fields(string(data)) {
+		if pid != "0" {
+			return pid, nil
+		}
+	}
+	return "", fmt.Errorf("cgroup has no visible processes")
+}
+
+// readNetDev sums received and transmitted bytes over all interfaces except loopback
+func readNetDev(path string) (rx, tx uint64, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		iface, counters, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(iface) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		r, err1 := strconv.ParseUint(fields[0], 10, 64)
+		t, err2 := strconv.ParseUint(fields[8], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		rx += r
+		tx += t
+	}
+	return rx, tx, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testContainerID = "4f1c2a9e8b7d6c5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f"
+
+// writeTree creates files below root from a path -> content map
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const testNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  123456     100    0    0    0     0          0         0   123456     100    0    0    0     0       0          0
+  eth0: 734003200 1200000    0    0    0     0          0         0 1468006400 1500000    0    0    0     0       0          0
+`
+
+// cgroupV2Files is a container cgroup on a unified hierarchy host
+func cgroupV2Files(dir string) map[string]string {
+	return map[string]string{
+		"cgroup.controllers":                "cpuset cpu io memory pids",
+		dir + "/cpu.stat":                   "usage_usec 512000000\nuser_usec 471000000\nsystem_usec 41000000\nnr_periods 12000\nnr_throttled 300\nthrottled_usec 45000000\n",
+		dir + "/memory.current":             "3328180224\n",
+		dir + "/memory.max":                 "max\n",
+		dir + "/memory.stat":                "anon 2936012800\nfile 314572800\nkernel_stack 999424\n",
+		dir + "/io.stat":                    "259:0 rbytes=209715200 wbytes=52428800 rios=1000 wios=500 dbytes=0 dios=0\n8:0 rbytes=1048576 wbytes=0 rios=10 wios=0 dbytes=0 dios=0\n",
+		dir + "/pids.current":               "61\n",
+		dir + "/pids.max":                   "4096\n",
+		dir + "/cgroup.procs":               "4242\n4250\n",
+		"system.slice/containerd.service/x": "",
+	}
+}
+
+// procFiles is a proc mount where pid runs in the container's cgroup
+func procFiles(pid, cgroupPath string) map[string]string {
+	return map[string]string{
+		"meminfo":        "MemTotal:       16384000 kB\nMemFree:         8192000 kB\n",
+		pid + "/cgroup":  "0::/" + cgroupPath + "\n",
+		pid + "/net/dev": testNetDev,
+		"1/cgroup":       "0::/init.scope\n",
+		"1/net/dev":      testNetDev,
+	}
+}
+
+func TestCgroupReaderV2(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{"systemd driver", "system.slice/docker-" + testContainerID + ".scope"},
+		{"cgroupfs driver", "docker/" + testContainerID},
+		{"custom cgroup parent", "zomboid.slice/zomboid-servers.slice/docker-" + testContainerID + ".scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, procRoot := t.TempDir(), t.TempDir()
+			writeTree(t, root, cgroupV2Files(tt.dir))
+			writeTree(t, procRoot, procFiles("4242", tt.dir))
+
+			r := newCgroupReader(root, procRoot)
+			if r == nil || !r.v2 {
+				t.Fatal("cgroup v2 hierarchy not detected")
+			}
+			var m ContainerMetrics
+			c, err := r.sample(testContainerID, &m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.CPUUsageNs != 512e9 || c.CPUPeriods != 12000 || c.CPUThrottledPeriods != 300 || c.CPUThrottledTimeNs != 45e9 {
+				t.Errorf("cpu counters = %+v", c)
+			}
+			if m.MemoryUsedMB != 3174 || m.MemoryLimitMB != 16000 || m.MemoryRSSMB != 2800 || m.MemoryCacheMB != 300 {
+				t.Errorf("memory = used %d limit %d rss %d cache %d", m.MemoryUsedMB, m.MemoryLimitMB, m.MemoryRSSMB, m.MemoryCacheMB)
+			}
+			if c.DiskReadBytes != 210763776 || c.DiskWriteBytes != 52428800 {
+				t.Errorf("disk = %d/%d", c.DiskReadBytes, c.DiskWriteBytes)
+			}
+			if m.PIDs != 61 || m.PIDsLimit != 4096 {
+				t.Errorf("pids = %d/%d", m.PIDs, m.PIDsLimit)
+			}
+			// Loopback is excluded
+			if c.NetRxBytes != 734003200 || c.NetTxBytes != 1468006400 {
+				t.Errorf("network = %d/%d", c.NetRxBytes, c.NetTxBytes)
+			}
+		})
+	}
+}
+
+func TestCgroupReaderV1(t *testing.T) {
+	root, procRoot := t.TempDir(), t.TempDir()
+	path := "docker/" + testContainerID
+	writeTree(t, root, map[string]string{
+		"cpu,cpuacct/" + path + "/cpuacct.usage":                       "100000000000\n",
+		"cpu,cpuacct/" + path + "/cpu.stat":                            "nr_periods 500\nnr_throttled 50\nthrottled_time 2000000000\n",
+		"memory/" + path + "/memory.usage_in_bytes":                    "2147483648\n",
+		"memory/" + path + "/memory.limit_in_bytes":                    "9223372036854771712\n",
+		"memory/" + path + "/memory.stat":                              "cache 268435456\nrss 1610612736\ntotal_cache 272629760\ntotal_rss 1614807040\n",
+		"memory/" + path + "/cgroup.procs":                             "4242\n",
+		"blkio/" + path + "/blkio.throttle.io_service_bytes_recursive": "8:0 Read 104857600\n8:0 Write 20971520\n8:0 Sync 125829120\n8:0 Async 0\n8:0 Total 125829120\nTotal 125829120\n",
+		"pids/" + path + "/pids.current":                               "48\n",
+		"pids/" + path + "/pids.max":                                   "max\n",
+	})
+	writeTree(t, procRoot, procFiles("4242", path))
+
+	r := newCgroupReader(root, procRoot)
+	if r == nil || r.v2 {
+		t.Fatal("cgroup v1 hierarchy not detected")
+	}
+	var m ContainerMetrics
+	c, err := r.sample(testContainerID, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.CPUUsageNs != 100e9 || c.CPUPeriods != 500 || c.CPUThrottledPeriods != 50 || c.CPUThrottledTimeNs != 2e9 {
+		t.Errorf("cpu counters = %+v", c)
+	}
+	// An unlimited v1 limit falls back to MemTotal
+	if m.MemoryUsedMB != 2048 || m.MemoryLimitMB != 16000 || m.MemoryRSSMB != 1540 || m.MemoryCacheMB != 260 {
+		t.Errorf("memory = used %d limit %d rss %d cache %d", m.MemoryUsedMB, m.MemoryLimitMB, m.MemoryRSSMB, m.MemoryCacheMB)
+	}
+	if c.DiskReadBytes != 104857600 || c.DiskWriteBytes != 20971520 {
+		t.Errorf("disk = %d/%d", c.DiskReadBytes, c.DiskWriteBytes)
+	}
+	if m.PIDs != 48 || m.PIDsLimit != 0 {
+		t.Errorf("pids = %d/%d", m.PIDs, m.PIDsLimit)
+	}
+	if c.NetRxBytes != 734003200 {
+		t.Errorf("network rx = %d", c.NetRxBytes)
+	}
+}
+
+func TestCgroupReaderNotFound(t *testing.T) {
+	root, procRoot := t.TempDir(), t.TempDir()
+	writeTree(t, root, cgroupV2Files("system.slice/docker-other.scope"))
+
+	r := newCgroupReader(root, procRoot)
+	var m ContainerMetrics
+	if _, err := r.sample(testContainerID, &m); !errors.Is(err, errCgroupNotFound) {
+		t.Fatalf("sample error = %v, want errCgroupNotFound", err)
+	}
+}
+
+func TestCgroupReaderNetworkAcrossPIDNamespaces(t *testing.T) {
+	dir := "system.slice/docker-" + testContainerID + ".scope"
+
+	t.Run("cgroup.procs from another namespace", func(t *testing.T) {
+		// The agent runs in its own PID namespace: cgroup.procs lists container processes as 0,
+		// and procRoot is the host's /proc, where the daemon's PID for the container is valid
+		root, procRoot := t.TempDir(), t.TempDir()
+		files := cgroupV2Files(dir)
+		files[dir+"/cgroup.procs"] = "0\n0\n"
+		writeTree(t, root, files)
+		writeTree(t, procRoot, procFiles("31337", dir))
+
+		r := newCgroupReader(root, procRoot)
+		var m ContainerMetrics
+		if c, _ := r.sample(testContainerID, &m); c.NetRxBytes != 0 {
+			t.Fatalf("network read without a visible PID: %d", c.NetRxBytes)
+		}
+
+		r.pidOf = func(string) (int, error) { return 31337, nil }
+		c, err := r.sample(testContainerID, &m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.NetRxBytes != 734003200 {
+			t.Errorf("network rx = %d, want the counters of the daemon's PID", c.NetRxBytes)
+		}
+	})
+
+	t.Run("cgroup.procs PID of an unrelated host process", func(t *testing.T) {
+		// PID 1 in the agent's namespace is the host's init in procRoot
+		root, procRoot := t.TempDir(), t.TempDir()
+		files := cgroupV2Files(dir)
+		files[dir+"/cgroup.procs"] = "1\n"
+		writeTree(t, root, files)
+		procs := procFiles("31337", dir)
+		procs["1/net/dev"] = "  eth0: 999 0 0 0 0 0 0 0 999 0 0 0 0 0 0 0\n"
+		writeTree(t, procRoot, procs)
+
+		r := newCgroupReader(root, procRoot)
+		var m ContainerMetrics
+		if c, _ := r.sample(testContainerID, &m); c.NetRxBytes != 0 {
+			t.Fatalf("read another process's network counters: %d", c.NetRxBytes)
+		}
+	})
+
+	t.Run("restarted container", func(t *testing.T) {
+		root, procRoot := t.TempDir(), t.TempDir()
+		writeTree(t, root, cgroupV2Files(dir))
+		writeTree(t, procRoot, procFiles("31337", dir))
+
+		pid := 31337
+		r := newCgroupReader(root, procRoot)
+		r.pidOf = func(string) (int, error) { return pid, nil }
+		var m ContainerMetrics
+		if _, err := r.sample(testContainerID, &m); err != nil {
+			t.Fatal(err)
+		}
+
+		// The old process is gone; the cached PID must be looked up again
+		os.RemoveAll(filepath.Join(procRoot, "31337"))
+		writeTree(t, procRoot, procFiles("40000", dir))
+		pid = 40000
+		c, err := r.sample(testContainerID, &m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.NetRxBytes != 734003200 {
+			t.Errorf("network rx = %d after restart", c.NetRxBytes)
+		}
+	})
+}
+
+func TestFindCgroupDepth(t *testing.T) {
+	base := t.TempDir()
+	deep := "a/b/c/d/docker-" + testContainerID + ".scope"
+	writeTree(t, base, map[string]string{deep + "/cpu.stat": ""})
+	if _, err := findCgroup(base, testContainerID); err == nil {
+		t.Error("found a cgroup below the search depth")
+	}
+
+	shallow := "a/b/docker-" + testContainerID + ".scope"
+	writeTree(t, base, map[string]string{shallow + "/cpu.stat": ""})
+	if got, err := findCgroup(base, testContainerID); err != nil || got != shallow {
+		t.Errorf("findCgroup = %q, %v, want %q", got, err, shallow)
+	}
+}
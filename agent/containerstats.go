@@ -0,0 +1,148 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// containerSampleMaxAge drops samples of containers that are no longer sampled (stopped or removed)
+const containerSampleMaxAge = 10 * time.Minute
+
+// containerCounters are the cumulative counters of one container sample that rates are derived from
+type containerCounters struct {
+	Time                time.Time
+	CPUUsageNs          uint64
+	NetRxBytes          uint64
+	NetTxBytes          uint64
+	DiskReadBytes       uint64
+	DiskWriteBytes      uint64
+	CPUPeriods          uint64
+	CPUThrottledPeriods uint64
+	CPUThrottledTimeNs  uint64
+}
+
+// containerRateTracker keeps the previous sample per container so counters can be turned into rates
+type containerRateTracker struct {
+	mu      sync.Mutex
+	samples map[string]containerCounters // containerID -> previous sample
+}
+
+func newContainerRateTracker() *containerRateTracker {
+	return &containerRateTracker{samples: make(map[string]containerCounters)}
+}
+
+// observe stores cur as the container's latest sample and fills the rate fields of m from the
+// previous one, which is also returned. The first sample of a container, and any counter that went
+// backwards (container restarted, or counters reset), yields a rate of 0.
+func (t *containerRateTracker) observe(containerID string, cur containerCounters, m *ContainerMetrics) (containerCounters, bool) {
+	t.mu.Lock()
+	prev, ok := t.samples[containerID]
+	if !ok || cur.Time.After(prev.Time) {
+		t.samples[containerID] = cur
+	}
+	for id, s := range t.samples {
+		if cur.Time.Sub(s.Time) > containerSampleMaxAge {
+			delete(t.samples, id)
+		}
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		return prev, false
+	}
+	applyContainerRates(prev, cur, m)
+	return prev, true
+}
+
+// applyContainerRates fills the per-second rates and throttling share of m from two samples
+func applyContainerRates(prev, cur containerCounters, m *ContainerMetrics) {
+	elapsed := cur.Time.Sub(prev.Time).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	m.NetRxBytesPerSec = counterRate(prev.NetRxBytes, cur.NetRxBytes, elapsed)
+	m.NetTxBytesPerSec = counterRate(prev.NetTxBytes, cur.NetTxBytes, elapsed)
+	m.DiskReadBytesPerSec = counterRate(prev.DiskReadBytes, cur.DiskReadBytes, elapsed)
+	m.DiskWriteBytesPerSec = counterRate(prev.DiskWriteBytes, cur.DiskWriteBytes, elapsed)
+
+	if cur.CPUPeriods > prev.CPUPeriods && cur.CPUThrottledPeriods >= prev.CPUThrottledPeriods {
+		m.CPUThrottledPercent = float64(cur.CPUThrottledPeriods-prev.CPUThrottledPeriods) /
+			float64(cur.CPUPeriods-prev.CPUPeriods) * 100
+	}
+}
+
+// cpuPercentFromCounters returns CPU usage in percent of one core between two samples, matching
+// what the Docker stats API reports
+func cpuPercentFromCounters(prev, cur containerCounters) float64 {
+	elapsed := cur.Time.Sub(prev.Time).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return counterRate(prev.CPUUsageNs, cur.CPUUsageNs, elapsed) / 1e9 * 100
+}
+
+// counterRate returns the per-second increase of a counter, or 0 if it was reset
+func counterRate(prev, cur uint64, elapsed float64) float64 {
+	if cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / elapsed
+}
+
+// countersFromStats extracts cumulative counters and point-in-time values from a Docker stats sample
+func countersFromStats(stats *StatsData, m *ContainerMetrics) containerCounters {
+	c := containerCounters{Time: stats.Read, CPUUsageNs: stats.CPUStats.CPUUsage.TotalUsage}
+	if c.Time.IsZero() {
+		c.Time = time.Now()
+	}
+
+	for _, n := range stats.Networks {
+		c.NetRxBytes += n.RxBytes
+		c.NetTxBytes += n.TxBytes
+	}
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "read", "Read":
+			c.DiskReadBytes += entry.Value
+		case "write", "Write":
+			c.DiskWriteBytes += entry.Value
+		}
+	}
+	throttling := stats.CPUStats.ThrottlingData
+	c.CPUPeriods = throttling.Periods
+	c.CPUThrottledPeriods = throttling.ThrottledPeriods
+	c.CPUThrottledTimeNs = throttling.ThrottledTime
+
+	c.apply(m)
+	m.PIDs = stats.PidsStats.Current
+	m.PIDsLimit = stats.PidsStats.Limit
+	m.MemoryRSSMB, m.MemoryCacheMB = memoryBreakdown(stats.MemoryStats.Stats)
+	return c
+}
+
+// apply copies the cumulative counters into m
+func (c containerCounters) apply(m *ContainerMetrics) {
+	m.NetRxBytes = c.NetRxBytes
+	m.NetTxBytes = c.NetTxBytes
+	m.DiskReadBytes = c.DiskReadBytes
+	m.DiskWriteBytes = c.DiskWriteBytes
+	m.DiskReadMB = int64(c.DiskReadBytes / 1024 / 1024)
+	m.DiskWriteMB = int64(c.DiskWriteBytes / 1024 / 1024)
+	m.CPUThrottledPeriods = c.CPUThrottledPeriods
+	m.CPUThrottledSeconds = float64(c.CPUThrottledTimeNs) / 1e9
+}
+
+// memoryBreakdown returns resident (anonymous) and page cache memory in MB. cgroup v1 reports
+// rss/cache (total_* include child cgroups); cgroup v2 reports anon/file.
+func memoryBreakdown(stats map[string]uint64) (rssMB, cacheMB int64) {
+	pick := func(keys ...string) int64 {
+		for _, k := range keys {
+			if v, ok := stats[k]; ok {
+				return int64(v / 1024 / 1024)
+			}
+		}
+		return 0
+	}
+	return pick("total_rss", "rss", "anon"), pick("total_cache", "cache", "file")
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadStats reads a captured `docker stats --no-stream` API payload
+func loadStats(t *testing.T, name string) *StatsData {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "dockerstats", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats StatsData
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatal(err)
+	}
+	return &stats
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestCalculateCPUPercent(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    float64
+	}{
+		{"cgroupv2-1.json", 150}, // 1.5 cores busy of 4 online
+		{"cgroupv2-2.json", 150}, // Same load a sample later
+		{"cgroupv1.json", 100},   // No online_cpus: falls back to len(percpu_usage)
+		{"restarted.json", 0},    // Usage below the pre-sample after a restart
+	}
+	for _, tt := range tests {
+		if got := calculateCPUPercent(loadStats(t, tt.fixture)); !approx(got, tt.want) {
+			t.Errorf("%s: CPU = %v%%, want %v%%", tt.fixture, got, tt.want)
+		}
+	}
+}
+
+func TestCountersFromStats(t *testing.T) {
+	tests := []struct {
+		fixture          string
+		rxBytes, txBytes uint64
+		readMB, writeMB  int64
+		rssMB, cacheMB   int64
+		pids, pidsLimit  uint64
+		throttledPeriods uint64
+		throttledSeconds float64
+	}{
+		// cgroup v2 reports anon/file and lowercase blkio ops
+		{"cgroupv2-1.json", 734003200, 1468006400, 200, 50, 2800, 300, 61, 4096, 300, 45},
+		// cgroup v1 reports total_rss/total_cache, capitalized ops plus Sync/Total, and has no pids limit;
+		// counters of every interface are summed
+		{"cgroupv1.json", 1572864, 2097152, 100, 20, 1540, 260, 48, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		var m ContainerMetrics
+		countersFromStats(loadStats(t, tt.fixture), &m)
+		if m.NetRxBytes != tt.rxBytes || m.NetTxBytes != tt.txBytes {
+			t.Errorf("%s: network = %d/%d, want %d/%d", tt.fixture, m.NetRxBytes, m.NetTxBytes, tt.rxBytes, tt.txBytes)
+		}
+		if m.DiskReadMB != tt.readMB || m.DiskWriteMB != tt.writeMB {
+			t.Errorf("%s: disk = %d/%d MB, want %d/%d", tt.fixture, m.DiskReadMB, m.DiskWriteMB, tt.readMB, tt.writeMB)
+		}
+		if m.MemoryRSSMB != tt.rssMB || m.MemoryCacheMB != tt.cacheMB {
+			t.Errorf("%s: memory = %d/%d MB, want %d/%d", tt.fixture, m.MemoryRSSMB, m.MemoryCacheMB, tt.rssMB, tt.cacheMB)
+		}
+		if m.PIDs != tt.pids || m.PIDsLimit != tt.pidsLimit {
+			t.Errorf("%s: pids = %d/%d, want %d/%d", tt.fixture, m.PIDs, m.PIDsLimit, tt.pids, tt.pidsLimit)
+		}
+		if m.CPUThrottledPeriods != tt.throttledPeriods || !approx(m.CPUThrottledSeconds, tt.throttledSeconds) {
+			t.Errorf("%s: throttled = %d periods/%vs, want %d/%vs", tt.fixture, m.CPUThrottledPeriods, m.CPUThrottledSeconds, tt.throttledPeriods, tt.throttledSeconds)
+		}
+	}
+}
+
+func TestContainerRateTracker(t *testing.T) {
+	const id = "4f1c2a9e8b7d"
+	tracker := newContainerRateTracker()
+
+	var first ContainerMetrics
+	if _, ok := tracker.observe(id, countersFromStats(loadStats(t, "cgroupv2-1.json"), &first), &first); ok {
+		t.Fatal("first sample has a previous sample")
+	}
+	if first.NetRxBytesPerSec != 0 || first.CPUThrottledPercent != 0 {
+		t.Errorf("first sample rates = %+v, want 0", first)
+	}
+
+	// Samples 10s apart
+	var second ContainerMetrics
+	cur := countersFromStats(loadStats(t, "cgroupv2-2.json"), &second)
+	prev, ok := tracker.observe(id, cur, &second)
+	if !ok {
+		t.Fatal("second sample has no previous sample")
+	}
+	rates := []struct {
+		name      string
+		got, want float64
+	}{
+		{"net rx", second.NetRxBytesPerSec, 1048576},
+		{"net tx", second.NetTxBytesPerSec, 2097152},
+		{"disk read", second.DiskReadBytesPerSec, 1048576},
+		{"disk write", second.DiskWriteBytesPerSec, 1048576},
+		{"throttled", second.CPUThrottledPercent, 25}, // 25 of 100 periods
+		{"cpu", cpuPercentFromCounters(prev, cur), 150},
+	}
+	for _, r := range rates {
+		if !approx(r.got, r.want) {
+			t.Errorf("%s = %v, want %v", r.name, r.got, r.want)
+		}
+	}
+
+	// Counters went backwards: the container restarted between samples
+	var third ContainerMetrics
+	cur = countersFromStats(loadStats(t, "restarted.json"), &third)
+	prev, _ = tracker.observe(id, cur, &third)
+	if third.NetRxBytesPerSec != 0 || third.NetTxBytesPerSec != 0 || third.DiskReadBytesPerSec != 0 ||
+		third.CPUThrottledPercent != 0 || cpuPercentFromCounters(prev, cur) != 0 {
+		t.Errorf("rates after restart = %+v, want 0", third)
+	}
+}
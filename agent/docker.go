@@ -1,12 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"sort"
 	"strings"
 	"time"
@@ -29,7 +29,9 @@ var RequiredNetworks = []string{"zomboid-backend", "zomboid-servers"}
 
 // DockerClient wraps the Docker client and provides container operations
 type DockerClient struct {
-	cli *client.Client
+	cli     *client.Client
+	rates   *containerRateTracker // Previous stats samples for network/disk rates
+	cgroups *cgroupReader         // Direct cgroup sampling (nil = Docker stats API only)
 }
 
 // NewDockerClient creates a new Docker client
@@ -39,7 +41,35 @@ func NewDockerClient() (*DockerClient, error) {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
 	}
 
-	return &DockerClient{cli: cli}, nil
+	return &DockerClient{cli: cli, rates: newContainerRateTracker()}, nil
+}
+
+// UseCgroupStats samples container stats from the cgroup mount at root when it is readable.
+// An empty root keeps the Docker stats API.
+func (dc *DockerClient) UseCgroupStats(root, procRoot string) {
+	if root != "" {
+		dc.cgroups = newCgroupReader(root, procRoot)
+	}
+	if dc.cgroups != nil {
+		dc.cgroups.pidOf = dc.containerPID
+		dockerLogger.Infof("Sampling container stats from cgroup %s at %s", dc.cgroups.version(), root)
+	} else {
+		dockerLogger.Infof("Sampling container stats through the Docker stats API")
+	}
+}
+
+// containerPID returns the main process of a running container in the host PID namespace
+func (dc *DockerClient) containerPID(containerID string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	inspect, err := dc.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return 0, err
+	}
+	if inspect.State == nil || inspect.State.Pid == 0 {
+		return 0, fmt.Errorf("container %s is not running", containerID)
+	}
+	return inspect.State.Pid, nil
 }
 
 // Close closes the Docker client
@@ -60,13 +90,13 @@ func (dc *DockerClient) GracefulSave(ctx context.Context, containerID string) bo
 
 	inspect, err := dc.cli.ContainerInspect(ctx, containerID)
 	if err != nil {
-		log.Printf("[GracefulSave] Failed to inspect container %s: %v", containerID, err)
+		gracefulSaveLogger.Errorf("Failed to inspect container %s: %v", containerID, err)
 		return false
 	}
 
 	// Only attempt save on running containers
 	if !inspect.State.Running {
-		log.Printf("[GracefulSave] Container %s is not running, skipping save", containerID)
+		gracefulSaveLogger.Infof("Container %s is not running, skipping save", containerID)
 		return false
 	}
 
@@ -78,38 +108,39 @@ func (dc *DockerClient) GracefulSave(ctx context.Context, containerID string) bo
 			rconPort = strings.TrimPrefix(env, "RCON_PORT=")
 		} else if strings.HasPrefix(env, "RCON_PASSWORD=") {
 			rconPassword = strings.TrimPrefix(env, "RCON_PASSWORD=")
+			RegisterSecret(rconPassword)
 		}
 	}
 
 	if rconPort == "" || rconPassword == "" {
-		log.Printf("[GracefulSave] Container %s missing RCON_PORT or RCON_PASSWORD ENV, skipping save", containerID)
+		gracefulSaveLogger.Warnf("Container %s missing RCON_PORT or RCON_PASSWORD ENV, skipping save", containerID)
 		return false
 	}
 
 	// Get container IP on zomboid-backend network
 	net := inspect.NetworkSettings.Networks["zomboid-backend"]
 	if net == nil || net.IPAddress == "" {
-		log.Printf("[GracefulSave] Container %s not on zomboid-backend network, skipping save", containerID)
+		gracefulSaveLogger.Warnf("Container %s not on zomboid-backend network, skipping save", containerID)
 		return false
 	}
 
 	addr := fmt.Sprintf("%s:%s", net.IPAddress, rconPort)
-	log.Printf("[GracefulSave] Connecting to RCON at %s for pre-stop save", addr)
+	gracefulSaveLogger.Infof("Connecting to RCON at %s for pre-stop save", addr)
 
 	conn, err := rcon.Dial(addr, rconPassword, rcon.SetDialTimeout(5*time.Second))
 	if err != nil {
-		log.Printf("[GracefulSave] RCON connection failed: %v", err)
+		gracefulSaveLogger.Warnf("RCON connection failed: %v", err)
 		return false
 	}
 	defer conn.Close()
 
 	_, err = conn.Execute("save")
 	if err != nil {
-		log.Printf("[GracefulSave] RCON save command failed: %v", err)
+		gracefulSaveLogger.Warnf("RCON save command failed: %v", err)
 		return false
 	}
 
-	log.Printf("[GracefulSave] Save command sent, waiting 3s for disk flush")
+	gracefulSaveLogger.Infof("Save command sent, waiting 3s for disk flush")
 	time.Sleep(3 * time.Second)
 	return true
 }
@@ -132,11 +163,11 @@ func (dc *DockerClient) EnsureNetworks(ctx context.Context) error {
 	// Create missing networks
 	for _, netName := range RequiredNetworks {
 		if existing[netName] {
-			log.Printf("Docker network '%s' already exists", netName)
+			dockerLogger.Infof("Docker network '%s' already exists", netName)
 			continue
 		}
 
-		log.Printf("Creating Docker network: %s", netName)
+		dockerLogger.Infof("Creating Docker network: %s", netName)
 		_, err := dc.cli.NetworkCreate(ctx, netName, network.CreateOptions{
 			Driver: "bridge",
 			Labels: map[string]string{
@@ -146,7 +177,7 @@ func (dc *DockerClient) EnsureNetworks(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("failed to create network %s: %w", netName, err)
 		}
-		log.Printf("Created Docker network: %s", netName)
+		dockerLogger.Infof("Created Docker network: %s", netName)
 	}
 
 	return nil
@@ -209,20 +240,20 @@ func (dc *DockerClient) ListContainers(ctx context.Context) ([]ContainerInfo, er
 
 // StartContainer starts a container by ID
 func (dc *DockerClient) StartContainer(ctx context.Context, containerID string) error {
-	log.Printf("Starting container: %s", containerID)
+	dockerLogger.Infof("Starting container: %s", containerID)
 
 	err := dc.cli.ContainerStart(ctx, containerID, container.StartOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to start container %s: %w", containerID, err)
 	}
 
-	log.Printf("Container started successfully: %s", containerID)
+	dockerLogger.Infof("Container started successfully: %s", containerID)
 	return nil
 }
 
 // StopContainer stops a container by ID with graceful RCON save
 func (dc *DockerClient) StopContainer(ctx context.Context, containerID string) error {
-	log.Printf("Stopping container: %s", containerID)
+	dockerLogger.Infof("Stopping container: %s", containerID)
 
 	// Attempt graceful save before stopping
 	dc.GracefulSave(ctx, containerID)
@@ -233,13 +264,13 @@ func (dc *DockerClient) StopContainer(ctx context.Context, containerID string) e
 		return fmt.Errorf("failed to stop container %s: %w", containerID, err)
 	}
 
-	log.Printf("Container stopped successfully: %s", containerID)
+	dockerLogger.Infof("Container stopped successfully: %s", containerID)
 	return nil
 }
 
 // RestartContainer restarts a container by ID with graceful RCON save
 func (dc *DockerClient) RestartContainer(ctx context.Context, containerID string) error {
-	log.Printf("Restarting container: %s", containerID)
+	dockerLogger.Infof("Restarting container: %s", containerID)
 
 	// Attempt graceful save before restarting
 	dc.GracefulSave(ctx, containerID)
@@ -250,7 +281,7 @@ func (dc *DockerClient) RestartContainer(ctx context.Context, containerID string
 		return fmt.Errorf("failed to restart container %s: %w", containerID, err)
 	}
 
-	log.Printf("Container restarted successfully: %s", containerID)
+	dockerLogger.Infof("Container restarted successfully: %s", containerID)
 	return nil
 }
 
@@ -345,9 +376,22 @@ type LogLine struct {
 	Message     string `json:"message"`
 }
 
+// LogStreamOptions selects which container output StreamContainerLogsWithOptions returns
+type LogStreamOptions struct {
+	Follow bool      // Keep streaming new lines until ctx is cancelled
+	Tail   int       // Number of lines from the end (-1 = all; 0 with Since set = all lines since)
+	Since  time.Time // Only lines after this time (zero = no lower bound)
+	Until  time.Time // Only lines before this time (zero = no upper bound)
+}
+
 // StreamContainerLogs streams logs from a container
 // Returns a channel that receives log lines and an error channel
 func (dc *DockerClient) StreamContainerLogs(ctx context.Context, containerID string, tail int) (<-chan LogLine, <-chan error) {
+	return dc.StreamContainerLogsWithOptions(ctx, containerID, LogStreamOptions{Follow: true, Tail: tail})
+}
+
+// StreamContainerLogsWithOptions streams logs from a container with explicit follow/tail/since options
+func (dc *DockerClient) StreamContainerLogsWithOptions(ctx context.Context, containerID string, opts LogStreamOptions) (<-chan LogLine, <-chan error) {
 	logChan := make(chan LogLine, 100) // Buffer for log lines
 	errChan := make(chan error, 1)
 
@@ -359,9 +403,25 @@ func (dc *DockerClient) StreamContainerLogs(ctx context.Context, containerID str
 		options := container.LogsOptions{
 			ShowStdout: true,
 			ShowStderr: true,
-			Follow:     true,
+			Follow:     opts.Follow,
 			Timestamps: true,
-			Tail:       fmt.Sprintf("%d", tail),
+		}
+		if opts.Tail < 0 {
+			options.Tail = "all"
+		} else if opts.Tail > 0 || opts.Since.IsZero() {
+			options.Tail = fmt.Sprintf("%d", opts.Tail)
+		}
+		if !opts.Since.IsZero() {
+			options.Since = fmt.Sprintf("%d.%09d", opts.Since.Unix(), opts.Since.Nanosecond())
+		}
+		if !opts.Until.IsZero() {
+			options.Until = fmt.Sprintf("%d.%09d", opts.Until.Unix(), opts.Until.Nanosecond())
+		}
+
+		// TTY containers write a raw stream without multiplexing headers
+		tty := false
+		if inspect, err := dc.cli.ContainerInspect(ctx, containerID); err == nil && inspect.Config != nil {
+			tty = inspect.Config.Tty
 		}
 
 		// Get log stream
@@ -372,69 +432,35 @@ func (dc *DockerClient) StreamContainerLogs(ctx context.Context, containerID str
 		}
 		defer reader.Close()
 
-		log.Printf("Started streaming logs for container: %s", containerID)
+		if opts.Follow {
+			dockerLogger.Infof("Started streaming logs for container: %s", containerID)
+		}
 
-		// Docker uses multiplexed stream format
-		// Read 8-byte header + payload
-		header := make([]byte, 8)
+		decoder := NewLogDecoder(reader, tty)
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("Stopped streaming logs for container: %s", containerID)
+				if opts.Follow {
+					dockerLogger.Infof("Stopped streaming logs for container: %s", containerID)
+				}
 				return
 			default:
 			}
 
-			// Read header
-			_, err := io.ReadFull(reader, header)
+			logLine, err := decoder.Next()
 			if err != nil {
 				if err == io.EOF {
-					log.Printf("Log stream ended for container: %s", containerID)
+					if opts.Follow {
+						dockerLogger.Infof("Log stream ended for container: %s", containerID)
+					}
 					return
 				}
-				errChan <- fmt.Errorf("failed to read log header: %w", err)
-				return
-			}
-
-			// Parse header
-			// Byte 0: Stream type (0=stdin, 1=stdout, 2=stderr)
-			// Bytes 4-7: Frame size (big-endian uint32)
-			streamType := header[0]
-			frameSize := binary.BigEndian.Uint32(header[4:8])
-
-			// Read payload
-			payload := make([]byte, frameSize)
-			_, err = io.ReadFull(reader, payload)
-			if err != nil {
-				errChan <- fmt.Errorf("failed to read log payload: %w", err)
+				if ctx.Err() == nil {
+					errChan <- err
+				}
 				return
 			}
-
-			// Parse log line
-			// Docker format with timestamps: "2024-01-10T12:34:56.789Z message here"
-			line := string(payload)
-
-			// Determine stream name
-			var stream string
-			switch streamType {
-			case 1:
-				stream = "stdout"
-			case 2:
-				stream = "stderr"
-			default:
-				stream = "unknown"
-			}
-
-			// Parse timestamp and message
-			timestamp, message := parseLogLine(line)
-
-			// Send log line to channel
-			logLine := LogLine{
-				ContainerID: containerID,
-				Timestamp:   timestamp,
-				Stream:      stream,
-				Message:     message,
-			}
+			logLine.ContainerID = containerID
 
 			select {
 			case logChan <- logLine:
@@ -448,6 +474,70 @@ func (dc *DockerClient) StreamContainerLogs(ctx context.Context, containerID str
 	return logChan, errChan
 }
 
+// LogDecoder splits a Docker log stream (requested with timestamps) into log lines.
+// Non-TTY containers use the multiplexed format: an 8-byte header per frame
+// (byte 0 = stream type, bytes 4-7 = big-endian payload size) followed by the payload.
+// TTY containers write a raw stream with no headers, which is split on newlines.
+type LogDecoder struct {
+	reader *bufio.Reader
+	tty    bool
+	header [8]byte
+}
+
+// NewLogDecoder creates a decoder for a Docker log stream
+func NewLogDecoder(r io.Reader, tty bool) *LogDecoder {
+	return &LogDecoder{reader: bufio.NewReader(r), tty: tty}
+}
+
+// Next returns the next log line, or io.EOF at the end of the stream.
+// ContainerID is left for the caller to fill in.
+func (d *LogDecoder) Next() (LogLine, error) {
+	if d.tty {
+		line, err := d.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return LogLine{}, err
+		}
+		timestamp, message := parseLogLine(strings.TrimRight(line, "\r\n"))
+		return LogLine{Timestamp: timestamp, Stream: "stdout", Message: message}, nil
+	}
+
+	// Read header
+	if _, err := io.ReadFull(d.reader, d.header[:]); err != nil {
+		if err == io.EOF {
+			return LogLine{}, io.EOF
+		}
+		return LogLine{}, fmt.Errorf("failed to read log header: %w", err)
+	}
+
+	// Parse header
+	// Byte 0: Stream type (0=stdin, 1=stdout, 2=stderr)
+	// Bytes 4-7: Frame size (big-endian uint32)
+	streamType := d.header[0]
+	frameSize := binary.BigEndian.Uint32(d.header[4:8])
+
+	// Read payload
+	payload := make([]byte, frameSize)
+	if _, err := io.ReadFull(d.reader, payload); err != nil {
+		return LogLine{}, fmt.Errorf("failed to read log payload: %w", err)
+	}
+
+	// Determine stream name
+	var stream string
+	switch streamType {
+	case 1:
+		stream = "stdout"
+	case 2:
+		stream = "stderr"
+	default:
+		stream = "unknown"
+	}
+
+	// Parse timestamp and message
+	// Docker format with timestamps: "2024-01-10T12:34:56.789Z message here"
+	timestamp, message := parseLogLine(string(payload))
+	return LogLine{Timestamp: timestamp, Stream: stream, Message: message}, nil
+}
+
 // parseLogLine parses a Docker log line with timestamp
 // Format: "2024-01-10T12:34:56.789123456Z message here"
 // Returns timestamp in milliseconds and the message
@@ -485,21 +575,47 @@ type ContainerMetrics struct {
 	CPUPercent    float64 `json:"cpuPercent"`
 	MemoryUsedMB  int64   `json:"memoryUsedMB"`
 	MemoryLimitMB int64   `json:"memoryLimitMB"`
+	MemoryRSSMB   int64   `json:"memoryRssMB"`   // Anonymous (resident) memory
+	MemoryCacheMB int64   `json:"memoryCacheMB"` // Page cache, reclaimable
 	DiskReadMB    int64   `json:"diskReadMB"`
 	DiskWriteMB   int64   `json:"diskWriteMB"`
 	Uptime        string  `json:"uptime"`        // Human-readable: "2h 34m"
 	UptimeSeconds int64   `json:"uptimeSeconds"` // Raw seconds for calculations
+
+	// Cumulative counters since container start
+	NetRxBytes          uint64  `json:"netRxBytes"`
+	NetTxBytes          uint64  `json:"netTxBytes"`
+	DiskReadBytes       uint64  `json:"diskReadBytes"`
+	DiskWriteBytes      uint64  `json:"diskWriteBytes"`
+	CPUThrottledPeriods uint64  `json:"cpuThrottledPeriods"`
+	CPUThrottledSeconds float64 `json:"cpuThrottledSeconds"`
+
+	// Rates since the previous sample of the container (0 on the first sample)
+	NetRxBytesPerSec     float64 `json:"netRxBytesPerSec"`
+	NetTxBytesPerSec     float64 `json:"netTxBytesPerSec"`
+	DiskReadBytesPerSec  float64 `json:"diskReadBytesPerSec"`
+	DiskWriteBytesPerSec float64 `json:"diskWriteBytesPerSec"`
+	CPUThrottledPercent  float64 `json:"cpuThrottledPercent"` // Share of CFS periods that were throttled
+
+	PIDs      uint64 `json:"pids"`
+	PIDsLimit uint64 `json:"pidsLimit,omitempty"` // 0 = unlimited
 }
 
 // StatsData represents Docker container stats JSON structure
 type StatsData struct {
+	Read     time.Time `json:"read"`
 	CPUStats struct {
 		CPUUsage struct {
 			TotalUsage  uint64   `json:"total_usage"`
 			PercpuUsage []uint64 `json:"percpu_usage"`
 		} `json:"cpu_usage"`
-		SystemUsage uint64 `json:"system_cpu_usage"`
-		OnlineCPUs  uint64 `json:"online_cpus"`
+		SystemUsage    uint64 `json:"system_cpu_usage"`
+		OnlineCPUs     uint64 `json:"online_cpus"`
+		ThrottlingData struct {
+			Periods          uint64 `json:"periods"`
+			ThrottledPeriods uint64 `json:"throttled_periods"`
+			ThrottledTime    uint64 `json:"throttled_time"` // Nanoseconds
+		} `json:"throttling_data"`
 	} `json:"cpu_stats"`
 	PreCPUStats struct {
 		CPUUsage struct {
@@ -508,9 +624,18 @@ type StatsData struct {
 		SystemUsage uint64 `json:"system_cpu_usage"`
 	} `json:"precpu_stats"`
 	MemoryStats struct {
-		Usage uint64 `json:"usage"`
-		Limit uint64 `json:"limit"`
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"` // rss/cache (cgroup v1) or anon/file (cgroup v2)
 	} `json:"memory_stats"`
+	PidsStats struct {
+		Current uint64 `json:"current"`
+		Limit   uint64 `json:"limit"`
+	} `json:"pids_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
 	BlkioStats struct {
 		IoServiceBytesRecursive []struct {
 			Op    string `json:"op"`
@@ -519,8 +644,46 @@ type StatsData struct {
 	} `json:"blkio_stats"`
 }
 
-// CollectContainerMetrics collects resource usage metrics for a specific container
+// CollectContainerMetrics collects resource usage metrics for a specific container.
+// Reads cgroups directly when possible and falls back to the Docker stats API.
 func (dc *DockerClient) CollectContainerMetrics(ctx context.Context, containerID string) (*ContainerMetrics, error) {
+	var metrics *ContainerMetrics
+	if dc.cgroups != nil {
+		metrics = &ContainerMetrics{ContainerID: containerID}
+		counters, err := dc.cgroups.sample(containerID, metrics)
+		if err == nil {
+			// CPU% needs two samples; the first cgroup sample of a container reports 0
+			if prev, ok := dc.rates.observe(containerID, counters, metrics); ok {
+				metrics.CPUPercent = cpuPercentFromCounters(prev, counters)
+			}
+		} else {
+			dockerLogger.Debugf("Cgroup stats unavailable for %s, using Docker API: %v", containerID[:12], err)
+			metrics = nil
+		}
+	}
+
+	if metrics == nil {
+		var err error
+		if metrics, err = dc.collectStatsFromAPI(ctx, containerID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Get uptime
+	uptime, uptimeSeconds, err := dc.GetContainerUptime(ctx, containerID)
+	if err != nil {
+		dockerLogger.Warnf("Failed to get container uptime: %v", err)
+		uptime = "N/A"
+		uptimeSeconds = 0
+	}
+
+	metrics.Uptime = uptime
+	metrics.UptimeSeconds = uptimeSeconds
+	return metrics, nil
+}
+
+// collectStatsFromAPI takes a one-shot sample from the Docker stats API (blocks ~1s for the CPU pre-sample)
+func (dc *DockerClient) collectStatsFromAPI(ctx context.Context, containerID string) (*ContainerMetrics, error) {
 	// Get container stats (one-time snapshot, not streaming)
 	stats, err := dc.cli.ContainerStats(ctx, containerID, false)
 	if err != nil {
@@ -542,38 +705,28 @@ func (dc *DockerClient) CollectContainerMetrics(ctx context.Context, containerID
 	memoryUsedMB := int64(statsJSON.MemoryStats.Usage / 1024 / 1024)
 	memoryLimitMB := int64(statsJSON.MemoryStats.Limit / 1024 / 1024)
 
-	// Get disk I/O stats (convert bytes to MB)
-	var diskReadMB, diskWriteMB int64
-	for _, bioEntry := range statsJSON.BlkioStats.IoServiceBytesRecursive {
-		if bioEntry.Op == "read" || bioEntry.Op == "Read" {
-			diskReadMB += int64(bioEntry.Value / 1024 / 1024)
-		} else if bioEntry.Op == "write" || bioEntry.Op == "Write" {
-			diskWriteMB += int64(bioEntry.Value / 1024 / 1024)
-		}
-	}
-
-	// Get uptime
-	uptime, uptimeSeconds, err := dc.GetContainerUptime(ctx, containerID)
-	if err != nil {
-		log.Printf("Warning: failed to get container uptime: %v", err)
-		uptime = "N/A"
-		uptimeSeconds = 0
-	}
-
-	return &ContainerMetrics{
+	metrics := &ContainerMetrics{
 		ContainerID:   containerID,
 		CPUPercent:    cpuPercent,
 		MemoryUsedMB:  memoryUsedMB,
 		MemoryLimitMB: memoryLimitMB,
-		DiskReadMB:    diskReadMB,
-		DiskWriteMB:   diskWriteMB,
-		Uptime:        uptime,
-		UptimeSeconds: uptimeSeconds,
-	}, nil
+	}
+
+	// Network, disk I/O, PIDs and throttling; rates are relative to the previous sample
+	counters := countersFromStats(&statsJSON, metrics)
+	dc.rates.observe(containerID, counters, metrics)
+	return metrics, nil
 }
 
 // calculateCPUPercent calculates CPU usage percentage from Docker stats
 func calculateCPUPercent(stats *StatsData) float64 {
+	// A counter below its pre-sample means the container restarted in between; the unsigned
+	// difference would wrap around
+	if stats.CPUStats.CPUUsage.TotalUsage < stats.PreCPUStats.CPUUsage.TotalUsage ||
+		stats.CPUStats.SystemUsage < stats.PreCPUStats.SystemUsage {
+		return 0
+	}
+
 	// Calculate the change in CPU usage
 	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage - stats.PreCPUStats.CPUUsage.TotalUsage)
 
@@ -665,7 +818,7 @@ func (dc *DockerClient) GetImageDefaults(ctx context.Context, imageTag string) (
 	inspect, err := dc.cli.ImageInspect(ctx, imageTag)
 	if err != nil {
 		// If direct inspection fails, try to find an image with matching tag
-		log.Printf("Direct inspection of '%s' failed, searching for images with matching tag...", imageTag)
+		dockerLogger.Infof("Direct inspection of '%s' failed, searching for images with matching tag...", imageTag)
 
 		images, listErr := dc.cli.ImageList(ctx, image.ListOptions{})
 		if listErr != nil {
@@ -680,7 +833,7 @@ func (dc *DockerClient) GetImageDefaults(ctx context.Context, imageTag string) (
 				// e.g., "registry.gitlab.../steam-zomboid:latest" matches ":latest"
 				if strings.HasSuffix(tag, ":"+imageTag) || tag == imageTag {
 					matchedImage = tag
-					log.Printf("Found matching image: %s", matchedImage)
+					dockerLogger.Infof("Found matching image: %s", matchedImage)
 					break
 				}
 			}
@@ -709,7 +862,7 @@ func (dc *DockerClient) GetImageDefaults(ctx context.Context, imageTag string) (
 		}
 	}
 
-	log.Printf("Extracted %d ENV defaults from image %s", len(defaults), imageTag)
+	dockerLogger.Infof("Extracted %d ENV defaults from image %s", len(defaults), imageTag)
 	return defaults, nil
 }
 
@@ -720,7 +873,7 @@ func ListRegistryTags(registry string) ([]string, error) {
 		return nil, fmt.Errorf("registry is required")
 	}
 
-	log.Printf("Fetching tags from registry: %s", registry)
+	dockerLogger.Infof("Fetching tags from registry: %s", registry)
 
 	rawTags, err := crane.ListTags(registry)
 	if err != nil {
@@ -748,6 +901,6 @@ func ListRegistryTags(registry string) ([]string, error) {
 		return tags[i] > tags[j]
 	})
 
-	log.Printf("Found %d tags from registry %s", len(tags), registry)
+	dockerLogger.Infof("Found %d tags from registry %s", len(tags), registry)
 	return tags, nil
 }
@@ -0,0 +1,649 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// gameLogKinds are the PZ log file suffixes the tailer follows (Logs/*_{kind}.txt).
+// DebugLog-server produces no game events; it is followed for log alert rules.
+var gameLogKinds = []string{"user", "chat", "pvp", "admin", "cmd", "DebugLog-server"}
+
+// Game event types published as game.event
+const (
+	GameEventConnect      = "connect"
+	GameEventDisconnect   = "disconnect"
+	GameEventChat         = "chat"
+	GameEventPvPKill      = "pvp_kill"
+	GameEventDeath        = "death"
+	GameEventAdminCommand = "admin_command"
+	GameEventItemSpawn    = "item_spawn"
+)
+
+// GameEvent is a structured event parsed from the PZ server log files
+type GameEvent struct {
+	ServerID   string `json:"serverId"`
+	ServerName string `json:"serverName"`
+	Type       string `json:"type"`
+	Timestamp  int64  `json:"timestamp"` // Unix milliseconds
+	Source     string `json:"source"`    // Log kind: user, chat, pvp, admin, cmd
+	Player     string `json:"player,omitempty"`
+	SteamID    string `json:"steamId,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	Target     string `json:"target,omitempty"`   // Victim (pvp_kill), recipient (item_spawn), command target
+	Position   string `json:"position,omitempty"` // "x,y,z"
+	Channel    string `json:"channel,omitempty"`  // Chat channel
+	Message    string `json:"message,omitempty"`  // Chat text or command arguments
+	Command    string `json:"command,omitempty"`
+	Item       string `json:"item,omitempty"`
+	Count      int    `json:"count,omitempty"`
+	Raw        string `json:"raw"`
+}
+
+var (
+	gameLogTimestampRe = regexp.MustCompile(`^\[(\d{2}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?)\]\s*(?:\[[A-Za-z]+\]\s*)?`)
+	gameLogIPRe        = regexp.MustCompile(`\b(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})\b`)
+
+	userConnectRe    = regexp.MustCompile(`(\d{17}) "([^"]*)" fully connected \(([^)]*)\)`)
+	userDisconnectRe = regexp.MustCompile(`(\d{17}) "([^"]*)" disconnected player \(([^)]*)\)`)
+	userSteamIDRe    = regexp.MustCompile(`(\d{17}) "([^"]*)"`)
+	userDeathRe      = regexp.MustCompile(`user (.+?) died at \(([^)]*)\)`)
+	chatMessageRe    = regexp.MustCompile(`ChatMessage\{chat=([^,]*), author='([^']*)', text='(.*)'\}`)
+	pvpKillRe        = regexp.MustCompile(`"([^"]+)" \(([^)]*)\) killed "([^"]+)" \(([^)]*)\)`)
+	adminAddItemRe   = regexp.MustCompile(`^(.+?) added item (\S+?)(?: x(\d+))? in (.+?)'s inventory`)
+	cmdLineRe        = regexp.MustCompile(`^(\d{17}) "([^"]*)" (\S+)\s*(.*?)\.?$`)
+	quotedArgRe      = regexp.MustCompile(`"([^"]*)"|(\S+)`)
+)
+
+// tailedFile tracks the read position in one log file. The file stays open between reads
+// so it can still be drained after PZ moves it into a logs_* subdirectory.
+type tailedFile struct {
+	path   string
+	inode  uint64
+	offset int64
+	file   *os.File
+}
+
+// serverLogTail is the tail state for one server's Logs directory
+type serverLogTail struct {
+	serverID   string
+	serverName string
+	logsDir    string
+	files      map[string]*tailedFile // kind -> file
+	steamIPs   map[string]string      // steamID -> last seen IP
+	primed     bool                   // First scan done (existing content skipped)
+	location   *time.Location         // Zone the server writes log timestamps in
+	observe    GameLogLineObserver    // Optional raw line consumer
+}
+
+// GameLogLineObserver receives every new raw line read from a server's PZ log files,
+// with the zone their timestamps are written in
+type GameLogLineObserver func(serverID, serverName, kind string, lines []string, location *time.Location)
+
+// GameEventObserver receives every parsed game event, whether or not the manager is connected
+type GameEventObserver func(event GameEvent)
+
+// GameLogTailer follows the PZ log files of every managed server and publishes game events
+type GameLogTailer struct {
+	mu            sync.Mutex
+	docker        *DockerClient
+	agent         *Agent
+	servers       map[string]*serverLogTail // serverID -> tail state
+	stopCh        chan struct{}
+	pollInterval  time.Duration
+	discoverEvery time.Duration
+	lastDiscover  time.Time
+	location      *time.Location // Fallback log zone for containers without TZ
+	observe       GameLogLineObserver
+	observeEvent  GameEventObserver
+}
+
+// NewGameLogTailer creates a new game log tailer
+func NewGameLogTailer(docker *DockerClient, agent *Agent) *GameLogTailer {
+	return &GameLogTailer{
+		docker:        docker,
+		agent:         agent,
+		servers:       make(map[string]*serverLogTail),
+		stopCh:        make(chan struct{}),
+		pollInterval:  2 * time.Second,
+		discoverEvery: 30 * time.Second,
+		location:      time.UTC,
+	}
+}
+
+// SetLocation sets the zone log timestamps are parsed in when a server container has no TZ
+// environment variable. Must be called before Start.
+func (t *GameLogTailer) SetLocation(location *time.Location) {
+	t.location = location
+}
+
+// SetLineObserver registers a consumer for raw log lines. Must be called before Start.
+func (t *GameLogTailer) SetLineObserver(observe GameLogLineObserver) {
+	t.observe = observe
+}
+
+// SetEventObserver registers a consumer for parsed game events. Must be called before Start.
+func (t *GameLogTailer) SetEventObserver(observe GameEventObserver) {
+	t.observeEvent = observe
+}
+
+// Start begins the background tail loop
+func (t *GameLogTailer) Start() {
+	gameLogsLogger.Infof("Starting game log tailer (2s interval)")
+	go t.tailLoop()
+}
+
+// Stop stops the tailer
+func (t *GameLogTailer) Stop() {
+	gameLogsLogger.Infof("Stopping game log tailer")
+	close(t.stopCh)
+}
+
+// tailLoop runs the main polling loop
+func (t *GameLogTailer) tailLoop() {
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopCh:
+			t.mu.Lock()
+			for _, tail := range t.servers {
+				tail.close()
+			}
+			t.mu.Unlock()
+			return
+		case <-ticker.C:
+			if time.Since(t.lastDiscover) >= t.discoverEvery {
+				t.discoverServers()
+				t.lastDiscover = time.Now()
+			}
+			t.pollAll()
+		}
+	}
+}
+
+// discoverServers refreshes the set of managed servers and their Logs directories
+func (t *GameLogTailer) discoverServers() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", "zedops.managed=true")
+
+	containers, err := t.docker.cli.ContainerList(ctx, container.ListOptions{
+		All:     true, // Stopped servers keep their logs; we just stop seeing new lines
+		Filters: filterArgs,
+	})
+	if err != nil {
+		gameLogsLogger.Errorf("Failed to list containers: %v", err)
+		return
+	}
+
+	found := make(map[string]bool)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range containers {
+		serverID := c.Labels["zedops.server.id"]
+		if serverID == "" {
+			continue
+		}
+
+		// Container is recreated on rebuild, but the data mount stays the same
+		dataMount := ""
+		for _, m := range c.Mounts {
+			if m.Destination == "/home/steam/Zomboid" {
+				dataMount = m.Source
+				break
+			}
+		}
+		if dataMount == "" {
+			continue
+		}
+		found[serverID] = true
+
+		logsDir := filepath.Join(dataMount, "Logs")
+		if existing, ok := t.servers[serverID]; ok {
+			existing.serverName = c.Labels["zedops.server.name"]
+			if existing.logsDir == logsDir {
+				continue
+			}
+			gameLogsLogger.Infof("Logs directory changed for %s: %s", existing.serverName, logsDir)
+			existing.close()
+		}
+
+		t.servers[serverID] = &serverLogTail{
+			serverID:   serverID,
+			serverName: c.Labels["zedops.server.name"],
+			logsDir:    logsDir,
+			files:      make(map[string]*tailedFile),
+			steamIPs:   make(map[string]string),
+			location:   t.containerLocation(ctx, c.ID),
+			observe:    t.observe,
+		}
+		gameLogsLogger.Infof("Following %s (%s)", c.Labels["zedops.server.name"], logsDir)
+	}
+
+	for serverID, tail := range t.servers {
+		if !found[serverID] {
+			gameLogsLogger.Infof("No longer following %s", tail.serverName)
+			tail.close()
+			delete(t.servers, serverID)
+		}
+	}
+}
+
+// containerLocation returns the zone a server writes its logs in: the container's TZ
+// environment variable if set and known, otherwise the configured fallback
+func (t *GameLogTailer) containerLocation(ctx context.Context, containerID string) *time.Location {
+	inspect, err := t.docker.cli.ContainerInspect(ctx, containerID)
+	if err != nil || inspect.Config == nil {
+		return t.location
+	}
+	for _, env := range inspect.Config.Env {
+		tz, ok := strings.CutPrefix(env, "TZ=")
+		if !ok || tz == "" {
+			continue
+		}
+		location, err := time.LoadLocation(strings.TrimPrefix(tz, ":"))
+		if err != nil {
+			gameLogsLogger.Warnf("Unknown TZ %q on container %s, using %s", tz, containerID[:12], t.location)
+			return t.location
+		}
+		return location
+	}
+	return t.location
+}
+
+// pollAll reads new lines from every followed server
+func (t *GameLogTailer) pollAll() {
+	t.mu.Lock()
+	tails := make([]*serverLogTail, 0, len(t.servers))
+	for _, tail := range t.servers {
+		tails = append(tails, tail)
+	}
+	t.mu.Unlock()
+
+	for _, tail := range tails {
+		for _, event := range tail.poll() {
+			if t.observeEvent != nil {
+				t.observeEvent(event)
+			}
+			t.publish(event)
+		}
+	}
+}
+
+// poll reads new lines from all log kinds of this server.
+// On the first poll existing content is skipped so a restart doesn't replay old events.
+func (s *serverLogTail) poll() []GameEvent {
+	var events []GameEvent
+
+	for _, kind := range gameLogKinds {
+		current := s.files[kind]
+		newest := newestLogFile(s.logsDir, kind)
+
+		if newest == "" {
+			continue
+		}
+
+		if current == nil || current.path != newest {
+			// Rotation: PZ starts a new timestamped file on restart and moves the old one
+			// into a logs_* subdirectory. Drain whatever is left of the old file first,
+			// through the handle that is still open on it.
+			if current != nil {
+				events = append(events, s.parseLines(kind, current.drain())...)
+				current.close()
+			}
+
+			next := &tailedFile{path: newest}
+			if info, err := os.Stat(newest); err == nil {
+				next.inode = fileInode(info)
+				if !s.primed {
+					next.offset = info.Size()
+				}
+			}
+			s.files[kind] = next
+			current = next
+		}
+
+		lines, err := current.readNew()
+		if err != nil {
+			if !os.IsNotExist(err) {
+				gameLogsLogger.Errorf("Failed to read %s: %v", current.path, err)
+			}
+			continue
+		}
+		events = append(events, s.parseLines(kind, lines)...)
+	}
+
+	s.primed = true
+	return events
+}
+
+// parseLines turns raw log lines into events, remembering SteamID -> IP along the way
+func (s *serverLogTail) parseLines(kind string, lines []string) []GameEvent {
+	if s.observe != nil && len(lines) > 0 {
+		s.observe(s.serverID, s.serverName, kind, lines, s.location)
+	}
+
+	var events []GameEvent
+	for _, line := range lines {
+		if kind == "user" {
+			if m := userSteamIDRe.FindStringSubmatch(line); m != nil {
+				if ip := gameLogIPRe.FindString(line); ip != "" {
+					s.steamIPs[m[1]] = ip
+				}
+			}
+		}
+
+		event, ok := parseGameLogLine(kind, line, s.location)
+		if !ok {
+			continue
+		}
+		event.ServerID = s.serverID
+		event.ServerName = s.serverName
+		if event.IP == "" && event.SteamID != "" {
+			event.IP = s.steamIPs[event.SteamID]
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// close releases the open log files of this server
+func (s *serverLogTail) close() {
+	for _, f := range s.files {
+		f.close()
+	}
+}
+
+// close releases the file handle; the next read reopens the path
+func (f *tailedFile) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+// drain reads everything left in the file, including a final line without a newline
+func (f *tailedFile) drain() []string {
+	var lines []string
+	for {
+		more, err := f.readNew()
+		if err != nil || len(more) == 0 {
+			break
+		}
+		lines = append(lines, more...)
+	}
+	if f.file == nil {
+		return lines
+	}
+	if _, err := f.file.Seek(f.offset, io.SeekStart); err != nil {
+		return lines
+	}
+	if rest, err := io.ReadAll(io.LimitReader(f.file, 64*1024)); err == nil {
+		if line := strings.TrimRight(string(rest), "\r\n"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// readNew returns complete lines appended since the last read.
+// A trailing partial line is left for the next read.
+func (f *tailedFile) readNew() ([]string, error) {
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		// Replaced while closed: start over
+		if inode := fileInode(info); f.inode != 0 && inode != f.inode {
+			f.offset = 0
+		}
+		f.inode = fileInode(info)
+		f.file = file
+	}
+
+	info, err := f.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Truncated: start over
+	if info.Size() < f.offset {
+		f.offset = 0
+	}
+	if info.Size() == f.offset {
+		// Replaced in place: once the old file is drained, switch to the new one
+		if st, err := os.Stat(f.path); err == nil && fileInode(st) != f.inode {
+			f.close()
+			f.inode = 0
+			f.offset = 0
+		}
+		return nil, nil
+	}
+
+	if _, err := f.file.Seek(f.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Bound a single read so a huge backlog can't stall the loop
+	const maxRead = 4 * 1024 * 1024
+	data, err := io.ReadAll(io.LimitReader(f.file, maxRead))
+	if err != nil {
+		return nil, err
+	}
+
+	end := strings.LastIndexByte(string(data), '\n')
+	if end < 0 {
+		if len(data) == maxRead {
+			// A single line longer than maxRead: skip it
+			f.offset += int64(len(data))
+		}
+		return nil, nil
+	}
+	f.offset += int64(end + 1)
+
+	var lines []string
+	for _, line := range strings.Split(string(data[:end]), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// newestLogFile returns the most recent Logs/*_{kind}.txt file, or "" if none
+func newestLogFile(logsDir, kind string) string {
+	matches, err := filepath.Glob(filepath.Join(logsDir, "*_"+kind+".txt"))
+	if err != nil || len(matches) == 0 {
+		return ""
+	}
+
+	type candidate struct {
+		path    string
+		modTime time.Time
+	}
+	candidates := make([]candidate, 0, len(matches))
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		candidates = append(candidates, candidate{path: m, modTime: info.ModTime()})
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].modTime.Equal(candidates[j].modTime) {
+			return candidates[i].path > candidates[j].path
+		}
+		return candidates[i].modTime.After(candidates[j].modTime)
+	})
+	return candidates[0].path
+}
+
+// fileInode returns the inode number of a file (0 if unavailable)
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
+
+// parseGameLogLine parses a single PZ log line of the given kind, whose timestamp is in
+// location. Returns false for lines that don't map to a known event.
+func parseGameLogLine(kind, line string, location *time.Location) (GameEvent, bool) {
+	event := GameEvent{Source: kind, Raw: line}
+
+	body := line
+	if m := gameLogTimestampRe.FindStringSubmatch(line); m != nil {
+		event.Timestamp = parseGameLogTimestamp(m[1], location)
+		body = line[len(m[0]):]
+	} else {
+		event.Timestamp = time.Now().UnixMilli()
+	}
+
+	switch kind {
+	case "user":
+		if m := userConnectRe.FindStringSubmatch(body); m != nil {
+			event.Type = GameEventConnect
+			event.SteamID, event.Player, event.Position = m[1], m[2], m[3]
+		} else if m := userDisconnectRe.FindStringSubmatch(body); m != nil {
+			event.Type = GameEventDisconnect
+			event.SteamID, event.Player, event.Position = m[1], m[2], m[3]
+		} else if m := userDeathRe.FindStringSubmatch(body); m != nil {
+			event.Type = GameEventDeath
+			event.Player, event.Position = m[1], m[2]
+		} else {
+			return event, false
+		}
+		event.IP = gameLogIPRe.FindString(body)
+
+	case "chat":
+		m := chatMessageRe.FindStringSubmatch(body)
+		if m == nil {
+			return event, false
+		}
+		event.Type = GameEventChat
+		event.Channel, event.Player, event.Message = m[1], m[2], m[3]
+
+	case "pvp":
+		m := pvpKillRe.FindStringSubmatch(body)
+		if m == nil {
+			return event, false
+		}
+		event.Type = GameEventPvPKill
+		event.Player, event.Target, event.Position = m[1], m[3], m[4]
+
+	case "admin":
+		body = strings.TrimSuffix(strings.TrimSpace(body), ".")
+		if body == "" {
+			return event, false
+		}
+		if m := adminAddItemRe.FindStringSubmatch(body); m != nil {
+			event.Type = GameEventItemSpawn
+			event.Player, event.Item, event.Target = m[1], m[2], m[4]
+			event.Count = 1
+			if m[3] != "" {
+				event.Count, _ = strconv.Atoi(m[3])
+			}
+		} else {
+			event.Type = GameEventAdminCommand
+			parts := strings.SplitN(body, " ", 2)
+			event.Player = parts[0]
+			if len(parts) == 2 {
+				event.Message = parts[1]
+			}
+		}
+
+	case "cmd":
+		m := cmdLineRe.FindStringSubmatch(strings.TrimSpace(body))
+		if m == nil {
+			return event, false
+		}
+		event.SteamID, event.Player, event.Command, event.Message = m[1], m[2], m[3], m[4]
+		event.Type = GameEventAdminCommand
+
+		args := parseQuotedArgs(event.Message)
+		if strings.EqualFold(event.Command, "additem") && len(args) >= 2 {
+			// additem "player" "module.item" [count]
+			event.Type = GameEventItemSpawn
+			event.Target, event.Item = args[0], args[1]
+			event.Count = 1
+			if len(args) >= 3 {
+				if n, err := strconv.Atoi(args[2]); err == nil {
+					event.Count = n
+				}
+			}
+		} else if len(args) > 0 {
+			event.Target = args[0]
+		}
+
+	default:
+		return event, false
+	}
+
+	return event, true
+}
+
+// parseGameLogTimestamp parses the "dd-MM-yy HH:mm:ss.SSS" prefix PZ writes in the server's
+// local time, which is the container's zone rather than the agent's
+func parseGameLogTimestamp(s string, location *time.Location) int64 {
+	layout := "02-01-06 15:04:05"
+	if strings.Contains(s, ".") {
+		layout += "." + strings.Repeat("0", len(s)-strings.LastIndex(s, ".")-1)
+	}
+	t, err := time.ParseInLocation(layout, s, location)
+	if err != nil {
+		return time.Now().UnixMilli()
+	}
+	return t.UnixMilli()
+}
+
+// parseQuotedArgs splits command arguments, keeping "quoted strings" together
+func parseQuotedArgs(s string) []string {
+	var args []string
+	for _, m := range quotedArgRe.FindAllStringSubmatch(s, -1) {
+		if m[1] != "" || strings.HasPrefix(m[0], `"`) {
+			args = append(args, m[1])
+		} else {
+			args = append(args, m[2])
+		}
+	}
+	return args
+}
+
+// publish sends a game event to the manager
+func (t *GameLogTailer) publish(event GameEvent) {
+	if !t.agent.IsAuthenticated() {
+		return
+	}
+	if err := t.agent.sendMessage(NewMessage("game.event", event)); err != nil {
+		gameLogsLogger.Errorf("Failed to send game event: %v", err)
+	}
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	idlePoliciesFile      = "idle-policies.json"
+	hibernatedServersFile = "hibernated-servers.json"
+)
+
+// Hibernation states reported via server.hibernation
+const (
+	HibernationStateHibernated = "hibernated"
+	HibernationStateWaking     = "waking"
+	HibernationStateRunning    = "running"
+	HibernationStateWakeFailed = "wake_failed"
+)
+
+// raknetOfflineMagic is the fixed 16-byte sequence in RakNet offline (pre-connection) packets
+var raknetOfflineMagic = []byte{0x00, 0xff, 0xff, 0x00, 0xfe, 0xfe, 0xfe, 0xfe, 0xfd, 0xfd, 0xfd, 0xfd, 0x12, 0x34, 0x56, 0x78}
+
+// raknetOpenConnectionRequest1 is the first packet a PZ client sends when joining.
+// Unconnected pings (server browser refreshes) use a different ID and must not wake the server.
+const raknetOpenConnectionRequest1 = 0x05
+
+// IdlePolicy configures automatic hibernation for one server
+type IdlePolicy struct {
+	ServerID      string `json:"serverId"`
+	Enabled       bool   `json:"enabled"`
+	IdleMinutes   int    `json:"idleMinutes"`   // Stop after this many minutes with zero players
+	WakeOnConnect bool   `json:"wakeOnConnect"` // Listen on the game ports and start on a join attempt
+}
+
+// HibernatedServer is a server the agent stopped for being idle
+type HibernatedServer struct {
+	ServerID    string `json:"serverId"`
+	ServerName  string `json:"serverName"`
+	ContainerID string `json:"containerId"`
+	Ports       []int  `json:"ports"` // Host UDP game ports
+	State       string `json:"state"`
+	Since       int64  `json:"since"` // Unix seconds
+}
+
+// HibernationStatus is the server.hibernation event payload
+type HibernationStatus struct {
+	ServerID    string `json:"serverId"`
+	ServerName  string `json:"serverName"`
+	ContainerID string `json:"containerId"`
+	State       string `json:"state"`
+	Reason      string `json:"reason,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Timestamp   int64  `json:"timestamp"` // Unix seconds
+}
+
+// IdleManager stops servers that have been empty too long and wakes them on demand
+type IdleManager struct {
+	mu          sync.Mutex
+	agent       *Agent
+	policies    map[string]IdlePolicy        // serverID -> policy
+	hibernated  map[string]*HibernatedServer // serverID -> record
+	emptySince  map[string]time.Time         // serverID -> first empty observation
+	inProgress  map[string]bool              // serverID -> stop in progress
+	listeners   map[string]*WakeListener     // serverID -> UDP wake listener
+	dir         string
+	listenHost  string
+	stopServer  func(ctx context.Context, containerID string) error
+	startServer func(ctx context.Context, containerID string) error
+	udpPorts    func(ctx context.Context, containerID string) ([]int, error)
+}
+
+// NewIdleManager creates an idle manager backed by the agent's Docker client
+func NewIdleManager(docker *DockerClient, agent *Agent) *IdleManager {
+	return &IdleManager{
+		agent:       agent,
+		policies:    make(map[string]IdlePolicy),
+		hibernated:  make(map[string]*HibernatedServer),
+		emptySince:  make(map[string]time.Time),
+		inProgress:  make(map[string]bool),
+		listeners:   make(map[string]*WakeListener),
+		dir:         StateDir(),
+		stopServer:  docker.StopContainer, // Includes GracefulSave
+		startServer: docker.StartContainer,
+		udpPorts:    docker.GetContainerUDPPorts,
+	}
+}
+
+// Start loads persisted policies and re-arms wake listeners for servers hibernated before a restart
+func (im *IdleManager) Start() {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if data, err := os.ReadFile(filepath.Join(im.dir, idlePoliciesFile)); err == nil {
+		if err := json.Unmarshal(data, &im.policies); err != nil {
+			idleLogger.Warnf("Failed to parse idle policies: %v", err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(im.dir, hibernatedServersFile)); err == nil {
+		if err := json.Unmarshal(data, &im.hibernated); err != nil {
+			idleLogger.Warnf("Failed to parse hibernated servers: %v", err)
+		}
+	}
+
+	for serverID, h := range im.hibernated {
+		if im.policies[serverID].WakeOnConnect {
+			im.armListener(h)
+		}
+	}
+
+	idleLogger.Infof("Loaded %d idle policies, %d hibernated server(s)", len(im.policies), len(im.hibernated))
+}
+
+// Stop closes all wake listeners
+func (im *IdleManager) Stop() {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	for serverID, l := range im.listeners {
+		l.Close()
+		delete(im.listeners, serverID)
+	}
+}
+
+// Observe records a player count sample from the stats collector and hibernates
+// the server once it has been empty for longer than its policy allows.
+func (im *IdleManager) Observe(serverID, serverName, containerID string, playerCount int, now time.Time) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	// A hibernated server that answers RCON again is fully awake
+	if h, ok := im.hibernated[serverID]; ok {
+		delete(im.hibernated, serverID)
+		im.closeListener(serverID)
+		im.saveHibernated()
+		idleLogger.Infof("%s is running again", serverName)
+		im.sendStatus(HibernationStatus{ServerID: serverID, ServerName: serverName, ContainerID: h.ContainerID, State: HibernationStateRunning})
+	}
+
+	policy, ok := im.policies[serverID]
+	if !ok || !policy.Enabled || policy.IdleMinutes <= 0 {
+		delete(im.emptySince, serverID)
+		return
+	}
+
+	if playerCount > 0 {
+		delete(im.emptySince, serverID)
+		return
+	}
+
+	since, ok := im.emptySince[serverID]
+	if !ok {
+		im.emptySince[serverID] = now
+		return
+	}
+
+	if now.Sub(since) < time.Duration(policy.IdleMinutes)*time.Minute || im.inProgress[serverID] {
+		return
+	}
+
+	im.inProgress[serverID] = true
+	go im.hibernate(serverID, serverName, containerID, policy, now.Sub(since))
+}
+
+// Forget drops idle tracking for a server that is no longer running
+func (im *IdleManager) Forget(serverID string) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	delete(im.emptySince, serverID)
+}
+
+// ReleaseContainer closes the wake listener and hibernation record for a container.
+// Called before the manager starts, rebuilds or deletes it so the game ports are free.
+func (im *IdleManager) ReleaseContainer(containerID string) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	for serverID, h := range im.hibernated {
+		if h.ContainerID != containerID {
+			continue
+		}
+		idleLogger.Infof("Releasing hibernation of %s (manual operation)", h.ServerName)
+		im.closeListener(serverID)
+		delete(im.hibernated, serverID)
+		im.saveHibernated()
+	}
+}
+
+// hibernate stops an idle server and, if configured, arms a wake listener on its game ports
+func (im *IdleManager) hibernate(serverID, serverName, containerID string, policy IdlePolicy, idleFor time.Duration) {
+	defer func() {
+		im.mu.Lock()
+		delete(im.inProgress, serverID)
+		delete(im.emptySince, serverID)
+		im.mu.Unlock()
+	}()
+
+	idleLogger.Infof("%s has been empty for %v, hibernating", serverName, idleFor.Round(time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Resolve ports before stopping: they're read from the container's port bindings
+	ports, err := im.udpPorts(ctx, containerID)
+	if err != nil {
+		idleLogger.Warnf("Failed to read game ports of %s: %v", serverName, err)
+	}
+
+	if err := im.stopServer(ctx, containerID); err != nil {
+		idleLogger.Errorf("Failed to stop %s: %v", serverName, err)
+		return
+	}
+
+	h := &HibernatedServer{
+		ServerID:    serverID,
+		ServerName:  serverName,
+		ContainerID: containerID,
+		Ports:       ports,
+		State:       HibernationStateHibernated,
+		Since:       time.Now().Unix(),
+	}
+
+	im.mu.Lock()
+	im.hibernated[serverID] = h
+	im.saveHibernated()
+	if policy.WakeOnConnect {
+		im.armListener(h)
+	}
+	im.mu.Unlock()
+
+	im.sendStatus(HibernationStatus{
+		ServerID:    serverID,
+		ServerName:  serverName,
+		ContainerID: containerID,
+		State:       HibernationStateHibernated,
+		Reason:      fmt.Sprintf("no players for %d minutes", policy.IdleMinutes),
+	})
+}
+
+// armListener starts a UDP wake listener for a hibernated server. Must be called with im.mu held.
+func (im *IdleManager) armListener(h *HibernatedServer) {
+	if len(h.Ports) == 0 {
+		idleLogger.Infof("%s has no UDP game ports, wake-on-connect unavailable", h.ServerName)
+		return
+	}
+	im.closeListener(h.ServerID)
+
+	serverID := h.ServerID
+	l, err := StartWakeListener(im.listenHost, h.Ports, func(from net.Addr) {
+		im.wake(serverID, from)
+	})
+	if err != nil {
+		idleLogger.Errorf("Failed to listen on %v for %s: %v", h.Ports, h.ServerName, err)
+		return
+	}
+	im.listeners[serverID] = l
+	idleLogger.Infof("Wake-on-connect armed for %s on UDP %v", h.ServerName, h.Ports)
+}
+
+// closeListener stops a server's wake listener. Must be called with im.mu held.
+func (im *IdleManager) closeListener(serverID string) {
+	if l, ok := im.listeners[serverID]; ok {
+		l.Close()
+		delete(im.listeners, serverID)
+	}
+}
+
+// wake starts a hibernated server after a client connection attempt
+func (im *IdleManager) wake(serverID string, from net.Addr) {
+	im.mu.Lock()
+	h, ok := im.hibernated[serverID]
+	if !ok || h.State == HibernationStateWaking {
+		im.mu.Unlock()
+		return
+	}
+	// Release the ports before the container tries to bind them
+	im.closeListener(serverID)
+	h.State = HibernationStateWaking
+	im.saveHibernated()
+	record := *h
+	im.mu.Unlock()
+
+	idleLogger.Infof("Connection attempt from %s, waking %s", from, record.ServerName)
+	im.sendStatus(HibernationStatus{
+		ServerID:    record.ServerID,
+		ServerName:  record.ServerName,
+		ContainerID: record.ContainerID,
+		State:       HibernationStateWaking,
+		Reason:      fmt.Sprintf("connection attempt from %s", from),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := im.startServer(ctx, record.ContainerID); err != nil {
+		idleLogger.Errorf("Failed to wake %s: %v", record.ServerName, err)
+
+		im.mu.Lock()
+		if h, ok := im.hibernated[serverID]; ok {
+			h.State = HibernationStateHibernated
+			im.saveHibernated()
+			im.armListener(h)
+		}
+		im.mu.Unlock()
+
+		im.sendStatus(HibernationStatus{
+			ServerID:    record.ServerID,
+			ServerName:  record.ServerName,
+			ContainerID: record.ContainerID,
+			State:       HibernationStateWakeFailed,
+			Error:       err.Error(),
+		})
+	}
+	// The record is cleared by Observe once RCON answers again
+}
+
+// SetPolicy stores an idle policy and persists all policies
+func (im *IdleManager) SetPolicy(policy IdlePolicy) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if policy.Enabled {
+		im.policies[policy.ServerID] = policy
+	} else {
+		delete(im.policies, policy.ServerID)
+		delete(im.emptySince, policy.ServerID)
+	}
+
+	if h, ok := im.hibernated[policy.ServerID]; ok {
+		if policy.WakeOnConnect && policy.Enabled {
+			if _, armed := im.listeners[policy.ServerID]; !armed && h.State == HibernationStateHibernated {
+				im.armListener(h)
+			}
+		} else {
+			im.closeListener(policy.ServerID)
+		}
+	}
+
+	if err := os.MkdirAll(im.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create state directory %s: %w", im.dir, err)
+	}
+	return writeFileAtomic(filepath.Join(im.dir, idlePoliciesFile), im.policies)
+}
+
+// Snapshot returns the configured policies and hibernated servers
+func (im *IdleManager) Snapshot() ([]IdlePolicy, []HibernatedServer) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	policies := make([]IdlePolicy, 0, len(im.policies))
+	for _, p := range im.policies {
+		policies = append(policies, p)
+	}
+	hibernated := make([]HibernatedServer, 0, len(im.hibernated))
+	for _, h := range im.hibernated {
+		hibernated = append(hibernated, *h)
+	}
+	return policies, hibernated
+}
+
+// saveHibernated persists hibernation records. Must be called with im.mu held.
+func (im *IdleManager) saveHibernated() {
+	if err := os.MkdirAll(im.dir, 0700); err != nil {
+		idleLogger.Warnf("Failed to create state directory %s: %v", im.dir, err)
+		return
+	}
+	if err := writeFileAtomic(filepath.Join(im.dir, hibernatedServersFile), im.hibernated); err != nil {
+		idleLogger.Warnf("Failed to save hibernated servers: %v", err)
+	}
+}
+
+// sendStatus reports a hibernation state change to the manager
+func (im *IdleManager) sendStatus(status HibernationStatus) {
+	if im.agent == nil || !im.agent.IsAuthenticated() {
+		return
+	}
+	status.Timestamp = time.Now().Unix()
+	if err := im.agent.sendMessage(NewMessage("server.hibernation", status)); err != nil {
+		idleLogger.Errorf("Failed to send hibernation status: %v", err)
+	}
+}
+
+// WakeListener listens on a hibernated server's UDP game ports for a client join attempt
+type WakeListener struct {
+	conns  []net.PacketConn
+	once   sync.Once
+	closed chan struct{}
+}
+
+// StartWakeListener binds host:port for every port and calls onWake once on the first
+// RakNet connection request. Unrelated traffic (pings, scanners) is ignored.
+func StartWakeListener(host string, ports []int, onWake func(from net.Addr)) (*WakeListener, error) {
+	l := &WakeListener{closed: make(chan struct{})}
+
+	for _, port := range ports {
+		conn, err := net.ListenPacket("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			l.Close()
+			return nil, fmt.Errorf("failed to listen on UDP %d: %w", port, err)
+		}
+		l.conns = append(l.conns, conn)
+	}
+
+	var fired sync.Once
+	for _, conn := range l.conns {
+		go func(conn net.PacketConn) {
+			buf := make([]byte, 1500)
+			for {
+				n, from, err := conn.ReadFrom(buf)
+				if err != nil {
+					return // Closed
+				}
+				if !isConnectionAttempt(buf[:n]) {
+					continue
+				}
+				fired.Do(func() { go onWake(from) })
+				return
+			}
+		}(conn)
+	}
+
+	return l, nil
+}
+
+// Addrs returns the bound local addresses (useful when listening on port 0)
+func (l *WakeListener) Addrs() []net.Addr {
+	addrs := make([]net.Addr, len(l.conns))
+	for i, c := range l.conns {
+		addrs[i] = c.LocalAddr()
+	}
+	return addrs
+}
+
+// Close releases all ports
+func (l *WakeListener) Close() {
+	l.once.Do(func() {
+		close(l.closed)
+		for _, c := range l.conns {
+			c.Close()
+		}
+	})
+}
+
+// isConnectionAttempt reports whether a datagram is a RakNet Open Connection Request 1
+func isConnectionAttempt(packet []byte) bool {
+	if len(packet) < 1+len(raknetOfflineMagic) || packet[0] != raknetOpenConnectionRequest1 {
+		return false
+	}
+	return bytes.Equal(packet[1:1+len(raknetOfflineMagic)], raknetOfflineMagic)
+}
+
+// GetContainerUDPPorts returns the host UDP ports a container publishes
+func (dc *DockerClient) GetContainerUDPPorts(ctx context.Context, containerID string) ([]int, error) {
+	inspect, err := dc.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	var ports []int
+	seen := make(map[int]bool)
+	for containerPort, bindings := range inspect.HostConfig.PortBindings {
+		if !strings.HasSuffix(string(containerPort), "/udp") {
+			continue
+		}
+		for _, b := range bindings {
+			port, err := strconv.Atoi(b.HostPort)
+			if err != nil || seen[port] {
+				continue
+			}
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+// IdlePolicyResponse is the reply to server.idle.set and server.idle.get
+type IdlePolicyResponse struct {
+	Success    bool               `json:"success"`
+	Policies   []IdlePolicy       `json:"policies,omitempty"`
+	Hibernated []HibernatedServer `json:"hibernated,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// handleServerIdleSet handles server.idle.set messages
+func (a *Agent) handleServerIdleSet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var policy IdlePolicy
+	if err := json.Unmarshal(data, &policy); err != nil || policy.ServerID == "" {
+		a.sendIdleResponse(msg.Reply, IdlePolicyResponse{Error: "Invalid request format (serverId required)"})
+		return
+	}
+
+	if a.idle == nil {
+		a.sendIdleResponse(msg.Reply, IdlePolicyResponse{Error: "Docker client not initialized"})
+		return
+	}
+
+	if err := a.idle.SetPolicy(policy); err != nil {
+		idleLogger.Errorf("Failed to save idle policy: %v", err)
+		a.sendIdleResponse(msg.Reply, IdlePolicyResponse{Error: err.Error()})
+		return
+	}
+
+	idleLogger.Infof("Policy for %s: enabled=%v idleMinutes=%d wakeOnConnect=%v",
+		policy.ServerID, policy.Enabled, policy.IdleMinutes, policy.WakeOnConnect)
+	a.sendIdleResponse(msg.Reply, IdlePolicyResponse{Success: true})
+}
+
+// handleServerIdleGet handles server.idle.get messages
+func (a *Agent) handleServerIdleGet(msg Message) {
+	if a.idle == nil {
+		a.sendIdleResponse(msg.Reply, IdlePolicyResponse{Error: "Docker client not initialized"})
+		return
+	}
+
+	policies, hibernated := a.idle.Snapshot()
+	a.sendIdleResponse(msg.Reply, IdlePolicyResponse{
+		Success:    true,
+		Policies:   policies,
+		Hibernated: hibernated,
+	})
+}
+
+// sendIdleResponse replies to an idle policy request
+func (a *Agent) sendIdleResponse(replyTo string, resp IdlePolicyResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestIdleManager returns an idle manager with stubbed Docker calls and state in a temp dir.
+// Started containers are reported on the returned channel.
+func newTestIdleManager(t *testing.T, ports []int) (*IdleManager, chan string) {
+	t.Helper()
+	started := make(chan string, 4)
+	im := &IdleManager{
+		policies:   make(map[string]IdlePolicy),
+		hibernated: make(map[string]*HibernatedServer),
+		emptySince: make(map[string]time.Time),
+		inProgress: make(map[string]bool),
+		listeners:  make(map[string]*WakeListener),
+		dir:        t.TempDir(),
+		listenHost: "127.0.0.1",
+		stopServer: func(ctx context.Context, containerID string) error { return nil },
+		startServer: func(ctx context.Context, containerID string) error {
+			started <- containerID
+			return nil
+		},
+		udpPorts: func(ctx context.Context, containerID string) ([]int, error) { return ports, nil },
+	}
+	t.Cleanup(im.Stop)
+	return im, started
+}
+
+// freeUDPPort returns a UDP port on localhost that is free right now
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// sendUDP sends one datagram to a local port, like a game client would
+func sendUDP(t *testing.T, port int, packet []byte) {
+	t.Helper()
+	conn, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(packet); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func raknetPacket(id byte) []byte {
+	packet := append([]byte{id}, raknetOfflineMagic...)
+	return append(packet, make([]byte, 32)...) // Protocol version and MTU padding
+}
+
+func portIsFree(port int) bool {
+	conn, err := net.ListenPacket("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func TestIsConnectionAttempt(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   bool
+	}{
+		{"open connection request 1", raknetPacket(raknetOpenConnectionRequest1), true},
+		{"unconnected ping", raknetPacket(0x01), false},
+		{"wrong magic", append([]byte{raknetOpenConnectionRequest1}, make([]byte, 32)...), false},
+		{"truncated", raknetPacket(raknetOpenConnectionRequest1)[:8], false},
+		{"empty", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isConnectionAttempt(tt.packet); got != tt.want {
+			t.Errorf("%s: isConnectionAttempt = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWakeOnConnectionAttempt(t *testing.T) {
+	port := freeUDPPort(t)
+	im, started := newTestIdleManager(t, []int{port})
+	policy := IdlePolicy{ServerID: "srv-1", Enabled: true, IdleMinutes: 1, WakeOnConnect: true}
+	im.policies[policy.ServerID] = policy
+
+	im.hibernate("srv-1", "test", "container-1", policy, time.Minute)
+	if portIsFree(port) {
+		t.Fatal("wake listener is not holding the game port")
+	}
+
+	// Server browser pings must not wake the server
+	sendUDP(t, port, raknetPacket(0x01))
+	select {
+	case id := <-started:
+		t.Fatalf("ping woke %s", id)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	sendUDP(t, port, raknetPacket(raknetOpenConnectionRequest1))
+	select {
+	case id := <-started:
+		if id != "container-1" {
+			t.Fatalf("started %s, want container-1", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection attempt did not wake the server")
+	}
+
+	_, hibernated := im.Snapshot()
+	if len(hibernated) != 1 || hibernated[0].State != HibernationStateWaking {
+		t.Fatalf("hibernated = %+v, want one waking server", hibernated)
+	}
+	if !portIsFree(port) {
+		t.Fatal("game port still held after wake")
+	}
+
+	// RCON answering again clears the record
+	im.Observe("srv-1", "test", "container-1", 0, time.Now())
+	if _, hibernated := im.Snapshot(); len(hibernated) != 0 {
+		t.Fatalf("hibernated = %+v after the server came back", hibernated)
+	}
+}
+
+func TestReleaseContainerFreesPorts(t *testing.T) {
+	port := freeUDPPort(t)
+	im, started := newTestIdleManager(t, []int{port})
+	policy := IdlePolicy{ServerID: "srv-1", Enabled: true, IdleMinutes: 1, WakeOnConnect: true}
+	im.policies[policy.ServerID] = policy
+
+	im.hibernate("srv-1", "test", "container-1", policy, time.Minute)
+	im.ReleaseContainer("container-1")
+
+	if !portIsFree(port) {
+		t.Fatal("game port still held after ReleaseContainer")
+	}
+	if _, hibernated := im.Snapshot(); len(hibernated) != 0 {
+		t.Fatalf("hibernated = %+v after ReleaseContainer", hibernated)
+	}
+	select {
+	case id := <-started:
+		t.Fatalf("ReleaseContainer started %s", id)
+	default:
+	}
+}
+
+func TestObserveHibernatesIdleServer(t *testing.T) {
+	im, _ := newTestIdleManager(t, nil)
+	stopped := make(chan string, 1)
+	im.stopServer = func(ctx context.Context, containerID string) error {
+		stopped <- containerID
+		return nil
+	}
+	im.policies["srv-1"] = IdlePolicy{ServerID: "srv-1", Enabled: true, IdleMinutes: 5}
+
+	now := time.Now()
+	im.Observe("srv-1", "test", "container-1", 0, now)
+	im.Observe("srv-1", "test", "container-1", 0, now.Add(4*time.Minute))
+	select {
+	case <-stopped:
+		t.Fatal("stopped before the idle period elapsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	im.Observe("srv-1", "test", "container-1", 0, now.Add(6*time.Minute))
+	select {
+	case id := <-stopped:
+		if id != "container-1" {
+			t.Fatalf("stopped %s, want container-1", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle server was not stopped")
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const logAlertRulesFile = "log-alert-rules.json"
+
+const (
+	logAlertDefaultWindow   = 60  // seconds
+	logAlertDefaultCooldown = 300 // seconds
+	logAlertMaxLines        = 20  // Offending lines attached to an alert
+	logAlertMaxLineLength   = 2000
+)
+
+// Log alert sources
+const (
+	LogAlertSourceContainer = "container" // Container stdout/stderr
+	LogAlertSourcePZLog     = "pzlog"     // PZ Logs/*.txt files
+)
+
+// LogAlertRule matches a regex against server output and fires after Threshold matches within the window
+type LogAlertRule struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Pattern         string   `json:"pattern"`
+	ServerID        string   `json:"serverId,omitempty"` // Empty = all servers
+	Sources         []string `json:"sources,omitempty"`  // container, pzlog (empty = both)
+	Threshold       int      `json:"threshold"`          // Matches needed within the window (default 1)
+	WindowSeconds   int      `json:"windowSeconds"`      // Default 60
+	CooldownSeconds int      `json:"cooldownSeconds"`    // Minimum time between alerts (default 300)
+	Severity        string   `json:"severity,omitempty"` // info, warning, critical
+	Enabled         bool     `json:"enabled"`
+}
+
+// LogAlertLine is one offending line attached to an alert
+type LogAlertLine struct {
+	Timestamp int64  `json:"timestamp"` // Unix milliseconds
+	Source    string `json:"source"`    // container:stdout, container:stderr, pzlog:<kind>
+	Line      string `json:"line"`
+}
+
+// LogAlertEvent is the server.alert payload
+type LogAlertEvent struct {
+	RuleID        string         `json:"ruleId"`
+	RuleName      string         `json:"ruleName"`
+	Severity      string         `json:"severity"`
+	ServerID      string         `json:"serverId"`
+	ServerName    string         `json:"serverName"`
+	Pattern       string         `json:"pattern"`
+	MatchCount    int            `json:"matchCount"`
+	WindowSeconds int            `json:"windowSeconds"`
+	Lines         []LogAlertLine `json:"lines"`
+	Timestamp     int64          `json:"timestamp"` // Unix milliseconds
+}
+
+// compiledLogAlertRule is a validated rule with its regex
+type compiledLogAlertRule struct {
+	LogAlertRule
+	re        *regexp.Regexp
+	container bool
+	pzlog     bool
+}
+
+// logAlertWindow tracks recent matches of one rule on one server
+type logAlertWindow struct {
+	matches   []time.Time
+	lines     []LogAlertLine
+	lastFired time.Time
+}
+
+// GetLogAlertRulesPath returns the path to the cached log alert rules.
+func GetLogAlertRulesPath() string {
+	return filepath.Join(StateDir(), logAlertRulesFile)
+}
+
+// LoadLogAlertRules loads the cached log alert rules from disk.
+// Returns nil if the file doesn't exist.
+func LoadLogAlertRules() ([]LogAlertRule, error) {
+	data, err := os.ReadFile(GetLogAlertRulesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read log alert rules: %w", err)
+	}
+
+	var rules []LogAlertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse log alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// SaveLogAlertRules saves the log alert rules to disk.
+func SaveLogAlertRules(rules []LogAlertRule) error {
+	if err := ensureStateDir(); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(GetLogAlertRulesPath(), rules); err != nil {
+		return fmt.Errorf("failed to write log alert rules: %w", err)
+	}
+	return nil
+}
+
+// compileLogAlertRules validates rules and fills in defaults
+func compileLogAlertRules(rules []LogAlertRule) ([]*compiledLogAlertRule, error) {
+	compiled := make([]*compiledLogAlertRule, 0, len(rules))
+	seen := make(map[string]bool)
+
+	for _, r := range rules {
+		if r.ID == "" {
+			return nil, fmt.Errorf("rule %q has no id", r.Name)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("duplicate rule id %q", r.ID)
+		}
+		seen[r.ID] = true
+
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: invalid pattern: %w", r.ID, err)
+		}
+		if r.Threshold <= 0 {
+			r.Threshold = 1
+		}
+		if r.WindowSeconds <= 0 {
+			r.WindowSeconds = logAlertDefaultWindow
+		}
+		if r.CooldownSeconds <= 0 {
+			r.CooldownSeconds = logAlertDefaultCooldown
+		}
+		if r.Severity == "" {
+			r.Severity = "warning"
+		}
+		if r.Name == "" {
+			r.Name = r.ID
+		}
+
+		c := &compiledLogAlertRule{LogAlertRule: r, re: re}
+		if len(r.Sources) == 0 {
+			c.container, c.pzlog = true, true
+		}
+		for _, src := range r.Sources {
+			switch src {
+			case LogAlertSourceContainer:
+				c.container = true
+			case LogAlertSourcePZLog:
+				c.pzlog = true
+			default:
+				return nil, fmt.Errorf("rule %q: unknown source %q", r.ID, src)
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// LogAlertEngine evaluates log alert rules against container output (observed on the log
+// archiver's feed) and PZ log files (observed on the game log tailer)
+type LogAlertEngine struct {
+	mu      sync.Mutex
+	rules   []LogAlertRule
+	active  []*compiledLogAlertRule
+	windows map[string]*logAlertWindow // ruleID|serverID -> window
+	started int64                      // Unix milliseconds; older output predates the engine
+	emit    func(LogAlertEvent)
+}
+
+// NewLogAlertEngine creates an engine that reports alerts through the agent
+func NewLogAlertEngine(agent *Agent) *LogAlertEngine {
+	return &LogAlertEngine{
+		windows: make(map[string]*logAlertWindow),
+		emit: func(event LogAlertEvent) {
+			delivered := false
+			if agent.IsAuthenticated() {
+				if err := agent.sendMessage(NewMessage("server.alert", event)); err != nil {
+					logAlertsLogger.Errorf("Failed to send alert: %v", err)
+				} else {
+					delivered = true
+				}
+			}
+			agent.NotifyLogAlert(event, delivered)
+		},
+	}
+}
+
+// Start loads cached rules
+func (e *LogAlertEngine) Start() {
+	rules, err := LoadLogAlertRules()
+	if err != nil {
+		logAlertsLogger.Warnf("%v", err)
+	} else if rules != nil {
+		if err := e.setRules(rules); err != nil {
+			logAlertsLogger.Warnf("Ignoring cached rules: %v", err)
+		}
+	}
+
+	e.mu.Lock()
+	e.started = time.Now().UnixMilli()
+	e.mu.Unlock()
+	logAlertsLogger.Infof("Starting log alert engine (%d rule(s))", len(e.Rules()))
+}
+
+// Rules returns the configured rules
+func (e *LogAlertEngine) Rules() []LogAlertRule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]LogAlertRule{}, e.rules...)
+}
+
+// SetRules validates, applies and caches a new rule set
+func (e *LogAlertEngine) SetRules(rules []LogAlertRule) error {
+	if err := e.setRules(rules); err != nil {
+		return err
+	}
+	return SaveLogAlertRules(rules)
+}
+
+// setRules swaps in a rule set and resets match windows
+func (e *LogAlertEngine) setRules(rules []LogAlertRule) error {
+	compiled, err := compileLogAlertRules(rules)
+	if err != nil {
+		return err
+	}
+
+	var active []*compiledLogAlertRule
+	for _, c := range compiled {
+		if c.Enabled {
+			active = append(active, c)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+	e.active = active
+	e.windows = make(map[string]*logAlertWindow)
+	return nil
+}
+
+// ObserveContainerLine is the LogArchiver output observer. Output the archiver replays from
+// before the engine started is skipped: it was evaluated before, or predates the rules.
+func (e *LogAlertEngine) ObserveContainerLine(serverID, serverName string, line LogLine) {
+	e.mu.Lock()
+	skip := serverID == "" || line.Timestamp < e.started
+	e.mu.Unlock()
+	if skip {
+		return
+	}
+	e.Evaluate(serverID, serverName, LogAlertSourceContainer, LogAlertLine{
+		Timestamp: line.Timestamp,
+		Source:    LogAlertSourceContainer + ":" + line.Stream,
+		Line:      line.Message,
+	})
+}
+
+// ObservePZLogLines is the GameLogTailer line observer
+func (e *LogAlertEngine) ObservePZLogLines(serverID, serverName, kind string, lines []string, location *time.Location) {
+	for _, line := range lines {
+		timestamp := time.Now().UnixMilli()
+		if m := gameLogTimestampRe.FindStringSubmatch(line); m != nil {
+			timestamp = parseGameLogTimestamp(m[1], location)
+		}
+		e.Evaluate(serverID, serverName, LogAlertSourcePZLog, LogAlertLine{
+			Timestamp: timestamp,
+			Source:    LogAlertSourcePZLog + ":" + kind,
+			Line:      line,
+		})
+	}
+}
+
+// Evaluate matches one line against the active rules and emits server.alert when a rule trips
+func (e *LogAlertEngine) Evaluate(serverID, serverName, source string, line LogAlertLine) {
+	if len(line.Line) > logAlertMaxLineLength {
+		line.Line = line.Line[:logAlertMaxLineLength]
+	}
+
+	var fired []LogAlertEvent
+	now := time.Now()
+
+	e.mu.Lock()
+	for _, r := range e.active {
+		if r.ServerID != "" && r.ServerID != serverID {
+			continue
+		}
+		if (source == LogAlertSourceContainer && !r.container) || (source == LogAlertSourcePZLog && !r.pzlog) {
+			continue
+		}
+		if !r.re.MatchString(line.Line) {
+			continue
+		}
+
+		key := r.ID + "|" + serverID
+		w, ok := e.windows[key]
+		if !ok {
+			w = &logAlertWindow{}
+			e.windows[key] = w
+		}
+
+		// Slide the window
+		cutoff := now.Add(-time.Duration(r.WindowSeconds) * time.Second)
+		keep := 0
+		for keep < len(w.matches) && w.matches[keep].Before(cutoff) {
+			keep++
+		}
+		w.matches = w.matches[keep:]
+		w.matches = append(w.matches, now)
+		w.lines = append(w.lines, line)
+		if len(w.lines) > logAlertMaxLines {
+			w.lines = w.lines[len(w.lines)-logAlertMaxLines:]
+		}
+
+		if len(w.matches) < r.Threshold {
+			continue
+		}
+		if !w.lastFired.IsZero() && now.Sub(w.lastFired) < time.Duration(r.CooldownSeconds)*time.Second {
+			continue
+		}
+
+		fired = append(fired, LogAlertEvent{
+			RuleID:        r.ID,
+			RuleName:      r.Name,
+			Severity:      r.Severity,
+			ServerID:      serverID,
+			ServerName:    serverName,
+			Pattern:       r.Pattern,
+			MatchCount:    len(w.matches),
+			WindowSeconds: r.WindowSeconds,
+			Lines:         append([]LogAlertLine{}, w.lines...),
+			Timestamp:     now.UnixMilli(),
+		})
+		w.lastFired = now
+		w.matches = nil
+		w.lines = nil
+	}
+	e.mu.Unlock()
+
+	for _, event := range fired {
+		logAlertsLogger.Infof("Rule %q fired for %s (%d match(es) in %ds)", event.RuleName, event.ServerName, event.MatchCount, event.WindowSeconds)
+		e.emit(event)
+	}
+}
+
+// LogAlertRulesRequest is the alert.rules.set request
+type LogAlertRulesRequest struct {
+	Rules []LogAlertRule `json:"rules"`
+}
+
+// LogAlertRulesResponse is the reply to alert.rules.set and alert.rules.get
+type LogAlertRulesResponse struct {
+	Success bool           `json:"success"`
+	Rules   []LogAlertRule `json:"rules,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// handleAlertRulesSet handles alert.rules.set messages (replaces the whole rule set)
+func (a *Agent) handleAlertRulesSet(msg Message) {
+	if a.logAlerts == nil {
+		a.sendLogAlertRulesResponse(msg.Reply, LogAlertRulesResponse{Error: "Docker client not initialized"})
+		return
+	}
+
+	data, _ := json.Marshal(msg.Data)
+	var req LogAlertRulesRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendLogAlertRulesResponse(msg.Reply, LogAlertRulesResponse{Error: "Invalid request format"})
+		return
+	}
+
+	if err := a.logAlerts.SetRules(req.Rules); err != nil {
+		logAlertsLogger.Infof("Rejected rules: %v", err)
+		a.sendLogAlertRulesResponse(msg.Reply, LogAlertRulesResponse{Error: err.Error()})
+		return
+	}
+
+	logAlertsLogger.Infof("Updated rules (%d rule(s))", len(req.Rules))
+	a.sendLogAlertRulesResponse(msg.Reply, LogAlertRulesResponse{Success: true, Rules: req.Rules})
+}
+
+// handleAlertRulesGet handles alert.rules.get messages
+func (a *Agent) handleAlertRulesGet(msg Message) {
+	if a.logAlerts == nil {
+		a.sendLogAlertRulesResponse(msg.Reply, LogAlertRulesResponse{Error: "Docker client not initialized"})
+		return
+	}
+	a.sendLogAlertRulesResponse(msg.Reply, LogAlertRulesResponse{Success: true, Rules: a.logAlerts.Rules()})
+}
+
+// sendLogAlertRulesResponse replies to a log alert rules request
+func (a *Agent) sendLogAlertRulesResponse(replyTo string, resp LogAlertRulesResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
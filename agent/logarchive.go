@@ -0,0 +1,786 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+const (
+	logArchiveDirName         = "logs"
+	logArchiveActiveSegment   = "current.jsonl"
+	logArchiveSegmentMaxBytes = 16 * 1024 * 1024 // Rotate the active segment after 16MB...
+	logArchiveSegmentMaxAge   = 6 * time.Hour    // ...or 6h, so time-range queries can skip whole segments
+	logArchiveDiscoverEvery   = 15 * time.Second
+	logArchiveRetentionEvery  = 10 * time.Minute
+	logArchiveFlushEvery      = 2 * time.Second
+
+	logQueryDefaultLimit = 500
+	logQueryMaxLimit     = 5000
+)
+
+// ArchivedLogLine is one container output line as stored in a segment file
+type ArchivedLogLine struct {
+	Timestamp   int64  `json:"ts"` // Unix milliseconds
+	Stream      string `json:"stream"`
+	Message     string `json:"msg"`
+	ContainerID string `json:"cid,omitempty"`
+}
+
+// LogArchiveConfig controls archive retention
+type LogArchiveConfig struct {
+	MaxAge   time.Duration // Delete segments older than this (0 = keep forever)
+	MaxBytes int64         // Per-server size cap on disk (0 = unlimited)
+	FeedOnly bool          // Follow output for the observers only, without writing segments
+}
+
+// ContainerOutputObserver receives every new line of a managed server container's output
+type ContainerOutputObserver func(serverID, serverName string, line LogLine)
+
+// serverLogArchive is the segment set of one server (keyed by server name, so it survives rebuilds)
+type serverLogArchive struct {
+	mu       sync.Mutex
+	dir      string
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+	firstTs  int64 // First timestamp in the active segment (0 = empty)
+	lastTs   int64 // Last archived timestamp across all segments
+	openedAt time.Time
+	dirty    bool
+}
+
+// LogArchiver continuously captures managed container output into rotated, compressed segments.
+// Its reader is the agent's one persistent Docker log stream per container: log alerts and
+// log sinks observe the same lines instead of following the container themselves.
+type LogArchiver struct {
+	mu        sync.Mutex
+	docker    *DockerClient
+	config    LogArchiveConfig
+	root      string
+	servers   map[string]*serverLogArchive  // serverName -> archive
+	readers   map[string]context.CancelFunc // containerID -> reader cancel
+	observers []ContainerOutputObserver
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewLogArchiver creates a log archiver rooted in the agent state directory
+func NewLogArchiver(docker *DockerClient, config LogArchiveConfig) *LogArchiver {
+	return &LogArchiver{
+		docker:  docker,
+		config:  config,
+		root:    filepath.Join(StateDir(), logArchiveDirName),
+		servers: make(map[string]*serverLogArchive),
+		readers: make(map[string]context.CancelFunc),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// AddObserver registers a consumer for container output. Must be called before Start.
+func (la *LogArchiver) AddObserver(observe ContainerOutputObserver) {
+	la.observers = append(la.observers, observe)
+}
+
+// Start begins discovering containers and archiving their output
+func (la *LogArchiver) Start() {
+	if la.config.FeedOnly {
+		logArchiveLogger.Infof("Starting container output feed (archiving disabled)")
+	} else {
+		logArchiveLogger.Infof("Starting log archiver (dir=%s, maxAge=%v, maxBytes=%d)", la.root, la.config.MaxAge, la.config.MaxBytes)
+	}
+	go la.loop()
+}
+
+// Stop stops all readers and flushes active segments
+func (la *LogArchiver) Stop() {
+	logArchiveLogger.Infof("Stopping log archiver")
+	close(la.stopCh)
+
+	la.mu.Lock()
+	for containerID, cancel := range la.readers {
+		cancel()
+		delete(la.readers, containerID)
+	}
+	la.mu.Unlock()
+	la.wg.Wait()
+
+	la.mu.Lock()
+	defer la.mu.Unlock()
+	for _, sa := range la.servers {
+		sa.mu.Lock()
+		sa.closeActive()
+		sa.mu.Unlock()
+	}
+}
+
+// loop runs discovery, flushing and retention
+func (la *LogArchiver) loop() {
+	la.discover()
+	if !la.config.FeedOnly {
+		la.enforceRetention()
+	}
+
+	discoverTicker := time.NewTicker(logArchiveDiscoverEvery)
+	defer discoverTicker.Stop()
+	flushTicker := time.NewTicker(logArchiveFlushEvery)
+	defer flushTicker.Stop()
+	retentionTicker := time.NewTicker(logArchiveRetentionEvery)
+	defer retentionTicker.Stop()
+
+	for {
+		select {
+		case <-la.stopCh:
+			return
+		case <-discoverTicker.C:
+			la.discover()
+		case <-flushTicker.C:
+			la.flushAll()
+		case <-retentionTicker.C:
+			if !la.config.FeedOnly {
+				la.enforceRetention()
+			}
+		}
+	}
+}
+
+// discover starts a reader for every running managed container that doesn't have one
+func (la *LogArchiver) discover() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", "zedops.managed=true")
+
+	containers, err := la.docker.cli.ContainerList(ctx, container.ListOptions{Filters: filterArgs})
+	if err != nil {
+		logArchiveLogger.Errorf("Failed to list containers: %v", err)
+		return
+	}
+
+	la.mu.Lock()
+	defer la.mu.Unlock()
+
+	select {
+	case <-la.stopCh:
+		return // Stop() may already be waiting on readers
+	default:
+	}
+
+	for _, c := range containers {
+		serverName := c.Labels["zedops.server.name"]
+		if serverName == "" || !validArchiveName(serverName) {
+			continue
+		}
+		if _, running := la.readers[c.ID]; running {
+			continue
+		}
+
+		var sa *serverLogArchive
+		if !la.config.FeedOnly {
+			var err error
+			if sa, err = la.archiveLocked(serverName); err != nil {
+				logArchiveLogger.Errorf("Failed to open archive for %s: %v", serverName, err)
+				continue
+			}
+		}
+
+		readerCtx, readerCancel := context.WithCancel(context.Background())
+		la.readers[c.ID] = readerCancel
+		la.wg.Add(1)
+		go la.archiveContainer(readerCtx, c.ID, c.Labels["zedops.server.id"], serverName, sa)
+	}
+}
+
+// archiveContainer follows one container's output into its server archive and the observers
+// until the stream ends. Observers see each line once: the lines new to the archive, which
+// after an agent restart includes output produced while the agent was down.
+// sa is nil in feed-only mode, where only output from now on is followed.
+func (la *LogArchiver) archiveContainer(ctx context.Context, containerID, serverID, serverName string, sa *serverLogArchive) {
+	defer la.wg.Done()
+	defer func() {
+		la.mu.Lock()
+		delete(la.readers, containerID)
+		la.mu.Unlock()
+	}()
+
+	opts := LogStreamOptions{Follow: true, Since: time.Now()}
+	var resumeTs int64
+	if sa != nil {
+		sa.mu.Lock()
+		// Without a previous position, capture everything the container still has
+		opts.Since = time.Unix(1, 0)
+		if sa.lastTs > 0 {
+			opts.Since = time.UnixMilli(sa.lastTs)
+			resumeTs = sa.lastTs
+		}
+		sa.mu.Unlock()
+	}
+
+	logChan, errChan := la.docker.StreamContainerLogsWithOptions(ctx, containerID, opts)
+	for line := range logChan {
+		la.archiveLine(sa, resumeTs, containerID, serverID, serverName, line)
+	}
+	if err, ok := <-errChan; ok && err != nil && ctx.Err() == nil {
+		logArchiveLogger.Infof("Log stream for %s ended: %v", serverName, err)
+	}
+
+	if sa != nil {
+		sa.mu.Lock()
+		sa.flush()
+		sa.mu.Unlock()
+	}
+}
+
+// archiveLine archives one line of a container's output and passes it to the observers.
+// Lines at or before resumeTs, the archive position when the reader started, are dropped:
+// Docker's since filter has second granularity on some engines, so the stream replays them.
+// Live lines are never compared with each other, as many can share a millisecond.
+func (la *LogArchiver) archiveLine(sa *serverLogArchive, resumeTs int64, containerID, serverID, serverName string, line LogLine) {
+	if line.Timestamp <= resumeTs {
+		return
+	}
+	if sa != nil {
+		sa.mu.Lock()
+		if err := sa.append(ArchivedLogLine{
+			Timestamp:   line.Timestamp,
+			Stream:      line.Stream,
+			Message:     line.Message,
+			ContainerID: containerID,
+		}); err != nil {
+			logArchiveLogger.Errorf("Failed to write %s: %v", serverName, err)
+		}
+		sa.mu.Unlock()
+	}
+
+	line.ContainerID = containerID
+	for _, observe := range la.observers {
+		observe(serverID, serverName, line)
+	}
+}
+
+// archiveLocked returns (opening if needed) the archive of a server. Must be called with la.mu held.
+func (la *LogArchiver) archiveLocked(serverName string) (*serverLogArchive, error) {
+	if sa, ok := la.servers[serverName]; ok {
+		return sa, nil
+	}
+
+	dir := filepath.Join(la.root, serverName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	sa := &serverLogArchive{dir: dir}
+	if err := sa.recover(); err != nil {
+		return nil, err
+	}
+	la.servers[serverName] = sa
+	return sa, nil
+}
+
+// flushAll flushes buffered writes of all archives
+func (la *LogArchiver) flushAll() {
+	la.mu.Lock()
+	archives := make([]*serverLogArchive, 0, len(la.servers))
+	for _, sa := range la.servers {
+		archives = append(archives, sa)
+	}
+	la.mu.Unlock()
+
+	for _, sa := range archives {
+		sa.mu.Lock()
+		sa.flush()
+		sa.mu.Unlock()
+	}
+}
+
+// recover re-reads the active segment left by a previous run to restore size and timestamps
+func (sa *serverLogArchive) recover() error {
+	segments, err := listLogSegments(sa.dir)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		if seg.lastTs > sa.lastTs {
+			sa.lastTs = seg.lastTs
+		}
+	}
+
+	activePath := filepath.Join(sa.dir, logArchiveActiveSegment)
+	info, err := os.Stat(activePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sa.size = info.Size()
+	sa.openedAt = info.ModTime()
+	err = scanLogSegment(activePath, func(line ArchivedLogLine) bool {
+		if sa.firstTs == 0 {
+			sa.firstTs = line.Timestamp
+		}
+		if line.Timestamp > sa.lastTs {
+			sa.lastTs = line.Timestamp
+		}
+		return true
+	})
+	return err
+}
+
+// append writes one line to the active segment, rotating first if it is full. Must be called with sa.mu held.
+func (sa *serverLogArchive) append(line ArchivedLogLine) error {
+	if sa.firstTs != 0 && (sa.size >= logArchiveSegmentMaxBytes || time.Since(sa.openedAt) >= logArchiveSegmentMaxAge) {
+		if err := sa.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if sa.file == nil {
+		f, err := os.OpenFile(filepath.Join(sa.dir, logArchiveActiveSegment), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		sa.file = f
+		sa.writer = bufio.NewWriterSize(f, 64*1024)
+		if sa.firstTs == 0 {
+			sa.openedAt = time.Now()
+		}
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := sa.writer.Write(data); err != nil {
+		return err
+	}
+
+	sa.size += int64(len(data))
+	sa.dirty = true
+	if sa.firstTs == 0 {
+		sa.firstTs = line.Timestamp
+	}
+	sa.lastTs = line.Timestamp
+	return nil
+}
+
+// flush writes buffered lines to disk. Must be called with sa.mu held.
+func (sa *serverLogArchive) flush() {
+	if sa.writer != nil && sa.dirty {
+		if err := sa.writer.Flush(); err != nil {
+			logArchiveLogger.Warnf("Flush failed in %s: %v", sa.dir, err)
+		}
+		sa.dirty = false
+	}
+}
+
+// closeActive flushes and closes the active segment file. Must be called with sa.mu held.
+func (sa *serverLogArchive) closeActive() {
+	sa.flush()
+	if sa.file != nil {
+		sa.file.Close()
+		sa.file = nil
+		sa.writer = nil
+	}
+}
+
+// rotate compresses the active segment into <firstTs>-<lastTs>.jsonl.gz. Must be called with sa.mu held.
+func (sa *serverLogArchive) rotate() error {
+	sa.closeActive()
+
+	activePath := filepath.Join(sa.dir, logArchiveActiveSegment)
+	name := fmt.Sprintf("%d-%d.jsonl.gz", sa.firstTs, sa.lastTs)
+	if err := gzipFile(activePath, filepath.Join(sa.dir, name)); err != nil {
+		return fmt.Errorf("failed to compress segment: %w", err)
+	}
+	if err := os.Remove(activePath); err != nil {
+		return err
+	}
+
+	sa.size = 0
+	sa.firstTs = 0
+	return nil
+}
+
+// gzipFile compresses src into dst atomically
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		gz.Close()
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// logSegment describes one segment file on disk
+type logSegment struct {
+	path    string
+	firstTs int64
+	lastTs  int64 // 0 for the active segment (open-ended)
+	size    int64
+	active  bool
+}
+
+// listLogSegments returns a server's segments, oldest first, with the active segment last
+func listLogSegments(dir string) ([]logSegment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var segments []logSegment
+	var active *logSegment
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || e.IsDir() {
+			continue
+		}
+		if e.Name() == logArchiveActiveSegment {
+			active = &logSegment{path: filepath.Join(dir, e.Name()), size: info.Size(), active: true}
+			continue
+		}
+		if !strings.HasSuffix(e.Name(), ".jsonl.gz") {
+			continue
+		}
+		first, last, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".jsonl.gz"), "-")
+		if !ok {
+			continue
+		}
+		firstTs, err1 := strconv.ParseInt(first, 10, 64)
+		lastTs, err2 := strconv.ParseInt(last, 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		segments = append(segments, logSegment{path: filepath.Join(dir, e.Name()), firstTs: firstTs, lastTs: lastTs, size: info.Size()})
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i].firstTs < segments[j].firstTs })
+	if active != nil {
+		segments = append(segments, *active)
+	}
+	return segments, nil
+}
+
+// scanLogSegment calls fn for every line of a (possibly gzipped) segment until fn returns false.
+// Unparseable lines (e.g. a partially flushed last line) are skipped.
+func scanLogSegment(path string, fn func(ArchivedLogLine) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line ArchivedLogLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if !fn(line) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// enforceRetention deletes compressed segments past the age limit, then the oldest ones over the size cap
+func (la *LogArchiver) enforceRetention() {
+	entries, err := os.ReadDir(la.root)
+	if err != nil {
+		return
+	}
+
+	cutoff := int64(0)
+	if la.config.MaxAge > 0 {
+		cutoff = time.Now().Add(-la.config.MaxAge).UnixMilli()
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(la.root, e.Name())
+		segments, err := listLogSegments(dir)
+		if err != nil {
+			logArchiveLogger.Warnf("Failed to list %s: %v", dir, err)
+			continue
+		}
+
+		var total int64
+		for _, seg := range segments {
+			total += seg.size
+		}
+
+		removed := 0
+		for _, seg := range segments {
+			if seg.active {
+				continue
+			}
+			expired := cutoff > 0 && seg.lastTs < cutoff
+			oversize := la.config.MaxBytes > 0 && total > la.config.MaxBytes
+			if !expired && !oversize {
+				break // Segments are sorted oldest first
+			}
+			if err := os.Remove(seg.path); err != nil {
+				logArchiveLogger.Warnf("Failed to remove %s: %v", seg.path, err)
+				continue
+			}
+			total -= seg.size
+			removed++
+		}
+		if removed > 0 {
+			logArchiveLogger.Infof("Retention removed %d segment(s) of %s", removed, e.Name())
+		}
+	}
+}
+
+// validArchiveName rejects server names that can't be used as a directory name
+func validArchiveName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// LogQueryRequest is the logs.query request
+type LogQueryRequest struct {
+	ServerName string `json:"serverName"`
+	Since      int64  `json:"since,omitempty"`    // Unix milliseconds (inclusive)
+	Until      int64  `json:"until,omitempty"`    // Unix milliseconds (exclusive)
+	Stream     string `json:"stream,omitempty"`   // stdout, stderr or empty for both
+	Contains   string `json:"contains,omitempty"` // Case-insensitive substring
+	Regex      string `json:"regex,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	Cursor     string `json:"cursor,omitempty"` // From a previous response's nextCursor
+}
+
+// LogQueryResponse is the logs.query response
+type LogQueryResponse struct {
+	Success    bool              `json:"success"`
+	Lines      []ArchivedLogLine `json:"lines"`
+	HasMore    bool              `json:"hasMore"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// logQueryCursor marks a position as "after skip lines with timestamp ts"
+type logQueryCursor struct {
+	ts   int64
+	skip int
+}
+
+func (c logQueryCursor) String() string {
+	return fmt.Sprintf("%d:%d", c.ts, c.skip)
+}
+
+func parseLogQueryCursor(s string) (logQueryCursor, error) {
+	tsStr, skipStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return logQueryCursor{}, fmt.Errorf("invalid cursor")
+	}
+	ts, err1 := strconv.ParseInt(tsStr, 10, 64)
+	skip, err2 := strconv.Atoi(skipStr)
+	if err1 != nil || err2 != nil || skip < 0 {
+		return logQueryCursor{}, fmt.Errorf("invalid cursor")
+	}
+	return logQueryCursor{ts: ts, skip: skip}, nil
+}
+
+// Query returns archived lines in time order matching the request
+func (la *LogArchiver) Query(req LogQueryRequest) (LogQueryResponse, error) {
+	if !validArchiveName(req.ServerName) {
+		return LogQueryResponse{}, fmt.Errorf("serverName is required")
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = logQueryDefaultLimit
+	}
+	if limit > logQueryMaxLimit {
+		limit = logQueryMaxLimit
+	}
+
+	var re *regexp.Regexp
+	if req.Regex != "" {
+		var err error
+		if re, err = regexp.Compile(req.Regex); err != nil {
+			return LogQueryResponse{}, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	contains := strings.ToLower(req.Contains)
+
+	cursor := logQueryCursor{ts: req.Since, skip: 0}
+	hasCursor := req.Cursor != ""
+	if hasCursor {
+		var err error
+		if cursor, err = parseLogQueryCursor(req.Cursor); err != nil {
+			return LogQueryResponse{}, err
+		}
+	}
+
+	// Make sure buffered lines are visible to the reader
+	la.mu.Lock()
+	sa := la.servers[req.ServerName]
+	la.mu.Unlock()
+	if sa != nil {
+		sa.mu.Lock()
+		sa.flush()
+		sa.mu.Unlock()
+	}
+
+	segments, err := listLogSegments(filepath.Join(la.root, req.ServerName))
+	if err != nil {
+		return LogQueryResponse{}, err
+	}
+
+	resp := LogQueryResponse{Success: true, Lines: []ArchivedLogLine{}}
+	sameTs := 0 // Matching lines seen at cursor.ts (to honour skip)
+	last := logQueryCursor{}
+
+	for _, seg := range segments {
+		if !seg.active && (seg.lastTs < cursor.ts || (req.Until > 0 && seg.firstTs >= req.Until)) {
+			continue
+		}
+
+		done := false
+		err := scanLogSegment(seg.path, func(line ArchivedLogLine) bool {
+			if line.Timestamp < cursor.ts {
+				return true
+			}
+			if req.Until > 0 && line.Timestamp >= req.Until {
+				done = true
+				return false
+			}
+			if req.Stream != "" && line.Stream != req.Stream {
+				return true
+			}
+			if contains != "" && !strings.Contains(strings.ToLower(line.Message), contains) {
+				return true
+			}
+			if re != nil && !re.MatchString(line.Message) {
+				return true
+			}
+			if hasCursor && line.Timestamp == cursor.ts {
+				sameTs++
+				if sameTs <= cursor.skip {
+					return true
+				}
+			}
+
+			if len(resp.Lines) == limit {
+				resp.HasMore = true
+				done = true
+				return false
+			}
+			resp.Lines = append(resp.Lines, line)
+
+			if line.Timestamp == last.ts {
+				last.skip++
+			} else {
+				last = logQueryCursor{ts: line.Timestamp, skip: 1}
+			}
+			return true
+		})
+		if err != nil && !os.IsNotExist(err) {
+			// A segment rotated away between listing and reading is skipped
+			logArchiveLogger.Warnf("Failed to read %s: %v", seg.path, err)
+		}
+		if done {
+			break
+		}
+	}
+
+	if resp.HasMore {
+		// Lines at the same timestamp on the previous page still count towards skip
+		if hasCursor && last.ts == cursor.ts {
+			last.skip += cursor.skip
+		}
+		resp.NextCursor = last.String()
+	}
+	return resp, nil
+}
+
+// handleLogsQuery handles logs.query messages
+func (a *Agent) handleLogsQuery(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req LogQueryRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendLogQueryResponse(msg.Reply, LogQueryResponse{Error: "Invalid request format"})
+		return
+	}
+
+	if a.logArchive == nil {
+		a.sendLogQueryResponse(msg.Reply, LogQueryResponse{Error: "Log archive not enabled"})
+		return
+	}
+
+	resp, err := a.logArchive.Query(req)
+	if err != nil {
+		a.sendLogQueryResponse(msg.Reply, LogQueryResponse{Error: err.Error()})
+		return
+	}
+	a.sendLogQueryResponse(msg.Reply, resp)
+}
+
+// sendLogQueryResponse replies to a logs.query request
+func (a *Agent) sendLogQueryResponse(replyTo string, resp LogQueryResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestLogArchiver returns an archiver rooted in a temporary directory, without Docker
+func newTestLogArchiver(t *testing.T, config LogArchiveConfig) *LogArchiver {
+	t.Helper()
+	return &LogArchiver{
+		config:  config,
+		root:    t.TempDir(),
+		servers: make(map[string]*serverLogArchive),
+		readers: make(map[string]context.CancelFunc),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// archivedLines reads back every line of a server's archive
+func archivedLines(t *testing.T, la *LogArchiver, serverName string) []ArchivedLogLine {
+	t.Helper()
+	resp, err := la.Query(LogQueryRequest{ServerName: serverName, Limit: logQueryMaxLimit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Lines
+}
+
+func lineMessages(lines []ArchivedLogLine) string {
+	messages := make([]string, len(lines))
+	for i, l := range lines {
+		messages[i] = l.Message
+	}
+	return strings.Join(messages, ",")
+}
+
+func TestLogArchiverArchiveLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   []LogLine // Archived by a previous run
+		stream   []LogLine // What the new reader receives
+		want     string    // Archive afterwards
+		observed string
+	}{
+		{
+			name:     "lines sharing a millisecond",
+			stream:   []LogLine{{Timestamp: 1000, Message: "a"}, {Timestamp: 1000, Message: "b"}, {Timestamp: 1000, Message: "c"}, {Timestamp: 1001, Message: "d"}},
+			want:     "a,b,c,d",
+			observed: "a,b,c,d",
+		},
+		{
+			name:     "replay after a restart",
+			before:   []LogLine{{Timestamp: 1000, Message: "a"}, {Timestamp: 2000, Message: "b"}, {Timestamp: 2000, Message: "c"}},
+			stream:   []LogLine{{Timestamp: 2000, Message: "b"}, {Timestamp: 2000, Message: "c"}, {Timestamp: 2500, Message: "d"}, {Timestamp: 2500, Message: "e"}},
+			want:     "a,b,c,d,e",
+			observed: "d,e",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			la := newTestLogArchiver(t, LogArchiveConfig{})
+			var observed []string
+			la.AddObserver(func(serverID, serverName string, line LogLine) {
+				if serverID != "srv-1" || serverName != "survival" || line.ContainerID != "c1" {
+					t.Errorf("observed %s/%s/%s", serverID, serverName, line.ContainerID)
+				}
+				observed = append(observed, line.Message)
+			})
+
+			if len(tt.before) > 0 {
+				previous := newTestLogArchiver(t, LogArchiveConfig{})
+				previous.root = la.root
+				sa, err := previous.archiveLocked("survival")
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, line := range tt.before {
+					previous.archiveLine(sa, 0, "c0", "srv-1", "survival", line)
+				}
+				sa.closeActive()
+			}
+
+			// A reader resumes from the archive's position, as archiveContainer does
+			sa, err := la.archiveLocked("survival")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resumeTs := sa.lastTs
+			for _, line := range tt.stream {
+				la.archiveLine(sa, resumeTs, "c1", "srv-1", "survival", line)
+			}
+
+			if got := lineMessages(archivedLines(t, la, "survival")); got != tt.want {
+				t.Errorf("archived %q, want %q", got, tt.want)
+			}
+			if got := strings.Join(observed, ","); got != tt.observed {
+				t.Errorf("observed %q, want %q", got, tt.observed)
+			}
+		})
+	}
+}
+
+func TestServerLogArchiveRotateAndRecover(t *testing.T) {
+	tests := []struct {
+		name     string
+		batches  [][]int64 // Timestamps appended, with a rotation between batches
+		segments []string  // Files on disk afterwards
+		firstTs  int64     // Of the active segment, as recovered
+		lastTs   int64
+	}{
+		{"active only", [][]int64{{100, 100, 200}}, []string{"current.jsonl"}, 100, 200},
+		{"rotated", [][]int64{{100, 200}, {300}}, []string{"100-200.jsonl.gz", "current.jsonl"}, 300, 300},
+		{"rotated twice", [][]int64{{100}, {200, 200}, {200, 400}}, []string{"100-100.jsonl.gz", "200-200.jsonl.gz", "current.jsonl"}, 200, 400},
+		{"rotated, nothing since", [][]int64{{100, 200}, {}}, []string{"100-200.jsonl.gz"}, 0, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sa := &serverLogArchive{dir: dir}
+			total := 0
+			for i, batch := range tt.batches {
+				if i > 0 {
+					if err := sa.rotate(); err != nil {
+						t.Fatal(err)
+					}
+				}
+				for _, ts := range batch {
+					if err := sa.append(ArchivedLogLine{Timestamp: ts, Stream: "stdout", Message: fmt.Sprint("line ", total)}); err != nil {
+						t.Fatal(err)
+					}
+					total++
+				}
+			}
+			sa.closeActive()
+
+			entries, _ := os.ReadDir(dir)
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			if strings.Join(names, " ") != strings.Join(tt.segments, " ") {
+				t.Errorf("segments = %v, want %v", names, tt.segments)
+			}
+
+			recovered := &serverLogArchive{dir: dir}
+			if err := recovered.recover(); err != nil {
+				t.Fatal(err)
+			}
+			if recovered.firstTs != tt.firstTs || recovered.lastTs != tt.lastTs || recovered.size != sa.size {
+				t.Errorf("recovered firstTs=%d lastTs=%d size=%d, want %d %d %d",
+					recovered.firstTs, recovered.lastTs, recovered.size, tt.firstTs, tt.lastTs, sa.size)
+			}
+
+			// Every line is still readable, in order
+			la := newTestLogArchiver(t, LogArchiveConfig{})
+			la.root = filepath.Dir(dir)
+			lines := archivedLines(t, la, filepath.Base(dir))
+			if len(lines) != total {
+				t.Errorf("read back %d lines, want %d", len(lines), total)
+			}
+			for i, line := range lines {
+				if line.Message != fmt.Sprint("line ", i) {
+					t.Errorf("line %d = %q", i, line.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestServerLogArchiveRotatesOldSegment(t *testing.T) {
+	sa := &serverLogArchive{dir: t.TempDir()}
+	if err := sa.append(ArchivedLogLine{Timestamp: 100, Message: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	sa.openedAt = time.Now().Add(-logArchiveSegmentMaxAge)
+	if err := sa.append(ArchivedLogLine{Timestamp: 200, Message: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	sa.closeActive()
+	if _, err := os.Stat(filepath.Join(sa.dir, "100-100.jsonl.gz")); err != nil {
+		t.Errorf("old segment not rotated: %v", err)
+	}
+	if sa.firstTs != 200 {
+		t.Errorf("active segment starts at %d, want 200", sa.firstTs)
+	}
+}
+
+func TestLogArchiverRetention(t *testing.T) {
+	now := time.Now()
+	hoursAgo := func(h int) int64 { return now.Add(-time.Duration(h) * time.Hour).UnixMilli() }
+	segment := func(from, to int) string { return fmt.Sprintf("%d-%d.jsonl.gz", hoursAgo(from), hoursAgo(to)) }
+	files := []string{segment(50, 40), segment(40, 30), segment(30, 20), segment(20, 10), "current.jsonl"}
+
+	tests := []struct {
+		name   string
+		config LogArchiveConfig
+		want   []string
+	}{
+		{"unlimited", LogArchiveConfig{}, files},
+		{"by age", LogArchiveConfig{MaxAge: 35 * time.Hour}, files[1:]},
+		{"by size", LogArchiveConfig{MaxBytes: 250}, files[3:]},
+		{"by age and size", LogArchiveConfig{MaxAge: 45 * time.Hour, MaxBytes: 350}, files[2:]},
+		// The active segment is never deleted, even alone over the cap
+		{"active over the cap", LogArchiveConfig{MaxBytes: 50}, files[4:]},
+		{"everything expired", LogArchiveConfig{MaxAge: time.Hour}, files[4:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			la := newTestLogArchiver(t, tt.config)
+			dir := filepath.Join(la.root, "survival")
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Repeat("x", 100)), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			la.enforceRetention()
+
+			segments, err := listLogSegments(filepath.Join(la.root, "survival"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, seg := range segments {
+				names = append(names, filepath.Base(seg.path))
+			}
+			if strings.Join(names, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("kept %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogArchiverQueryPagesAcrossSegments(t *testing.T) {
+	la := newTestLogArchiver(t, LogArchiveConfig{})
+	sa, err := la.archiveLocked("survival")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Five lines share ts 2000 across a rotation, three share ts 3000 in the active segment
+	segments := [][]int64{{1000, 2000, 2000}, {2000, 2000, 2000, 3000}, {3000, 3000, 4000}}
+	var all []ArchivedLogLine
+	for i, batch := range segments {
+		if i > 0 {
+			if err := sa.rotate(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, ts := range batch {
+			stream := "stdout"
+			if len(all)%2 == 1 {
+				stream = "stderr"
+			}
+			line := ArchivedLogLine{Timestamp: ts, Stream: stream, Message: fmt.Sprint("line ", len(all))}
+			if err := sa.append(line); err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, line)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		req   LogQueryRequest
+		match func(ArchivedLogLine) bool
+	}{
+		{"all", LogQueryRequest{}, func(ArchivedLogLine) bool { return true }},
+		{"stderr", LogQueryRequest{Stream: "stderr"}, func(l ArchivedLogLine) bool { return l.Stream == "stderr" }},
+		{"since", LogQueryRequest{Since: 2000}, func(l ArchivedLogLine) bool { return l.Timestamp >= 2000 }},
+		{"until", LogQueryRequest{Until: 3000}, func(l ArchivedLogLine) bool { return l.Timestamp < 3000 }},
+		{"regex", LogQueryRequest{Regex: `[02468]$`}, func(l ArchivedLogLine) bool { return strings.ContainsAny(l.Message[len(l.Message)-1:], "02468") }},
+	}
+	for _, tt := range tests {
+		var want []ArchivedLogLine
+		for _, line := range all {
+			if tt.match(line) {
+				want = append(want, line)
+			}
+		}
+		for _, limit := range []int{1, 2, 3, 4, 100} {
+			t.Run(fmt.Sprintf("%s/limit %d", tt.name, limit), func(t *testing.T) {
+				req := tt.req
+				req.ServerName = "survival"
+				req.Limit = limit
+				var got []ArchivedLogLine
+				for page := 0; ; page++ {
+					if page > len(all) {
+						t.Fatal("paging doesn't end")
+					}
+					resp, err := la.Query(req)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, resp.Lines...)
+					if !resp.HasMore {
+						break
+					}
+					if len(resp.Lines) != limit {
+						t.Errorf("page %d has %d lines, want %d", page, len(resp.Lines), limit)
+					}
+					req.Cursor = resp.NextCursor
+				}
+				if lineMessages(got) != lineMessages(want) {
+					t.Errorf("paged %q, want %q", lineMessages(got), lineMessages(want))
+				}
+			})
+		}
+	}
+
+	if _, err := la.Query(LogQueryRequest{ServerName: "survival", Cursor: "2000"}); err == nil {
+		t.Error("accepted a cursor without skip")
+	}
+	if _, err := la.Query(LogQueryRequest{ServerName: "../etc"}); err == nil {
+		t.Error("accepted a server name outside the archive")
+	}
+}
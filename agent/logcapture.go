@@ -1,28 +1,26 @@
 package main
 
 import (
-	"fmt"
-	"io"
-	"os"
 	"sync"
-	"time"
 )
 
 // AgentLogLine represents a single agent log entry
 type AgentLogLine struct {
-	Timestamp int64  `json:"timestamp"`
-	Level     string `json:"level"`
-	Message   string `json:"message"`
+	Timestamp int64                  `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Subsystem string                 `json:"subsystem,omitempty"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
 }
 
-// LogCapture captures log output and broadcasts to subscribers
+// LogCapture keeps recent structured log records and broadcasts them to subscribers
 type LogCapture struct {
 	mu           sync.RWMutex
 	buffer       []AgentLogLine
 	bufferSize   int
 	subscribers  map[chan AgentLogLine]bool
-	originalOut  io.Writer
-	isSubscribed bool // Whether manager is currently subscribed
+	listeners    []func(AgentLogLine) // Called for every line, unlike subscribers which drop when behind
+	isSubscribed bool                 // Whether manager is currently subscribed
 }
 
 // NewLogCapture creates a new log capture with specified buffer size
@@ -31,55 +29,20 @@ func NewLogCapture(bufferSize int) *LogCapture {
 		buffer:      make([]AgentLogLine, 0, bufferSize),
 		bufferSize:  bufferSize,
 		subscribers: make(map[chan AgentLogLine]bool),
-		originalOut: os.Stderr,
 	}
 }
 
-// Write implements io.Writer interface
-// This is called by the log package when logging
-func (lc *LogCapture) Write(p []byte) (n int, err error) {
-	// Write to original output first
-	n, err = lc.originalOut.Write(p)
-
-	// Parse and store the log line
-	message := string(p)
-	if len(message) > 0 && message[len(message)-1] == '\n' {
-		message = message[:len(message)-1]
-	}
-
-	// Detect log level from message prefix
-	level := "INFO"
-	if len(message) > 0 {
-		// Standard log package uses date/time prefix, look for keywords
-		if containsAny(message, []string{
-			"ERROR", "error:", "Error:",
-			"FATAL", "fatal:",
-			"Failed to", "failed to",
-			"Failed:", "failed:",
-		}) {
-			level = "ERROR"
-		} else if containsAny(message, []string{"WARN", "Warning:", "warning:"}) {
-			level = "WARN"
-		} else if containsAny(message, []string{"DEBUG", "debug:"}) {
-			level = "DEBUG"
-		}
-	}
-
-	logLine := AgentLogLine{
-		Timestamp: time.Now().UnixMilli(),
-		Level:     level,
-		Message:   message,
-	}
-
-	lc.addLine(logLine)
-
-	return n, err
+// Append records a log entry. Called by the slog handler for every emitted record.
+func (lc *LogCapture) Append(line AgentLogLine) {
+	// Records from the log handler are already redacted; this covers any other caller,
+	// since captured lines are streamed to the manager via agent.logs
+	line.Message = RedactString(line.Message)
+	lc.addLine(line)
 }
 
-// addLine adds a log line to the buffer and broadcasts to subscribers
+// addLine adds a log line to the buffer and broadcasts to subscribers and listeners
 func (lc *LogCapture) addLine(line AgentLogLine) {
 	lc.mu.Lock()
-	defer lc.mu.Unlock()
 
 	// Add to ring buffer
 	if len(lc.buffer) >= lc.bufferSize {
@@ -96,6 +59,20 @@ func (lc *LogCapture) addLine(line AgentLogLine) {
 			// Channel full, skip this line for this subscriber
 		}
 	}
+	listeners := lc.listeners
+	lc.mu.Unlock()
+
+	for _, listen := range listeners {
+		listen(line)
+	}
+}
+
+// AddListener registers a function called synchronously with every captured line.
+// It must not block or log.
+func (lc *LogCapture) AddListener(listen func(AgentLogLine)) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.listeners = append(lc.listeners, listen)
 }
 
 // GetHistory returns the buffered log history
@@ -145,46 +122,3 @@ func (lc *LogCapture) HasSubscribers() bool {
 	defer lc.mu.RUnlock()
 	return lc.isSubscribed
 }
-
-// Helper function to check if string contains any of the substrings
-func containsAny(s string, substrs []string) bool {
-	for _, substr := range substrs {
-		if len(s) >= len(substr) {
-			for i := 0; i <= len(s)-len(substr); i++ {
-				if s[i:i+len(substr)] == substr {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
-
-// LogCaptureWriter wraps LogCapture to work with log.SetOutput
-type LogCaptureWriter struct {
-	capture *LogCapture
-}
-
-// Write implements io.Writer for LogCaptureWriter
-func (w *LogCaptureWriter) Write(p []byte) (n int, err error) {
-	return w.capture.Write(p)
-}
-
-// SetupLogCapture configures the standard log package to use LogCapture
-func SetupLogCapture(bufferSize int) *LogCapture {
-	capture := NewLogCapture(bufferSize)
-
-	// Create a multi-writer that writes to both stderr and our capture
-	multiWriter := io.MultiWriter(os.Stderr, capture)
-
-	// Note: We can't easily redirect log.Printf to capture structured logs
-	// Instead, we'll set up the log package output
-	// The LogCapture.Write method will parse the log format
-
-	// For now, return the capture for the agent to use
-	// The agent will need to configure its own logging
-	fmt.Fprintf(os.Stderr, "Log capture initialized with buffer size %d\n", bufferSize)
-	_ = multiWriter // Will be used when we set up proper logging
-
-	return capture
-}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	logFetchDefaultLimit = 500
+	logFetchMaxLimit     = 5000
+	logFetchTimeout      = 30 * time.Second
+)
+
+// LogFetchRequest is the logs.fetch request
+type LogFetchRequest struct {
+	ContainerID string `json:"containerId"`
+	Since       int64  `json:"since,omitempty"`  // Unix milliseconds (inclusive)
+	Until       int64  `json:"until,omitempty"`  // Unix milliseconds
+	Tail        int    `json:"tail,omitempty"`   // Last N lines of the range (first page only)
+	Limit       int    `json:"limit,omitempty"`  // Page size
+	Cursor      string `json:"cursor,omitempty"` // From a previous response's nextCursor
+}
+
+// LogFetchResponse is the logs.fetch response
+type LogFetchResponse struct {
+	Success     bool      `json:"success"`
+	ContainerID string    `json:"containerId"`
+	Lines       []LogLine `json:"lines"`
+	HasMore     bool      `json:"hasMore"`
+	NextCursor  string    `json:"nextCursor,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// FetchContainerLogs reads one page of a container's past output without following.
+// Pages are in time order; the cursor resumes after the last line returned.
+func (dc *DockerClient) FetchContainerLogs(ctx context.Context, req LogFetchRequest) (LogFetchResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = logFetchDefaultLimit
+	}
+	if limit > logFetchMaxLimit {
+		limit = logFetchMaxLimit
+	}
+
+	cursor := logQueryCursor{ts: req.Since}
+	hasCursor := req.Cursor != ""
+	if hasCursor {
+		var err error
+		if cursor, err = parseLogQueryCursor(req.Cursor); err != nil {
+			return LogFetchResponse{}, err
+		}
+	}
+
+	opts := LogStreamOptions{Follow: false}
+	if cursor.ts > 0 {
+		opts.Since = time.UnixMilli(cursor.ts)
+	}
+	if req.Until > 0 {
+		opts.Until = time.UnixMilli(req.Until)
+	}
+	if req.Tail > 0 && !hasCursor {
+		opts.Tail = req.Tail
+	} else if opts.Since.IsZero() {
+		opts.Tail = -1 // Whole log; "0" would return nothing
+	}
+
+	// Stop reading as soon as the page is full
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logChan, errChan := dc.StreamContainerLogsWithOptions(readCtx, req.ContainerID, opts)
+
+	resp := LogFetchResponse{Success: true, ContainerID: req.ContainerID, Lines: []LogLine{}}
+	sameTs := 0
+	last := logQueryCursor{}
+
+	for line := range logChan {
+		// Docker's since is inclusive; skip what earlier pages already returned at the cursor timestamp
+		if hasCursor && line.Timestamp <= cursor.ts {
+			if line.Timestamp < cursor.ts {
+				continue
+			}
+			sameTs++
+			if sameTs <= cursor.skip {
+				continue
+			}
+		}
+
+		if len(resp.Lines) == limit {
+			resp.HasMore = true
+			cancel()
+			break
+		}
+		resp.Lines = append(resp.Lines, line)
+
+		if line.Timestamp == last.ts {
+			last.skip++
+		} else {
+			last = logQueryCursor{ts: line.Timestamp, skip: 1}
+		}
+	}
+
+	if !resp.HasMore {
+		if err, ok := <-errChan; ok && err != nil {
+			return LogFetchResponse{}, err
+		}
+	}
+
+	if resp.HasMore {
+		if hasCursor && last.ts == cursor.ts {
+			last.skip += cursor.skip
+		}
+		resp.NextCursor = last.String()
+	}
+	return resp, nil
+}
+
+// handleLogsFetch handles logs.fetch messages
+func (a *Agent) handleLogsFetch(msg Message) {
+	if a.docker == nil {
+		a.sendLogFetchResponse(msg.Reply, LogFetchResponse{Error: "Docker client not initialized"})
+		return
+	}
+
+	data, _ := json.Marshal(msg.Data)
+	var req LogFetchRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ContainerID == "" {
+		a.sendLogFetchResponse(msg.Reply, LogFetchResponse{Error: "Invalid request format (containerId required)"})
+		return
+	}
+	if req.Since > 0 && req.Until > 0 && req.Until <= req.Since {
+		a.sendLogFetchResponse(msg.Reply, LogFetchResponse{ContainerID: req.ContainerID, Error: "until must be after since"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), logFetchTimeout)
+	defer cancel()
+
+	resp, err := a.docker.FetchContainerLogs(ctx, req)
+	if err != nil {
+		agentLogger.Errorf("Failed to fetch logs for container %s: %v", req.ContainerID, err)
+		a.sendLogFetchResponse(msg.Reply, LogFetchResponse{
+			ContainerID: req.ContainerID,
+			Error:       fmt.Sprintf("failed to fetch logs: %v", err),
+		})
+		return
+	}
+	a.sendLogFetchResponse(msg.Reply, resp)
+}
+
+// sendLogFetchResponse replies to a logs.fetch request
+func (a *Agent) sendLogFetchResponse(replyTo string, resp LogFetchResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
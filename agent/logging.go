@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subsystem loggers. Each tags its records with subsystem=<name>, and its level
+// can be changed at runtime with agent.loglevel.
+var (
+	agentLogger        = NewLogger("Agent")
+	agentLogsLogger    = NewLogger("AgentLogs")
+	alertLogger        = NewLogger("Alert")
+	backupLogger       = NewLogger("Backup")
+	connLogger         = NewLogger("Connection")
+	dockerLogger       = NewLogger("Docker")
+	gameLogsLogger     = NewLogger("GameLogs")
+	gracefulSaveLogger = NewLogger("GracefulSave")
+	idleLogger         = NewLogger("Idle")
+	logAlertsLogger    = NewLogger("LogAlerts")
+	logArchiveLogger   = NewLogger("LogArchive")
+	logHubLogger       = NewLogger("LogHub")
+	logSinksLogger     = NewLogger("LogSinks")
+	metricsLogger      = NewLogger("MetricsCollector")
+	metricAlertsLogger = NewLogger("MetricAlerts")
+	migrationLogger    = NewLogger("Migration")
+	networkLogger      = NewLogger("Network")
+	playerStatsLogger  = NewLogger("PlayerStats")
+	rconLogger         = NewLogger("RCON")
+	restoreLogger      = NewLogger("Restore")
+	serverLogger       = NewLogger("Server")
+	telemetryLogger    = NewLogger("Telemetry")
+	tokenLogger        = NewLogger("Token")
+	updateLogger       = NewLogger("Update")
+)
+
+// subsystemKey is the attribute carrying the subsystem name
+const subsystemKey = "subsystem"
+
+// Logger is a subsystem logger: a *slog.Logger plus printf-style helpers with explicit levels
+type Logger struct {
+	*slog.Logger
+}
+
+// NewLogger returns a logger tagged with a subsystem
+func NewLogger(subsystem string) *Logger {
+	return &Logger{slog.New(rootLogHandler).With(subsystemKey, subsystem)}
+}
+
+// With returns a logger with extra structured fields
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{l.Logger.With(args...)}
+}
+
+// Debugf logs a formatted message at debug level
+func (l *Logger) Debugf(format string, args ...any) {
+	l.logf(slog.LevelDebug, format, args...)
+}
+
+// Infof logs a formatted message at info level
+func (l *Logger) Infof(format string, args ...any) {
+	l.logf(slog.LevelInfo, format, args...)
+}
+
+// Warnf logs a formatted message at warn level
+func (l *Logger) Warnf(format string, args ...any) {
+	l.logf(slog.LevelWarn, format, args...)
+}
+
+// Errorf logs a formatted message at error level
+func (l *Logger) Errorf(format string, args ...any) {
+	l.logf(slog.LevelError, format, args...)
+}
+
+// Fatalf logs a formatted message at error level and exits
+func (l *Logger) Fatalf(format string, args ...any) {
+	l.logf(slog.LevelError, format, args...)
+	os.Exit(1)
+}
+
+func (l *Logger) logf(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	l.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+// LoggingConfig selects the log output format and levels
+type LoggingConfig struct {
+	Format string     // text or json
+	Level  slog.Level // Default level for subsystems without an override
+	Output io.Writer
+}
+
+// logState is shared by every handler derived from rootLogHandler
+type logState struct {
+	mu           sync.RWMutex
+	out          slog.Handler
+	capture      *LogCapture
+	defaultLevel slog.Level
+	levels       map[string]slog.Level // subsystem -> level override
+}
+
+var rootLogState = &logState{
+	out:          slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	defaultLevel: slog.LevelInfo,
+	levels:       make(map[string]slog.Level),
+}
+
+var rootLogHandler slog.Handler = &agentLogHandler{state: rootLogState}
+
+// SetupLogging configures output format, default level and log capture, and routes
+// the standard log package (used by dependencies) through the same handler.
+func SetupLogging(config LoggingConfig, capture *LogCapture) error {
+	output := config.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	// Filtering is done per subsystem by agentLogHandler, so the output handler accepts everything
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var out slog.Handler
+	switch config.Format {
+	case "", "text":
+		out = slog.NewTextHandler(output, opts)
+	case "json":
+		out = slog.NewJSONHandler(output, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (expected text or json)", config.Format)
+	}
+
+	rootLogState.mu.Lock()
+	rootLogState.out = out
+	rootLogState.capture = capture
+	rootLogState.defaultLevel = config.Level
+	rootLogState.mu.Unlock()
+
+	slog.SetDefault(slog.New(rootLogHandler))
+	log.SetFlags(0)
+	return nil
+}
+
+// SetLogLevel sets the level of one subsystem, or the default level when subsystem is empty
+func SetLogLevel(subsystem string, level slog.Level) {
+	rootLogState.mu.Lock()
+	defer rootLogState.mu.Unlock()
+
+	if subsystem == "" {
+		rootLogState.defaultLevel = level
+		return
+	}
+	rootLogState.levels[subsystem] = level
+}
+
+// ResetLogLevel removes a subsystem override so it follows the default level again
+func ResetLogLevel(subsystem string) {
+	rootLogState.mu.Lock()
+	defer rootLogState.mu.Unlock()
+	delete(rootLogState.levels, subsystem)
+}
+
+// LogLevels returns the default level and the per-subsystem overrides
+func LogLevels() (string, map[string]string) {
+	rootLogState.mu.RLock()
+	defer rootLogState.mu.RUnlock()
+
+	overrides := make(map[string]string, len(rootLogState.levels))
+	for subsystem, level := range rootLogState.levels {
+		overrides[subsystem] = level.String()
+	}
+	return rootLogState.defaultLevel.String(), overrides
+}
+
+// ParseLogLevel parses debug, info, warn or error (case-insensitive)
+func ParseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+func (s *logState) enabled(subsystem string, level slog.Level) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	min, ok := s.levels[subsystem]
+	if !ok {
+		min = s.defaultLevel
+	}
+	return level >= min
+}
+
+// agentLogHandler applies per-subsystem levels, writes to the configured output and feeds LogCapture
+type agentLogHandler struct {
+	state     *logState
+	subsystem string
+	attrs     []slog.Attr
+	groups    []string
+}
+
+func (h *agentLogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.state.enabled(h.subsystem, level)
+}
+
+func (h *agentLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.state.mu.RLock()
+	out := h.state.out
+	capture := h.state.capture
+	h.state.mu.RUnlock()
+
+	// Attributes bound with With are scrubbed again: secrets may have been registered since
+	r = redactRecord(r)
+	attrs := make([]slog.Attr, len(h.attrs))
+	for i, a := range h.attrs {
+		attrs[i] = redactAttr(a)
+	}
+	if len(attrs) > 0 {
+		out = out.WithAttrs(attrs)
+	}
+	for _, g := range h.groups {
+		out = out.WithGroup(g)
+	}
+	err := out.Handle(ctx, r)
+
+	if capture != nil {
+		fields := make(map[string]interface{})
+		for _, a := range attrs {
+			if a.Key != subsystemKey {
+				fields[a.Key] = captureValue(a.Value)
+			}
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			fields[a.Key] = captureValue(a.Value)
+			return true
+		})
+		if len(fields) == 0 {
+			fields = nil
+		}
+
+		timestamp := r.Time
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+		capture.Append(AgentLogLine{
+			Timestamp: timestamp.UnixMilli(),
+			Level:     levelName(r.Level),
+			Subsystem: h.subsystem,
+			Message:   r.Message,
+			Fields:    fields,
+		})
+	}
+	return err
+}
+
+func (h *agentLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		next.attrs = append(next.attrs, redactAttr(a))
+	}
+	for _, a := range attrs {
+		if a.Key == subsystemKey && len(h.groups) == 0 {
+			next.subsystem = a.Value.String()
+		}
+	}
+	return &next
+}
+
+func (h *agentLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.groups = append(append([]string{}, h.groups...), name)
+	return &next
+}
+
+// redactRecord returns a copy of a record with secrets removed from the message and attributes
+func redactRecord(r slog.Record) slog.Record {
+	clean := slog.NewRecord(r.Time, r.Level, RedactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(redactAttr(a))
+		return true
+	})
+	return clean
+}
+
+// redactAttr removes secrets from an attribute: sensitive keys are masked, values are scrubbed
+func redactAttr(a slog.Attr) slog.Attr {
+	if a.Key == subsystemKey {
+		return a
+	}
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, redactedPlaceholder)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		cleaned := make([]any, 0, len(group))
+		for _, ga := range group {
+			cleaned = append(cleaned, redactAttr(ga))
+		}
+		return slog.Group(a.Key, cleaned...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+		return slog.Any(a.Key, RedactValue(v.Any()))
+	default:
+		// Numeric passwords logged as numbers
+		if text := v.String(); RedactString(text) != text {
+			return slog.String(a.Key, redactedPlaceholder)
+		}
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
+
+// captureValue converts an attribute value to something that survives JSON encoding
+func captureValue(v slog.Value) interface{} {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return v.Any()
+	case slog.KindDuration, slog.KindTime, slog.KindGroup:
+		return v.String()
+	default:
+		return v.Any()
+	}
+}
+
+// levelName maps slog levels to the names the manager's log viewer expects
+func levelName(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARN"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// LogLevelRequest is the agent.loglevel request. An empty level only reads the current levels.
+type LogLevelRequest struct {
+	Subsystem string `json:"subsystem,omitempty"` // Empty = default level
+	Level     string `json:"level,omitempty"`     // debug, info, warn, error, or "reset" to drop an override
+}
+
+// LogLevelResponse is the agent.loglevel response
+type LogLevelResponse struct {
+	Success    bool              `json:"success"`
+	Default    string            `json:"default"`
+	Subsystems map[string]string `json:"subsystems"`
+	Known      []string          `json:"known"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// knownSubsystems lists subsystems that have logged or have a logger declared
+func knownSubsystems() []string {
+	seen := make(map[string]bool)
+	for _, l := range []*Logger{
+		agentLogger, agentLogsLogger, alertLogger, backupLogger, connLogger, dockerLogger,
+		gameLogsLogger, gracefulSaveLogger, idleLogger, logAlertsLogger, logArchiveLogger,
+		logHubLogger, logSinksLogger, metricAlertsLogger, metricsLogger, migrationLogger, networkLogger, playerStatsLogger,
+		rconLogger, restoreLogger, serverLogger, telemetryLogger, tokenLogger, updateLogger,
+	} {
+		if h, ok := l.Handler().(*agentLogHandler); ok {
+			seen[h.subsystem] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handleAgentLogLevel handles agent.loglevel messages
+func (a *Agent) handleAgentLogLevel(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req LogLevelRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendLogLevelResponse(msg.Reply, LogLevelResponse{Error: "Invalid request format"})
+		return
+	}
+
+	switch strings.ToLower(req.Level) {
+	case "":
+		// Read only
+	case "reset":
+		if req.Subsystem != "" {
+			ResetLogLevel(req.Subsystem)
+			agentLogger.Infof("Log level override for %s removed", req.Subsystem)
+		}
+	default:
+		level, err := ParseLogLevel(req.Level)
+		if err != nil {
+			a.sendLogLevelResponse(msg.Reply, LogLevelResponse{Error: err.Error()})
+			return
+		}
+		SetLogLevel(req.Subsystem, level)
+		if req.Subsystem == "" {
+			agentLogger.Infof("Default log level set to %s", level)
+		} else {
+			agentLogger.Infof("Log level for %s set to %s", req.Subsystem, level)
+		}
+	}
+
+	def, overrides := LogLevels()
+	a.sendLogLevelResponse(msg.Reply, LogLevelResponse{
+		Success:    true,
+		Default:    def,
+		Subsystems: overrides,
+		Known:      knownSubsystems(),
+	})
+}
+
+// sendLogLevelResponse replies to an agent.loglevel request
+func (a *Agent) sendLogLevelResponse(replyTo string, resp LogLevelResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	logHubHistoryLines      = 1000                   // Recent lines kept per container for late subscribers
+	logBatchInterval        = 250 * time.Millisecond // Flush pending lines at least this often...
+	logBatchMaxLines        = 200                    // ...or as soon as this many are pending
+	logSubscriptionMaxQueue = 2000                   // Pending lines per subscription before dropping
+	logStreamDefaultRate    = 500                    // Lines per second per subscription
+)
+
+// LogBatch is the log.batch payload: consecutive lines for one subscription
+type LogBatch struct {
+	SubscriptionID string    `json:"subscriptionId"`
+	ContainerID    string    `json:"containerId"`
+	Lines          []LogLine `json:"lines"`
+	Dropped        int       `json:"dropped,omitempty"` // Lines dropped since the previous batch
+}
+
+// LogHub fans out one Docker log reader per container to any number of subscriptions
+type LogHub struct {
+	mu      sync.Mutex
+	readers map[string]*containerLogReader // containerID -> reader
+	subs    map[string]*logSubscription    // subscriptionID -> subscription
+	stream  func(ctx context.Context, containerID string, tail int) (<-chan LogLine, <-chan error)
+	send    func(Message) error
+}
+
+// containerLogReader is the single Docker log stream of a container
+type containerLogReader struct {
+	containerID string
+	cancel      context.CancelFunc
+	history     []LogLine // Ring of the most recent lines
+	historyPos  int
+	backfill    int   // Tail lines the stream may still replay, queued outside the rate limit
+	startedAt   int64 // Unix milliseconds when the stream was opened; later lines are live
+	subs        map[string]*logSubscription
+}
+
+// logSubscription queues lines for one subscriber and delivers them in rate-limited batches
+type logSubscription struct {
+	id          string
+	containerID string
+	hub         *LogHub
+
+	mu         sync.Mutex
+	pending    []LogLine
+	dropped    int
+	tokens     float64
+	rate       float64
+	lastRefill time.Time
+
+	wake chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewLogHub creates a log hub reading through the Docker client and sending via the agent
+func NewLogHub(docker *DockerClient, agent *Agent) *LogHub {
+	return &LogHub{
+		readers: make(map[string]*containerLogReader),
+		subs:    make(map[string]*logSubscription),
+		stream:  docker.StreamContainerLogs,
+		send:    agent.sendMessage,
+	}
+}
+
+// Subscribe adds a subscription for a container, starting its reader if this is the first one.
+// The last tail lines already seen are delivered first. rate <= 0 uses the default rate limit.
+func (h *LogHub) Subscribe(containerID string, tail, rate int) string {
+	if rate <= 0 {
+		rate = logStreamDefaultRate
+	}
+
+	sub := &logSubscription{
+		id:          uuid.New().String(),
+		containerID: containerID,
+		hub:         h,
+		rate:        float64(rate),
+		tokens:      float64(rate),
+		lastRefill:  time.Now(),
+		wake:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	h.subs[sub.id] = sub
+	reader, exists := h.readers[containerID]
+	if exists {
+		// Late subscriber: replay recent lines outside the rate limit
+		backlog := reader.recent(tail)
+		sub.pending = append(sub.pending, backlog...)
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		reader = &containerLogReader{
+			containerID: containerID,
+			cancel:      cancel,
+			subs:        make(map[string]*logSubscription),
+			backfill:    tail,
+			startedAt:   time.Now().UnixMilli(),
+		}
+		h.readers[containerID] = reader
+		logChan, errChan := h.stream(ctx, containerID, tail)
+		go h.readLoop(reader, logChan, errChan)
+		logHubLogger.Infof("Started log reader for container %s (tail: %d)", containerID, tail)
+	}
+	reader.subs[sub.id] = sub
+	h.mu.Unlock()
+
+	go sub.flushLoop()
+	logHubLogger.Infof("Subscription %s added for container %s (%d subscriber(s))", sub.id, containerID, len(reader.subs))
+	return sub.id
+}
+
+// Unsubscribe removes a subscription; the container reader stops with its last subscription
+func (h *LogHub) Unsubscribe(subscriptionID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.removeLocked(subscriptionID)
+}
+
+// UnsubscribeContainer removes every subscription of a container and returns how many there were
+func (h *LogHub) UnsubscribeContainer(containerID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	reader, ok := h.readers[containerID]
+	if !ok {
+		return 0
+	}
+	n := 0
+	for id := range reader.subs {
+		if h.removeLocked(id) {
+			n++
+		}
+	}
+	return n
+}
+
+// UnsubscribeAll removes every subscription (e.g. when the manager connection drops)
+func (h *LogHub) UnsubscribeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id := range h.subs {
+		h.removeLocked(id)
+	}
+}
+
+// removeLocked drops a subscription and stops an unreferenced reader. Must be called with h.mu held.
+func (h *LogHub) removeLocked(subscriptionID string) bool {
+	sub, ok := h.subs[subscriptionID]
+	if !ok {
+		return false
+	}
+	delete(h.subs, subscriptionID)
+	sub.close()
+
+	if reader, ok := h.readers[sub.containerID]; ok {
+		delete(reader.subs, subscriptionID)
+		if len(reader.subs) == 0 {
+			reader.cancel()
+			delete(h.readers, sub.containerID)
+			logHubLogger.Infof("Stopped log reader for container %s (no subscribers)", sub.containerID)
+		}
+	}
+	return true
+}
+
+// readLoop distributes lines from a container's Docker stream to its subscriptions
+func (h *LogHub) readLoop(reader *containerLogReader, logChan <-chan LogLine, errChan <-chan error) {
+	for line := range logChan {
+		h.mu.Lock()
+		reader.remember(line)
+		// The stream replays the requested tail first: like a late subscriber's history,
+		// it isn't subject to the rate limit
+		backfill := reader.backfill > 0 && line.Timestamp < reader.startedAt
+		if backfill {
+			reader.backfill--
+		} else {
+			reader.backfill = 0
+		}
+		for _, sub := range reader.subs {
+			if backfill {
+				sub.queue(line)
+			} else {
+				sub.offer(line)
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	var streamErr error
+	if err, ok := <-errChan; ok {
+		streamErr = err
+	}
+
+	// Stream ended (container stopped, error or cancelled): release all subscriptions
+	h.mu.Lock()
+	if h.readers[reader.containerID] != reader {
+		h.mu.Unlock()
+		return // Cancelled by the last unsubscribe
+	}
+	subs := make([]*logSubscription, 0, len(reader.subs))
+	for id, sub := range reader.subs {
+		subs = append(subs, sub)
+		h.removeLocked(id)
+	}
+	h.mu.Unlock()
+
+	if streamErr != nil {
+		logHubLogger.Errorf("Log streaming error for %s: %v", reader.containerID, streamErr)
+	}
+	for _, sub := range subs {
+		sub.flush() // Deliver what was read before the stream ended
+		if streamErr != nil {
+			h.send(Message{
+				Subject: "log.stream.error",
+				Data: map[string]interface{}{
+					"success":        false,
+					"containerId":    reader.containerID,
+					"subscriptionId": sub.id,
+					"error":          streamErr.Error(),
+					"errorCode":      "DOCKER_LOG_FAILED",
+				},
+				Timestamp: time.Now().Unix(),
+			})
+		}
+	}
+}
+
+// remember stores a line in the reader's history ring
+func (r *containerLogReader) remember(line LogLine) {
+	if len(r.history) < logHubHistoryLines {
+		r.history = append(r.history, line)
+		return
+	}
+	r.history[r.historyPos] = line
+	r.historyPos = (r.historyPos + 1) % logHubHistoryLines
+}
+
+// recent returns up to n of the most recent lines, oldest first
+func (r *containerLogReader) recent(n int) []LogLine {
+	ordered := append(append([]LogLine{}, r.history[r.historyPos:]...), r.history[:r.historyPos]...)
+	if n >= 0 && n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	return ordered
+}
+
+// offer queues a line if the subscription's rate limit and queue allow it, otherwise counts it as dropped
+func (s *logSubscription) offer(line LogLine) {
+	s.mu.Lock()
+	now := time.Now()
+	s.tokens += now.Sub(s.lastRefill).Seconds() * s.rate
+	if s.tokens > s.rate {
+		s.tokens = s.rate // Burst of one second
+	}
+	s.lastRefill = now
+
+	if s.tokens < 1 || len(s.pending) >= logSubscriptionMaxQueue {
+		s.dropped++
+		s.mu.Unlock()
+		return
+	}
+	s.tokens--
+	s.mu.Unlock()
+	s.queue(line)
+}
+
+// queue adds a line to the pending batch without rate limiting, waking the flush loop when a batch is full
+func (s *logSubscription) queue(line LogLine) {
+	s.mu.Lock()
+	s.pending = append(s.pending, line)
+	full := len(s.pending) >= logBatchMaxLines
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flushLoop sends pending lines every logBatchInterval or when a batch fills up
+func (s *logSubscription) flushLoop() {
+	ticker := time.NewTicker(logBatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		if err := s.flush(); err != nil {
+			logHubLogger.Errorf("Failed to send log batch for %s: %v", s.id, err)
+			s.hub.Unsubscribe(s.id)
+			return
+		}
+	}
+}
+
+// flush sends pending lines in batches of at most logBatchMaxLines. A marker line
+// reports how many lines the rate limit dropped since the last flush.
+func (s *logSubscription) flush() error {
+	s.mu.Lock()
+	lines := s.pending
+	dropped := s.dropped
+	s.pending = nil
+	s.dropped = 0
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		lines = append(lines, LogLine{
+			ContainerID: s.containerID,
+			Timestamp:   time.Now().UnixMilli(),
+			Stream:      "system",
+			Message:     fmt.Sprintf("[zedops] %d log lines dropped (rate limit %d lines/s)", dropped, int(s.rate)),
+		})
+	}
+
+	for len(lines) > 0 {
+		n := len(lines)
+		if n > logBatchMaxLines {
+			n = logBatchMaxLines
+		}
+		batch := LogBatch{
+			SubscriptionID: s.id,
+			ContainerID:    s.containerID,
+			Lines:          lines[:n],
+		}
+		lines = lines[n:]
+		if len(lines) == 0 {
+			batch.Dropped = dropped
+		}
+
+		if err := s.hub.send(Message{
+			Subject:   "log.batch",
+			Data:      batch,
+			Timestamp: time.Now().Unix(),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close stops the flush loop
+func (s *logSubscription) close() {
+	s.once.Do(func() { close(s.done) })
+}
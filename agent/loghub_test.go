@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLogHub is a hub whose container streams are fed by the test and whose batches are captured
+type testLogHub struct {
+	*LogHub
+	mu      sync.Mutex
+	streams map[string]chan LogLine
+	tails   map[string]int
+	batches []LogBatch
+}
+
+func newTestLogHub() *testLogHub {
+	h := &testLogHub{streams: map[string]chan LogLine{}, tails: map[string]int{}}
+	h.LogHub = &LogHub{
+		readers: make(map[string]*containerLogReader),
+		subs:    make(map[string]*logSubscription),
+		stream: func(ctx context.Context, containerID string, tail int) (<-chan LogLine, <-chan error) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			lines := make(chan LogLine)
+			h.streams[containerID] = lines
+			h.tails[containerID] = tail
+			return lines, make(chan error)
+		},
+		send: func(msg Message) error {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if batch, ok := msg.Data.(LogBatch); ok {
+				h.batches = append(h.batches, batch)
+			}
+			return nil
+		},
+	}
+	return h
+}
+
+// feed writes lines into a container's stream, as Docker would
+func (h *testLogHub) feed(containerID string, lines []LogLine) {
+	h.mu.Lock()
+	stream := h.streams[containerID]
+	h.mu.Unlock()
+	for _, line := range lines {
+		stream <- line
+	}
+}
+
+// received waits until a subscription has been sent n lines (including dropped ones) and
+// returns its lines and the dropped count of its batches
+func (h *testLogHub) received(t *testing.T, subscriptionID string, n int) ([]LogLine, int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var lines []LogLine
+		dropped := 0
+		h.mu.Lock()
+		for _, b := range h.batches {
+			if b.SubscriptionID == subscriptionID {
+				lines = append(lines, b.Lines...)
+				dropped += b.Dropped
+			}
+		}
+		h.mu.Unlock()
+		delivered := 0
+		for _, l := range lines {
+			if l.Stream != "system" {
+				delivered++
+			}
+		}
+		if delivered+dropped >= n || time.Now().After(deadline) {
+			return lines, dropped
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func testLogLines(n int, ts int64, prefix string) []LogLine {
+	lines := make([]LogLine, n)
+	for i := range lines {
+		lines[i] = LogLine{Timestamp: ts, Stream: "stdout", Message: fmt.Sprintf("%s %d", prefix, i)}
+	}
+	return lines
+}
+
+func TestLogHubDeliversBackfillOutsideRateLimit(t *testing.T) {
+	h := newTestLogHub()
+	defer h.UnsubscribeAll()
+
+	first := h.Subscribe("c1", 1000, 0)
+	if h.tails["c1"] != 1000 {
+		t.Fatalf("stream opened with tail %d", h.tails["c1"])
+	}
+	// Docker replays the tail, written before the stream opened
+	backfill := testLogLines(1000, time.Now().Add(-time.Minute).UnixMilli(), "old")
+	h.feed("c1", backfill)
+
+	lines, dropped := h.received(t, first, 1000)
+	if len(lines) != 1000 || dropped != 0 {
+		t.Fatalf("first subscriber got %d lines, %d dropped, want the whole tail", len(lines), dropped)
+	}
+	for i, l := range lines {
+		if l.Message != backfill[i].Message {
+			t.Fatalf("line %d = %q, want %q", i, l.Message, backfill[i].Message)
+		}
+	}
+
+	// A late subscriber gets the same history from the reader
+	second := h.Subscribe("c1", 1000, 0)
+	if lines, dropped := h.received(t, second, 1000); len(lines) != 1000 || dropped != 0 {
+		t.Errorf("late subscriber got %d lines, %d dropped", len(lines), dropped)
+	}
+
+	// Live lines are rate limited again, for both
+	h.feed("c1", testLogLines(logStreamDefaultRate*2, time.Now().UnixMilli()+1, "live"))
+	for _, id := range []string{first, second} {
+		lines, dropped := h.received(t, id, 1000+logStreamDefaultRate*2)
+		if dropped == 0 || len(lines) >= 1000+logStreamDefaultRate*2 {
+			t.Errorf("%s: %d lines, %d dropped, want live lines over the rate dropped", id, len(lines), dropped)
+		}
+	}
+}
+
+func TestLogHubReportsDroppedLines(t *testing.T) {
+	h := newTestLogHub()
+	defer h.UnsubscribeAll()
+
+	const rate, sent = 10, 50
+	id := h.Subscribe("c1", 0, rate)
+	h.feed("c1", testLogLines(sent, time.Now().UnixMilli()+1, "live"))
+
+	lines, dropped := h.received(t, id, sent)
+	var delivered []LogLine
+	var markers []string
+	for _, l := range lines {
+		if l.Stream == "system" {
+			markers = append(markers, l.Message)
+		} else {
+			delivered = append(delivered, l)
+		}
+	}
+	// One second of burst, plus whatever refilled while the lines were fed
+	if len(delivered) < rate || len(delivered) > rate+2 || len(delivered)+dropped != sent {
+		t.Errorf("delivered %d, dropped %d of %d at %d lines/s", len(delivered), dropped, sent, rate)
+	}
+	// A flush during the feed splits the count over two markers
+	reported := 0
+	for _, m := range markers {
+		var n, r int
+		if _, err := fmt.Sscanf(m, "[zedops] %d log lines dropped (rate limit %d lines/s)", &n, &r); err != nil || r != rate {
+			t.Errorf("marker %q", m)
+		}
+		reported += n
+	}
+	if len(markers) == 0 || reported != dropped {
+		t.Errorf("markers %q report %d dropped lines, want %d", markers, reported, dropped)
+	}
+	for i, l := range delivered {
+		if l.Message != fmt.Sprintf("live %d", i) {
+			t.Errorf("line %d = %q: the first lines should be delivered", i, l.Message)
+		}
+	}
+}
@@ -0,0 +1,1024 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const logSinksFile = "log-sinks.json"
+
+const (
+	logSinkDefaultBuffer = 10000           // Records queued per sink before the oldest are dropped
+	logSinkDefaultBatch  = 500             // Records per write
+	logSinkDefaultFlush  = 2 * time.Second // Flush at least this often
+	logSinkRetryMin      = time.Second
+	logSinkRetryMax      = time.Minute
+	logSinkWriteTimeout  = 10 * time.Second
+
+	syslogUDPMaxMessage = 8192 // Longer datagrams are truncated
+	logFileDefaultMaxMB = 50
+	logFileDefaultFiles = 5
+)
+
+// Log record sources a sink can select
+const (
+	LogSourceAgent     = "agent"     // Agent's own logs
+	LogSourceContainer = "container" // Server container stdout/stderr
+	LogSourceGame      = "game"      // Game events parsed from PZ log files
+)
+
+// Log sink types
+const (
+	LogSinkSyslog = "syslog"
+	LogSinkLoki   = "loki"
+	LogSinkFile   = "file"
+)
+
+// LogSinkConfig configures one external log destination
+type LogSinkConfig struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`              // syslog, loki or file
+	Sources []string `json:"sources,omitempty"` // agent, container, game (empty = all)
+	Level   string   `json:"level,omitempty"`   // Minimum level of agent logs (default: all that are logged)
+	Format  string   `json:"format,omitempty"`  // Line format for loki and file: text or json
+
+	// syslog (RFC 5424)
+	Network  string `json:"network,omitempty"`  // udp (default) or tcp
+	Address  string `json:"address,omitempty"`  // host:port
+	Facility string `json:"facility,omitempty"` // user, daemon or local0-7 (default local0)
+	AppName  string `json:"appName,omitempty"`  // Default zedops-agent
+
+	// loki (push API)
+	URL         string            `json:"url,omitempty"` // e.g. http://loki:3100/loki/api/v1/push
+	Labels      map[string]string `json:"labels,omitempty"`
+	TenantID    string            `json:"tenantId,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	BearerToken string            `json:"bearerToken,omitempty"`
+
+	// file
+	Path      string `json:"path,omitempty"`
+	MaxSizeMB int64  `json:"maxSizeMb,omitempty"` // Rotate at this size (default 50)
+	MaxFiles  int    `json:"maxFiles,omitempty"`  // Rotated files kept (default 5)
+
+	// Buffering
+	BufferSize    int `json:"bufferSize,omitempty"`    // Queued records (default 10000)
+	BatchSize     int `json:"batchSize,omitempty"`     // Records per write (default 500)
+	FlushInterval int `json:"flushInterval,omitempty"` // Seconds (default 2)
+}
+
+// LogSinksConfig is the log-sinks.json file
+type LogSinksConfig struct {
+	Sinks []LogSinkConfig `json:"sinks"`
+}
+
+// LogRecord is one line forwarded to the sinks
+type LogRecord struct {
+	Timestamp   int64                  `json:"timestamp"` // Unix milliseconds
+	Source      string                 `json:"source"`
+	Level       string                 `json:"level"`
+	Subsystem   string                 `json:"subsystem,omitempty"`
+	ServerID    string                 `json:"serverId,omitempty"`
+	ServerName  string                 `json:"serverName,omitempty"`
+	ContainerID string                 `json:"containerId,omitempty"`
+	Stream      string                 `json:"stream,omitempty"`    // stdout or stderr
+	EventType   string                 `json:"eventType,omitempty"` // Game event type
+	Message     string                 `json:"message"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+}
+
+// LogSink writes batches of records to an external destination
+type LogSink interface {
+	Write(ctx context.Context, records []LogRecord) error
+	Close() error
+}
+
+// permanentSinkError marks a batch the destination rejected; retrying won't help
+type permanentSinkError struct {
+	err error
+}
+
+func (e *permanentSinkError) Error() string { return e.err.Error() }
+func (e *permanentSinkError) Unwrap() error { return e.err }
+
+// partialSinkError reports a failed write after the first written records were delivered;
+// only the rest of the batch is retried
+type partialSinkError struct {
+	written int
+	err     error
+}
+
+func (e *partialSinkError) Error() string { return e.err.Error() }
+func (e *partialSinkError) Unwrap() error { return e.err }
+
+// GetLogSinksConfigPath returns the default path of the log sinks config
+func GetLogSinksConfigPath() string {
+	return filepath.Join(StateDir(), logSinksFile)
+}
+
+// LoadLogSinksConfig loads a log sinks config file.
+// Returns nil if the file doesn't exist.
+func LoadLogSinksConfig(path string) (*LogSinksConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read log sinks config: %w", err)
+	}
+
+	var config LogSinksConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse log sinks config: %w", err)
+	}
+	for _, s := range config.Sinks {
+		RegisterSecret(s.Password)
+		RegisterSecret(s.BearerToken)
+	}
+	return &config, nil
+}
+
+// newLogSink creates the sink for a config
+func newLogSink(config LogSinkConfig, hostname string) (LogSink, error) {
+	switch config.Type {
+	case LogSinkSyslog:
+		return newSyslogSink(config, hostname)
+	case LogSinkLoki:
+		return newLokiSink(config, hostname)
+	case LogSinkFile:
+		return newFileSink(config)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q (expected syslog, loki or file)", config.Type)
+	}
+}
+
+// LogSinkManager collects agent logs, container output and game events and forwards them to the sinks.
+// Container output comes from the log archiver feed (ObserveContainerLine), so no extra Docker
+// stream is opened per container.
+type LogSinkManager struct {
+	mu      sync.Mutex
+	capture *LogCapture
+	runners []*logSinkRunner
+	sources map[string]bool // Sources selected by at least one sink
+	started int64           // Unix milliseconds; older container output predates the sinks
+	stopped bool
+}
+
+// NewLogSinkManager creates the sinks
+func NewLogSinkManager(capture *LogCapture, configs []LogSinkConfig, hostname string) (*LogSinkManager, error) {
+	m := &LogSinkManager{
+		capture: capture,
+		sources: make(map[string]bool),
+	}
+
+	for i, config := range configs {
+		if config.Name == "" {
+			config.Name = fmt.Sprintf("%s-%d", config.Type, i+1)
+		}
+		runner, err := newLogSinkRunner(config, hostname)
+		if err != nil {
+			for _, r := range m.runners {
+				r.sink.Close()
+			}
+			return nil, fmt.Errorf("sink %s: %w", config.Name, err)
+		}
+		m.runners = append(m.runners, runner)
+		for source := range runner.sources {
+			m.sources[source] = true
+		}
+	}
+	return m, nil
+}
+
+// Start begins forwarding. Agent logs are taken from the capture synchronously: its
+// subscriber channels drop lines under load, while the sink buffers are sized for bursts.
+func (m *LogSinkManager) Start() {
+	logSinksLogger.Infof("Starting %d log sink(s)", len(m.runners))
+	m.mu.Lock()
+	m.started = time.Now().UnixMilli()
+	m.mu.Unlock()
+	for _, r := range m.runners {
+		go r.run()
+	}
+
+	if m.sources[LogSourceAgent] && m.capture != nil {
+		m.capture.AddListener(m.observeAgentLog)
+	}
+}
+
+// Stop flushes what is buffered
+func (m *LogSinkManager) Stop() {
+	logSinksLogger.Infof("Stopping log sinks")
+	m.mu.Lock()
+	m.stopped = true
+	m.mu.Unlock()
+
+	for _, r := range m.runners {
+		r.stop()
+	}
+}
+
+// Ingest queues a record on every sink that selects its source
+func (m *LogSinkManager) Ingest(record LogRecord) {
+	for _, r := range m.runners {
+		if r.accepts(record) {
+			r.enqueue(record)
+		}
+	}
+}
+
+// ObserveGameEvent is the GameLogTailer event observer
+func (m *LogSinkManager) ObserveGameEvent(event GameEvent) {
+	if !m.sources[LogSourceGame] {
+		return
+	}
+
+	// Everything but what the record already carries goes into fields
+	var fields map[string]interface{}
+	if data, err := json.Marshal(event); err == nil && json.Unmarshal(data, &fields) == nil {
+		for _, k := range []string{"serverId", "serverName", "type", "timestamp", "raw"} {
+			delete(fields, k)
+		}
+		for k, v := range fields {
+			if v == "" {
+				delete(fields, k)
+			}
+		}
+	}
+
+	m.Ingest(LogRecord{
+		Timestamp:  event.Timestamp,
+		Source:     LogSourceGame,
+		Level:      "INFO",
+		ServerID:   event.ServerID,
+		ServerName: event.ServerName,
+		EventType:  event.Type,
+		Message:    event.Raw,
+		Fields:     fields,
+	})
+}
+
+// ObserveContainerLine is the log archiver observer. Output the archiver replays from
+// before the sinks started was forwarded by the previous run.
+func (m *LogSinkManager) ObserveContainerLine(serverID, serverName string, line LogLine) {
+	if !m.sources[LogSourceContainer] {
+		return
+	}
+	m.mu.Lock()
+	skip := m.stopped || line.Timestamp < m.started
+	m.mu.Unlock()
+	if skip {
+		return
+	}
+
+	m.Ingest(LogRecord{
+		Timestamp:   line.Timestamp,
+		Source:      LogSourceContainer,
+		Level:       "INFO",
+		ServerID:    serverID,
+		ServerName:  serverName,
+		ContainerID: line.ContainerID,
+		Stream:      line.Stream,
+		Message:     line.Message,
+	})
+}
+
+// observeAgentLog is the log capture listener
+func (m *LogSinkManager) observeAgentLog(line AgentLogLine) {
+	if line.Subsystem == "LogSinks" {
+		return // Sink errors would feed back into the failing sink
+	}
+	m.mu.Lock()
+	stopped := m.stopped
+	m.mu.Unlock()
+	if stopped {
+		return
+	}
+
+	m.Ingest(LogRecord{
+		Timestamp: line.Timestamp,
+		Source:    LogSourceAgent,
+		Level:     line.Level,
+		Subsystem: line.Subsystem,
+		Message:   line.Message,
+		Fields:    line.Fields,
+	})
+}
+
+// logSinkRunner buffers records for one sink and writes them in batches, retrying with backoff
+type logSinkRunner struct {
+	config   LogSinkConfig
+	sink     LogSink
+	sources  map[string]bool
+	minLevel slog.Level
+	hasLevel bool
+
+	bufferSize    int
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	queue   []LogRecord
+	dropped int
+
+	wake   chan struct{}
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// newLogSinkRunner validates a sink config and fills in defaults
+func newLogSinkRunner(config LogSinkConfig, hostname string) (*logSinkRunner, error) {
+	r := &logSinkRunner{
+		config:        config,
+		sources:       make(map[string]bool),
+		bufferSize:    config.BufferSize,
+		batchSize:     config.BatchSize,
+		flushInterval: time.Duration(config.FlushInterval) * time.Second,
+		wake:          make(chan struct{}, 1),
+		stopCh:        make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if r.bufferSize <= 0 {
+		r.bufferSize = logSinkDefaultBuffer
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = logSinkDefaultBatch
+	}
+	if r.flushInterval <= 0 {
+		r.flushInterval = logSinkDefaultFlush
+	}
+
+	sources := config.Sources
+	if len(sources) == 0 {
+		sources = []string{LogSourceAgent, LogSourceContainer, LogSourceGame}
+	}
+	for _, s := range sources {
+		switch s {
+		case LogSourceAgent, LogSourceContainer, LogSourceGame:
+			r.sources[s] = true
+		default:
+			return nil, fmt.Errorf("unknown source %q (expected agent, container or game)", s)
+		}
+	}
+
+	if config.Level != "" {
+		level, err := ParseLogLevel(config.Level)
+		if err != nil {
+			return nil, err
+		}
+		r.minLevel = level
+		r.hasLevel = true
+	}
+
+	switch config.Format {
+	case "", "text", "json":
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected text or json)", config.Format)
+	}
+
+	sink, err := newLogSink(config, hostname)
+	if err != nil {
+		return nil, err
+	}
+	r.sink = sink
+	return r, nil
+}
+
+// accepts reports whether the sink selects a record
+func (r *logSinkRunner) accepts(record LogRecord) bool {
+	if !r.sources[record.Source] {
+		return false
+	}
+	if r.hasLevel && record.Source == LogSourceAgent {
+		level, err := ParseLogLevel(record.Level)
+		if err == nil && level < r.minLevel {
+			return false
+		}
+	}
+	return true
+}
+
+// enqueue adds a record, dropping the oldest when the buffer is full
+func (r *logSinkRunner) enqueue(record LogRecord) {
+	r.mu.Lock()
+	if len(r.queue) >= r.bufferSize {
+		r.queue = r.queue[1:]
+		r.dropped++
+	}
+	r.queue = append(r.queue, record)
+	full := len(r.queue) >= r.batchSize
+	r.mu.Unlock()
+
+	if full {
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// take removes up to one batch from the queue
+func (r *logSinkRunner) take() ([]LogRecord, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.queue)
+	if n > r.batchSize {
+		n = r.batchSize
+	}
+	batch := append([]LogRecord{}, r.queue[:n]...)
+	r.queue = r.queue[n:]
+	dropped := r.dropped
+	r.dropped = 0
+	return batch, dropped
+}
+
+// run writes batches until stop. A failed batch is retried with exponential backoff
+// while new records keep buffering behind it.
+func (r *logSinkRunner) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	backoff := logSinkRetryMin
+	var batch []LogRecord
+	for {
+		if batch == nil {
+			var dropped int
+			batch, dropped = r.take()
+			if dropped > 0 {
+				logSinksLogger.Warnf("Sink %s dropped %d record(s) (buffer full)", r.config.Name, dropped)
+			}
+		}
+
+		if len(batch) == 0 {
+			batch = nil
+		} else {
+			err := r.write(batch)
+			switch {
+			case err == nil:
+				batch = nil
+				backoff = logSinkRetryMin
+				if r.pending() >= r.batchSize {
+					continue // More full batches waiting
+				}
+			case errors.As(err, new(*permanentSinkError)):
+				logSinksLogger.Errorf("Sink %s rejected %d record(s): %v", r.config.Name, len(batch), err)
+				batch = nil
+			default:
+				// Records already delivered would be duplicated by a retry
+				var partial *partialSinkError
+				if errors.As(err, &partial) {
+					batch = batch[partial.written:]
+				}
+				logSinksLogger.Warnf("Sink %s write failed, retrying in %s: %v", r.config.Name, backoff, err)
+				select {
+				case <-r.stopCh:
+					return
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+				if backoff > logSinkRetryMax {
+					backoff = logSinkRetryMax
+				}
+				continue
+			}
+		}
+
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// write sends one batch with a timeout
+func (r *logSinkRunner) write(batch []LogRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), logSinkWriteTimeout)
+	defer cancel()
+	return r.sink.Write(ctx, batch)
+}
+
+// pending returns the number of queued records
+func (r *logSinkRunner) pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.queue)
+}
+
+// stop ends the write loop, makes one last attempt to write what is queued and closes the sink
+func (r *logSinkRunner) stop() {
+	close(r.stopCh)
+	<-r.done
+
+	for {
+		batch, _ := r.take()
+		if len(batch) == 0 {
+			break
+		}
+		if err := r.write(batch); err != nil {
+			lost := len(batch) + r.pending()
+			var partial *partialSinkError
+			if errors.As(err, &partial) {
+				lost -= partial.written
+			}
+			logSinksLogger.Warnf("Sink %s: %d record(s) lost on shutdown: %v", r.config.Name, lost, err)
+			break
+		}
+	}
+	if err := r.sink.Close(); err != nil {
+		logSinksLogger.Warnf("Failed to close sink %s: %v", r.config.Name, err)
+	}
+}
+
+// formatLogRecordText renders a record as a single human-readable line
+func formatLogRecordText(record LogRecord) string {
+	var b strings.Builder
+	b.WriteString(time.UnixMilli(record.Timestamp).UTC().Format("2006-01-02T15:04:05.000Z"))
+	b.WriteByte(' ')
+	b.WriteString(record.Level)
+	b.WriteByte(' ')
+	b.WriteString(record.Source)
+	switch {
+	case record.ServerName != "":
+		b.WriteString(" [" + record.ServerName + "]")
+	case record.Subsystem != "":
+		b.WriteString(" [" + record.Subsystem + "]")
+	}
+	if record.Stream != "" {
+		b.WriteString(" " + record.Stream)
+	}
+	if record.EventType != "" {
+		b.WriteString(" " + record.EventType)
+	}
+	b.WriteString(": ")
+	b.WriteString(record.Message)
+	b.WriteString(formatLogFields(record.Fields))
+	return b.String()
+}
+
+// formatLogFields renders fields as " key=value" pairs in key order
+func formatLogFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return b.String()
+}
+
+// formatLogRecordLine renders a record in a sink's configured format
+func formatLogRecordLine(record LogRecord, format string) string {
+	if format == "json" {
+		data, err := json.Marshal(record)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return formatLogRecordText(record)
+}
+
+// syslogSink sends RFC 5424 messages over UDP (one per datagram) or TCP (octet-counted framing)
+type syslogSink struct {
+	network  string
+	address  string
+	hostname string
+	appName  string
+	facility int
+	conn     net.Conn
+}
+
+var syslogFacilities = map[string]int{
+	"user": 1, "daemon": 3,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+func newSyslogSink(config LogSinkConfig, hostname string) (*syslogSink, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("syslog sink requires an address")
+	}
+	network := config.Network
+	if network == "" {
+		network = "udp"
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unknown syslog network %q (expected udp or tcp)", network)
+	}
+	facility := "local0"
+	if config.Facility != "" {
+		facility = config.Facility
+	}
+	code, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+	appName := config.AppName
+	if appName == "" {
+		appName = "zedops-agent"
+	}
+	return &syslogSink{
+		network:  network,
+		address:  config.Address,
+		hostname: hostname,
+		appName:  appName,
+		facility: code,
+	}, nil
+}
+
+func (s *syslogSink) Write(ctx context.Context, records []LogRecord) error {
+	for i, record := range records {
+		msg := s.format(record)
+		if s.network == "udp" && len(msg) > syslogUDPMaxMessage {
+			msg = msg[:syslogUDPMaxMessage]
+		}
+		if s.network == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+
+		// One reconnect per record: the connection may have been dropped by the server since the last batch
+		var err error
+		for attempt := 0; attempt < 2; attempt++ {
+			if err = s.send(ctx, msg); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			if i > 0 {
+				return &partialSinkError{written: i, err: err}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// send writes one message, dialing if needed; the connection is discarded on error
+func (s *syslogSink) send(ctx context.Context, msg string) error {
+	if s.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", s.address, err)
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+	if _, err := io.WriteString(s.conn, msg); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to write to %s: %w", s.address, err)
+	}
+	return nil
+}
+
+// format renders an RFC 5424 message: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (s *syslogSink) format(record LogRecord) string {
+	procID := "-"
+	if record.Source == LogSourceAgent {
+		procID = strconv.Itoa(os.Getpid())
+	}
+
+	sd := []string{}
+	for _, p := range [][2]string{
+		{"subsystem", record.Subsystem},
+		{"server", record.ServerName},
+		{"serverId", record.ServerID},
+		{"stream", record.Stream},
+		{"event", record.EventType},
+	} {
+		if p[1] != "" {
+			sd = append(sd, fmt.Sprintf(`%s="%s"`, p[0], syslogEscapeParam(p[1])))
+		}
+	}
+	structured := "-"
+	if len(sd) > 0 {
+		structured = "[zedops@32473 " + strings.Join(sd, " ") + "]"
+	}
+
+	msg := record.Message + formatLogFields(record.Fields)
+
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		s.facility*8+syslogSeverity(record.Level),
+		time.UnixMilli(record.Timestamp).UTC().Format("2006-01-02T15:04:05.000Z"),
+		syslogHeaderField(s.hostname, 255),
+		syslogHeaderField(s.appName, 48),
+		procID,
+		syslogHeaderField(record.Source, 32),
+		structured,
+		msg,
+	)
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// syslogSeverity maps log levels to syslog severities
+func syslogSeverity(level string) int {
+	switch strings.ToUpper(level) {
+	case "ERROR":
+		return 3
+	case "WARN":
+		return 4
+	case "DEBUG":
+		return 7
+	default:
+		return 6 // informational
+	}
+}
+
+// syslogHeaderField makes a header field printable ASCII without spaces, or "-" when empty
+func syslogHeaderField(s string, maxLen int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > maxLen {
+		b = b[:maxLen]
+	}
+	return string(b)
+}
+
+// syslogEscapeParam escapes a structured data parameter value
+func syslogEscapeParam(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// lokiSink pushes records to a Loki-compatible /loki/api/v1/push endpoint
+type lokiSink struct {
+	url      string
+	labels   map[string]string
+	format   string
+	tenantID string
+	username string
+	password string
+	bearer   string
+	client   *http.Client
+}
+
+func newLokiSink(config LogSinkConfig, hostname string) (*lokiSink, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("loki sink requires a url")
+	}
+	labels := map[string]string{"job": "zedops-agent", "host": hostname}
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+	return &lokiSink{
+		url:      config.URL,
+		labels:   labels,
+		format:   config.Format,
+		tenantID: config.TenantID,
+		username: config.Username,
+		password: config.Password,
+		bearer:   config.BearerToken,
+		client:   &http.Client{},
+	}, nil
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (s *lokiSink) Write(ctx context.Context, records []LogRecord) error {
+	// One stream per label set; labels stay low-cardinality (no container IDs or event fields)
+	streams := make(map[string]*lokiStream)
+	var keys []string
+	for _, record := range records {
+		labels := make(map[string]string, len(s.labels)+4)
+		for k, v := range s.labels {
+			labels[k] = v
+		}
+		labels["source"] = record.Source
+		labels["level"] = strings.ToLower(record.Level)
+		if record.ServerName != "" {
+			labels["server"] = record.ServerName
+		}
+		if record.Stream != "" {
+			labels["stream"] = record.Stream
+		}
+
+		key := lokiStreamKey(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+		stream.Values = append(stream.Values, [2]string{
+			strconv.FormatInt(record.Timestamp*int64(time.Millisecond), 10),
+			formatLogRecordLine(record, s.format),
+		})
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range keys {
+		stream := streams[key]
+		sort.SliceStable(stream.Values, func(i, j int) bool {
+			a, _ := strconv.ParseInt(stream.Values[i][0], 10, 64)
+			b, _ := strconv.ParseInt(stream.Values[j][0], 10, 64)
+			return a < b
+		})
+		payload.Streams = append(payload.Streams, stream)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return &permanentSinkError{fmt.Errorf("failed to encode push request: %w", err)}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentSinkError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.tenantID)
+	}
+	if s.bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+s.bearer)
+	} else if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("push returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return &permanentSinkError{err}
+}
+
+func (s *lokiSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// lokiStreamKey identifies a label set
+func lokiStreamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + labels[k] + "\x00")
+	}
+	return b.String()
+}
+
+// fileSink appends records to a local file, rotating it by size (path.1 is the newest rotated file)
+type fileSink struct {
+	path     string
+	format   string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func newFileSink(config LogSinkConfig) (*fileSink, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("file sink requires a path")
+	}
+	maxMB := config.MaxSizeMB
+	if maxMB <= 0 {
+		maxMB = logFileDefaultMaxMB
+	}
+	maxFiles := config.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = logFileDefaultFiles
+	}
+	format := config.Format
+	if format == "" {
+		format = "json"
+	}
+	return &fileSink{
+		path:     config.Path,
+		format:   format,
+		maxBytes: maxMB * 1024 * 1024,
+		maxFiles: maxFiles,
+	}, nil
+}
+
+func (s *fileSink) Write(_ context.Context, records []LogRecord) error {
+	if err := s.open(); err != nil {
+		return err
+	}
+	for i, record := range records {
+		line := formatLogRecordLine(record, s.format) + "\n"
+		if s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+			if err := s.rotate(); err != nil {
+				return partialFileError(i, err)
+			}
+		}
+		n, err := s.file.WriteString(line)
+		s.size += int64(n)
+		if err != nil {
+			return partialFileError(i, fmt.Errorf("failed to write %s: %w", s.path, err))
+		}
+	}
+	return nil
+}
+
+// partialFileError wraps a write error after the first written records were appended
+func partialFileError(written int, err error) error {
+	if written > 0 {
+		return &partialSinkError{written: written, err: err}
+	}
+	return err
+}
+
+// open opens the active file for appending if it isn't open yet
+func (s *fileSink) open() error {
+	if s.file != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", s.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat %s: %w", s.path, err)
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shifts path.N-1 -> path.N ... path -> path.1 and reopens an empty file
+func (s *fileSink) rotate() error {
+	s.file.Close()
+	s.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxFiles))
+	for i := s.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate %s: %w", s.path, err)
+	}
+	return s.open()
+}
+
+func (s *fileSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testRecord(i int) LogRecord {
+	return LogRecord{
+		Timestamp:  time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC).UnixMilli() + int64(i),
+		Source:     LogSourceContainer,
+		Level:      "INFO",
+		ServerName: "survival",
+		Stream:     "stdout",
+		Message:    fmt.Sprintf("line %d", i),
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := newSyslogSink(LogSinkConfig{Type: LogSinkSyslog, Address: conn.LocalAddr().String()}, "host-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	records := []LogRecord{testRecord(0), testRecord(1)}
+	records[1].Level = "ERROR"
+	if err := sink.Write(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`<134>1 2026-03-01T12:00:00.000Z host-1 zedops-agent - container [zedops@32473 server="survival" stream="stdout"] line 0`,
+		`<131>1 2026-03-01T12:00:00.001Z host-1 zedops-agent - container [zedops@32473 server="survival" stream="stdout"] line 1`,
+	}
+	buf := make([]byte, syslogUDPMaxMessage)
+	for _, w := range want {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != w {
+			t.Errorf("datagram = %q, want %q", got, w)
+		}
+	}
+}
+
+// failAfterConn accepts a number of writes, then fails like a dropped connection
+type failAfterConn struct {
+	net.Conn
+	writes []string
+	limit  int
+}
+
+func (c *failAfterConn) Write(b []byte) (int, error) {
+	if len(c.writes) >= c.limit {
+		return 0, errors.New("connection reset")
+	}
+	c.writes = append(c.writes, string(b))
+	return len(b), nil
+}
+
+func (c *failAfterConn) SetWriteDeadline(time.Time) error { return nil }
+func (c *failAfterConn) Close() error                     { return nil }
+
+func TestSyslogSinkReportsPartialWrite(t *testing.T) {
+	// Reconnects go to a closed port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	sink, err := newSyslogSink(LogSinkConfig{Type: LogSinkSyslog, Network: "tcp", Address: address}, "host-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := &failAfterConn{limit: 2}
+	sink.conn = conn
+
+	err = sink.Write(context.Background(), []LogRecord{testRecord(0), testRecord(1), testRecord(2), testRecord(3)})
+	var partial *partialSinkError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Write error = %v, want a partial write", err)
+	}
+	if partial.written != 2 || len(conn.writes) != 2 {
+		t.Fatalf("written = %d (%d sent), want 2", partial.written, len(conn.writes))
+	}
+	// Octet-counted framing: "<len> <msg>"
+	frame := strings.SplitN(conn.writes[0], " ", 2)
+	if len(frame) != 2 || fmt.Sprint(len(frame[1])) != frame[0] {
+		t.Errorf("bad TCP framing: %q", conn.writes[0])
+	}
+}
+
+// recordingSink delivers the first records of its first batch, then fails
+type recordingSink struct {
+	mu        sync.Mutex
+	delivered []LogRecord
+	failAfter int
+	failed    bool
+}
+
+func (s *recordingSink) Write(ctx context.Context, records []LogRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.failed {
+		s.failed = true
+		s.delivered = append(s.delivered, records[:s.failAfter]...)
+		return &partialSinkError{written: s.failAfter, err: errors.New("connection reset")}
+	}
+	s.delivered = append(s.delivered, records...)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func (s *recordingSink) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, r := range s.delivered {
+		out = append(out, r.Message)
+	}
+	return out
+}
+
+func TestLogSinkRunnerRetriesOnlyUndeliveredRecords(t *testing.T) {
+	sink := &recordingSink{failAfter: 2}
+	r := &logSinkRunner{
+		config:        LogSinkConfig{Name: "test"},
+		sink:          sink,
+		sources:       map[string]bool{LogSourceContainer: true},
+		bufferSize:    100,
+		batchSize:     5,
+		flushInterval: time.Hour,
+		wake:          make(chan struct{}, 1),
+		stopCh:        make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for i := 0; i < 5; i++ {
+		r.enqueue(testRecord(i))
+	}
+	go r.run()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sink.messages()) < 5 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	r.stop()
+
+	got := sink.messages()
+	want := []string{"line 0", "line 1", "line 2", "line 3", "line 4"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+}
+
+func TestLokiSinkPush(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+		bodies   [][]byte
+		status   = http.StatusNoContent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		requests = append(requests, req)
+		bodies = append(bodies, body)
+		code := status
+		mu.Unlock()
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	sink, err := newLokiSink(LogSinkConfig{
+		Type:        LogSinkLoki,
+		URL:         server.URL + "/loki/api/v1/push",
+		TenantID:    "tenant-1",
+		BearerToken: "push-token",
+		Labels:      map[string]string{"env": "test"},
+	}, "host-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	agentRecord := LogRecord{Timestamp: testRecord(0).Timestamp, Source: LogSourceAgent, Level: "WARN", Subsystem: "Backup", Message: "slow"}
+	records := []LogRecord{testRecord(1), agentRecord, testRecord(0)}
+	if err := sink.Write(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("%d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if req.Header.Get("X-Scope-OrgID") != "tenant-1" || req.Header.Get("Authorization") != "Bearer push-token" {
+		t.Errorf("headers = %v", req.Header)
+	}
+
+	var payload struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Streams) != 2 {
+		t.Fatalf("%d streams, want 2: %s", len(payload.Streams), bodies[0])
+	}
+	container := payload.Streams[0]
+	wantLabels := map[string]string{"job": "zedops-agent", "host": "host-1", "env": "test", "source": "container", "level": "info", "server": "survival", "stream": "stdout"}
+	if fmt.Sprint(container.Stream) != fmt.Sprint(wantLabels) {
+		t.Errorf("labels = %v, want %v", container.Stream, wantLabels)
+	}
+	// Values are sorted by timestamp within a stream, in nanoseconds
+	if len(container.Values) != 2 || container.Values[0][0] != fmt.Sprint(testRecord(0).Timestamp*1e6) {
+		t.Errorf("values = %v", container.Values)
+	}
+	if !strings.HasSuffix(container.Values[0][1], "container [survival] stdout: line 0") {
+		t.Errorf("line = %q", container.Values[0][1])
+	}
+
+	mu.Lock()
+	status = http.StatusBadRequest
+	mu.Unlock()
+	err = sink.Write(context.Background(), records)
+	if !errors.As(err, new(*permanentSinkError)) {
+		t.Errorf("400: error = %v, want permanent", err)
+	}
+
+	mu.Lock()
+	status = http.StatusServiceUnavailable
+	mu.Unlock()
+	err = sink.Write(context.Background(), records)
+	if err == nil || errors.As(err, new(*permanentSinkError)) {
+		t.Errorf("503: error = %v, want retryable", err)
+	}
+}
+
+func TestLogSinkManagerForwardsEveryAgentLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	capture := NewLogCapture(10)
+	m, err := NewLogSinkManager(capture, []LogSinkConfig{{
+		Type:    LogSinkFile,
+		Path:    path,
+		Sources: []string{LogSourceAgent, LogSourceContainer},
+	}}, "host-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Start()
+
+	// Far more than the capture's subscriber channel holds
+	const lines = 2000
+	now := time.Now().UnixMilli()
+	for i := 0; i < lines; i++ {
+		capture.Append(AgentLogLine{Timestamp: now, Level: "INFO", Subsystem: "Test", Message: fmt.Sprintf("agent %d", i)})
+	}
+	capture.Append(AgentLogLine{Timestamp: now, Level: "WARN", Subsystem: "LogSinks", Message: "feedback"})
+
+	// Container output replayed by the archiver from before the sinks started is skipped
+	m.ObserveContainerLine("srv-1", "survival", LogLine{ContainerID: "c1", Timestamp: now - 60000, Stream: "stdout", Message: "old"})
+	m.ObserveContainerLine("srv-1", "survival", LogLine{ContainerID: "c1", Timestamp: now + 1, Stream: "stdout", Message: "new"})
+	m.Stop()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var agent, container []LogRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record LogRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		switch record.Source {
+		case LogSourceAgent:
+			agent = append(agent, record)
+		case LogSourceContainer:
+			container = append(container, record)
+		}
+	}
+	if len(agent) != lines {
+		t.Errorf("forwarded %d agent lines, want %d", len(agent), lines)
+	}
+	if len(container) != 1 || container[0].Message != "new" || container[0].ServerID != "srv-1" || container[0].ContainerID != "c1" {
+		t.Errorf("container records = %+v, want only the new line", container)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
 	"strings"
@@ -15,6 +14,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Version is set at build time via ldflags
@@ -26,6 +26,29 @@ var (
 	agentName   = flag.String("name", "", "Agent name (default: hostname)")
 	showVersion = flag.Bool("version", false, "Print version and exit")
 	noUpdate    = flag.Bool("no-update", false, "Skip auto-update check on startup (for development)")
+
+	logArchiveEnabled = flag.Bool("log-archive", true, "Archive managed container output to the state directory")
+	logArchiveMaxAge  = flag.Duration("log-archive-max-age", 7*24*time.Hour, "Delete archived container logs older than this (0 = keep forever)")
+	logArchiveMaxMB   = flag.Int64("log-archive-max-mb", 512, "Per-server size cap for archived container logs in MB (0 = unlimited)")
+
+	logFormat = flag.String("log-format", "text", "Log output format: text or json (for journald)")
+	logLevel  = flag.String("log-level", "info", "Default log level: debug, info, warn or error")
+	logSinks  = flag.String("log-sinks", "", "Log sinks config file (default: log-sinks.json in the state directory)")
+	gameLogTZ = flag.String("game-log-timezone", "UTC", "Zone PZ log timestamps are written in, for server containers without a TZ variable")
+	notifiers = flag.String("notifiers", "", "Notification channels and routes config file (default: notifiers.json in the state directory)")
+
+	metricsListen = flag.String("metrics-listen", "", "Serve Prometheus metrics on this address, e.g. 127.0.0.1:9273 (empty = disabled)")
+	metricsToken  = flag.String("metrics-token", "", "Bearer token required to scrape /metrics (empty = no auth)")
+
+	cgroupRoot          = flag.String("cgroup-root", "/sys/fs/cgroup", "cgroup mount to sample container stats from (empty = always use the Docker stats API)")
+	procRoot            = flag.String("proc-root", "/proc", "proc mount to sample host metrics from (e.g. /host/proc when the agent runs in a container; host mounts, network and disks are then read through its PID 1)")
+	metricsHistory      = flag.Bool("metrics-history", true, "Keep server metrics history in the state directory for metrics.query and backfill")
+	metricsHistoryMaxMB = flag.Int64("metrics-history-max-mb", 256, "Size cap for metrics history in MB (0 = unlimited)")
+
+	otelEndpoint = flag.String("otel-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP/HTTP endpoint for traces and metrics, e.g. http://otel-collector:4318 (empty = disabled)")
+	otelHeaders  = flag.String("otel-headers", os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), "Extra OTLP request headers as key=value,key2=value2")
+	otelService  = flag.String("otel-service-name", "zedops-agent", "service.name reported with traces and metrics")
+	otelInterval = flag.Duration("otel-metric-interval", 30*time.Second, "How often OTel metrics are exported")
 )
 
 // volumeSizeCache holds cached volume sizes with expiry
@@ -48,10 +71,20 @@ type Agent struct {
 	conn             *websocket.Conn
 	connMutex        sync.Mutex                    // Protects WebSocket writes
 	docker           *DockerClient
-	logStreams       map[string]context.CancelFunc // containerID -> cancel function
+	logHub           *LogHub                       // Container log fan-out to subscriptions
 	rconManager      *RCONManager                  // RCON session manager
 	playerStats      *PlayerStatsCollector         // Player stats collector
 	metricsCollector *MetricsCollector             // Metrics collector for sparklines
+	metricsStore     *MetricsStore                 // Metrics history for metrics.query
+	hostCollector    *HostCollector                // Host metrics for heartbeats and exporters
+	gameLogs         *GameLogTailer                // PZ log file tailer for game events
+	idle             *IdleManager                  // Idle auto-stop and wake-on-connect
+	backupScheduler  *BackupScheduler              // Cron backups that run without the manager
+	backupUploader   *BackupUploader               // Copies backups to off-host targets
+	logArchive       *LogArchiver                  // Persistent container log archive
+	logAlerts        *LogAlertEngine               // Regex alert rules over server output
+	metricAlerts     *MetricAlertEngine            // Threshold alert rules over host and server metrics
+	logSinks         *LogSinkManager               // Forwarding to syslog, Loki and log files
 	logCapture       *LogCapture                   // Agent log capture for streaming
 	agentLogChan     chan AgentLogLine             // Channel for agent log subscription
 	agentLogMutex    sync.Mutex                    // Protects agent log subscription
@@ -63,6 +96,8 @@ type Agent struct {
 	authMutex        sync.RWMutex                  // Protects isAuthenticated, wasAuthenticated, lastDisconnect
 	updater          *AutoUpdater                  // Auto-updater for push notifications
 	alertConfig      *AlertConfig                  // Cached alert config (Resend API key + recipients)
+	notifier         *NotifyRouter                 // Alert delivery to email, chat and webhooks
+	alerts           *AlertManager                 // Alert dedup, silences and escalation
 }
 
 func main() {
@@ -77,12 +112,19 @@ func main() {
 	// Initialize log capture early (before any logging)
 	// This captures all log output for streaming to manager
 	logCapture := NewLogCapture(1000) // Keep last 1000 log lines
-	log.SetOutput(logCapture)
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+	level, err := ParseLogLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := SetupLogging(LoggingConfig{Format: *logFormat, Level: level}, logCapture); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	// Validate required flags
 	if *managerURL == "" {
-		log.Fatal("Error: --manager-url is required")
+		agentLogger.Fatalf("--manager-url is required")
 	}
 
 	// Get agent name (default to hostname)
@@ -90,7 +132,7 @@ func main() {
 	if name == "" {
 		hostname, err := os.Hostname()
 		if err != nil {
-			log.Fatal("Failed to get hostname:", err)
+			agentLogger.Fatalf("Failed to get hostname: %v", err)
 		}
 		name = hostname
 	}
@@ -101,37 +143,39 @@ func main() {
 	// Load tokens: permanent first, then ephemeral file, then --token CLI flag
 	permanentToken, err := LoadToken()
 	if err != nil {
-		log.Fatal("Failed to load token:", err)
+		agentLogger.Fatalf("Failed to load token: %v", err)
 	}
 
 	ephemeralToken := *token // from --token CLI flag
+	RegisterSecret(ephemeralToken)
 	if permanentToken == "" && ephemeralToken == "" {
 		// No permanent token and no CLI flag — check for ephemeral token file
 		fileToken, err := LoadEphemeralToken()
 		if err != nil {
-			log.Fatal("Failed to load ephemeral token:", err)
+			agentLogger.Fatalf("Failed to load ephemeral token: %v", err)
 		}
 		ephemeralToken = fileToken
 	}
 
 	if permanentToken == "" && ephemeralToken == "" {
-		log.Fatal("Error: No token found. Generate a token in the manager UI and run the install script.")
+		agentLogger.Fatalf("No token found. Generate a token in the manager UI and run the install script.")
 	}
 
 	// Initialize Docker client
 	dockerClient, err := NewDockerClient()
 	if err != nil {
-		log.Printf("Warning: Failed to initialize Docker client: %v", err)
-		log.Println("Container control features will be unavailable")
+		agentLogger.Warnf("Failed to initialize Docker client: %v", err)
+		agentLogger.Infof("Container control features will be unavailable")
 	} else {
 		defer dockerClient.Close()
-		log.Println("Docker client initialized successfully")
+		agentLogger.Infof("Docker client initialized successfully")
+		dockerClient.UseCgroupStats(*cgroupRoot, *procRoot)
 
 		// Ensure required Docker networks exist (for server containers)
 		ctx := context.Background()
 		if err := dockerClient.EnsureNetworks(ctx); err != nil {
-			log.Printf("Warning: Failed to ensure Docker networks: %v", err)
-			log.Println("Server creation may fail if networks are missing")
+			agentLogger.Warnf("Failed to ensure Docker networks: %v", err)
+			agentLogger.Warnf("Server creation may fail if networks are missing")
 		}
 	}
 
@@ -145,30 +189,188 @@ func main() {
 		ephemeralToken: ephemeralToken,
 		permanentToken: permanentToken,
 		docker:         dockerClient,
-		logStreams:     make(map[string]context.CancelFunc),
 		rconManager:    rconManager,
 		logCapture:     logCapture,
 		volumeCache:    make(map[string]*volumeSizeCache),
+		hostCollector:  NewHostCollector(*procRoot, dockerClient),
 	}
 
 	// Load cached alert config from disk (available before auth, for offline alerting)
 	if cachedConfig, err := LoadAlertConfig(); err != nil {
-		log.Printf("Warning: failed to load alert config: %v", err)
+		agentLogger.Warnf("Failed to load alert config: %v", err)
 	} else if cachedConfig != nil {
 		agent.alertConfig = cachedConfig
-		log.Printf("Loaded cached alert config (%d recipient(s))", len(cachedConfig.AlertRecipients))
+		agentLogger.Infof("Loaded cached alert config (%d recipient(s))", len(cachedConfig.AlertRecipients))
+	}
+
+	// Initialize notification channels (the manager's Resend config is always available as "email")
+	notifyPath := *notifiers
+	if notifyPath == "" {
+		notifyPath = GetNotifyConfigPath()
+	}
+	notifyConfig, err := LoadNotifyConfig(notifyPath)
+	if err != nil {
+		agentLogger.Warnf("Failed to load notifiers config: %v", err)
+	}
+	agent.notifier, err = NewNotifyRouter(notifyConfig, agent.alertEmailNotifier)
+	if err != nil {
+		agentLogger.Warnf("Notifiers config ignored: %v", err)
+		agent.notifier, _ = NewNotifyRouter(nil, agent.alertEmailNotifier)
+	} else if len(agent.notifier.Channels()) > 0 {
+		agentLogger.Infof("Notification channels: %s", strings.Join(agent.notifier.Channels(), ", "))
+	}
+	if templates, err := LoadNotifyTemplates(); err != nil {
+		agentLogger.Warnf("%v", err)
+	} else if templates != nil {
+		if err := notifyTemplates.SetOverrides(templates); err != nil {
+			agentLogger.Warnf("Ignoring cached notification templates: %v", err)
+		}
+	}
+	if policies, err := LoadBackupRetention(); err != nil {
+		agentLogger.Warnf("%v", err)
+	} else if policies != nil {
+		backupRetention.SetPolicies(policies)
+	}
+	notifyTemplates.SetMetricsSource(func() *HostMetrics {
+		metrics, _ := agent.hostCollector.Latest()
+		return metrics
+	})
+	agent.alerts = NewAlertManager(notifyConfig, agent.notifier)
+	agent.alerts.Start()
+	defer agent.alerts.Stop()
+
+	// Initialize external log sinks (syslog, Loki, files) if configured
+	sinksPath := *logSinks
+	if sinksPath == "" {
+		sinksPath = GetLogSinksConfigPath()
+	}
+	if sinksConfig, err := LoadLogSinksConfig(sinksPath); err != nil {
+		agentLogger.Warnf("Failed to load log sinks config: %v", err)
+	} else if sinksConfig != nil && len(sinksConfig.Sinks) > 0 {
+		sinks, err := NewLogSinkManager(logCapture, sinksConfig.Sinks, name)
+		if err != nil {
+			agentLogger.Warnf("Log sinks disabled: %v", err)
+		} else {
+			agent.logSinks = sinks
+			agent.logSinks.Start()
+			defer agent.logSinks.Stop()
+		}
 	}
 
-	// Initialize player stats collector (requires Docker client and agent for messaging)
+	// Initialize Docker-backed collectors (require Docker client and agent for messaging)
 	if dockerClient != nil {
+		// Initialize container log hub (one Docker reader per container, shared by subscriptions)
+		agent.logHub = NewLogHub(dockerClient, agent)
+
+		// Initialize idle manager before player stats, which feeds it player counts
+		agent.idle = NewIdleManager(dockerClient, agent)
+		agent.idle.Start()
+		defer agent.idle.Stop()
+
+		// Initialize player stats collector
 		agent.playerStats = NewPlayerStatsCollector(dockerClient, agent)
 		agent.playerStats.Start()
 		defer agent.playerStats.Stop()
 
+		// Initialize backup uploader before the scheduler, which hands it new backups
+		agent.backupUploader = NewBackupUploader(agent)
+		agent.backupUploader.Start()
+		defer agent.backupUploader.Stop()
+
+		// Initialize backup scheduler after player stats, which it asks for activity since the last backup
+		agent.backupScheduler = NewBackupScheduler(agent)
+		agent.backupScheduler.Start()
+		defer agent.backupScheduler.Stop()
+
+		// Initialize metrics history (raw points for a day, downsamples for a month)
+		if *metricsHistory {
+			agent.metricsStore = NewMetricsStore(MetricsStoreConfig{
+				MaxBytes: *metricsHistoryMaxMB * 1024 * 1024,
+			})
+			agent.metricsStore.Start()
+			defer agent.metricsStore.Stop()
+		}
+
 		// Initialize metrics collector for sparklines (10s interval)
 		agent.metricsCollector = NewMetricsCollector(dockerClient, agent)
 		agent.metricsCollector.Start()
 		defer agent.metricsCollector.Stop()
+
+		// Initialize container log archiver (keeps output across rebuilds for logs.query). Its
+		// reader is also the container output feed for log alerts and sinks, so it runs even
+		// when archiving is disabled.
+		logArchive := NewLogArchiver(dockerClient, LogArchiveConfig{
+			MaxAge:   *logArchiveMaxAge,
+			MaxBytes: *logArchiveMaxMB * 1024 * 1024,
+			FeedOnly: !*logArchiveEnabled,
+		})
+		if *logArchiveEnabled {
+			agent.logArchive = logArchive
+		}
+
+		// Initialize log alert engine (regex rules over container output and PZ log files)
+		agent.logAlerts = NewLogAlertEngine(agent)
+		agent.logAlerts.Start()
+		logArchive.AddObserver(agent.logAlerts.ObserveContainerLine)
+		if agent.logSinks != nil {
+			logArchive.AddObserver(agent.logSinks.ObserveContainerLine)
+		}
+
+		// Initialize game log tailer (parses PZ Logs/*.txt into game.event messages)
+		agent.gameLogs = NewGameLogTailer(dockerClient, agent)
+		if location, err := time.LoadLocation(*gameLogTZ); err == nil {
+			agent.gameLogs.SetLocation(location)
+		} else {
+			gameLogsLogger.Warnf("Invalid -game-log-timezone %q, using UTC: %v", *gameLogTZ, err)
+		}
+		agent.gameLogs.SetLineObserver(agent.logAlerts.ObservePZLogLines)
+		if agent.logSinks != nil {
+			agent.gameLogs.SetEventObserver(agent.logSinks.ObserveGameEvent)
+		}
+		agent.gameLogs.Start()
+		defer agent.gameLogs.Stop()
+
+		logArchive.Start()
+		defer logArchive.Stop()
+	}
+
+	// Initialize metric alert engine (host rules work without Docker)
+	agent.metricAlerts = NewMetricAlertEngine(agent)
+	agent.metricAlerts.Start()
+	defer agent.metricAlerts.Stop()
+
+	// Export OpenTelemetry traces and metrics if enabled
+	if *otelEndpoint != "" {
+		headers, err := ParseOTLPHeaders(*otelHeaders)
+		if err != nil {
+			agentLogger.Fatalf("Invalid --otel-headers: %v", err)
+		}
+		shutdown, err := SetupTelemetry(context.Background(), TelemetryConfig{
+			Endpoint:       *otelEndpoint,
+			Headers:        headers,
+			ServiceName:    *otelService,
+			AgentName:      name,
+			MetricInterval: *otelInterval,
+		}, agent)
+		if err != nil {
+			agentLogger.Fatalf("Failed to set up telemetry: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				telemetryLogger.Warnf("Failed to flush telemetry: %v", err)
+			}
+		}()
+	}
+
+	// Serve Prometheus metrics if enabled
+	if *metricsListen != "" {
+		exporter := NewPrometheusExporter(agent, *metricsListen, *metricsToken)
+		if err := exporter.Start(); err != nil {
+			agentLogger.Fatalf("Failed to start metrics listener: %v", err)
+		}
+		defer exporter.Stop()
 	}
 
 	// Set up graceful shutdown
@@ -180,31 +382,31 @@ func main() {
 
 	go func() {
 		<-sigCh
-		log.Println("Received shutdown signal, closing connection...")
+		agentLogger.Infof("Received shutdown signal, closing connection...")
 		cancel()
 	}()
 
 	// Run with automatic reconnection
-	log.Printf("Starting agent: %s", agent.agentName)
-	log.Printf("Manager URL: %s", agent.managerURL)
-	log.Printf("Agent version: %s", Version)
+	agentLogger.Infof("Starting agent: %s", agent.agentName)
+	agentLogger.Infof("Manager URL: %s", agent.managerURL)
+	agentLogger.Infof("Agent version: %s", Version)
 
 	// Create auto-updater and assign to agent for push notification handling
 	if *noUpdate {
-		log.Println("Auto-update disabled via --no-update flag")
+		agentLogger.Infof("Auto-update disabled via --no-update flag")
 	} else {
 		updater := NewAutoUpdater(*managerURL)
 		updater.onBeforeRestart = func() {
 			if !agent.IsAuthenticated() {
-				log.Println("Not connected to manager — skipping update restart notification")
+				agentLogger.Warnf("Not connected to manager — skipping update restart notification")
 				return
 			}
-			log.Println("Notifying manager of imminent update restart...")
+			agentLogger.Infof("Notifying manager of imminent update restart...")
 			if err := agent.sendMessage(Message{
 				Subject: "agent.update.starting",
 				Data:    map[string]interface{}{"version": Version},
 			}); err != nil {
-				log.Printf("Failed to notify manager of update restart: %v", err)
+				agentLogger.Errorf("Failed to notify manager of update restart: %v", err)
 			}
 			// Brief pause to let the message reach the manager before process replacement
 			time.Sleep(100 * time.Millisecond)
@@ -217,13 +419,13 @@ func main() {
 		// Check if this is an authentication failure - exit with special code
 		// so systemd knows not to restart
 		if errors.Is(err, ErrAuthFailure) {
-			log.Printf("Exiting with code %d (auth failure - systemd will not restart)", ExitCodeAuthFailure)
+			agentLogger.Errorf("Exiting with code %d (auth failure - systemd will not restart)", ExitCodeAuthFailure)
 			os.Exit(ExitCodeAuthFailure)
 		}
-		log.Fatal("Agent error:", err)
+		agentLogger.Fatalf("Agent error: %v", err)
 	}
 
-	log.Println("Agent stopped")
+	agentLogger.Infof("Agent stopped")
 }
 
 func (a *Agent) register() error {
@@ -232,7 +434,7 @@ func (a *Agent) register() error {
 		return a.authenticate()
 	}
 
-	log.Println("Registering with ephemeral token...")
+	agentLogger.Infof("Registering with ephemeral token...")
 
 	// Send registration message
 	regMsg := NewMessage("agent.register", RegisterRequest{
@@ -298,8 +500,8 @@ func (a *Agent) register() error {
 		}
 		DeleteEphemeralToken()
 
-		log.Printf("Registration successful! Agent ID: %s", a.agentID)
-		log.Printf("Permanent token saved to %s", GetTokenPath())
+		agentLogger.Infof("Registration successful! Agent ID: %s", a.agentID)
+		agentLogger.Infof("Permanent token saved to %s", GetTokenPath())
 		return nil
 
 	case <-timeout:
@@ -309,7 +511,7 @@ func (a *Agent) register() error {
 }
 
 func (a *Agent) authenticate() error {
-	log.Println("Authenticating with permanent token...")
+	agentLogger.Infof("Authenticating with permanent token...")
 
 	// Send authentication message
 	authMsg := NewMessage("agent.auth", map[string]string{
@@ -361,12 +563,14 @@ func (a *Agent) authenticate() error {
 
 		// Parse authentication response
 		var resp struct {
-			AgentID         string           `json:"agentId"`
-			AgentName       string           `json:"agentName"`
-			Message         string           `json:"message"`
-			AlertRecipients []AlertRecipient `json:"alertRecipients"`
-			ResendApiKey    string           `json:"resendApiKey"`
-			ResendFromEmail string           `json:"resendFromEmail"`
+			AgentID         string            `json:"agentId"`
+			AgentName       string            `json:"agentName"`
+			Message         string            `json:"message"`
+			AlertRecipients []AlertRecipient  `json:"alertRecipients"`
+			ResendApiKey    string            `json:"resendApiKey"`
+			ResendFromEmail string            `json:"resendFromEmail"`
+			Silences        *[]AlertSilence   `json:"silences"`
+			Templates       *[]NotifyTemplate `json:"notificationTemplates"`
 		}
 		data, _ := json.Marshal(result.msg.Data)
 		if err := json.Unmarshal(data, &resp); err != nil {
@@ -374,7 +578,7 @@ func (a *Agent) authenticate() error {
 		}
 
 		a.agentID = resp.AgentID
-		log.Printf("Authentication successful! Agent ID: %s", a.agentID)
+		agentLogger.Infof("Authentication successful! Agent ID: %s", a.agentID)
 
 		// Cache alert config to disk (available even when manager is unreachable)
 		if resp.ResendApiKey != "" && len(resp.AlertRecipients) > 0 {
@@ -384,9 +588,21 @@ func (a *Agent) authenticate() error {
 				AlertRecipients: resp.AlertRecipients,
 			}
 			if err := SaveAlertConfig(a.alertConfig); err != nil {
-				log.Printf("Warning: failed to save alert config: %v", err)
+				agentLogger.Warnf("Failed to save alert config: %v", err)
 			} else {
-				log.Printf("Alert config cached (%d recipient(s))", len(resp.AlertRecipients))
+				agentLogger.Infof("Alert config cached (%d recipient(s))", len(resp.AlertRecipients))
+			}
+		}
+
+		// Silences are cached too, so they still apply while the manager is unreachable
+		if resp.Silences != nil {
+			if err := a.alerts.SetSilences(*resp.Silences); err != nil {
+				agentLogger.Warnf("Ignoring silences from manager: %v", err)
+			}
+		}
+		if resp.Templates != nil {
+			if err := a.applyNotifyTemplates(*resp.Templates); err != nil {
+				agentLogger.Warnf("Ignoring notification templates from manager: %v", err)
 			}
 		}
 
@@ -399,9 +615,12 @@ func (a *Agent) authenticate() error {
 }
 
 func (a *Agent) sendMessage(msg Message) error {
+	traceOutgoing(&msg)
 	a.connMutex.Lock()
-	defer a.connMutex.Unlock()
-	return a.conn.WriteJSON(msg)
+	err := a.conn.WriteJSON(msg)
+	a.connMutex.Unlock()
+	agentStats.MessageSent(msg.Subject, err)
+	return err
 }
 
 // IsAuthenticated returns true if the agent is currently authenticated
@@ -436,12 +655,15 @@ func (a *Agent) receiveMessages() {
 		err := a.conn.ReadJSON(&msg)
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
-				log.Println("WebSocket error:", err)
+				agentLogger.Errorf("WebSocket error: %v", err)
 			}
 			return
 		}
 
-		log.Printf("Received: %s - %v", msg.Subject, msg.Data)
+		agentStats.MessageReceived(msg.Subject)
+		RegisterMessageSecrets(msg.Subject, msg.Data)
+		agentLogger.Debugf("Received: %s - %v", msg.Subject, RedactMessageData(msg.Subject, msg.Data))
+		span := startMessageSpan(&msg)
 
 		// Handle different message types
 		switch msg.Subject {
@@ -449,10 +671,8 @@ func (a *Agent) receiveMessages() {
 			a.handleUpdateAvailable(msg)
 		case "agent.register.success":
 			// Already handled in register()
-			continue
 		case "agent.heartbeat.ack":
 			// Heartbeat acknowledged (silent)
-			continue
 		case "container.list":
 			a.handleContainerList(msg)
 		case "container.start":
@@ -504,9 +724,69 @@ func (a *Agent) receiveMessages() {
 		case "backup.list":
 			a.handleBackupList(msg)
 		case "backup.delete":
-			a.handleBackupDelete(msg)
+			go a.handleBackupDelete(msg)
 		case "backup.restore":
 			go a.handleBackupRestore(msg)
+		case "backup.retention.set":
+			a.handleBackupRetentionSet(msg)
+		case "backup.retention.get":
+			a.handleBackupRetentionGet(msg)
+		case "backup.retention.preview":
+			a.handleBackupRetentionPreview(msg)
+		case "backup.pin":
+			a.handleBackupPin(msg)
+		case "backup.verify":
+			go a.handleBackupVerify(msg)
+		case "backup.extract":
+			go a.handleBackupExtract(msg)
+		case "backup.export":
+			go a.handleBackupExport(msg)
+		case "backup.schedule.set":
+			a.handleBackupScheduleSet(msg)
+		case "backup.schedule.get":
+			a.handleBackupScheduleGet(msg)
+		case "backup.target.set":
+			go a.handleBackupTargetSet(msg)
+		case "backup.target.get":
+			a.handleBackupTargetGet(msg)
+		case "backup.target.sync":
+			go a.handleBackupTargetSync(msg)
+		case "backup.remote.list":
+			go a.handleBackupRemoteList(msg)
+		case "backup.remote.fetch":
+			go a.handleBackupRemoteFetch(msg)
+		case "server.idle.set":
+			a.handleServerIdleSet(msg)
+		case "server.idle.get":
+			a.handleServerIdleGet(msg)
+		case "alert.rules.set":
+			a.handleAlertRulesSet(msg)
+		case "alert.rules.get":
+			a.handleAlertRulesGet(msg)
+		case "alert.metric.rules.set":
+			a.handleMetricAlertRulesSet(msg)
+		case "alert.metric.rules.get":
+			a.handleMetricAlertRulesGet(msg)
+		case "notify.test":
+			go a.handleNotifyTest(msg)
+		case "alert.silences.set":
+			a.handleAlertSilencesSet(msg)
+		case "alert.silences.get":
+			a.handleAlertSilencesGet(msg)
+		case "notify.templates.set":
+			a.handleNotifyTemplatesSet(msg)
+		case "notify.templates.get":
+			a.handleNotifyTemplatesGet(msg)
+		case "logs.fetch":
+			go a.handleLogsFetch(msg)
+		case "logs.query":
+			go a.handleLogsQuery(msg)
+		case "metrics.query":
+			go a.handleMetricsQuery(msg)
+		case "players.history":
+			a.handlePlayersHistory(msg)
+		case "agent.loglevel":
+			a.handleAgentLogLevel(msg)
 		case "agent.logs.subscribe":
 			a.handleAgentLogsSubscribe(msg)
 		case "agent.logs.unsubscribe":
@@ -515,10 +795,11 @@ func (a *Agent) receiveMessages() {
 			var errResp ErrorResponse
 			data, _ := json.Marshal(msg.Data)
 			json.Unmarshal(data, &errResp)
-			log.Printf("Error from manager: %s", errResp.Message)
+			agentLogger.Errorf("Error from manager: %s", errResp.Message)
 		default:
-			log.Printf("Unknown message subject: %s", msg.Subject)
+			agentLogger.Warnf("Unknown message subject: %s", msg.Subject)
 		}
+		finishMessageSpan(msg, span)
 	}
 }
 
@@ -527,13 +808,13 @@ func (a *Agent) handleUpdateAvailable(msg Message) {
 	// Extract version from message data
 	data, ok := msg.Data.(map[string]interface{})
 	if !ok {
-		log.Println("Invalid update notification format")
+		agentLogger.Errorf("Invalid update notification format")
 		return
 	}
 
 	version, ok := data["version"].(string)
 	if !ok {
-		log.Println("Update notification missing version")
+		agentLogger.Errorf("Update notification missing version")
 		return
 	}
 
@@ -541,13 +822,13 @@ func (a *Agent) handleUpdateAvailable(msg Message) {
 	if a.updater != nil {
 		a.updater.TriggerUpdate(version)
 	} else {
-		log.Println("Auto-updater not initialized, cannot process update notification")
+		agentLogger.Warnf("Auto-updater not initialized, cannot process update notification")
 	}
 }
 
 // handleContainerList handles container.list messages
 func (a *Agent) handleContainerList(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -558,12 +839,12 @@ func (a *Agent) handleContainerList(msg Message) {
 	// List containers
 	containers, err := a.docker.ListContainers(ctx)
 	if err != nil {
-		log.Printf("Failed to list containers: %v", err)
+		agentLogger.Errorf("Failed to list containers: %v", err)
 		a.sendContainerErrorWithReply("", "list", err.Error(), "DOCKER_LIST_FAILED", msg.Reply)
 		return
 	}
 
-	log.Printf("Listed %d containers", len(containers))
+	agentLogger.Debugf("Listed %d containers", len(containers))
 
 	// Send response to reply subject if specified, otherwise use default
 	subject := "container.list.response"
@@ -584,7 +865,7 @@ func (a *Agent) handleContainerList(msg Message) {
 
 // handleContainerStart handles container.start messages
 func (a *Agent) handleContainerStart(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -600,10 +881,15 @@ func (a *Agent) handleContainerStart(msg Message) {
 		return
 	}
 
+	// Free game ports held by a wake listener if the server is hibernated
+	if a.idle != nil {
+		a.idle.ReleaseContainer(op.ContainerID)
+	}
+
 	// Start container
 	err := a.docker.StartContainer(ctx, op.ContainerID)
 	if err != nil {
-		log.Printf("Failed to start container %s: %v", op.ContainerID, err)
+		agentLogger.Errorf("Failed to start container %s: %v", op.ContainerID, err)
 		a.sendContainerErrorWithReply(op.ContainerID, "start", err.Error(), "DOCKER_START_FAILED", msg.Reply)
 		return
 	}
@@ -614,7 +900,7 @@ func (a *Agent) handleContainerStart(msg Message) {
 
 // handleContainerStop handles container.stop messages
 func (a *Agent) handleContainerStop(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -633,7 +919,7 @@ func (a *Agent) handleContainerStop(msg Message) {
 	// Stop container
 	err := a.docker.StopContainer(ctx, op.ContainerID)
 	if err != nil {
-		log.Printf("Failed to stop container %s: %v", op.ContainerID, err)
+		agentLogger.Errorf("Failed to stop container %s: %v", op.ContainerID, err)
 		a.sendContainerErrorWithReply(op.ContainerID, "stop", err.Error(), "DOCKER_STOP_FAILED", msg.Reply)
 		return
 	}
@@ -644,7 +930,7 @@ func (a *Agent) handleContainerStop(msg Message) {
 
 // handleContainerRestart handles container.restart messages
 func (a *Agent) handleContainerRestart(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -660,10 +946,15 @@ func (a *Agent) handleContainerRestart(msg Message) {
 		return
 	}
 
+	// Free game ports held by a wake listener if the server is hibernated
+	if a.idle != nil {
+		a.idle.ReleaseContainer(op.ContainerID)
+	}
+
 	// Restart container
 	err := a.docker.RestartContainer(ctx, op.ContainerID)
 	if err != nil {
-		log.Printf("Failed to restart container %s: %v", op.ContainerID, err)
+		agentLogger.Errorf("Failed to restart container %s: %v", op.ContainerID, err)
 		a.sendContainerErrorWithReply(op.ContainerID, "restart", err.Error(), "DOCKER_RESTART_FAILED", msg.Reply)
 		return
 	}
@@ -674,7 +965,7 @@ func (a *Agent) handleContainerRestart(msg Message) {
 
 // handleContainerMetrics handles container.metrics messages
 func (a *Agent) handleContainerMetrics(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -697,17 +988,17 @@ func (a *Agent) handleContainerMetrics(msg Message) {
 		return
 	}
 
-	log.Printf("Collecting metrics for container: %s", request.ContainerID)
+	agentLogger.Debugf("Collecting metrics for container: %s", request.ContainerID)
 
 	// Collect container metrics
 	metrics, err := a.docker.CollectContainerMetrics(ctx, request.ContainerID)
 	if err != nil {
-		log.Printf("Failed to collect metrics for container %s: %v", request.ContainerID, err)
+		agentLogger.Errorf("Failed to collect metrics for container %s: %v", request.ContainerID, err)
 		a.sendContainerErrorWithReply(request.ContainerID, "metrics", err.Error(), "METRICS_COLLECTION_FAILED", msg.Reply)
 		return
 	}
 
-	log.Printf("Collected metrics for container %s: CPU=%.2f%%, Memory=%dMB/%dMB, Uptime=%s",
+	agentLogger.Debugf("Collected metrics for container %s: CPU=%.2f%%, Memory=%dMB/%dMB, Uptime=%s",
 		request.ContainerID, metrics.CPUPercent, metrics.MemoryUsedMB, metrics.MemoryLimitMB, metrics.Uptime)
 
 	// Send response to reply subject
@@ -789,7 +1080,7 @@ func (a *Agent) sendContainerErrorWithReply(containerID, operation, errorMsg, er
 // handleLogStreamStart handles log.stream.start messages
 func (a *Agent) handleLogStreamStart(msg Message) {
 	// Check if Docker client is available
-	if a.docker == nil {
+	if a.docker == nil || a.logHub == nil {
 		a.sendLogStreamError("", "Docker client not initialized", "DOCKER_NOT_AVAILABLE", msg.Reply)
 		return
 	}
@@ -801,92 +1092,47 @@ func (a *Agent) handleLogStreamStart(msg Message) {
 		Tail        int    `json:"tail"`
 		Follow      bool   `json:"follow"`
 		Timestamps  bool   `json:"timestamps"`
+		RateLimit   int    `json:"rateLimit"` // Lines per second (0 = default)
 	}
 	if err := json.Unmarshal(data, &req); err != nil {
 		a.sendLogStreamError("", "Invalid request format", "INVALID_REQUEST", msg.Reply)
 		return
 	}
 
-	// Check if already streaming this container
-	if _, exists := a.logStreams[req.ContainerID]; exists {
-		a.sendLogStreamError(req.ContainerID, "Already streaming logs for this container", "ALREADY_STREAMING", msg.Reply)
-		return
-	}
-
 	// Default tail to 1000 if not specified
 	if req.Tail == 0 {
 		req.Tail = 1000
 	}
 
-	// Create context for this log stream
-	ctx, cancel := context.WithCancel(context.Background())
-	a.logStreams[req.ContainerID] = cancel
-
-	log.Printf("Starting log stream for container: %s (tail: %d)", req.ContainerID, req.Tail)
+	// Several subscribers share one Docker reader per container
+	subscriptionID := a.logHub.Subscribe(req.ContainerID, req.Tail, req.RateLimit)
 
-	// Start streaming logs
-	logChan, errChan := a.docker.StreamContainerLogs(ctx, req.ContainerID, req.Tail)
+	agentLogger.Infof("Starting log stream for container: %s (tail: %d, subscription: %s)", req.ContainerID, req.Tail, subscriptionID)
 
 	// Send acknowledgment
 	if msg.Reply != "" {
 		response := Message{
 			Subject: msg.Reply,
 			Data: map[string]interface{}{
-				"success":     true,
-				"containerId": req.ContainerID,
-				"message":     "Log streaming started",
+				"success":        true,
+				"containerId":    req.ContainerID,
+				"subscriptionId": subscriptionID,
+				"message":        "Log streaming started",
 			},
 			Timestamp: time.Now().Unix(),
 		}
 		a.sendMessage(response)
 	}
-
-	// Forward logs to manager
-	go func() {
-		defer func() {
-			delete(a.logStreams, req.ContainerID)
-			log.Printf("Stopped log stream for container: %s", req.ContainerID)
-		}()
-
-		for {
-			select {
-			case logLine, ok := <-logChan:
-				if !ok {
-					// Channel closed, stream ended
-					return
-				}
-
-				// Send log line to manager
-				logMsg := Message{
-					Subject:   "log.line",
-					Data:      logLine,
-					Timestamp: time.Now().Unix(),
-				}
-				if err := a.sendMessage(logMsg); err != nil {
-					log.Printf("Failed to send log line: %v", err)
-					return
-				}
-
-			case err, ok := <-errChan:
-				if ok && err != nil {
-					log.Printf("Log streaming error for %s: %v", req.ContainerID, err)
-					a.sendLogStreamError(req.ContainerID, err.Error(), "DOCKER_LOG_FAILED", "")
-					return
-				}
-
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
 }
 
 // handleLogStreamStop handles log.stream.stop messages
+// With a subscriptionId only that subscription ends; with just a containerId all of the container's do.
 func (a *Agent) handleLogStreamStop(msg Message) {
 	// Parse request
 	data, _ := json.Marshal(msg.Data)
 	var req struct {
-		ContainerID string `json:"containerId"`
+		ContainerID    string `json:"containerId"`
+		SubscriptionID string `json:"subscriptionId"`
 	}
 	if err := json.Unmarshal(data, &req); err != nil {
 		a.sendLogStreamError("", "Invalid request format", "INVALID_REQUEST", msg.Reply)
@@ -894,26 +1140,30 @@ func (a *Agent) handleLogStreamStop(msg Message) {
 	}
 
 	// Check if stream exists
-	cancel, exists := a.logStreams[req.ContainerID]
-	if !exists {
+	stopped := false
+	if a.logHub != nil {
+		if req.SubscriptionID != "" {
+			stopped = a.logHub.Unsubscribe(req.SubscriptionID)
+		} else {
+			stopped = a.logHub.UnsubscribeContainer(req.ContainerID) > 0
+		}
+	}
+	if !stopped {
 		a.sendLogStreamError(req.ContainerID, "No active log stream for this container", "NOT_STREAMING", msg.Reply)
 		return
 	}
 
-	// Cancel the stream
-	cancel()
-	delete(a.logStreams, req.ContainerID)
-
-	log.Printf("Stopped log stream for container: %s", req.ContainerID)
+	agentLogger.Infof("Stopped log stream for container: %s", req.ContainerID)
 
 	// Send acknowledgment
 	if msg.Reply != "" {
 		response := Message{
 			Subject: msg.Reply,
 			Data: map[string]interface{}{
-				"success":     true,
-				"containerId": req.ContainerID,
-				"message":     "Log streaming stopped",
+				"success":        true,
+				"containerId":    req.ContainerID,
+				"subscriptionId": req.SubscriptionID,
+				"message":        "Log streaming stopped",
 			},
 			Timestamp: time.Now().Unix(),
 		}
@@ -943,7 +1193,7 @@ func (a *Agent) sendLogStreamError(containerID, errorMsg, errorCode, replyTo str
 
 // handleServerCreate handles server.create messages
 func (a *Agent) handleServerCreate(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -968,7 +1218,7 @@ func (a *Agent) handleServerCreate(msg Message) {
 		}
 	}
 
-	log.Printf("Creating server: %s (registry: %s, tag: %s)", req.Name, req.Registry, req.ImageTag)
+	agentLogger.Infof("Creating server: %s (registry: %s, tag: %s)", req.Name, req.Registry, req.ImageTag)
 
 	// Create server config
 	config := ServerConfig{
@@ -986,22 +1236,22 @@ func (a *Agent) handleServerCreate(msg Message) {
 	// Create server
 	containerID, err := a.docker.CreateServer(ctx, config)
 	if err != nil {
-		log.Printf("Failed to create server %s: %v", req.Name, err)
+		agentLogger.Errorf("Failed to create server %s: %v", req.Name, err)
 		a.sendServerErrorWithReply(req.ServerID, "", "create", err.Error(), "SERVER_CREATE_FAILED", msg.Reply)
 		return
 	}
 
-	log.Printf("Server created successfully: %s (container: %s)", req.Name, containerID)
+	agentLogger.Infof("Server created successfully: %s (container: %s)", req.Name, containerID)
 
 	// Inspect container to get resolved image name
 	var resolvedImageName string
 	inspect, err := a.docker.cli.ContainerInspect(ctx, containerID)
 	if err != nil {
-		log.Printf("Warning: failed to inspect container for image name: %v", err)
+		agentLogger.Warnf("Failed to inspect container for image name: %v", err)
 		resolvedImageName = req.ImageTag // Fallback to requested tag
 	} else {
 		resolvedImageName = inspect.Config.Image
-		log.Printf("Resolved image name: %s", resolvedImageName)
+		agentLogger.Infof("Resolved image name: %s", resolvedImageName)
 	}
 
 	// Send success response with resolved image name
@@ -1021,7 +1271,7 @@ func (a *Agent) handleServerCreate(msg Message) {
 
 // handleServerDelete handles server.delete messages
 func (a *Agent) handleServerDelete(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -1037,17 +1287,22 @@ func (a *Agent) handleServerDelete(msg Message) {
 		return
 	}
 
-	log.Printf("Deleting server: containerID=%s, serverName=%s, removeVolumes=%v, dataPath=%s", req.ContainerID, req.ServerName, req.RemoveVolumes, req.DataPath)
+	agentLogger.Infof("Deleting server: containerID=%s, serverName=%s, removeVolumes=%v, dataPath=%s", req.ContainerID, req.ServerName, req.RemoveVolumes, req.DataPath)
+
+	// Free game ports held by a wake listener if the server is hibernated
+	if a.idle != nil {
+		a.idle.ReleaseContainer(req.ContainerID)
+	}
 
 	// Delete server (container and/or data)
 	err := a.docker.DeleteServer(ctx, req.ContainerID, req.ServerName, req.RemoveVolumes, req.DataPath)
 	if err != nil {
-		log.Printf("Failed to delete server (container=%s, name=%s): %v", req.ContainerID, req.ServerName, err)
+		agentLogger.Errorf("Failed to delete server (container=%s, name=%s): %v", req.ContainerID, req.ServerName, err)
 		a.sendServerErrorWithReply("", req.ContainerID, "delete", err.Error(), "SERVER_DELETE_FAILED", msg.Reply)
 		return
 	}
 
-	log.Printf("Server deleted successfully: %s", req.ServerName)
+	agentLogger.Infof("Server deleted successfully: %s", req.ServerName)
 
 	// Send success response
 	a.sendServerSuccessWithReply("", req.ContainerID, "delete", msg.Reply)
@@ -1055,7 +1310,7 @@ func (a *Agent) handleServerDelete(msg Message) {
 
 // handleServerRebuild handles server.rebuild messages
 func (a *Agent) handleServerRebuild(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -1071,17 +1326,22 @@ func (a *Agent) handleServerRebuild(msg Message) {
 		return
 	}
 
-	log.Printf("Rebuilding server container: %s", req.ContainerID)
+	agentLogger.Infof("Rebuilding server container: %s", req.ContainerID)
+
+	// Free game ports held by a wake listener if the server is hibernated
+	if a.idle != nil {
+		a.idle.ReleaseContainer(req.ContainerID)
+	}
 
 	// Rebuild server (pass full request for config update support)
 	newContainerID, err := a.docker.RebuildServerWithConfig(ctx, req)
 	if err != nil {
-		log.Printf("Failed to rebuild server %s: %v", req.ContainerID, err)
+		agentLogger.Errorf("Failed to rebuild server %s: %v", req.ContainerID, err)
 		a.sendServerErrorWithReply("", req.ContainerID, "rebuild", err.Error(), "SERVER_REBUILD_FAILED", msg.Reply)
 		return
 	}
 
-	log.Printf("Server rebuilt successfully: %s -> %s", req.ContainerID, newContainerID)
+	agentLogger.Infof("Server rebuilt successfully: %s -> %s", req.ContainerID, newContainerID)
 
 	// Send success response with new container ID
 	if msg.Reply != "" {
@@ -1119,7 +1379,7 @@ func (a *Agent) handleServerCheckData(msg Message) {
 		return
 	}
 
-	log.Printf("Checking data existence for %d server(s)", len(req.Servers))
+	agentLogger.Infof("Checking data existence for %d server(s)", len(req.Servers))
 
 	// Check data existence for each server
 	statuses := make([]ServerDataStatus, 0, len(req.Servers))
@@ -1127,7 +1387,7 @@ func (a *Agent) handleServerCheckData(msg Message) {
 		status := a.docker.CheckServerData(serverName, req.DataPath)
 		statuses = append(statuses, status)
 
-		log.Printf("Server %s: data_exists=%t (bin=%t, data=%t)",
+		agentLogger.Infof("Server %s: data_exists=%t (bin=%t, data=%t)",
 			serverName, status.DataExists, status.BinExists, status.DataFolderExists)
 	}
 
@@ -1166,10 +1426,10 @@ func (a *Agent) handleServerGetDataPath(msg Message) {
 		return
 	}
 
-	log.Printf("Getting data path from container: %s", req.ContainerID)
+	agentLogger.Infof("Getting data path from container: %s", req.ContainerID)
 
 	// Get the actual data path from container mounts
-	ctx := context.Background()
+	ctx := msg.Context()
 	dataPath, err := a.docker.GetContainerDataPath(ctx, req.ContainerID)
 
 	if msg.Reply != "" {
@@ -1213,9 +1473,9 @@ func (a *Agent) handleServerReadINI(msg Message) {
 		return
 	}
 
-	log.Printf("Reading INI for server %s from container %s", req.ServerName, req.ContainerID)
+	agentLogger.Infof("Reading INI for server %s from container %s", req.ServerName, req.ContainerID)
 
-	ctx := context.Background()
+	ctx := msg.Context()
 	result, err := a.docker.ReadServerINI(ctx, req.ContainerID, req.ServerName)
 
 	if msg.Reply != "" {
@@ -1259,7 +1519,7 @@ func (a *Agent) handleServerVolumeSizes(msg Message) {
 		return
 	}
 
-	log.Printf("Getting volume sizes for server: %s (dataPath: %s)", req.ServerName, req.DataPath)
+	agentLogger.Infof("Getting volume sizes for server: %s (dataPath: %s)", req.ServerName, req.DataPath)
 
 	// Check cache first
 	cacheKey := req.ServerName + ":" + req.DataPath
@@ -1268,7 +1528,7 @@ func (a *Agent) handleServerVolumeSizes(msg Message) {
 	a.volumeCacheMu.RUnlock()
 
 	if found && time.Now().Before(cached.expiresAt) {
-		log.Printf("Returning cached volume sizes for %s", req.ServerName)
+		agentLogger.Debugf("Returning cached volume sizes for %s", req.ServerName)
 		if msg.Reply != "" {
 			response := Message{
 				Subject: msg.Reply,
@@ -1306,7 +1566,7 @@ func (a *Agent) handleServerVolumeSizes(msg Message) {
 			}
 			a.volumeCacheMu.Unlock()
 
-			log.Printf("Volume sizes for %s: bin=%d bytes, data=%d bytes, total=%d bytes",
+			agentLogger.Infof("Volume sizes for %s: bin=%d bytes, data=%d bytes, total=%d bytes",
 				req.ServerName, sizes.BinBytes, sizes.DataBytes, sizes.TotalBytes)
 
 			response = Message{
@@ -1378,13 +1638,25 @@ func (a *Agent) handleServerMoveData(msg Message) {
 		return
 	}
 
-	log.Printf("Moving server data: %s from %s to %s", req.ServerName, req.OldPath, req.NewPath)
+	agentLogger.Infof("Moving server data: %s from %s to %s", req.ServerName, req.OldPath, req.NewPath)
+
+	_, span := startSpan(msg.Context(), "server.movedata",
+		attribute.String("zedops.server.name", req.ServerName),
+		attribute.String("zedops.move.old_path", req.OldPath),
+		attribute.String("zedops.move.new_path", req.NewPath))
 
 	// Create progress callback to stream updates via WebSocket
+	lastPhase := ""
 	progressFn := func(progress MoveProgress) {
 		// Add server name to progress
 		progress.ServerName = req.ServerName
 
+		// Phase changes become span events (copying reports far too often to record each update)
+		if progress.Phase != lastPhase {
+			lastPhase = progress.Phase
+			addPhaseEvent(span, progress.Phase, progress.Percent)
+		}
+
 		// Send progress message
 		progressMsg := Message{
 			Subject:   "move.progress",
@@ -1396,8 +1668,9 @@ func (a *Agent) handleServerMoveData(msg Message) {
 
 	// Perform the move with progress streaming
 	result, err := a.docker.MoveServerData(req.ServerName, req.OldPath, req.NewPath, progressFn)
+	endSpan(span, err)
 	if err != nil {
-		log.Printf("Failed to move server data: %v", err)
+		agentLogger.Errorf("Failed to move server data: %v", err)
 		if msg.Reply != "" {
 			response := Message{
 				Subject: msg.Reply,
@@ -1415,7 +1688,7 @@ func (a *Agent) handleServerMoveData(msg Message) {
 		return
 	}
 
-	log.Printf("Server data moved successfully: %s (%d files, %d bytes)", req.ServerName, result.FilesMoved, result.BytesMoved)
+	agentLogger.Infof("Server data moved successfully: %s (%d files, %d bytes)", req.ServerName, result.FilesMoved, result.BytesMoved)
 
 	// Send success response
 	if msg.Reply != "" {
@@ -1430,7 +1703,7 @@ func (a *Agent) handleServerMoveData(msg Message) {
 
 // handlePortCheck handles port.check messages
 func (a *Agent) handlePortCheck(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Check if Docker client is available
 	if a.docker == nil {
@@ -1466,12 +1739,12 @@ func (a *Agent) handlePortCheck(msg Message) {
 		return
 	}
 
-	log.Printf("Checking port availability for ports: %v", req.Ports)
+	agentLogger.Infof("Checking port availability for ports: %v", req.Ports)
 
 	// Check port availability
 	availability, err := a.docker.CheckPortAvailability(ctx, req.Ports)
 	if err != nil {
-		log.Printf("Failed to check port availability: %v", err)
+		agentLogger.Errorf("Failed to check port availability: %v", err)
 		if msg.Reply != "" {
 			response := Message{
 				Subject: msg.Reply,
@@ -1486,7 +1759,7 @@ func (a *Agent) handlePortCheck(msg Message) {
 		return
 	}
 
-	log.Printf("Port check complete: %d available, %d unavailable",
+	agentLogger.Infof("Port check complete: %d available, %d unavailable",
 		len(availability.Available), len(availability.Unavailable))
 
 	// Send response
@@ -1564,9 +1837,9 @@ func (a *Agent) handleRCONConnect(msg Message) {
 	}
 
 	// Connect to RCON via Docker network
-	sessionID, err := a.rconManager.Connect(req.ServerID, req.ContainerID, req.Port, req.Password)
+	sessionID, err := a.rconManager.Connect(msg.Context(), req.ServerID, req.ContainerID, req.Port, req.Password)
 	if err != nil {
-		log.Printf("[RCON] Connection failed for container %s: %v", req.ContainerID[:12], err)
+		rconLogger.Error("Connection failed", "containerId", req.ContainerID[:12], "error", err)
 		a.sendRCONError("", err.Error(), "RCON_CONNECT_FAILED", msg.Reply)
 		return
 	}
@@ -1602,9 +1875,9 @@ func (a *Agent) handleRCONCommand(msg Message) {
 	}
 
 	// Execute command
-	response, err := a.rconManager.Execute(req.SessionID, req.Command)
+	response, err := a.rconManager.Execute(msg.Context(), req.SessionID, req.Command)
 	if err != nil {
-		log.Printf("RCON command failed: %v", err)
+		agentLogger.Errorf("RCON command failed: %v", err)
 		a.sendRCONError(req.SessionID, err.Error(), "RCON_COMMAND_FAILED", msg.Reply)
 		return
 	}
@@ -1641,7 +1914,7 @@ func (a *Agent) handleRCONDisconnect(msg Message) {
 	// Disconnect
 	err := a.rconManager.Disconnect(req.SessionID)
 	if err != nil {
-		log.Printf("RCON disconnect failed: %v", err)
+		agentLogger.Errorf("RCON disconnect failed: %v", err)
 		a.sendRCONError(req.SessionID, err.Error(), "RCON_DISCONNECT_FAILED", msg.Reply)
 		return
 	}
@@ -1716,11 +1989,11 @@ func (a *Agent) handleRegistryTags(msg Message) {
 		return
 	}
 
-	log.Printf("Fetching registry tags for: %s", req.Registry)
+	agentLogger.Infof("Fetching registry tags for: %s", req.Registry)
 
 	tags, err := ListRegistryTags(req.Registry)
 	if err != nil {
-		log.Printf("Failed to fetch registry tags: %v", err)
+		agentLogger.Errorf("Failed to fetch registry tags: %v", err)
 		if msg.Reply != "" {
 			response := Message{
 				Subject: msg.Reply,
@@ -1735,7 +2008,7 @@ func (a *Agent) handleRegistryTags(msg Message) {
 		return
 	}
 
-	log.Printf("Found %d registry tags for %s", len(tags), req.Registry)
+	agentLogger.Infof("Found %d registry tags for %s", len(tags), req.Registry)
 
 	if msg.Reply != "" {
 		response := Message{
@@ -1752,7 +2025,7 @@ func (a *Agent) handleRegistryTags(msg Message) {
 
 // handleImageInspect handles images.inspect messages - returns default ENV variables from Docker image
 func (a *Agent) handleImageInspect(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	// Parse request
 	var req struct {
@@ -1786,7 +2059,7 @@ func (a *Agent) handleImageInspect(msg Message) {
 		return
 	}
 
-	log.Printf("Inspecting image for defaults: %s", req.ImageTag)
+	agentLogger.Infof("Inspecting image for defaults: %s", req.ImageTag)
 
 	// Get image defaults
 	defaults, err := a.docker.GetImageDefaults(ctx, req.ImageTag)
@@ -1823,7 +2096,7 @@ func (a *Agent) handleAgentLogsSubscribe(msg Message) {
 
 	// Check if already subscribed
 	if a.agentLogChan != nil {
-		log.Println("Agent logs: Already streaming to manager")
+		agentLogsLogger.Infof("Already streaming to manager")
 		if msg.Reply != "" {
 			response := Message{
 				Subject: msg.Reply,
@@ -1850,7 +2123,7 @@ func (a *Agent) handleAgentLogsSubscribe(msg Message) {
 		req.Tail = 500
 	}
 
-	log.Printf("Agent logs: Starting stream (tail: %d)", req.Tail)
+	agentLogsLogger.Infof("Starting stream (tail: %d)", req.Tail)
 
 	// Send history first
 	history := a.logCapture.GetHistory(req.Tail)
@@ -1891,11 +2164,11 @@ func (a *Agent) handleAgentLogsSubscribe(msg Message) {
 				Timestamp: time.Now().Unix(),
 			}
 			if err := a.sendMessage(logMsg); err != nil {
-				log.Printf("Agent logs: Failed to send log line: %v", err)
+				agentLogsLogger.Errorf("Failed to send log line: %v", err)
 				return
 			}
 		}
-		log.Println("Agent logs: Stream ended")
+		agentLogsLogger.Infof("Stream ended")
 	}()
 }
 
@@ -1905,7 +2178,7 @@ func (a *Agent) handleAgentLogsUnsubscribe(msg Message) {
 	defer a.agentLogMutex.Unlock()
 
 	if a.agentLogChan == nil {
-		log.Println("Agent logs: Not currently streaming")
+		agentLogsLogger.Infof("Not currently streaming")
 		if msg.Reply != "" {
 			response := Message{
 				Subject: msg.Reply,
@@ -1920,7 +2193,7 @@ func (a *Agent) handleAgentLogsUnsubscribe(msg Message) {
 		return
 	}
 
-	log.Println("Agent logs: Stopping stream")
+	agentLogsLogger.Infof("Stopping stream")
 	a.logCapture.Unsubscribe(a.agentLogChan)
 	a.agentLogChan = nil
 
@@ -1940,11 +2213,15 @@ func (a *Agent) handleAgentLogsUnsubscribe(msg Message) {
 // cleanupOnDisconnect resets state when WebSocket connection is lost
 // This ensures log streaming can be re-established on reconnect
 func (a *Agent) cleanupOnDisconnect() {
+	if a.logHub != nil {
+		a.logHub.UnsubscribeAll()
+	}
+
 	a.agentLogMutex.Lock()
 	defer a.agentLogMutex.Unlock()
 
 	if a.agentLogChan != nil {
-		log.Println("Agent logs: Cleaning up stream on disconnect")
+		agentLogsLogger.Infof("Cleaning up stream on disconnect")
 		a.logCapture.Unsubscribe(a.agentLogChan)
 		a.agentLogChan = nil
 	}
@@ -1952,7 +2229,7 @@ func (a *Agent) cleanupOnDisconnect() {
 
 // handleServerInspect handles server.inspect messages
 func (a *Agent) handleServerInspect(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	if a.docker == nil {
 		if msg.Reply != "" {
@@ -1978,7 +2255,7 @@ func (a *Agent) handleServerInspect(msg Message) {
 		return
 	}
 
-	log.Printf("Inspecting container: %s", req.ContainerID)
+	agentLogger.Infof("Inspecting container: %s", req.ContainerID)
 
 	resp, err := a.docker.InspectContainer(ctx, req.ContainerID)
 	if err != nil {
@@ -2003,7 +2280,7 @@ func (a *Agent) handleServerInspect(msg Message) {
 
 // handleServerAdopt handles server.adopt messages
 func (a *Agent) handleServerAdopt(msg Message) {
-	ctx := context.Background()
+	ctx := msg.Context()
 
 	if a.docker == nil {
 		if msg.Reply != "" {
@@ -2029,7 +2306,12 @@ func (a *Agent) handleServerAdopt(msg Message) {
 		return
 	}
 
-	log.Printf("Adopting container %s as server '%s'", req.ContainerID, req.Name)
+	agentLogger.Infof("Adopting container %s as server '%s'", req.ContainerID, req.Name)
+
+	// Free game ports held by a wake listener before the new container binds them
+	if a.idle != nil {
+		a.idle.ReleaseContainer(req.ContainerID)
+	}
 
 	// Create progress callback to stream updates via WebSocket
 	progressFn := func(progress AdoptProgress) {
@@ -2042,7 +2324,7 @@ func (a *Agent) handleServerAdopt(msg Message) {
 
 	result, err := a.docker.AdoptServer(ctx, req, progressFn)
 	if err != nil {
-		log.Printf("Failed to adopt container %s: %v", req.ContainerID, err)
+		agentLogger.Errorf("Failed to adopt container %s: %v", req.ContainerID, err)
 		if msg.Reply != "" {
 			a.sendMessage(Message{
 				Subject:   msg.Reply,
@@ -2053,7 +2335,7 @@ func (a *Agent) handleServerAdopt(msg Message) {
 		return
 	}
 
-	log.Printf("Container adopted successfully: %s -> %s (dataPath: %s)", req.ContainerID, result.ContainerID, result.DataPath)
+	agentLogger.Infof("Container adopted successfully: %s -> %s (dataPath: %s)", req.ContainerID, result.ContainerID, result.DataPath)
 
 	if msg.Reply != "" {
 		a.sendMessage(Message{
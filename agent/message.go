@@ -1,16 +1,28 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 )
 
 // Message represents a NATS-inspired message
 type Message struct {
-	Subject   string      `json:"subject"`
-	Reply     string      `json:"reply,omitempty"`
-	Data      interface{} `json:"data"`
-	Timestamp int64       `json:"timestamp,omitempty"`
+	Subject     string      `json:"subject"`
+	Reply       string      `json:"reply,omitempty"`
+	Data        interface{} `json:"data"`
+	Timestamp   int64       `json:"timestamp,omitempty"`
+	TraceParent string      `json:"traceparent,omitempty"` // W3C traceparent: the trace ID shared by a request, its agent spans and its reply
+
+	ctx context.Context // Handling context of a received message (carries its span)
+}
+
+// Context returns the context a received message is handled in
+func (m Message) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
 }
 
 // NewMessage creates a new message with timestamp
@@ -0,0 +1,641 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const metricAlertRulesFile = "metric-alert-rules.json"
+
+const (
+	metricAlertEvalEvery = 15 * time.Second
+	metricAlertStaleAge  = 10 * time.Minute // Series not reported this long are dropped (and resolved if firing)
+)
+
+// Metric alert states
+const (
+	MetricAlertFiring   = "firing"
+	MetricAlertResolved = "resolved"
+)
+
+// metricAlertMetrics lists the metrics rules can watch and how they are described in notifications
+var metricAlertMetrics = map[string]string{
+	"host.cpu.percent":             "Host CPU",
+	"host.memory.percent":          "Host memory",
+	"host.swap.percent":            "Host swap",
+	"host.disk.percent":            "Disk",
+	"host.load1.per_core":          "Host load per core",
+	"host.iowait.percent":          "Host iowait",
+	"host.steal.percent":           "Host CPU steal",
+	"server.cpu.percent":           "Server CPU",
+	"server.memory.percent":        "Server memory (of limit)",
+	"server.cpu.throttled.percent": "Server CPU throttling",
+	"server.pids":                  "Server processes",
+	"server.rcon.up":               "RCON",
+	"server.players":               "Players",
+	"server.players.percent":       "Players (of max)",
+}
+
+// MetricAlertRule fires when a metric compares true against Threshold for ForSeconds (and at least
+// ForSamples consecutive new samples), and resolves once it no longer compares true against
+// RecoverThreshold for RecoverForSeconds. RecoverThreshold provides hysteresis, e.g. fire above 90
+// and resolve below 85.
+type MetricAlertRule struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	Metric            string   `json:"metric"`             // See metricAlertMetrics
+	ServerID          string   `json:"serverId,omitempty"` // Empty = all servers (server.* metrics)
+	Operator          string   `json:"operator"`           // >, >=, <, <=, ==, !=
+	Threshold         float64  `json:"threshold"`
+	RecoverThreshold  *float64 `json:"recoverThreshold,omitempty"` // Default: Threshold
+	ForSeconds        int      `json:"forSeconds"`
+	ForSamples        int      `json:"forSamples,omitempty"` // Consecutive new samples required (e.g. RCON polls)
+	RecoverForSeconds int      `json:"recoverForSeconds,omitempty"`
+	Severity          string   `json:"severity,omitempty"` // info, warning, critical
+	Enabled           bool     `json:"enabled"`
+}
+
+// MetricAlertEvent is the alert.metric payload, sent when an alert fires and when it resolves
+type MetricAlertEvent struct {
+	RuleID     string  `json:"ruleId"`
+	RuleName   string  `json:"ruleName"`
+	Severity   string  `json:"severity"`
+	Status     string  `json:"status"` // firing, resolved
+	Metric     string  `json:"metric"`
+	Instance   string  `json:"instance,omitempty"` // Disk path for host.disk.percent
+	ServerID   string  `json:"serverId,omitempty"`
+	ServerName string  `json:"serverName,omitempty"`
+	Value      float64 `json:"value"`
+	Operator   string  `json:"operator"`
+	Threshold  float64 `json:"threshold"`
+	ForSeconds int     `json:"forSeconds"`
+	Summary    string  `json:"summary"`
+	Note       string  `json:"note,omitempty"`
+	StartedAt  int64   `json:"startedAt"` // Unix milliseconds the alert fired
+	Timestamp  int64   `json:"timestamp"` // Unix milliseconds
+}
+
+// metricSample is one observed value of a metric series
+type metricSample struct {
+	Metric     string
+	Instance   string
+	ServerID   string
+	ServerName string
+	Value      float64
+	Timestamp  int64 // Sample time (Unix seconds); a new value counts towards ForSamples
+}
+
+// metricAlertState tracks one rule on one series
+type metricAlertState struct {
+	pendingSince time.Time
+	samples      int
+	lastSampleTs int64
+	firing       bool
+	firedAt      time.Time
+	recoverSince time.Time
+	lastSeen     time.Time
+	last         metricSample
+}
+
+// DefaultMetricAlertRules are used until the manager sends a rule set
+func DefaultMetricAlertRules() []MetricAlertRule {
+	recoverDisk := 85.0
+	recoverMemory := 90.0
+	return []MetricAlertRule{
+		{ID: "disk-full", Name: "Disk almost full", Metric: "host.disk.percent", Operator: ">", Threshold: 90,
+			RecoverThreshold: &recoverDisk, ForSeconds: 300, Severity: "critical", Enabled: true},
+		{ID: "server-memory", Name: "Server near memory limit", Metric: "server.memory.percent", Operator: ">", Threshold: 95,
+			RecoverThreshold: &recoverMemory, ForSeconds: 120, Severity: "warning", Enabled: true},
+		{ID: "rcon-down", Name: "RCON unreachable", Metric: "server.rcon.up", Operator: "<", Threshold: 1,
+			ForSamples: 3, Severity: "warning", Enabled: true},
+		{ID: "server-full", Name: "Server full", Metric: "server.players.percent", Operator: ">=", Threshold: 100,
+			ForSeconds: 1800, Severity: "info", Enabled: true},
+	}
+}
+
+// GetMetricAlertRulesPath returns the path to the cached metric alert rules.
+func GetMetricAlertRulesPath() string {
+	return filepath.Join(StateDir(), metricAlertRulesFile)
+}
+
+// LoadMetricAlertRules loads the cached metric alert rules from disk.
+// Returns nil if the file doesn't exist.
+func LoadMetricAlertRules() ([]MetricAlertRule, error) {
+	data, err := os.ReadFile(GetMetricAlertRulesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read metric alert rules: %w", err)
+	}
+
+	var rules []MetricAlertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse metric alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// SaveMetricAlertRules saves the metric alert rules to disk.
+func SaveMetricAlertRules(rules []MetricAlertRule) error {
+	if err := ensureStateDir(); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(GetMetricAlertRulesPath(), rules); err != nil {
+		return fmt.Errorf("failed to write metric alert rules: %w", err)
+	}
+	return nil
+}
+
+// compileMetricAlertRules validates rules, fills in defaults and returns the enabled ones
+func compileMetricAlertRules(rules []MetricAlertRule) ([]MetricAlertRule, error) {
+	var active []MetricAlertRule
+	seen := make(map[string]bool)
+
+	for _, r := range rules {
+		if r.ID == "" {
+			return nil, fmt.Errorf("rule %q has no id", r.Name)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("duplicate rule id %q", r.ID)
+		}
+		seen[r.ID] = true
+
+		if _, ok := metricAlertMetrics[r.Metric]; !ok {
+			return nil, fmt.Errorf("rule %q: unknown metric %q", r.ID, r.Metric)
+		}
+		if _, err := compareMetric(r.Operator, 0, 0); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.ID, err)
+		}
+		if r.ForSeconds < 0 || r.ForSamples < 0 || r.RecoverForSeconds < 0 {
+			return nil, fmt.Errorf("rule %q: durations must not be negative", r.ID)
+		}
+		if r.Severity == "" {
+			r.Severity = "warning"
+		}
+		if r.Name == "" {
+			r.Name = r.ID
+		}
+		if r.Enabled {
+			active = append(active, r)
+		}
+	}
+	return active, nil
+}
+
+// compareMetric applies a rule operator
+func compareMetric(op string, value, threshold float64) (bool, error) {
+	switch op {
+	case ">":
+		return value > threshold, nil
+	case ">=":
+		return value >= threshold, nil
+	case "<":
+		return value < threshold, nil
+	case "<=":
+		return value <= threshold, nil
+	case "==":
+		return value == threshold, nil
+	case "!=":
+		return value != threshold, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// MetricAlertEngine evaluates threshold rules over host and server metrics
+type MetricAlertEngine struct {
+	mu     sync.Mutex
+	rules  []MetricAlertRule
+	active []MetricAlertRule
+	states map[string]*metricAlertState // ruleID|metric|serverID|instance -> state
+	now    func() time.Time
+
+	// Overridable for fixtures
+	collect func() []metricSample
+	emit    func(MetricAlertEvent)
+	stopCh  chan struct{}
+}
+
+// NewMetricAlertEngine creates an engine over the agent's collectors that delivers through the agent
+func NewMetricAlertEngine(agent *Agent) *MetricAlertEngine {
+	return &MetricAlertEngine{
+		states:  make(map[string]*metricAlertState),
+		now:     time.Now,
+		collect: agent.metricAlertSamples,
+		emit:    agent.deliverMetricAlert,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Start loads cached rules (or the defaults) and begins evaluating
+func (e *MetricAlertEngine) Start() {
+	rules, err := LoadMetricAlertRules()
+	if err != nil {
+		metricAlertsLogger.Warnf("%v", err)
+	}
+	if rules == nil {
+		rules = DefaultMetricAlertRules()
+	}
+	if err := e.setRules(rules); err != nil {
+		metricAlertsLogger.Warnf("Ignoring cached rules: %v", err)
+		e.setRules(DefaultMetricAlertRules())
+	}
+
+	metricAlertsLogger.Infof("Starting metric alert engine (%d rule(s), every %v)", len(e.Rules()), metricAlertEvalEvery)
+	go e.loop()
+}
+
+// Stop stops evaluating
+func (e *MetricAlertEngine) Stop() {
+	metricAlertsLogger.Infof("Stopping metric alert engine")
+	close(e.stopCh)
+}
+
+// Rules returns the configured rules
+func (e *MetricAlertEngine) Rules() []MetricAlertRule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]MetricAlertRule{}, e.rules...)
+}
+
+// SetRules validates, applies and caches a new rule set
+func (e *MetricAlertEngine) SetRules(rules []MetricAlertRule) error {
+	if err := e.setRules(rules); err != nil {
+		return err
+	}
+	return SaveMetricAlertRules(rules)
+}
+
+// setRules swaps in a rule set. State of rules that still exist unchanged is kept, so updating
+// an unrelated rule neither re-fires nor silently drops an active alert.
+func (e *MetricAlertEngine) setRules(rules []MetricAlertRule) error {
+	active, err := compileMetricAlertRules(rules)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	unchanged := make(map[string]bool)
+	for _, r := range active {
+		for _, old := range e.active {
+			if old.ID == r.ID && metricAlertRuleEqual(old, r) {
+				unchanged[r.ID] = true
+			}
+		}
+	}
+	for key := range e.states {
+		ruleID, _, _ := strings.Cut(key, "|")
+		if !unchanged[ruleID] {
+			delete(e.states, key)
+		}
+	}
+
+	e.rules = rules
+	e.active = active
+	return nil
+}
+
+// metricAlertRuleEqual compares two normalized rules
+func metricAlertRuleEqual(a, b MetricAlertRule) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// Active returns the currently firing alerts
+func (e *MetricAlertEngine) Active() []MetricAlertEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []MetricAlertEvent
+	for _, r := range e.active {
+		for key, st := range e.states {
+			if st.firing && strings.HasPrefix(key, r.ID+"|") {
+				events = append(events, metricAlertEvent(r, st, MetricAlertFiring, st.lastSeen))
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].StartedAt < events[j].StartedAt })
+	return events
+}
+
+// loop evaluates the rules every metricAlertEvalEvery
+func (e *MetricAlertEngine) loop() {
+	ticker := time.NewTicker(metricAlertEvalEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			for _, event := range e.Evaluate(e.collect(), e.now()) {
+				metricAlertsLogger.Infof("%s: %s", strings.ToUpper(event.Status), event.Summary)
+				e.emit(event)
+			}
+		}
+	}
+}
+
+// Evaluate advances every rule over the samples and returns the alerts that fired or resolved
+func (e *MetricAlertEngine) Evaluate(samples []metricSample, now time.Time) []MetricAlertEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []MetricAlertEvent
+	for _, r := range e.active {
+		for _, s := range samples {
+			if s.Metric != r.Metric || (r.ServerID != "" && r.ServerID != s.ServerID) {
+				continue
+			}
+
+			key := r.ID + "|" + s.Metric + "|" + s.ServerID + "|" + s.Instance
+			st, ok := e.states[key]
+			if !ok {
+				st = &metricAlertState{}
+				e.states[key] = st
+			}
+			newSample := s.Timestamp != st.lastSampleTs
+			st.lastSampleTs = s.Timestamp
+			st.lastSeen = now
+			st.last = s
+
+			if event, changed := advanceMetricAlert(r, st, newSample, now); changed {
+				events = append(events, event)
+			}
+		}
+	}
+
+	// Drop series that stopped reporting (server removed, disk unmounted)
+	for key, st := range e.states {
+		if now.Sub(st.lastSeen) < metricAlertStaleAge {
+			continue
+		}
+		if st.firing {
+			ruleID, _, _ := strings.Cut(key, "|")
+			for _, r := range e.active {
+				if r.ID == ruleID {
+					event := metricAlertEvent(r, st, MetricAlertResolved, now)
+					event.Note = "no longer reported"
+					event.Summary = fmt.Sprintf("%s stopped reporting while alerting (%s)", r.Name, metricAlertSubject(st.last))
+					events = append(events, event)
+				}
+			}
+		}
+		delete(e.states, key)
+	}
+	return events
+}
+
+// advanceMetricAlert moves one series through pending -> firing -> resolved
+func advanceMetricAlert(r MetricAlertRule, st *metricAlertState, newSample bool, now time.Time) (MetricAlertEvent, bool) {
+	value := st.last.Value
+
+	if !st.firing {
+		breach, _ := compareMetric(r.Operator, value, r.Threshold)
+		if !breach {
+			st.pendingSince = time.Time{}
+			st.samples = 0
+			return MetricAlertEvent{}, false
+		}
+		if st.pendingSince.IsZero() {
+			st.pendingSince = now
+			st.samples = 0
+		}
+		if newSample {
+			st.samples++
+		}
+		if now.Sub(st.pendingSince) < time.Duration(r.ForSeconds)*time.Second || st.samples < r.ForSamples {
+			return MetricAlertEvent{}, false
+		}
+		st.firing = true
+		st.firedAt = now
+		st.recoverSince = time.Time{}
+		return metricAlertEvent(r, st, MetricAlertFiring, now), true
+	}
+
+	recoverAt := r.Threshold
+	if r.RecoverThreshold != nil {
+		recoverAt = *r.RecoverThreshold
+	}
+	if still, _ := compareMetric(r.Operator, value, recoverAt); still {
+		st.recoverSince = time.Time{}
+		return MetricAlertEvent{}, false
+	}
+	if st.recoverSince.IsZero() {
+		st.recoverSince = now
+	}
+	if now.Sub(st.recoverSince) < time.Duration(r.RecoverForSeconds)*time.Second {
+		return MetricAlertEvent{}, false
+	}
+
+	event := metricAlertEvent(r, st, MetricAlertResolved, now)
+	*st = metricAlertState{lastSampleTs: st.lastSampleTs, lastSeen: st.lastSeen, last: st.last}
+	return event, true
+}
+
+// metricAlertEvent builds the event for a series' current state
+func metricAlertEvent(r MetricAlertRule, st *metricAlertState, status string, now time.Time) MetricAlertEvent {
+	s := st.last
+	subject := metricAlertSubject(s)
+
+	var summary string
+	if status == MetricAlertFiring {
+		var conditions []string
+		if s.Metric != "server.rcon.up" {
+			conditions = append(conditions, r.Operator+" "+formatMetricValue(s.Metric, r.Threshold))
+		}
+		if r.ForSeconds > 0 {
+			conditions = append(conditions, fmt.Sprintf("for %v", time.Duration(r.ForSeconds)*time.Second))
+		}
+		if r.ForSamples > 0 {
+			conditions = append(conditions, fmt.Sprintf("%d polls in a row", r.ForSamples))
+		}
+		summary = fmt.Sprintf("%s is %s", subject, formatMetricValue(s.Metric, s.Value))
+		if len(conditions) > 0 {
+			summary += " (" + strings.Join(conditions, ", ") + ")"
+		}
+	} else {
+		summary = fmt.Sprintf("%s recovered (%s) after %v", subject, formatMetricValue(s.Metric, s.Value), now.Sub(st.firedAt).Round(time.Second))
+	}
+
+	return MetricAlertEvent{
+		RuleID:     r.ID,
+		RuleName:   r.Name,
+		Severity:   r.Severity,
+		Status:     status,
+		Metric:     s.Metric,
+		Instance:   s.Instance,
+		ServerID:   s.ServerID,
+		ServerName: s.ServerName,
+		Value:      s.Value,
+		Operator:   r.Operator,
+		Threshold:  r.Threshold,
+		ForSeconds: r.ForSeconds,
+		Summary:    summary,
+		StartedAt:  st.firedAt.UnixMilli(),
+		Timestamp:  now.UnixMilli(),
+	}
+}
+
+// metricAlertSubject describes a series, e.g. "Disk /data" or "RCON on pz-main"
+func metricAlertSubject(s metricSample) string {
+	subject := metricAlertMetrics[s.Metric]
+	if s.Instance != "" {
+		subject += " " + s.Instance
+	}
+	if s.ServerName != "" {
+		subject += " on " + s.ServerName
+	}
+	return subject
+}
+
+// formatMetricValue renders a value for notification text
+func formatMetricValue(metric string, v float64) string {
+	switch {
+	case metric == "server.rcon.up":
+		if v >= 1 {
+			return "up"
+		}
+		return "down"
+	case strings.HasSuffix(metric, ".percent"):
+		return fmt.Sprintf("%.1f%%", v)
+	case metric == "server.players" || metric == "server.pids":
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// metricAlertSamples gathers the latest host, container and player samples
+func (a *Agent) metricAlertSamples() []metricSample {
+	var samples []metricSample
+
+	if host, err := a.hostCollector.Latest(); err != nil {
+		metricAlertsLogger.Debugf("Host metrics unavailable: %v", err)
+	} else {
+		add := func(metric string, value float64) {
+			samples = append(samples, metricSample{Metric: metric, Value: value, Timestamp: host.Timestamp})
+		}
+		add("host.cpu.percent", host.CPUPercent)
+		if host.MemoryTotalMB > 0 {
+			add("host.memory.percent", float64(host.MemoryUsedMB)/float64(host.MemoryTotalMB)*100)
+		}
+		if host.SwapTotalMB > 0 {
+			add("host.swap.percent", float64(host.SwapUsedMB)/float64(host.SwapTotalMB)*100)
+		}
+		if host.CPUCount > 0 {
+			add("host.load1.per_core", host.Load1/float64(host.CPUCount))
+		}
+		add("host.iowait.percent", host.IOWaitPercent)
+		add("host.steal.percent", host.StealPercent)
+		for _, d := range host.Disks {
+			samples = append(samples, metricSample{Metric: "host.disk.percent", Instance: d.Path, Value: d.Percent, Timestamp: host.Timestamp})
+		}
+	}
+
+	if a.metricsCollector != nil {
+		for _, s := range a.metricsCollector.Latest() {
+			add := func(metric string, value float64) {
+				samples = append(samples, metricSample{Metric: metric, ServerID: s.ServerID, ServerName: s.ServerName, Value: value, Timestamp: s.Timestamp})
+			}
+			add("server.cpu.percent", s.CPUPercent)
+			if s.MemoryLimitMB > 0 {
+				add("server.memory.percent", s.MemoryPercent)
+			}
+			add("server.cpu.throttled.percent", s.CPUThrottledPercent)
+			add("server.pids", float64(s.PIDs))
+		}
+	}
+
+	if a.playerStats != nil {
+		for _, s := range a.playerStats.GetStats() {
+			add := func(metric string, value float64) {
+				samples = append(samples, metricSample{Metric: metric, ServerID: s.ServerID, ServerName: s.ServerName, Value: value, Timestamp: s.LastUpdate})
+			}
+			add("server.rcon.up", boolFloat(s.RCONConnected))
+			if !s.RCONConnected {
+				continue // Player counts are unknown, not zero
+			}
+			add("server.players", float64(s.PlayerCount))
+			if s.MaxPlayers > 0 {
+				add("server.players.percent", float64(s.PlayerCount)/float64(s.MaxPlayers)*100)
+			}
+		}
+	}
+	return samples
+}
+
+// deliverMetricAlert sends an alert to the manager and routes it to the notifiers. When the
+// manager is unreachable the implicit email channel delivers it instead.
+func (a *Agent) deliverMetricAlert(event MetricAlertEvent) {
+	delivered := false
+	if a.IsAuthenticated() {
+		if err := a.sendMessage(NewMessage("alert.metric", event)); err != nil {
+			metricAlertsLogger.Warnf("Failed to send alert to manager: %v", err)
+		} else {
+			delivered = true
+		}
+	}
+	a.NotifyMetricAlert(event, delivered)
+}
+
+// MetricAlertRulesRequest is the alert.metric.rules.set request
+type MetricAlertRulesRequest struct {
+	Rules []MetricAlertRule `json:"rules"`
+}
+
+// MetricAlertRulesResponse is the reply to alert.metric.rules.set and alert.metric.rules.get
+type MetricAlertRulesResponse struct {
+	Success bool               `json:"success"`
+	Rules   []MetricAlertRule  `json:"rules,omitempty"`
+	Active  []MetricAlertEvent `json:"active,omitempty"` // Currently firing alerts
+	Error   string             `json:"error,omitempty"`
+}
+
+// handleMetricAlertRulesSet handles alert.metric.rules.set messages (replaces the whole rule set)
+func (a *Agent) handleMetricAlertRulesSet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req MetricAlertRulesRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendMetricAlertRulesResponse(msg.Reply, MetricAlertRulesResponse{Error: "Invalid request format"})
+		return
+	}
+
+	if err := a.metricAlerts.SetRules(req.Rules); err != nil {
+		metricAlertsLogger.Infof("Rejected rules: %v", err)
+		a.sendMetricAlertRulesResponse(msg.Reply, MetricAlertRulesResponse{Error: err.Error()})
+		return
+	}
+
+	metricAlertsLogger.Infof("Updated rules (%d rule(s))", len(req.Rules))
+	a.sendMetricAlertRulesResponse(msg.Reply, MetricAlertRulesResponse{Success: true, Rules: req.Rules, Active: a.metricAlerts.Active()})
+}
+
+// handleMetricAlertRulesGet handles alert.metric.rules.get messages
+func (a *Agent) handleMetricAlertRulesGet(msg Message) {
+	a.sendMetricAlertRulesResponse(msg.Reply, MetricAlertRulesResponse{
+		Success: true,
+		Rules:   a.metricAlerts.Rules(),
+		Active:  a.metricAlerts.Active(),
+	})
+}
+
+// sendMetricAlertRulesResponse replies to a metric alert rules request
+func (a *Agent) sendMetricAlertRulesResponse(replyTo string, resp MetricAlertRulesResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
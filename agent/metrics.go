@@ -5,8 +5,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -31,40 +34,107 @@ type HostMetrics struct {
 	MemoryTotalMB int64        `json:"memoryTotalMB"`
 	Disks         []DiskMetric `json:"disks"`
 	Timestamp     int64        `json:"timestamp"`
+
+	CPUCount      int                  `json:"cpuCount"`
+	CPUPerCore    []float64            `json:"cpuPerCore,omitempty"` // Busy percent per core, in cpuN order
+	IOWaitPercent float64              `json:"iowaitPercent"`
+	StealPercent  float64              `json:"stealPercent"` // Time the hypervisor ran other guests (VPS contention)
+	Load1         float64              `json:"load1"`
+	Load5         float64              `json:"load5"`
+	Load15        float64              `json:"load15"`
+	SwapUsedMB    int64                `json:"swapUsedMB"`
+	SwapTotalMB   int64                `json:"swapTotalMB"`
+	Network       []NetInterfaceMetric `json:"network,omitempty"`
+	Pressure      *PressureMetrics     `json:"pressure,omitempty"` // nil when the kernel has no PSI
 }
 
-// cpuStats stores CPU stats for delta calculation
-type cpuStats struct {
-	total uint64
-	idle  uint64
+// NetInterfaceMetric is the throughput of one network interface
+type NetInterfaceMetric struct {
+	Name          string  `json:"name"`
+	RxBytes       uint64  `json:"rxBytes"` // Cumulative since boot
+	TxBytes       uint64  `json:"txBytes"`
+	RxBytesPerSec float64 `json:"rxBytesPerSec"` // Since the previous collection (0 on the first)
+	TxBytesPerSec float64 `json:"txBytesPerSec"`
 }
 
-var lastCPUStats *cpuStats
+// PressureMetrics is Linux pressure stall information (/proc/pressure/*)
+type PressureMetrics struct {
+	CPU    PressureStall `json:"cpu"`
+	Memory PressureStall `json:"memory"`
+	IO     PressureStall `json:"io"`
+}
 
-// CollectHostMetrics collects CPU, memory, and disk metrics from the host
-// dc is optional - if nil, only root filesystem is checked for disk metrics
-func CollectHostMetrics(dc *DockerClient) (*HostMetrics, error) {
-	metrics := &HostMetrics{
-		Timestamp: time.Now().Unix(),
-	}
+// PressureStall is the share of time some (or all) runnable tasks were stalled on a resource,
+// averaged over 10s, 60s and 300s, plus the cumulative stall time
+type PressureStall struct {
+	SomeAvg10   float64 `json:"someAvg10"`
+	SomeAvg60   float64 `json:"someAvg60"`
+	SomeAvg300  float64 `json:"someAvg300"`
+	SomeTotalUs uint64  `json:"someTotalUs"`
+	FullAvg10   float64 `json:"fullAvg10"`
+	FullAvg60   float64 `json:"fullAvg60"`
+	FullAvg300  float64 `json:"fullAvg300"`
+	FullTotalUs uint64  `json:"fullTotalUs"`
+}
 
-	// Collect CPU usage
-	cpu, err := getCPUUsage()
-	if err != nil {
-		return nil, fmt.Errorf("CPU collection failed: %w", err)
+// cpuTimes are the jiffy counters of one /proc/stat cpu line
+type cpuTimes struct {
+	user, nice, system, idle, iowait, irq, softirq, steal uint64
+}
+
+func (t cpuTimes) total() uint64 {
+	// guest and guest_nice are already included in user and nice
+	return t.user + t.nice + t.system + t.idle + t.iowait + t.irq + t.softirq + t.steal
+}
+
+// netCounters are the cumulative byte counters of one interface
+type netCounters struct {
+	rx, tx uint64
+}
+
+// HostCollector samples host metrics from a proc filesystem. CPU and network figures are deltas
+// against the previous Collect, so the collector keeps that sample.
+type HostCollector struct {
+	procRoot string        // Normally /proc; a fixture directory in tests
+	docker   *DockerClient // Optional, for discovering server data disks
+
+	// statfs reports the filesystem at a path and its device ID (getDiskMetricWithDeviceID; stubbed in tests)
+	statfs func(path, label string) (*DiskMetric, string, error)
+
+	mu       sync.Mutex
+	prevCPU  map[string]cpuTimes // "cpu", "cpu0", ... -> previous sample
+	prevNet  map[string]netCounters
+	prevTime time.Time
+
+	cacheMu  sync.Mutex
+	latest   *HostMetrics
+	latestAt time.Time
+}
+
+// hostMetricsCacheTTL keeps frequent scrapes from re-reading /proc and statfs every time
+const hostMetricsCacheTTL = 5 * time.Second
+
+// NewHostCollector creates a host collector reading procRoot. dc is optional - if nil, only the
+// root filesystem is checked for disk metrics.
+func NewHostCollector(procRoot string, dc *DockerClient) *HostCollector {
+	return &HostCollector{
+		procRoot: procRoot,
+		docker:   dc,
+		statfs:   getDiskMetricWithDeviceID,
+		prevCPU:  make(map[string]cpuTimes),
+		prevNet:  make(map[string]netCounters),
 	}
-	metrics.CPUPercent = cpu
+}
 
-	// Collect memory usage
-	memUsed, memTotal, err := getMemoryUsage()
+// Collect collects CPU, memory, load, network, pressure and disk metrics from the host
+func (hc *HostCollector) Collect() (*HostMetrics, error) {
+	metrics, err := hc.collectProc(time.Now())
 	if err != nil {
-		return nil, fmt.Errorf("memory collection failed: %w", err)
+		return nil, err
 	}
-	metrics.MemoryUsedMB = memUsed
-	metrics.MemoryTotalMB = memTotal
 
 	// Collect disk usage for all server data volumes
-	disks, err := collectDiskMetrics(dc)
+	disks, err := hc.collectDiskMetrics()
 	if err != nil {
 		return nil, fmt.Errorf("disk collection failed: %w", err)
 	}
@@ -73,118 +143,275 @@ func CollectHostMetrics(dc *DockerClient) (*HostMetrics, error) {
 	return metrics, nil
 }
 
-// getCPUUsage parses /proc/stat to calculate CPU usage percentage
-func getCPUUsage() (float64, error) {
-	file, err := os.Open("/proc/stat")
+// Latest returns host metrics collected at most hostMetricsCacheTTL ago. Heartbeats and the
+// exporters share it, so CPU and network rates cover the interval since any consumer last asked.
+func (hc *HostCollector) Latest() (*HostMetrics, error) {
+	hc.cacheMu.Lock()
+	defer hc.cacheMu.Unlock()
+
+	if hc.latest != nil && time.Since(hc.latestAt) < hostMetricsCacheTTL {
+		return hc.latest, nil
+	}
+	metrics, err := hc.Collect()
 	if err != nil {
-		return 0, fmt.Errorf("failed to open /proc/stat: %w", err)
+		return nil, err
 	}
-	defer file.Close()
+	hc.latest = metrics
+	hc.latestAt = time.Now()
+	return metrics, nil
+}
 
-	scanner := bufio.NewScanner(file)
-	if !scanner.Scan() {
-		return 0, fmt.Errorf("failed to read /proc/stat")
+// hostNamespaced returns the path of a per-namespace proc file (mounts, net/dev). The top-level
+// files are links to /proc/self, which is the agent's own namespace even when procRoot is the
+// host's proc mounted into the agent's container, so they are read through the host's init.
+func (hc *HostCollector) hostNamespaced(name ...string) string {
+	if hc.procRoot == "/proc" {
+		return filepath.Join(append([]string{hc.procRoot}, name...)...)
 	}
+	return filepath.Join(append([]string{hc.procRoot, "1"}, name...)...)
+}
 
-	line := scanner.Text()
-	if !strings.HasPrefix(line, "cpu ") {
-		return 0, fmt.Errorf("invalid /proc/stat format")
+// hostPath returns where a host path can be statted from the agent: through the root of the
+// host's init when procRoot is the host's proc mounted into the agent's container
+func (hc *HostCollector) hostPath(path string) string {
+	if hc.procRoot == "/proc" {
+		return path
 	}
+	return filepath.Join(hc.procRoot, "1", "root", path)
+}
 
-	// Parse CPU line: cpu  user nice system idle iowait irq softirq steal guest guest_nice
-	fields := strings.Fields(line)
-	if len(fields) < 5 {
-		return 0, fmt.Errorf("invalid /proc/stat CPU line")
-	}
+// collectProc collects everything that comes from the proc filesystem
+func (hc *HostCollector) collectProc(now time.Time) (*HostMetrics, error) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
 
-	// Calculate total and idle time
-	var total, idle uint64
-	for i := 1; i < len(fields); i++ {
-		val, err := strconv.ParseUint(fields[i], 10, 64)
-		if err != nil {
-			return 0, fmt.Errorf("failed to parse CPU field: %w", err)
-		}
-		total += val
-		if i == 4 { // idle is the 4th field (index 4)
-			idle = val
-		}
+	metrics := &HostMetrics{
+		Timestamp: now.Unix(),
 	}
 
-	// Calculate percentage from delta if we have previous stats
-	var cpuPercent float64
-	if lastCPUStats != nil {
-		totalDelta := total - lastCPUStats.total
-		idleDelta := idle - lastCPUStats.idle
+	// Collect CPU usage
+	if err := hc.collectCPU(metrics); err != nil {
+		return nil, fmt.Errorf("CPU collection failed: %w", err)
+	}
 
-		if totalDelta > 0 {
-			cpuPercent = float64(totalDelta-idleDelta) / float64(totalDelta) * 100.0
-		}
-	} else {
-		// First run, return 0% (need two samples for accurate percentage)
-		cpuPercent = 0.0
+	// Collect memory and swap usage
+	if err := hc.collectMemory(metrics); err != nil {
+		return nil, fmt.Errorf("memory collection failed: %w", err)
 	}
 
-	// Store current stats for next calculation
-	lastCPUStats = &cpuStats{
-		total: total,
-		idle:  idle,
+	// Load, network and pressure are best effort (PSI needs Linux 4.20+ with CONFIG_PSI)
+	if err := hc.collectLoad(metrics); err != nil {
+		metricsLogger.Debugf("Load average unavailable: %v", err)
+	}
+	if err := hc.collectNetwork(metrics, now); err != nil {
+		metricsLogger.Debugf("Network counters unavailable: %v", err)
 	}
+	metrics.Pressure = hc.collectPressure()
 
-	return cpuPercent, nil
+	hc.prevTime = now
+	return metrics, nil
 }
 
-// getMemoryUsage parses /proc/meminfo to calculate memory usage
-func getMemoryUsage() (usedMB, totalMB int64, err error) {
-	file, err := os.Open("/proc/meminfo")
+// collectCPU parses /proc/stat into overall and per-core busy percent, iowait and steal
+func (hc *HostCollector) collectCPU(metrics *HostMetrics) error {
+	file, err := os.Open(filepath.Join(hc.procRoot, "stat"))
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to open /proc/meminfo: %w", err)
+		return fmt.Errorf("failed to open /proc/stat: %w", err)
 	}
 	defer file.Close()
 
-	var memTotal, memAvailable uint64
-	foundTotal, foundAvailable := false, false
-
+	found := false
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		fields := strings.Fields(line)
-		if len(fields) < 2 {
+		// Parse CPU lines: cpu[N]  user nice system idle iowait irq softirq steal guest guest_nice
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
 			continue
 		}
 
-		switch fields[0] {
-		case "MemTotal:":
-			memTotal, err = strconv.ParseUint(fields[1], 10, 64)
-			if err != nil {
-				return 0, 0, fmt.Errorf("failed to parse MemTotal: %w", err)
-			}
-			foundTotal = true
-		case "MemAvailable:":
-			memAvailable, err = strconv.ParseUint(fields[1], 10, 64)
+		var values [8]uint64
+		for i := 1; i < len(fields) && i <= len(values); i++ {
+			values[i-1], err = strconv.ParseUint(fields[i], 10, 64)
 			if err != nil {
-				return 0, 0, fmt.Errorf("failed to parse MemAvailable: %w", err)
+				return fmt.Errorf("failed to parse CPU field: %w", err)
 			}
-			foundAvailable = true
 		}
-
-		if foundTotal && foundAvailable {
-			break
+		cur := cpuTimes{values[0], values[1], values[2], values[3], values[4], values[5], values[6], values[7]}
+
+		// Percentages need two samples; the first collection reports 0
+		var busy, iowait, steal float64
+		if prev, ok := hc.prevCPU[fields[0]]; ok && cur.total() > prev.total() {
+			totalDelta := float64(cur.total() - prev.total())
+			busy = float64(cur.total()-prev.total()-(cur.idle-prev.idle)) / totalDelta * 100.0
+			iowait = float64(cur.iowait-prev.iowait) / totalDelta * 100.0
+			steal = float64(cur.steal-prev.steal) / totalDelta * 100.0
+		}
+		hc.prevCPU[fields[0]] = cur
+
+		if fields[0] == "cpu" {
+			found = true
+			metrics.CPUPercent = busy
+			metrics.IOWaitPercent = iowait
+			metrics.StealPercent = steal
+		} else {
+			metrics.CPUPerCore = append(metrics.CPUPerCore, busy)
 		}
 	}
-
 	if err := scanner.Err(); err != nil {
-		return 0, 0, fmt.Errorf("error reading /proc/meminfo: %w", err)
+		return fmt.Errorf("error reading /proc/stat: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("invalid /proc/stat format")
+	}
+
+	metrics.CPUCount = len(metrics.CPUPerCore)
+	return nil
+}
+
+// collectMemory parses /proc/meminfo for memory and swap usage
+func (hc *HostCollector) collectMemory(metrics *HostMetrics) error {
+	info, err := readKeyValueFile(filepath.Join(hc.procRoot, "meminfo"))
+	if err != nil {
+		return fmt.Errorf("failed to read /proc/meminfo: %w", err)
 	}
 
+	memTotal, foundTotal := info["MemTotal:"]
+	memAvailable, foundAvailable := info["MemAvailable:"]
 	if !foundTotal || !foundAvailable {
-		return 0, 0, fmt.Errorf("missing required memory fields in /proc/meminfo")
+		return fmt.Errorf("missing required memory fields in /proc/meminfo")
 	}
 
 	// Convert from KB to MB
-	totalMB = int64(memTotal / 1024)
-	usedMB = int64((memTotal - memAvailable) / 1024)
+	metrics.MemoryTotalMB = int64(memTotal / 1024)
+	metrics.MemoryUsedMB = int64((memTotal - memAvailable) / 1024)
+	metrics.SwapTotalMB = int64(info["SwapTotal:"] / 1024)
+	if info["SwapTotal:"] >= info["SwapFree:"] {
+		metrics.SwapUsedMB = int64((info["SwapTotal:"] - info["SwapFree:"]) / 1024)
+	}
+	return nil
+}
 
-	return usedMB, totalMB, nil
+// collectLoad parses /proc/loadavg
+func (hc *HostCollector) collectLoad(metrics *HostMetrics) error {
+	data, err := os.ReadFile(filepath.Join(hc.procRoot, "loadavg"))
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return fmt.Errorf("invalid /proc/loadavg format")
+	}
+	loads := make([]float64, 3)
+	for i := range loads {
+		if loads[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return fmt.Errorf("invalid /proc/loadavg format: %w", err)
+		}
+	}
+	metrics.Load1, metrics.Load5, metrics.Load15 = loads[0], loads[1], loads[2]
+	return nil
+}
+
+// collectNetwork parses /proc/net/dev into per-interface throughput. Loopback and container
+// networking (veth ends and the Docker bridges, whose traffic also crosses the physical NIC) are
+// skipped; container traffic is reported per server instead.
+func (hc *HostCollector) collectNetwork(metrics *HostMetrics, now time.Time) error {
+	data, err := os.ReadFile(hc.hostNamespaced("net", "dev"))
+	if err != nil {
+		return err
+	}
+
+	elapsed := now.Sub(hc.prevTime).Seconds()
+	seen := make(map[string]netCounters)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, counters, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || isVirtualInterface(name) {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		rx, err1 := strconv.ParseUint(fields[0], 10, 64)
+		tx, err2 := strconv.ParseUint(fields[8], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		iface := NetInterfaceMetric{Name: name, RxBytes: rx, TxBytes: tx}
+		if prev, ok := hc.prevNet[name]; ok && !hc.prevTime.IsZero() && elapsed > 0 {
+			iface.RxBytesPerSec = counterRate(prev.rx, rx, elapsed)
+			iface.TxBytesPerSec = counterRate(prev.tx, tx, elapsed)
+		}
+		seen[name] = netCounters{rx: rx, tx: tx}
+		metrics.Network = append(metrics.Network, iface)
+	}
+	hc.prevNet = seen
+
+	sort.Slice(metrics.Network, func(i, j int) bool { return metrics.Network[i].Name < metrics.Network[j].Name })
+	return nil
+}
+
+// isVirtualInterface reports loopback and the interfaces Docker creates for container networks
+func isVirtualInterface(name string) bool {
+	return name == "lo" || name == "docker0" || name == "docker_gwbridge" ||
+		strings.HasPrefix(name, "veth") || strings.HasPrefix(name, "br-")
+}
+
+// collectPressure reads /proc/pressure/{cpu,memory,io}, or returns nil if PSI is unavailable
+func (hc *HostCollector) collectPressure() *PressureMetrics {
+	var pressure PressureMetrics
+	for _, res := range []struct {
+		name string
+		dst  *PressureStall
+	}{{"cpu", &pressure.CPU}, {"memory", &pressure.Memory}, {"io", &pressure.IO}} {
+		data, err := os.ReadFile(filepath.Join(hc.procRoot, "pressure", res.name))
+		if err != nil {
+			return nil
+		}
+		*res.dst = parsePressure(string(data))
+	}
+	return &pressure
+}
+
+// parsePressure parses a PSI file:
+//
+//	some avg10=0.12 avg60=0.05 avg300=0.01 total=123456
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(data string) PressureStall {
+	var stall PressureStall
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			v, _ := strconv.ParseFloat(value, 64)
+			switch fields[0] + "." + key {
+			case "some.avg10":
+				stall.SomeAvg10 = v
+			case "some.avg60":
+				stall.SomeAvg60 = v
+			case "some.avg300":
+				stall.SomeAvg300 = v
+			case "some.total":
+				stall.SomeTotalUs, _ = strconv.ParseUint(value, 10, 64)
+			case "full.avg10":
+				stall.FullAvg10 = v
+			case "full.avg60":
+				stall.FullAvg60 = v
+			case "full.avg300":
+				stall.FullAvg300 = v
+			case "full.total":
+				stall.FullTotalUs, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+	}
+	return stall
 }
 
 // getDiskUsage uses syscall.Statfs to get disk usage for a given path
@@ -260,8 +487,8 @@ type mountInfo struct {
 }
 
 // parseMounts reads /proc/mounts and returns a list of mount points
-func parseMounts() ([]mountInfo, error) {
-	file, err := os.Open("/proc/mounts")
+func parseMounts(path string) ([]mountInfo, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open /proc/mounts: %w", err)
 	}
@@ -317,29 +544,12 @@ func findMountForPath(path string, mounts []mountInfo) *mountInfo {
 }
 
 // collectDiskMetrics discovers unique filesystems used by container bind mounts
-func collectDiskMetrics(dc *DockerClient) ([]DiskMetric, error) {
+func (hc *HostCollector) collectDiskMetrics() ([]DiskMetric, error) {
 	// Parse system mounts
-	mounts, err := parseMounts()
-	if err != nil {
-		// Fallback to root if we can't parse mounts
-		metric, _, err := getDiskMetricWithDeviceID("/", "Root")
-		if err != nil {
-			return nil, err
-		}
-		return []DiskMetric{*metric}, nil
-	}
-
-	// Track unique devices
-	seenDevices := make(map[string]bool)
-	var metrics []DiskMetric
-
-	// If no DockerClient, fallback to root filesystem only
-	if dc == nil {
-		metric, _, err := getDiskMetricWithDeviceID("/", "Root")
-		if err != nil {
-			return nil, err
-		}
-		return []DiskMetric{*metric}, nil
+	mounts, err := parseMounts(hc.hostNamespaced("mounts"))
+	if err != nil || hc.docker == nil {
+		// Fallback to root if we can't parse mounts or have no DockerClient
+		return hc.rootDiskMetrics()
 	}
 
 	// List all ZedOps-managed containers
@@ -347,64 +557,77 @@ func collectDiskMetrics(dc *DockerClient) ([]DiskMetric, error) {
 	containerFilters := filters.NewArgs()
 	containerFilters.Add("label", "zedops.managed=true")
 
-	containers, err := dc.cli.ContainerList(ctx, container.ListOptions{
+	containers, err := hc.docker.cli.ContainerList(ctx, container.ListOptions{
 		All:     true, // Include stopped containers
 		Filters: containerFilters,
 	})
 	if err != nil {
 		// Fallback to root if we can't list containers
-		metric, _, err := getDiskMetricWithDeviceID("/", "Root")
-		if err != nil {
-			return nil, err
-		}
-		return []DiskMetric{*metric}, nil
+		return hc.rootDiskMetrics()
 	}
 
 	// Collect ALL bind mount source paths from each container
+	var sources []string
 	for _, c := range containers {
-		inspect, err := dc.cli.ContainerInspect(ctx, c.ID)
+		inspect, err := hc.docker.cli.ContainerInspect(ctx, c.ID)
 		if err != nil {
 			continue // Skip containers we can't inspect
 		}
-
 		for _, mount := range inspect.Mounts {
-			if mount.Type != "bind" {
-				continue
+			if mount.Type == "bind" {
+				sources = append(sources, mount.Source)
 			}
+		}
+	}
+	return hc.diskMetricsForPaths(sources, mounts)
+}
 
-			// Find which filesystem this path belongs to
-			mountInfo := findMountForPath(mount.Source, mounts)
-			if mountInfo == nil {
-				continue
-			}
+// diskMetricsForPaths reports each filesystem holding one of the host paths once
+func (hc *HostCollector) diskMetricsForPaths(paths []string, mounts []mountInfo) ([]DiskMetric, error) {
+	// Track unique devices
+	seenDevices := make(map[string]bool)
+	var metrics []DiskMetric
 
-			// Skip if we've already seen this device
-			if seenDevices[mountInfo.Device] {
-				continue
-			}
-			seenDevices[mountInfo.Device] = true
+	for _, path := range paths {
+		// Find which filesystem this path belongs to
+		mountInfo := findMountForPath(path, mounts)
+		if mountInfo == nil {
+			continue
+		}
 
-			// Get disk metrics for this mount point
-			metric, _, err := getDiskMetricWithDeviceID(mountInfo.MountPoint, mountInfo.MountPoint)
-			if err != nil {
-				continue
-			}
+		// Skip if we've already seen this device
+		if seenDevices[mountInfo.Device] {
+			continue
+		}
+		seenDevices[mountInfo.Device] = true
 
-			// Use mount point as both path and label
-			metric.Path = mountInfo.MountPoint
-			metric.Label = mountInfo.MountPoint
-			metrics = append(metrics, *metric)
+		// Get disk metrics for this mount point
+		metric, _, err := hc.statfs(hc.hostPath(mountInfo.MountPoint), mountInfo.MountPoint)
+		if err != nil {
+			continue
 		}
+
+		// Use mount point as both path and label
+		metric.Path = mountInfo.MountPoint
+		metric.MountPoint = mountInfo.MountPoint
+		metric.Label = mountInfo.MountPoint
+		metrics = append(metrics, *metric)
 	}
 
 	// If no volumes found from containers, fallback to root
 	if len(metrics) == 0 {
-		metric, _, err := getDiskMetricWithDeviceID("/", "Root")
-		if err != nil {
-			return nil, err
-		}
-		return []DiskMetric{*metric}, nil
+		return hc.rootDiskMetrics()
 	}
-
 	return metrics, nil
 }
+
+// rootDiskMetrics reports the host's root filesystem
+func (hc *HostCollector) rootDiskMetrics() ([]DiskMetric, error) {
+	metric, _, err := hc.statfs(hc.hostPath("/"), "Root")
+	if err != nil {
+		return nil, err
+	}
+	metric.Path = "/"
+	metric.MountPoint = "/"
+	return []DiskMetric{*metric}, nil
+}
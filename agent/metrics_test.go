@@ -0,0 +1,169 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// hostProcFiles is the host's proc mounted into the agent's container. The top-level mounts and
+// net/dev are the agent's own namespace; the host's are under PID 1.
+func hostProcFiles(stat, netDev string) map[string]string {
+	return map[string]string{
+		"stat": stat,
+		"meminfo": "MemTotal:       16384000 kB\nMemFree:         1024000 kB\nMemAvailable:    8192000 kB\n" +
+			"SwapTotal:       2097152 kB\nSwapFree:        1048576 kB\n",
+		"loadavg":         "0.52 0.48 0.40 2/345 6789\n",
+		"pressure/cpu":    "some avg10=1.50 avg60=0.75 avg300=0.20 total=123456\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"pressure/memory": "some avg10=0.00 avg60=0.00 avg300=0.00 total=10\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=5\n",
+		"pressure/io":     "some avg10=4.00 avg60=2.00 avg300=1.00 total=999\nfull avg10=3.00 avg60=1.50 avg300=0.50 total=888\n",
+		"net/dev":         "  eth0: 5 0 0 0 0 0 0 0 5 0 0 0 0 0 0 0\n",
+		"mounts":          "overlay / overlay rw 0 0\n",
+		"1/net/dev":       netDev,
+		"1/mounts": "/dev/sda1 / ext4 rw,relatime 0 0\nproc /proc proc rw 0 0\n/dev/sdb1 /data ext4 rw 0 0\n" +
+			"/dev/sdc1 /data/backups xfs rw 0 0\ntmpfs /run tmpfs rw 0 0\n",
+	}
+}
+
+const (
+	testStat1 = "cpu  1000 0 500 8000 300 0 100 100 0 0\n" +
+		"cpu0 500 0 250 4000 150 0 50 50 0 0\n" +
+		"cpu1 500 0 250 4000 150 0 50 50 0 0\n" +
+		"intr 12345\nctxt 67890\n"
+	testStat2 = "cpu  1600 0 700 8900 400 0 150 250 0 0\n" +
+		"cpu0 900 0 350 4400 200 0 75 75 0 0\n" +
+		"cpu1 700 0 350 4500 200 0 75 175 0 0\n" +
+		"intr 12345\nctxt 67890\n"
+
+	testHostNetDev1 = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 9999999 100 0 0 0 0 0 0 9999999 100 0 0 0 0 0 0
+  eth0: 1000000 1000 0 0 0 0 0 0 2000000 2000 0 0 0 0 0 0
+docker0: 500000 500 0 0 0 0 0 0 400000 400 0 0 0 0 0 0
+br-3f2a9c1d: 600000 600 0 0 0 0 0 0 300000 300 0 0 0 0 0 0
+veth12ab34: 700000 700 0 0 0 0 0 0 200000 200 0 0 0 0 0 0
+`
+	testHostNetDev2 = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 9999999 100 0 0 0 0 0 0 9999999 100 0 0 0 0 0 0
+  eth0: 11000000 1000 0 0 0 0 0 0 7000000 2000 0 0 0 0 0 0
+docker0: 5500000 500 0 0 0 0 0 0 4400000 400 0 0 0 0 0 0
+br-3f2a9c1d: 6600000 600 0 0 0 0 0 0 3300000 300 0 0 0 0 0 0
+veth12ab34: 7700000 700 0 0 0 0 0 0 2200000 200 0 0 0 0 0 0
+`
+)
+
+func TestHostCollectorProc(t *testing.T) {
+	procRoot := t.TempDir()
+	writeTree(t, procRoot, hostProcFiles(testStat1, testHostNetDev1))
+	hc := NewHostCollector(procRoot, nil)
+
+	start := time.Unix(1772366400, 0)
+	first, err := hc.collectProc(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rates need two samples
+	if first.CPUPercent != 0 || len(first.Network) != 1 || first.Network[0].RxBytesPerSec != 0 {
+		t.Errorf("first sample = %+v", first)
+	}
+
+	writeTree(t, procRoot, hostProcFiles(testStat2, testHostNetDev2))
+	m, err := hc.collectProc(start.Add(10 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []struct {
+		name      string
+		got, want float64
+	}{
+		{"cpu", m.CPUPercent, 55},
+		{"iowait", m.IOWaitPercent, 5},
+		{"steal", m.StealPercent, 7.5},
+		{"load1", m.Load1, 0.52},
+		{"load15", m.Load15, 0.40},
+		{"cpu pressure", m.Pressure.CPU.SomeAvg10, 1.5},
+		{"io full pressure", m.Pressure.IO.FullAvg60, 1.5},
+	}
+	for _, v := range values {
+		if math.Abs(v.got-v.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", v.name, v.got, v.want)
+		}
+	}
+	if m.CPUCount != 2 || !reflect.DeepEqual(m.CPUPerCore, []float64{60, 50}) {
+		t.Errorf("cores = %d %v, want 2 [60 50]", m.CPUCount, m.CPUPerCore)
+	}
+	if m.MemoryTotalMB != 16000 || m.MemoryUsedMB != 8000 || m.SwapTotalMB != 2048 || m.SwapUsedMB != 1024 {
+		t.Errorf("memory = %d/%d swap %d/%d", m.MemoryUsedMB, m.MemoryTotalMB, m.SwapUsedMB, m.SwapTotalMB)
+	}
+
+	// Only the physical NIC of the host namespace: bridge and veth traffic also crosses eth0
+	want := []NetInterfaceMetric{{Name: "eth0", RxBytes: 11000000, TxBytes: 7000000, RxBytesPerSec: 1000000, TxBytesPerSec: 500000}}
+	if !reflect.DeepEqual(m.Network, want) {
+		t.Errorf("network = %+v, want %+v", m.Network, want)
+	}
+}
+
+func TestHostCollectorDisksThroughHostRoot(t *testing.T) {
+	procRoot := t.TempDir()
+	writeTree(t, procRoot, hostProcFiles(testStat1, testHostNetDev1))
+
+	var statted []string
+	hc := NewHostCollector(procRoot, nil)
+	hc.statfs = func(path, label string) (*DiskMetric, string, error) {
+		statted = append(statted, path)
+		return &DiskMetric{Path: path, Label: label, UsedGB: 40, TotalGB: 100, Percent: 40}, path, nil
+	}
+
+	mounts, err := parseMounts(hc.hostNamespaced("mounts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := []string{"/data/servers/survival/bin", "/data/servers/survival/data", "/data/backups/survival", "/opt/zedops"}
+	disks, err := hc.diskMetricsForPaths(sources, mounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantStatted := []string{
+		filepath.Join(procRoot, "1", "root", "data"),
+		filepath.Join(procRoot, "1", "root", "data", "backups"),
+		filepath.Join(procRoot, "1", "root"),
+	}
+	if !reflect.DeepEqual(statted, wantStatted) {
+		t.Errorf("statted %v, want %v", statted, wantStatted)
+	}
+	var mountPoints []string
+	for _, d := range disks {
+		mountPoints = append(mountPoints, d.MountPoint)
+		if d.Path != d.MountPoint {
+			t.Errorf("disk path %q, want the host mount point %q", d.Path, d.MountPoint)
+		}
+	}
+	if !reflect.DeepEqual(mountPoints, []string{"/data", "/data/backups", "/"}) {
+		t.Errorf("mount points = %v", mountPoints)
+	}
+
+	// Without Docker, only the host's root filesystem
+	statted = nil
+	disks, err = hc.collectDiskMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(disks) != 1 || disks[0].MountPoint != "/" || len(statted) != 1 || statted[0] != filepath.Join(procRoot, "1", "root") {
+		t.Errorf("disks = %+v statted %v, want the host root", disks, statted)
+	}
+}
+
+func TestHostCollectorDefaultProcUsesOwnNamespace(t *testing.T) {
+	hc := NewHostCollector("/proc", nil)
+	if got := hc.hostPath("/data"); got != "/data" {
+		t.Errorf("hostPath = %q, want /data", got)
+	}
+	if got := hc.hostNamespaced("net", "dev"); got != "/proc/net/dev" {
+		t.Errorf("hostNamespaced = %q, want /proc/net/dev", got)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"log"
 	"sync"
 	"time"
 
@@ -19,6 +18,22 @@ type ServerMetricsPoint struct {
 	MemoryUsedMB  int64   `json:"memoryUsedMB"`
 	MemoryLimitMB int64   `json:"memoryLimitMB"`
 	PlayerCount   *int    `json:"playerCount,omitempty"` // nil if not available
+
+	MemoryRSSMB          int64   `json:"memoryRssMB"`
+	MemoryCacheMB        int64   `json:"memoryCacheMB"`
+	NetRxBytesPerSec     float64 `json:"netRxBytesPerSec"`
+	NetTxBytesPerSec     float64 `json:"netTxBytesPerSec"`
+	DiskReadBytesPerSec  float64 `json:"diskReadBytesPerSec"`
+	DiskWriteBytesPerSec float64 `json:"diskWriteBytesPerSec"`
+	PIDs                 uint64  `json:"pids"`
+	CPUThrottledPercent  float64 `json:"cpuThrottledPercent"`
+}
+
+// CollectedServerMetrics is the last sample of one server as kept by the collector
+type CollectedServerMetrics struct {
+	ServerMetricsPoint
+	ServerName string
+	Container  *ContainerMetrics
 }
 
 // MetricsBatch represents a batch of metrics to send to manager
@@ -34,6 +49,7 @@ type MetricsCollector struct {
 	agent        *Agent
 	stopCh       chan struct{}
 	pollInterval time.Duration
+	latest       []CollectedServerMetrics // Last collected samples, also kept while disconnected
 }
 
 // NewMetricsCollector creates a new metrics collector
@@ -48,13 +64,13 @@ func NewMetricsCollector(docker *DockerClient, agent *Agent) *MetricsCollector {
 
 // Start begins the background collection loop
 func (mc *MetricsCollector) Start() {
-	log.Println("[MetricsCollector] Starting metrics collector (10s interval)")
+	metricsLogger.Infof("Starting metrics collector (10s interval)")
 	go mc.collectLoop()
 }
 
 // Stop stops the collector
 func (mc *MetricsCollector) Stop() {
-	log.Println("[MetricsCollector] Stopping metrics collector")
+	metricsLogger.Infof("Stopping metrics collector")
 	close(mc.stopCh)
 }
 
@@ -79,12 +95,17 @@ func (mc *MetricsCollector) collectLoop() {
 	}
 }
 
-// collectAndSend collects metrics from all running containers and sends to manager
+// Latest returns the most recently collected points
+func (mc *MetricsCollector) Latest() []CollectedServerMetrics {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return append([]CollectedServerMetrics{}, mc.latest...)
+}
+
+// collectAndSend collects metrics from all running containers and sends to manager.
+// Metrics are collected even when not authenticated so the local exporter stays current.
 func (mc *MetricsCollector) collectAndSend() {
-	// Don't send metrics if not authenticated
-	if !mc.agent.IsAuthenticated() {
-		return
-	}
+	defer recordCollectorRun("metrics", time.Now())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
 	defer cancel()
@@ -98,19 +119,26 @@ func (mc *MetricsCollector) collectAndSend() {
 		Filters: filterArgs,
 	})
 	if err != nil {
-		log.Printf("[MetricsCollector] Failed to list containers: %v", err)
+		metricsLogger.Errorf("Failed to list containers: %v", err)
 		return
 	}
 
 	if len(containers) == 0 {
 		// No running containers, nothing to collect
-		log.Printf("[MetricsCollector] No running ZedOps-managed containers found")
+		mc.mu.Lock()
+		mc.latest = nil
+		mc.mu.Unlock()
+		if mc.agent.metricsStore != nil {
+			mc.agent.metricsStore.Record(nil, time.Now()) // Close buckets of stopped servers
+		}
+		metricsLogger.Infof("No running ZedOps-managed containers found")
 		return
 	}
-	log.Printf("[MetricsCollector] Found %d running containers", len(containers))
+	metricsLogger.Infof("Found %d running containers", len(containers))
 
 	// Collect metrics for each container
 	var points []ServerMetricsPoint
+	var collected []CollectedServerMetrics
 	now := time.Now().Unix()
 
 	// Get player stats for cross-reference
@@ -129,7 +157,7 @@ func (mc *MetricsCollector) collectAndSend() {
 		// Collect container metrics
 		metrics, err := mc.docker.CollectContainerMetrics(ctx, c.ID)
 		if err != nil {
-			log.Printf("[MetricsCollector] Failed to collect metrics for %s: %v", c.ID[:12], err)
+			metricsLogger.Errorf("Failed to collect metrics for %s: %v", c.ID[:12], err)
 			continue
 		}
 
@@ -146,6 +174,15 @@ func (mc *MetricsCollector) collectAndSend() {
 			MemoryPercent: memoryPercent,
 			MemoryUsedMB:  metrics.MemoryUsedMB,
 			MemoryLimitMB: metrics.MemoryLimitMB,
+
+			MemoryRSSMB:          metrics.MemoryRSSMB,
+			MemoryCacheMB:        metrics.MemoryCacheMB,
+			NetRxBytesPerSec:     metrics.NetRxBytesPerSec,
+			NetTxBytesPerSec:     metrics.NetTxBytesPerSec,
+			DiskReadBytesPerSec:  metrics.DiskReadBytesPerSec,
+			DiskWriteBytesPerSec: metrics.DiskWriteBytesPerSec,
+			PIDs:                 metrics.PIDs,
+			CPUThrottledPercent:  metrics.CPUThrottledPercent,
 		}
 
 		// Add player count if available
@@ -154,12 +191,31 @@ func (mc *MetricsCollector) collectAndSend() {
 		}
 
 		points = append(points, point)
+		collected = append(collected, CollectedServerMetrics{
+			ServerMetricsPoint: point,
+			ServerName:         c.Labels["zedops.server.name"],
+			Container:          metrics,
+		})
+	}
+
+	mc.mu.Lock()
+	mc.latest = collected
+	mc.mu.Unlock()
+
+	// Keep history even while disconnected so the manager can backfill the gap
+	if mc.agent.metricsStore != nil {
+		mc.agent.metricsStore.Record(points, time.Now())
 	}
 
 	if len(points) == 0 {
 		return // No metrics collected
 	}
 
+	// Don't send metrics if not authenticated
+	if !mc.agent.IsAuthenticated() {
+		return
+	}
+
 	// Send batch to manager
 	batch := MetricsBatch{
 		AgentID: mc.agent.agentID,
@@ -167,9 +223,9 @@ func (mc *MetricsCollector) collectAndSend() {
 	}
 
 	if err := mc.sendMetricsBatch(batch); err != nil {
-		log.Printf("[MetricsCollector] Failed to send metrics batch: %v", err)
+		metricsLogger.Errorf("Failed to send metrics batch: %v", err)
 	} else {
-		log.Printf("[MetricsCollector] Sent batch: %d servers", len(points))
+		metricsLogger.Infof("Sent batch: %d servers", len(points))
 	}
 }
 
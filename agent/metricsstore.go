@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	metricsStoreDirName        = "metrics"
+	metricsStoreRetentionEvery = 10 * time.Minute
+
+	metricsQueryDefaultLimit = 1000
+	metricsQueryMaxLimit     = 3000 // Keeps a reply page well under the manager's WebSocket message limit
+)
+
+// metricsTier is one resolution kept by the store. Segment files cover a fixed, aligned time window
+// so range queries and retention can skip whole files.
+type metricsTier struct {
+	Name       string
+	Step       int64 // Seconds per point (raw points arrive every collector interval)
+	Segment    int64 // Seconds covered by one segment file
+	Retention  time.Duration
+	Aggregated bool
+}
+
+// metricsTiers in order of resolution. When the store is over its size cap the oldest raw segments
+// go first, then 1m, then 15m.
+var metricsTiers = []metricsTier{
+	{Name: "raw", Step: 10, Segment: 3600, Retention: 24 * time.Hour},
+	{Name: "1m", Step: 60, Segment: 6 * 3600, Retention: 30 * 24 * time.Hour, Aggregated: true},
+	{Name: "15m", Step: 900, Segment: 24 * 3600, Retention: 30 * 24 * time.Hour, Aggregated: true},
+}
+
+// MetricRange summarises one field over an aggregation bucket
+type MetricRange struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+// MetricsAggregate is one downsampled bucket of a server's metrics
+type MetricsAggregate struct {
+	ServerID      string       `json:"serverId"`
+	Timestamp     int64        `json:"timestamp"` // Bucket start (Unix seconds)
+	Count         int          `json:"count"`     // Raw points in the bucket
+	CPUPercent    MetricRange  `json:"cpuPercent"`
+	MemoryPercent MetricRange  `json:"memoryPercent"`
+	MemoryUsedMB  MetricRange  `json:"memoryUsedMB"`
+	MemoryLimitMB int64        `json:"memoryLimitMB"`
+	PlayerCount   *MetricRange `json:"playerCount,omitempty"` // nil if no point had a player count
+}
+
+// MetricsStoreConfig controls the on-disk size of the store
+type MetricsStoreConfig struct {
+	MaxBytes int64 // Size cap across all tiers (0 = unlimited)
+}
+
+// metricsBucket accumulates raw points into one aggregate
+type metricsBucket struct {
+	agg         MetricsAggregate
+	playerCount int // Points that carried a player count
+}
+
+// metricsSegmentWriter appends to the segment file currently being written in one tier
+type metricsSegmentWriter struct {
+	start  int64
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// MetricsStore keeps server metrics history on disk: raw points for a day, and min/avg/max
+// downsamples for a month, so the manager can fill gaps and chart ranges it doesn't hold
+type MetricsStore struct {
+	mu      sync.Mutex
+	root    string
+	config  MetricsStoreConfig
+	buckets map[string]map[string]*metricsBucket // tier -> serverID -> open bucket
+	writers map[string]*metricsSegmentWriter     // tier -> active segment
+	stopCh  chan struct{}
+}
+
+// NewMetricsStore creates a metrics store rooted in the agent state directory
+func NewMetricsStore(config MetricsStoreConfig) *MetricsStore {
+	return newMetricsStoreAt(filepath.Join(StateDir(), metricsStoreDirName), config)
+}
+
+func newMetricsStoreAt(root string, config MetricsStoreConfig) *MetricsStore {
+	ms := &MetricsStore{
+		root:    root,
+		config:  config,
+		buckets: make(map[string]map[string]*metricsBucket),
+		writers: make(map[string]*metricsSegmentWriter),
+		stopCh:  make(chan struct{}),
+	}
+	for _, tier := range metricsTiers {
+		ms.buckets[tier.Name] = make(map[string]*metricsBucket)
+	}
+	return ms
+}
+
+// Start begins periodic retention
+func (ms *MetricsStore) Start() {
+	metricsLogger.Infof("Starting metrics history (dir=%s, maxBytes=%d)", ms.root, ms.config.MaxBytes)
+	go ms.loop()
+}
+
+// Stop writes partially filled buckets and closes segment files. Buckets written again after a
+// restart are merged at query time.
+func (ms *MetricsStore) Stop() {
+	metricsLogger.Infof("Stopping metrics history")
+	close(ms.stopCh)
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, tier := range metricsTiers {
+		for serverID, b := range ms.buckets[tier.Name] {
+			ms.writeLocked(tier, b.agg.Timestamp, b.finish())
+			delete(ms.buckets[tier.Name], serverID)
+		}
+	}
+	for name, w := range ms.writers {
+		w.close()
+		delete(ms.writers, name)
+	}
+}
+
+func (ms *MetricsStore) loop() {
+	ms.enforceRetention()
+
+	ticker := time.NewTicker(metricsStoreRetentionEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ms.stopCh:
+			return
+		case <-ticker.C:
+			ms.enforceRetention()
+		}
+	}
+}
+
+// Record stores one collector pass. Buckets that have ended, including those of servers that
+// stopped reporting, are written out.
+func (ms *MetricsStore) Record(points []ServerMetricsPoint, now time.Time) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, tier := range metricsTiers {
+		buckets := ms.buckets[tier.Name]
+		if !tier.Aggregated {
+			for _, p := range points {
+				ms.writeLocked(tier, p.Timestamp, p)
+			}
+			continue
+		}
+
+		for _, p := range points {
+			start := p.Timestamp - p.Timestamp%tier.Step
+			b := buckets[p.ServerID]
+			if b != nil && b.agg.Timestamp != start {
+				ms.writeLocked(tier, b.agg.Timestamp, b.finish())
+				b = nil
+			}
+			if b == nil {
+				b = newMetricsBucket(p.ServerID, start)
+				buckets[p.ServerID] = b
+			}
+			b.add(p)
+		}
+		for serverID, b := range buckets {
+			if b.agg.Timestamp+tier.Step <= now.Unix() {
+				ms.writeLocked(tier, b.agg.Timestamp, b.finish())
+				delete(buckets, serverID)
+			}
+		}
+	}
+
+	for _, w := range ms.writers {
+		if err := w.writer.Flush(); err != nil {
+			metricsLogger.Warnf("Failed to flush metrics history: %v", err)
+		}
+	}
+}
+
+// writeLocked appends a record to the tier segment holding ts. Must be called with ms.mu held.
+func (ms *MetricsStore) writeLocked(tier metricsTier, ts int64, record interface{}) {
+	start := ts - ts%tier.Segment
+	w := ms.writers[tier.Name]
+	if w == nil || w.start != start {
+		if w != nil {
+			w.close()
+		}
+		dir := filepath.Join(ms.root, tier.Name)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			metricsLogger.Warnf("Failed to create %s: %v", dir, err)
+			delete(ms.writers, tier.Name)
+			return
+		}
+		f, err := os.OpenFile(filepath.Join(dir, metricsSegmentName(start)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			metricsLogger.Warnf("Failed to open metrics segment: %v", err)
+			delete(ms.writers, tier.Name)
+			return
+		}
+		w = &metricsSegmentWriter{start: start, file: f, writer: bufio.NewWriter(f)}
+		ms.writers[tier.Name] = w
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	w.writer.Write(append(data, '\n'))
+}
+
+func (w *metricsSegmentWriter) close() {
+	w.writer.Flush()
+	w.file.Close()
+}
+
+func newMetricsBucket(serverID string, start int64) *metricsBucket {
+	return &metricsBucket{agg: MetricsAggregate{
+		ServerID:      serverID,
+		Timestamp:     start,
+		CPUPercent:    MetricRange{Min: math.Inf(1), Max: math.Inf(-1)},
+		MemoryPercent: MetricRange{Min: math.Inf(1), Max: math.Inf(-1)},
+		MemoryUsedMB:  MetricRange{Min: math.Inf(1), Max: math.Inf(-1)},
+	}}
+}
+
+// add folds a raw point in; Avg holds the running sum until finish
+func (b *metricsBucket) add(p ServerMetricsPoint) {
+	b.agg.Count++
+	b.agg.CPUPercent.observe(p.CPUPercent)
+	b.agg.MemoryPercent.observe(p.MemoryPercent)
+	b.agg.MemoryUsedMB.observe(float64(p.MemoryUsedMB))
+	b.agg.MemoryLimitMB = p.MemoryLimitMB
+	if p.PlayerCount != nil {
+		if b.agg.PlayerCount == nil {
+			b.agg.PlayerCount = &MetricRange{Min: math.Inf(1), Max: math.Inf(-1)}
+		}
+		b.agg.PlayerCount.observe(float64(*p.PlayerCount))
+		b.playerCount++
+	}
+}
+
+// finish returns the aggregate with sums turned into averages
+func (b *metricsBucket) finish() MetricsAggregate {
+	agg := b.agg
+	n := float64(agg.Count)
+	agg.CPUPercent.Avg /= n
+	agg.MemoryPercent.Avg /= n
+	agg.MemoryUsedMB.Avg /= n
+	if agg.PlayerCount != nil {
+		players := *agg.PlayerCount
+		players.Avg /= float64(b.playerCount)
+		agg.PlayerCount = &players
+	}
+	return agg
+}
+
+func (r *MetricRange) observe(v float64) {
+	r.Min = math.Min(r.Min, v)
+	r.Max = math.Max(r.Max, v)
+	r.Avg += v
+}
+
+// mergeMetricRange combines two ranges weighted by their point counts
+func mergeMetricRange(a MetricRange, an int, b MetricRange, bn int) MetricRange {
+	return MetricRange{
+		Min: math.Min(a.Min, b.Min),
+		Max: math.Max(a.Max, b.Max),
+		Avg: (a.Avg*float64(an) + b.Avg*float64(bn)) / float64(an+bn),
+	}
+}
+
+// mergeMetricsAggregates combines two writes of the same bucket (a partial bucket written at shutdown
+// and the rest after restart)
+func mergeMetricsAggregates(a, b MetricsAggregate) MetricsAggregate {
+	out := a
+	out.Count = a.Count + b.Count
+	out.CPUPercent = mergeMetricRange(a.CPUPercent, a.Count, b.CPUPercent, b.Count)
+	out.MemoryPercent = mergeMetricRange(a.MemoryPercent, a.Count, b.MemoryPercent, b.Count)
+	out.MemoryUsedMB = mergeMetricRange(a.MemoryUsedMB, a.Count, b.MemoryUsedMB, b.Count)
+	out.MemoryLimitMB = b.MemoryLimitMB
+	switch {
+	case a.PlayerCount == nil:
+		out.PlayerCount = b.PlayerCount
+	case b.PlayerCount != nil:
+		players := mergeMetricRange(*a.PlayerCount, a.Count, *b.PlayerCount, b.Count)
+		out.PlayerCount = &players
+	}
+	return out
+}
+
+func metricsSegmentName(start int64) string {
+	return strconv.FormatInt(start, 10) + ".jsonl"
+}
+
+// metricsSegment is one segment file of a tier
+type metricsSegment struct {
+	path  string
+	start int64
+	size  int64
+}
+
+// listMetricsSegments returns a tier's segments, oldest first
+func (ms *MetricsStore) listMetricsSegments(tier metricsTier) ([]metricsSegment, error) {
+	dir := filepath.Join(ms.root, tier.Name)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var segments []metricsSegment
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		start, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), ".jsonl"), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, metricsSegment{path: filepath.Join(dir, e.Name()), start: start, size: info.Size()})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].start < segments[j].start })
+	return segments, nil
+}
+
+// enforceRetention deletes segments past their tier's retention, then the oldest ones over the size cap
+func (ms *MetricsStore) enforceRetention() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	var total int64
+	tierSegments := make([][]metricsSegment, len(metricsTiers))
+	removed := 0
+
+	for i, tier := range metricsTiers {
+		segments, err := ms.listMetricsSegments(tier)
+		if err != nil {
+			metricsLogger.Warnf("Failed to list %s metrics history: %v", tier.Name, err)
+			continue
+		}
+		cutoff := now.Add(-tier.Retention).Unix()
+		var kept []metricsSegment
+		for _, seg := range segments {
+			if seg.start+tier.Segment <= cutoff && ms.removeSegmentLocked(tier, seg) {
+				removed++
+				continue
+			}
+			kept = append(kept, seg)
+			total += seg.size
+		}
+		tierSegments[i] = kept
+	}
+
+	for i, tier := range metricsTiers {
+		for _, seg := range tierSegments[i] {
+			if ms.config.MaxBytes <= 0 || total <= ms.config.MaxBytes {
+				break
+			}
+			if w := ms.writers[tier.Name]; w != nil && w.start == seg.start {
+				break // Never drop the segment being written
+			}
+			if ms.removeSegmentLocked(tier, seg) {
+				total -= seg.size
+				removed++
+			}
+		}
+	}
+
+	if removed > 0 {
+		metricsLogger.Infof("Metrics history retention removed %d segment(s)", removed)
+	}
+}
+
+// removeSegmentLocked deletes a segment file. Must be called with ms.mu held.
+func (ms *MetricsStore) removeSegmentLocked(tier metricsTier, seg metricsSegment) bool {
+	if w := ms.writers[tier.Name]; w != nil && w.start == seg.start {
+		w.close()
+		delete(ms.writers, tier.Name)
+	}
+	if err := os.Remove(seg.path); err != nil {
+		metricsLogger.Warnf("Failed to remove %s: %v", seg.path, err)
+		return false
+	}
+	return true
+}
+
+// MetricsQueryRequest is the metrics.query request
+type MetricsQueryRequest struct {
+	ServerID   string `json:"serverId,omitempty"`   // Empty for all servers
+	From       int64  `json:"from"`                 // Unix seconds (inclusive)
+	To         int64  `json:"to,omitempty"`         // Unix seconds (exclusive, 0 = now)
+	Resolution string `json:"resolution,omitempty"` // raw, 1m, 15m or empty to pick from the range
+	Limit      int    `json:"limit,omitempty"`
+}
+
+// MetricsQueryResponse is the metrics.query response. Raw queries fill Points, downsampled ones Aggregates.
+type MetricsQueryResponse struct {
+	Success    bool                 `json:"success"`
+	Resolution string               `json:"resolution,omitempty"`
+	Step       int64                `json:"step,omitempty"` // Seconds between points
+	Points     []ServerMetricsPoint `json:"points,omitempty"`
+	Aggregates []MetricsAggregate   `json:"aggregates,omitempty"`
+	HasMore    bool                 `json:"hasMore"`
+	NextFrom   int64                `json:"nextFrom,omitempty"` // From of the next page when HasMore
+	Error      string               `json:"error,omitempty"`
+}
+
+// pickMetricsTier chooses the finest tier that still holds the start of the range without
+// returning an unreasonable number of points
+func pickMetricsTier(resolution string, from, to int64, now time.Time) (metricsTier, error) {
+	if resolution != "" {
+		for _, tier := range metricsTiers {
+			if tier.Name == resolution {
+				return tier, nil
+			}
+		}
+		return metricsTier{}, fmt.Errorf("unknown resolution %q", resolution)
+	}
+
+	span := to - from
+	switch {
+	case from >= now.Add(-metricsTiers[0].Retention).Unix() && span <= 6*3600:
+		return metricsTiers[0], nil
+	case span <= 7*24*3600:
+		return metricsTiers[1], nil
+	default:
+		return metricsTiers[2], nil
+	}
+}
+
+// Query returns a tier's records in [From, To), ordered by time then server. Pages end on a
+// timestamp boundary so NextFrom never splits the servers of one collector pass.
+func (ms *MetricsStore) Query(req MetricsQueryRequest, now time.Time) (MetricsQueryResponse, error) {
+	to := req.To
+	if to <= 0 {
+		to = now.Unix() + 1
+	}
+	if req.From >= to {
+		return MetricsQueryResponse{}, fmt.Errorf("from must be before to")
+	}
+	tier, err := pickMetricsTier(req.Resolution, req.From, to, now)
+	if err != nil {
+		return MetricsQueryResponse{}, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = metricsQueryDefaultLimit
+	}
+	if limit > metricsQueryMaxLimit {
+		limit = metricsQueryMaxLimit
+	}
+
+	// Make buffered records visible to the reader
+	ms.mu.Lock()
+	if w := ms.writers[tier.Name]; w != nil {
+		w.writer.Flush()
+	}
+	segments, err := ms.listMetricsSegments(tier)
+	ms.mu.Unlock()
+	if err != nil {
+		return MetricsQueryResponse{}, err
+	}
+
+	resp := MetricsQueryResponse{Success: true, Resolution: tier.Name, Step: tier.Step}
+	count := 0
+	lastTs := int64(-1)
+	for _, seg := range segments {
+		if seg.start+tier.Segment <= req.From || seg.start >= to {
+			continue
+		}
+
+		if tier.Aggregated {
+			aggs, err := readMetricsAggregates(seg.path, req, to)
+			if err != nil {
+				metricsLogger.Warnf("Failed to read %s: %v", seg.path, err)
+				continue
+			}
+			for _, agg := range aggs {
+				if count >= limit && agg.Timestamp != lastTs {
+					resp.HasMore, resp.NextFrom = true, agg.Timestamp
+					return resp, nil
+				}
+				resp.Aggregates = append(resp.Aggregates, agg)
+				count++
+				lastTs = agg.Timestamp
+			}
+		} else {
+			points, err := readMetricsPoints(seg.path, req, to)
+			if err != nil {
+				metricsLogger.Warnf("Failed to read %s: %v", seg.path, err)
+				continue
+			}
+			for _, p := range points {
+				if count >= limit && p.Timestamp != lastTs {
+					resp.HasMore, resp.NextFrom = true, p.Timestamp
+					return resp, nil
+				}
+				resp.Points = append(resp.Points, p)
+				count++
+				lastTs = p.Timestamp
+			}
+		}
+	}
+	return resp, nil
+}
+
+// scanMetricsSegment calls fn with every line of a segment. Unparseable lines (e.g. a partially
+// written last line after a crash) are left to fn's decoder to skip.
+func scanMetricsSegment(path string, fn func([]byte)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fn(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+func readMetricsPoints(path string, req MetricsQueryRequest, to int64) ([]ServerMetricsPoint, error) {
+	var points []ServerMetricsPoint
+	err := scanMetricsSegment(path, func(line []byte) {
+		var p ServerMetricsPoint
+		if json.Unmarshal(line, &p) != nil || p.Timestamp < req.From || p.Timestamp >= to {
+			return
+		}
+		if req.ServerID != "" && p.ServerID != req.ServerID {
+			return
+		}
+		points = append(points, p)
+	})
+	sort.SliceStable(points, func(i, j int) bool {
+		if points[i].Timestamp != points[j].Timestamp {
+			return points[i].Timestamp < points[j].Timestamp
+		}
+		return points[i].ServerID < points[j].ServerID
+	})
+	return points, err
+}
+
+func readMetricsAggregates(path string, req MetricsQueryRequest, to int64) ([]MetricsAggregate, error) {
+	type bucketKey struct {
+		serverID string
+		ts       int64
+	}
+	byKey := make(map[bucketKey]MetricsAggregate)
+	err := scanMetricsSegment(path, func(line []byte) {
+		var agg MetricsAggregate
+		if json.Unmarshal(line, &agg) != nil || agg.Count == 0 || agg.Timestamp < req.From || agg.Timestamp >= to {
+			return
+		}
+		if req.ServerID != "" && agg.ServerID != req.ServerID {
+			return
+		}
+		key := bucketKey{agg.ServerID, agg.Timestamp}
+		if prev, ok := byKey[key]; ok {
+			agg = mergeMetricsAggregates(prev, agg)
+		}
+		byKey[key] = agg
+	})
+
+	aggs := make([]MetricsAggregate, 0, len(byKey))
+	for _, agg := range byKey {
+		aggs = append(aggs, agg)
+	}
+	sort.Slice(aggs, func(i, j int) bool {
+		if aggs[i].Timestamp != aggs[j].Timestamp {
+			return aggs[i].Timestamp < aggs[j].Timestamp
+		}
+		return aggs[i].ServerID < aggs[j].ServerID
+	})
+	return aggs, err
+}
+
+// handleMetricsQuery handles metrics.query messages
+func (a *Agent) handleMetricsQuery(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req MetricsQueryRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendMetricsQueryResponse(msg.Reply, MetricsQueryResponse{Error: "Invalid request format"})
+		return
+	}
+
+	if a.metricsStore == nil {
+		a.sendMetricsQueryResponse(msg.Reply, MetricsQueryResponse{Error: "Metrics history not enabled"})
+		return
+	}
+
+	resp, err := a.metricsStore.Query(req, time.Now())
+	if err != nil {
+		a.sendMetricsQueryResponse(msg.Reply, MetricsQueryResponse{Error: err.Error()})
+		return
+	}
+	a.sendMetricsQueryResponse(msg.Reply, resp)
+}
+
+// sendMetricsQueryResponse replies to a metrics.query request
+func (a *Agent) sendMetricsQueryResponse(replyTo string, resp MetricsQueryResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
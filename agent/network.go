@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"strconv"
 	"strings"
@@ -47,7 +46,7 @@ func (dc *DockerClient) CheckPortAvailability(ctx context.Context, ports []int)
 	dockerPorts := make(map[int]string) // port -> container name
 	containers, err := dc.cli.ContainerList(ctx, container.ListOptions{All: true})
 	if err != nil {
-		log.Printf("Warning: Failed to list containers for port check: %v", err)
+		networkLogger.Warnf("Failed to list containers for port check: %v", err)
 	} else {
 		for _, c := range containers {
 			// Get container name
@@ -68,7 +67,7 @@ func (dc *DockerClient) CheckPortAvailability(ctx context.Context, ports []int)
 	// 2. Check host-level network bindings
 	hostPorts, err := getHostBoundPorts()
 	if err != nil {
-		log.Printf("Warning: Failed to get host bound ports: %v", err)
+		networkLogger.Warnf("Failed to get host bound ports: %v", err)
 		// Continue with Docker-only checking
 		hostPorts = make(map[int]bool)
 	}
@@ -118,7 +117,7 @@ func getHostBoundPorts() (map[int]bool, error) {
 	for _, procFile := range procFiles {
 		filePorts, err := parseProcNetFile(procFile)
 		if err != nil {
-			log.Printf("Warning: Failed to read %s: %v", procFile, err)
+			networkLogger.Warnf("Failed to read %s: %v", procFile, err)
 			continue
 		}
 
@@ -128,7 +127,7 @@ func getHostBoundPorts() (map[int]bool, error) {
 		}
 	}
 
-	log.Printf("Found %d bound ports at host level", len(ports))
+	networkLogger.Infof("Found %d bound ports at host level", len(ports))
 	return ports, nil
 }
 
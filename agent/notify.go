@@ -0,0 +1,826 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const notifyConfigFile = "notifiers.json"
+
+const (
+	notifySendTimeout  = 15 * time.Second
+	resendAPIURL       = "https://api.resend.com/emails"
+	resendDefaultFrom  = "ZedOps Alerts <noreply@example.com>"
+	notifyImplicitName = "email" // Resend channel built from the manager's alert config
+)
+
+// Notifier types
+const (
+	NotifierResend  = "resend"
+	NotifierSMTP    = "smtp"
+	NotifierDiscord = "discord"
+	NotifierSlack   = "slack"
+	NotifierWebhook = "webhook"
+)
+
+// Notification kinds
+const (
+	NotifyAgentOffline   = "agent.offline"
+	NotifyAgentRecovered = "agent.recovered"
+	NotifyMetricAlert    = "alert.metric"
+	NotifyLogAlert       = "alert.log"
+)
+
+// notifySeverityRank orders severities for NotifyRoute.MinSeverity
+var notifySeverityRank = map[string]int{"info": 0, "warning": 1, "critical": 2}
+
+// NotificationField is a labelled value shown in chat embeds and emails
+type NotificationField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Notification is one alert, rendered by each notifier in its own format
+type Notification struct {
+	Kind       string              `json:"kind"`               // agent.offline, agent.recovered, alert.metric, alert.log
+	Status     string              `json:"status"`             // firing or resolved
+	Severity   string              `json:"severity"`           // info, warning, critical
+	Title      string              `json:"title"`              // One line, also the email subject
+	Text       string              `json:"text"`               // Plain text body
+	Fields     []NotificationField `json:"fields,omitempty"`   // Extra details
+	RuleID     string              `json:"ruleId,omitempty"`   // Alert rule, part of the fingerprint
+	Instance   string              `json:"instance,omitempty"` // e.g. disk path, part of the fingerprint
+	AgentName  string              `json:"agentName"`
+	ServerID   string              `json:"serverId,omitempty"`
+	ServerName string              `json:"serverName,omitempty"`
+	Timestamp  time.Time           `json:"timestamp"`
+	Event      interface{}         `json:"event,omitempty"` // Original payload, included in webhook bodies
+	Channels   []string            `json:"channels,omitempty"`
+	// Channels set by an escalation tier replace routing (empty = routes decide)
+
+	// Template variables (see templates.go)
+	Template     string    `json:"template,omitempty"`     // Templates to render with (default: Kind)
+	Reminder     bool      `json:"reminder,omitempty"`     // Repeat of a notification already sent
+	Reason       string    `json:"reason,omitempty"`       // Why the agent is offline
+	FailingSince time.Time `json:"failingSince,omitempty"` // Start of the outage or alert
+
+	// The manager received the event and emails its own recipients, so the implicit
+	// email channel is skipped to avoid duplicates
+	ManagerDelivered bool `json:"managerDelivered,omitempty"`
+}
+
+// Fingerprint identifies the alert a notification belongs to, across restarts
+func (n Notification) Fingerprint() string {
+	return alertFingerprint(n.Kind, n.RuleID, n.ServerID, n.Instance)
+}
+
+// Notifier delivers notifications to one destination
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifyChannelConfig configures one notification destination
+type NotifyChannelConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // resend, smtp, discord, slack or webhook
+
+	// resend and smtp
+	APIKey string   `json:"apiKey,omitempty"` // resend
+	From   string   `json:"from,omitempty"`
+	To     []string `json:"to,omitempty"`
+
+	// smtp
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"` // Default 587 (465 with tls)
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	TLS      string `json:"tls,omitempty"` // starttls (default, required), tls (implicit) or none
+
+	// discord, slack and webhook
+	URL string `json:"url,omitempty"`
+
+	// webhook
+	Secret  string            `json:"secret,omitempty"` // HMAC-SHA256 key for X-ZedOps-Signature
+	Headers map[string]string `json:"headers,omitempty"`
+
+	MinSeverity string `json:"minSeverity,omitempty"` // Skip less severe notifications (default: all)
+	Locale      string `json:"locale,omitempty"`      // Email template locale (resend and smtp)
+}
+
+// NotifyRoute sends matching notifications to a set of channels. A notification goes to every
+// channel of every route it matches.
+type NotifyRoute struct {
+	Name        string   `json:"name,omitempty"`
+	Kinds       []string `json:"kinds,omitempty"`       // e.g. agent.offline, alert.* (empty = all)
+	MinSeverity string   `json:"minSeverity,omitempty"` // info, warning or critical (default: all)
+	ServerIDs   []string `json:"serverIds,omitempty"`   // Empty = any (including agent-level notifications)
+	Channels    []string `json:"channels"`
+}
+
+// NotifyConfig is the notifiers.json file. Without routes, every channel receives everything.
+type NotifyConfig struct {
+	Channels    []NotifyChannelConfig `json:"channels"`
+	Routes      []NotifyRoute         `json:"routes,omitempty"`
+	Policy      AlertPolicy           `json:"policy,omitempty"`
+	Escalations []AlertEscalation     `json:"escalations,omitempty"`
+}
+
+// GetNotifyConfigPath returns the default path of the notifiers config
+func GetNotifyConfigPath() string {
+	return filepath.Join(StateDir(), notifyConfigFile)
+}
+
+// LoadNotifyConfig loads a notifiers config file.
+// Returns nil if the file doesn't exist.
+func LoadNotifyConfig(path string) (*NotifyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read notifiers config: %w", err)
+	}
+
+	var config NotifyConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse notifiers config: %w", err)
+	}
+	for _, c := range config.Channels {
+		RegisterSecret(c.APIKey)
+		RegisterSecret(c.Password)
+		RegisterSecret(c.Secret)
+		if c.Type == NotifierDiscord || c.Type == NotifierSlack {
+			RegisterSecret(c.URL) // Webhook URLs embed their token
+		}
+	}
+	return &config, nil
+}
+
+// newNotifier creates the notifier for a channel config
+func newNotifier(config NotifyChannelConfig) (Notifier, error) {
+	client := &http.Client{Timeout: notifySendTimeout}
+	switch config.Type {
+	case NotifierResend:
+		if config.APIKey == "" || len(config.To) == 0 {
+			return nil, fmt.Errorf("resend needs apiKey and to")
+		}
+		recipients := make([]AlertRecipient, len(config.To))
+		for i, to := range config.To {
+			recipients[i] = AlertRecipient{Email: to, Colors: DefaultEmailColors(), Locale: config.Locale}
+		}
+		return &ResendNotifier{APIKey: config.APIKey, From: config.From, Recipients: recipients, client: client}, nil
+	case NotifierSMTP:
+		return newSMTPNotifier(config)
+	case NotifierDiscord:
+		if config.URL == "" {
+			return nil, fmt.Errorf("discord needs url")
+		}
+		return &DiscordNotifier{URL: config.URL, client: client}, nil
+	case NotifierSlack:
+		if config.URL == "" {
+			return nil, fmt.Errorf("slack needs url")
+		}
+		return &SlackNotifier{URL: config.URL, client: client}, nil
+	case NotifierWebhook:
+		if config.URL == "" {
+			return nil, fmt.Errorf("webhook needs url")
+		}
+		return &WebhookNotifier{URL: config.URL, Secret: config.Secret, Headers: config.Headers, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q (expected resend, smtp, discord, slack or webhook)", config.Type)
+	}
+}
+
+// NotifyRouter delivers notifications to the channels their routes select
+type NotifyRouter struct {
+	channels    map[string]Notifier
+	order       []string          // Channel names in config order
+	minSeverity map[string]string // Channel name -> minimum severity
+	routes      []NotifyRoute
+
+	// implicit returns the Resend channel for the manager-provided alert config, or nil
+	implicit func() Notifier
+}
+
+// NewNotifyRouter creates the configured channels. config may be nil (implicit email only).
+func NewNotifyRouter(config *NotifyConfig, implicit func() Notifier) (*NotifyRouter, error) {
+	r := &NotifyRouter{channels: make(map[string]Notifier), minSeverity: make(map[string]string), implicit: implicit}
+	if config == nil {
+		return r, nil
+	}
+
+	for i, c := range config.Channels {
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("%s-%d", c.Type, i+1)
+		}
+		if _, dup := r.channels[c.Name]; dup {
+			return nil, fmt.Errorf("duplicate channel name %q", c.Name)
+		}
+		n, err := newNotifier(c)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %w", c.Name, err)
+		}
+		if _, ok := notifySeverityRank[c.MinSeverity]; c.MinSeverity != "" && !ok {
+			return nil, fmt.Errorf("channel %s: unknown severity %q", c.Name, c.MinSeverity)
+		}
+		r.channels[c.Name] = n
+		r.minSeverity[c.Name] = c.MinSeverity
+		r.order = append(r.order, c.Name)
+	}
+
+	for i, route := range config.Routes {
+		if len(route.Channels) == 0 {
+			return nil, fmt.Errorf("route %d has no channels", i+1)
+		}
+		for _, name := range route.Channels {
+			if _, ok := r.channels[name]; !ok && name != notifyImplicitName {
+				return nil, fmt.Errorf("route %d: unknown channel %q", i+1, name)
+			}
+		}
+		if _, ok := notifySeverityRank[route.MinSeverity]; route.MinSeverity != "" && !ok {
+			return nil, fmt.Errorf("route %d: unknown severity %q", i+1, route.MinSeverity)
+		}
+	}
+	r.routes = config.Routes
+	return r, nil
+}
+
+// Channels returns the configured channel names (the implicit email channel is not included)
+func (r *NotifyRouter) Channels() []string {
+	return append([]string{}, r.order...)
+}
+
+// matches reports whether a route selects a notification
+func (route NotifyRoute) matches(n Notification) bool {
+	if len(route.Kinds) > 0 {
+		ok := false
+		for _, k := range route.Kinds {
+			if k == n.Kind || (strings.HasSuffix(k, ".*") && strings.HasPrefix(n.Kind, strings.TrimSuffix(k, "*"))) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if route.MinSeverity != "" && notifySeverityRank[n.Severity] < notifySeverityRank[route.MinSeverity] {
+		return false
+	}
+	if len(route.ServerIDs) > 0 {
+		ok := false
+		for _, id := range route.ServerIDs {
+			if id == n.ServerID {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// notifierFor returns a channel's notifier, or nil if it can't deliver (the implicit email
+// channel needs a manager-provided alert config)
+func (r *NotifyRouter) notifierFor(name string) Notifier {
+	if n := r.channels[name]; n != nil {
+		return n
+	}
+	if name == notifyImplicitName && r.implicit != nil {
+		return r.implicit()
+	}
+	return nil
+}
+
+// Route returns the names of the channels a notification goes to
+func (r *NotifyRouter) Route(n Notification) []string {
+	var candidates []string
+	switch {
+	case len(n.Channels) > 0:
+		candidates = n.Channels
+	case len(r.routes) == 0:
+		candidates = append([]string{notifyImplicitName}, r.order...)
+	default:
+		for _, route := range r.routes {
+			if route.matches(n) {
+				candidates = append(candidates, route.Channels...)
+			}
+		}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range candidates {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		// The manager emails its own recipients; a configured channel named "email" still receives
+		if name == notifyImplicitName && r.channels[name] == nil && n.ManagerDelivered {
+			continue
+		}
+		if min := r.minSeverity[name]; min != "" && notifySeverityRank[n.Severity] < notifySeverityRank[min] {
+			continue
+		}
+		if r.notifierFor(name) != nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Send delivers a notification to its routed channels in parallel and returns the failures
+func (r *NotifyRouter) Send(ctx context.Context, n Notification) error {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now()
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, name := range r.Route(n) {
+		notifier := r.notifierFor(name)
+		if notifier == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, notifier Notifier) {
+			defer wg.Done()
+			sendCtx, cancel := context.WithTimeout(ctx, notifySendTimeout)
+			defer cancel()
+			if err := notifier.Notify(sendCtx, n); err != nil {
+				alertLogger.Errorf("Failed to notify %s: %v", name, err)
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				mu.Unlock()
+				return
+			}
+			alertLogger.Debugf("Notified %s: %s", name, n.Title)
+		}(name, notifier)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// notificationHTML renders a notification as plain HTML, for when the templates fail
+func notificationHTML(n Notification) string {
+	var b strings.Builder
+	b.WriteString("<p>")
+	b.WriteString(strings.ReplaceAll(html.EscapeString(n.Text), "\n", "<br>"))
+	b.WriteString("</p>")
+	for _, f := range n.Fields {
+		fmt.Fprintf(&b, "<p>%s: <strong>%s</strong></p>", html.EscapeString(f.Name), html.EscapeString(f.Value))
+	}
+	return b.String()
+}
+
+// notifyColor picks the accent color of chat messages
+func notifyColor(n Notification) int {
+	switch {
+	case n.Status == MetricAlertResolved:
+		return 0x22c55e
+	case n.Severity == "critical":
+		return 0xef4444
+	case n.Severity == "warning":
+		return 0xf59e0b
+	default:
+		return 0x3b82f6
+	}
+}
+
+// postJSON posts a JSON body and fails on non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "zedops-agent/"+Version)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("rate limited (retry after %s)", resp.Header.Get("Retry-After"))
+		}
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// ResendNotifier emails each recipient through the Resend API in their theme colors
+type ResendNotifier struct {
+	APIKey     string
+	From       string
+	Recipients []AlertRecipient
+
+	endpoint string // Default resendAPIURL; a local stand-in in tests
+	client   *http.Client
+}
+
+// Notify implements Notifier
+func (r *ResendNotifier) Notify(ctx context.Context, n Notification) error {
+	from := r.From
+	if from == "" {
+		from = resendDefaultFrom
+	}
+	endpoint := r.endpoint
+	if endpoint == "" {
+		endpoint = resendAPIURL
+	}
+	client := r.client
+	if client == nil {
+		client = &http.Client{Timeout: notifySendTimeout}
+	}
+
+	var errs []error
+	for _, rcpt := range r.Recipients {
+		if rcpt.MinSeverity != "" && notifySeverityRank[n.Severity] < notifySeverityRank[rcpt.MinSeverity] {
+			continue
+		}
+		rendered := notifyTemplates.Render(n, rcpt.Locale, rcpt.Colors)
+		payload, err := json.Marshal(map[string]interface{}{
+			"from":    from,
+			"to":      []string{rcpt.Email},
+			"subject": rendered.Subject,
+			"html":    rendered.HTML,
+			"text":    rendered.Text,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal email payload: %w", err)
+		}
+		if err := postJSON(ctx, client, endpoint, payload, map[string]string{"Authorization": "Bearer " + r.APIKey}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rcpt.Email, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SMTPNotifier emails the recipients through an SMTP server
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLS      string // starttls, tls or none
+	From     string
+	To       []string
+	Locale   string // Template locale for every recipient
+}
+
+func newSMTPNotifier(config NotifyChannelConfig) (*SMTPNotifier, error) {
+	if config.Host == "" || config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("smtp needs host, from and to")
+	}
+	s := &SMTPNotifier{
+		Host: config.Host, Port: config.Port, Username: config.Username, Password: config.Password,
+		TLS: config.TLS, From: config.From, To: config.To, Locale: config.Locale,
+	}
+	switch s.TLS {
+	case "":
+		s.TLS = "starttls"
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q (expected starttls, tls or none)", s.TLS)
+	}
+	// net/smtp only sends PLAIN credentials over TLS or to localhost
+	if s.Username != "" && s.TLS == "none" && !isLocalSMTPHost(s.Host) {
+		return nil, fmt.Errorf("smtp auth needs tls or starttls unless the host is localhost")
+	}
+	if s.Port == 0 {
+		s.Port = 587
+		if s.TLS == "tls" {
+			s.Port = 465
+		}
+	}
+	return s, nil
+}
+
+// isLocalSMTPHost reports whether smtp.PlainAuth accepts a host without TLS
+func isLocalSMTPHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// Notify implements Notifier
+func (s *SMTPNotifier) Notify(ctx context.Context, n Notification) error {
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: s.Host}
+	if s.TLS == "tls" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	defer c.Close()
+
+	if s.TLS == "starttls" {
+		// Never fall back to plaintext: set tls to none to send without encryption
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not offer STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls failed: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.From); err != nil {
+		return fmt.Errorf("MAIL FROM rejected: %w", err)
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("RCPT TO %s rejected: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("DATA rejected: %w", err)
+	}
+	if _, err := w.Write(s.message(n)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+	return c.Quit()
+}
+
+// message builds the RFC 5322 message with plaintext and HTML alternatives
+func (s *SMTPNotifier) message(n Notification) []byte {
+	rendered := notifyTemplates.Render(n, s.Locale, DefaultEmailColors())
+	boundary := fmt.Sprintf("zedops-%x", n.Timestamp.UnixNano())
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", rendered.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", rendered.Text}, // Least preferred first (RFC 2046)
+		{"text/html", rendered.HTML},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&b)
+		qp.Write([]byte(part.body))
+		qp.Close()
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes()
+}
+
+// DiscordNotifier posts an embed to a Discord webhook
+type DiscordNotifier struct {
+	URL    string
+	client *http.Client
+}
+
+// Notify implements Notifier
+func (d *DiscordNotifier) Notify(ctx context.Context, n Notification) error {
+	type field struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	}
+	fields := make([]field, 0, len(n.Fields))
+	for _, f := range n.Fields {
+		fields = append(fields, field{Name: f.Name, Value: f.Value, Inline: true})
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"username": "ZedOps",
+		"embeds": []map[string]interface{}{{
+			"title":       truncateRunes(n.Title, 256),
+			"description": truncateRunes(n.Text, 4096),
+			"color":       notifyColor(n),
+			"fields":      fields,
+			"timestamp":   n.Timestamp.UTC().Format(time.RFC3339),
+			"footer":      map[string]string{"text": "Agent " + n.AgentName},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, d.client, d.URL, payload, nil)
+}
+
+// SlackNotifier posts an attachment to a Slack incoming webhook
+type SlackNotifier struct {
+	URL    string
+	client *http.Client
+}
+
+// Notify implements Notifier
+func (s *SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	fields := make([]field, 0, len(n.Fields))
+	for _, f := range n.Fields {
+		fields = append(fields, field{Title: f.Name, Value: f.Value, Short: true})
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"text": n.Title, // Notification fallback
+		"attachments": []map[string]interface{}{{
+			"color":  fmt.Sprintf("#%06x", notifyColor(n)),
+			"title":  n.Title,
+			"text":   n.Text,
+			"fields": fields,
+			"footer": "Agent " + n.AgentName,
+			"ts":     n.Timestamp.Unix(),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.URL, payload, nil)
+}
+
+// WebhookNotifier posts the notification as JSON. With a secret, X-ZedOps-Signature carries
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)) where timestamp is the
+// X-ZedOps-Timestamp header, so receivers can reject forged and replayed requests.
+type WebhookNotifier struct {
+	URL     string
+	Secret  string
+	Headers map[string]string
+	client  *http.Client
+}
+
+// WebhookPayload is the generic webhook body
+type WebhookPayload struct {
+	Kind       string              `json:"kind"`
+	Status     string              `json:"status"`
+	Severity   string              `json:"severity"`
+	Title      string              `json:"title"`
+	Text       string              `json:"text"`
+	Fields     []NotificationField `json:"fields,omitempty"`
+	Agent      string              `json:"agent"`
+	ServerID   string              `json:"serverId,omitempty"`
+	ServerName string              `json:"serverName,omitempty"`
+	Timestamp  int64               `json:"timestamp"` // Unix milliseconds
+	Event      interface{}         `json:"event,omitempty"`
+}
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(WebhookPayload{
+		Kind:       n.Kind,
+		Status:     n.Status,
+		Severity:   n.Severity,
+		Title:      n.Title,
+		Text:       n.Text,
+		Fields:     n.Fields,
+		Agent:      n.AgentName,
+		ServerID:   n.ServerID,
+		ServerName: n.ServerName,
+		Timestamp:  n.Timestamp.UnixMilli(),
+		Event:      n.Event,
+	})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"X-ZedOps-Event": n.Kind}
+	for k, v := range w.Headers {
+		headers[k] = v
+	}
+	if w.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		headers["X-ZedOps-Timestamp"] = ts
+		headers["X-ZedOps-Signature"] = "sha256=" + signWebhook(w.Secret, ts, body)
+	}
+	return postJSON(ctx, w.client, w.URL, body, headers)
+}
+
+// signWebhook computes the hex HMAC-SHA256 of timestamp + "." + body
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// NotifyTestRequest is the notify.test request
+type NotifyTestRequest struct {
+	Channel string `json:"channel,omitempty"` // Empty = route like any agent notification
+}
+
+// NotifyTestResponse is the reply to notify.test
+type NotifyTestResponse struct {
+	Success  bool     `json:"success"`
+	Channels []string `json:"channels,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// handleNotifyTest handles notify.test messages (sends a test notification)
+func (a *Agent) handleNotifyTest(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req NotifyTestRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendNotifyTestResponse(msg.Reply, NotifyTestResponse{Error: "Invalid request format"})
+		return
+	}
+
+	n := Notification{
+		Kind:      "test",
+		Severity:  "info",
+		Title:     fmt.Sprintf(`Test notification from agent "%s"`, a.agentName),
+		Text:      "If you can read this, the notification channel works.",
+		AgentName: a.agentName,
+		Timestamp: time.Now(),
+	}
+
+	router := a.notifier
+	if req.Channel != "" {
+		notifier := router.notifierFor(req.Channel)
+		if notifier == nil {
+			a.sendNotifyTestResponse(msg.Reply, NotifyTestResponse{Error: fmt.Sprintf("unknown channel %q", req.Channel)})
+			return
+		}
+		router = &NotifyRouter{channels: map[string]Notifier{req.Channel: notifier}, order: []string{req.Channel}}
+	}
+
+	channels := router.Route(n)
+	if err := router.Send(msg.Context(), n); err != nil {
+		a.sendNotifyTestResponse(msg.Reply, NotifyTestResponse{Channels: channels, Error: err.Error()})
+		return
+	}
+	a.sendNotifyTestResponse(msg.Reply, NotifyTestResponse{Success: true, Channels: channels})
+}
+
+// sendNotifyTestResponse replies to a notify.test request
+func (a *Agent) sendNotifyTestResponse(replyTo string, resp NotifyTestResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testNotification() Notification {
+	return Notification{
+		Kind:       "alert.metric",
+		Status:     MetricAlertFiring,
+		Severity:   "critical",
+		Title:      "Disk almost full on survival",
+		Text:       "Disk usage is 91 percent.",
+		Fields:     []NotificationField{{Name: "Disk", Value: "/data"}},
+		AgentName:  "host-1",
+		ServerID:   "srv-1",
+		ServerName: "survival",
+		Timestamp:  time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// fakeSMTP is an SMTP server stand-in that accepts one session and records it
+type fakeSMTP struct {
+	addr     string
+	startTLS bool // Advertise STARTTLS
+
+	mu    sync.Mutex
+	auth  string // Decoded AUTH PLAIN response
+	from  string
+	rcpts []string
+	data  string
+	done  chan struct{}
+}
+
+func newFakeSMTP(t *testing.T, startTLS bool) *fakeSMTP {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	s := &fakeSMTP{addr: l.Addr().String(), startTLS: startTLS, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(conn)
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(lines ...string) { io.WriteString(conn, strings.Join(lines, "\r\n")+"\r\n") }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if s.startTLS {
+				reply("250-fake", "250-STARTTLS", "250 AUTH PLAIN")
+			} else {
+				reply("250-fake", "250 AUTH PLAIN")
+			}
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			s.mu.Lock()
+			s.auth = string(decoded)
+			s.mu.Unlock()
+			reply("235 ok")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = arg
+			s.mu.Unlock()
+			reply("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, arg)
+			s.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = b.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func smtpTestConfig(addr string) NotifyChannelConfig {
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	return NotifyChannelConfig{
+		Type: NotifierSMTP, Host: host, Port: p,
+		From: "zedops@example.com", To: []string{"ops@example.com", "oncall@example.com"},
+	}
+}
+
+func TestSMTPNotifierDelivers(t *testing.T) {
+	server := newFakeSMTP(t, false)
+	config := smtpTestConfig(server.addr)
+	config.TLS = "none"
+	config.Username = "mailer"
+	config.Password = "mail-pass"
+	s, err := newSMTPNotifier(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatal(err)
+	}
+	<-server.done
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.auth != "\x00mailer\x00mail-pass" {
+		t.Errorf("auth = %q", server.auth)
+	}
+	if server.from != "FROM:<zedops@example.com>" || strings.Join(server.rcpts, ",") != "TO:<ops@example.com>,TO:<oncall@example.com>" {
+		t.Errorf("envelope = %s %v", server.from, server.rcpts)
+	}
+	for _, want := range []string{
+		"To: ops@example.com, oncall@example.com\r\n",
+		"Content-Type: multipart/alternative;",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+		"Disk usage is 91 percent.",
+	} {
+		if !strings.Contains(server.data, want) {
+			t.Errorf("message is missing %q:\n%s", want, server.data)
+		}
+	}
+}
+
+func TestSMTPNotifierRequiresOfferedSTARTTLS(t *testing.T) {
+	server := newFakeSMTP(t, false)
+	s, err := newSMTPNotifier(smtpTestConfig(server.addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Notify(context.Background(), testNotification())
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Notify error = %v, want STARTTLS not offered", err)
+	}
+	<-server.done
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.from != "" || server.data != "" {
+		t.Errorf("sent in plaintext: from %q, data %q", server.from, server.data)
+	}
+}
+
+func TestNewSMTPNotifierValidation(t *testing.T) {
+	base := NotifyChannelConfig{Type: NotifierSMTP, Host: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}}
+	tests := []struct {
+		name     string
+		edit     func(c *NotifyChannelConfig)
+		wantErr  bool
+		wantPort int
+	}{
+		{"starttls default", func(c *NotifyChannelConfig) {}, false, 587},
+		{"implicit tls", func(c *NotifyChannelConfig) { c.TLS = "tls" }, false, 465},
+		{"unknown tls mode", func(c *NotifyChannelConfig) { c.TLS = "ssl" }, true, 0},
+		{"missing recipients", func(c *NotifyChannelConfig) { c.To = nil }, true, 0},
+		{"auth over starttls", func(c *NotifyChannelConfig) { c.Username = "u" }, false, 587},
+		{"auth in plaintext to a remote host", func(c *NotifyChannelConfig) { c.Username, c.TLS = "u", "none" }, true, 0},
+		{"auth in plaintext to localhost", func(c *NotifyChannelConfig) { c.Username, c.TLS, c.Host = "u", "none", "localhost" }, false, 587},
+		{"no auth in plaintext", func(c *NotifyChannelConfig) { c.TLS = "none" }, false, 587},
+	}
+	for _, tt := range tests {
+		c := base
+		tt.edit(&c)
+		s, err := newSMTPNotifier(c)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && s.Port != tt.wantPort {
+			t.Errorf("%s: port = %d, want %d", tt.name, s.Port, tt.wantPort)
+		}
+	}
+}
+
+// capturedRequest is a request received by a webhook stand-in
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests []capturedRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		requests = append(requests, capturedRequest{header: req.Header.Clone(), body: body})
+		mu.Unlock()
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "30")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest{}, requests...)
+	}
+}
+
+func TestResendNotifier(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+	r := &ResendNotifier{
+		APIKey: "re_test_key",
+		Recipients: []AlertRecipient{
+			{Email: "ops@example.com", Colors: DefaultEmailColors()},
+			{Email: "quiet@example.com", Colors: DefaultEmailColors(), MinSeverity: "critical"},
+			{Email: "fr@example.com", Colors: DefaultEmailColors(), MinSeverity: "critical", Locale: "fr"},
+		},
+		endpoint: server.URL,
+		client:   server.Client(),
+	}
+
+	n := testNotification()
+	n.Severity = "warning"
+	if err := r.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("%d emails for a warning, want 1", len(got))
+	}
+	if got[0].header.Get("Authorization") != "Bearer re_test_key" {
+		t.Errorf("authorization = %q", got[0].header.Get("Authorization"))
+	}
+	var payload struct {
+		From    string   `json:"from"`
+		To      []string `json:"to"`
+		Subject string   `json:"subject"`
+		HTML    string   `json:"html"`
+		Text    string   `json:"text"`
+	}
+	if err := json.Unmarshal(got[0].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.From != resendDefaultFrom || len(payload.To) != 1 || payload.To[0] != "ops@example.com" {
+		t.Errorf("payload = %+v", payload)
+	}
+	if payload.Subject == "" || payload.HTML == "" || !strings.Contains(payload.Text, n.Text) {
+		t.Errorf("rendered email = %+v", payload)
+	}
+
+	// Critical alerts reach every recipient
+	if err := r.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(requests()); n != 4 {
+		t.Errorf("%d emails in total, want 4", n)
+	}
+}
+
+func TestChatNotifiers(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusNoContent)
+	n := testNotification()
+
+	discord := &DiscordNotifier{URL: server.URL, client: server.Client()}
+	if err := discord.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	var embed struct {
+		Embeds []struct {
+			Title  string `json:"title"`
+			Color  int    `json:"color"`
+			Fields []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"fields"`
+			Timestamp string `json:"timestamp"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal(requests()[0].body, &embed); err != nil {
+		t.Fatal(err)
+	}
+	if len(embed.Embeds) != 1 || embed.Embeds[0].Title != n.Title || embed.Embeds[0].Color != 0xef4444 ||
+		len(embed.Embeds[0].Fields) != 1 || embed.Embeds[0].Timestamp != "2026-03-01T12:00:00Z" {
+		t.Errorf("discord payload = %s", requests()[0].body)
+	}
+
+	slack := &SlackNotifier{URL: server.URL, client: server.Client()}
+	if err := slack.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	var attachment struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Color string `json:"color"`
+			Text  string `json:"text"`
+			TS    int64  `json:"ts"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(requests()[1].body, &attachment); err != nil {
+		t.Fatal(err)
+	}
+	if attachment.Text != n.Title || len(attachment.Attachments) != 1 || attachment.Attachments[0].Color != "#ef4444" ||
+		attachment.Attachments[0].TS != n.Timestamp.Unix() {
+		t.Errorf("slack payload = %s", requests()[1].body)
+	}
+}
+
+func TestWebhookNotifierSignature(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+	w := &WebhookNotifier{URL: server.URL, Secret: "hook-secret", Headers: map[string]string{"X-Team": "ops"}, client: server.Client()}
+
+	n := testNotification()
+	if err := w.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	req := requests()[0]
+	ts := req.header.Get("X-ZedOps-Timestamp")
+	if want := "sha256=" + signWebhook("hook-secret", ts, req.body); ts == "" || req.header.Get("X-ZedOps-Signature") != want {
+		t.Errorf("signature = %q, want %q", req.header.Get("X-ZedOps-Signature"), want)
+	}
+	if req.header.Get("X-ZedOps-Event") != "alert.metric" || req.header.Get("X-Team") != "ops" {
+		t.Errorf("headers = %v", req.header)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Kind != n.Kind || payload.ServerName != "survival" || payload.Timestamp != n.Timestamp.UnixMilli() {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	// Without a secret, nothing is signed
+	w.Secret = ""
+	if err := w.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if sig := requests()[1].header.Get("X-ZedOps-Signature"); sig != "" {
+		t.Errorf("unsigned webhook has signature %q", sig)
+	}
+}
+
+func TestPostJSONErrors(t *testing.T) {
+	limited, _ := newCaptureServer(t, http.StatusTooManyRequests)
+	err := postJSON(context.Background(), limited.Client(), limited.URL, []byte("{}"), nil)
+	if err == nil || !strings.Contains(err.Error(), "retry after 30") {
+		t.Errorf("429 error = %v", err)
+	}
+
+	failing, _ := newCaptureServer(t, http.StatusInternalServerError)
+	if err := postJSON(context.Background(), failing.Client(), failing.URL, []byte("{}"), nil); err == nil {
+		t.Error("500 accepted")
+	}
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	playerSessionsFile     = "player-sessions.jsonl"
+	openPlayerSessionsFile = "player-sessions-open.json"
+
+	// playerSessionRetention is how long closed sessions are kept on disk
+	playerSessionRetention = 180 * 24 * time.Hour
+
+	// playerSessionPruneInterval is how often closed sessions past retention are dropped
+	// from memory and the sessions file while the agent runs
+	playerSessionPruneInterval = time.Hour
+
+	// playerSessionStaleGap is the longest gap between two sightings that still counts
+	// as continuous play. Larger gaps (agent down, server crashed) close the session at
+	// the last time the player was actually seen.
+	playerSessionStaleGap = 2 * time.Minute
+)
+
+// PlayerSession is a single play session of a player on a server
+type PlayerSession struct {
+	Player          string `json:"player"`
+	ServerID        string `json:"serverId"`
+	ServerName      string `json:"serverName"`
+	Start           int64  `json:"start"`         // Unix seconds
+	End             int64  `json:"end,omitempty"` // Unix seconds, 0 while still online
+	DurationSeconds int64  `json:"durationSeconds"`
+}
+
+// openPlayerSession is a session still in progress, persisted so restarts don't lose it
+type openPlayerSession struct {
+	PlayerSession
+	LastSeen int64 `json:"lastSeen"` // Unix seconds
+
+	savedLastSeen int64 // LastSeen as last written to disk
+}
+
+// PlayerSessionEvent is the payload for players.joined and players.left
+type PlayerSessionEvent struct {
+	ServerID        string `json:"serverId"`
+	ServerName      string `json:"serverName"`
+	Player          string `json:"player"`
+	Timestamp       int64  `json:"timestamp"`                 // Unix seconds
+	DurationSeconds int64  `json:"durationSeconds,omitempty"` // Session length (players.left only)
+}
+
+// PlayerHistoryRequest is the players.history request payload
+type PlayerHistoryRequest struct {
+	Player   string `json:"player"`   // Optional, case-insensitive exact match
+	ServerID string `json:"serverId"` // Optional
+	Since    int64  `json:"since"`    // Unix seconds, optional
+	Until    int64  `json:"until"`    // Unix seconds, optional (default: now)
+	Limit    int    `json:"limit"`    // Max sessions returned (default 500)
+}
+
+// PlayerPlaytime is the aggregated playtime of a player within the queried range
+type PlayerPlaytime struct {
+	Player       string `json:"player"`
+	TotalSeconds int64  `json:"totalSeconds"`
+	Sessions     int    `json:"sessions"`
+	LastSeen     int64  `json:"lastSeen"` // Unix seconds
+	Online       bool   `json:"online"`
+}
+
+// PlayerHistoryResponse is the players.history response payload
+type PlayerHistoryResponse struct {
+	Success  bool             `json:"success"`
+	Sessions []PlayerSession  `json:"sessions"`
+	Totals   []PlayerPlaytime `json:"totals"` // Sorted by total playtime, descending
+	Error    string           `json:"error,omitempty"`
+}
+
+// PlayerSessionStore tracks open sessions and persists closed ones to the state dir
+type PlayerSessionStore struct {
+	mu     sync.Mutex
+	dir    string
+	open   map[string]map[string]*openPlayerSession // serverID -> player -> session
+	closed []PlayerSession
+	pruned time.Time // Last retention prune
+}
+
+// NewPlayerSessionStore creates a session store rooted at dir
+func NewPlayerSessionStore(dir string) *PlayerSessionStore {
+	return &PlayerSessionStore{
+		dir:  dir,
+		open: make(map[string]map[string]*openPlayerSession),
+	}
+}
+
+// Load reads closed and open sessions from disk, pruning sessions past retention
+func (s *PlayerSessionStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-playerSessionRetention).Unix()
+	pruned := false
+
+	file, err := os.Open(filepath.Join(s.dir, playerSessionsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to open player sessions: %w", err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var session PlayerSession
+			if err := json.Unmarshal(scanner.Bytes(), &session); err != nil {
+				continue // Skip torn writes
+			}
+			if session.End < cutoff {
+				pruned = true
+				continue
+			}
+			s.closed = append(s.closed, session)
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read player sessions: %w", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, openPlayerSessionsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read open player sessions: %w", err)
+	}
+	if err == nil {
+		var open []openPlayerSession
+		if err := json.Unmarshal(data, &open); err != nil {
+			return fmt.Errorf("failed to parse open player sessions: %w", err)
+		}
+		for i := range open {
+			session := open[i]
+			if s.open[session.ServerID] == nil {
+				s.open[session.ServerID] = make(map[string]*openPlayerSession)
+			}
+			session.savedLastSeen = session.LastSeen
+			s.open[session.ServerID][session.Player] = &session
+		}
+	}
+
+	if pruned {
+		if err := s.rewriteClosed(); err != nil {
+			playerStatsLogger.Warnf("Failed to prune player sessions: %v", err)
+		}
+	}
+	s.pruned = time.Now()
+
+	return nil
+}
+
+// Update diffs the current player list of a server against its open sessions.
+// Returns the players that joined and the sessions that ended since the last update.
+func (s *PlayerSessionStore) Update(serverID, serverName string, players []string, now time.Time) ([]string, []PlayerSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]bool, len(players))
+	for _, p := range players {
+		current[p] = true
+	}
+
+	open := s.open[serverID]
+	if open == nil {
+		open = make(map[string]*openPlayerSession)
+		s.open[serverID] = open
+	}
+
+	var joined []string
+	var left []PlayerSession
+
+	for player, session := range open {
+		if current[player] {
+			continue
+		}
+		left = append(left, s.closeSession(session, now))
+		delete(open, player)
+	}
+
+	changed := false
+	for _, player := range players {
+		if session, exists := open[player]; exists {
+			if now.Unix()-session.LastSeen > int64(playerSessionStaleGap.Seconds()) {
+				// Seen again after a long gap: the old session ended when we lost sight of it
+				left = append(left, s.closeSession(session, now))
+			} else {
+				session.LastSeen = now.Unix()
+				// Sightings are saved every half stale gap, so an agent restarted within
+				// the other half still continues the session
+				if session.ServerName != serverName || session.LastSeen-session.savedLastSeen >= int64(playerSessionStaleGap.Seconds())/2 {
+					changed = true
+				}
+				session.ServerName = serverName
+				continue
+			}
+		}
+		open[player] = &openPlayerSession{
+			PlayerSession: PlayerSession{
+				Player:     player,
+				ServerID:   serverID,
+				ServerName: serverName,
+				Start:      now.Unix(),
+			},
+			LastSeen: now.Unix(),
+		}
+		joined = append(joined, player)
+	}
+
+	if len(open) == 0 {
+		delete(s.open, serverID)
+	}
+
+	// Polls that only see the same players again don't rewrite the open sessions
+	if changed || len(joined) > 0 || len(left) > 0 {
+		s.persist(left)
+	}
+	return joined, left
+}
+
+// CloseServer ends all open sessions for a server that is no longer running
+func (s *PlayerSessionStore) CloseServer(serverID string, now time.Time) []PlayerSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	open := s.open[serverID]
+	if len(open) == 0 {
+		return nil
+	}
+
+	var left []PlayerSession
+	for _, session := range open {
+		left = append(left, s.closeSession(session, now))
+	}
+	delete(s.open, serverID)
+
+	s.persist(left)
+	return left
+}
+
+// OpenServers returns the IDs of servers that have sessions in progress
+func (s *PlayerSessionStore) OpenServers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.open))
+	for id := range s.open {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Query returns sessions overlapping the requested range plus per-player totals
+func (s *PlayerSessionStore) Query(req PlayerHistoryRequest, now time.Time) ([]PlayerSession, []PlayerPlaytime) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until := req.Until
+	if until <= 0 {
+		until = now.Unix()
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 500
+	}
+
+	matches := func(session PlayerSession) bool {
+		if req.Player != "" && !strings.EqualFold(session.Player, req.Player) {
+			return false
+		}
+		if req.ServerID != "" && session.ServerID != req.ServerID {
+			return false
+		}
+		end := session.End
+		if end == 0 {
+			end = now.Unix()
+		}
+		return end >= req.Since && session.Start <= until
+	}
+
+	var sessions []PlayerSession
+	for _, session := range s.closed {
+		if matches(session) {
+			sessions = append(sessions, session)
+		}
+	}
+	for _, open := range s.open {
+		for _, session := range open {
+			live := session.PlayerSession
+			live.DurationSeconds = now.Unix() - live.Start
+			if matches(live) {
+				sessions = append(sessions, live)
+			}
+		}
+	}
+
+	totals := make(map[string]*PlayerPlaytime)
+	for _, session := range sessions {
+		t, ok := totals[session.Player]
+		if !ok {
+			t = &PlayerPlaytime{Player: session.Player}
+			totals[session.Player] = t
+		}
+
+		// Clip the session to the queried range
+		start, end := session.Start, session.End
+		if end == 0 {
+			end = now.Unix()
+			t.Online = true
+		}
+		if end > t.LastSeen {
+			t.LastSeen = end
+		}
+		if start < req.Since {
+			start = req.Since
+		}
+		if end > until {
+			end = until
+		}
+		if end > start {
+			t.TotalSeconds += end - start
+		}
+		t.Sessions++
+	}
+
+	totalList := make([]PlayerPlaytime, 0, len(totals))
+	for _, t := range totals {
+		totalList = append(totalList, *t)
+	}
+	sort.Slice(totalList, func(i, j int) bool {
+		return totalList[i].TotalSeconds > totalList[j].TotalSeconds
+	})
+
+	// Newest first
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Start > sessions[j].Start
+	})
+	if len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+
+	return sessions, totalList
+}
+
+// closeSession finalizes an open session. Must be called with s.mu held.
+func (s *PlayerSessionStore) closeSession(session *openPlayerSession, now time.Time) PlayerSession {
+	end := now.Unix()
+	if end-session.LastSeen > int64(playerSessionStaleGap.Seconds()) {
+		end = session.LastSeen
+	}
+
+	closed := session.PlayerSession
+	closed.End = end
+	closed.DurationSeconds = end - closed.Start
+	if closed.DurationSeconds < 0 {
+		closed.DurationSeconds = 0
+	}
+
+	s.closed = append(s.closed, closed)
+	return closed
+}
+
+// pruneClosed drops closed sessions past retention, at most once per prune interval, so a
+// long-running agent's history doesn't grow without bound. Must be called with s.mu held.
+func (s *PlayerSessionStore) pruneClosed(now time.Time) {
+	if now.Sub(s.pruned) < playerSessionPruneInterval {
+		return
+	}
+	s.pruned = now
+
+	cutoff := now.Add(-playerSessionRetention).Unix()
+	kept := s.closed[:0]
+	for _, session := range s.closed {
+		if session.End >= cutoff {
+			kept = append(kept, session)
+		}
+	}
+	if len(kept) == len(s.closed) {
+		return
+	}
+	clear(s.closed[len(kept):])
+	s.closed = kept
+
+	if err := s.rewriteClosed(); err != nil {
+		playerStatsLogger.Warnf("Failed to prune player sessions: %v", err)
+	}
+}
+
+// persist appends newly closed sessions and rewrites the open sessions file.
+// Must be called with s.mu held.
+func (s *PlayerSessionStore) persist(closed []PlayerSession) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		playerStatsLogger.Warnf("Failed to create session dir: %v", err)
+		return
+	}
+
+	if len(closed) > 0 {
+		file, err := os.OpenFile(filepath.Join(s.dir, playerSessionsFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			playerStatsLogger.Warnf("Failed to open player sessions: %v", err)
+		} else {
+			for _, session := range closed {
+				line, _ := json.Marshal(session)
+				file.Write(append(line, '\n'))
+			}
+			file.Close()
+		}
+		s.pruneClosed(time.Now())
+	}
+
+	var open []openPlayerSession
+	for _, sessions := range s.open {
+		for _, session := range sessions {
+			session.savedLastSeen = session.LastSeen
+			open = append(open, *session)
+		}
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, openPlayerSessionsFile), open); err != nil {
+		playerStatsLogger.Warnf("Failed to save open player sessions: %v", err)
+	}
+}
+
+// rewriteClosed replaces the sessions file with the in-memory closed sessions.
+// Must be called with s.mu held.
+func (s *PlayerSessionStore) rewriteClosed() error {
+	path := filepath.Join(s.dir, playerSessionsFile)
+	tmp := path + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, session := range s.closed {
+		line, _ := json.Marshal(session)
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	file.Close()
+
+	return os.Rename(tmp, path)
+}
+
+// writeFileAtomic marshals v as JSON and replaces path via a temp file rename
+func writeFileAtomic(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// handlePlayersHistory handles players.history messages
+func (a *Agent) handlePlayersHistory(msg Message) {
+	if msg.Reply == "" {
+		return
+	}
+
+	data, _ := json.Marshal(msg.Data)
+	var req PlayerHistoryRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendMessage(Message{
+			Subject:   msg.Reply,
+			Data:      PlayerHistoryResponse{Success: false, Error: "Invalid request format"},
+			Timestamp: time.Now().Unix(),
+		})
+		return
+	}
+
+	if a.playerStats == nil {
+		a.sendMessage(Message{
+			Subject:   msg.Reply,
+			Data:      PlayerHistoryResponse{Success: false, Error: "Player stats collector not initialized"},
+			Timestamp: time.Now().Unix(),
+		})
+		return
+	}
+
+	sessions, totals := a.playerStats.sessions.Query(req, time.Now())
+	if sessions == nil {
+		sessions = []PlayerSession{}
+	}
+
+	playerStatsLogger.Infof("History query (player=%q, server=%q): %d sessions, %d players",
+		req.Player, req.ServerID, len(sessions), len(totals))
+
+	a.sendMessage(Message{
+		Subject:   msg.Reply,
+		Data:      PlayerHistoryResponse{Success: true, Sessions: sessions, Totals: totals},
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlayerSessionStoreWritesOpenSessionsOnChange(t *testing.T) {
+	s := NewPlayerSessionStore(t.TempDir())
+	openPath := filepath.Join(s.dir, openPlayerSessionsFile)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		players []string
+		after   time.Duration
+		written bool
+	}{
+		{"join", []string{"alice"}, 0, true},
+		{"same players", []string{"alice"}, 5 * time.Second, false},
+		{"same players again", []string{"alice"}, 30 * time.Second, false},
+		{"half the stale gap since the last write", []string{"alice"}, playerSessionStaleGap / 2, true},
+		{"another join", []string{"alice", "bob"}, playerSessionStaleGap/2 + 5*time.Second, true},
+		{"steady", []string{"alice", "bob"}, playerSessionStaleGap/2 + 10*time.Second, false},
+		{"leave", []string{"bob"}, playerSessionStaleGap/2 + 15*time.Second, true},
+	}
+	for _, tt := range tests {
+		os.Remove(openPath)
+		s.Update("srv-1", "survival", tt.players, start.Add(tt.after))
+		_, err := os.Stat(openPath)
+		if written := err == nil; written != tt.written {
+			t.Errorf("%s: written = %v, want %v", tt.name, written, tt.written)
+		}
+	}
+
+	// A restart within the other half of the gap continues the sessions from disk
+	restarted := NewPlayerSessionStore(s.dir)
+	if err := restarted.Load(); err != nil {
+		t.Fatal(err)
+	}
+	joined, left := restarted.Update("srv-1", "survival", []string{"bob"}, start.Add(playerSessionStaleGap+10*time.Second))
+	if len(joined) != 0 || len(left) != 0 {
+		t.Errorf("after restart: joined %v, left %+v, want bob's session continued", joined, left)
+	}
+}
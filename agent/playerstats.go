@@ -2,8 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,36 +15,84 @@ import (
 	"github.com/gorcon/rcon"
 )
 
+// Player stats polling parameters
+const (
+	playerStatsWorkers        = 4                // Max servers polled concurrently
+	playerStatsServerTimeout  = 8 * time.Second  // Budget for one server (dial + command)
+	playerStatsDiscoverEvery  = 10 * time.Second // How often running containers are re-listed
+	playerStatsActiveInterval = 5 * time.Second  // Poll interval while players are online
+	playerStatsIdleInterval   = 10 * time.Second // Poll interval for a healthy server (default)
+	playerStatsEmptyInterval  = 30 * time.Second // Poll interval after a server has been empty for a while
+	playerStatsMaxInterval    = 60 * time.Second // Cap for the RCON failure backoff
+	playerStatsEmptyAfter     = 3                // Empty polls before slowing down
+)
+
+// RCON collection error codes reported in PlayerStats.ErrorCode
+const (
+	PlayerStatsErrInspect    = "INSPECT_FAILED"
+	PlayerStatsErrNoNetwork  = "NO_BACKEND_NETWORK"
+	PlayerStatsErrDial       = "RCON_DIAL_FAILED"
+	PlayerStatsErrCommand    = "RCON_COMMAND_FAILED"
+	PlayerStatsErrTimeout    = "TIMEOUT"
+	PlayerStatsErrNotRunning = "NOT_RUNNING"
+)
+
 // PlayerStats represents player information for a server
 type PlayerStats struct {
-	ServerID      string   `json:"serverId"`
-	ServerName    string   `json:"serverName"`
-	PlayerCount   int      `json:"playerCount"`
-	MaxPlayers    int      `json:"maxPlayers"`
-	Players       []string `json:"players"`
-	RCONConnected bool     `json:"rconConnected"` // P2: RCON health status
-	LastUpdate    int64    `json:"lastUpdate"`
+	ServerID            string   `json:"serverId"`
+	ServerName          string   `json:"serverName"`
+	PlayerCount         int      `json:"playerCount"`
+	MaxPlayers          int      `json:"maxPlayers"`
+	Players             []string `json:"players"`
+	RCONConnected       bool     `json:"rconConnected"` // P2: RCON health status
+	LastUpdate          int64    `json:"lastUpdate"`
+	CollectionMs        int64    `json:"collectionMs"`                  // Time spent collecting this sample
+	ErrorCode           string   `json:"errorCode,omitempty"`           // Why RCON failed (see PlayerStatsErr*)
+	Error               string   `json:"error,omitempty"`               // Underlying error message
+	ConsecutiveFailures int      `json:"consecutiveFailures,omitempty"` // Failed polls in a row
+	PollIntervalSeconds int      `json:"pollIntervalSeconds"`           // Current adaptive poll interval
 }
 
 // ServerRCONConfig holds RCON connection info for a server
 type ServerRCONConfig struct {
-	ServerID    string
-	ServerName  string
-	ContainerID string
-	RCONPort    int
+	ServerID     string
+	ServerName   string
+	ContainerID  string
+	RCONPort     int
 	RCONPassword string
-	MaxPlayers  int
+	MaxPlayers   int
 }
 
+// serverPollState holds the adaptive scheduling state for one server
+type serverPollState struct {
+	nextPoll    time.Time
+	interval    time.Duration
+	failures    int
+	emptyPolls  int
+	containerID string
+}
+
+// playerStatsError is a collection failure with a reportable code
+type playerStatsError struct {
+	code string
+	err  error
+}
+
+func (e *playerStatsError) Error() string { return e.err.Error() }
+
 // PlayerStatsCollector maintains persistent RCON connections and collects player stats
 type PlayerStatsCollector struct {
 	mu           sync.RWMutex
-	connections  map[string]*rcon.Conn // serverID -> RCON connection
-	stats        map[string]*PlayerStats // serverID -> latest stats
+	connections  map[string]*rcon.Conn       // serverID -> RCON connection
+	stats        map[string]*PlayerStats     // serverID -> latest stats
+	polls        map[string]*serverPollState // serverID -> schedule
+	configs      []ServerRCONConfig          // Last discovered servers
+	lastDiscover time.Time
+	sessions     *PlayerSessionStore // Join/leave tracking and playtime history
 	docker       *DockerClient
 	agent        *Agent
 	stopCh       chan struct{}
-	pollInterval time.Duration
+	pollInterval time.Duration // Scheduler tick (fastest possible per-server interval)
 }
 
 // NewPlayerStatsCollector creates a new player stats collector
@@ -52,22 +100,28 @@ func NewPlayerStatsCollector(docker *DockerClient, agent *Agent) *PlayerStatsCol
 	return &PlayerStatsCollector{
 		connections:  make(map[string]*rcon.Conn),
 		stats:        make(map[string]*PlayerStats),
+		polls:        make(map[string]*serverPollState),
+		sessions:     NewPlayerSessionStore(StateDir()),
 		docker:       docker,
 		agent:        agent,
 		stopCh:       make(chan struct{}),
-		pollInterval: 10 * time.Second,
+		pollInterval: playerStatsActiveInterval,
 	}
 }
 
 // Start begins the background polling loop
 func (psc *PlayerStatsCollector) Start() {
-	log.Println("[PlayerStats] Starting player stats collector (10s interval)")
+	playerStatsLogger.Infof("Starting player stats collector (adaptive %v-%v interval, %d workers)",
+		playerStatsActiveInterval, playerStatsMaxInterval, playerStatsWorkers)
+	if err := psc.sessions.Load(); err != nil {
+		playerStatsLogger.Warnf("Failed to load player sessions: %v", err)
+	}
 	go psc.pollLoop()
 }
 
 // Stop stops the collector and closes all connections
 func (psc *PlayerStatsCollector) Stop() {
-	log.Println("[PlayerStats] Stopping player stats collector")
+	playerStatsLogger.Infof("Stopping player stats collector")
 	close(psc.stopCh)
 
 	psc.mu.Lock()
@@ -75,7 +129,7 @@ func (psc *PlayerStatsCollector) Stop() {
 
 	for serverID, conn := range psc.connections {
 		conn.Close()
-		log.Printf("[PlayerStats] Closed RCON connection for server %s", serverID)
+		playerStatsLogger.Infof("Closed RCON connection for server %s", serverID)
 	}
 	psc.connections = make(map[string]*rcon.Conn)
 }
@@ -110,19 +164,25 @@ func (psc *PlayerStatsCollector) pollLoop() {
 	}
 }
 
-// collectAllStats discovers running servers and collects their player stats
+// collectAllStats polls every server whose adaptive interval has elapsed.
+// Servers are polled concurrently (bounded by playerStatsWorkers) and each one
+// gets playerStatsServerTimeout, so a hung server can't delay the others.
+// Collection continues while disconnected so session history has no gaps;
+// only the updates to the manager are skipped.
 func (psc *PlayerStatsCollector) collectAllStats() {
-	// Don't collect if not authenticated
-	if !psc.agent.IsAuthenticated() {
-		return
-	}
+	defer recordCollectorRun("playerstats", time.Now())
 
-	// Get running ZedOps containers
-	configs, err := psc.discoverServers()
-	if err != nil {
-		log.Printf("[PlayerStats] Failed to discover servers: %v", err)
-		return
+	// Refresh the list of running ZedOps containers
+	if time.Since(psc.lastDiscover) >= playerStatsDiscoverEvery {
+		configs, err := psc.discoverServers()
+		if err != nil {
+			playerStatsLogger.Errorf("Failed to discover servers: %v", err)
+			return
+		}
+		psc.configs = configs
+		psc.lastDiscover = time.Now()
 	}
+	configs := psc.configs
 
 	if len(configs) == 0 {
 		// No running servers, clear stats and connections
@@ -131,20 +191,109 @@ func (psc *PlayerStatsCollector) collectAllStats() {
 			conn.Close()
 			delete(psc.connections, serverID)
 		}
+		hadStats := len(psc.stats) > 0
+		if psc.agent.idle != nil {
+			for serverID := range psc.stats {
+				psc.agent.idle.Forget(serverID)
+			}
+		}
 		psc.stats = make(map[string]*PlayerStats)
+		psc.polls = make(map[string]*serverPollState)
 		psc.mu.Unlock()
+
+		for _, serverID := range psc.sessions.OpenServers() {
+			psc.closeServerSessions(serverID, "")
+		}
+		if hadStats && psc.agent.IsAuthenticated() {
+			psc.sendStatsUpdate(map[string]*PlayerStats{})
+		}
 		return
 	}
 
-	// Track which servers we found
+	// Track which servers we found and which are due
+	now := time.Now()
 	foundServers := make(map[string]bool)
+	var due []ServerRCONConfig
 
-	// Collect stats for each server
-	allStats := make(map[string]*PlayerStats)
+	psc.mu.Lock()
 	for _, config := range configs {
 		foundServers[config.ServerID] = true
-		stats := psc.collectServerStats(config)
-		allStats[config.ServerID] = stats // P2: Always include stats (even with RCONConnected=false)
+		state, ok := psc.polls[config.ServerID]
+		if !ok || state.containerID != config.ContainerID {
+			// New server (or recreated container): poll right away at the default rate
+			state = &serverPollState{interval: playerStatsIdleInterval, containerID: config.ContainerID}
+			psc.polls[config.ServerID] = state
+		}
+		if !now.Before(state.nextPoll) {
+			due = append(due, config)
+		}
+	}
+	for serverID := range psc.polls {
+		if !foundServers[serverID] {
+			delete(psc.polls, serverID)
+		}
+	}
+	psc.mu.Unlock()
+
+	// Collect stats for due servers with a bounded worker pool
+	allStats := make(map[string]*PlayerStats)
+	var resultsMu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, playerStatsWorkers)
+
+	for _, config := range due {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(config ServerRCONConfig) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			stats := psc.collectServerStatsWithTimeout(config, playerStatsServerTimeout)
+			resultsMu.Lock()
+			allStats[config.ServerID] = stats // P2: Always include stats (even with RCONConnected=false)
+			resultsMu.Unlock()
+		}(config)
+	}
+	wg.Wait()
+
+	// Reschedule polled servers
+	psc.mu.Lock()
+	for serverID, stats := range allStats {
+		if state, ok := psc.polls[serverID]; ok {
+			psc.reschedule(state, stats, now)
+			stats.PollIntervalSeconds = int(state.interval.Seconds())
+		}
+	}
+	psc.mu.Unlock()
+
+	// Diff player lists against open sessions (only when RCON gave us a real list)
+	for _, stats := range allStats {
+		if !stats.RCONConnected {
+			continue
+		}
+		joined, left := psc.sessions.Update(stats.ServerID, stats.ServerName, stats.Players, now)
+		psc.sendSessionEvents(stats.ServerID, stats.ServerName, joined, left, now)
+	}
+	for _, serverID := range psc.sessions.OpenServers() {
+		if !foundServers[serverID] {
+			psc.closeServerSessions(serverID, "")
+		}
+	}
+
+	// Feed player counts to the idle manager (only real RCON answers count as "empty")
+	if idle := psc.agent.idle; idle != nil {
+		for _, config := range configs {
+			if stats, ok := allStats[config.ServerID]; ok && stats.RCONConnected {
+				idle.Observe(config.ServerID, config.ServerName, config.ContainerID, stats.PlayerCount, now)
+			}
+		}
+		psc.mu.Lock()
+		for serverID := range psc.stats {
+			if !foundServers[serverID] {
+				idle.Forget(serverID)
+			}
+		}
+		psc.mu.Unlock()
 	}
 
 	// Close connections for servers that are no longer running
@@ -153,25 +302,66 @@ func (psc *PlayerStatsCollector) collectAllStats() {
 		if !foundServers[serverID] {
 			conn.Close()
 			delete(psc.connections, serverID)
+			playerStatsLogger.Infof("Closed connection for stopped server %s", serverID)
+		}
+	}
+	for serverID := range psc.stats {
+		if !foundServers[serverID] {
 			delete(psc.stats, serverID)
-			log.Printf("[PlayerStats] Closed connection for stopped server %s", serverID)
 		}
 	}
 	psc.mu.Unlock()
 
-	// Update stats and send to manager
-	if len(allStats) > 0 {
-		psc.mu.Lock()
-		for serverID, stats := range allStats {
-			psc.stats[serverID] = stats
-		}
-		psc.mu.Unlock()
+	if len(allStats) == 0 {
+		return
+	}
+
+	// Update stats and send the full current view to the manager
+	psc.mu.Lock()
+	for serverID, stats := range allStats {
+		psc.stats[serverID] = stats
+	}
+	psc.mu.Unlock()
 
-		// Send update to manager
-		psc.sendStatsUpdate(allStats)
+	if psc.agent.IsAuthenticated() {
+		psc.sendStatsUpdate(psc.GetStats())
 	}
 }
 
+// reschedule picks the next poll interval for a server from its latest result:
+// fast while players are online, slower once empty, backing off while RCON fails.
+// Must be called with psc.mu held.
+func (psc *PlayerStatsCollector) reschedule(state *serverPollState, stats *PlayerStats, now time.Time) {
+	switch {
+	case !stats.RCONConnected:
+		state.failures++
+		state.emptyPolls = 0
+		backoff := playerStatsIdleInterval
+		for i := 1; i < state.failures && backoff < playerStatsMaxInterval; i++ {
+			backoff *= 2
+		}
+		if backoff > playerStatsMaxInterval {
+			backoff = playerStatsMaxInterval
+		}
+		state.interval = backoff
+	case stats.PlayerCount > 0:
+		state.failures = 0
+		state.emptyPolls = 0
+		state.interval = playerStatsActiveInterval
+	default:
+		state.failures = 0
+		state.emptyPolls++
+		if state.emptyPolls >= playerStatsEmptyAfter {
+			state.interval = playerStatsEmptyInterval
+		} else {
+			state.interval = playerStatsIdleInterval
+		}
+	}
+
+	stats.ConsecutiveFailures = state.failures
+	state.nextPoll = now.Add(state.interval)
+}
+
 // discoverServers finds running ZedOps containers and gets their RCON config from container env
 func (psc *PlayerStatsCollector) discoverServers() ([]ServerRCONConfig, error) {
 	ctx := context.Background()
@@ -202,7 +392,7 @@ func (psc *PlayerStatsCollector) discoverServers() ([]ServerRCONConfig, error) {
 		// Inspect container to get environment variables
 		inspect, err := psc.docker.cli.ContainerInspect(ctx, c.ID)
 		if err != nil {
-			log.Printf("[PlayerStats] Failed to inspect container %s: %v", c.ID[:12], err)
+			playerStatsLogger.Errorf("Failed to inspect container %s: %v", c.ID[:12], err)
 			continue
 		}
 
@@ -224,6 +414,7 @@ func (psc *PlayerStatsCollector) discoverServers() ([]ServerRCONConfig, error) {
 			// RCON not configured, skip this server
 			continue
 		}
+		RegisterSecret(rconPassword)
 
 		// Get RCON port from env (default 27015)
 		rconPort := 27015
@@ -254,12 +445,44 @@ func (psc *PlayerStatsCollector) discoverServers() ([]ServerRCONConfig, error) {
 	return configs, nil
 }
 
-// collectServerStats collects player stats for a single server
-func (psc *PlayerStatsCollector) collectServerStats(config ServerRCONConfig) *PlayerStats {
-	conn := psc.getOrCreateConnection(config)
-	if conn == nil {
+// collectServerStatsWithTimeout collects stats for one server, giving up after timeout.
+// On timeout the server's RCON connection is closed, which unblocks the stuck command.
+func (psc *PlayerStatsCollector) collectServerStatsWithTimeout(config ServerRCONConfig, timeout time.Duration) *PlayerStats {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type result struct {
+		stats *PlayerStats
+		err   error
+	}
+	resultCh := make(chan result, 1)
+
+	go func() {
+		stats, err := psc.collectServerStats(ctx, config)
+		resultCh <- result{stats: stats, err: err}
+	}()
+
+	var stats *PlayerStats
+	var err error
+	select {
+	case r := <-resultCh:
+		stats, err = r.stats, r.err
+	case <-ctx.Done():
+		psc.dropConnection(config.ServerID)
+		err = &playerStatsError{code: PlayerStatsErrTimeout, err: fmt.Errorf("no response within %v", timeout)}
+	}
+
+	if err != nil {
+		code := PlayerStatsErrCommand
+		var pse *playerStatsError
+		if errors.As(err, &pse) {
+			code = pse.code
+		}
+		playerStatsLogger.Error("Collection failed", "server", config.ServerName, "serverId", config.ServerID, "code", code, "error", err)
+
 		// P2: Return stats with RCONConnected=false so frontend shows "Error"
-		return &PlayerStats{
+		stats = &PlayerStats{
 			ServerID:      config.ServerID,
 			ServerName:    config.ServerName,
 			PlayerCount:   0,
@@ -267,30 +490,31 @@ func (psc *PlayerStatsCollector) collectServerStats(config ServerRCONConfig) *Pl
 			Players:       nil,
 			RCONConnected: false,
 			LastUpdate:    time.Now().Unix(),
+			ErrorCode:     code,
+			Error:         err.Error(),
 		}
 	}
 
+	stats.CollectionMs = time.Since(start).Milliseconds()
+	return stats
+}
+
+// collectServerStats collects player stats for a single server
+func (psc *PlayerStatsCollector) collectServerStats(ctx context.Context, config ServerRCONConfig) (*PlayerStats, error) {
+	conn, err := psc.getOrCreateConnection(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
 	// Execute "players" command
 	response, err := conn.Execute("players")
 	if err != nil {
-		log.Printf("[PlayerStats] RCON command failed for %s: %v", config.ServerName, err)
 		// Connection might be broken, remove it so we reconnect next time
-		psc.mu.Lock()
-		if c, exists := psc.connections[config.ServerID]; exists {
-			c.Close()
-			delete(psc.connections, config.ServerID)
-		}
-		psc.mu.Unlock()
-		// P2: Return stats with RCONConnected=false so frontend shows "Error"
-		return &PlayerStats{
-			ServerID:      config.ServerID,
-			ServerName:    config.ServerName,
-			PlayerCount:   0,
-			MaxPlayers:    config.MaxPlayers,
-			Players:       nil,
-			RCONConnected: false,
-			LastUpdate:    time.Now().Unix(),
+		psc.dropConnection(config.ServerID)
+		if ctx.Err() != nil {
+			return nil, &playerStatsError{code: PlayerStatsErrTimeout, err: err}
 		}
+		return nil, &playerStatsError{code: PlayerStatsErrCommand, err: err}
 	}
 
 	// Parse player list from response
@@ -306,56 +530,73 @@ func (psc *PlayerStatsCollector) collectServerStats(config ServerRCONConfig) *Pl
 		LastUpdate:    time.Now().Unix(),
 	}
 
-	return stats
+	return stats, nil
+}
+
+// dropConnection closes and forgets the RCON connection for a server
+func (psc *PlayerStatsCollector) dropConnection(serverID string) {
+	psc.mu.Lock()
+	defer psc.mu.Unlock()
+
+	if c, exists := psc.connections[serverID]; exists {
+		c.Close()
+		delete(psc.connections, serverID)
+	}
 }
 
 // getOrCreateConnection gets an existing RCON connection or creates a new one
-func (psc *PlayerStatsCollector) getOrCreateConnection(config ServerRCONConfig) *rcon.Conn {
+func (psc *PlayerStatsCollector) getOrCreateConnection(ctx context.Context, config ServerRCONConfig) (*rcon.Conn, error) {
 	psc.mu.RLock()
 	conn, exists := psc.connections[config.ServerID]
 	psc.mu.RUnlock()
 
 	if exists {
-		return conn
+		return conn, nil
 	}
 
-	// Need to create new connection
-	ctx := context.Background()
-
-	// Get container IP
+	// Need to create new connection; get container IP
 	inspect, err := psc.docker.cli.ContainerInspect(ctx, config.ContainerID)
 	if err != nil {
-		log.Printf("[PlayerStats] Failed to inspect container %s: %v", config.ContainerID[:12], err)
-		return nil
+		return nil, &playerStatsError{code: PlayerStatsErrInspect, err: fmt.Errorf("failed to inspect container %s: %w", config.ContainerID[:12], err)}
 	}
 
-	network := inspect.NetworkSettings.Networks["zomboid-backend"]
-	if network == nil {
-		log.Printf("[PlayerStats] Container %s not on zomboid-backend network", config.ContainerID[:12])
-		return nil
+	if inspect.State != nil && !inspect.State.Running {
+		return nil, &playerStatsError{code: PlayerStatsErrNotRunning, err: fmt.Errorf("container %s is not running", config.ContainerID[:12])}
 	}
 
-	containerIP := network.IPAddress
-	if containerIP == "" {
-		log.Printf("[PlayerStats] Container %s has no IP", config.ContainerID[:12])
-		return nil
+	network := inspect.NetworkSettings.Networks["zomboid-backend"]
+	if network == nil || network.IPAddress == "" {
+		return nil, &playerStatsError{code: PlayerStatsErrNoNetwork, err: fmt.Errorf("container %s has no IP on zomboid-backend network", config.ContainerID[:12])}
 	}
 
-	addr := fmt.Sprintf("%s:%d", containerIP, config.RCONPort)
-	log.Printf("[PlayerStats] Connecting to %s RCON at %s", config.ServerName, addr)
+	addr := fmt.Sprintf("%s:%d", network.IPAddress, config.RCONPort)
+	playerStatsLogger.Infof("Connecting to %s RCON at %s", config.ServerName, addr)
 
-	conn, err = rcon.Dial(addr, config.RCONPassword, rcon.SetDialTimeout(10*time.Second))
+	// Bound both the dial and every command by what's left of the per-server budget
+	budget := playerStatsServerTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		budget = time.Until(deadline)
+	}
+	conn, err = rcon.Dial(addr, config.RCONPassword, rcon.SetDialTimeout(budget), rcon.SetDeadline(budget))
 	if err != nil {
-		log.Printf("[PlayerStats] RCON connection failed for %s: %v", config.ServerName, err)
-		return nil
+		if ctx.Err() != nil {
+			return nil, &playerStatsError{code: PlayerStatsErrTimeout, err: err}
+		}
+		return nil, &playerStatsError{code: PlayerStatsErrDial, err: err}
+	}
+
+	// The collection may have timed out while we were dialing
+	if ctx.Err() != nil {
+		conn.Close()
+		return nil, &playerStatsError{code: PlayerStatsErrTimeout, err: ctx.Err()}
 	}
 
 	psc.mu.Lock()
 	psc.connections[config.ServerID] = conn
 	psc.mu.Unlock()
 
-	log.Printf("[PlayerStats] Connected to %s RCON", config.ServerName)
-	return conn
+	playerStatsLogger.Infof("Connected to %s RCON", config.ServerName)
+	return conn, nil
 }
 
 // parsePlayersResponse parses the RCON "players" command response
@@ -420,13 +661,58 @@ func (psc *PlayerStatsCollector) sendStatsUpdate(stats map[string]*PlayerStats)
 	})
 
 	if err := psc.agent.sendMessage(msg); err != nil {
-		log.Printf("[PlayerStats] Failed to send stats update: %v", err)
+		playerStatsLogger.Errorf("Failed to send stats update: %v", err)
 	} else {
 		// Log summary
 		total := 0
 		for _, s := range statsList {
 			total += s.PlayerCount
 		}
-		log.Printf("[PlayerStats] Sent update: %d servers, %d total players", len(statsList), total)
+		playerStatsLogger.Infof("Sent update: %d servers, %d total players", len(statsList), total)
+	}
+}
+
+// closeServerSessions ends all open sessions for a server that stopped running
+func (psc *PlayerStatsCollector) closeServerSessions(serverID, serverName string) {
+	now := time.Now()
+	left := psc.sessions.CloseServer(serverID, now)
+	if len(left) > 0 {
+		if serverName == "" {
+			serverName = left[0].ServerName
+		}
+		psc.sendSessionEvents(serverID, serverName, nil, left, now)
+	}
+}
+
+// sendSessionEvents sends players.joined and players.left events to the manager.
+// Events are dropped while disconnected; the session store keeps the history.
+func (psc *PlayerStatsCollector) sendSessionEvents(serverID, serverName string, joined []string, left []PlayerSession, now time.Time) {
+	for _, session := range left {
+		playerStatsLogger.Infof("%s left %s (played %s)", session.Player, serverName, formatUptime(time.Duration(session.DurationSeconds)*time.Second))
+	}
+	for _, player := range joined {
+		playerStatsLogger.Infof("%s joined %s", player, serverName)
+	}
+
+	if !psc.agent.IsAuthenticated() {
+		return
+	}
+
+	for _, session := range left {
+		psc.agent.sendMessage(NewMessage("players.left", PlayerSessionEvent{
+			ServerID:        serverID,
+			ServerName:      serverName,
+			Player:          session.Player,
+			Timestamp:       session.End,
+			DurationSeconds: session.DurationSeconds,
+		}))
+	}
+	for _, player := range joined {
+		psc.agent.sendMessage(NewMessage("players.joined", PlayerSessionEvent{
+			ServerID:   serverID,
+			ServerName: serverName,
+			Player:     player,
+			Timestamp:  now.Unix(),
+		}))
 	}
 }
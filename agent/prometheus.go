@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrometheusExporter serves agent, host, container and player metrics in Prometheus text format
+type PrometheusExporter struct {
+	agent  *Agent
+	addr   string
+	token  string
+	server *http.Server
+
+	// Overridable for fixtures
+	collectHost func() (*HostMetrics, error)
+}
+
+// NewPrometheusExporter creates an exporter listening on addr. An empty token disables authentication.
+func NewPrometheusExporter(agent *Agent, addr, token string) *PrometheusExporter {
+	RegisterSecret(token)
+	e := &PrometheusExporter{
+		agent: agent,
+		addr:  addr,
+		token: token,
+		collectHost: func() (*HostMetrics, error) {
+			return agent.hostCollector.Latest()
+		},
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	e.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return e
+}
+
+// Start binds the listener and serves in the background
+func (e *PrometheusExporter) Start() error {
+	ln, err := net.Listen("tcp", e.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", e.addr, err)
+	}
+	auth := "no auth"
+	if e.token != "" {
+		auth = "bearer token required"
+	}
+	metricsLogger.Infof("Prometheus metrics on http://%s/metrics (%s)", ln.Addr(), auth)
+
+	go func() {
+		if err := e.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			metricsLogger.Errorf("Metrics listener stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the listener down
+func (e *PrometheusExporter) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.server.Shutdown(ctx)
+}
+
+// ServeHTTP handles /metrics
+func (e *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if e.token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(e.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="zedops-agent"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	e.WriteMetrics(&buf)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// WriteMetrics renders every metric family
+func (e *PrometheusExporter) WriteMetrics(w io.Writer) {
+	p := &promWriter{w: w}
+	e.writeAgentMetrics(p)
+	e.writeHostMetrics(p)
+	e.writeContainerMetrics(p)
+	e.writePlayerMetrics(p)
+}
+
+// writeAgentMetrics renders connection state, message counters and backup durations
+func (e *PrometheusExporter) writeAgentMetrics(p *promWriter) {
+	snap := agentStats.Snapshot()
+
+	p.header("zedops_agent_info", "Agent build information.", "gauge")
+	p.sample("zedops_agent_info", 1, "version", Version, "name", e.agent.agentName)
+
+	p.header("zedops_agent_start_time_seconds", "Agent start time in Unix seconds.", "gauge")
+	p.sample("zedops_agent_start_time_seconds", float64(snap.StartTime.Unix()))
+
+	p.header("zedops_agent_connected", "Whether the agent is connected and authenticated to the manager.", "gauge")
+	p.sample("zedops_agent_connected", boolFloat(e.agent.IsAuthenticated()))
+
+	p.header("zedops_agent_reconnects_total", "Manager connections lost since start.", "counter")
+	p.sample("zedops_agent_reconnects_total", float64(snap.Reconnects))
+
+	p.header("zedops_agent_messages_received_total", "Messages received from the manager by subject.", "counter")
+	for _, subject := range sortedKeys(snap.MessagesReceived) {
+		p.sample("zedops_agent_messages_received_total", float64(snap.MessagesReceived[subject]), "subject", subject)
+	}
+
+	p.header("zedops_agent_messages_sent_total", "Messages sent to the manager by subject.", "counter")
+	for _, subject := range sortedKeys(snap.MessagesSent) {
+		p.sample("zedops_agent_messages_sent_total", float64(snap.MessagesSent[subject]), "subject", subject)
+	}
+
+	p.header("zedops_agent_message_send_errors_total", "Messages that failed to send by subject.", "counter")
+	for _, subject := range sortedKeys(snap.SendErrors) {
+		p.sample("zedops_agent_message_send_errors_total", float64(snap.SendErrors[subject]), "subject", subject)
+	}
+
+	keys := make([]backupStatKey, 0, len(snap.BackupDurations))
+	for k := range snap.BackupDurations {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Operation != keys[j].Operation {
+			return keys[i].Operation < keys[j].Operation
+		}
+		return keys[i].Result < keys[j].Result
+	})
+	p.header("zedops_backup_duration_seconds", "Duration of backup create and restore operations.", "histogram")
+	for _, k := range keys {
+		h := snap.BackupDurations[k]
+		for i, bound := range h.Buckets {
+			p.sample("zedops_backup_duration_seconds_bucket", float64(h.Counts[i]),
+				"operation", k.Operation, "result", k.Result, "le", formatPromValue(bound))
+		}
+		p.sample("zedops_backup_duration_seconds_bucket", float64(h.Count), "operation", k.Operation, "result", k.Result, "le", "+Inf")
+		p.sample("zedops_backup_duration_seconds_sum", h.Sum, "operation", k.Operation, "result", k.Result)
+		p.sample("zedops_backup_duration_seconds_count", float64(h.Count), "operation", k.Operation, "result", k.Result)
+	}
+}
+
+// writeHostMetrics renders HostMetrics and DiskMetric
+func (e *PrometheusExporter) writeHostMetrics(p *promWriter) {
+	host, err := e.collectHost()
+	p.header("zedops_host_metrics_up", "Whether host metrics could be collected.", "gauge")
+	p.sample("zedops_host_metrics_up", boolFloat(err == nil))
+	if err != nil {
+		metricsLogger.Debugf("Host metrics unavailable for scrape: %v", err)
+		return
+	}
+
+	p.header("zedops_host_cpu_percent", "Host CPU usage in percent.", "gauge")
+	p.sample("zedops_host_cpu_percent", host.CPUPercent)
+	p.header("zedops_host_memory_used_bytes", "Host memory in use.", "gauge")
+	p.sample("zedops_host_memory_used_bytes", float64(host.MemoryUsedMB)*1024*1024)
+	p.header("zedops_host_memory_total_bytes", "Host memory total.", "gauge")
+	p.sample("zedops_host_memory_total_bytes", float64(host.MemoryTotalMB)*1024*1024)
+	p.header("zedops_host_swap_used_bytes", "Host swap in use.", "gauge")
+	p.sample("zedops_host_swap_used_bytes", float64(host.SwapUsedMB)*1024*1024)
+	p.header("zedops_host_swap_total_bytes", "Host swap total.", "gauge")
+	p.sample("zedops_host_swap_total_bytes", float64(host.SwapTotalMB)*1024*1024)
+
+	p.header("zedops_host_cpu_iowait_percent", "Host CPU time waiting on I/O in percent.", "gauge")
+	p.sample("zedops_host_cpu_iowait_percent", host.IOWaitPercent)
+	p.header("zedops_host_cpu_steal_percent", "Host CPU time taken by the hypervisor for other guests in percent.", "gauge")
+	p.sample("zedops_host_cpu_steal_percent", host.StealPercent)
+	p.header("zedops_host_cpu_core_percent", "Host CPU usage per core in percent.", "gauge")
+	for i, v := range host.CPUPerCore {
+		p.sample("zedops_host_cpu_core_percent", v, "cpu", strconv.Itoa(i))
+	}
+	p.header("zedops_host_cpus", "Host CPU cores.", "gauge")
+	p.sample("zedops_host_cpus", float64(host.CPUCount))
+
+	p.header("zedops_host_load1", "Host load average over 1 minute.", "gauge")
+	p.sample("zedops_host_load1", host.Load1)
+	p.header("zedops_host_load5", "Host load average over 5 minutes.", "gauge")
+	p.sample("zedops_host_load5", host.Load5)
+	p.header("zedops_host_load15", "Host load average over 15 minutes.", "gauge")
+	p.sample("zedops_host_load15", host.Load15)
+
+	p.header("zedops_host_network_receive_bytes_total", "Bytes received per host interface.", "counter")
+	for _, n := range host.Network {
+		p.sample("zedops_host_network_receive_bytes_total", float64(n.RxBytes), "interface", n.Name)
+	}
+	p.header("zedops_host_network_transmit_bytes_total", "Bytes sent per host interface.", "counter")
+	for _, n := range host.Network {
+		p.sample("zedops_host_network_transmit_bytes_total", float64(n.TxBytes), "interface", n.Name)
+	}
+
+	if psi := host.Pressure; psi != nil {
+		p.header("zedops_host_pressure_avg10_percent", "Share of the last 10s tasks were stalled on a resource (PSI).", "gauge")
+		p.header("zedops_host_pressure_stalled_seconds_total", "Time tasks were stalled on a resource (PSI).", "counter")
+		for _, r := range []struct {
+			name  string
+			stall PressureStall
+		}{{"cpu", psi.CPU}, {"memory", psi.Memory}, {"io", psi.IO}} {
+			p.sample("zedops_host_pressure_avg10_percent", r.stall.SomeAvg10, "resource", r.name, "kind", "some")
+			p.sample("zedops_host_pressure_avg10_percent", r.stall.FullAvg10, "resource", r.name, "kind", "full")
+			p.sample("zedops_host_pressure_stalled_seconds_total", float64(r.stall.SomeTotalUs)/1e6, "resource", r.name, "kind", "some")
+			p.sample("zedops_host_pressure_stalled_seconds_total", float64(r.stall.FullTotalUs)/1e6, "resource", r.name, "kind", "full")
+		}
+	}
+
+	p.header("zedops_host_disk_used_bytes", "Used space of filesystems holding server data.", "gauge")
+	for _, d := range host.Disks {
+		p.sample("zedops_host_disk_used_bytes", float64(d.UsedGB)*1024*1024*1024, "path", d.Path, "mountpoint", d.MountPoint, "label", d.Label)
+	}
+	p.header("zedops_host_disk_total_bytes", "Size of filesystems holding server data.", "gauge")
+	for _, d := range host.Disks {
+		p.sample("zedops_host_disk_total_bytes", float64(d.TotalGB)*1024*1024*1024, "path", d.Path, "mountpoint", d.MountPoint, "label", d.Label)
+	}
+	p.header("zedops_host_disk_used_percent", "Used space of filesystems holding server data in percent.", "gauge")
+	for _, d := range host.Disks {
+		p.sample("zedops_host_disk_used_percent", d.Percent, "path", d.Path, "mountpoint", d.MountPoint, "label", d.Label)
+	}
+}
+
+// writeContainerMetrics renders the metrics collector's last ContainerMetrics per server
+func (e *PrometheusExporter) writeContainerMetrics(p *promWriter) {
+	if e.agent.metricsCollector == nil {
+		return
+	}
+	servers := e.agent.metricsCollector.Latest()
+	sort.Slice(servers, func(i, j int) bool { return servers[i].ServerID < servers[j].ServerID })
+
+	families := []struct {
+		name, help, typ string
+		value           func(CollectedServerMetrics) float64
+	}{
+		{"zedops_container_cpu_percent", "Server container CPU usage in percent of one core.", "gauge",
+			func(s CollectedServerMetrics) float64 { return s.CPUPercent }},
+		{"zedops_container_memory_used_bytes", "Server container memory usage.", "gauge",
+			func(s CollectedServerMetrics) float64 { return float64(s.MemoryUsedMB) * 1024 * 1024 }},
+		{"zedops_container_memory_limit_bytes", "Server container memory limit.", "gauge",
+			func(s CollectedServerMetrics) float64 { return float64(s.MemoryLimitMB) * 1024 * 1024 }},
+		{"zedops_container_memory_rss_bytes", "Server container anonymous (resident) memory.", "gauge",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.MemoryRSSMB) * 1024 * 1024 }},
+		{"zedops_container_memory_cache_bytes", "Server container page cache.", "gauge",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.MemoryCacheMB) * 1024 * 1024 }},
+		{"zedops_container_disk_read_bytes_total", "Bytes read by the server container.", "counter",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.DiskReadBytes) }},
+		{"zedops_container_disk_write_bytes_total", "Bytes written by the server container.", "counter",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.DiskWriteBytes) }},
+		{"zedops_container_network_receive_bytes_total", "Bytes received by the server container.", "counter",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.NetRxBytes) }},
+		{"zedops_container_network_transmit_bytes_total", "Bytes sent by the server container.", "counter",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.NetTxBytes) }},
+		{"zedops_container_pids", "Processes and threads in the server container.", "gauge",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.PIDs) }},
+		{"zedops_container_cpu_throttled_periods_total", "CFS periods in which the server container was throttled.", "counter",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.CPUThrottledPeriods) }},
+		{"zedops_container_cpu_throttled_seconds_total", "Time the server container was throttled by its CPU limit.", "counter",
+			func(s CollectedServerMetrics) float64 { return s.Container.CPUThrottledSeconds }},
+		{"zedops_container_uptime_seconds", "Server container uptime.", "gauge",
+			func(s CollectedServerMetrics) float64 { return float64(s.Container.UptimeSeconds) }},
+		{"zedops_container_last_sample_timestamp_seconds", "When the container was last sampled (Unix seconds).", "gauge",
+			func(s CollectedServerMetrics) float64 { return float64(s.Timestamp) }},
+	}
+	for _, f := range families {
+		p.header(f.name, f.help, f.typ)
+		for _, s := range servers {
+			if s.Container == nil {
+				continue
+			}
+			p.sample(f.name, f.value(s), "server_id", s.ServerID, "server_name", s.ServerName)
+		}
+	}
+}
+
+// writePlayerMetrics renders per-server player counts and RCON health
+func (e *PrometheusExporter) writePlayerMetrics(p *promWriter) {
+	if e.agent.playerStats == nil {
+		return
+	}
+	statsByID := e.agent.playerStats.GetStats()
+	stats := make([]*PlayerStats, 0, len(statsByID))
+	for _, s := range statsByID {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ServerID < stats[j].ServerID })
+
+	families := []struct {
+		name, help string
+		value      func(*PlayerStats) float64
+	}{
+		{"zedops_server_players", "Players online.",
+			func(s *PlayerStats) float64 { return float64(s.PlayerCount) }},
+		{"zedops_server_max_players", "Configured player slots.",
+			func(s *PlayerStats) float64 { return float64(s.MaxPlayers) }},
+		{"zedops_server_rcon_up", "Whether the last RCON poll succeeded.",
+			func(s *PlayerStats) float64 { return boolFloat(s.RCONConnected) }},
+		{"zedops_server_rcon_consecutive_failures", "Failed RCON polls in a row.",
+			func(s *PlayerStats) float64 { return float64(s.ConsecutiveFailures) }},
+		{"zedops_server_rcon_poll_duration_seconds", "Time the last RCON poll took.",
+			func(s *PlayerStats) float64 { return float64(s.CollectionMs) / 1000 }},
+		{"zedops_server_rcon_last_poll_timestamp_seconds", "When the server was last polled (Unix seconds).",
+			func(s *PlayerStats) float64 { return float64(s.LastUpdate) }},
+	}
+	for _, f := range families {
+		p.header(f.name, f.help, "gauge")
+		for _, s := range stats {
+			p.sample(f.name, f.value(s), "server_id", s.ServerID, "server_name", s.ServerName)
+		}
+	}
+}
+
+// promWriter writes the Prometheus text exposition format
+type promWriter struct {
+	w io.Writer
+}
+
+func (p *promWriter) header(name, help, typ string) {
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes one sample; labels are name/value pairs
+func (p *promWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i])
+			b.WriteString(`="`)
+			b.WriteString(promLabelEscaper.Replace(labels[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatPromValue(value))
+	b.WriteByte('\n')
+	io.WriteString(p.w, b.String())
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatPromValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
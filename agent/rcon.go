@@ -3,13 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/docker/docker/client"
 	"github.com/google/uuid"
 	"github.com/gorcon/rcon"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 // RCONSession represents an active RCON connection
@@ -46,12 +48,16 @@ func NewRCONManager(dockerClient *client.Client) *RCONManager {
 }
 
 // Connect establishes a new RCON connection using Docker network
-func (rm *RCONManager) Connect(serverId, containerID string, port int, password string) (string, error) {
+func (rm *RCONManager) Connect(ctx context.Context, serverId, containerID string, port int, password string) (sessionId string, err error) {
+	ctx, span := startSpan(ctx, "rcon.connect",
+		attribute.String("zedops.server.id", serverId),
+		attribute.String("container.id", containerID))
+	defer func() { endSpan(span, err) }()
+
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
 	// Inspect container to get network IP
-	ctx := context.Background()
 	inspect, err := rm.dockerClient.ContainerInspect(ctx, containerID)
 	if err != nil {
 		return "", fmt.Errorf("failed to inspect container: %w", err)
@@ -71,7 +77,7 @@ func (rm *RCONManager) Connect(serverId, containerID string, port int, password
 	// Create connection address using internal container IP
 	addr := fmt.Sprintf("%s:%d", containerIP, port)
 
-	log.Printf("[RCON] Connecting to container %s (IP: %s, Port: %d)", containerID[:12], containerIP, port)
+	rconLogger.Infof("Connecting to container %s (IP: %s, Port: %d)", containerID[:12], containerIP, port)
 
 	// Dial RCON connection with timeout
 	conn, err := rcon.Dial(addr, password, rcon.SetDialTimeout(30*time.Second))
@@ -80,7 +86,7 @@ func (rm *RCONManager) Connect(serverId, containerID string, port int, password
 	}
 
 	// Generate session ID
-	sessionId := uuid.New().String()
+	sessionId = uuid.New().String()
 
 	// Store session
 	session := &RCONSession{
@@ -93,13 +99,16 @@ func (rm *RCONManager) Connect(serverId, containerID string, port int, password
 
 	rm.sessions[sessionId] = session
 
-	log.Printf("[RCON] Connected to %s (container: %s, session: %s)", addr, containerID[:12], sessionId)
+	rconLogger.Infof("Connected to %s (container: %s, session: %s)", addr, containerID[:12], sessionId)
 
 	return sessionId, nil
 }
 
 // Execute sends a command to an existing RCON session
-func (rm *RCONManager) Execute(sessionId, command string) (string, error) {
+func (rm *RCONManager) Execute(ctx context.Context, sessionId, command string) (response string, err error) {
+	_, span := startSpan(ctx, "rcon.execute", attribute.String("rcon.command", rconCommandName(command)))
+	defer func() { endSpan(span, err) }()
+
 	rm.mu.RLock()
 	session, exists := rm.sessions[sessionId]
 	rm.mu.RUnlock()
@@ -114,12 +123,16 @@ func (rm *RCONManager) Execute(sessionId, command string) (string, error) {
 	rm.mu.Unlock()
 
 	// Execute command
-	response, err := session.conn.Execute(command)
+	started := time.Now()
+	response, err = session.conn.Execute(command)
+	rconCommandHist.Record(ctx, time.Since(started).Seconds(), metric.WithAttributes(
+		attribute.String("command", rconCommandName(command)),
+		attribute.Bool("error", err != nil)))
 	if err != nil {
 		return "", fmt.Errorf("RCON command failed: %w", err)
 	}
 
-	log.Printf("[RCON] Executed command '%s' on session %s", command, sessionId)
+	rconLogger.Infof("Executed command '%s' on session %s", command, sessionId)
 
 	return response, nil
 }
@@ -136,13 +149,13 @@ func (rm *RCONManager) Disconnect(sessionId string) error {
 
 	// Close connection
 	if err := session.conn.Close(); err != nil {
-		log.Printf("[RCON] Warning: error closing session %s: %v", sessionId, err)
+		rconLogger.Warnf("Error closing session %s: %v", sessionId, err)
 	}
 
 	// Remove from sessions map
 	delete(rm.sessions, sessionId)
 
-	log.Printf("[RCON] Disconnected session %s", sessionId)
+	rconLogger.Infof("Disconnected session %s", sessionId)
 
 	return nil
 }
@@ -156,7 +169,7 @@ func (rm *RCONManager) cleanupIdleSessions() {
 			now := time.Now()
 			for sessionId, session := range rm.sessions {
 				if now.Sub(session.lastUsed) > 5*time.Minute {
-					log.Printf("[RCON] Auto-disconnect idle session %s (server: %s)", sessionId, session.serverId)
+					rconLogger.Infof("Auto-disconnect idle session %s (server: %s)", sessionId, session.serverId)
 					session.conn.Close()
 					delete(rm.sessions, sessionId)
 				}
@@ -180,8 +193,36 @@ func (rm *RCONManager) Close() {
 	// Close all sessions
 	for sessionId, session := range rm.sessions {
 		session.conn.Close()
-		log.Printf("[RCON] Closed session %s", sessionId)
+		rconLogger.Infof("Closed session %s", sessionId)
 	}
 
 	rm.sessions = make(map[string]*RCONSession)
 }
+
+// rconCommandNames are the Project Zomboid server commands reported by name in spans and
+// metrics; anything else is "other" so user input can't grow attribute cardinality
+var rconCommandNames = map[string]bool{
+	"additem": true, "addalltowhitelist": true, "adduser": true, "addusertowhitelist": true,
+	"addvehicle": true, "addxp": true, "alarm": true, "banid": true, "banuser": true,
+	"changeoption": true, "checkmodsneedupdate": true, "chopper": true, "clear": true,
+	"createhorde": true, "createhorde2": true, "godmod": true, "grantadmin": true,
+	"gunshot": true, "help": true, "invisible": true, "kick": true, "kickuser": true,
+	"lightning": true, "log": true, "noclip": true, "players": true, "quit": true,
+	"releasesafehouse": true, "reloadlua": true, "reloadoptions": true, "removeadmin": true,
+	"removeuserfromwhitelist": true, "removezombies": true, "replay": true, "save": true,
+	"servermsg": true, "sendpulse": true, "setaccesslevel": true, "showoptions": true,
+	"startrain": true, "startstorm": true, "stats": true, "stoprain": true,
+	"stopweather": true, "teleport": true, "teleportto": true, "thunder": true,
+	"unbanid": true, "unbanuser": true, "voiceban": true,
+}
+
+// rconCommandName returns the command verb without arguments, which may hold player names or
+// messages, or "other" when it isn't a known server command
+func rconCommandName(command string) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(command), " ")
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	if !rconCommandNames[name] {
+		return "other"
+	}
+	return name
+}
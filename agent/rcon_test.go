@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestRCONCommandName(t *testing.T) {
+	tests := map[string]string{
+		"players":                      "players",
+		"  SERVERMSG \"restart in 5\"": "servermsg",
+		"/kickuser Bob":                "kickuser",
+		"Bob said hello":               "other",
+		"":                             "other",
+	}
+	for command, want := range tests {
+		if got := rconCommandName(command); got != want {
+			t.Errorf("rconCommandName(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
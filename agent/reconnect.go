@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/url"
 	"time"
 
@@ -43,7 +42,7 @@ func (a *Agent) ConnectWithRetry(ctx context.Context) error {
 		}
 
 		attempt++
-		log.Printf("Connection attempt #%d (backoff: %v)", attempt, backoff)
+		connLogger.Infof("Connection attempt #%d (backoff: %v)", attempt, backoff)
 
 		// Parse manager URL
 		u, err := url.Parse(a.managerURL)
@@ -59,7 +58,7 @@ func (a *Agent) ConnectWithRetry(ctx context.Context) error {
 		// Attempt connection
 		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
 		if err != nil {
-			log.Printf("Connection failed: %v", err)
+			connLogger.Warnf("Connection failed: %v", err)
 
 			// Wait before retry with exponential backoff
 			select {
@@ -76,7 +75,7 @@ func (a *Agent) ConnectWithRetry(ctx context.Context) error {
 
 		// Connection successful
 		a.conn = conn
-		log.Println("WebSocket connection established")
+		connLogger.Infof("WebSocket connection established")
 
 		// Reset backoff on successful connection
 		backoff = initialBackoff
@@ -90,8 +89,6 @@ func (a *Agent) RunWithReconnect(ctx context.Context) error {
 	transientRetries := 0
 	authBackoff := initialBackoff
 	var firstFailure time.Time
-	alertSent := false
-	const alertDelay = 10 * time.Minute
 
 	for {
 		select {
@@ -105,7 +102,7 @@ func (a *Agent) RunWithReconnect(ctx context.Context) error {
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
-			log.Printf("Failed to connect: %v", err)
+			connLogger.Warnf("Failed to connect: %v", err)
 			continue
 		}
 
@@ -127,7 +124,7 @@ func (a *Agent) RunWithReconnect(ctx context.Context) error {
 					retryDelay = reconnectBackoff
 					if transientRetries <= 10 || transientRetries%5 == 0 {
 						elapsed := time.Since(firstFailure).Round(time.Second)
-						log.Printf("Auth failed (reconnecting), fast retry in %v (#%d, failing for %v)...",
+						connLogger.Warnf("Auth failed (reconnecting), fast retry in %v (#%d, failing for %v)...",
 							retryDelay, transientRetries, elapsed)
 					}
 				} else {
@@ -135,16 +132,14 @@ func (a *Agent) RunWithReconnect(ctx context.Context) error {
 					retryDelay = authBackoff
 					if transientRetries <= 10 || transientRetries%5 == 0 {
 						elapsed := time.Since(firstFailure).Round(time.Second)
-						log.Printf("Auth failed (transient), retrying (#%d, backoff %v, failing for %v)...",
+						connLogger.Warnf("Auth failed (transient), retrying (#%d, backoff %v, failing for %v)...",
 							transientRetries, retryDelay, elapsed)
 					}
 				}
 
-				// Send alert email after 10 minutes of continuous failure (once per outage)
-				if !alertSent && time.Since(firstFailure) >= alertDelay {
-					alertSent = true
-					go a.SendAlertEmail(err.Error(), firstFailure)
-				}
+				// The alert manager holds this back for the offline escalation delay (10 minutes
+				// by default) and notifies once per outage, even across restarts
+				a.NotifyAgentOffline(err.Error())
 
 				select {
 				case <-time.After(retryDelay):
@@ -162,39 +157,41 @@ func (a *Agent) RunWithReconnect(ctx context.Context) error {
 			}
 
 			// Permanent failure — server explicitly rejected us
-			log.Println("")
-			log.Println("========================================")
-			log.Println("AUTHENTICATION FAILED - AGENT STOPPING")
-			log.Println("========================================")
-			log.Printf("Error: %v", err)
-			log.Println("")
-			log.Println("Possible causes:")
-			log.Println("  - Agent was deleted from the manager")
-			log.Println("  - Token is invalid or expired")
-			log.Println("  - Agent name mismatch")
-			log.Println("")
-			log.Println("To fix:")
-			log.Println("  1. Generate a new token in the manager UI")
-			log.Println("  2. Re-run the install script with --token flag")
-			log.Printf("     Or manually update %s", GetTokenPath())
-			log.Println("  3. Restart the agent: sudo systemctl start zedops-agent")
-			log.Println("========================================")
+			connLogger.Errorf("")
+			connLogger.Errorf("========================================")
+			connLogger.Errorf("AUTHENTICATION FAILED - AGENT STOPPING")
+			connLogger.Errorf("========================================")
+			connLogger.Errorf("Error: %v", err)
+			connLogger.Errorf("")
+			connLogger.Errorf("Possible causes:")
+			connLogger.Errorf("  - Agent was deleted from the manager")
+			connLogger.Errorf("  - Token is invalid or expired")
+			connLogger.Errorf("  - Agent name mismatch")
+			connLogger.Errorf("")
+			connLogger.Errorf("To fix:")
+			connLogger.Errorf("  1. Generate a new token in the manager UI")
+			connLogger.Errorf("  2. Re-run the install script with --token flag")
+			connLogger.Errorf("     Or manually update %s", GetTokenPath())
+			connLogger.Errorf("  3. Restart the agent: sudo systemctl start zedops-agent")
+			connLogger.Errorf("========================================")
 			return fmt.Errorf("%w: %v", ErrAuthFailure, err)
 		}
 
-		// Auth succeeded — send recovery email if alert was sent during this outage
-		if alertSent {
-			go a.SendRecoveryEmail(firstFailure)
-		}
+		// Auth succeeded — resolve the outage (recipients of the offline alert hear about it)
+		a.NotifyAgentRecovered()
 
 		// Reset transient retry state
 		transientRetries = 0
 		authBackoff = initialBackoff
-		alertSent = false
 
 		// Mark as authenticated
 		a.setAuthenticated(true)
-		log.Println("Agent authenticated successfully")
+		connLogger.Infof("Agent authenticated successfully")
+
+		// Report scheduled backups that ran while disconnected
+		if a.backupScheduler != nil {
+			go a.backupScheduler.FlushResults()
+		}
 
 		// Start heartbeat
 		heartbeatCtx, heartbeatCancel := context.WithCancel(ctx)
@@ -215,18 +212,19 @@ func (a *Agent) RunWithReconnect(ctx context.Context) error {
 			a.conn.Close()
 			a.setAuthenticated(false) // Mark as not authenticated (also records lastDisconnect)
 			a.cleanupOnDisconnect()   // Reset log streaming state
-			log.Println("Connection lost, reconnecting...")
+			agentStats.Reconnected()
+			connLogger.Infof("Connection lost, reconnecting...")
 			time.Sleep(initialBackoff)
 			continue
 		case <-ctx.Done():
 			// Graceful shutdown
 			heartbeatCancel()
-			log.Println("Shutting down...")
+			connLogger.Infof("Shutting down...")
 			// Use mutex to prevent concurrent writes during shutdown
 			a.connMutex.Lock()
 			err := a.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 			if err != nil {
-				log.Println("Error sending close message:", err)
+				connLogger.Errorf("Error sending close message: %v", err)
 			}
 			a.conn.Close()
 			a.connMutex.Unlock()
@@ -243,19 +241,19 @@ func (a *Agent) sendHeartbeats(ctx context.Context) {
 	for {
 		select {
 		case <-ticker.C:
-			// Collect host metrics (pass DockerClient for disk path discovery)
-			metrics, err := CollectHostMetrics(a.docker)
+			// Collect host metrics (the collector uses DockerClient for disk path discovery)
+			metrics, err := a.hostCollector.Latest()
 			if err != nil {
-				log.Printf("Warning: Failed to collect metrics: %v", err)
+				connLogger.Warnf("Failed to collect metrics: %v", err)
 				// Send heartbeat without metrics (backward compatible)
 				msg := NewMessage("agent.heartbeat", map[string]string{
 					"agentId": a.agentID,
 				})
 				if err := a.sendMessage(msg); err != nil {
-					log.Printf("Failed to send heartbeat: %v", err)
+					connLogger.Errorf("Failed to send heartbeat: %v", err)
 					return
 				}
-				log.Println("Heartbeat sent (without metrics)")
+				connLogger.Debugf("Heartbeat sent (without metrics)")
 				continue
 			}
 
@@ -265,7 +263,7 @@ func (a *Agent) sendHeartbeats(ctx context.Context) {
 				"metrics": metrics,
 			})
 			if err := a.sendMessage(msg); err != nil {
-				log.Printf("Failed to send heartbeat: %v", err)
+				connLogger.Errorf("Failed to send heartbeat: %v", err)
 				return
 			}
 			// Log summary of metrics
@@ -276,7 +274,7 @@ func (a *Agent) sendHeartbeats(ctx context.Context) {
 				}
 				diskSummary += fmt.Sprintf("%s: %dGB/%dGB", disk.Label, disk.UsedGB, disk.TotalGB)
 			}
-			log.Printf("Heartbeat sent with metrics (CPU: %.1f%%, Mem: %dMB/%dMB, Disks: [%s])",
+			connLogger.Debugf("Heartbeat sent with metrics (CPU: %.1f%%, Mem: %dMB/%dMB, Disks: [%s])",
 				metrics.CPUPercent, metrics.MemoryUsedMB, metrics.MemoryTotalMB, diskSummary)
 		case <-ctx.Done():
 			return
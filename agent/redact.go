@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedPlaceholder replaces every redacted value
+const redactedPlaceholder = "[REDACTED]"
+
+// shortSecretLength: registered secrets shorter than this (typical RCON and admin passwords)
+// are only replaced where they stand alone, so "1234" doesn't mangle port 12345
+const shortSecretLength = 6
+
+// sensitiveKeyRe matches field/attribute/ENV names whose values are secrets
+var sensitiveKeyRe = regexp.MustCompile(`(?i)(passw(or)?d|pwd|token|secret|api[_-]?key|credential|private[_-]?key)`)
+
+// redactedSubjectFields lists sensitive fields per message subject whose names the
+// generic key pattern doesn't catch (dot-separated paths into the message data)
+var redactedSubjectFields = map[string][]string{
+	"rcon.connect":           {"password"},
+	"backup.create":          {"rconPassword"},
+	"backup.restore":         {"rconPassword"},
+	"server.create":          {"config.ADMIN_PASSWORD", "config.RCON_PASSWORD", "config.SERVER_PASSWORD"},
+	"server.rebuild":         {"config.ADMIN_PASSWORD", "config.RCON_PASSWORD", "config.SERVER_PASSWORD"},
+	"server.adopt":           {"config.ADMIN_PASSWORD", "config.RCON_PASSWORD", "config.SERVER_PASSWORD"},
+	"agent.register":         {"token"},
+	"agent.auth":             {"token"},
+	"agent.register.success": {"token"},
+	"agent.auth.success":     {"resendApiKey"},
+}
+
+// Free-text patterns: KEY=value / key: value, "key":"value" (JSON), bearer tokens and JWTs
+var (
+	redactKeyValueRe  = regexp.MustCompile(`(?i)\b([A-Za-z0-9_.-]*(?:passw(?:or)?d|pwd|token|secret|api[_-]?key|credential)[A-Za-z0-9_.-]*)(\s*[=:]\s*)(\[REDACTED\]|"[^"]*"|'[^']*'|[^\s,;&"'}\]]+)`)
+	redactJSONFieldRe = regexp.MustCompile(`(?i)("[^"]*(?:passw(?:or)?d|pwd|token|secret|api[_-]?key|credential)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	redactBearerRe    = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+	redactJWTRe       = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+)
+
+// registeredSecrets holds literal secret values the agent knows about (tokens, RCON passwords, API keys)
+var registeredSecrets = struct {
+	sync.RWMutex
+	values []string // Longest first so overlapping secrets are fully replaced
+}{}
+
+// RegisterSecret adds a literal value that must never appear in logs
+func RegisterSecret(value string) {
+	if value == "" || value == redactedPlaceholder {
+		return
+	}
+
+	registeredSecrets.Lock()
+	defer registeredSecrets.Unlock()
+
+	for _, v := range registeredSecrets.values {
+		if v == value {
+			return
+		}
+	}
+	registeredSecrets.values = append(registeredSecrets.values, value)
+	sort.Slice(registeredSecrets.values, func(i, j int) bool {
+		return len(registeredSecrets.values[i]) > len(registeredSecrets.values[j])
+	})
+}
+
+// IsSensitiveKey reports whether a field or attribute name holds a secret
+func IsSensitiveKey(key string) bool {
+	return sensitiveKeyRe.MatchString(key)
+}
+
+// RedactString removes secrets from free text
+func RedactString(s string) string {
+	if s == "" {
+		return s
+	}
+
+	registeredSecrets.RLock()
+	for _, secret := range registeredSecrets.values {
+		if !strings.Contains(s, secret) {
+			continue
+		}
+		if len(secret) < shortSecretLength {
+			s = replaceStandalone(s, secret)
+		} else {
+			s = strings.ReplaceAll(s, secret, redactedPlaceholder)
+		}
+	}
+	registeredSecrets.RUnlock()
+
+	s = redactJSONFieldRe.ReplaceAllString(s, `${1}"`+redactedPlaceholder+`"`)
+	s = redactKeyValueRe.ReplaceAllStringFunc(s, func(match string) string {
+		m := redactKeyValueRe.FindStringSubmatch(match)
+		if m[3] == redactedPlaceholder || m[3] == `"`+redactedPlaceholder+`"` {
+			return match
+		}
+		return m[1] + m[2] + redactedPlaceholder
+	})
+	s = redactBearerRe.ReplaceAllString(s, "${1}"+redactedPlaceholder)
+	s = redactJWTRe.ReplaceAllString(s, redactedPlaceholder)
+	return s
+}
+
+// replaceStandalone replaces occurrences of secret that aren't part of a longer word or number
+func replaceStandalone(s, secret string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, secret)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(secret)
+		standalone := (i == 0 || !isWordByte(s[i-1])) && (end == len(s) || !isWordByte(s[end]))
+		b.WriteString(s[:i])
+		if standalone {
+			b.WriteString(redactedPlaceholder)
+		} else {
+			b.WriteString(secret)
+		}
+		s = s[end:]
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// RedactValue returns a copy of v (any JSON-encodable value) with secrets removed:
+// fields with sensitive names are replaced and remaining strings are scrubbed.
+func RedactValue(v interface{}) interface{} {
+	return RedactMessageData("", v)
+}
+
+// RedactMessageData redacts message data, including the subject's known sensitive fields
+func RedactMessageData(subject string, data interface{}) interface{} {
+	return redactData(subject, data, nil)
+}
+
+// RegisterMessageSecrets registers the values of a message's sensitive fields, so they are
+// also redacted when they show up later in free text (e.g. an error echoing a container ENV)
+func RegisterMessageSecrets(subject string, data interface{}) {
+	redactData(subject, data, RegisterSecret)
+}
+
+// redactData redacts a JSON round-tripped copy of data, passing each removed string to found
+func redactData(subject string, data interface{}, found func(string)) interface{} {
+	if data == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return redactedPlaceholder // Can't inspect it, so don't log it
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return redactedPlaceholder
+	}
+
+	for _, path := range redactedSubjectFields[subject] {
+		redactPath(generic, strings.Split(path, "."), found)
+	}
+	return redactWalk(generic, found)
+}
+
+// redactPath replaces the value at a dot path if present
+func redactPath(v interface{}, path []string, found func(string)) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(path) == 0 {
+		return
+	}
+	child, ok := m[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		if s, ok := child.(string); ok && found != nil {
+			found(s)
+		}
+		m[path[0]] = redactedPlaceholder
+		return
+	}
+	redactPath(child, path[1:], found)
+}
+
+// redactWalk replaces sensitive keys and scrubs strings recursively
+func redactWalk(v interface{}, found func(string)) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if IsSensitiveKey(k) {
+				s, isString := child.(string)
+				if isString && found != nil {
+					found(s)
+				}
+				if !isString || (s != "" && s != redactedPlaceholder) {
+					val[k] = redactedPlaceholder
+				}
+				continue
+			}
+			val[k] = redactWalk(child, found)
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = redactWalk(child, found)
+		}
+		return val
+	case string:
+		return RedactString(val)
+	default:
+		return val
+	}
+}
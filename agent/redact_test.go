@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactStringRegisteredSecrets(t *testing.T) {
+	RegisterSecret("s3cr3t-long-token-value")
+	RegisterSecret("pz42") // Short RCON password
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"long secret embedded", "url=https://x/?k=s3cr3t-long-token-value&a=1", "url=https://x/?k=[REDACTED]&a=1"},
+		{"short secret alone", "connecting with pz42", "connecting with [REDACTED]"},
+		{"short secret quoted", `{"pw":"pz42"}`, `{"pw":"[REDACTED]"}`},
+		{"short secret repeated", "pz42/pz42", "[REDACTED]/[REDACTED]"},
+		{"short secret inside a word", "server pz4200 on port 16261", "server pz4200 on port 16261"},
+		{"sensitive key", "password=hunter2 user=admin", "password=[REDACTED] user=admin"},
+		{"bearer", "Authorization: Bearer abc.def", "Authorization: Bearer [REDACTED]"},
+		{"no secrets", "server started", "server started"},
+	}
+	for _, tt := range tests {
+		if got := RedactString(tt.in); got != tt.want {
+			t.Errorf("%s: RedactString(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterSecretIgnoresEmpty(t *testing.T) {
+	RegisterSecret("")
+	RegisterSecret(redactedPlaceholder)
+	if got := RedactString("nothing to hide"); got != "nothing to hide" {
+		t.Errorf("RedactString = %q", got)
+	}
+}
+
+// TestSecretsNeverReachLogCapture logs registered secrets every way the agent does and
+// checks that neither the ring buffer nor the log output contains them
+func TestSecretsNeverReachLogCapture(t *testing.T) {
+	var output bytes.Buffer
+	capture := NewLogCapture(100)
+	if err := SetupLogging(LoggingConfig{Level: slog.LevelDebug, Output: &output}, capture); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetupLogging(LoggingConfig{Level: slog.LevelInfo}, nil) })
+
+	// Bound before the secrets are known, like the package-level subsystem loggers
+	early := NewLogger("Test").With("rcon", "rp7", "admin", 8801)
+
+	const token = "agent-token-0123456789abcdef"
+	RegisterSecret(token)
+	RegisterMessageSecrets("rcon.connect", map[string]interface{}{"serverId": "srv-1", "password": "rp7"})
+	RegisterMessageSecrets("server.create", map[string]interface{}{
+		"config": map[string]interface{}{"ADMIN_PASSWORD": "8801", "RCON_PASSWORD": "rp7"},
+	})
+	secrets := []string{token, "rp7", "8801"}
+
+	logger := NewLogger("Test")
+	logger.Infof("auth with %s", token)
+	logger.Infof("rcon connect password rp7 failed")
+	logger.Warnf("RCON_PASSWORD=rp7 ADMIN_PASSWORD=8801")
+	logger.Errorf("request failed: %v", errors.New(`{"password":"rp7","token":"`+token+`"}`))
+	logger.Info("connect", "pass", "rp7", "admin", 8801, "err", errors.New("bad password rp7"))
+	logger.Info("config", "env", map[string]interface{}{"RCON_PASSWORD": "rp7", "note": "uses " + token})
+	logger.Info("group", slog.Group("rcon", slog.String("value", "rp7"), slog.Int("admin", 8801)))
+	logger.With("token", token).Info("bound")
+	early.Info("bound before registration")
+
+	history := capture.GetHistory(0)
+	if len(history) != 9 {
+		t.Fatalf("captured %d lines, want 9", len(history))
+	}
+	for _, line := range history {
+		raw, err := json.Marshal(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range secrets {
+			if containsStandalone(string(raw), secret) {
+				t.Errorf("secret %q reached the ring buffer: %s", secret, raw)
+			}
+		}
+	}
+	for _, secret := range secrets {
+		if containsStandalone(output.String(), secret) {
+			t.Errorf("secret %q reached the log output:\n%s", secret, output.String())
+		}
+	}
+}
+
+func containsStandalone(s, secret string) bool {
+	if len(secret) >= shortSecretLength {
+		return strings.Contains(s, secret)
+	}
+	return regexp.MustCompile(`(^|[^A-Za-z0-9_])` + regexp.QuoteMeta(secret) + `($|[^A-Za-z0-9_])`).MatchString(s)
+}
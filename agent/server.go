@@ -4,13 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
@@ -32,13 +32,13 @@ type ServerConfig struct {
 
 // CreateServer creates a new Project Zomboid server container
 func (dc *DockerClient) CreateServer(ctx context.Context, config ServerConfig) (string, error) {
-	log.Printf("Creating server: %s (image: %s:%s)", config.Name, config.Registry, config.ImageTag)
+	serverLogger.Infof("Creating server: %s (image: %s:%s)", config.Name, config.Registry, config.ImageTag)
 
 	// Construct full image path
 	fullImage := fmt.Sprintf("%s:%s", config.Registry, config.ImageTag)
 
 	// Pull latest image (always check registry for updates)
-	log.Printf("Pulling image: %s (checking registry for updates...)", fullImage)
+	serverLogger.Infof("Pulling image: %s (checking registry for updates...)", fullImage)
 	reader, err := dc.cli.ImagePull(ctx, fullImage, image.PullOptions{
 		// Note: Docker will check registry and pull if digest differs from local cache
 	})
@@ -59,11 +59,11 @@ func (dc *DockerClient) CreateServer(ctx context.Context, config ServerConfig) (
 		// Check if we actually downloaded new layers
 		outputStr := string(pullOutput)
 		if strings.Contains(outputStr, "Already exists") || strings.Contains(outputStr, "Layer already exists") {
-			log.Printf("Image up to date (using cached): %s", fullImage)
+			serverLogger.Infof("Image up to date (using cached): %s", fullImage)
 		} else if strings.Contains(outputStr, "Pull complete") || strings.Contains(outputStr, "Download complete") {
-			log.Printf("Image updated from registry: %s", fullImage)
+			serverLogger.Infof("Image updated from registry: %s", fullImage)
 		} else {
-			log.Printf("Image pulled: %s", fullImage)
+			serverLogger.Infof("Image pulled: %s", fullImage)
 		}
 	}
 
@@ -85,7 +85,7 @@ func (dc *DockerClient) CreateServer(ctx context.Context, config ServerConfig) (
 		return "", fmt.Errorf("failed to create data directory: %w", err)
 	}
 
-	log.Printf("Created volume directories: %s", basePath)
+	serverLogger.Infof("Created volume directories: %s", basePath)
 
 	// Configure port bindings
 	portBindings := nat.PortMap{
@@ -152,7 +152,7 @@ func (dc *DockerClient) CreateServer(ctx context.Context, config ServerConfig) (
 		return "", fmt.Errorf("failed to create container: %w", err)
 	}
 
-	log.Printf("Container created: %s (ID: %s)", containerName, resp.ID)
+	serverLogger.Infof("Container created: %s (ID: %s)", containerName, resp.ID)
 
 	// Start container
 	if err := dc.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
@@ -161,19 +161,19 @@ func (dc *DockerClient) CreateServer(ctx context.Context, config ServerConfig) (
 		return "", fmt.Errorf("failed to start container: %w", err)
 	}
 
-	log.Printf("Container started successfully: %s", resp.ID)
+	serverLogger.Infof("Container started successfully: %s", resp.ID)
 
 	// Inspect container to get the resolved full image name
 	// This is important because user might provide just "latest" but Docker
 	// resolves it to full name like "registry.gitlab.com/user/image:latest"
 	inspect, err := dc.cli.ContainerInspect(ctx, resp.ID)
 	if err != nil {
-		log.Printf("Warning: failed to inspect container for image name: %v", err)
+		serverLogger.Warnf("Failed to inspect container for image name: %v", err)
 		// Don't fail the creation, just return container ID
 		return resp.ID, nil
 	}
 
-	log.Printf("Resolved image name: %s (from tag: %s)", inspect.Config.Image, fullImage)
+	serverLogger.Infof("Resolved image name: %s (from tag: %s)", inspect.Config.Image, fullImage)
 
 	return resp.ID, nil
 }
@@ -181,7 +181,7 @@ func (dc *DockerClient) CreateServer(ctx context.Context, config ServerConfig) (
 // DeleteServer removes a server container and optionally its volumes
 // If containerID is empty, only removes data directories (for purging soft-deleted servers)
 func (dc *DockerClient) DeleteServer(ctx context.Context, containerID string, serverName string, removeVolumes bool, dataPath string) error {
-	log.Printf("Deleting server: containerID=%s, serverName=%s, removeVolumes=%v, dataPath=%s", containerID, serverName, removeVolumes, dataPath)
+	serverLogger.Infof("Deleting server: containerID=%s, serverName=%s, removeVolumes=%v, dataPath=%s", containerID, serverName, removeVolumes, dataPath)
 
 	// If containerID is provided, try to remove container
 	if containerID != "" {
@@ -189,35 +189,35 @@ func (dc *DockerClient) DeleteServer(ctx context.Context, containerID string, se
 		if serverName == "" {
 			inspect, err := dc.cli.ContainerInspect(ctx, containerID)
 			if err != nil {
-				log.Printf("Warning: failed to inspect container (may not exist): %v", err)
+				serverLogger.Warnf("Failed to inspect container (may not exist): %v", err)
 			} else {
 				serverName = inspect.Config.Labels["zedops.server.name"]
 			}
 		}
 
 		if serverName != "" {
-			log.Printf("Removing container for server: %s", serverName)
+			serverLogger.Infof("Removing container for server: %s", serverName)
 		}
 
 		// Graceful save before stopping
 		dc.GracefulSave(ctx, containerID)
 
 		// Stop container if running
-		log.Printf("Stopping container: %s", containerID)
+		serverLogger.Infof("Stopping container: %s", containerID)
 		timeout := GracefulStopTimeout
 		if err := dc.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout}); err != nil {
-			log.Printf("Warning: failed to stop container (may already be stopped): %v", err)
+			serverLogger.Warnf("Failed to stop container (may already be stopped): %v", err)
 		}
 
 		// Remove container
-		log.Printf("Removing container: %s", containerID)
+		serverLogger.Infof("Removing container: %s", containerID)
 		if err := dc.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
 			Force:         true,
 			RemoveVolumes: false, // We manage volumes manually
 		}); err != nil {
-			log.Printf("Warning: failed to remove container (may not exist): %v", err)
+			serverLogger.Warnf("Failed to remove container (may not exist): %v", err)
 		} else {
-			log.Printf("Container removed successfully: %s", containerID)
+			serverLogger.Infof("Container removed successfully: %s", containerID)
 		}
 	}
 
@@ -225,15 +225,15 @@ func (dc *DockerClient) DeleteServer(ctx context.Context, containerID string, se
 	// IMPORTANT: This works even if container doesn't exist (for purging soft-deleted servers)
 	if removeVolumes && serverName != "" && dataPath != "" {
 		basePath := filepath.Join(dataPath, serverName)
-		log.Printf("Removing volume directories: %s", basePath)
+		serverLogger.Infof("Removing volume directories: %s", basePath)
 		if err := os.RemoveAll(basePath); err != nil {
 			return fmt.Errorf("failed to remove volumes: %w", err)
 		}
-		log.Printf("Volumes removed successfully")
+		serverLogger.Infof("Volumes removed successfully")
 	} else if serverName != "" && dataPath != "" {
-		log.Printf("Volumes preserved at: %s", filepath.Join(dataPath, serverName))
+		serverLogger.Infof("Volumes preserved at: %s", filepath.Join(dataPath, serverName))
 	} else {
-		log.Printf("Warning: Cannot determine server name or data path, skipping volume removal")
+		serverLogger.Warnf("Cannot determine server name or data path, skipping volume removal")
 	}
 
 	return nil
@@ -241,7 +241,7 @@ func (dc *DockerClient) DeleteServer(ctx context.Context, containerID string, se
 
 // RebuildServer rebuilds a server container with the latest image while preserving volumes
 func (dc *DockerClient) RebuildServer(ctx context.Context, containerID string) (string, error) {
-	log.Printf("Rebuilding server container: %s", containerID)
+	serverLogger.Infof("Rebuilding server container: %s", containerID)
 
 	// 1. Inspect existing container to extract config
 	inspect, err := dc.cli.ContainerInspect(ctx, containerID)
@@ -268,10 +268,10 @@ func (dc *DockerClient) RebuildServer(ctx context.Context, containerID string) (
 		networkNames = append(networkNames, networkName)
 	}
 
-	log.Printf("Container config extracted: image=%s, name=%s, networks=%v", oldImage, containerName, networkNames)
+	serverLogger.Infof("Container config extracted: image=%s, name=%s, networks=%v", oldImage, containerName, networkNames)
 
 	// 2. Pull latest image
-	log.Printf("Pulling latest image: %s (checking registry for updates...)", oldImage)
+	serverLogger.Infof("Pulling latest image: %s (checking registry for updates...)", oldImage)
 	reader, err := dc.cli.ImagePull(ctx, oldImage, image.PullOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to pull image: %w", err)
@@ -287,11 +287,11 @@ func (dc *DockerClient) RebuildServer(ctx context.Context, containerID string) (
 	if len(pullOutput) > 0 {
 		outputStr := string(pullOutput)
 		if strings.Contains(outputStr, "Already exists") || strings.Contains(outputStr, "Layer already exists") {
-			log.Printf("Image up to date (using cached): %s", oldImage)
+			serverLogger.Infof("Image up to date (using cached): %s", oldImage)
 		} else if strings.Contains(outputStr, "Pull complete") || strings.Contains(outputStr, "Download complete") {
-			log.Printf("Image updated from registry: %s", oldImage)
+			serverLogger.Infof("Image updated from registry: %s", oldImage)
 		} else {
-			log.Printf("Image pulled: %s", oldImage)
+			serverLogger.Infof("Image pulled: %s", oldImage)
 		}
 	}
 
@@ -300,20 +300,20 @@ func (dc *DockerClient) RebuildServer(ctx context.Context, containerID string) (
 	if err == nil {
 		if version, ok := imgInspect.Config.Labels["org.opencontainers.image.version"]; ok {
 			labels["org.opencontainers.image.version"] = version
-			log.Printf("Updated image version label: %s", version)
+			serverLogger.Infof("Updated image version label: %s", version)
 		}
 	}
 
 	// 4. Graceful save, then stop and remove old container
 	dc.GracefulSave(ctx, containerID)
 
-	log.Printf("Stopping old container: %s", containerID)
+	serverLogger.Infof("Stopping old container: %s", containerID)
 	timeout := GracefulStopTimeout
 	if err := dc.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout}); err != nil {
-		log.Printf("Warning: failed to stop container (may already be stopped): %v", err)
+		serverLogger.Warnf("Failed to stop container (may already be stopped): %v", err)
 	}
 
-	log.Printf("Removing old container: %s", containerID)
+	serverLogger.Infof("Removing old container: %s", containerID)
 	if err := dc.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
 		Force:         true,
 		RemoveVolumes: false, // Preserve volumes
@@ -344,14 +344,14 @@ func (dc *DockerClient) RebuildServer(ctx context.Context, containerID string) (
 		networkConfig.EndpointsConfig[networkName] = &network.EndpointSettings{}
 	}
 
-	log.Printf("Creating new container: %s", containerName)
+	serverLogger.Infof("Creating new container: %s", containerName)
 	resp, err := dc.cli.ContainerCreate(ctx, containerConfig, hostConfig, networkConfig, nil, containerName)
 	if err != nil {
 		return "", fmt.Errorf("failed to create new container: %w", err)
 	}
 
 	newContainerID := resp.ID
-	log.Printf("New container created: %s (ID: %s)", containerName, newContainerID)
+	serverLogger.Infof("New container created: %s (ID: %s)", containerName, newContainerID)
 
 	// 5. Start new container
 	if err := dc.cli.ContainerStart(ctx, newContainerID, container.StartOptions{}); err != nil {
@@ -360,24 +360,24 @@ func (dc *DockerClient) RebuildServer(ctx context.Context, containerID string) (
 		return "", fmt.Errorf("failed to start new container: %w", err)
 	}
 
-	log.Printf("New container started successfully: %s", newContainerID)
-	log.Printf("Server rebuild complete: %s -> %s", containerID, newContainerID)
+	serverLogger.Infof("New container started successfully: %s", newContainerID)
+	serverLogger.Infof("Server rebuild complete: %s -> %s", containerID, newContainerID)
 
 	return newContainerID, nil
 }
 
 // RebuildServerWithConfig rebuilds a server container, optionally with new configuration
 func (dc *DockerClient) RebuildServerWithConfig(ctx context.Context, req ServerRebuildRequest) (string, error) {
-	log.Printf("Rebuilding server container: %s", req.ContainerID)
+	serverLogger.Infof("Rebuilding server container: %s", req.ContainerID)
 
 	// If new config provided, use it; otherwise use existing container config
 	if req.Name != "" && req.Registry != "" && req.Config != nil {
-		log.Printf("Rebuilding with new configuration (config update mode)")
+		serverLogger.Infof("Rebuilding with new configuration (config update mode)")
 		return dc.rebuildWithNewConfig(ctx, req)
 	}
 
 	// Default: simple rebuild with existing config
-	log.Printf("Rebuilding with existing configuration (simple rebuild mode)")
+	serverLogger.Infof("Rebuilding with existing configuration (simple rebuild mode)")
 	return dc.RebuildServer(ctx, req.ContainerID)
 }
 
@@ -399,7 +399,7 @@ func (dc *DockerClient) rebuildWithNewConfig(ctx context.Context, req ServerRebu
 		networkNames = append(networkNames, networkName)
 	}
 
-	log.Printf("Container config extracted: name=%s, networks=%v", containerName, networkNames)
+	serverLogger.Infof("Container config extracted: name=%s, networks=%v", containerName, networkNames)
 
 	// 2. Construct full image path
 	// Check if ImageTag already contains a colon (full image reference like "registry.com/repo/image:tag")
@@ -415,7 +415,7 @@ func (dc *DockerClient) rebuildWithNewConfig(ctx context.Context, req ServerRebu
 	}
 
 	// 3. Pull latest image
-	log.Printf("Pulling image: %s (checking registry for updates...)", fullImage)
+	serverLogger.Infof("Pulling image: %s (checking registry for updates...)", fullImage)
 	reader, err := dc.cli.ImagePull(ctx, fullImage, image.PullOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to pull image: %w", err)
@@ -431,11 +431,11 @@ func (dc *DockerClient) rebuildWithNewConfig(ctx context.Context, req ServerRebu
 	if len(pullOutput) > 0 {
 		outputStr := string(pullOutput)
 		if strings.Contains(outputStr, "Already exists") || strings.Contains(outputStr, "Layer already exists") {
-			log.Printf("Image up to date (using cached): %s", fullImage)
+			serverLogger.Infof("Image up to date (using cached): %s", fullImage)
 		} else if strings.Contains(outputStr, "Pull complete") || strings.Contains(outputStr, "Download complete") {
-			log.Printf("Image updated from registry: %s", fullImage)
+			serverLogger.Infof("Image updated from registry: %s", fullImage)
 		} else {
-			log.Printf("Image pulled: %s", fullImage)
+			serverLogger.Infof("Image pulled: %s", fullImage)
 		}
 	}
 
@@ -444,10 +444,10 @@ func (dc *DockerClient) rebuildWithNewConfig(ctx context.Context, req ServerRebu
 	if imgErr == nil {
 		if version, ok := imgInspect.Config.Labels["org.opencontainers.image.version"]; ok {
 			labels["org.opencontainers.image.version"] = version
-			log.Printf("Updated image version label: %s", version)
+			serverLogger.Infof("Updated image version label: %s", version)
 		}
 	} else {
-		log.Printf("Warning: could not inspect image %s for version label: %v", fullImage, imgErr)
+		serverLogger.Warnf("Could not inspect image %s for version label: %v", fullImage, imgErr)
 	}
 
 	// 5. Convert config map to ENV array
@@ -462,7 +462,7 @@ func (dc *DockerClient) rebuildWithNewConfig(ctx context.Context, req ServerRebu
 	dataPath := filepath.Join(basePath, "data")
 
 	// Note: directories should already exist from initial creation
-	log.Printf("Using volume directories: %s", basePath)
+	serverLogger.Infof("Using volume directories: %s", basePath)
 
 	// 6. Configure port bindings
 	portBindings := nat.PortMap{
@@ -482,13 +482,13 @@ func (dc *DockerClient) rebuildWithNewConfig(ctx context.Context, req ServerRebu
 	// 7. Graceful save, then stop and remove old container
 	dc.GracefulSave(ctx, req.ContainerID)
 
-	log.Printf("Stopping old container: %s", req.ContainerID)
+	serverLogger.Infof("Stopping old container: %s", req.ContainerID)
 	timeout := GracefulStopTimeout
 	if err := dc.cli.ContainerStop(ctx, req.ContainerID, container.StopOptions{Timeout: &timeout}); err != nil {
-		log.Printf("Warning: failed to stop container (may already be stopped): %v", err)
+		serverLogger.Warnf("Failed to stop container (may already be stopped): %v", err)
 	}
 
-	log.Printf("Removing old container: %s", req.ContainerID)
+	serverLogger.Infof("Removing old container: %s", req.ContainerID)
 	if err := dc.cli.ContainerRemove(ctx, req.ContainerID, container.RemoveOptions{
 		Force:         true,
 		RemoveVolumes: false, // Preserve volumes
@@ -531,14 +531,14 @@ func (dc *DockerClient) rebuildWithNewConfig(ctx context.Context, req ServerRebu
 		networkConfig.EndpointsConfig[networkName] = &network.EndpointSettings{}
 	}
 
-	log.Printf("Creating new container with updated config: %s", containerName)
+	serverLogger.Infof("Creating new container with updated config: %s", containerName)
 	resp, err := dc.cli.ContainerCreate(ctx, containerConfig, hostConfig, networkConfig, nil, containerName)
 	if err != nil {
 		return "", fmt.Errorf("failed to create new container: %w", err)
 	}
 
 	newContainerID := resp.ID
-	log.Printf("New container created: %s (ID: %s)", containerName, newContainerID)
+	serverLogger.Infof("New container created: %s (ID: %s)", containerName, newContainerID)
 
 	// 9. Start new container
 	if err := dc.cli.ContainerStart(ctx, newContainerID, container.StartOptions{}); err != nil {
@@ -547,8 +547,8 @@ func (dc *DockerClient) rebuildWithNewConfig(ctx context.Context, req ServerRebu
 		return "", fmt.Errorf("failed to start new container: %w", err)
 	}
 
-	log.Printf("New container started successfully with updated configuration: %s", newContainerID)
-	log.Printf("Server rebuild complete: %s -> %s", req.ContainerID, newContainerID)
+	serverLogger.Infof("New container started successfully with updated configuration: %s", newContainerID)
+	serverLogger.Infof("Server rebuild complete: %s -> %s", req.ContainerID, newContainerID)
 
 	return newContainerID, nil
 }
@@ -723,7 +723,7 @@ func (dc *DockerClient) MoveServerData(serverName, oldBasePath, newBasePath stri
 	srcDir := filepath.Join(oldBasePath, serverName)
 	dstDir := filepath.Join(newBasePath, serverName)
 
-	log.Printf("Moving server data: %s -> %s", srcDir, dstDir)
+	serverLogger.Infof("Moving server data: %s -> %s", srcDir, dstDir)
 
 	// Validate source exists
 	if !dirExists(srcDir) {
@@ -757,7 +757,7 @@ func (dc *DockerClient) MoveServerData(serverName, oldBasePath, newBasePath stri
 		return nil, fmt.Errorf("failed to calculate source size: %w", err)
 	}
 
-	log.Printf("Source size: %d bytes, %d files", totalBytes, totalFiles)
+	serverLogger.Infof("Source size: %d bytes, %d files", totalBytes, totalFiles)
 
 	// Send initial progress (calculating complete, starting copy)
 	if progressFn != nil {
@@ -807,7 +807,7 @@ func (dc *DockerClient) MoveServerData(serverName, oldBasePath, newBasePath stri
 			if totalBytes > 0 {
 				percent = int((bytesCopied * 100) / totalBytes)
 			}
-			log.Printf("Copy progress: %d%% (%d/%d files, %d/%d bytes)", percent, filesCopied, totalFiles, bytesCopied, totalBytes)
+			serverLogger.Infof("Copy progress: %d%% (%d/%d files, %d/%d bytes)", percent, filesCopied, totalFiles, bytesCopied, totalBytes)
 
 			// Send progress update
 			if progressFn != nil {
@@ -828,12 +828,12 @@ func (dc *DockerClient) MoveServerData(serverName, oldBasePath, newBasePath stri
 
 	if err != nil {
 		// Clean up partial copy on failure
-		log.Printf("Copy failed, cleaning up partial destination: %s", dstDir)
+		serverLogger.Errorf("Copy failed, cleaning up partial destination: %s", dstDir)
 		os.RemoveAll(dstDir)
 		return nil, fmt.Errorf("failed to copy data: %w", err)
 	}
 
-	log.Printf("Copy complete: %d files, %d bytes", filesCopied, bytesCopied)
+	serverLogger.Infof("Copy complete: %d files, %d bytes", filesCopied, bytesCopied)
 
 	// Send verifying progress
 	if progressFn != nil {
@@ -858,7 +858,7 @@ func (dc *DockerClient) MoveServerData(serverName, oldBasePath, newBasePath stri
 
 	if dstFileCount != totalFiles {
 		// Clean up and fail
-		log.Printf("File count mismatch after copy: expected %d, got %d", totalFiles, dstFileCount)
+		serverLogger.Errorf("File count mismatch after copy: expected %d, got %d", totalFiles, dstFileCount)
 		os.RemoveAll(dstDir)
 		return nil, fmt.Errorf("copy verification failed: file count mismatch (expected %d, got %d)", totalFiles, dstFileCount)
 	}
@@ -876,13 +876,13 @@ func (dc *DockerClient) MoveServerData(serverName, oldBasePath, newBasePath stri
 	}
 
 	// Delete source directory (only after successful copy)
-	log.Printf("Removing source directory: %s", srcDir)
+	serverLogger.Infof("Removing source directory: %s", srcDir)
 	if err := os.RemoveAll(srcDir); err != nil {
 		// Log but don't fail - data is already copied
-		log.Printf("Warning: failed to remove source directory (data already copied): %v", err)
+		serverLogger.Warnf("Failed to remove source directory (data already copied): %v", err)
 	}
 
-	log.Printf("Server data move complete: %s", serverName)
+	serverLogger.Infof("Server data move complete: %s", serverName)
 
 	// Send complete progress
 	if progressFn != nil {
@@ -926,7 +926,7 @@ func (dc *DockerClient) GetContainerDataPath(ctx context.Context, containerID st
 			serverPath := strings.TrimSuffix(binPath, "/bin")
 			// Remove server name to get base path
 			basePath := filepath.Dir(serverPath)
-			log.Printf("Extracted data path from container mounts: %s (from bin mount: %s)", basePath, binPath)
+			serverLogger.Infof("Extracted data path from container mounts: %s (from bin mount: %s)", basePath, binPath)
 			return basePath, nil
 		}
 	}
@@ -934,6 +934,26 @@ func (dc *DockerClient) GetContainerDataPath(ctx context.Context, containerID st
 	return "", fmt.Errorf("no bin mount found in container (expected mount at /home/steam/zomboid-dedicated)")
 }
 
+// ServerLocation finds a server's container by server ID and returns the server's current name
+// and data path, for state saved before a rename or data move
+func (dc *DockerClient) ServerLocation(ctx context.Context, serverID string) (string, string, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", "zedops.server.id="+serverID)
+	containers, err := dc.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: filterArgs})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list containers: %w", err)
+	}
+	if len(containers) == 0 {
+		return "", "", fmt.Errorf("no container for server %s", serverID)
+	}
+
+	dataPath, err := dc.GetContainerDataPath(ctx, containers[0].ID)
+	if err != nil {
+		return "", "", err
+	}
+	return containers[0].Labels["zedops.server.name"], dataPath, nil
+}
+
 // ReadServerINI reads a PZ server's .ini file and extracts Mods and WorkshopItems lines.
 // It inspects the container to find the data mount, then reads {dataMount}/Server/{serverName}.ini.
 func (dc *DockerClient) ReadServerINI(ctx context.Context, containerID, serverName string) (*ServerReadINIResponse, error) {
@@ -1187,7 +1207,7 @@ type AdoptProgressCallback func(progress AdoptProgress)
 // ({dataPath}/{name}/bin and {dataPath}/{name}/data), removes the old container,
 // and creates a new one with proper ZedOps labels pointing at the standard paths.
 func (dc *DockerClient) AdoptServer(ctx context.Context, req ServerAdoptRequest, progressFn AdoptProgressCallback) (*AdoptResult, error) {
-	log.Printf("Adopting container %s as server '%s' (dataPath: %s)", req.ContainerID, req.Name, req.DataPath)
+	serverLogger.Infof("Adopting container %s as server '%s' (dataPath: %s)", req.ContainerID, req.Name, req.DataPath)
 
 	// 1. Inspect existing container to capture state and mounts
 	inspect, err := dc.cli.ContainerInspect(ctx, req.ContainerID)
@@ -1209,7 +1229,7 @@ func (dc *DockerClient) AdoptServer(ctx context.Context, req ServerAdoptRequest,
 		}
 	}
 
-	log.Printf("Existing mounts — bin: %q, data: %q", oldBinSource, oldDataSource)
+	serverLogger.Infof("Existing mounts — bin: %q, data: %q", oldBinSource, oldDataSource)
 
 	// 3. Determine standard ZedOps layout paths
 	serverDir := filepath.Join(req.DataPath, req.Name)
@@ -1236,7 +1256,7 @@ func (dc *DockerClient) AdoptServer(ctx context.Context, req ServerAdoptRequest,
 		dc.GracefulSave(ctx, req.ContainerID)
 		timeout := GracefulStopTimeout
 		if err := dc.cli.ContainerStop(ctx, req.ContainerID, container.StopOptions{Timeout: &timeout}); err != nil {
-			log.Printf("Warning: failed to stop container: %v", err)
+			serverLogger.Warnf("Failed to stop container: %v", err)
 		}
 	}
 
@@ -1263,7 +1283,7 @@ func (dc *DockerClient) AdoptServer(ctx context.Context, req ServerAdoptRequest,
 
 	// 7. Migrate data from old mounts to standard layout (copy, not move — leave originals for safety)
 	if binNeedsMigration {
-		log.Printf("Migrating bin data: %s -> %s", oldBinSource, newBinPath)
+		serverLogger.Infof("Migrating bin data: %s -> %s", oldBinSource, newBinPath)
 		if progressFn != nil {
 			progressFn(AdoptProgress{ServerName: req.Name, Phase: "copying-bin", Percent: 0, TotalBytes: totalBytes, BytesCopied: 0})
 		}
@@ -1276,10 +1296,10 @@ func (dc *DockerClient) AdoptServer(ctx context.Context, req ServerAdoptRequest,
 		}); err != nil {
 			return nil, fmt.Errorf("failed to migrate bin data: %w", err)
 		}
-		log.Printf("Bin data migration complete")
+		serverLogger.Infof("Bin data migration complete")
 	}
 	if dataNeedsMigration {
-		log.Printf("Migrating game data: %s -> %s", oldDataSource, newDataPath)
+		serverLogger.Infof("Migrating game data: %s -> %s", oldDataSource, newDataPath)
 		if progressFn != nil {
 			pct := 0
 			if totalBytes > 0 {
@@ -1301,14 +1321,14 @@ func (dc *DockerClient) AdoptServer(ctx context.Context, req ServerAdoptRequest,
 			}
 			return nil, fmt.Errorf("failed to migrate game data: %w", err)
 		}
-		log.Printf("Game data migration complete")
+		serverLogger.Infof("Game data migration complete")
 	}
 
 	// 8. Remove old container and create new one
 	if progressFn != nil {
 		progressFn(AdoptProgress{ServerName: req.Name, Phase: "creating-container", Percent: 95, TotalBytes: totalBytes, BytesCopied: bytesCopied})
 	}
-	log.Printf("Removing old container: %s", req.ContainerID)
+	serverLogger.Infof("Removing old container: %s", req.ContainerID)
 	if err := dc.cli.ContainerRemove(ctx, req.ContainerID, container.RemoveOptions{
 		Force:         true,
 		RemoveVolumes: false,
@@ -1385,14 +1405,14 @@ func (dc *DockerClient) AdoptServer(ctx context.Context, req ServerAdoptRequest,
 		},
 	}
 
-	log.Printf("Creating adopted container: %s (image: %s, bin: %s, data: %s)", containerName, fullImage, newBinPath, newDataPath)
+	serverLogger.Infof("Creating adopted container: %s (image: %s, bin: %s, data: %s)", containerName, fullImage, newBinPath, newDataPath)
 	resp, err := dc.cli.ContainerCreate(ctx, containerConfig, hostConfig, networkConfig, nil, containerName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create adopted container: %w", err)
 	}
 
 	newContainerID := resp.ID
-	log.Printf("Adopted container created: %s (ID: %s)", containerName, newContainerID)
+	serverLogger.Infof("Adopted container created: %s (ID: %s)", containerName, newContainerID)
 
 	// 12. Start container
 	if err := dc.cli.ContainerStart(ctx, newContainerID, container.StartOptions{}); err != nil {
@@ -1400,7 +1420,7 @@ func (dc *DockerClient) AdoptServer(ctx context.Context, req ServerAdoptRequest,
 		return nil, fmt.Errorf("failed to start adopted container: %w", err)
 	}
 
-	log.Printf("Adopted container started: %s (dataPath: %s)", newContainerID, req.DataPath)
+	serverLogger.Infof("Adopted container started: %s (dataPath: %s)", newContainerID, req.DataPath)
 	if progressFn != nil {
 		progressFn(AdoptProgress{ServerName: req.Name, Phase: "complete", Percent: 100, TotalBytes: totalBytes, BytesCopied: bytesCopied})
 	}
@@ -1499,7 +1519,7 @@ func GetServerVolumeSizes(serverName, dataPath string) (*ServerVolumeSizes, erro
 		diskFree = int64(statfs.Bavail) * int64(statfs.Bsize) // Use Bavail for available to users
 		diskUsed = diskTotal - int64(statfs.Bfree)*int64(statfs.Bsize)
 	} else {
-		log.Printf("Warning: failed to get disk stats for %s: %v", dataPath, err)
+		serverLogger.Warnf("Failed to get disk stats for %s: %v", dataPath, err)
 	}
 
 	return &ServerVolumeSizes{
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/lasmarois/zedops/agent"
+
+// replySpanTimeout ends request spans whose handler never replied
+const replySpanTimeout = 10 * time.Minute
+
+// Tracer and meter of the agent. Both are no-ops until SetupTelemetry installs real providers.
+var (
+	tracer = otel.Tracer(instrumentationName)
+	meter  = otel.Meter(instrumentationName)
+
+	backupDurationHist, _    = meter.Float64Histogram("zedops.backup.duration", metric.WithUnit("s"), metric.WithDescription("Duration of backup create and restore operations"))
+	collectorDurationHist, _ = meter.Float64Histogram("zedops.collector.duration", metric.WithUnit("s"), metric.WithDescription("Duration of one collector pass"))
+	rconCommandHist, _       = meter.Float64Histogram("zedops.rcon.command.duration", metric.WithUnit("s"), metric.WithDescription("Duration of RCON commands"))
+)
+
+// tracePropagator reads and writes Message.TraceParent
+var tracePropagator = propagation.TraceContext{}
+
+// TelemetryConfig configures OTLP/HTTP export
+type TelemetryConfig struct {
+	Endpoint       string            // Base URL, e.g. http://otel-collector:4318 (/v1/traces and /v1/metrics are appended)
+	Headers        map[string]string // Extra request headers (e.g. authentication)
+	ServiceName    string
+	AgentName      string
+	MetricInterval time.Duration
+}
+
+// SetupTelemetry installs OTLP/HTTP trace and metric exporters as the global providers.
+// The returned function flushes and shuts them down.
+func SetupTelemetry(ctx context.Context, config TelemetryConfig, agent *Agent) (func(context.Context) error, error) {
+	base := strings.TrimSuffix(config.Endpoint, "/")
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		return nil, fmt.Errorf("otel endpoint must be an http:// or https:// URL, got %q", config.Endpoint)
+	}
+	for _, v := range config.Headers {
+		RegisterSecret(v)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", config.ServiceName),
+		attribute.String("service.version", Version),
+		attribute.String("service.instance.id", config.AgentName),
+		attribute.String("host.name", config.AgentName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build telemetry resource: %w", err)
+	}
+
+	traceExporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(base+"/v1/traces"),
+		otlptracehttp.WithHeaders(config.Headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL(base+"/v1/metrics"),
+		otlpmetrichttp.WithHeaders(config.Headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+
+	interval := config.MetricInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithResource(res),
+	)
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(interval))),
+		sdkmetric.WithResource(res),
+	)
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		telemetryLogger.Warnf("%v", err)
+	}))
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	otel.SetTextMapPropagator(tracePropagator)
+
+	if err := registerTelemetryMetrics(agent); err != nil {
+		telemetryLogger.Warnf("Failed to register observable metrics: %v", err)
+	}
+
+	telemetryLogger.Infof("Exporting traces and metrics to %s (metrics every %s)", base, interval)
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// ParseOTLPHeaders parses "key=value,key2=value2" (the OTEL_EXPORTER_OTLP_HEADERS format)
+func ParseOTLPHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid header %q (expected key=value)", pair)
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers, nil
+}
+
+// registerTelemetryMetrics exports the collectors' latest values and the agent counters as observable instruments
+func registerTelemetryMetrics(agent *Agent) error {
+	hostCPU, err := meter.Float64ObservableGauge("zedops.host.cpu.utilization", metric.WithUnit("%"))
+	if err != nil {
+		return err
+	}
+	hostMemUsed, _ := meter.Int64ObservableGauge("zedops.host.memory.used", metric.WithUnit("By"))
+	hostMemTotal, _ := meter.Int64ObservableGauge("zedops.host.memory.total", metric.WithUnit("By"))
+	diskUsed, _ := meter.Int64ObservableGauge("zedops.host.disk.used", metric.WithUnit("By"))
+	diskTotal, _ := meter.Int64ObservableGauge("zedops.host.disk.total", metric.WithUnit("By"))
+	hostCPUMode, _ := meter.Float64ObservableGauge("zedops.host.cpu.mode.utilization", metric.WithUnit("%"))
+	hostLoad, _ := meter.Float64ObservableGauge("zedops.host.load_average")
+	hostSwapUsed, _ := meter.Int64ObservableGauge("zedops.host.swap.used", metric.WithUnit("By"))
+	hostNet, _ := meter.Int64ObservableCounter("zedops.host.network.io", metric.WithUnit("By"))
+	hostPressure, _ := meter.Float64ObservableGauge("zedops.host.pressure.avg10", metric.WithUnit("%"))
+	containerCPU, _ := meter.Float64ObservableGauge("zedops.container.cpu.utilization", metric.WithUnit("%"))
+	containerMem, _ := meter.Int64ObservableGauge("zedops.container.memory.used", metric.WithUnit("By"))
+	containerMemLimit, _ := meter.Int64ObservableGauge("zedops.container.memory.limit", metric.WithUnit("By"))
+	containerNet, _ := meter.Int64ObservableCounter("zedops.container.network.io", metric.WithUnit("By"))
+	containerDisk, _ := meter.Int64ObservableCounter("zedops.container.disk.io", metric.WithUnit("By"))
+	containerPIDs, _ := meter.Int64ObservableGauge("zedops.container.pids")
+	containerThrottled, _ := meter.Float64ObservableCounter("zedops.container.cpu.throttled_time", metric.WithUnit("s"))
+	players, _ := meter.Int64ObservableGauge("zedops.server.players")
+	rconUp, _ := meter.Int64ObservableGauge("zedops.server.rcon.up")
+	connected, _ := meter.Int64ObservableGauge("zedops.agent.connected")
+	reconnects, _ := meter.Int64ObservableCounter("zedops.agent.reconnects")
+	received, _ := meter.Int64ObservableCounter("zedops.agent.messages.received")
+	sent, _ := meter.Int64ObservableCounter("zedops.agent.messages.sent")
+	sendErrors, _ := meter.Int64ObservableCounter("zedops.agent.messages.send_errors")
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		if host, err := agent.hostCollector.Latest(); err == nil {
+			o.ObserveFloat64(hostCPU, host.CPUPercent)
+			o.ObserveInt64(hostMemUsed, host.MemoryUsedMB*1024*1024)
+			o.ObserveInt64(hostMemTotal, host.MemoryTotalMB*1024*1024)
+			for _, d := range host.Disks {
+				attrs := metric.WithAttributes(attribute.String("path", d.Path), attribute.String("label", d.Label))
+				o.ObserveInt64(diskUsed, d.UsedGB*1024*1024*1024, attrs)
+				o.ObserveInt64(diskTotal, d.TotalGB*1024*1024*1024, attrs)
+			}
+			o.ObserveFloat64(hostCPUMode, host.IOWaitPercent, metric.WithAttributes(attribute.String("mode", "iowait")))
+			o.ObserveFloat64(hostCPUMode, host.StealPercent, metric.WithAttributes(attribute.String("mode", "steal")))
+			o.ObserveFloat64(hostLoad, host.Load1, metric.WithAttributes(attribute.String("window", "1m")))
+			o.ObserveFloat64(hostLoad, host.Load5, metric.WithAttributes(attribute.String("window", "5m")))
+			o.ObserveFloat64(hostLoad, host.Load15, metric.WithAttributes(attribute.String("window", "15m")))
+			o.ObserveInt64(hostSwapUsed, host.SwapUsedMB*1024*1024)
+			for _, n := range host.Network {
+				iface := attribute.String("interface", n.Name)
+				o.ObserveInt64(hostNet, int64(n.RxBytes), metric.WithAttributes(iface, attribute.String("direction", "receive")))
+				o.ObserveInt64(hostNet, int64(n.TxBytes), metric.WithAttributes(iface, attribute.String("direction", "transmit")))
+			}
+			if psi := host.Pressure; psi != nil {
+				o.ObserveFloat64(hostPressure, psi.CPU.SomeAvg10, metric.WithAttributes(attribute.String("resource", "cpu")))
+				o.ObserveFloat64(hostPressure, psi.Memory.SomeAvg10, metric.WithAttributes(attribute.String("resource", "memory")))
+				o.ObserveFloat64(hostPressure, psi.IO.SomeAvg10, metric.WithAttributes(attribute.String("resource", "io")))
+			}
+		}
+
+		if agent.metricsCollector != nil {
+			for _, s := range agent.metricsCollector.Latest() {
+				attrs := metric.WithAttributes(attribute.String("server.id", s.ServerID), attribute.String("server.name", s.ServerName))
+				o.ObserveFloat64(containerCPU, s.CPUPercent, attrs)
+				o.ObserveInt64(containerMem, s.MemoryUsedMB*1024*1024, attrs)
+				o.ObserveInt64(containerMemLimit, s.MemoryLimitMB*1024*1024, attrs)
+				if c := s.Container; c != nil {
+					serverID, serverName := attribute.String("server.id", s.ServerID), attribute.String("server.name", s.ServerName)
+					o.ObserveInt64(containerNet, int64(c.NetRxBytes), metric.WithAttributes(serverID, serverName, attribute.String("direction", "receive")))
+					o.ObserveInt64(containerNet, int64(c.NetTxBytes), metric.WithAttributes(serverID, serverName, attribute.String("direction", "transmit")))
+					o.ObserveInt64(containerDisk, int64(c.DiskReadBytes), metric.WithAttributes(serverID, serverName, attribute.String("direction", "read")))
+					o.ObserveInt64(containerDisk, int64(c.DiskWriteBytes), metric.WithAttributes(serverID, serverName, attribute.String("direction", "write")))
+					o.ObserveInt64(containerPIDs, int64(c.PIDs), attrs)
+					o.ObserveFloat64(containerThrottled, c.CPUThrottledSeconds, attrs)
+				}
+			}
+		}
+
+		if agent.playerStats != nil {
+			for _, s := range agent.playerStats.GetStats() {
+				attrs := metric.WithAttributes(attribute.String("server.id", s.ServerID), attribute.String("server.name", s.ServerName))
+				o.ObserveInt64(players, int64(s.PlayerCount), attrs)
+				o.ObserveInt64(rconUp, int64(boolFloat(s.RCONConnected)), attrs)
+			}
+		}
+
+		snap := agentStats.Snapshot()
+		o.ObserveInt64(connected, int64(boolFloat(agent.IsAuthenticated())))
+		o.ObserveInt64(reconnects, int64(snap.Reconnects))
+		for subject, n := range snap.MessagesReceived {
+			o.ObserveInt64(received, int64(n), metric.WithAttributes(attribute.String("subject", subject)))
+		}
+		for subject, n := range snap.MessagesSent {
+			o.ObserveInt64(sent, int64(n), metric.WithAttributes(attribute.String("subject", subject)))
+		}
+		for subject, n := range snap.SendErrors {
+			o.ObserveInt64(sendErrors, int64(n), metric.WithAttributes(attribute.String("subject", subject)))
+		}
+		return nil
+	}, hostCPU, hostMemUsed, hostMemTotal, diskUsed, diskTotal, hostCPUMode, hostLoad, hostSwapUsed, hostNet, hostPressure, containerCPU, containerMem, containerMemLimit,
+		containerNet, containerDisk, containerPIDs, containerThrottled, players, rconUp, connected, reconnects, received, sent, sendErrors)
+	return err
+}
+
+// recordCollectorRun records how long one collector pass took
+func recordCollectorRun(collector string, started time.Time) {
+	collectorDurationHist.Record(context.Background(), time.Since(started).Seconds(),
+		metric.WithAttributes(attribute.String("collector", collector)))
+}
+
+// recordBackupDuration records a backup operation in the OTel histogram
+func recordBackupDuration(operation, result string, d time.Duration) {
+	backupDurationHist.Record(context.Background(), d.Seconds(),
+		metric.WithAttributes(attribute.String("operation", operation), attribute.String("result", result)))
+}
+
+// replySpan is a request span waiting for the handler's reply
+type replySpan struct {
+	span    trace.Span
+	started time.Time
+}
+
+// replySpans maps reply inboxes to the span of the request being handled
+var replySpans = struct {
+	sync.Mutex
+	m map[string]replySpan
+}{m: make(map[string]replySpan)}
+
+// startMessageSpan starts the span for an incoming message, continuing the manager's trace
+// when the message carries a traceparent, and attaches it to msg. Requests keep their span
+// open until the reply is sent.
+func startMessageSpan(msg *Message) trace.Span {
+	ctx := context.Background()
+	if msg.TraceParent != "" {
+		ctx = tracePropagator.Extract(ctx, propagation.MapCarrier{"traceparent": msg.TraceParent})
+	}
+	ctx, span := tracer.Start(ctx, msg.Subject,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "zedops"),
+			attribute.String("messaging.destination.name", msg.Subject),
+			attribute.Bool("messaging.request", msg.Reply != ""),
+		),
+	)
+	msg.ctx = ctx
+
+	if msg.Reply != "" && span.IsRecording() {
+		now := time.Now()
+		replySpans.Lock()
+		for inbox, rs := range replySpans.m {
+			if now.Sub(rs.started) > replySpanTimeout {
+				rs.span.SetStatus(codes.Error, "no reply sent")
+				rs.span.End()
+				delete(replySpans.m, inbox)
+			}
+		}
+		replySpans.m[msg.Reply] = replySpan{span: span, started: now}
+		replySpans.Unlock()
+	}
+	return span
+}
+
+// finishMessageSpan ends the span of a message that doesn't expect a reply
+func finishMessageSpan(msg Message, span trace.Span) {
+	if msg.Reply == "" || !span.IsRecording() {
+		span.End()
+	}
+}
+
+// traceOutgoing stamps a reply with the traceparent of the request span and ends that span
+func traceOutgoing(msg *Message) {
+	if !strings.HasPrefix(msg.Subject, "_INBOX.") {
+		return
+	}
+	replySpans.Lock()
+	rs, ok := replySpans.m[msg.Subject]
+	delete(replySpans.m, msg.Subject)
+	replySpans.Unlock()
+	if !ok {
+		return
+	}
+
+	carrier := propagation.MapCarrier{}
+	tracePropagator.Inject(trace.ContextWithSpan(context.Background(), rs.span), carrier)
+	msg.TraceParent = carrier.Get("traceparent")
+	rs.span.End()
+}
+
+// startSpan starts an internal span as a child of ctx
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on the span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		msg := RedactString(err.Error())
+		span.RecordError(errors.New(msg))
+		span.SetStatus(codes.Error, msg)
+	}
+	span.End()
+}
+
+// addPhaseEvent records a progress phase of a long operation as a span event
+func addPhaseEvent(span trace.Span, phase string, percent int) {
+	span.AddEvent(phase, trace.WithAttributes(attribute.Int("percent", percent)))
+}
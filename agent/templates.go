@@ -0,0 +1,515 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+const notifyTemplatesFile = "notify-templates.json"
+
+// NotifyTemplate is one template override pushed by the manager
+type NotifyTemplate struct {
+	Name   string `json:"name"`             // <kind>.subject, <kind>.text or <kind>.html (or a partial like email.start)
+	Locale string `json:"locale,omitempty"` // e.g. "fr" or "pt-BR" (empty = every locale)
+	Body   string `json:"body"`
+}
+
+// RenderedNotification is a notification rendered for one recipient
+type RenderedNotification struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// notifyTemplateData is what templates see: the notification's fields plus render-time values
+type notifyTemplateData struct {
+	Notification
+	Locale  string
+	Colors  EmailColors
+	Elapsed time.Duration // Since FailingSince (until Timestamp once resolved)
+	Metrics *HostMetrics  // Latest host metrics, nil if unavailable
+}
+
+// notifyTemplateFuncs are available to every template
+var notifyTemplateFuncs = map[string]interface{}{
+	"duration": func(d time.Duration) string {
+		if d < time.Minute {
+			return d.Round(time.Second).String()
+		}
+		return d.Round(time.Minute).String()
+	},
+	"formatTime": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+	"pct": func(v float64) string {
+		return fmt.Sprintf("%.1f%%", v)
+	},
+	"metricValue": formatMetricValue,
+	"truncate":    truncateRunes,
+}
+
+// notifyTemplateSet is the parsed templates for one locale
+type notifyTemplateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// NotifyTemplates renders notifications with the built-in templates and the manager's
+// overrides, per recipient locale
+type NotifyTemplates struct {
+	mu        sync.RWMutex
+	overrides []NotifyTemplate
+	sets      map[string]*notifyTemplateSet // Locale -> templates ("" = default)
+	metrics   func() *HostMetrics
+}
+
+// notifyTemplates is shared by every email notifier
+var notifyTemplates = NewNotifyTemplates()
+
+// Built-in templates, parsed once and cloned for each locale (never executed directly)
+var (
+	builtinTextTemplates = texttemplate.Must(texttemplate.New("notify").Funcs(notifyTemplateFuncs).Parse(builtinNotifyText))
+	builtinHTMLTemplates = htmltemplate.Must(htmltemplate.New("notify").Funcs(notifyTemplateFuncs).Parse(builtinNotifyHTML))
+)
+
+// NewNotifyTemplates creates a template store with only the built-in templates
+func NewNotifyTemplates() *NotifyTemplates {
+	t := &NotifyTemplates{}
+	sets, _ := buildNotifyTemplateSets(nil)
+	t.sets = sets
+	return t
+}
+
+// SetMetricsSource sets where templates get the latest host metrics from
+func (t *NotifyTemplates) SetMetricsSource(metrics func() *HostMetrics) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.metrics = metrics
+}
+
+// Overrides returns the manager's template overrides
+func (t *NotifyTemplates) Overrides() []NotifyTemplate {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]NotifyTemplate{}, t.overrides...)
+}
+
+// SetOverrides replaces the template overrides. Nothing changes if any template fails to parse.
+func (t *NotifyTemplates) SetOverrides(overrides []NotifyTemplate) error {
+	sets, err := parseNotifyOverrides(overrides)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.overrides = overrides
+	t.sets = sets
+	t.mu.Unlock()
+	return nil
+}
+
+// ValidateNotifyTemplates checks that template overrides parse, without installing them
+func ValidateNotifyTemplates(overrides []NotifyTemplate) error {
+	_, err := parseNotifyOverrides(overrides)
+	return err
+}
+
+// parseNotifyOverrides checks the override names and builds their template sets
+func parseNotifyOverrides(overrides []NotifyTemplate) (map[string]*notifyTemplateSet, error) {
+	for _, o := range overrides {
+		if o.Name == "" {
+			return nil, fmt.Errorf("template has no name")
+		}
+		if !strings.Contains(o.Name, ".") {
+			return nil, fmt.Errorf("template %q: name must be <kind>.subject, <kind>.text or <kind>.html", o.Name)
+		}
+	}
+	return buildNotifyTemplateSets(overrides)
+}
+
+// normalizeLocale lowercases a locale and uses "-" as the separator ("pt_BR" -> "pt-br")
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// localeLayers returns the locales whose overrides apply to a locale, least specific first
+func localeLayers(locale string) []string {
+	layers := []string{""}
+	if locale == "" {
+		return layers
+	}
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		layers = append(layers, base)
+	}
+	return append(layers, locale)
+}
+
+// buildNotifyTemplateSets parses the built-in templates plus overrides for every locale the
+// overrides mention
+func buildNotifyTemplateSets(overrides []NotifyTemplate) (map[string]*notifyTemplateSet, error) {
+	byLocale := make(map[string][]NotifyTemplate)
+	locales := map[string]bool{"": true}
+	for _, o := range overrides {
+		locale := normalizeLocale(o.Locale)
+		byLocale[locale] = append(byLocale[locale], o)
+		for _, l := range localeLayers(locale) {
+			locales[l] = true
+		}
+	}
+
+	sets := make(map[string]*notifyTemplateSet, len(locales))
+	for locale := range locales {
+		text, err := builtinTextTemplates.Clone()
+		if err != nil {
+			return nil, err
+		}
+		html, err := builtinHTMLTemplates.Clone()
+		if err != nil {
+			return nil, err
+		}
+		for _, layer := range localeLayers(locale) {
+			for _, o := range byLocale[layer] {
+				if strings.HasSuffix(o.Name, ".html") {
+					_, err = html.New(o.Name).Parse(o.Body)
+				} else {
+					_, err = text.New(o.Name).Parse(o.Body)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("template %s (%s): %w", o.Name, layer, err)
+				}
+			}
+		}
+		sets[locale] = &notifyTemplateSet{text: text, html: html}
+	}
+	return sets, nil
+}
+
+// Render renders a notification for a recipient's locale and theme. Notification kinds
+// without templates, and templates that fail, use the "default" templates.
+func (t *NotifyTemplates) Render(n Notification, locale string, colors EmailColors) RenderedNotification {
+	t.mu.RLock()
+	locale = normalizeLocale(locale)
+	set := t.sets[""]
+	for _, l := range localeLayers(locale) {
+		if s, ok := t.sets[l]; ok {
+			set = s
+		}
+	}
+	metrics := t.metrics
+	t.mu.RUnlock()
+
+	data := notifyTemplateData{Notification: n, Locale: locale, Colors: colors}
+	if !n.FailingSince.IsZero() {
+		if n.Status == MetricAlertResolved {
+			data.Elapsed = n.Timestamp.Sub(n.FailingSince)
+		} else {
+			data.Elapsed = time.Since(n.FailingSince)
+		}
+	}
+	if metrics != nil {
+		data.Metrics = metrics()
+	}
+
+	name := n.Template
+	if name == "" {
+		name = n.Kind
+	}
+	if set.text.Lookup(name+".subject") == nil {
+		name = "default"
+	}
+	r, err := set.render(name, data)
+	if err != nil && name != "default" {
+		alertLogger.Warnf("Template %s failed, using default: %v", name, err)
+		r, err = set.render("default", data)
+	}
+	if err != nil {
+		alertLogger.Warnf("Default template failed: %v", err)
+		return RenderedNotification{Subject: "[ZedOps] " + n.Title, Text: n.Text, HTML: notificationHTML(n)}
+	}
+	return r
+}
+
+// render executes a kind's subject, text and html templates
+func (s *notifyTemplateSet) render(name string, data notifyTemplateData) (RenderedNotification, error) {
+	var subject, text, html bytes.Buffer
+	if err := s.text.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return RenderedNotification{}, err
+	}
+	if err := s.text.ExecuteTemplate(&text, name+".text", data); err != nil {
+		return RenderedNotification{}, err
+	}
+	if err := s.html.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return RenderedNotification{}, err
+	}
+	return RenderedNotification{
+		Subject: strings.Join(strings.Fields(subject.String()), " "), // Headers are one line
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// GetNotifyTemplatesPath returns the path to the cached template overrides
+func GetNotifyTemplatesPath() string {
+	return filepath.Join(StateDir(), notifyTemplatesFile)
+}
+
+// LoadNotifyTemplates loads the cached template overrides from disk.
+// Returns nil if the file doesn't exist.
+func LoadNotifyTemplates() ([]NotifyTemplate, error) {
+	data, err := os.ReadFile(GetNotifyTemplatesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read notification templates: %w", err)
+	}
+
+	var templates []NotifyTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse notification templates: %w", err)
+	}
+	return templates, nil
+}
+
+// SaveNotifyTemplates saves the template overrides to disk.
+func SaveNotifyTemplates(templates []NotifyTemplate) error {
+	if err := ensureStateDir(); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(GetNotifyTemplatesPath(), templates); err != nil {
+		return fmt.Errorf("failed to write notification templates: %w", err)
+	}
+	return nil
+}
+
+// NotifyTemplatesRequest is the notify.templates.set request
+type NotifyTemplatesRequest struct {
+	Templates []NotifyTemplate `json:"templates"`
+	DryRun    bool             `json:"dryRun,omitempty"` // Only check that the templates parse
+}
+
+// NotifyTemplatesResponse is the reply to notify.templates.set and notify.templates.get
+type NotifyTemplatesResponse struct {
+	Success   bool             `json:"success"`
+	Templates []NotifyTemplate `json:"templates,omitempty"` // Overrides in effect
+	Builtin   []string         `json:"builtin,omitempty"`   // Names of the built-in templates
+	Error     string           `json:"error,omitempty"`
+}
+
+// handleNotifyTemplatesSet handles notify.templates.set messages
+func (a *Agent) handleNotifyTemplatesSet(msg Message) {
+	data, _ := json.Marshal(msg.Data)
+	var req NotifyTemplatesRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		a.sendNotifyTemplatesResponse(msg.Reply, NotifyTemplatesResponse{Error: "Invalid request format"})
+		return
+	}
+
+	if req.DryRun {
+		if err := ValidateNotifyTemplates(req.Templates); err != nil {
+			a.sendNotifyTemplatesResponse(msg.Reply, NotifyTemplatesResponse{Error: err.Error()})
+			return
+		}
+		a.sendNotifyTemplatesResponse(msg.Reply, NotifyTemplatesResponse{Success: true})
+		return
+	}
+
+	if err := a.applyNotifyTemplates(req.Templates); err != nil {
+		a.sendNotifyTemplatesResponse(msg.Reply, NotifyTemplatesResponse{Error: err.Error()})
+		return
+	}
+	a.sendNotifyTemplatesResponse(msg.Reply, NotifyTemplatesResponse{Success: true, Templates: notifyTemplates.Overrides()})
+}
+
+// handleNotifyTemplatesGet handles notify.templates.get messages
+func (a *Agent) handleNotifyTemplatesGet(msg Message) {
+	var builtin []string
+	for _, t := range builtinTextTemplates.Templates() {
+		builtin = append(builtin, t.Name())
+	}
+	for _, t := range builtinHTMLTemplates.Templates() {
+		builtin = append(builtin, t.Name())
+	}
+	a.sendNotifyTemplatesResponse(msg.Reply, NotifyTemplatesResponse{Success: true, Templates: notifyTemplates.Overrides(), Builtin: builtin})
+}
+
+// applyNotifyTemplates installs template overrides and caches them to disk
+func (a *Agent) applyNotifyTemplates(templates []NotifyTemplate) error {
+	if err := notifyTemplates.SetOverrides(templates); err != nil {
+		return err
+	}
+	if err := SaveNotifyTemplates(templates); err != nil {
+		alertLogger.Warnf("Failed to cache notification templates: %v", err)
+	}
+	alertLogger.Infof("Notification templates updated (%d override(s))", len(templates))
+	return nil
+}
+
+// sendNotifyTemplatesResponse replies to a notify.templates request
+func (a *Agent) sendNotifyTemplatesResponse(replyTo string, resp NotifyTemplatesResponse) {
+	if replyTo == "" {
+		return
+	}
+	a.sendMessage(Message{
+		Subject:   replyTo,
+		Data:      resp,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// builtinNotifyText holds the subject and plaintext templates
+const builtinNotifyText = `
+{{- define "agent.offline.subject"}}[ZedOps] {{if .Reminder}}Reminder: {{end}}Agent "{{.AgentName}}" cannot reach manager{{end}}
+{{- define "agent.offline.text"}}
+Agent {{.AgentName}} has been unable to reach the ZedOps manager for {{duration .Elapsed}}.
+
+Reason: {{.Reason}}
+Failing since: {{formatTime .FailingSince}}
+{{template "metrics.text" .}}
+The agent will keep retrying automatically. You will be notified when it recovers.
+{{end}}
+
+{{- define "agent.recovered.subject"}}[ZedOps] Agent "{{.AgentName}}" is back online{{end}}
+{{- define "agent.recovered.text"}}
+Agent {{.AgentName}} is back online. Connection to the ZedOps manager has been restored.
+
+Total downtime: {{duration .Elapsed}}
+{{template "metrics.text" .}}{{end}}
+
+{{- define "alert.metric.subject"}}[ZedOps] {{if .Reminder}}Reminder: {{end}}{{if eq .Status "resolved"}}RESOLVED{{else}}FIRING{{end}}: {{.Event.RuleName}} on agent "{{.AgentName}}"{{end}}
+{{- define "alert.metric.text"}}
+{{if eq .Status "resolved"}}RESOLVED{{else}}FIRING{{end}}: {{.Event.Summary}}
+
+Agent: {{.AgentName}}
+{{- with .ServerName}}
+Server: {{.}}{{end}}
+Severity: {{.Severity}}
+Value: {{metricValue .Event.Metric .Event.Value}} ({{.Event.Operator}} {{metricValue .Event.Metric .Event.Threshold}})
+{{- with .Event.Note}}
+Note: {{.}}{{end}}
+{{template "metrics.text" .}}{{end}}
+
+{{- define "alert.log.subject"}}[ZedOps] {{.Event.RuleName}} on server "{{.ServerName}}"{{end}}
+{{- define "alert.log.text"}}
+{{.Event.MatchCount}} line(s) matching "{{.Event.Pattern}}" within {{.Event.WindowSeconds}}s on server {{.ServerName}}.
+
+Agent: {{.AgentName}}
+Severity: {{.Severity}}
+{{range .Event.Lines}}
+{{truncate .Line 300}}{{end}}
+{{end}}
+
+{{- define "default.subject"}}[ZedOps] {{if .Reminder}}Reminder: {{end}}{{.Title}}{{end}}
+{{- define "default.text"}}
+{{.Text}}
+{{range .Fields}}
+{{.Name}}: {{.Value}}{{end}}
+{{end}}
+
+{{- define "metrics.text"}}{{with .Metrics}}
+Host: CPU {{pct .CPUPercent}}, memory {{.MemoryUsedMB}}/{{.MemoryTotalMB}} MB, load {{printf "%.2f" .Load1}}
+{{- range .Disks}}
+Disk {{.Label}}: {{pct .Percent}} ({{.UsedGB}}/{{.TotalGB}} GB){{end}}
+{{end}}{{end}}
+`
+
+// builtinNotifyHTML holds the HTML email templates. Kinds build on the shared layout partials:
+// email.start, email.subtitle (opens a <p>), email.body, email.footer (opens a <p>), email.end.
+const builtinNotifyHTML = `
+{{- define "email.start"}}<!DOCTYPE html>
+<html lang="{{or .Locale "en"}}">
+<head><meta charset="utf-8"><meta name="color-scheme" content="dark"></head>
+<body style="margin:0;padding:0;background-color:{{.Colors.Bg}};font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;color:{{.Colors.Text}};">
+  <table width="100%" cellpadding="0" cellspacing="0" bgcolor="{{.Colors.Bg}}" style="padding:40px 20px;">
+    <tr><td align="center">
+      <table width="100%" cellpadding="0" cellspacing="0" bgcolor="{{.Colors.Card}}" style="max-width:520px;border-radius:12px;border:1px solid {{.Colors.Border}};">
+        <tr><td bgcolor="{{.Colors.Card}}" style="padding:32px 32px 16px;text-align:center;border-bottom:1px solid {{.Colors.Border}};">
+          <h1 style="margin:0;font-size:26px;font-weight:700;color:{{.Colors.Accent}};">ZedOps</h1>
+{{end}}
+{{- define "email.subtitle"}}          <p style="margin:6px 0 0;font-size:12px;color:{{.Colors.Muted}};letter-spacing:1px;text-transform:uppercase;">{{end}}
+{{- define "email.body"}}
+        </td></tr>
+        <tr><td bgcolor="{{.Colors.Card}}" style="padding:24px 32px;">
+{{end}}
+{{- define "email.footer"}}
+        </td></tr>
+        <tr><td bgcolor="{{.Colors.Card}}" style="padding:16px 32px;border-top:1px solid {{.Colors.Border}};">
+          <p style="margin:0;font-size:12px;color:{{.Colors.Muted}};text-align:center;">{{end}}
+{{- define "email.end"}}
+        </td></tr>
+      </table>
+    </td></tr>
+  </table>
+</body>
+</html>{{end}}
+
+{{- define "metrics.html"}}{{with .Metrics}}
+          <p style="margin:16px 0 0;font-size:12px;color:{{$.Colors.Muted}};">
+            Host: CPU {{pct .CPUPercent}} &middot; memory {{.MemoryUsedMB}}/{{.MemoryTotalMB}} MB &middot; load {{printf "%.2f" .Load1}}
+            {{- range .Disks}}<br>Disk {{.Label}}: {{pct .Percent}} ({{.UsedGB}}/{{.TotalGB}} GB){{end}}
+          </p>{{end}}{{end}}
+
+{{- define "agent.offline.html"}}{{template "email.start" .}}{{template "email.subtitle" .}}Agent Alert</p>{{template "email.body" .}}
+          <p style="margin:0 0 16px;font-size:16px;color:{{.Colors.Text}};">
+            Agent <strong style="color:{{.Colors.Accent}};">{{.AgentName}}</strong> has been unable to reach the ZedOps manager for <strong style="color:{{.Colors.Error}};">{{duration .Elapsed}}</strong>.
+          </p>
+          <p style="margin:0 0 8px;font-size:14px;color:{{.Colors.Muted}};">Reason: <strong style="color:{{.Colors.Text}};">{{.Reason}}</strong></p>
+          <p style="margin:0 0 8px;font-size:14px;color:{{.Colors.Muted}};">Failing since: <strong style="color:{{.Colors.Text}};">{{formatTime .FailingSince}}</strong></p>
+          <p style="margin:16px 0 0;font-size:14px;color:{{.Colors.Muted}};">
+            The agent will keep retrying automatically. You will be notified when it recovers.
+          </p>{{template "metrics.html" .}}
+{{- template "email.footer" .}}Sent directly by the agent because the manager is unreachable.</p>{{template "email.end" .}}{{end}}
+
+{{- define "agent.recovered.html"}}{{template "email.start" .}}{{template "email.subtitle" .}}Agent Recovery</p>{{template "email.body" .}}
+          <p style="margin:0 0 16px;font-size:16px;color:{{.Colors.Text}};">
+            Agent <strong style="color:{{.Colors.Accent}};">{{.AgentName}}</strong> is back <strong style="color:{{.Colors.Success}};">online</strong>.
+          </p>
+          <p style="margin:0;font-size:14px;color:{{.Colors.Muted}};">
+            Total downtime: <strong style="color:{{.Colors.Text}};">{{duration .Elapsed}}</strong>
+          </p>{{template "metrics.html" .}}
+{{- template "email.footer" .}}Connection to ZedOps manager has been restored.</p>{{template "email.end" .}}{{end}}
+
+{{- define "alert.metric.html"}}{{template "email.start" .}}{{template "email.subtitle" .}}Metric {{if eq .Status "resolved"}}Recovery{{else}}Alert{{end}}</p>{{template "email.body" .}}
+          <p style="margin:0 0 16px;font-size:16px;color:{{.Colors.Text}};">
+            {{if eq .Status "resolved"}}<strong style="color:{{.Colors.Success}};">RESOLVED</strong>{{else}}<strong style="color:{{.Colors.Error}};">FIRING</strong>{{end}} &mdash; {{.Event.Summary}}
+          </p>
+          <p style="margin:0 0 8px;font-size:14px;color:{{.Colors.Muted}};">Agent: <strong style="color:{{.Colors.Text}};">{{.AgentName}}</strong></p>
+          {{- with .ServerName}}
+          <p style="margin:0 0 8px;font-size:14px;color:{{$.Colors.Muted}};">Server: <strong style="color:{{$.Colors.Text}};">{{.}}</strong></p>
+          {{- end}}
+          <p style="margin:0 0 8px;font-size:14px;color:{{.Colors.Muted}};">Severity: <strong style="color:{{.Colors.Text}};">{{.Severity}}</strong></p>
+          <p style="margin:0 0 8px;font-size:14px;color:{{.Colors.Muted}};">Value: <strong style="color:{{.Colors.Text}};">{{metricValue .Event.Metric .Event.Value}}</strong> ({{.Event.Operator}} {{metricValue .Event.Metric .Event.Threshold}})</p>
+          {{- with .Event.Note}}
+          <p style="margin:0 0 8px;font-size:14px;color:{{$.Colors.Muted}};">{{.}}</p>
+          {{- end}}{{template "metrics.html" .}}
+{{- template "email.footer" .}}Sent directly by the agent.</p>{{template "email.end" .}}{{end}}
+
+{{- define "alert.log.html"}}{{template "email.start" .}}{{template "email.subtitle" .}}Log Alert</p>{{template "email.body" .}}
+          <p style="margin:0 0 16px;font-size:16px;color:{{.Colors.Text}};">
+            <strong style="color:{{.Colors.Error}};">{{.Event.RuleName}}</strong> &mdash; {{.Event.MatchCount}} line(s) matching <code>{{.Event.Pattern}}</code> within {{.Event.WindowSeconds}}s.
+          </p>
+          <p style="margin:0 0 8px;font-size:14px;color:{{.Colors.Muted}};">Server: <strong style="color:{{.Colors.Text}};">{{.ServerName}}</strong></p>
+          <p style="margin:0 0 8px;font-size:14px;color:{{.Colors.Muted}};">Severity: <strong style="color:{{.Colors.Text}};">{{.Severity}}</strong></p>
+          {{- if .Event.Lines}}
+          <pre style="margin:16px 0 0;padding:12px;font-size:12px;white-space:pre-wrap;color:{{.Colors.Text}};border:1px solid {{.Colors.Border}};border-radius:6px;">
+            {{- range .Event.Lines}}{{truncate .Line 300}}
+{{end}}</pre>
+          {{- end}}
+{{- template "email.footer" .}}Sent directly by the agent.</p>{{template "email.end" .}}{{end}}
+
+{{- define "default.html"}}{{template "email.start" .}}{{template "email.subtitle" .}}Notification</p>{{template "email.body" .}}
+          <p style="margin:0 0 16px;font-size:16px;color:{{.Colors.Text}};">{{.Title}}</p>
+          <p style="margin:0 0 16px;font-size:14px;color:{{.Colors.Text}};white-space:pre-wrap;">{{.Text}}</p>
+          {{- range .Fields}}
+          <p style="margin:0 0 8px;font-size:14px;color:{{$.Colors.Muted}};">{{.Name}}: <strong style="color:{{$.Colors.Text}};">{{.Value}}</strong></p>
+          {{- end}}
+{{- template "email.footer" .}}Sent directly by the agent.</p>{{template "email.end" .}}{{end}}
+`
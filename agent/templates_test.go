@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetricAlertEmailHTML renders the metric alert email through the default templates:
+// the layout must come out as valid HTML with no leftover format verbs
+func TestMetricAlertEmailHTML(t *testing.T) {
+	event := MetricAlertEvent{
+		RuleID: "cpu-high", RuleName: "CPU high", Severity: "critical", Status: MetricAlertFiring,
+		Metric: "host.cpu.percent", Value: 97.5, Threshold: 90, Operator: ">",
+	}
+	n := Notification{
+		Kind:      NotifyMetricAlert,
+		Status:    MetricAlertFiring,
+		Severity:  "critical",
+		Title:     `FIRING: CPU high on agent "host-1"`,
+		Text:      "CPU at 97.5% (> 90%)",
+		AgentName: "host-1",
+		Timestamp: time.Now(),
+		Event:     event,
+	}
+
+	r := NewNotifyTemplates().Render(n, "", DefaultEmailColors())
+	if !strings.Contains(r.HTML, `<table width="100%"`) {
+		t.Errorf("layout table width missing or mangled:\n%s", r.HTML)
+	}
+	for _, bad := range []string{"100%%", "%!", "<no value>"} {
+		if strings.Contains(r.HTML, bad) || strings.Contains(r.Text, bad) {
+			t.Errorf("rendered email contains %q:\n%s\n%s", bad, r.Text, r.HTML)
+		}
+	}
+	if !strings.Contains(r.Subject, "CPU high") {
+		t.Errorf("subject = %q", r.Subject)
+	}
+}
+
+func TestSetOverridesRejectsWholeSetAtomically(t *testing.T) {
+	good := NotifyTemplate{Name: "agent.offline.subject", Body: "Down: {{.AgentName}}"}
+	bad := NotifyTemplate{Name: "agent.offline.text", Locale: "fr", Body: "{{.AgentName"}
+
+	if err := ValidateNotifyTemplates([]NotifyTemplate{good}); err != nil {
+		t.Fatalf("valid template rejected: %v", err)
+	}
+	if err := ValidateNotifyTemplates([]NotifyTemplate{good, bad}); err == nil {
+		t.Fatal("unterminated action accepted")
+	}
+
+	templates := NewNotifyTemplates()
+	if err := templates.SetOverrides([]NotifyTemplate{good}); err != nil {
+		t.Fatal(err)
+	}
+	if err := templates.SetOverrides([]NotifyTemplate{good, bad}); err == nil {
+		t.Fatal("SetOverrides accepted a template that doesn't parse")
+	}
+	if got := templates.Overrides(); len(got) != 1 || got[0] != good {
+		t.Errorf("overrides = %+v, want the previous set", got)
+	}
+	n := Notification{Kind: "agent.offline", AgentName: "host-1", Timestamp: time.Now()}
+	if r := templates.Render(n, "fr", DefaultEmailColors()); r.Subject != "Down: host-1" {
+		t.Errorf("subject = %q", r.Subject)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -47,7 +46,7 @@ func MigrateFromLegacyDir() {
 	}
 
 	if err := ensureStateDir(); err != nil {
-		log.Printf("Migration: failed to create state dir: %v", err)
+		migrationLogger.Errorf("Failed to create state dir: %v", err)
 		return
 	}
 
@@ -67,25 +66,25 @@ func MigrateFromLegacyDir() {
 
 		data, err := os.ReadFile(src)
 		if err != nil {
-			log.Printf("Migration: failed to read %s: %v", src, err)
+			migrationLogger.Errorf("Failed to read %s: %v", src, err)
 			continue
 		}
 
 		// Skip migrating stale ephemeral JWTs from the token file
 		// (old install script saved ephemeral tokens to the permanent path)
 		if f == tokenFile && isEphemeralJWT(string(data)) {
-			log.Printf("Migration: skipping stale ephemeral JWT at %s (deleting)", src)
+			migrationLogger.Warnf("Skipping stale ephemeral JWT at %s (deleting)", src)
 			os.Remove(src)
 			continue
 		}
 
 		if err := os.WriteFile(dst, data, 0600); err != nil {
-			log.Printf("Migration: failed to write %s: %v", dst, err)
+			migrationLogger.Errorf("Failed to write %s: %v", dst, err)
 			continue
 		}
 		os.Remove(src)
 		migrated++
-		log.Printf("Migration: moved %s -> %s", src, dst)
+		migrationLogger.Infof("Moved %s -> %s", src, dst)
 	}
 
 	if migrated > 0 {
@@ -139,17 +138,20 @@ func LoadToken() (string, error) {
 	}
 
 	if isEphemeralJWT(token) {
-		log.Printf("WARNING: Permanent token file contains a stale ephemeral JWT — deleting %s", GetTokenPath())
+		tokenLogger.Warnf("Permanent token file contains a stale ephemeral JWT — deleting %s", GetTokenPath())
 		os.Remove(GetTokenPath())
 		return "", nil
 	}
 
+	RegisterSecret(token)
 	return token, nil
 }
 
 // LoadEphemeralToken loads the ephemeral token from disk.
 func LoadEphemeralToken() (string, error) {
-	return loadFile(GetEphemeralTokenPath())
+	token, err := loadFile(GetEphemeralTokenPath())
+	RegisterSecret(token)
+	return token, err
 }
 
 // loadFile reads a token file, returning "" if it doesn't exist.
@@ -166,6 +168,7 @@ func loadFile(path string) (string, error) {
 
 // SaveToken saves the permanent token to disk.
 func SaveToken(token string) error {
+	RegisterSecret(token)
 	if err := ensureStateDir(); err != nil {
 		return err
 	}
@@ -112,7 +112,7 @@ export function PerformanceTab({ serverId, isRunning }: PerformanceTabProps) {
           Showing {metricsHistory.count} data points
           {timeRange === '12h' && ' (1-minute averages)'}
           {timeRange === '24h' && ' (5-minute averages)'}
-          {timeRange === '3d' && ' (15-minute averages)'}
+          {(timeRange === '3d' || timeRange === '7d' || timeRange === '30d') && ' (15-minute averages)'}
         </div>
       )}
     </div>
@@ -14,6 +14,8 @@ const TIME_RANGES: { value: MetricsTimeRange; label: string }[] = [
   { value: '12h', label: '12h' },
   { value: '24h', label: '24h' },
   { value: '3d', label: '3d' },
+  { value: '7d', label: '7d' },
+  { value: '30d', label: '30d' },
 ]
 
 export function TimeRangeSelector({ value, onChange }: TimeRangeSelectorProps) {
@@ -8,7 +8,7 @@ import { getToken } from '../lib/auth';
 export interface LogLine {
   containerId: string;
   timestamp: number;
-  stream: 'stdout' | 'stderr' | 'unknown';
+  stream: 'stdout' | 'stderr' | 'system' | 'unknown';
   message: string;
 }
 
@@ -492,7 +492,7 @@ export function useServerStorage(
 /**
  * Hook to fetch metrics history for sparkline display and Performance tab
  * @param serverId - Server ID to fetch history for
- * @param range - Time range: '30m' | '3h' | '12h' | '24h' | '3d'
+ * @param range - Time range: '30m' | '3h' | '12h' | '24h' | '3d' | '7d' | '30d'
  * @param enabled - Whether to enable the query (e.g., only for running servers)
  */
 export function useServerMetricsHistory(
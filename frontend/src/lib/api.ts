@@ -54,6 +54,43 @@ export interface HostMetrics {
   diskPercent?: number;
   timestamp?: number;
   lastUpdate?: number;
+  // Extended host metrics (newer agents)
+  cpuCount?: number;
+  cpuPerCore?: number[];
+  iowaitPercent?: number;
+  stealPercent?: number;
+  load1?: number;
+  load5?: number;
+  load15?: number;
+  swapUsedMB?: number;
+  swapTotalMB?: number;
+  network?: NetInterfaceMetric[];
+  pressure?: PressureMetrics;
+}
+
+export interface NetInterfaceMetric {
+  name: string;
+  rxBytes: number;
+  txBytes: number;
+  rxBytesPerSec: number;
+  txBytesPerSec: number;
+}
+
+export interface PressureStall {
+  someAvg10: number;
+  someAvg60: number;
+  someAvg300: number;
+  someTotalUs: number;
+  fullAvg10: number;
+  fullAvg60: number;
+  fullAvg300: number;
+  fullTotalUs: number;
+}
+
+export interface PressureMetrics {
+  cpu: PressureStall;
+  memory: PressureStall;
+  io: PressureStall;
 }
 
 export interface Agent {
@@ -1067,7 +1104,7 @@ export interface MetricsHistoryResponse {
 }
 
 /** Valid time ranges for metrics history */
-export type MetricsTimeRange = '30m' | '3h' | '12h' | '24h' | '3d';
+export type MetricsTimeRange = '30m' | '3h' | '12h' | '24h' | '3d' | '7d' | '30d';
 
 /**
  * Fetch server metrics history for sparklines and Performance tab
@@ -0,0 +1,11 @@
+-- Migration: Extended container metrics in server_metrics_history
+-- Network and disk rates are bytes/second since the agent's previous sample.
+-- NULL for rows written by older agents or backfilled from downsampled history.
+ALTER TABLE server_metrics_history ADD COLUMN memory_rss_mb INTEGER;
+ALTER TABLE server_metrics_history ADD COLUMN memory_cache_mb INTEGER;
+ALTER TABLE server_metrics_history ADD COLUMN net_rx_bytes_per_sec REAL;
+ALTER TABLE server_metrics_history ADD COLUMN net_tx_bytes_per_sec REAL;
+ALTER TABLE server_metrics_history ADD COLUMN disk_read_bytes_per_sec REAL;
+ALTER TABLE server_metrics_history ADD COLUMN disk_write_bytes_per_sec REAL;
+ALTER TABLE server_metrics_history ADD COLUMN pids INTEGER;
+ALTER TABLE server_metrics_history ADD COLUMN cpu_throttled_percent REAL;
@@ -0,0 +1,17 @@
+-- Alert silences: mute matching agent alerts until ends_at.
+-- NULL matchers match everything; pushed to the agent, which caches them for offline use.
+CREATE TABLE IF NOT EXISTS alert_silences (
+  id TEXT PRIMARY KEY,
+  agent_id TEXT NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+  kind TEXT,
+  server_id TEXT,
+  rule_id TEXT,
+  fingerprint TEXT,
+  starts_at INTEGER NOT NULL,
+  ends_at INTEGER NOT NULL,
+  comment TEXT,
+  created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+  created_at INTEGER NOT NULL DEFAULT (unixepoch())
+);
+
+CREATE INDEX idx_alert_silences_agent ON alert_silences(agent_id, ends_at);
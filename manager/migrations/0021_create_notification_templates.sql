@@ -0,0 +1,13 @@
+-- Notification template overrides, pushed to every agent (cached there for offline alerts).
+-- locale '' = every locale; otherwise e.g. 'fr' or 'pt-BR'.
+CREATE TABLE IF NOT EXISTS notification_templates (
+  name TEXT NOT NULL,
+  locale TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL,
+  updated_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+  updated_at INTEGER NOT NULL DEFAULT (unixepoch()),
+  PRIMARY KEY (name, locale)
+);
+
+-- Per-user locale for alert emails (NULL = English)
+ALTER TABLE users ADD COLUMN locale TEXT;
@@ -0,0 +1,2 @@
+-- Pinned backups are exempt from the agent's retention pruning
+ALTER TABLE backups ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;
@@ -17,6 +17,8 @@ import {
   parseMessage,
   createMessage,
   isInboxSubject,
+  generateTraceParent,
+  traceIdFromTraceParent,
 } from "../types/Message";
 import {
   verifyToken,
@@ -30,7 +32,7 @@ import {
 } from "../types/LogMessage";
 import { logRconCommand, logAgentRegistered } from "../lib/audit";
 import { getAlertRecipientsForAgent } from "../lib/permissions";
-import { sendEmail, buildAgentOfflineEmailHtml, buildAgentRecoveredEmailHtml, buildAgentUpdatedEmailHtml, getEmailThemeColors } from "../lib/email";
+import { sendEmail, buildAgentOfflineEmailHtml, buildAgentRecoveredEmailHtml, buildAgentUpdatedEmailHtml, buildMetricAlertEmailHtml, buildLogAlertEmailHtml, getEmailThemeColors } from "../lib/email";
 
 export class AgentConnection extends DurableObject {
   // Core agent state (restored from storage after hibernation)
@@ -280,6 +282,17 @@ export class AgentConnection extends DurableObject {
       });
     }
 
+    // Hibernation state of idle-stopped servers
+    if (url.pathname === "/hibernation" && request.method === "GET") {
+      const entries = await this.ctx.storage.list({ prefix: 'hibernation:' });
+      return new Response(JSON.stringify({
+        success: true,
+        servers: Array.from(entries.values()),
+      }), {
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+
     // Player stats endpoint (single server)
     if (url.pathname.startsWith("/players/") && request.method === "GET") {
       const serverId = url.pathname.split("/")[2];
@@ -320,6 +333,21 @@ export class AgentConnection extends DurableObject {
       return this.handlePortCheckRequest(request);
     }
 
+    // Push notification template overrides to the agent
+    if (url.pathname === "/notify/templates" && request.method === "POST") {
+      return this.handleNotificationTemplatesPush(request);
+    }
+
+    // Alert silences: GET reads the agent's silences and active alerts, POST pushes D1's silences
+    if (url.pathname === "/alerts/silences" && (request.method === "GET" || request.method === "POST")) {
+      return this.handleAlertSilencesRequest(request.method === "POST");
+    }
+
+    // Metric alert rules: GET reads the agent's rules and firing alerts, POST replaces the rules
+    if (url.pathname === "/alerts/metric-rules" && (request.method === "GET" || request.method === "POST")) {
+      return this.handleMetricAlertRulesRequest(request);
+    }
+
     // Notify agent of available update
     if (url.pathname === "/notify-update" && request.method === "POST") {
       return this.handleNotifyUpdate(request);
@@ -406,6 +434,13 @@ export class AgentConnection extends DurableObject {
       }
     }
 
+    // Metrics history endpoint (agent-side history, see metrics.query)
+    if (url.pathname.startsWith("/servers/") && url.pathname.endsWith("/metrics/history") && request.method === "GET") {
+      const parts = url.pathname.split("/");
+      const serverId = parts[2];
+      return this.handleMetricsQueryRequest(serverId, url.searchParams);
+    }
+
     // Server storage endpoint
     if (url.pathname.startsWith("/servers/") && url.pathname.endsWith("/storage") && request.method === "POST") {
       const parts = url.pathname.split("/");
@@ -442,6 +477,45 @@ export class AgentConnection extends DurableObject {
       }
     }
 
+    // Backup schedules: GET reads them from the agent, POST replaces the server's schedules
+    if (url.pathname.match(/^\/servers\/[^/]+\/backup-schedules$/) && (request.method === "GET" || request.method === "POST")) {
+      const serverId = url.pathname.split("/")[2];
+      return this.handleBackupScheduleRequest(request, serverId);
+    }
+
+    // Backup retention: GET/POST read or set the server's policy, /preview lists what it would delete
+    if (url.pathname.match(/^\/servers\/[^/]+\/backup-retention$/) && (request.method === "GET" || request.method === "POST")) {
+      return this.handleBackupAgentRequest(request, request.method === "POST" ? "backup.retention.set" : "backup.retention.get");
+    }
+    if (url.pathname.match(/^\/servers\/[^/]+\/backup-retention\/preview$/) && request.method === "POST") {
+      return this.handleBackupAgentRequest(request, "backup.retention.preview");
+    }
+
+    // Off-host backup target: GET/POST read or set it, /sync uploads now, /backups lists or fetches remote copies
+    if (url.pathname.match(/^\/servers\/[^/]+\/backup-target$/) && (request.method === "GET" || request.method === "POST")) {
+      return this.handleBackupAgentRequest(request, request.method === "POST" ? "backup.target.set" : "backup.target.get", 60000);
+    }
+    if (url.pathname.match(/^\/servers\/[^/]+\/backup-target\/sync$/) && request.method === "POST") {
+      return this.handleBackupAgentRequest(request, "backup.target.sync", 1800000);
+    }
+    if (url.pathname.match(/^\/servers\/[^/]+\/backup-target\/backups$/) && request.method === "GET") {
+      return this.handleBackupAgentRequest(request, "backup.remote.list", 120000);
+    }
+    if (url.pathname.match(/^\/servers\/[^/]+\/backup-target\/backups\/[^/]+\/fetch$/) && request.method === "POST") {
+      return this.handleBackupAgentRequest(request, "backup.remote.fetch", 1800000);
+    }
+
+    // Backup pin endpoint (pinned backups are exempt from retention)
+    if (url.pathname.match(/^\/servers\/[^/]+\/backups\/[^/]+\/pin$/) && request.method === "POST") {
+      return this.handleBackupAgentRequest(request, "backup.pin");
+    }
+
+    // Backup verify / extract / export endpoints (reading a large backup can take minutes)
+    const backupFileMatch = url.pathname.match(/^\/servers\/[^/]+\/backups\/[^/]+\/(verify|extract|export)$/);
+    if (backupFileMatch && request.method === "POST") {
+      return this.handleBackupAgentRequest(request, `backup.${backupFileMatch[1]}`, 600000);
+    }
+
     // M12: Backup sync endpoint
     if (url.pathname.startsWith("/servers/") && url.pathname.endsWith("/backups/sync") && request.method === "POST") {
       const parts = url.pathname.split("/");
@@ -658,6 +732,10 @@ export class AgentConnection extends DurableObject {
         await this.handleLogLine(message);
         break;
 
+      case "log.batch":
+        await this.handleLogBatch(message);
+        break;
+
       case "log.stream.error":
         await this.handleLogStreamError(message);
         break;
@@ -694,6 +772,14 @@ export class AgentConnection extends DurableObject {
         await this.handleBackupProgress(message);
         break;
 
+      case "backup.target.synced":
+        await this.handleBackupTargetSynced(message);
+        break;
+
+      case "backup.scheduled":
+        await this.handleScheduledBackup(message);
+        break;
+
       case "adopt.progress":
         await this.handleAdoptProgress(message);
         break;
@@ -702,6 +788,27 @@ export class AgentConnection extends DurableObject {
         this.handlePlayersUpdate(message);
         break;
 
+      case "players.joined":
+      case "players.left":
+        this.handlePlayerSessionEvent(message);
+        break;
+
+      case "game.event":
+        this.handleGameEvent(message);
+        break;
+
+      case "server.hibernation":
+        await this.handleHibernationStatus(message);
+        break;
+
+      case "alert.metric":
+        await this.handleMetricAlert(message);
+        break;
+
+      case "server.alert":
+        await this.handleLogAlert(message);
+        break;
+
       case "server.metrics.batch":
         await this.handleMetricsBatch(message);
         break;
@@ -976,6 +1083,9 @@ export class AgentConnection extends DurableObject {
       // Trigger initial server status sync in background
       this.ctx.waitUntil(this.triggerServerSync());
 
+      // Fill the metrics gap left by the disconnect from the agent's local history
+      this.ctx.waitUntil(this.backfillMetrics(now));
+
       // Subscribe to agent logs for caching
       if (!this.isAgentLogStreaming) {
         console.log(`[AgentConnection] Subscribing to agent logs for caching`);
@@ -1095,6 +1205,7 @@ export class AgentConnection extends DurableObject {
       const alertRecipients = rawRecipients.map(r => ({
         email: r.email,
         colors: getEmailThemeColors(r.theme),
+        locale: r.locale || undefined,
       }));
       const resendApiKey = this.env.RESEND_API_KEY || null;
       const resendFromEmail = this.env.RESEND_FROM_EMAIL
@@ -1108,6 +1219,8 @@ export class AgentConnection extends DurableObject {
         alertRecipients,
         resendApiKey,
         resendFromEmail,
+        silences: await this.loadAlertSilences(agentId),
+        notificationTemplates: await this.loadNotificationTemplates(),
       }));
 
       // Trigger initial server status sync in background
@@ -1203,6 +1316,118 @@ export class AgentConnection extends DurableObject {
     console.log(`[AgentConnection] Player stats update: ${servers.length} servers, ${totalPlayers} total players`);
   }
 
+  /**
+   * Handle players.joined / players.left from agent — broadcast the session change to all UI WebSockets
+   */
+  private handlePlayerSessionEvent(message: Message): void {
+    const event = message.data;
+    if (!event?.serverId || !event.player) {
+      console.error(`[AgentConnection] Invalid ${message.subject} payload`);
+      return;
+    }
+
+    const broadcastMessage = JSON.stringify(createMessage(message.subject, event));
+    for (const ws of this.ctx.getWebSockets("ui")) {
+      try {
+        ws.send(broadcastMessage);
+      } catch (err) {
+        console.error("[AgentConnection] Failed to send player session event to UI:", err);
+      }
+    }
+  }
+
+  /**
+   * Handle game.event from agent — broadcast parsed PZ log events (chat, kills, admin commands) to all UI WebSockets
+   */
+  private handleGameEvent(message: Message): void {
+    const event = message.data;
+    if (!event?.serverId || !event.type) {
+      console.error("[AgentConnection] Invalid game.event payload");
+      return;
+    }
+
+    const broadcastMessage = JSON.stringify(createMessage("game.event", event));
+    for (const ws of this.ctx.getWebSockets("ui")) {
+      try {
+        ws.send(broadcastMessage);
+      } catch (err) {
+        console.error("[AgentConnection] Failed to send game event to UI:", err);
+      }
+    }
+  }
+
+  /**
+   * Handle server.hibernation from agent — store the idle-stop state and broadcast it to all UI WebSockets.
+   * Stored in ctx.storage: a server can stay hibernated for days, across DO hibernation.
+   */
+  private async handleHibernationStatus(message: Message): Promise<void> {
+    const status = message.data;
+    if (!status?.serverId || !status.state) {
+      console.error("[AgentConnection] Invalid server.hibernation payload");
+      return;
+    }
+
+    console.log(`[AgentConnection] Server ${status.serverName} hibernation state: ${status.state}${status.error ? ` (${status.error})` : ''}`);
+
+    if (status.state === "running") {
+      await this.ctx.storage.delete(`hibernation:${status.serverId}`);
+    } else {
+      await this.ctx.storage.put(`hibernation:${status.serverId}`, status);
+    }
+
+    const broadcastMessage = JSON.stringify(createMessage("server.hibernation", status));
+    for (const ws of this.ctx.getWebSockets("ui")) {
+      try {
+        ws.send(broadcastMessage);
+      } catch (err) {
+        console.error("[AgentConnection] Failed to send hibernation state to UI:", err);
+      }
+    }
+  }
+
+  /**
+   * Insert metrics points into server_metrics_history
+   */
+  private async storeMetricsPoints(DB: D1Database, points: any[]): Promise<void> {
+    const now = Math.floor(Date.now() / 1000);
+    const insertStmt = DB.prepare(`
+      INSERT INTO server_metrics_history (id, server_id, timestamp, cpu_percent, memory_percent, memory_used_mb, memory_limit_mb, player_count, created_at,
+        memory_rss_mb, memory_cache_mb, net_rx_bytes_per_sec, net_tx_bytes_per_sec, disk_read_bytes_per_sec, disk_write_bytes_per_sec, pids, cpu_throttled_percent)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `);
+
+    const insertBatch: D1PreparedStatement[] = [];
+    for (const point of points) {
+      const id = crypto.randomUUID();
+      insertBatch.push(
+        insertStmt.bind(
+          id,
+          point.serverId,
+          point.timestamp,
+          point.cpuPercent,
+          point.memoryPercent,
+          point.memoryUsedMB,
+          point.memoryLimitMB,
+          point.playerCount ?? null,
+          now,
+          point.memoryRssMB ?? null,
+          point.memoryCacheMB ?? null,
+          point.netRxBytesPerSec ?? null,
+          point.netTxBytesPerSec ?? null,
+          point.diskReadBytesPerSec ?? null,
+          point.diskWriteBytesPerSec ?? null,
+          point.pids ?? null,
+          point.cpuThrottledPercent ?? null
+        )
+      );
+    }
+
+    // Keep each D1 batch small (backfill pages can hold thousands of points)
+    for (let i = 0; i < insertBatch.length; i += 500) {
+      await DB.batch(insertBatch.slice(i, i + 500));
+    }
+  }
+
   /**
    * Handle server.metrics.batch message from agent
    */
@@ -1220,30 +1445,7 @@ export class AgentConnection extends DurableObject {
     const threesDaysAgo = now - (3 * 24 * 60 * 60);
 
     try {
-      const insertStmt = DB.prepare(`
-        INSERT INTO server_metrics_history (id, server_id, timestamp, cpu_percent, memory_percent, memory_used_mb, memory_limit_mb, player_count, created_at)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-      `);
-
-      const insertBatch: D1PreparedStatement[] = [];
-      for (const point of points) {
-        const id = crypto.randomUUID();
-        insertBatch.push(
-          insertStmt.bind(
-            id,
-            point.serverId,
-            point.timestamp,
-            point.cpuPercent,
-            point.memoryPercent,
-            point.memoryUsedMB,
-            point.memoryLimitMB,
-            point.playerCount ?? null,
-            now
-          )
-        );
-      }
-
-      await DB.batch(insertBatch);
+      await this.storeMetricsPoints(DB, points);
 
       if (Math.random() < 0.01) {
         await DB.prepare(`DELETE FROM server_metrics_history WHERE timestamp < ?`)
@@ -1258,6 +1460,140 @@ export class AgentConnection extends DurableObject {
     }
   }
 
+  /**
+   * Backfill server_metrics_history from the agent's local history after a reconnect.
+   * For each server, fetches points between its last stored point and the reconnect time:
+   * raw 10s points where the agent still has them, 1-minute averages before that.
+   * Runs at most once per 10 minutes so a flapping connection doesn't keep re-paging history.
+   */
+  private async backfillMetrics(until: number): Promise<void> {
+    if (!this.agentId) return;
+
+    const DB = (this.env as any).DB;
+    if (!DB) return;
+
+    const lastBackfill = await this.ctx.storage.get<number>('metricsBackfillAt');
+    if (lastBackfill && Date.now() - lastBackfill < 10 * 60 * 1000) {
+      console.log(`[AgentConnection] Skipping metrics backfill (last run ${Math.round((Date.now() - lastBackfill) / 1000)}s ago)`);
+      return;
+    }
+    await this.ctx.storage.put('metricsBackfillAt', Date.now());
+
+    const retentionStart = until - (3 * 24 * 60 * 60); // server_metrics_history keeps 3 days
+    const rawStart = until - (24 * 60 * 60) + 60;       // Agent keeps raw points for 24h
+
+    try {
+      const result = await DB.prepare(`
+        SELECT s.id AS server_id, MAX(h.timestamp) AS last_ts
+        FROM servers s
+        JOIN server_metrics_history h ON h.server_id = s.id AND h.timestamp < ?
+        WHERE s.agent_id = ?
+        GROUP BY s.id
+      `).bind(until, this.agentId).all() as { results?: Array<{ server_id: string; last_ts: number }> };
+
+      let total = 0;
+      for (const row of result.results || []) {
+        const from = Math.max(row.last_ts + 1, retentionStart);
+        if (until - from < 30) continue; // No gap beyond the collector interval
+
+        // Older part of the gap from 1-minute aggregates
+        if (from < rawStart) {
+          total += await this.backfillMetricsRange(DB, row.server_id, from, rawStart, '1m');
+        }
+        total += await this.backfillMetricsRange(DB, row.server_id, Math.max(from, rawStart), until, 'raw');
+      }
+
+      if (total > 0) {
+        console.log(`[AgentConnection] Backfilled ${total} metrics points from agent history`);
+      }
+    } catch (error) {
+      console.error("[AgentConnection] Metrics backfill failed:", error);
+    }
+  }
+
+  /**
+   * Page through metrics.query for one server and range, storing the results.
+   * Pages are small and spaced out so the backfill doesn't crowd out live traffic.
+   * Aggregates are stored as their averages. Returns the number of points stored.
+   */
+  private async backfillMetricsRange(DB: D1Database, serverId: string, from: number, to: number, resolution: 'raw' | '1m'): Promise<number> {
+    let stored = 0;
+    let cursor = from;
+
+    while (cursor < to) {
+      const reply = await this.sendMessageWithReply({
+        subject: "metrics.query",
+        data: { serverId, from: cursor, to, resolution, limit: 1000 },
+      }, 30000);
+
+      if (!reply.data?.success) {
+        // Agents without metrics history just leave the gap
+        console.log(`[AgentConnection] Metrics backfill unavailable: ${reply.data?.error || 'unknown error'}`);
+        return stored;
+      }
+
+      const points = resolution === 'raw'
+        ? (reply.data.points || [])
+        : (reply.data.aggregates || []).map((agg: any) => ({
+            serverId: agg.serverId,
+            timestamp: agg.timestamp,
+            cpuPercent: agg.cpuPercent.avg,
+            memoryPercent: agg.memoryPercent.avg,
+            memoryUsedMB: Math.round(agg.memoryUsedMB.avg),
+            memoryLimitMB: agg.memoryLimitMB,
+            playerCount: agg.playerCount ? Math.round(agg.playerCount.avg) : null,
+          }));
+
+      if (points.length > 0) {
+        await this.storeMetricsPoints(DB, points);
+        stored += points.length;
+      }
+
+      if (!reply.data.hasMore || !reply.data.nextFrom) break;
+      cursor = reply.data.nextFrom;
+      await new Promise((resolve) => setTimeout(resolve, 1000));
+    }
+    return stored;
+  }
+
+  /**
+   * Query the agent's metrics history (for ranges beyond what D1 keeps)
+   */
+  private async handleMetricsQueryRequest(serverId: string, params: URLSearchParams): Promise<Response> {
+    if (!this.isRegistered || !this.agentId) {
+      return new Response(JSON.stringify({ error: "Agent not connected" }), {
+        status: 503,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+
+    try {
+      const reply = await this.sendMessageWithReply({
+        subject: "metrics.query",
+        data: {
+          serverId,
+          from: Number(params.get("from") || 0),
+          to: Number(params.get("to") || 0),
+          resolution: params.get("resolution") || "",
+          limit: Number(params.get("limit") || 0),
+        },
+      }, 30000);
+
+      const success = reply.data.success !== false;
+      return new Response(JSON.stringify(reply.data), {
+        status: success ? 200 : 500,
+        headers: { "Content-Type": "application/json" },
+      });
+    } catch (error) {
+      return new Response(JSON.stringify({
+        error: error instanceof Error ? error.message : "Metrics query failed",
+      }), {
+        status: 504,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+  }
+
   public getPlayerStats(): Array<{
     serverId: string;
     serverName: string;
@@ -1425,6 +1761,102 @@ export class AgentConnection extends DurableObject {
     }
   }
 
+  // ─── Metric Alerts ────────────────────────────────────────────────
+
+  /**
+   * Handle a firing or resolved metric alert from the agent: forward it to the UI and email
+   * recipients (firing uses the offline alert preference, resolved the recovery preference)
+   */
+  private async handleMetricAlert(message: Message): Promise<void> {
+    const event = message.data;
+    if (!event?.ruleId || (event.status !== "firing" && event.status !== "resolved")) {
+      console.error("[AgentConnection] Invalid alert.metric payload");
+      return;
+    }
+
+    console.log(`[AgentConnection] Metric alert ${event.status}: ${event.summary}`);
+
+    const uiMessage = JSON.stringify(createMessage("alert.metric", { agentId: this.agentId, ...event }));
+    for (const ws of this.ctx.getWebSockets("ui")) {
+      try {
+        ws.send(uiMessage);
+      } catch (err) {
+        console.error("[AgentConnection] Failed to send metric alert to UI:", err);
+      }
+    }
+
+    const apiKey = this.env.RESEND_API_KEY;
+    if (!apiKey || !this.agentId) return;
+
+    const agentName = this.agentName || this.agentId;
+    const recipients = await getAlertRecipientsForAgent(this.env.DB, this.agentId, event.status === "firing" ? "offline" : "recovery");
+    const fromEmail = this.env.RESEND_FROM_EMAIL
+      ? `ZedOps Alerts <${this.env.RESEND_FROM_EMAIL}>`
+      : undefined;
+
+    for (const recipient of recipients) {
+      const colors = getEmailThemeColors(recipient.theme);
+      const html = buildMetricAlertEmailHtml(agentName, event, colors);
+      const result = await sendEmail(apiKey, {
+        to: recipient.email,
+        subject: `[ZedOps] ${event.status === "firing" ? "FIRING" : "RESOLVED"}: ${event.ruleName} on agent "${agentName}"`,
+        html,
+        from: fromEmail,
+      });
+
+      if (!result.success) {
+        console.error(`[AgentConnection] Failed to send metric alert to ${recipient.email}: ${result.error}`);
+      }
+    }
+  }
+
+  /**
+   * Handle a log rule alert from the agent: forward it to the UI and email recipients with the
+   * offline alert preference. The agent leaves email to the manager when this message got through.
+   */
+  private async handleLogAlert(message: Message): Promise<void> {
+    const event = message.data;
+    if (!event?.ruleId || !event.serverId) {
+      console.error("[AgentConnection] Invalid server.alert payload");
+      return;
+    }
+
+    console.log(`[AgentConnection] Log alert ${event.ruleName} on ${event.serverName}: ${event.matchCount} match(es)`);
+
+    const uiMessage = JSON.stringify(createMessage("server.alert", { agentId: this.agentId, ...event }));
+    for (const ws of this.ctx.getWebSockets("ui")) {
+      try {
+        ws.send(uiMessage);
+      } catch (err) {
+        console.error("[AgentConnection] Failed to send log alert to UI:", err);
+      }
+    }
+
+    const apiKey = this.env.RESEND_API_KEY;
+    if (!apiKey || !this.agentId) return;
+
+    const agentName = this.agentName || this.agentId;
+    const recipients = await getAlertRecipientsForAgent(this.env.DB, this.agentId, "offline");
+    const fromEmail = this.env.RESEND_FROM_EMAIL
+      ? `ZedOps Alerts <${this.env.RESEND_FROM_EMAIL}>`
+      : undefined;
+
+    for (const recipient of recipients) {
+      const colors = getEmailThemeColors(recipient.theme);
+      const html = buildLogAlertEmailHtml(agentName, event, colors);
+      const result = await sendEmail(apiKey, {
+        to: recipient.email,
+        subject: `[ZedOps] ${event.ruleName} on server "${event.serverName}"`,
+        html,
+        from: fromEmail,
+      });
+
+      if (!result.success) {
+        console.error(`[AgentConnection] Failed to send log alert to ${recipient.email}: ${result.error}`);
+      }
+    }
+  }
+
   // ─── Send Helpers ─────────────────────────────────────────────────
 
   /**
@@ -1447,10 +1879,12 @@ export class AgentConnection extends DurableObject {
     }
 
     const inbox = `_INBOX.${crypto.randomUUID()}`;
+    const traceparent = generateTraceParent();
 
     const replyPromise = new Promise<Message>((resolve, reject) => {
       const timeoutId = setTimeout(() => {
         this.pendingReplies.delete(inbox);
+        console.warn(`[AgentConnection] ${message.subject} timed out (trace ${traceIdFromTraceParent(traceparent)})`);
         reject(new Error(`Request timeout after ${timeout}ms`));
       }, timeout);
 
@@ -1465,6 +1899,7 @@ export class AgentConnection extends DurableObject {
       data: message.data,
       reply: inbox,
       timestamp: Date.now(),
+      traceparent,
     });
 
     return replyPromise;
@@ -1656,6 +2091,199 @@ export class AgentConnection extends DurableObject {
     }
   }
 
+  /**
+   * Load the agent's unexpired alert silences in the agent's wire format
+   */
+  private async loadAlertSilences(agentId: string): Promise<any[]> {
+    const { results } = await this.env.DB.prepare(
+      `SELECT id, kind, server_id, rule_id, fingerprint, starts_at, ends_at, comment, created_by
+       FROM alert_silences WHERE agent_id = ? AND ends_at > ? ORDER BY starts_at`
+    ).bind(agentId, Date.now()).all();
+
+    return (results || []).map((r: any) => ({
+      id: r.id,
+      kind: r.kind || undefined,
+      serverId: r.server_id || undefined,
+      ruleId: r.rule_id || undefined,
+      fingerprint: r.fingerprint || undefined,
+      startsAt: r.starts_at,
+      endsAt: r.ends_at,
+      comment: r.comment || undefined,
+      createdBy: r.created_by || undefined,
+    }));
+  }
+
+  /**
+   * Load the notification template overrides in the agent's wire format
+   */
+  private async loadNotificationTemplates(): Promise<any[]> {
+    const { results } = await this.env.DB.prepare(
+      `SELECT name, locale, body FROM notification_templates ORDER BY name, locale`
+    ).all();
+
+    return (results || []).map((r: any) => ({
+      name: r.name,
+      locale: r.locale || undefined,
+      body: r.body,
+    }));
+  }
+
+  /**
+   * Send the template overrides to the agent (notify.templates.set). Offline agents receive
+   * them with the next auth success instead. An optional body { templates, dryRun } sends
+   * a candidate set instead of D1's; with dryRun the agent only checks that it parses.
+   */
+  private async handleNotificationTemplatesPush(request: Request): Promise<Response> {
+    if (!this.isRegistered || !this.agentId) {
+      return new Response(JSON.stringify({ error: "Agent not connected" }), {
+        status: 503,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+
+    let body: { templates?: any[]; dryRun?: boolean } = {};
+    const raw = await request.text();
+    if (raw) {
+      try {
+        body = JSON.parse(raw);
+      } catch {
+        return new Response(JSON.stringify({ error: "Invalid JSON body" }), {
+          status: 400,
+          headers: { "Content-Type": "application/json" },
+        });
+      }
+    }
+
+    const templates = Array.isArray(body.templates) ? body.templates : await this.loadNotificationTemplates();
+    const inbox = `_INBOX.${crypto.randomUUID()}`;
+
+    const replyPromise = new Promise<Message>((resolve, reject) => {
+      const timeout = setTimeout(() => {
+        this.pendingReplies.delete(inbox);
+        reject(new Error("Request timeout"));
+      }, 10000);
+
+      this.pendingReplies.set(inbox, (msg: Message) => {
+        clearTimeout(timeout);
+        resolve(msg);
+      });
+    });
+
+    this.send({
+      subject: "notify.templates.set",
+      data: { templates, dryRun: body.dryRun === true },
+      reply: inbox,
+    });
+
+    try {
+      const reply = await replyPromise;
+      return new Response(JSON.stringify(reply.data), {
+        status: 200,
+        headers: { "Content-Type": "application/json" },
+      });
+    } catch (error) {
+      return new Response(JSON.stringify({
+        error: error instanceof Error ? error.message : "Request failed",
+      }), {
+        status: 504,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+  }
+
+  /**
+   * Send the current silences to the agent (alert.silences.set), or read its silences and
+   * active alerts (alert.silences.get). Offline agents receive silences with the next auth.
+   */
+  private async handleAlertSilencesRequest(push: boolean): Promise<Response> {
+    if (!this.isRegistered || !this.agentId) {
+      return new Response(JSON.stringify({ error: "Agent not connected" }), {
+        status: 503,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+
+    const data = push ? { silences: await this.loadAlertSilences(this.agentId) } : {};
+    const inbox = `_INBOX.${crypto.randomUUID()}`;
+
+    const replyPromise = new Promise<Message>((resolve, reject) => {
+      const timeout = setTimeout(() => {
+        this.pendingReplies.delete(inbox);
+        reject(new Error("Request timeout"));
+      }, 10000);
+
+      this.pendingReplies.set(inbox, (msg: Message) => {
+        clearTimeout(timeout);
+        resolve(msg);
+      });
+    });
+
+    this.send({
+      subject: push ? "alert.silences.set" : "alert.silences.get",
+      data,
+      reply: inbox,
+    });
+
+    try {
+      const reply = await replyPromise;
+      return new Response(JSON.stringify(reply.data), {
+        status: 200,
+        headers: { "Content-Type": "application/json" },
+      });
+    } catch (error) {
+      return new Response(JSON.stringify({
+        error: error instanceof Error ? error.message : "Request failed",
+      }), {
+        status: 504,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+  }
+
+  /**
+   * Read (alert.metric.rules.get) or replace (alert.metric.rules.set) the agent's metric alert rules
+   */
+  private async handleMetricAlertRulesRequest(request: Request): Promise<Response> {
+    if (!this.isRegistered || !this.agentId) {
+      return new Response(JSON.stringify({ error: "Agent not connected" }), {
+        status: 503,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+
+    let data: any = {};
+    if (request.method === "POST") {
+      try {
+        const body = await request.json() as any;
+        data = { rules: body.rules || [] };
+      } catch {
+        return new Response(JSON.stringify({ error: "Invalid JSON body" }), {
+          status: 400,
+          headers: { "Content-Type": "application/json" },
+        });
+      }
+    }
+
+    try {
+      const reply = await this.sendMessageWithReply({
+        subject: request.method === "POST" ? "alert.metric.rules.set" : "alert.metric.rules.get",
+        data,
+      });
+      const success = reply.data.success !== false;
+      return new Response(JSON.stringify(reply.data), {
+        status: success ? 200 : 400,
+        headers: { "Content-Type": "application/json" },
+      });
+    } catch (error) {
+      return new Response(JSON.stringify({
+        error: error instanceof Error ? error.message : "Request failed",
+      }), {
+        status: 504,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+  }
+
   private async handlePortCheckRequest(request: Request): Promise<Response> {
     if (!this.isRegistered || !this.agentId) {
       return new Response(JSON.stringify({ error: "Agent not connected" }), {
@@ -1958,6 +2586,18 @@ export class AgentConnection extends DurableObject {
     }
   }
 
+  /**
+   * Handle log.batch from agent — unpack into individual log lines
+   */
+  private async handleLogBatch(message: Message): Promise<void> {
+    const { lines } = message.data as { lines?: LogLine[] };
+    if (!Array.isArray(lines)) return;
+
+    for (const line of lines) {
+      await this.handleLogLine({ ...message, subject: "log.line", data: line });
+    }
+  }
+
   /**
    * Handle log.stream.error from agent
    */
@@ -2166,6 +2806,84 @@ export class AgentConnection extends DurableObject {
     }
   }
 
+  /**
+   * Handle backup.scheduled from agent — record scheduled backups in D1 and notify UI WebSockets.
+   * The agent queues these while disconnected, so they can arrive long after the backup ran.
+   */
+  private async handleScheduledBackup(message: Message): Promise<void> {
+    const result = message.data;
+    if (!result?.serverId || !result.status) {
+      console.error("[AgentConnection] Invalid backup.scheduled payload");
+      return;
+    }
+
+    console.log(`[AgentConnection] Scheduled backup ${result.status}: ${result.serverName} (schedule ${result.scheduleId})`);
+
+    if (this.agentId && result.backupId && result.status !== "skipped") {
+      try {
+        const server = await this.env.DB.prepare(
+          `SELECT id FROM servers WHERE id = ? AND agent_id = ?`
+        ).bind(result.serverId, this.agentId).first();
+
+        if (server) {
+          const complete = result.status === "success";
+          await this.env.DB.prepare(
+            `INSERT OR IGNORE INTO backups (id, server_id, agent_id, filename, size_bytes, notes, status, pre_save_success, created_at, completed_at)
+             VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+          ).bind(
+            result.backupId,
+            result.serverId,
+            this.agentId,
+            result.filename || '',
+            result.sizeBytes || 0,
+            result.notes || null,
+            complete ? 'complete' : 'failed',
+            result.preSaveSuccess ? 1 : 0,
+            result.startedAt * 1000,
+            complete ? result.finishedAt * 1000 : null,
+          ).run();
+
+          // Backups the agent's retention policy deleted to make room
+          for (const filename of result.pruned || []) {
+            await this.env.DB.prepare(
+              `DELETE FROM backups WHERE server_id = ? AND filename = ?`
+            ).bind(result.serverId, filename).run();
+          }
+        }
+      } catch (error) {
+        console.error("[AgentConnection] Failed to record scheduled backup:", error);
+      }
+    }
+
+    const broadcastMessage = JSON.stringify(createMessage("backup.scheduled", result));
+    for (const ws of this.ctx.getWebSockets("ui")) {
+      try {
+        ws.send(broadcastMessage);
+      } catch (error) {
+        console.error("[AgentConnection] Failed to send scheduled backup to UI:", error);
+      }
+    }
+  }
+
+  /**
+   * Handle backup.target.synced from agent — broadcast the off-host sync outcome to all UI WebSockets
+   */
+  private async handleBackupTargetSynced(message: Message): Promise<void> {
+    const status = message.data;
+    if (status?.error) {
+      console.warn(`[AgentConnection] Backup sync of ${status.serverName} failed: ${status.error}`);
+    }
+
+    const broadcastMessage = JSON.stringify(createMessage("backup.target.synced", status));
+    for (const ws of this.ctx.getWebSockets("ui")) {
+      try {
+        ws.send(broadcastMessage);
+      } catch (error) {
+        console.error("[AgentConnection] Failed to send backup sync status to UI:", error);
+      }
+    }
+  }
+
   /**
    * Handle adopt.progress from agent — broadcast to all UI WebSockets
    */
@@ -3407,7 +4125,7 @@ export class AgentConnection extends DurableObject {
       });
     }
 
-    const { serverName, dataPath, backupId, notes, containerId, rconPort, rconPassword } = body;
+    const { serverName, dataPath, backupId, notes, pinned, format, containerId, rconPort, rconPassword } = body;
     if (!serverName || !dataPath || !backupId) {
       return new Response(JSON.stringify({ error: "Missing required fields: serverName, dataPath, backupId" }), {
         status: 400,
@@ -3431,7 +4149,7 @@ export class AgentConnection extends DurableObject {
 
     this.send({
       subject: "backup.create",
-      data: { serverName, dataPath, backupId, notes, containerId: containerId || "", rconPort: rconPort || 0, rconPassword: rconPassword || "" },
+      data: { serverName, dataPath, backupId, notes, pinned: pinned === true, format: format || "", containerId: containerId || "", rconPort: rconPort || 0, rconPassword: rconPassword || "" },
       reply: inbox,
     });
 
@@ -3452,6 +4170,106 @@ export class AgentConnection extends DurableObject {
     }
   }
 
+  /**
+   * Forward a backup retention, pin, verify, extract, export or target request body to the agent and return its reply
+   */
+  private async handleBackupAgentRequest(request: Request, subject: string, timeout?: number): Promise<Response> {
+    if (!this.isRegistered || !this.agentId) {
+      return new Response(JSON.stringify({ error: "Agent not connected" }), {
+        status: 503,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+
+    let data: any = {};
+    if (request.method === "POST") {
+      try {
+        data = await request.json();
+      } catch {
+        return new Response(JSON.stringify({ error: "Invalid JSON body" }), {
+          status: 400,
+          headers: { "Content-Type": "application/json" },
+        });
+      }
+    } else {
+      data = { serverName: new URL(request.url).searchParams.get("serverName") || "" };
+    }
+
+    try {
+      const reply = await this.sendMessageWithReply({ subject, data }, timeout);
+      const success = reply.data.success !== false;
+      return new Response(JSON.stringify(reply.data), {
+        status: success ? 200 : 400,
+        headers: { "Content-Type": "application/json" },
+      });
+    } catch (error) {
+      return new Response(JSON.stringify({
+        error: error instanceof Error ? error.message : "Request failed",
+      }), {
+        status: 504,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+  }
+
+  private async handleBackupScheduleRequest(request: Request, serverId: string): Promise<Response> {
+    if (!this.isRegistered || !this.agentId) {
+      return new Response(JSON.stringify({ error: "Agent not connected" }), {
+        status: 503,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+
+    let data: any = { serverId };
+    if (request.method === "POST") {
+      try {
+        const body = await request.json() as any;
+        data = { serverId, schedules: body.schedules || [] };
+      } catch {
+        return new Response(JSON.stringify({ error: "Invalid JSON body" }), {
+          status: 400,
+          headers: { "Content-Type": "application/json" },
+        });
+      }
+    }
+
+    const inbox = `_INBOX.${crypto.randomUUID()}`;
+
+    const replyPromise = new Promise<Message>((resolve, reject) => {
+      const timeout = setTimeout(() => {
+        this.pendingReplies.delete(inbox);
+        reject(new Error("Request timeout"));
+      }, 10000);
+
+      this.pendingReplies.set(inbox, (msg: Message) => {
+        clearTimeout(timeout);
+        resolve(msg);
+      });
+    });
+
+    this.send({
+      subject: request.method === "POST" ? "backup.schedule.set" : "backup.schedule.get",
+      data,
+      reply: inbox,
+    });
+
+    try {
+      const reply = await replyPromise;
+      const success = reply.data.success !== false;
+      return new Response(JSON.stringify(reply.data), {
+        status: success ? 200 : 400,
+        headers: { "Content-Type": "application/json" },
+      });
+    } catch (error) {
+      return new Response(JSON.stringify({
+        error: error instanceof Error ? error.message : "Request failed",
+      }), {
+        status: 504,
+        headers: { "Content-Type": "application/json" },
+      });
+    }
+  }
+
   private async handleBackupSyncRequest(request: Request, serverId: string): Promise<Response> {
     if (!this.isRegistered || !this.agentId) {
       return new Response(JSON.stringify({ error: "Agent not connected" }), {
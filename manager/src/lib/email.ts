@@ -221,6 +221,127 @@ export function buildAgentUpdatedEmailHtml(agentName: string, oldVersion: string
 </html>`;
 }
 
+function escapeHtml(s: string): string {
+  return s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+}
+
+export interface MetricAlertEmailEvent {
+  ruleName: string;
+  severity: string;
+  status: 'firing' | 'resolved';
+  summary: string;
+  serverName?: string;
+}
+
+export function buildMetricAlertEmailHtml(agentName: string, event: MetricAlertEmailEvent, c: EmailThemeColors = DEFAULT_EMAIL_THEME): string {
+  const firing = event.status === 'firing';
+  const statusColor = firing ? c.error : c.success;
+  return `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <meta name="color-scheme" content="dark">
+  <meta name="supported-color-schemes" content="dark">
+</head>
+<body style="margin:0;padding:0;background-color:${c.bg};font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;color:${c.text};">
+  <table width="100%" cellpadding="0" cellspacing="0" bgcolor="${c.bg}" style="background-color:${c.bg};padding:40px 20px;">
+    <tr>
+      <td align="center" bgcolor="${c.bg}">
+        <table width="100%" cellpadding="0" cellspacing="0" bgcolor="${c.card}" style="max-width:520px;background-color:${c.card};border-radius:12px;border:1px solid ${c.border};">
+          <tr>
+            <td bgcolor="${c.card}" style="padding:32px 32px 16px;text-align:center;border-bottom:1px solid ${c.border};">
+              <h1 style="margin:0;font-size:26px;font-weight:700;color:${c.accent};letter-spacing:-0.5px;">ZedOps</h1>
+              <p style="margin:6px 0 0;font-size:12px;color:${c.muted};letter-spacing:1px;text-transform:uppercase;">${firing ? 'Metric Alert' : 'Metric Recovery'}</p>
+            </td>
+          </tr>
+          <tr>
+            <td bgcolor="${c.card}" style="padding:24px 32px;">
+              <p style="margin:0 0 16px;font-size:16px;color:${c.text};line-height:1.5;">
+                <strong style="color:${statusColor};">${firing ? 'FIRING' : 'RESOLVED'}</strong> &mdash; ${escapeHtml(event.summary)}
+              </p>
+              <p style="margin:0 0 8px;font-size:14px;color:${c.muted};line-height:1.5;">
+                Rule: <strong style="color:${c.text};">${escapeHtml(event.ruleName)}</strong> (${escapeHtml(event.severity)})
+              </p>
+              <p style="margin:0;font-size:14px;color:${c.muted};line-height:1.5;">
+                Agent: <strong style="color:${c.accent};">${escapeHtml(agentName)}</strong>${event.serverName ? ` &middot; Server: <strong style="color:${c.text};">${escapeHtml(event.serverName)}</strong>` : ''}
+              </p>
+            </td>
+          </tr>
+          <tr>
+            <td bgcolor="${c.card}" style="padding:16px 32px;border-top:1px solid ${c.border};">
+              <p style="margin:0;font-size:12px;color:${c.muted};text-align:center;">
+                You received this because you are assigned to this agent on ZedOps.
+              </p>
+            </td>
+          </tr>
+        </table>
+      </td>
+    </tr>
+  </table>
+</body>
+</html>`;
+}
+
+export interface LogAlertEmailEvent {
+  ruleName: string;
+  severity: string;
+  serverName: string;
+  pattern: string;
+  matchCount: number;
+  windowSeconds: number;
+  lines?: Array<{ source: string; line: string }>;
+}
+
+export function buildLogAlertEmailHtml(agentName: string, event: LogAlertEmailEvent, c: EmailThemeColors = DEFAULT_EMAIL_THEME): string {
+  const lines = (event.lines || []).slice(-10)
+    .map((l) => `<div style="margin:0 0 4px;">${escapeHtml(l.line.slice(0, 300))}</div>`)
+    .join('');
+  return `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <meta name="color-scheme" content="dark">
+  <meta name="supported-color-schemes" content="dark">
+</head>
+<body style="margin:0;padding:0;background-color:${c.bg};font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;color:${c.text};">
+  <table width="100%" cellpadding="0" cellspacing="0" bgcolor="${c.bg}" style="background-color:${c.bg};padding:40px 20px;">
+    <tr>
+      <td align="center" bgcolor="${c.bg}">
+        <table width="100%" cellpadding="0" cellspacing="0" bgcolor="${c.card}" style="max-width:520px;background-color:${c.card};border-radius:12px;border:1px solid ${c.border};">
+          <tr>
+            <td bgcolor="${c.card}" style="padding:32px 32px 16px;text-align:center;border-bottom:1px solid ${c.border};">
+              <h1 style="margin:0;font-size:26px;font-weight:700;color:${c.accent};letter-spacing:-0.5px;">ZedOps</h1>
+              <p style="margin:6px 0 0;font-size:12px;color:${c.muted};letter-spacing:1px;text-transform:uppercase;">Log Alert</p>
+            </td>
+          </tr>
+          <tr>
+            <td bgcolor="${c.card}" style="padding:24px 32px;">
+              <p style="margin:0 0 16px;font-size:16px;color:${c.text};line-height:1.5;">
+                <strong style="color:${c.error};">${escapeHtml(event.ruleName)}</strong> &mdash; ${event.matchCount} line(s) matching <code>${escapeHtml(event.pattern)}</code> within ${event.windowSeconds}s
+              </p>
+              <p style="margin:0 0 16px;font-size:14px;color:${c.muted};line-height:1.5;">
+                Agent: <strong style="color:${c.accent};">${escapeHtml(agentName)}</strong> &middot; Server: <strong style="color:${c.text};">${escapeHtml(event.serverName)}</strong> (${escapeHtml(event.severity)})
+              </p>
+              ${lines ? `<div style="padding:12px;background-color:${c.bg};border-radius:8px;font-family:monospace;font-size:12px;color:${c.text};word-break:break-all;">${lines}</div>` : ''}
+            </td>
+          </tr>
+          <tr>
+            <td bgcolor="${c.card}" style="padding:16px 32px;border-top:1px solid ${c.border};">
+              <p style="margin:0;font-size:12px;color:${c.muted};text-align:center;">
+                You received this because you are assigned to this agent on ZedOps.
+              </p>
+            </td>
+          </tr>
+        </table>
+      </td>
+    </tr>
+  </table>
+</body>
+</html>`;
+}
+
 export function buildInvitationEmailHtml(invitationUrl: string, role: string, c: EmailThemeColors = DEFAULT_EMAIL_THEME): string {
   return `<!DOCTYPE html>
 <html>
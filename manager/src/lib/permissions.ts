@@ -440,33 +440,33 @@ export async function getAlertRecipientsForAgent(
   db: D1Database,
   agentId: string,
   alertType: 'offline' | 'recovery' | 'update' = 'offline'
-): Promise<Array<{ email: string; theme: string | null }>> {
-  const recipients = new Map<string, { email: string; theme: string | null; userId: string }>();
+): Promise<Array<{ email: string; theme: string | null; locale: string | null }>> {
+  const recipients = new Map<string, { email: string; theme: string | null; locale: string | null; userId: string }>();
 
   // 1. All admin users
   const admins = await db
-    .prepare('SELECT id, email, theme FROM users WHERE role = ?')
+    .prepare('SELECT id, email, theme, locale FROM users WHERE role = ?')
     .bind('admin')
-    .all<{ id: string; email: string; theme: string | null }>();
+    .all<{ id: string; email: string; theme: string | null; locale: string | null }>();
 
   for (const admin of admins.results || []) {
-    recipients.set(admin.email, { email: admin.email, theme: admin.theme, userId: admin.id });
+    recipients.set(admin.email, { email: admin.email, theme: admin.theme, locale: admin.locale, userId: admin.id });
   }
 
   // 2. Users with agent-level or global assignments
   const assigned = await db
     .prepare(
-      `SELECT DISTINCT u.id, u.email, u.theme
+      `SELECT DISTINCT u.id, u.email, u.theme, u.locale
        FROM role_assignments ra
        JOIN users u ON ra.user_id = u.id
        WHERE (ra.scope = 'agent' AND ra.resource_id = ?)
           OR (ra.scope = 'global' AND ra.resource_id IS NULL)`
     )
     .bind(agentId)
-    .all<{ id: string; email: string; theme: string | null }>();
+    .all<{ id: string; email: string; theme: string | null; locale: string | null }>();
 
   for (const user of assigned.results || []) {
-    recipients.set(user.email, { email: user.email, theme: user.theme, userId: user.id });
+    recipients.set(user.email, { email: user.email, theme: user.theme, locale: user.locale, userId: user.id });
   }
 
   // 3. Filter by notification preferences (opt-out model: no row = ON)
@@ -476,7 +476,7 @@ export async function getAlertRecipientsForAgent(
     update: 'alert_update',
   };
   const alertColumn = alertColumnMap[alertType] || 'alert_offline';
-  const filtered: Array<{ email: string; theme: string | null }> = [];
+  const filtered: Array<{ email: string; theme: string | null; locale: string | null }> = [];
 
   for (const recipient of recipients.values()) {
     // Check per-agent preference first, then global, then default ON
@@ -494,7 +494,7 @@ export async function getAlertRecipientsForAgent(
 
     // No preference row = default ON (opt-out model)
     if (!pref || pref.enabled !== 0) {
-      filtered.push({ email: recipient.email, theme: recipient.theme });
+      filtered.push({ email: recipient.email, theme: recipient.theme, locale: recipient.locale });
     }
   }
 
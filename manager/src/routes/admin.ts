@@ -7,7 +7,7 @@
 import { Hono } from 'hono';
 import { generateEphemeralToken } from '../lib/tokens';
 import { requireAuth, requireRole, AuthUser } from '../middleware/auth';
-import { logAgentTokenGenerated, logAgentDeleted } from '../lib/audit';
+import { logAgentTokenGenerated, logAgentDeleted, logAudit } from '../lib/audit';
 
 type Bindings = {
   DB: D1Database;
@@ -210,4 +210,204 @@ admin.post('/broadcast-update', requireAuth(), requireRole('admin'), async (c) =
   });
 });
 
+type NotificationTemplate = { name: string; locale?: string; body: string };
+
+type TemplatePushResult = {
+  notified: string[];
+  failed: string[]; // Unreachable; they receive the templates with their next auth success
+  rejected: { agent: string; error: string }[]; // Reached, but the templates didn't parse
+};
+
+/**
+ * Load the notification template overrides in the agent's wire format
+ */
+async function loadNotificationTemplates(env: Bindings): Promise<NotificationTemplate[]> {
+  const { results } = await env.DB.prepare(
+    'SELECT name, locale, body FROM notification_templates ORDER BY name, locale'
+  ).all();
+
+  return (results || []).map((r: any) => ({
+    name: r.name,
+    locale: r.locale || undefined,
+    body: r.body,
+  }));
+}
+
+/**
+ * Push notification template overrides to every connected agent. Without a candidate set,
+ * agents receive D1's templates; with dryRun they only check that the set parses.
+ * Offline agents receive D1's templates with their next auth success.
+ */
+async function pushNotificationTemplates(
+  env: Bindings,
+  options: { templates?: NotificationTemplate[]; dryRun?: boolean; firstReply?: boolean } = {}
+): Promise<TemplatePushResult> {
+  const agents = await env.DB.prepare(
+    "SELECT id, name FROM agents WHERE status = 'online'"
+  ).all();
+
+  const result: TemplatePushResult = { notified: [], failed: [], rejected: [] };
+
+  for (const agent of agents.results || []) {
+    const agentName = agent.name as string;
+    try {
+      const doId = env.AGENT_CONNECTION.idFromName(agentName);
+      const stub = env.AGENT_CONNECTION.get(doId);
+      const response = await stub.fetch(new Request('http://internal/notify/templates', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ templates: options.templates, dryRun: options.dryRun }),
+      }));
+      const reply = await response.json() as { success?: boolean; error?: string };
+
+      if (reply.success) {
+        result.notified.push(agentName);
+      } else if (response.ok) {
+        // The agent answered: the set doesn't parse
+        console.error(`Agent ${agentName} rejected notification templates:`, reply.error);
+        result.rejected.push({ agent: agentName, error: reply.error || 'rejected' });
+      } else {
+        console.error(`Failed to push notification templates to ${agentName}:`, reply.error);
+        result.failed.push(agentName);
+      }
+    } catch (error) {
+      console.error(`Failed to push notification templates to ${agentName}:`, error);
+      result.failed.push(agentName);
+    }
+
+    // Every agent parses templates the same way: one answer is enough to validate
+    if (options.firstReply && (result.notified.length > 0 || result.rejected.length > 0)) {
+      break;
+    }
+  }
+
+  return result;
+}
+
+/**
+ * GET /api/admin/notification-templates
+ * List notification template overrides
+ *
+ * Requires: admin role
+ * Returns: { templates: { name, locale, body, updated_at }[] }
+ */
+admin.get('/notification-templates', requireAuth(), requireRole('admin'), async (c) => {
+  const { results } = await c.env.DB.prepare(
+    'SELECT name, locale, body, updated_by, updated_at FROM notification_templates ORDER BY name, locale'
+  ).all();
+
+  return c.json({ templates: results || [] });
+});
+
+/**
+ * PUT /api/admin/notification-templates
+ * Create or replace a template override and push it to connected agents.
+ * Templates use Go text/template (subject, text) or html/template (html) syntax. Agents reject
+ * the whole set if one template fails to parse, so the new set is checked by a connected agent
+ * before it is saved, and the previous template is restored if an agent still rejects it.
+ *
+ * Requires: admin role
+ * Body: { name: string, locale?: string, body: string } - e.g. name "agent.offline.subject"
+ * Returns: { success: boolean, notified: string[], failed: string[], rejected: { agent, error }[] }
+ */
+admin.put('/notification-templates', requireAuth(), requireRole('admin'), async (c) => {
+  const user = c.get('user');
+  const body = await c.req.json();
+  const { name, body: templateBody } = body;
+  const locale = typeof body.locale === 'string' ? body.locale : '';
+
+  if (!name || typeof name !== 'string' || !/^[a-z0-9_.-]+\.[a-z]+$/i.test(name)) {
+    return c.json({ error: 'name must look like "<kind>.subject", "<kind>.text" or "<kind>.html"' }, 400);
+  }
+  if (typeof templateBody !== 'string' || templateBody.length === 0) {
+    return c.json({ error: 'body is required' }, 400);
+  }
+
+  // Validate the resulting set before anything is saved
+  const current = await loadNotificationTemplates(c.env);
+  const candidate = current.filter((t) => !(t.name === name && (t.locale || '') === locale));
+  candidate.push({ name, locale: locale || undefined, body: templateBody });
+  const check = await pushNotificationTemplates(c.env, { templates: candidate, dryRun: true, firstReply: true });
+  if (check.rejected.length > 0) {
+    return c.json({ error: `Template rejected: ${check.rejected[0].error}`, rejected: check.rejected }, 400);
+  }
+
+  const previous = await c.env.DB.prepare(
+    'SELECT body, updated_by, updated_at FROM notification_templates WHERE name = ? AND locale = ?'
+  ).bind(name, locale).first();
+
+  await c.env.DB.prepare(
+    `INSERT INTO notification_templates (name, locale, body, updated_by, updated_at)
+     VALUES (?, ?, ?, ?, unixepoch())
+     ON CONFLICT(name, locale) DO UPDATE SET body = excluded.body, updated_by = excluded.updated_by, updated_at = excluded.updated_at`
+  ).bind(name, locale, templateBody, user.id).run();
+
+  const { notified, failed, rejected } = await pushNotificationTemplates(c.env);
+  if (rejected.length > 0) {
+    // No agent could check the set before saving (or agents disagree): restore the previous template
+    if (previous) {
+      await c.env.DB.prepare(
+        'UPDATE notification_templates SET body = ?, updated_by = ?, updated_at = ? WHERE name = ? AND locale = ?'
+      ).bind(previous.body, previous.updated_by, previous.updated_at, name, locale).run();
+    } else {
+      await c.env.DB.prepare(
+        'DELETE FROM notification_templates WHERE name = ? AND locale = ?'
+      ).bind(name, locale).run();
+    }
+    const restored = await pushNotificationTemplates(c.env);
+    return c.json({
+      success: false,
+      error: `Template rejected: ${rejected[0].error}`,
+      notified: [],
+      failed: restored.failed,
+      rejected,
+    }, 400);
+  }
+
+  await logAudit(c.env.DB, c, {
+    userId: user.id,
+    action: 'notification_template.updated',
+    resourceType: 'notification_template',
+    resourceId: locale ? `${name}:${locale}` : name,
+    details: { name, locale },
+  });
+
+  return c.json({ success: failed.length === 0, notified, failed, rejected });
+});
+
+/**
+ * DELETE /api/admin/notification-templates?name=...&locale=...
+ * Remove a template override (the agent's built-in template applies again)
+ *
+ * Requires: admin role
+ * Returns: { success: boolean, notified: string[], failed: string[], rejected: { agent, error }[] }
+ */
+admin.delete('/notification-templates', requireAuth(), requireRole('admin'), async (c) => {
+  const user = c.get('user');
+  const name = c.req.query('name');
+  const locale = c.req.query('locale') || '';
+
+  if (!name) {
+    return c.json({ error: 'name is required' }, 400);
+  }
+
+  const result = await c.env.DB.prepare(
+    'DELETE FROM notification_templates WHERE name = ? AND locale = ?'
+  ).bind(name, locale).run();
+  if (!result.meta?.changes) {
+    return c.json({ error: 'Template not found' }, 404);
+  }
+
+  await logAudit(c.env.DB, c, {
+    userId: user.id,
+    action: 'notification_template.deleted',
+    resourceType: 'notification_template',
+    resourceId: locale ? `${name}:${locale}` : name,
+    details: { name, locale },
+  });
+
+  const { notified, failed, rejected } = await pushNotificationTemplates(c.env);
+  return c.json({ success: failed.length === 0 && rejected.length === 0, notified, failed, rejected });
+});
+
 export { admin };
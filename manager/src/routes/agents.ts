@@ -1626,15 +1626,17 @@ agents.get('/:id/servers', async (c) => {
     let containerHealthMap: Record<string, string> = {};
     let containerVersionMap: Record<string, string> = {};
     let playerStatsMap: Record<string, { playerCount: number; maxPlayers: number; players: string[] }> = {};
+    let hibernationMap: Record<string, string> = {};
     if (agent.status === 'online') {
       try {
         const id = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
         const stub = c.env.AGENT_CONNECTION.get(id);
 
-        // Fetch containers and players in parallel
-        const [containerResponse, playersResponse] = await Promise.all([
+        // Fetch containers, players and hibernation states in parallel
+        const [containerResponse, playersResponse, hibernationResponse] = await Promise.all([
           stub.fetch(`http://do/containers`, { method: 'GET' }),
           stub.fetch(`http://do/players`, { method: 'GET' }),
+          stub.fetch(`http://do/hibernation`, { method: 'GET' }),
         ]);
 
         if (containerResponse.ok) {
@@ -1664,6 +1666,14 @@ agents.get('/:id/servers', async (c) => {
             }
           }
         }
+
+        // Servers stopped for being idle (hibernated, waking, wake_failed)
+        if (hibernationResponse.ok) {
+          const hibernationData = await hibernationResponse.json() as { servers?: Array<{ serverId: string; state: string }> };
+          for (const status of hibernationData.servers || []) {
+            hibernationMap[status.serverId] = status.state;
+          }
+        }
       } catch (err) {
         // If fetch fails, continue without health/player data
         console.log('[Agents API] Could not fetch container health/players:', err);
@@ -1688,6 +1698,7 @@ agents.get('/:id/servers', async (c) => {
       player_count: playerStatsMap[row.id]?.playerCount ?? null,
       max_players: playerStatsMap[row.id]?.maxPlayers ?? null,
       players: playerStatsMap[row.id]?.players ?? null,
+      hibernation_state: hibernationMap[row.id] ?? null,
       data_exists: row.data_exists === 1, // Convert SQLite integer to boolean
       deleted_at: row.deleted_at,
       created_at: row.created_at,
@@ -3640,16 +3651,19 @@ agents.post('/:id/servers/:serverId/backups', async (c) => {
     ).bind(serverId, agentId).first();
     if (!server) return c.json({ error: 'Server not found' }, 404);
 
-    const body = await c.req.json() as { notes?: string };
+    const body = await c.req.json() as { notes?: string; pinned?: boolean; format?: string };
+    if (body.format && body.format !== 'chunked' && body.format !== 'tar.gz') {
+      return c.json({ error: "format must be 'chunked' or 'tar.gz'" }, 400);
+    }
     const backupId = crypto.randomUUID();
     const dataPath = (server.server_data_path || agent.server_data_path || '/data') as string;
     const config = JSON.parse((server.config as string) || '{}');
 
     // Insert D1 row with status=creating
     await c.env.DB.prepare(
-      `INSERT INTO backups (id, server_id, agent_id, filename, size_bytes, notes, status, created_at)
-       VALUES (?, ?, ?, '', 0, ?, 'creating', ?)`
-    ).bind(backupId, serverId, agentId, body.notes || null, Date.now()).run();
+      `INSERT INTO backups (id, server_id, agent_id, filename, size_bytes, notes, status, pinned, created_at)
+       VALUES (?, ?, ?, '', 0, ?, 'creating', ?, ?)`
+    ).bind(backupId, serverId, agentId, body.notes || null, body.pinned ? 1 : 0, Date.now()).run();
 
     // Call DO
     const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
@@ -3663,6 +3677,8 @@ agents.post('/:id/servers/:serverId/backups', async (c) => {
         dataPath,
         backupId,
         notes: body.notes || '',
+        pinned: body.pinned === true,
+        format: body.format || '',
         containerId: server.status === 'running' ? (server.container_id || '') : '',
         rconPort: server.rcon_port || 0,
         rconPassword: config.RCON_PASSWORD || '',
@@ -3677,6 +3693,13 @@ agents.post('/:id/servers/:serverId/backups', async (c) => {
         `UPDATE backups SET filename = ?, size_bytes = ?, status = 'complete', pre_save_success = ?, completed_at = ? WHERE id = ?`
       ).bind(result.filename, result.sizeBytes, result.preSaveSuccess ? 1 : 0, Date.now(), backupId).run();
 
+      // Drop rows for older backups the agent's retention policy deleted
+      for (const filename of result.pruned || []) {
+        await c.env.DB.prepare(
+          `DELETE FROM backups WHERE server_id = ? AND filename = ?`
+        ).bind(serverId, filename).run();
+      }
+
       await logServerOperation(c.env.DB, c, user.id, 'backup.created', serverId, server.name as string, agentId);
     } else {
       // Mark as failed
@@ -3777,13 +3800,21 @@ agents.post('/:id/servers/:serverId/backups/sync', async (c) => {
     for (const disk of diskBackups) {
       if (!d1Map.has(disk.filename)) {
         await c.env.DB.prepare(
-          `INSERT INTO backups (id, server_id, agent_id, filename, size_bytes, notes, status, pre_save_success, created_at, completed_at)
-           VALUES (?, ?, ?, ?, ?, ?, 'complete', ?, ?, ?)`
+          `INSERT INTO backups (id, server_id, agent_id, filename, size_bytes, notes, status, pre_save_success, pinned, created_at, completed_at)
+           VALUES (?, ?, ?, ?, ?, ?, 'complete', ?, ?, ?, ?)`
         ).bind(
           disk.backupId || crypto.randomUUID(),
           serverId, agentId, disk.filename, disk.sizeBytes, disk.notes || null,
-          disk.preSaveSuccess ? 1 : 0, disk.createdAt * 1000, disk.createdAt * 1000
+          disk.preSaveSuccess ? 1 : 0, disk.pinned ? 1 : 0, disk.createdAt * 1000, disk.createdAt * 1000
         ).run();
+      } else {
+        // The .meta.json sidecar is authoritative for the pinned flag
+        const existing = d1Map.get(disk.filename) as any;
+        if (Boolean(existing.pinned) !== (disk.pinned === true)) {
+          await c.env.DB.prepare(
+            `UPDATE backups SET pinned = ? WHERE id = ?`
+          ).bind(disk.pinned ? 1 : 0, existing.id).run();
+        }
       }
     }
 
@@ -3922,4 +3953,909 @@ agents.post('/:id/servers/:serverId/backups/:backupId/restore', async (c) => {
   }
 });
 
+/**
+ * POST /agents/:id/servers/:serverId/backups/:backupId/pin — Pin or unpin a backup
+ * Body: { pinned: boolean }. Pinned backups are never deleted by retention.
+ */
+agents.post('/:id/servers/:serverId/backups/:backupId/pin', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+  const backupId = c.req.param('backupId');
+
+  try {
+    const hasPermission = await canControlServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name, server_data_path FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT * FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const backup = await c.env.DB.prepare(
+      `SELECT * FROM backups WHERE id = ? AND server_id = ?`
+    ).bind(backupId, serverId).first();
+    if (!backup || backup.status !== 'complete') return c.json({ error: 'Backup not found' }, 404);
+
+    const body = await c.req.json() as { pinned?: boolean };
+    const pinned = body.pinned === true;
+    const dataPath = (server.server_data_path || agent.server_data_path || '/data') as string;
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(`http://do/servers/${serverId}/backups/${encodeURIComponent(backup.filename as string)}/pin`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ serverName: server.name, dataPath, filename: backup.filename, pinned }),
+    });
+    const result = await doResponse.json() as any;
+
+    if (result.success) {
+      await c.env.DB.prepare(
+        `UPDATE backups SET pinned = ? WHERE id = ?`
+      ).bind(pinned ? 1 : 0, backupId).run();
+
+      await logServerOperation(c.env.DB, c, user.id, pinned ? 'backup.pinned' : 'backup.unpinned', serverId, server.name as string, agentId);
+    }
+
+    return c.json(result, doResponse.ok ? 200 : 500);
+  } catch (error) {
+    console.error('[Agents API] Error pinning backup:', error);
+    return c.json({ error: 'Failed to pin backup' }, 500);
+  }
+});
+
+/**
+ * Forward a verify, extract or export request for one backup to the agent's DO
+ */
+async function forwardBackupFileRequest(c: any, action: 'verify' | 'extract' | 'export', data: Record<string, unknown>) {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+  const backupId = c.req.param('backupId');
+
+  const hasPermission = action === 'verify'
+    ? await canViewServer(c.env.DB, user.id, user.role, serverId)
+    : await canControlServer(c.env.DB, user.id, user.role, serverId);
+  if (!hasPermission) {
+    return c.json({ error: 'Forbidden' }, 403);
+  }
+
+  const agent = await c.env.DB.prepare(
+    `SELECT id, name, server_data_path FROM agents WHERE id = ?`
+  ).bind(agentId).first();
+  if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+  const server = await c.env.DB.prepare(
+    `SELECT * FROM servers WHERE id = ? AND agent_id = ?`
+  ).bind(serverId, agentId).first();
+  if (!server) return c.json({ error: 'Server not found' }, 404);
+
+  const backup = await c.env.DB.prepare(
+    `SELECT * FROM backups WHERE id = ? AND server_id = ?`
+  ).bind(backupId, serverId).first();
+  if (!backup || backup.status !== 'complete') return c.json({ error: 'Backup not found' }, 404);
+
+  const dataPath = (server.server_data_path || agent.server_data_path || '/data') as string;
+
+  const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+  const stub = c.env.AGENT_CONNECTION.get(doId);
+
+  const doResponse = await stub.fetch(`http://do/servers/${serverId}/backups/${encodeURIComponent(backup.filename as string)}/${action}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ serverName: server.name, dataPath, filename: backup.filename, backupId, ...data }),
+  });
+  const result = await doResponse.json() as any;
+
+  if (result.success && action !== 'verify') {
+    await logServerOperation(c.env.DB, c, user.id, action === 'extract' ? 'backup.extracted' : 'backup.exported', serverId, server.name as string, agentId);
+  }
+
+  return c.json(result, doResponse.ok ? 200 : 500);
+}
+
+/**
+ * POST /agents/:id/servers/:serverId/backups/:backupId/verify — Check a backup is restorable
+ * Body: { full?: boolean }. Without full, chunked backups only check every chunk exists;
+ * with full, every chunk (or the whole tar.gz) is read and its checksum verified.
+ */
+agents.post('/:id/servers/:serverId/backups/:backupId/verify', async (c) => {
+  try {
+    const body = await c.req.json().catch(() => ({})) as { full?: boolean };
+    return await forwardBackupFileRequest(c, 'verify', { full: body.full === true });
+  } catch (error) {
+    console.error('[Agents API] Error verifying backup:', error);
+    return c.json({ error: 'Failed to verify backup' }, 500);
+  }
+});
+
+/**
+ * POST /agents/:id/servers/:serverId/backups/:backupId/extract — Restore a backup into a side directory
+ * Body: { target?: string }. Files land in {server}/restores/{target} on the agent;
+ * the live data/ directory and the running server are not touched.
+ */
+agents.post('/:id/servers/:serverId/backups/:backupId/extract', async (c) => {
+  try {
+    const body = await c.req.json().catch(() => ({})) as { target?: string };
+    return await forwardBackupFileRequest(c, 'extract', { target: body.target || '' });
+  } catch (error) {
+    console.error('[Agents API] Error extracting backup:', error);
+    return c.json({ error: 'Failed to extract backup' }, 500);
+  }
+});
+
+/**
+ * POST /agents/:id/servers/:serverId/backups/:backupId/export — Write a backup out as a portable tar.gz
+ * Returns: { success, path, sizeBytes }. The archive is written to {server}/exports on the agent.
+ */
+agents.post('/:id/servers/:serverId/backups/:backupId/export', async (c) => {
+  try {
+    return await forwardBackupFileRequest(c, 'export', {});
+  } catch (error) {
+    console.error('[Agents API] Error exporting backup:', error);
+    return c.json({ error: 'Failed to export backup' }, 500);
+  }
+});
+
+/**
+ * GET /agents/:id/servers/:serverId/backup-retention — Read the server's retention policy
+ * Returns: { success, policy: { keepLast, keepHourly, keepDaily, keepWeekly, keepMonthly, maxTotalBytes, maxAgeDays, timezone } }
+ */
+agents.get('/:id/servers/:serverId/backup-retention', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canViewServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT id, name FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(
+      `http://do/servers/${serverId}/backup-retention?serverName=${encodeURIComponent(server.name as string)}`,
+      { method: 'GET' }
+    );
+    const result = await doResponse.json() as any;
+
+    return c.json(result, doResponse.ok ? 200 : 500);
+  } catch (error) {
+    console.error('[Agents API] Error reading backup retention:', error);
+    return c.json({ error: 'Failed to read backup retention' }, 500);
+  }
+});
+
+/**
+ * PUT /agents/:id/servers/:serverId/backup-retention — Set the server's retention policy
+ * Body: { policy: { keepLast?, keepHourly?, keepDaily?, keepWeekly?, keepMonthly?, maxTotalBytes?, maxAgeDays?, timezone? } | null }
+ * A null policy restores the default (keep the newest 10).
+ */
+agents.put('/:id/servers/:serverId/backup-retention', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canControlServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden - you do not have permission to control this server' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT id, name FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const body = await c.req.json() as { policy?: any };
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(`http://do/servers/${serverId}/backup-retention`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ serverName: server.name, policy: body.policy || null }),
+    });
+    const result = await doResponse.json() as any;
+
+    if (result.success) {
+      await logServerOperation(c.env.DB, c, user.id, 'backup.retention_updated', serverId, server.name as string, agentId);
+    }
+
+    return c.json(result, doResponse.ok ? 200 : 400);
+  } catch (error) {
+    console.error('[Agents API] Error updating backup retention:', error);
+    return c.json({ error: 'Failed to update backup retention' }, 500);
+  }
+});
+
+/**
+ * POST /agents/:id/servers/:serverId/backup-retention/preview — Show what a policy would delete
+ * Body: { policy? } (defaults to the server's current policy)
+ * Returns: { success, policy, keep: [...], delete: [...], freedBytes }
+ */
+agents.post('/:id/servers/:serverId/backup-retention/preview', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canViewServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name, server_data_path FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT * FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const body = await c.req.json().catch(() => ({})) as { policy?: any };
+    const dataPath = (server.server_data_path || agent.server_data_path || '/data') as string;
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(`http://do/servers/${serverId}/backup-retention/preview`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ serverName: server.name, dataPath, policy: body.policy || undefined }),
+    });
+    const result = await doResponse.json() as any;
+
+    return c.json(result, doResponse.ok ? 200 : 400);
+  } catch (error) {
+    console.error('[Agents API] Error previewing backup retention:', error);
+    return c.json({ error: 'Failed to preview backup retention' }, 500);
+  }
+});
+
+/**
+ * GET /agents/:id/servers/:serverId/backup-target — Read the server's off-host backup target
+ * Returns: { success, settings: { target, autoUpload, retention }, status: { lastSync, lastSuccess, error, uploaded, deleted, remoteBackups } }
+ * Secrets in the target come back as "[REDACTED]".
+ */
+agents.get('/:id/servers/:serverId/backup-target', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canViewServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT id, name FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(
+      `http://do/servers/${serverId}/backup-target?serverName=${encodeURIComponent(server.name as string)}`,
+      { method: 'GET' }
+    );
+    const result = await doResponse.json() as any;
+
+    return c.json(result, doResponse.ok ? 200 : 500);
+  } catch (error) {
+    console.error('[Agents API] Error reading backup target:', error);
+    return c.json({ error: 'Failed to read backup target' }, 500);
+  }
+});
+
+/**
+ * PUT /agents/:id/servers/:serverId/backup-target — Set the server's off-host backup target
+ * Body: { target: { type: 'local'|'s3'|'sftp', ... } | null, autoUpload?: boolean, retention?: {...} }
+ * The agent checks it can reach the target before saving. A null target removes it
+ * (copies already on the target are left alone). Secrets sent as "[REDACTED]" keep their stored value.
+ */
+agents.put('/:id/servers/:serverId/backup-target', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canControlServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden - you do not have permission to control this server' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name, server_data_path FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT * FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const body = await c.req.json() as { target?: any; autoUpload?: boolean; retention?: any };
+    const dataPath = (server.server_data_path || agent.server_data_path || '/data') as string;
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(`http://do/servers/${serverId}/backup-target`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        serverName: server.name,
+        dataPath,
+        target: body.target || null,
+        autoUpload: body.autoUpload === true,
+        retention: body.retention || undefined,
+      }),
+    });
+    const result = await doResponse.json() as any;
+
+    if (result.success) {
+      await logServerOperation(c.env.DB, c, user.id, 'backup.target_updated', serverId, server.name as string, agentId);
+    }
+
+    return c.json(result, doResponse.ok ? 200 : 400);
+  } catch (error) {
+    console.error('[Agents API] Error updating backup target:', error);
+    return c.json({ error: 'Failed to update backup target' }, 500);
+  }
+});
+
+/**
+ * POST /agents/:id/servers/:serverId/backup-target/sync — Upload missing backups and apply remote retention now
+ * Returns: { success, status }
+ */
+agents.post('/:id/servers/:serverId/backup-target/sync', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canControlServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden - you do not have permission to control this server' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT id, name FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(`http://do/servers/${serverId}/backup-target/sync`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ serverName: server.name }),
+    });
+    const result = await doResponse.json() as any;
+
+    return c.json(result, doResponse.ok ? 200 : 500);
+  } catch (error) {
+    console.error('[Agents API] Error syncing backup target:', error);
+    return c.json({ error: 'Failed to sync backup target' }, 500);
+  }
+});
+
+/**
+ * GET /agents/:id/servers/:serverId/backup-target/backups — List the server's backups on its target
+ * Returns: { success, backups: [{ backupId, filename, format, sizeBytes, notes, pinned, createdAt }] }
+ */
+agents.get('/:id/servers/:serverId/backup-target/backups', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canViewServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT id, name FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(
+      `http://do/servers/${serverId}/backup-target/backups?serverName=${encodeURIComponent(server.name as string)}`,
+      { method: 'GET' }
+    );
+    const result = await doResponse.json() as any;
+
+    return c.json(result, doResponse.ok ? 200 : 500);
+  } catch (error) {
+    console.error('[Agents API] Error listing remote backups:', error);
+    return c.json({ error: 'Failed to list remote backups' }, 500);
+  }
+});
+
+/**
+ * POST /agents/:id/servers/:serverId/backup-target/backups/:filename/fetch — Download a remote backup to the agent
+ * The backup then shows up in the server's backup list and is restored like any other.
+ * Returns: { success, backup }
+ */
+agents.post('/:id/servers/:serverId/backup-target/backups/:filename/fetch', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+  const filename = c.req.param('filename');
+
+  try {
+    const hasPermission = await canControlServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden - you do not have permission to control this server' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT id, name FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(`http://do/servers/${serverId}/backup-target/backups/${encodeURIComponent(filename)}/fetch`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ serverName: server.name, filename }),
+    });
+    const result = await doResponse.json() as any;
+
+    if (result.success && result.backup) {
+      const meta = result.backup;
+      const existing = await c.env.DB.prepare(
+        `SELECT id FROM backups WHERE server_id = ? AND filename = ?`
+      ).bind(serverId, meta.filename).first();
+      if (!existing) {
+        await c.env.DB.prepare(
+          `INSERT INTO backups (id, server_id, agent_id, filename, size_bytes, notes, status, pre_save_success, pinned, created_at, completed_at)
+           VALUES (?, ?, ?, ?, ?, ?, 'complete', ?, ?, ?, ?)`
+        ).bind(
+          meta.backupId || crypto.randomUUID(),
+          serverId, agentId, meta.filename, meta.sizeBytes, meta.notes || null,
+          meta.preSaveSuccess ? 1 : 0, meta.pinned ? 1 : 0, meta.createdAt * 1000, Date.now()
+        ).run();
+      }
+
+      await logServerOperation(c.env.DB, c, user.id, 'backup.fetched', serverId, server.name as string, agentId);
+    }
+
+    return c.json(result, doResponse.ok ? 200 : 500);
+  } catch (error) {
+    console.error('[Agents API] Error fetching remote backup:', error);
+    return c.json({ error: 'Failed to fetch remote backup' }, 500);
+  }
+});
+
+/**
+ * GET /agents/:id/servers/:serverId/backup-schedules — Read the server's backup schedules from the agent
+ * Returns: { success, schedules: [{ id, cron, timezone, enabled, notesTemplate, jitterSeconds, skipIfNoPlayers, nextRun, lastRun, lastBackup }], pending }
+ */
+agents.get('/:id/servers/:serverId/backup-schedules', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canViewServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT id FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    // Schedules live on the agent, which runs them even when the manager is unreachable
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(`http://do/servers/${serverId}/backup-schedules`, { method: 'GET' });
+    const result = await doResponse.json() as any;
+
+    return c.json(result, doResponse.ok ? 200 : 500);
+  } catch (error) {
+    console.error('[Agents API] Error reading backup schedules:', error);
+    return c.json({ error: 'Failed to read backup schedules' }, 500);
+  }
+});
+
+/**
+ * PUT /agents/:id/servers/:serverId/backup-schedules — Replace the server's backup schedules
+ * Body: { schedules: [{ id?, cron, timezone?, enabled?, notesTemplate?, jitterSeconds?, skipIfNoPlayers? }] }
+ */
+agents.put('/:id/servers/:serverId/backup-schedules', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const serverId = c.req.param('serverId');
+
+  try {
+    const hasPermission = await canControlServer(c.env.DB, user.id, user.role, serverId);
+    if (!hasPermission) {
+      return c.json({ error: 'Forbidden - you do not have permission to control this server' }, 403);
+    }
+
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name, server_data_path FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const server = await c.env.DB.prepare(
+      `SELECT * FROM servers WHERE id = ? AND agent_id = ?`
+    ).bind(serverId, agentId).first();
+    if (!server) return c.json({ error: 'Server not found' }, 404);
+
+    const body = await c.req.json() as { schedules?: any[] };
+    if (!Array.isArray(body.schedules)) {
+      return c.json({ error: 'schedules must be an array' }, 400);
+    }
+    for (const schedule of body.schedules) {
+      if (typeof schedule?.cron !== 'string' || !schedule.cron.trim()) {
+        return c.json({ error: 'Each schedule requires a cron expression' }, 400);
+      }
+    }
+
+    const dataPath = (server.server_data_path || agent.server_data_path || '/data') as string;
+    const schedules = body.schedules.map((schedule) => ({
+      id: schedule.id || crypto.randomUUID(),
+      serverId,
+      serverName: server.name,
+      dataPath,
+      cron: schedule.cron.trim(),
+      timezone: schedule.timezone || '',
+      enabled: schedule.enabled !== false,
+      notesTemplate: schedule.notesTemplate || '',
+      jitterSeconds: typeof schedule.jitterSeconds === 'number' ? schedule.jitterSeconds : undefined,
+      skipIfNoPlayers: schedule.skipIfNoPlayers === true,
+    }));
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch(`http://do/servers/${serverId}/backup-schedules`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ schedules }),
+    });
+    const result = await doResponse.json() as any;
+
+    if (result.success) {
+      await logServerOperation(c.env.DB, c, user.id, 'backup.schedules_updated', serverId, server.name as string, agentId);
+    }
+
+    return c.json(result, doResponse.ok ? 200 : 400);
+  } catch (error) {
+    console.error('[Agents API] Error updating backup schedules:', error);
+    return c.json({ error: 'Failed to update backup schedules' }, 500);
+  }
+});
+
+/**
+ * Push an agent's silences (POST) or read its active alerts (GET) through its Durable Object.
+ * Returns the agent's reply, or null if the agent is offline; it picks silences up on its next auth.
+ */
+async function agentAlertSilences(env: any, agentName: string, method: 'GET' | 'POST'): Promise<any | null> {
+  try {
+    const doId = env.AGENT_CONNECTION.idFromName(agentName);
+    const stub = env.AGENT_CONNECTION.get(doId);
+    const response = await stub.fetch('http://do/alerts/silences', { method });
+    if (!response.ok) return null;
+    return await response.json();
+  } catch (error) {
+    console.error('[Agents API] Failed to push alert silences:', error);
+    return null;
+  }
+}
+
+/**
+ * GET /api/agents/:id/alerts/silences — List unexpired silences and the agent's active alerts
+ * Permission: Admin only
+ */
+agents.get('/:id/alerts/silences', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+
+  if (user.role !== 'admin') {
+    return c.json({ error: 'Forbidden - requires admin role' }, 403);
+  }
+
+  try {
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const { results } = await c.env.DB.prepare(
+      `SELECT * FROM alert_silences WHERE agent_id = ? AND ends_at > ? ORDER BY starts_at`
+    ).bind(agentId, Date.now()).all();
+
+    // Active alerts come from the agent (it owns the alert state)
+    const reply = await agentAlertSilences(c.env, agent.name as string, 'GET');
+
+    return c.json({
+      silences: results || [],
+      alerts: reply?.alerts || [],
+      agentReachable: reply !== null,
+    });
+  } catch (error) {
+    console.error('[Agents API] Error listing alert silences:', error);
+    return c.json({ error: 'Failed to list alert silences' }, 500);
+  }
+});
+
+/**
+ * POST /api/agents/:id/alerts/silences — Create a silence
+ * Body: { kind?, serverId?, ruleId?, fingerprint?, startsAt? (ms), endsAt? (ms) | durationMinutes?, comment? }
+ * Permission: Admin only
+ */
+agents.post('/:id/alerts/silences', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+
+  if (user.role !== 'admin') {
+    return c.json({ error: 'Forbidden - requires admin role' }, 403);
+  }
+
+  const body = await c.req.json();
+  const startsAt = typeof body.startsAt === 'number' ? body.startsAt : Date.now();
+  const endsAt = typeof body.endsAt === 'number'
+    ? body.endsAt
+    : typeof body.durationMinutes === 'number' ? startsAt + body.durationMinutes * 60_000 : null;
+  if (!endsAt || endsAt <= startsAt || endsAt <= Date.now()) {
+    return c.json({ error: 'endsAt or durationMinutes must end in the future' }, 400);
+  }
+
+  try {
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const silenceId = crypto.randomUUID();
+    await c.env.DB.prepare(
+      `INSERT INTO alert_silences (id, agent_id, kind, server_id, rule_id, fingerprint, starts_at, ends_at, comment, created_by)
+       VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+    ).bind(
+      silenceId, agentId, body.kind || null, body.serverId || null, body.ruleId || null,
+      body.fingerprint || null, startsAt, endsAt, body.comment || null, user.id
+    ).run();
+
+    const reply = await agentAlertSilences(c.env, agent.name as string, 'POST');
+
+    await logAudit(c.env.DB, c, {
+      userId: user.id,
+      action: 'alert.silence.created',
+      resourceType: 'agent',
+      resourceId: agentId,
+      details: {
+        silenceId,
+        agentName: agent.name,
+        kind: body.kind,
+        serverId: body.serverId,
+        ruleId: body.ruleId,
+        endsAt,
+        comment: body.comment,
+      },
+    });
+
+    return c.json({ success: true, id: silenceId, pushed: reply?.success === true });
+  } catch (error) {
+    console.error('[Agents API] Error creating alert silence:', error);
+    return c.json({ error: 'Failed to create alert silence' }, 500);
+  }
+});
+
+/**
+ * DELETE /api/agents/:id/alerts/silences/:silenceId — Expire a silence
+ * Permission: Admin only
+ */
+agents.delete('/:id/alerts/silences/:silenceId', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+  const silenceId = c.req.param('silenceId');
+
+  if (user.role !== 'admin') {
+    return c.json({ error: 'Forbidden - requires admin role' }, 403);
+  }
+
+  try {
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const result = await c.env.DB.prepare(
+      `DELETE FROM alert_silences WHERE id = ? AND agent_id = ?`
+    ).bind(silenceId, agentId).run();
+    if (!result.meta?.changes) return c.json({ error: 'Silence not found' }, 404);
+
+    const reply = await agentAlertSilences(c.env, agent.name as string, 'POST');
+
+    await logAudit(c.env.DB, c, {
+      userId: user.id,
+      action: 'alert.silence.deleted',
+      resourceType: 'agent',
+      resourceId: agentId,
+      details: { silenceId, agentName: agent.name },
+    });
+
+    return c.json({ success: true, pushed: reply?.success === true });
+  } catch (error) {
+    console.error('[Agents API] Error deleting alert silence:', error);
+    return c.json({ error: 'Failed to delete alert silence' }, 500);
+  }
+});
+
+/**
+ * GET /api/agents/:id/alerts/metric-rules — Read the agent's metric alert rules and firing alerts
+ * Returns: { success, rules: [{ id, name, metric, serverId?, operator, threshold, recoverThreshold?, forSeconds, forSamples?, recoverForSeconds?, severity?, enabled }], active }
+ * Permission: Admin only
+ */
+agents.get('/:id/alerts/metric-rules', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+
+  if (user.role !== 'admin') {
+    return c.json({ error: 'Forbidden - requires admin role' }, 403);
+  }
+
+  try {
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    // Rules live on the agent, which evaluates them even when the manager is unreachable
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch('http://do/alerts/metric-rules', { method: 'GET' });
+    const result = await doResponse.json() as any;
+
+    return c.json(result, doResponse.ok ? 200 : 500);
+  } catch (error) {
+    console.error('[Agents API] Error reading metric alert rules:', error);
+    return c.json({ error: 'Failed to read metric alert rules' }, 500);
+  }
+});
+
+/**
+ * PUT /api/agents/:id/alerts/metric-rules — Replace the agent's metric alert rules
+ * Body: { rules: [{ id?, name, metric, serverId?, operator, threshold, recoverThreshold?, forSeconds?, forSamples?, recoverForSeconds?, severity?, enabled? }] }
+ * Permission: Admin only
+ */
+agents.put('/:id/alerts/metric-rules', async (c) => {
+  const user = c.get('user');
+  const agentId = c.req.param('id');
+
+  if (user.role !== 'admin') {
+    return c.json({ error: 'Forbidden - requires admin role' }, 403);
+  }
+
+  const body = await c.req.json() as { rules?: any[] };
+  if (!Array.isArray(body.rules)) {
+    return c.json({ error: 'rules must be an array' }, 400);
+  }
+  for (const rule of body.rules) {
+    if (typeof rule?.name !== 'string' || typeof rule.metric !== 'string' ||
+        typeof rule.operator !== 'string' || typeof rule.threshold !== 'number') {
+      return c.json({ error: 'Each rule requires a name, metric, operator and numeric threshold' }, 400);
+    }
+  }
+
+  // The agent validates metrics, operators and durations and rejects the whole set on error
+  const rules = body.rules.map((rule) => ({
+    id: rule.id || crypto.randomUUID(),
+    name: rule.name,
+    metric: rule.metric,
+    serverId: rule.serverId || undefined,
+    operator: rule.operator,
+    threshold: rule.threshold,
+    recoverThreshold: typeof rule.recoverThreshold === 'number' ? rule.recoverThreshold : undefined,
+    forSeconds: typeof rule.forSeconds === 'number' ? rule.forSeconds : 0,
+    forSamples: typeof rule.forSamples === 'number' ? rule.forSamples : undefined,
+    recoverForSeconds: typeof rule.recoverForSeconds === 'number' ? rule.recoverForSeconds : undefined,
+    severity: rule.severity || undefined,
+    enabled: rule.enabled !== false,
+  }));
+
+  try {
+    const agent = await c.env.DB.prepare(
+      `SELECT id, name FROM agents WHERE id = ?`
+    ).bind(agentId).first();
+    if (!agent) return c.json({ error: 'Agent not found' }, 404);
+
+    const doId = c.env.AGENT_CONNECTION.idFromName(agent.name as string);
+    const stub = c.env.AGENT_CONNECTION.get(doId);
+
+    const doResponse = await stub.fetch('http://do/alerts/metric-rules', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ rules }),
+    });
+    const result = await doResponse.json() as any;
+
+    if (result.success) {
+      await logAudit(c.env.DB, c, {
+        userId: user.id,
+        action: 'alert.metric_rules.updated',
+        resourceType: 'agent',
+        resourceId: agentId,
+        details: { agentName: agent.name, rules: rules.length },
+      });
+    }
+
+    return c.json(result, doResponse.ok ? 200 : 400);
+  } catch (error) {
+    console.error('[Agents API] Error updating metric alert rules:', error);
+    return c.json({ error: 'Failed to update metric alert rules' }, 500);
+  }
+});
+
 export { agents };
@@ -2,7 +2,7 @@
  * User Preferences API
  *
  * GET  /api/preferences     - Get current user's preferences
- * PATCH /api/preferences    - Update preferences (theme, etc.)
+ * PATCH /api/preferences    - Update preferences (theme, locale, etc.)
  */
 
 import { Hono } from 'hono';
@@ -23,6 +23,9 @@ const VALID_THEMES = [
   'solar-flare',
 ];
 
+// BCP 47 language tag used for alert emails, e.g. "fr" or "pt-BR"
+const LOCALE_PATTERN = /^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$/;
+
 export const preferences = new Hono<{ Bindings: Bindings }>();
 
 preferences.use('*', requireAuth());
@@ -31,7 +34,7 @@ preferences.get('/', async (c) => {
   const user = c.get('user') as { id: string };
 
   const row = await c.env.DB.prepare(
-    'SELECT theme FROM users WHERE id = ?'
+    'SELECT theme, locale FROM users WHERE id = ?'
   ).bind(user.id).first();
 
   if (!row) {
@@ -45,6 +48,7 @@ preferences.get('/', async (c) => {
 
   return c.json({
     theme: row.theme || 'solar-flare',
+    locale: row.locale || null,
     notifications: {
       alertOffline: notifRow ? notifRow.alert_offline === 1 : true,
       alertRecovery: notifRow ? notifRow.alert_recovery === 1 : true,
@@ -56,7 +60,7 @@ preferences.get('/', async (c) => {
 preferences.patch('/', async (c) => {
   const user = c.get('user') as { id: string };
 
-  let body: { theme?: string; notifications?: { alertOffline?: boolean; alertRecovery?: boolean; alertUpdate?: boolean } };
+  let body: { theme?: string; locale?: string | null; notifications?: { alertOffline?: boolean; alertRecovery?: boolean; alertUpdate?: boolean } };
   try {
     body = await c.req.json();
   } catch {
@@ -73,6 +77,16 @@ preferences.patch('/', async (c) => {
     ).bind(body.theme, Date.now(), user.id).run();
   }
 
+  if (body.locale !== undefined) {
+    if (body.locale !== null && !LOCALE_PATTERN.test(body.locale)) {
+      return c.json({ error: 'Invalid locale (expected a language tag like "fr" or "pt-BR")' }, 400);
+    }
+
+    await c.env.DB.prepare(
+      'UPDATE users SET locale = ?, updated_at = ? WHERE id = ?'
+    ).bind(body.locale || null, Date.now(), user.id).run();
+  }
+
   if (body.notifications !== undefined) {
     const n = body.notifications;
     const alertOffline = n.alertOffline !== undefined ? (n.alertOffline ? 1 : 0) : null;
@@ -112,7 +126,7 @@ preferences.patch('/', async (c) => {
     }
   }
 
-  return c.json({ success: true, theme: body.theme });
+  return c.json({ success: true, theme: body.theme, locale: body.locale });
 });
 
 // --- Per-Agent Notification Preferences ---
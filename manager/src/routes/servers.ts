@@ -314,13 +314,14 @@ servers.get('/:id', async (c) => {
  * Get metrics history for Performance tab and sparklines
  *
  * Query params:
- *   - range: '30m' | '3h' | '12h' | '24h' | '3d' (default: '30m')
+ *   - range: '30m' | '3h' | '12h' | '24h' | '3d' | '7d' | '30d' (default: '30m')
  *
  * Downsampling strategy:
  *   - 30m, 3h: raw data (10s intervals)
  *   - 12h: 1-minute averages (~720 points)
  *   - 24h: 5-minute averages (~288 points)
  *   - 3d: 15-minute averages (~288 points)
+ *   - 7d, 30d: 15-minute averages from the agent's local history (agent must be online)
  *
  * Returns: Array of metrics points in chronological order
  * Permission: User must have view access to server
@@ -334,6 +335,7 @@ servers.get('/:serverId/metrics/history', async (c) => {
   const now = Math.floor(Date.now() / 1000);
   let fromTimestamp: number;
   let downsampleInterval: number = 0; // 0 = no downsampling
+  let fromAgent = false; // Beyond the 3 days kept in D1
 
   switch (range) {
     case '3h':
@@ -351,6 +353,14 @@ servers.get('/:serverId/metrics/history', async (c) => {
       fromTimestamp = now - (3 * 24 * 60 * 60);
       downsampleInterval = 900; // 15-minute averages
       break;
+    case '7d':
+      fromTimestamp = now - (7 * 24 * 60 * 60);
+      fromAgent = true;
+      break;
+    case '30d':
+      fromTimestamp = now - (30 * 24 * 60 * 60);
+      fromAgent = true;
+      break;
     case '30m':
     default:
       fromTimestamp = now - (30 * 60);
@@ -360,8 +370,10 @@ servers.get('/:serverId/metrics/history', async (c) => {
   try {
     // First verify user can view this server
     const server = await c.env.DB.prepare(
-      'SELECT id, agent_id FROM servers WHERE id = ?'
-    ).bind(serverId).first<{ id: string; agent_id: string }>();
+      `SELECT s.id, s.agent_id, a.name as agent_name, a.status as agent_status
+       FROM servers s JOIN agents a ON s.agent_id = a.id
+       WHERE s.id = ?`
+    ).bind(serverId).first<{ id: string; agent_id: string; agent_name: string; agent_status: string }>();
 
     if (!server) {
       return c.json({ error: 'Server not found' }, 404);
@@ -378,7 +390,38 @@ servers.get('/:serverId/metrics/history', async (c) => {
     // Fetch metrics history
     let points: { timestamp: number; cpu: number | null; memory: number | null; players: number | null }[];
 
-    if (downsampleInterval > 0) {
+    if (fromAgent) {
+      if (server.agent_status !== 'online') {
+        return c.json({ error: 'Agent is offline; ranges over 3 days are served from agent history' }, 503);
+      }
+
+      const id = c.env.AGENT_CONNECTION.idFromName(server.agent_name);
+      const stub = c.env.AGENT_CONNECTION.get(id);
+      const response = await stub.fetch(
+        `http://do/servers/${serverId}/metrics/history?from=${fromTimestamp}&to=${now + 1}&resolution=15m&limit=3000`,
+        { method: 'GET' }
+      );
+      const result = await response.json() as {
+        success?: boolean;
+        error?: string;
+        aggregates?: Array<{
+          timestamp: number;
+          cpuPercent: { avg: number };
+          memoryPercent: { avg: number };
+          playerCount?: { avg: number };
+        }>;
+      };
+      if (!response.ok || !result.success) {
+        return c.json({ error: result.error || 'Failed to query agent metrics history' }, 502);
+      }
+
+      points = (result.aggregates || []).map(agg => ({
+        timestamp: agg.timestamp,
+        cpu: agg.cpuPercent.avg,
+        memory: agg.memoryPercent.avg,
+        players: agg.playerCount ? Math.round(agg.playerCount.avg) : null,
+      }));
+    } else if (downsampleInterval > 0) {
       // Use SQLite GROUP BY for downsampling (bucket by interval)
       const query = `
         SELECT
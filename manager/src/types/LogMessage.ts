@@ -10,7 +10,7 @@
 export interface LogLine {
   containerId: string;
   timestamp: number;      // Unix timestamp in milliseconds
-  stream: 'stdout' | 'stderr' | 'unknown';
+  stream: 'stdout' | 'stderr' | 'system' | 'unknown'; // system = agent notices (e.g. dropped lines)
   message: string;
 }
 
@@ -20,6 +20,9 @@ export interface Message {
 
   /** Unix timestamp in milliseconds (optional) */
   timestamp?: number;
+
+  /** W3C traceparent correlating a request, the agent's spans and the reply (optional) */
+  traceparent?: string;
 }
 
 /**
@@ -88,6 +91,23 @@ export function generateInbox(): string {
   return `_INBOX.${crypto.randomUUID()}`;
 }
 
+/**
+ * Generate a W3C traceparent for a new sampled trace
+ * Format: 00-<32 hex trace id>-<16 hex parent id>-01
+ */
+export function generateTraceParent(): string {
+  const hex = (bytes: number) =>
+    Array.from(crypto.getRandomValues(new Uint8Array(bytes)), (b) => b.toString(16).padStart(2, '0')).join('');
+  return `00-${hex(16)}-${hex(8)}-01`;
+}
+
+/**
+ * Extract the trace id from a traceparent (for log correlation)
+ */
+export function traceIdFromTraceParent(traceparent: string | undefined): string | undefined {
+  return traceparent?.split('-')[1];
+}
+
 /**
  * Check if subject is an inbox reply
  */